@@ -0,0 +1,129 @@
+// Description: This file contains the code for the 'k8r auth' command.
+
+// Package auth implements a 'k8r auth' command for checking that k8r can
+// authenticate with a cluster before using it.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"k8s.io/client-go/kubernetes"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// Options contains options for the auth check command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Kube is the kubeconfig and context to check
+	Kube kube.Options
+}
+
+// NewOptions contains options for the auth check command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new auth command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "auth",
+		Usage: "Check authentication with Kubernetes clusters",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "check",
+				Usage: "Verify who k8r is authenticated as and which of the permissions checkup needs are granted",
+				Action: func(c *cli.Context) error {
+					o.Kube = kube.OptionsFromFlags(c)
+					return o.Check(c.Context)
+				},
+				Flags: kube.Flags(),
+			},
+		},
+	}
+}
+
+// Check checks that the cluster can be authenticated with and reports
+// which of the permissions checkup needs are granted
+func (o *Options) Check(ctx context.Context) error {
+	config, err := o.Kube.RESTConfig()
+	if err != nil {
+		return err
+	}
+
+	k, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	// Any request verifies the credentials, the version is a cheap one
+	version, err := k.Discovery().ServerVersion()
+	if err != nil {
+		return errors.Wrap(kube.ExplainError(err), "failed to authenticate")
+	}
+
+	user := "unknown, this requires Kubernetes 1.26+"
+	identity, ok, err := kube.WhoAmI(ctx, k)
+	if err != nil {
+		return err
+	}
+	if ok {
+		user = identity.Username
+		if len(identity.Groups) != 0 {
+			user += fmt.Sprintf(" (groups: %s)", strings.Join(identity.Groups, ", "))
+		}
+	}
+
+	bold.Println("🔑  Authentication:")
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "    Context:\t%s\n", o.Kube.ContextName())
+	fmt.Fprintf(tw, "    Server:\t%s (%s)\n", config.Host, version.GitVersion)
+	fmt.Fprintf(tw, "    Method:\t%s\n", kube.AuthMethod(config))
+	fmt.Fprintf(tw, "    User:\t%s\n", user)
+	tw.Flush()
+
+	results, err := checkup.CheckPermissions(ctx, k, checkup.RequiredPermissions)
+	if err != nil {
+		return kube.ExplainError(err)
+	}
+
+	fmt.Println()
+	bold.Println("🔒  Permissions checkup needs:")
+	tw = tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	missing := 0
+	for i := range results {
+		status := "✅"
+		if !results[i].Allowed {
+			status = "❌"
+			missing++
+		}
+		fmt.Fprintf(tw, "    %s  %s\t%s\n", status, results[i].String(), results[i].Reason)
+	}
+	tw.Flush()
+
+	if missing != 0 {
+		fmt.Println()
+		fmt.Printf("%d permissions are missing, checks that need them won't find problems\n", missing)
+	}
+
+	return nil
+}
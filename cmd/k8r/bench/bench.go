@@ -0,0 +1,272 @@
+// Description: This file contains the code for the 'k8r bench-apiserver'
+// command.
+
+// Package bench implements a 'k8r bench-apiserver' command that measures
+// how responsive the Kubernetes API server is.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// rejectedRequestsMetric is the API Priority and Fairness metric counting
+// requests that were rejected
+const rejectedRequestsMetric = "apiserver_flowcontrol_rejected_requests_total"
+
+// Options contains options for the bench-apiserver command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Iterations is how many times each request is made
+	Iterations int
+
+	// Limit is the page size used for list requests
+	Limit int64
+
+	// Kube is the kubeconfig and context of the cluster to benchmark
+	Kube kube.Options
+}
+
+// NewOptions contains options for the bench-apiserver command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new bench-apiserver command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "bench-apiserver",
+		Usage: "Measure Kubernetes API server latency and health",
+		Action: func(c *cli.Context) error {
+			o.Iterations = c.Int("iterations")
+			o.Limit = c.Int64("limit")
+			o.Kube = kube.OptionsFromFlags(c)
+			return o.Run(c.Context)
+		},
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:  "iterations",
+				Usage: "Number of times to make each request",
+				Value: 5,
+			},
+			&cli.Int64Flag{
+				Name:  "limit",
+				Usage: "Page size to use for list requests",
+				Value: 500,
+			},
+		}, kube.Flags()...),
+	}
+}
+
+// request is a request that is measured
+type request struct {
+	// Name is the name of the request, e.g. "list pods"
+	Name string
+
+	// Do makes the request
+	Do func(ctx context.Context) error
+}
+
+// requests returns the requests that are measured, these are meant to be
+// representative of what controllers and checkup do
+func (o *Options) requests(k kubernetes.Interface) []request {
+	opts := metav1.ListOptions{Limit: o.Limit}
+	return []request{
+		{"list pods", func(ctx context.Context) error {
+			_, err := k.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+			return err
+		}},
+		{"list nodes", func(ctx context.Context) error {
+			_, err := k.CoreV1().Nodes().List(ctx, opts)
+			return err
+		}},
+		{"list services", func(ctx context.Context) error {
+			_, err := k.CoreV1().Services(metav1.NamespaceAll).List(ctx, opts)
+			return err
+		}},
+		{"list configmaps", func(ctx context.Context) error {
+			_, err := k.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, opts)
+			return err
+		}},
+		{"list deployments", func(ctx context.Context) error {
+			_, err := k.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, opts)
+			return err
+		}},
+		{"get namespace", func(ctx context.Context) error {
+			_, err := k.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+			return err
+		}},
+	}
+}
+
+// Run runs the bench-apiserver command
+func (o *Options) Run(ctx context.Context) error {
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	bold.Println("⏱  Request latency:")
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "    REQUEST\tMIN\tP50\tMAX\tERRORS")
+	for _, r := range o.requests(k) {
+		durations := make([]time.Duration, 0, o.Iterations)
+		errs := 0
+		for i := 0; i < o.Iterations; i++ {
+			start := time.Now()
+			if err := r.Do(ctx); err != nil {
+				o.log.WithError(err).Debugf("request %q failed", r.Name)
+				errs++
+				continue
+			}
+			durations = append(durations, time.Since(start))
+		}
+
+		if len(durations) == 0 {
+			fmt.Fprintf(tw, "    %s\t-\t-\t-\t%d\n", r.Name, errs)
+			continue
+		}
+
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\t%d\n", r.Name,
+			durations[0].Round(time.Millisecond),
+			durations[len(durations)/2].Round(time.Millisecond),
+			durations[len(durations)-1].Round(time.Millisecond),
+			errs,
+		)
+	}
+	tw.Flush()
+
+	fmt.Println()
+	o.printReadyz(ctx, k)
+
+	fmt.Println()
+	o.printRejections(ctx, k)
+
+	return nil
+}
+
+// printReadyz prints the checks from /readyz?verbose that aren't passing
+func (o *Options) printReadyz(ctx context.Context, k kubernetes.Interface) {
+	bold.Println("🩺  Readiness (/readyz):")
+
+	// /readyz returns a non-200 status when a check fails, but the body
+	// still contains the verbose output
+	body, err := k.Discovery().RESTClient().Get().AbsPath("/readyz").Param("verbose", "").Do(ctx).Raw()
+	if len(body) == 0 && err != nil {
+		fmt.Println("    Unable to read /readyz:", err)
+		return
+	}
+
+	failing := make([]string, 0)
+	total := 0
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") {
+			continue
+		}
+		total++
+		if !strings.HasPrefix(line, "[+]") {
+			failing = append(failing, line)
+		}
+	}
+
+	if len(failing) == 0 {
+		fmt.Printf("    All %d checks passing\n", total)
+		return
+	}
+	for _, line := range failing {
+		fmt.Println("    -", color.HiRedString(line))
+	}
+}
+
+// printRejections prints API Priority and Fairness rejections by priority
+// level, if the API server's metrics are visible to us
+func (o *Options) printRejections(ctx context.Context, k kubernetes.Interface) {
+	bold.Println("🚦  Priority and fairness rejections:")
+
+	body, err := k.Discovery().RESTClient().Get().AbsPath("/metrics").Do(ctx).Raw()
+	if err != nil {
+		fmt.Println("    Unable to read API server metrics:", err)
+		return
+	}
+
+	rejections := parseRejections(body)
+	if len(rejections) == 0 {
+		fmt.Println("    No rejected requests")
+		return
+	}
+
+	levels := make([]string, 0, len(rejections))
+	for level := range rejections {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	for _, level := range levels {
+		fmt.Fprintf(tw, "    - %s:\t%s\n", bold.Sprint(level), color.HiYellowString("%.0f rejected", rejections[level]))
+	}
+	tw.Flush()
+}
+
+// parseRejections sums the rejected requests metric by priority level from
+// a Prometheus text exposition
+func parseRejections(metrics []byte) map[string]float64 {
+	rejections := make(map[string]float64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(metrics))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, rejectedRequestsMetric+"{") {
+			continue
+		}
+
+		labelsEnd := strings.LastIndex(line, "}")
+		if labelsEnd == -1 {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(line[labelsEnd+1:]), 64)
+		if err != nil || value == 0 {
+			continue
+		}
+
+		level := "unknown"
+		for _, label := range strings.Split(line[len(rejectedRequestsMetric)+1:labelsEnd], ",") {
+			if strings.HasPrefix(label, "priority_level=") {
+				level = strings.Trim(strings.TrimPrefix(label, "priority_level="), `"`)
+			}
+		}
+		rejections[level] += value
+	}
+
+	return rejections
+}
@@ -0,0 +1,149 @@
+// Description: This file contains code for annotating problems with the
+// Alertmanager alerts that are firing or silenced for the same resource
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// States an alert related to a problem can be in
+const (
+	// AlertFiring is an alert that is firing and notifying
+	AlertFiring = "firing"
+
+	// AlertSilenced is an alert that is firing but silenced
+	AlertSilenced = "silenced"
+
+	// AlertInhibited is an alert that is firing but inhibited by another
+	// alert
+	AlertInhibited = "inhibited"
+)
+
+// alertResourceLabels are the labels alerts name namespaced resources
+// with, as kube-state-metrics and the kubernetes-mixin alerts do
+var alertResourceLabels = []string{
+	"pod", "deployment", "statefulset", "daemonset", "replicaset", "job_name", "cronjob",
+	"persistentvolumeclaim", "horizontalpodautoscaler", "service",
+}
+
+// Alert is an Alertmanager alert related to a resource with a problem
+type Alert struct {
+	// Name is the alertname of the alert
+	Name string
+
+	// State is one of the Alert state constants
+	State string
+}
+
+// AlertmanagerAlert is an alert as returned by the Alertmanager v2 API
+type AlertmanagerAlert struct {
+	Labels map[string]string `json:"labels"`
+	Status struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+}
+
+// state returns the Alert state constant of the alert
+func (a *AlertmanagerAlert) state() string {
+	switch {
+	case len(a.Status.SilencedBy) != 0:
+		return AlertSilenced
+	case len(a.Status.InhibitedBy) != 0:
+		return AlertInhibited
+	}
+	return AlertFiring
+}
+
+// matches returns true if the alert is about the resource, by its
+// namespace and name for namespaced resources or its node otherwise
+func (a *AlertmanagerAlert) matches(r *Resource) bool {
+	i := strings.Index(r.Name, "/")
+	if i < 0 {
+		return a.Labels["node"] == r.Name
+	}
+
+	namespace, name := r.Name[:i], r.Name[i+1:]
+	if a.Labels["namespace"] != namespace {
+		return false
+	}
+	for _, l := range alertResourceLabels {
+		if a.Labels[l] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchAlerts returns the active alerts in an Alertmanager, including the
+// ones that are silenced or inhibited
+func FetchAlerts(ctx context.Context, url string) ([]AlertmanagerAlert, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+"/api/v2/alerts", http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create alertmanager request")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list alertmanager alerts")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("failed to list alertmanager alerts: got status %s", resp.Status)
+	}
+
+	var alerts []AlertmanagerAlert
+	if err := json.NewDecoder(resp.Body).Decode(&alerts); err != nil {
+		return nil, errors.Wrap(err, "failed to decode alertmanager alerts")
+	}
+	return alerts, nil
+}
+
+// AnnotateAlerts sets the alerts of each resource to the alerts about it,
+// including the resources consolidated into it
+func AnnotateAlerts(resources []Resource, alerts []AlertmanagerAlert) {
+	for i := range resources {
+		r := &resources[i]
+		r.Alerts = nil
+		seen := make(map[Alert]bool)
+		for j := range alerts {
+			a := &alerts[j]
+			if a.Status.State == "unprocessed" || !a.matches(r) {
+				continue
+			}
+			alert := Alert{Name: a.Labels["alertname"], State: a.state()}
+			if !seen[alert] {
+				seen[alert] = true
+				r.Alerts = append(r.Alerts, alert)
+			}
+		}
+		sort.Slice(r.Alerts, func(i, j int) bool { return r.Alerts[i].Name < r.Alerts[j].Name })
+		AnnotateAlerts(r.Related, alerts)
+	}
+}
+
+// annotateAlerts annotates the resources with the alerts of the configured
+// Alertmanager, problems are still reported when it can't be reached
+func (o *Options) annotateAlerts(ctx context.Context, resources []Resource) {
+	if o.cfg.AlertmanagerURL == "" {
+		return
+	}
+
+	alerts, err := FetchAlerts(ctx, o.cfg.AlertmanagerURL)
+	if err != nil {
+		o.log.WithError(err).Warn("failed to fetch alerts from alertmanager")
+		return
+	}
+	AnnotateAlerts(resources, alerts)
+}
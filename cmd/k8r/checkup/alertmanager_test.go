@@ -0,0 +1,54 @@
+package checkup_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+)
+
+func TestAnnotateAlerts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/alerts" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"labels": {"alertname": "KubePodCrashLooping", "namespace": "web", "pod": "api-0"},
+			 "status": {"state": "active", "silencedBy": [], "inhibitedBy": []}},
+			{"labels": {"alertname": "KubePodNotReady", "namespace": "web", "pod": "api-0"},
+			 "status": {"state": "suppressed", "silencedBy": ["4b1d"], "inhibitedBy": []}},
+			{"labels": {"alertname": "KubePodCrashLooping", "namespace": "other", "pod": "api-0"},
+			 "status": {"state": "active"}},
+			{"labels": {"alertname": "KubeNodeNotReady", "node": "node-1"},
+			 "status": {"state": "suppressed", "inhibitedBy": ["9f2c"]}}
+		]`))
+	}))
+	defer srv.Close()
+
+	alerts, err := checkup.FetchAlerts(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := []checkup.Resource{
+		{Name: "web/api-0", Type: "pod", ProblemID: "PodCrashLoopBackOff"},
+		{Name: "node-1", Type: "node", ProblemID: "NodeNotReady"},
+		{Name: "web/api-1", Type: "pod", ProblemID: "PodCrashLoopBackOff"},
+	}
+	checkup.AnnotateAlerts(resources, alerts)
+
+	want := [][]checkup.Alert{
+		{{Name: "KubePodCrashLooping", State: checkup.AlertFiring}, {Name: "KubePodNotReady", State: checkup.AlertSilenced}},
+		{{Name: "KubeNodeNotReady", State: checkup.AlertInhibited}},
+		nil,
+	}
+	for i := range resources {
+		if !reflect.DeepEqual(resources[i].Alerts, want[i]) {
+			t.Errorf("AnnotateAlerts() %s alerts = %+v, expected %+v", resources[i].Name, resources[i].Alerts, want[i])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Description: This file contains code for listing APIServices, which
+// client-go doesn't have a typed client for
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// apiServicesPath is the path APIServices are listed from
+const apiServicesPath = "/apis/apiregistration.k8s.io/v1/apiservices"
+
+// APIService is an API group version served by the API server, either by
+// the API server itself or by an aggregated API server behind a Service,
+// e.g. metrics-server. Only the fields problems are checked against are
+// decoded.
+type APIService struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   APIServiceSpec   `json:"spec"`
+	Status APIServiceStatus `json:"status"`
+}
+
+// APIServiceSpec is the spec of an APIService
+type APIServiceSpec struct {
+	// Service is the Service the API is served by, or nil when the API
+	// server serves it itself
+	Service *APIServiceReference `json:"service,omitempty"`
+
+	// Group is the API group that is served
+	Group string `json:"group,omitempty"`
+
+	// Version is the API version that is served
+	Version string `json:"version,omitempty"`
+}
+
+// APIServiceReference is a reference to the Service serving an API
+type APIServiceReference struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// APIServiceStatus is the status of an APIService
+type APIServiceStatus struct {
+	Conditions []APIServiceCondition `json:"conditions,omitempty"`
+}
+
+// APIServiceCondition is a condition of an APIService, e.g. Available
+type APIServiceCondition struct {
+	Type               string      `json:"type"`
+	Status             string      `json:"status"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string      `json:"reason,omitempty"`
+	Message            string      `json:"message,omitempty"`
+}
+
+// DeepCopyObject implements runtime.Object
+func (a *APIService) DeepCopyObject() runtime.Object {
+	out := *a
+	a.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if a.Spec.Service != nil {
+		svc := *a.Spec.Service
+		out.Spec.Service = &svc
+	}
+	out.Status.Conditions = append([]APIServiceCondition(nil), a.Status.Conditions...)
+	return &out
+}
+
+// Available returns the Available condition of the APIService, if it has
+// one
+func (a *APIService) Available() (*APIServiceCondition, bool) {
+	for i := range a.Status.Conditions {
+		if a.Status.Conditions[i].Type == "Available" {
+			return &a.Status.Conditions[i], true
+		}
+	}
+	return nil, false
+}
+
+// listAPIServices lists the APIServices registered with the API server
+func listAPIServices(ctx context.Context, k kubernetes.Interface) ([]APIService, error) {
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return nil, nil
+	}
+
+	body, err := rc.Get().AbsPath(apiServicesPath).Do(ctx).Raw()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list apiservices")
+	}
+
+	var list struct {
+		Items []APIService `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode apiservices")
+	}
+	return list.Items, nil
+}
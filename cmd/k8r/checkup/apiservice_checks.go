@@ -0,0 +1,38 @@
+// Description: This file contains code for problems related to the APIs
+// served by aggregated API servers
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ProblemAPIServiceUnavailable is a problem with an APIService that isn't
+// available, requests for its API fail, e.g. an unavailable metrics.k8s.io
+// breaks HPAs and kubectl top, and namespace deletion gets stuck
+// https://github.com/Ashvin-Ranjan/k8r/wiki/APIServiceUnavailable
+var ProblemAPIServiceUnavailable = Problem{
+	ID:               "APIServiceUnavailable",
+	ShortDescription: "An aggregated API is unavailable, breaking anything that uses it, e.g. HPAs and kubectl top for metrics.k8s.io",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/APIServiceUnavailable",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		svc, ok := obj.(*APIService)
+		if !ok {
+			return "", false, false
+		}
+
+		c, ok := svc.Available()
+		if !ok || c.Status == "True" {
+			return "", false, false
+		}
+
+		details := fmt.Sprintf("%s/%s is unavailable (%s): %s", svc.Spec.Group, svc.Spec.Version, c.Reason, c.Message)
+		if svc.Spec.Service != nil {
+			details += fmt.Sprintf(", it is served by service %s/%s", svc.Spec.Service.Namespace, svc.Spec.Service.Name)
+		}
+		return details, false, true
+	},
+}
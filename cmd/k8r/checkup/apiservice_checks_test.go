@@ -0,0 +1,26 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestAPIServiceUnavailable(t *testing.T) {
+	local := checkuptest.NewAPIService("apps", "v1")
+	local.Spec.Service = nil
+
+	checkuptest.RunCases(t, checkup.ProblemAPIServiceUnavailable, []checkuptest.Case{
+		{Name: "available", Object: checkuptest.NewAPIService("metrics.k8s.io", "v1beta1")},
+		{Name: "served locally", Object: local},
+		{
+			Name: "unavailable",
+			Object: checkuptest.APIServiceUnavailable(checkuptest.NewAPIService("metrics.k8s.io", "v1beta1"),
+				"FailedDiscoveryCheck", "failing or missing response from https://10.0.0.1:443"),
+			Occurring:      true,
+			DetailsContain: "metrics.k8s.io/v1beta1 is unavailable (FailedDiscoveryCheck): failing or missing response from https://10.0.0.1:443, it is served by service kube-system/metrics.k8s.io",
+		},
+		{Name: "not an APIService", Object: checkuptest.NewNode("node-1")},
+	})
+}
@@ -0,0 +1,192 @@
+// Description: This file contains code for exporting problems to Backstage
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// backstage constants
+const (
+	// defaultBackstageNamespace is the Backstage namespace used for entity
+	// refs when a mapping does not specify one
+	defaultBackstageNamespace = "default"
+
+	// backstageTimeout is how long pushing to the Backstage backend can
+	// take before the checkup gives up on it
+	backstageTimeout = 30 * time.Second
+)
+
+// BackstageMapping maps the value of a resource label to a Backstage entity
+// ref, e.g. the label backstage.io/kubernetes-id=payments mapped to the kind
+// component becomes component:default/payments
+type BackstageMapping struct {
+	// Label is the resource label whose value is the entity name
+	Label string
+
+	// Kind is the Backstage entity kind, e.g. component or group
+	Kind string
+
+	// Namespace is the Backstage namespace of the entity
+	Namespace string
+}
+
+// ParseBackstageMapping parses a mapping in the format label=kind[:namespace]
+func ParseBackstageMapping(s string) (BackstageMapping, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return BackstageMapping{}, fmt.Errorf("invalid backstage mapping %q, expected label=kind[:namespace]", s)
+	}
+
+	m := BackstageMapping{
+		Label:     parts[0],
+		Kind:      parts[1],
+		Namespace: defaultBackstageNamespace,
+	}
+	if entity := strings.SplitN(parts[1], ":", 2); len(entity) == 2 {
+		m.Kind = entity[0]
+		m.Namespace = entity[1]
+	}
+
+	return m, nil
+}
+
+// EntityRef returns the entity ref for a resource, if the resource has the
+// label this mapping is looking for
+func (m *BackstageMapping) EntityRef(r *Resource) (string, bool) {
+	name := r.Labels[m.Label]
+	if name == "" {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s:%s/%s", strings.ToLower(m.Kind), m.Namespace, name), true
+}
+
+// BackstageFinding is a problem attached to a Backstage entity
+type BackstageFinding struct {
+	ProblemID    string `json:"problemId"`
+	Description  string `json:"description"`
+	HelpURL      string `json:"helpUrl,omitempty"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Details      string `json:"details,omitempty"`
+	Severity     string `json:"severity"`
+}
+
+// BackstageEntity is a Backstage entity with all of the findings that
+// were mapped to it
+type BackstageEntity struct {
+	EntityRef string             `json:"entityRef"`
+	Findings  []BackstageFinding `json:"findings"`
+}
+
+// BackstageExport is the document written to disk or pushed to a
+// Backstage backend
+type BackstageExport struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Entities    []BackstageEntity `json:"entities"`
+}
+
+// NewBackstageExport maps the resources in a report to Backstage entities,
+// a resource is attached to every entity that one of the mappings matches
+func NewBackstageExport(report *Report, mappings []BackstageMapping) *BackstageExport {
+	byEntity := make(map[string][]BackstageFinding)
+	for i := range report.Resources {
+		r := &report.Resources[i]
+
+		p := report.GetProblemByID(r.ProblemID)
+		if p == nil {
+			continue
+		}
+
+		severity := r.GetSeverity().String()
+
+		for j := range mappings {
+			ref, ok := mappings[j].EntityRef(r)
+			if !ok {
+				continue
+			}
+
+			byEntity[ref] = append(byEntity[ref], BackstageFinding{
+				ProblemID:    p.ID,
+				Description:  p.ShortDescription,
+				HelpURL:      p.HelpURL,
+				ResourceName: r.Name,
+				ResourceType: r.Type,
+				Details:      r.ProblemDetails,
+				Severity:     severity,
+			})
+		}
+	}
+
+	export := &BackstageExport{
+		GeneratedAt: time.Now().UTC(),
+		Entities:    make([]BackstageEntity, 0, len(byEntity)),
+	}
+	for ref, findings := range byEntity {
+		export.Entities = append(export.Entities, BackstageEntity{EntityRef: ref, Findings: findings})
+	}
+
+	// Keep the output stable so that exported files can be diffed
+	sort.Slice(export.Entities, func(i, j int) bool {
+		return export.Entities[i].EntityRef < export.Entities[j].EntityRef
+	})
+
+	return export
+}
+
+// exportToBackstage writes the report to the configured Backstage file
+// and/or pushes it to the configured Backstage backend
+func (o *Options) exportToBackstage(ctx context.Context, report *Report) error {
+	if o.cfg.BackstageFile == "" && o.cfg.BackstageURL == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(NewBackstageExport(report, o.cfg.BackstageMappings), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal backstage export")
+	}
+
+	if o.cfg.BackstageFile != "" {
+		if err := os.WriteFile(o.cfg.BackstageFile, b, 0o600); err != nil {
+			return errors.Wrap(err, "failed to write backstage export")
+		}
+	}
+
+	if o.cfg.BackstageURL != "" {
+		// A backend that doesn't respond shouldn't hang the whole checkup
+		ctx, cancel := context.WithTimeout(ctx, backstageTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.BackstageURL, bytes.NewReader(b))
+		if err != nil {
+			return errors.Wrap(err, "failed to create backstage request")
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if o.cfg.BackstageToken != "" {
+			req.Header.Set("Authorization", "Bearer "+o.cfg.BackstageToken)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "failed to push to backstage")
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("failed to push to backstage: got status %s", resp.Status)
+		}
+	}
+
+	return nil
+}
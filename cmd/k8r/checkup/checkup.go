@@ -16,18 +16,24 @@ package checkup
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
 	"github.com/fatih/color"
-	"github.com/getoutreach/devenv/pkg/kube"
-
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	v1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // enabledPodProblems is a list of pod problem checkers that are enabled
@@ -38,6 +44,26 @@ var enabledPodProblems = []Problem{
 	ProblemPodOOMKilled,
 	// EDITS: New problems added
 	ProblemHighRestarts,
+	ProblemPodDNSSearchAmplification,
+	ProblemPodDNSNoNameservers,
+	ProblemPodHostAliasConflict,
+	ProblemPodHostPortConflict,
+	ProblemPodHostPortUnschedulable,
+	ProblemPodSecurityViolation,
+	ProblemSecretAsEnvVar,
+	ProblemPodSpotOnly,
+	ProblemMissingRequiredLabels,
+	ProblemPodExtendedResourceUnavailable,
+	ProblemPodMissingOSSelector,
+	ProblemInitContainerStuck,
+	ProblemPodImageNotLoaded,
+	ProblemLocalRegistryUnreachable,
+	ProblemPodReadinessGateUnmet,
+	ProblemPodSidecarNotReady,
+	ProblemPodSidecarBlocksJob,
+	ProblemPodTopologyUnsatisfiable,
+	ProblemPVCAlmostFull,
+	ProblemPodPending,
 }
 
 // EDIT: 2 new lists added
@@ -46,8 +72,221 @@ var enabledHPAProblems = []Problem{
 	ProblemMaxedOutHPAs,
 }
 
+// enabledHPAMetricProblems is a list of HPA problem checkers that are
+// checked against the autoscaling/v2 version of HPAs, which includes
+// the metrics they scale on
+var enabledHPAMetricProblems = []Problem{
+	ProblemHPAMetricUnavailable,
+}
+
+// enabledServiceProblems is a list of Service problem checkers that are enabled
+var enabledServiceProblems = []Problem{
+	ProblemServiceSelectorConflict,
+	ProblemServicePartialWorkload,
+	ProblemServiceNodePortConflict,
+}
+
+// enabledStatefulSetProblems is a list of StatefulSet problem checkers that are enabled
+var enabledStatefulSetProblems = []Problem{
+	ProblemStatefulSetServiceInvalid,
+	ProblemStatefulSetReplicasNotReady,
+	ProblemMissingRequiredLabels,
+}
+
+// enabledDeploymentProblems is a list of Deployment problem checkers that are enabled
+var enabledDeploymentProblems = []Problem{
+	ProblemDeploymentRecreateZeroDowntime,
+	ProblemDeploymentRollingUpdateUnavailable,
+	ProblemDeploymentRolloutStuck,
+	ProblemMissingRequiredLabels,
+}
+
+// enabledDaemonSetProblems is a list of DaemonSet problem checkers that are enabled
+var enabledDaemonSetProblems = []Problem{
+	ProblemDevicePluginUnhealthy,
+	ProblemDaemonSetPodsUnavailable,
+	ProblemDaemonSetStaleOnDelete,
+	ProblemDaemonSetMaxUnavailableHigh,
+	ProblemDaemonSetSurgeHostPort,
+	ProblemMissingRequiredLabels,
+}
+
+// enabledSecretProblems is a list of Secret problem checkers that are enabled
+var enabledSecretProblems = []Problem{
+	ProblemSecretCloudCredentials,
+	ProblemSecretUnused,
+}
+
+// enabledNodeProblems is a list of node problem checkers that are enabled
+var enabledNodeProblems = []Problem{
+	ProblemNodeNotReady,
+	ProblemNodeProblemDetected,
+	ProblemNodeClockSkew,
+	ProblemNodeDiskPressurePredicted,
+	ProblemNodeCertificateExpiring,
+	ProblemNodeGPUNotAllocatable,
+	ProblemWindowsNodeMisconfigured,
+	ProblemLocalNodeMemoryLow,
+}
+
+// enabledPVCProblems is a list of PersistentVolumeClaim problem checkers that are enabled
+var enabledPVCProblems = []Problem{
+	ProblemPVCResizeStuck,
+	ProblemPVCExpansionFailed,
+	ProblemPVCNoDefaultStorageClass,
+	ProblemPVCPending,
+}
+
+// enabledStorageClassProblems is a list of StorageClass problem checkers that are enabled
+var enabledStorageClassProblems = []Problem{
+	ProblemStorageClassMultipleDefaults,
+	ProblemStorageClassProvisionerMissing,
+}
+
+// enabledCSIDriverProblems is a list of CSIDriver problem checkers that are enabled
+var enabledCSIDriverProblems = []Problem{
+	ProblemCSIDriverUnhealthy,
+}
+
+// enabledVolumeAttachmentProblems is a list of VolumeAttachment problem checkers that are enabled
+var enabledVolumeAttachmentProblems = []Problem{
+	ProblemVolumeAttachmentStuck,
+}
+
+// enabledNamespaceProblems is a list of namespace problem checkers that are enabled
+var enabledNamespaceProblems = []Problem{
+	ProblemMissingRequiredLabels,
+}
+
+// enabledRBACProblems is a list of Role and ClusterRole problem checkers that are enabled
+var enabledRBACProblems = []Problem{
+	ProblemRBACWildcardGrant,
+}
+
+// enabledAPIServiceProblems is a list of APIService problem checkers that are enabled
+var enabledAPIServiceProblems = []Problem{
+	ProblemAPIServiceUnavailable,
+}
+
+// enabledFlowSchemaProblems is a list of FlowSchema problem checkers that are enabled
+var enabledFlowSchemaProblems = []Problem{
+	ProblemFlowSchemaMisconfigured,
+}
+
+// enabledPriorityLevelProblems is a list of PriorityLevelConfiguration problem checkers that are enabled
+var enabledPriorityLevelProblems = []Problem{
+	ProblemPriorityLevelStarved,
+}
+
+// enabledRolloutProblems is a list of Argo Rollout problem checkers that are enabled
+var enabledRolloutProblems = []Problem{
+	ProblemRolloutStuck,
+	ProblemCanaryAnalysisFailing,
+}
+
+// enabledOperatorProblems is a list of operator custom resource problem
+// checkers that are enabled
+var enabledOperatorProblems = []Problem{
+	ProblemOperatorResourceUnhealthy,
+}
+
+// enabledPolicyReportProblems is a list of PolicyReport problem checkers
+// that are enabled, one per scanner the reports come from
+var enabledPolicyReportProblems = []Problem{
+	ProblemKyvernoPolicyFailed,
+	ProblemPolarisCheckFailed,
+}
+
+// enabledVulnerabilityReportProblems is a list of Trivy Operator
+// VulnerabilityReport problem checkers that are enabled
+var enabledVulnerabilityReportProblems = []Problem{
+	ProblemTrivyVulnerabilities,
+}
+
+// enabledCanaryProblems is a list of Flagger Canary problem checkers that are enabled
+var enabledCanaryProblems = []Problem{
+	ProblemCanaryAnalysisFailing,
+}
+
+// enabledJobProblems is a list of Job problem checkers that are enabled
+var enabledJobProblems = []Problem{
+	ProblemJobBlockedBySidecar,
+	ProblemJobFailed,
+}
+
+// enabledCronJobProblems is a list of CronJob problem checkers that are enabled
+var enabledCronJobProblems = []Problem{
+	ProblemCronJobStale,
+	ProblemCronJobMissedSchedule,
+	ProblemCronJobRunsFailing,
+	ProblemCronJobScheduleInvalid,
+	ProblemCronJobOverlap,
+	ProblemCronJobMissingTimeZone,
+	ProblemMissingRequiredLabels,
+}
+
+// enabledImageProblems is a list of image problem checkers that are enabled
+var enabledImageProblems = []Problem{
+	ProblemImagePullSlow,
+}
+
+// enabledTaintProblems is a list of node taint problem checkers that are enabled
+var enabledTaintProblems = []Problem{
+	ProblemTaintBlocksPods,
+}
+
+// enabledControlPlaneProblems is a list of control plane problem checkers that are enabled
+var enabledControlPlaneProblems = []Problem{
+	ProblemEtcdUnhealthy,
+	ProblemEtcdDBSizeNearQuota,
+	ProblemEtcdSlowFsync,
+	ProblemDistroAddonUnhealthy,
+	ProblemAPIRequestsQueued,
+}
+
 // enbaledProblems is a list of all problem checkers that are enabled
-var enabledProblems = append(enabledPodProblems, enabledHPAProblems...)
+// EDIT: Include kustomize and Service problems
+var enabledProblems = concatProblems(
+	enabledPodProblems,
+	enabledHPAProblems,
+	enabledHPAMetricProblems,
+	enabledServiceProblems,
+	enabledStatefulSetProblems,
+	enabledDeploymentProblems,
+	enabledDaemonSetProblems,
+	enabledSecretProblems,
+	enabledNodeProblems,
+	enabledPVCProblems,
+	enabledStorageClassProblems,
+	enabledCSIDriverProblems,
+	enabledVolumeAttachmentProblems,
+	enabledNamespaceProblems,
+	enabledRBACProblems,
+	enabledAPIServiceProblems,
+	enabledFlowSchemaProblems,
+	enabledPriorityLevelProblems,
+	enabledRolloutProblems,
+	enabledCanaryProblems,
+	enabledOperatorProblems,
+	enabledPolicyReportProblems,
+	enabledVulnerabilityReportProblems,
+	enabledControlPlaneProblems,
+	enabledJobProblems,
+	enabledCronJobProblems,
+	enabledImageProblems,
+	enabledTaintProblems,
+	enabledKustomizeProblems,
+)
+
+// EDIT: New function
+// concatProblems returns a new list containing all of the given problems
+func concatProblems(lists ...[]Problem) []Problem {
+	problems := make([]Problem, 0)
+	for _, l := range lists {
+		problems = append(problems, l...)
+	}
+	return problems
+}
 
 // contains string helpers
 var (
@@ -62,14 +301,93 @@ var (
 type Options struct {
 	log logrus.FieldLogger
 	cfg *Config
+	out io.Writer
+
+	// EDIT: Machine-readable reports are written separately from progress
+	reportOut io.Writer
+
+	// EDIT: Keep the summary written to the result-file flag
+	result Result
+
+	// EDIT: Keep the problems found for each object to cache them
+	detections *detections
+
+	// EDIT: Keep track of whether the scan was interrupted before every
+	// resource was checked
+	interrupted bool
+
+	// EDIT: Keep track of how long each detector took
+	detectorStats map[string]*detectorStat
+
+	// EDIT: Keep the cluster that was checked to tag exported problems
+	// with, empty for lint
+	cluster string
+
+	// EDIT: Keep the likely root causes when they were worked out before
+	// the cluster was released, see relateProblems
+	related bool
+	causes  []likelyCause
+
+	// EDIT: Keep the client to publish problems with, nil for lint
+	client kubernetes.Interface
 }
 
 // NewOptions contains options for the devenv debug
 // command
 func NewOptions(log logrus.FieldLogger) *Options {
 	return &Options{
-		log: log,
+		log:       log,
+		out:       os.Stdout,
+		reportOut: os.Stdout,
+	}
+}
+
+// EDIT: New function
+// Configure sets the Config problems are checked with and where the
+// report is written to, for running checkup outside of the CLI, e.g. tests
+func (o *Options) Configure(cfg *Config, out io.Writer) {
+	o.cfg = cfg
+	o.out = out
+	o.reportOut = out
+}
+
+// EDIT: New error, printReport used to exit itself
+// ErrProblemsFound is returned when problems were found, the commands exit
+// non-zero when they get it
+var ErrProblemsFound = errors.New("problems found")
+
+// EDIT: New error
+// ErrCriticalProblemsFound is returned when critical problems were found,
+// the commands exit with criticalExitCode when they get it. It is an
+// ErrProblemsFound as well.
+var ErrCriticalProblemsFound = fmt.Errorf("critical %w", ErrProblemsFound)
+
+// EDIT: New constant
+// criticalExitCode is the code the commands exit with when critical
+// problems were found, so that e.g. only they page someone
+const criticalExitCode = 2
+
+// EDIT: New error
+// ErrScanInterrupted is returned when the scan was interrupted, e.g. by
+// Ctrl-C, after reporting the problems found until then
+var ErrScanInterrupted = errors.New("scan interrupted")
+
+// EDIT: New error
+// ErrScanIncomplete is returned when no problems were found but resources
+// couldn't be listed and the fail-on-incomplete flag is set
+var ErrScanIncomplete = errors.New("scan incomplete, some resources couldn't be listed")
+
+// EDIT: New function
+// exitOnProblems exits non-zero if problems were found, otherwise the
+// error is returned as is
+func exitOnProblems(err error) error {
+	if errors.Is(err, ErrCriticalProblemsFound) {
+		os.Exit(criticalExitCode)
 	}
+	if errors.Is(err, ErrProblemsFound) {
+		os.Exit(1)
+	}
+	return err
 }
 
 // NewCommand creates a new devenv debug command
@@ -82,20 +400,527 @@ func NewCommand(log logrus.FieldLogger) *cli.Command {
 		Usage: "Debug Kubernetes clusters",
 		// EDIT: Pass in config
 		Action: func(c *cli.Context) error {
-			o.cfg = &Config{
-				RestartThreshold: c.Int("restart-threshold"),
+			cfg, err := newConfig(c)
+			if err != nil {
+				return err
 			}
-			return o.Run(c.Context)
+			o.cfg = cfg
+			o.useOutput()
+
+			return o.runWithResult(func() error {
+				return o.Run(c.Context)
+			})
 		},
 		// EDIT: Add flags
-		Flags: []cli.Flag{
-			&cli.IntFlag{
-				Name:  "restart-threshold",
-				Usage: "Sets the restart threshold for the HighRestarts problem",
-				Value: 3,
-			},
+		Flags: append(newFlags(), kube.Flags()...),
+	}
+}
+
+// EDIT: New function
+// newFlags returns the flags that configure the problem checkers and
+// how problems are reported, shared by every command that runs them
+func newFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "restart-threshold",
+			Usage: "Sets the restart threshold for the HighRestarts problem",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "pod-security-level",
+			Usage: "Evaluates pods against this Pod Security Standards level instead of the level labeled on their namespace",
+		},
+		&cli.DurationFlag{
+			Name:  "cert-expiry-threshold",
+			Usage: "Sets how long before expiry certificates are reported by the NodeCertificateExpiring problem",
+			Value: 30 * 24 * time.Hour,
+		},
+		&cli.DurationFlag{
+			Name:  "pod-grace-period",
+			Usage: "Skips pods younger than this, as freshly deployed pods are expected to not be ready yet, set to 0 to disable",
+			Value: 2 * time.Minute,
+		},
+		&cli.StringSliceFlag{
+			Name:  "transient-namespaces",
+			Usage: "Namespaces (globs) whose pods are expected to come and go, e.g. CI runners, and are skipped",
+		},
+		&cli.DurationFlag{
+			Name:  "init-container-threshold",
+			Usage: "Sets how long an init container can run or retry before it is reported by the InitContainerStuck problem",
+			Value: 10 * time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "image-pull-threshold",
+			Usage: "Sets how long an image can take to pull before it is reported by the ImagePullSlow problem",
+			Value: 2 * time.Minute,
+		},
+		&cli.IntFlag{
+			Name:  "cronjob-stale-multiple",
+			Usage: "Sets how many of its schedule's intervals a CronJob can go without succeeding before it is reported by the CronJobStale problem",
+			Value: 3,
+		},
+		&cli.BoolFlag{
+			Name:  "require-cronjob-timezone",
+			Usage: "Reports CronJobs that don't set spec.timeZone with the CronJobMissingTimeZone problem",
+		},
+		&cli.DurationFlag{
+			Name:  "clock-skew-threshold",
+			Usage: "How far a node's clock can appear to be from the other nodes' before it is reported, should be over 1m as leases are renewed every 10s and nodes are marked not ready after 40s without a renewal",
+			Value: 2 * time.Minute,
+		},
+		&cli.IntFlag{
+			Name:  "disk-pressure-margin",
+			Usage: "How many percent of a node's disk can be left above kubelet's eviction threshold before it is reported by the NodeDiskPressurePredicted problem",
+			Value: 10,
+		},
+		&cli.IntFlag{
+			Name:  "pvc-usage-threshold",
+			Usage: "Sets how many percent full a PersistentVolumeClaim can be before it is reported by the PVCAlmostFull problem",
+			Value: 85,
+		},
+		&cli.DurationFlag{
+			Name:  "pvc-resize-threshold",
+			Usage: "Sets how long a PersistentVolumeClaim can be resizing before it is reported by the PVCResizeStuck problem",
+			Value: 30 * time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "pvc-pending-threshold",
+			Usage: "Sets how long a PersistentVolumeClaim can be Pending before it is reported by the PVCPending problem",
+			Value: 5 * time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "statefulset-grace-period",
+			Usage: "Sets how long a StatefulSet can have fewer ready replicas than it wants before it is reported by the StatefulSetReplicasNotReady problem",
+			Value: 10 * time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "volume-attachment-threshold",
+			Usage: "Sets how long a volume can be attaching or detaching before it is reported by the VolumeAttachmentStuck problem",
+			Value: 5 * time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "rollout-stuck-threshold",
+			Usage: "Sets how long a Rollout can be degraded or paused before it is reported by the RolloutStuck problem",
+			Value: time.Hour,
+		},
+		&cli.Int64Flag{
+			Name:  "etcd-quota-bytes",
+			Usage: "Sets the etcd quota used by the EtcdDBSizeNearQuota problem when etcd doesn't report its own",
+			Value: 2 << 30,
+		},
+		&cli.BoolFlag{
+			Name:  "probe-kubelet-certs",
+			Usage: "Connects to each kubelet to read its serving certificate, requires network access to the nodes",
+		},
+		&cli.StringSliceFlag{
+			Name:  "node-problem-conditions",
+			Usage: "Extra node condition types that mean a problem when true, e.g. from a custom node-problem-detector plugin, checked by the NodeProblemDetected problem",
+		},
+		&cli.StringSliceFlag{
+			Name:  "zero-downtime-namespaces",
+			Usage: "Namespaces (globs) whose Deployments must roll out without an availability dip, checked by the DeploymentRecreateZeroDowntime problem",
+		},
+		&cli.StringSliceFlag{
+			Name:  "secret-file-mount-namespaces",
+			Usage: "Namespaces (globs) where Secrets must be mounted as files instead of consumed as environment variables",
+		},
+		&cli.StringSliceFlag{
+			Name:  "required-labels",
+			Usage: "Labels that every workload and namespace must have, e.g. reporting_team, can be passed multiple times",
+		},
+		&cli.BoolFlag{
+			Name:  "local",
+			Usage: "Tunes the checks for local clusters, e.g. kind or minikube, skipping ones about running in production",
+		},
+		&cli.StringFlag{
+			Name:  "distro",
+			Usage: "Kubernetes distribution the cluster runs, e.g. eks or kind, detected when not set, generic turns off adjusting checks to it",
+		},
+		&cli.StringFlag{
+			Name:  "profiles-file",
+			Usage: "YAML file of profiles that change the checks and thresholds used for the namespaces mapped to them",
+		},
+		&cli.StringFlag{
+			Name:  "profiles-configmap",
+			Usage: "ConfigMap to read the profiles from instead of profiles-file, as namespace/name, read again on every scan",
+		},
+		&cli.StringFlag{
+			Name:  "profiles-configmap-key",
+			Usage: "Key of the profiles-configmap the profiles are read from",
+			Value: defaultProfilesConfigMapKey,
+		},
+		&cli.StringFlag{
+			Name:  "group-by",
+			Usage: fmt.Sprintf("Groups the problems in the report by one of %s", strings.Join(groupings, ", ")),
+			Value: GroupByProblem,
+		},
+		&cli.StringFlag{
+			Name:  "sort-namespaces-by",
+			Usage: fmt.Sprintf("Sorts namespaces by one of %s when grouping by namespace", strings.Join(namespaceSorts, ", ")),
+			Value: SortByScore,
+		},
+		&cli.BoolFlag{
+			Name:  "show-suppressed",
+			Usage: "Shows problems that are symptoms of another problem, e.g. pods not ready on a node that isn't ready",
+		},
+		&cli.StringSliceFlag{
+			Name: "ignore-owned-by",
+			Usage: "Skips resources whose root owner matches kind=Kind, controller=Kind/name or managed-by=tool, " +
+				"e.g. kind=CronJob, can be passed multiple times",
+		},
+		&cli.StringSliceFlag{
+			Name:  "disable-operator-preset",
+			Usage: "Disables the checks for an operator's resources, e.g. strimzi-kafka, can be passed multiple times",
+		},
+		&cli.StringSliceFlag{
+			Name:  "disable-problem",
+			Usage: "Disables the problem with the given ID, can be passed multiple times",
+		},
+		&cli.StringFlag{
+			Name:  "alertmanager-url",
+			Usage: "Alertmanager URL, problems are annotated with the alerts about the same resource and whether they are firing or silenced",
+		},
+		&cli.StringFlag{
+			Name:  "backstage-file",
+			Usage: "Writes problems mapped to Backstage entities to the given JSON file",
+		},
+		&cli.StringFlag{
+			Name:  "backstage-url",
+			Usage: "Pushes problems mapped to Backstage entities to the given Backstage backend URL",
+		},
+		&cli.StringFlag{
+			Name:    "backstage-token",
+			Usage:   "Bearer token used when pushing to the Backstage backend",
+			EnvVars: []string{"K8R_BACKSTAGE_TOKEN"},
 		},
+		&cli.StringFlag{
+			Name:    "datadog-api-key",
+			Usage:   "Datadog API key, problem counts are sent as k8r.problems gauges and new errors as events when set",
+			EnvVars: []string{"DD_API_KEY"},
+		},
+		&cli.StringFlag{
+			Name:  "datadog-url",
+			Usage: "Datadog API URL of your Datadog site, e.g. https://api.datadoghq.eu",
+			Value: DefaultDatadogURL,
+		},
+		&cli.StringSliceFlag{
+			Name:  "datadog-tags",
+			Usage: "Tags added to everything sent to Datadog, e.g. env:prod, can be passed multiple times",
+		},
+		&cli.StringFlag{
+			Name:  "datadog-state-file",
+			Usage: "File that the errors sent to Datadog as events are recorded in, so that each is only sent once while it lasts",
+			Value: DefaultDatadogStateFile(),
+		},
+		&cli.StringFlag{
+			Name:  "grafana-url",
+			Usage: "Grafana URL, an annotation is posted for each error that is found or resolved since the last run when set",
+		},
+		&cli.StringFlag{
+			Name:    "grafana-token",
+			Usage:   "Grafana service account token used when posting annotations",
+			EnvVars: []string{"GRAFANA_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:  "grafana-dashboard-uid",
+			Usage: "UID of the Grafana dashboard annotations are posted to, they are shown on every dashboard that queries their tags when not set",
+		},
+		&cli.StringSliceFlag{
+			Name:  "grafana-tags",
+			Usage: "Tags added to every Grafana annotation, e.g. env:prod, can be passed multiple times",
+		},
+		&cli.StringFlag{
+			Name:  "grafana-state-file",
+			Usage: "File that the errors annotated in Grafana are recorded in, to tell which were found or resolved since the last run",
+			Value: DefaultGrafanaStateFile(),
+		},
+		&cli.BoolFlag{
+			Name:  "desktop-notify",
+			Usage: "Shows a desktop notification about errors that weren't found by the last run, e.g. when running k8r in a loop during a deploy",
+		},
+		&cli.StringSliceFlag{
+			Name:  "desktop-notify-namespace",
+			Usage: "Only shows desktop notifications about errors in namespaces matching these globs, can be passed more than once",
+		},
+		&cli.StringFlag{
+			Name:  "desktop-notify-command",
+			Usage: "Command that shows desktop notifications, run with the title and message as arguments, defaults to notify-send on Linux and osascript on macOS",
+		},
+		&cli.StringFlag{
+			Name:  "desktop-notify-state-file",
+			Usage: "File that the errors shown in desktop notifications are recorded in, to only notify about new ones",
+			Value: DefaultDesktopNotifyStateFile(),
+		},
+		&cli.StringFlag{
+			Name:  "report-dir",
+			Usage: "Directory the JSON report of every run is kept in gzipped, e.g. for k8r fleet",
+		},
+		&cli.DurationFlag{
+			Name:  "report-retention",
+			Usage: "Deletes the reports in the report-dir flag's directory older than this, set to 0 to keep every report",
+			Value: defaultReportRetention,
+		},
+		&cli.BoolFlag{
+			Name:  "publish-problems",
+			Usage: "Publishes each problem as a Problem custom resource in the namespace of its resource, and deletes them once resolved, needs deploy/problem-crd.yaml applied",
+		},
+		&cli.StringFlag{
+			Name:  "publish-problems-namespace",
+			Usage: "Namespace the Problems about cluster scoped resources, e.g. nodes, are published in",
+			Value: "default",
+		},
+		&cli.StringFlag{
+			Name:    "otlp-endpoint",
+			Usage:   "OTLP/HTTP endpoint, e.g. http://otel-collector:4318, each problem is exported as a log record and problem counts as metrics when set",
+			EnvVars: []string{"OTEL_EXPORTER_OTLP_ENDPOINT"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "otlp-header",
+			Usage:   "Header sent with OTLP exports in the format key=value, e.g. for authentication, can be passed multiple times",
+			EnvVars: []string{"OTEL_EXPORTER_OTLP_HEADERS"},
+		},
+		&cli.BoolFlag{
+			Name:  "cache-detections",
+			Usage: "Reuses the problems found in the past 30 minutes for objects whose resourceVersion didn't change, for checks that only look at the object itself. The whole cluster is still listed.",
+		},
+		&cli.StringFlag{
+			Name:  "detection-cache-file",
+			Usage: "File that the problems found for each object are cached in with the cache-detections flag",
+			Value: defaultDetectionCacheFile(),
+		},
+		&cli.StringFlag{
+			Name:  "shard",
+			Usage: "Only checks the namespaces of one shard, in the format index/count, e.g. 2/5, to split a scan across instances",
+		},
+		&cli.StringFlag{
+			Name:  "min-severity",
+			Usage: "Only reports problems at least this severe, one of info, warning, error or critical",
+		},
+		&cli.StringSliceFlag{
+			Name:  "severity",
+			Usage: "Changes the severity of a problem, in the format problem=severity, e.g. NodeNotReady=critical, can be passed multiple times",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: fmt.Sprintf("Format the report is written in, one of %s, progress goes to stderr for json and yaml", strings.Join(outputs, ", ")),
+			Value: OutputText,
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-incomplete",
+			Usage: "Exits non-zero when resources couldn't be listed, even if no problems were found in the rest",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Shows how long each problem's check took and how many resources it checked",
+		},
+		&cli.BoolFlag{
+			Name:  "low-memory",
+			Usage: "Lists the largest kinds of resources a page at a time, strips fields no check reads and drops objects once checked, for huge clusters",
+		},
+		&cli.StringFlag{
+			Name:  "result-file",
+			Usage: "File a JSON summary of the run is written to, e.g. for CI, whatever the outcome",
+		},
+		&cli.StringFlag{
+			Name:  "history-file",
+			Usage: "File that problem counts are recorded in to warn when they spike and for k8r top problems, set to an empty string to disable",
+			Value: DefaultHistoryFile(),
+		},
+		&cli.StringSliceFlag{
+			Name:  "backstage-mapping",
+			Usage: "Maps a resource label to a Backstage entity kind, in the format label=kind[:namespace]",
+			Value: cli.NewStringSlice("backstage.io/kubernetes-id=component", "reporting_team=group"),
+		},
+	}
+}
+
+// EDIT: New function
+// newConfig creates a Config from the flags returned by newFlags
+func newConfig(c *cli.Context) (*Config, error) {
+	cfg := &Config{
+		RestartThreshold:   c.Int("restart-threshold"),
+		PodSecurityLevel:   c.String("pod-security-level"),
+		AlertmanagerURL:    c.String("alertmanager-url"),
+		BackstageFile:      c.String("backstage-file"),
+		BackstageURL:       c.String("backstage-url"),
+		BackstageToken:     c.String("backstage-token"),
+		DatadogAPIKey:      c.String("datadog-api-key"),
+		DatadogURL:         c.String("datadog-url"),
+		DatadogTags:        c.StringSlice("datadog-tags"),
+		DatadogStateFile:   c.String("datadog-state-file"),
+		GrafanaURL:         c.String("grafana-url"),
+		GrafanaToken:       c.String("grafana-token"),
+		GrafanaDashboard:   c.String("grafana-dashboard-uid"),
+		GrafanaTags:        c.StringSlice("grafana-tags"),
+		GrafanaStateFile:   c.String("grafana-state-file"),
+		DesktopNotify:      c.Bool("desktop-notify"),
+		NotifyNamespaces:   c.StringSlice("desktop-notify-namespace"),
+		NotifyCommand:      c.String("desktop-notify-command"),
+		NotifyStateFile:    c.String("desktop-notify-state-file"),
+		ReportDir:          c.String("report-dir"),
+		ReportRetention:    c.Duration("report-retention"),
+		PublishProblems:    c.Bool("publish-problems"),
+		ProblemsNamespace:  c.String("publish-problems-namespace"),
+		OTLPEndpoint:       c.String("otlp-endpoint"),
+		HistoryFile:        c.String("history-file"),
+		ResultFile:         c.String("result-file"),
+		LowMemory:          c.Bool("low-memory"),
+		Verbose:            c.Bool("verbose"),
+		FailOnIncomplete:   c.Bool("fail-on-incomplete"),
+		CacheDetections:    c.Bool("cache-detections"),
+		DetectionCacheFile: c.String("detection-cache-file"),
+		ShowSuppressed:     c.Bool("show-suppressed"),
+
+		CertExpiryThreshold:       c.Duration("cert-expiry-threshold"),
+		ProbeKubeletCerts:         c.Bool("probe-kubelet-certs"),
+		EtcdQuotaBytes:            c.Int64("etcd-quota-bytes"),
+		RolloutStuckThreshold:     c.Duration("rollout-stuck-threshold"),
+		ClockSkewThreshold:        c.Duration("clock-skew-threshold"),
+		DiskPressureMargin:        c.Int("disk-pressure-margin"),
+		PVCUsageThreshold:         c.Int("pvc-usage-threshold"),
+		PVCResizeThreshold:        c.Duration("pvc-resize-threshold"),
+		PVCPendingThreshold:       c.Duration("pvc-pending-threshold"),
+		StatefulSetGracePeriod:    c.Duration("statefulset-grace-period"),
+		VolumeAttachmentThreshold: c.Duration("volume-attachment-threshold"),
+		InitContainerThreshold:    c.Duration("init-container-threshold"),
+		ImagePullThreshold:        c.Duration("image-pull-threshold"),
+		CronJobStaleMultiple:      c.Int("cronjob-stale-multiple"),
+		RequireCronJobTimeZone:    c.Bool("require-cronjob-timezone"),
+		PodGracePeriod:            c.Duration("pod-grace-period"),
+		TransientNamespaces:       c.StringSlice("transient-namespaces"),
+		SecretFileMountNamespaces: c.StringSlice("secret-file-mount-namespaces"),
+		ZeroDowntimeNamespaces:    c.StringSlice("zero-downtime-namespaces"),
+		NodeProblemConditions:     c.StringSlice("node-problem-conditions"),
+		RequiredLabels:            c.StringSlice("required-labels"),
+		DisabledProblems:          make(map[string]bool),
+		Kube:                      kube.OptionsFromFlags(c),
+	}
+
+	for _, id := range c.StringSlice("disable-problem") {
+		cfg.DisabledProblems[CanonicalProblemID(id)] = true
+	}
+
+	cfg.DisabledOperatorPresets = make(map[string]bool)
+	for _, name := range c.StringSlice("disable-operator-preset") {
+		cfg.DisabledOperatorPresets[name] = true
+	}
+
+	groupBy, err := parseGroupBy(c.String("group-by"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.GroupBy = groupBy
+
+	sortBy, err := parseNamespaceSort(c.String("sort-namespaces-by"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.SortNamespacesBy = sortBy
+
+	distro, err := ParseDistro(c.String("distro"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Distro = distro
+
+	if s := c.String("min-severity"); s != "" {
+		minSeverity, err := ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MinSeverity = &minSeverity
+	}
+
+	cfg.SeverityOverrides = make(map[string]Severity)
+	for _, s := range c.StringSlice("severity") {
+		id, severity, err := ParseSeverityOverride(s)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SeverityOverrides[id] = severity
+	}
+
+	output, err := ParseOutput(c.String("output"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Output = output
+
+	shard, err := ParseShard(c.String("shard"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Shard = shard
+
+	cfg.Local = c.Bool("local")
+	if cfg.Local {
+		for _, p := range localSkippedProblems {
+			cfg.DisabledProblems[p.ID] = true
+		}
+	}
+
+	if path := c.String("profiles-file"); path != "" {
+		profiles, err := ReadProfiles(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Profiles = profiles
+	}
+
+	// The profiles-configmap is read when scanning, once there is a client
+	cfg.ProfilesConfigMap = c.String("profiles-configmap")
+	cfg.ProfilesConfigMapKey = c.String("profiles-configmap-key")
+	if cfg.ProfilesConfigMap != "" {
+		if cfg.Profiles != nil {
+			return nil, errors.New("profiles-file and profiles-configmap can't both be set")
+		}
+		if _, _, err := parseConfigMapRef(cfg.ProfilesConfigMap); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range c.StringSlice("ignore-owned-by") {
+		filter, err := ParseOwnerFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		cfg.IgnoreOwnedBy = append(cfg.IgnoreOwnedBy, filter)
+	}
+
+	for _, m := range c.StringSlice("backstage-mapping") {
+		mapping, err := ParseBackstageMapping(m)
+		if err != nil {
+			return nil, err
+		}
+		cfg.BackstageMappings = append(cfg.BackstageMappings, mapping)
+	}
+
+	headers, err := ParseOTLPHeaders(c.StringSlice("otlp-header"))
+	if err != nil {
+		return nil, err
 	}
+	cfg.OTLPHeaders = headers
+
+	return cfg, nil
+}
+
+// EDIT: New function
+// DefaultConfig returns the Config checkup runs with when no flags are
+// passed, e.g. for tests
+func DefaultConfig() (*Config, error) {
+	var cfg *Config
+	app := &cli.App{
+		Flags: newFlags(),
+		Action: func(c *cli.Context) error {
+			var err error
+			cfg, err = newConfig(c)
+			return err
+		},
+	}
+	if err := app.Run([]string{"checkup"}); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 // EDIT: Add type
@@ -103,6 +928,229 @@ func NewCommand(log logrus.FieldLogger) *cli.Command {
 type Config struct {
 	// RestartThreshold is from the restart-threshold flag
 	RestartThreshold int
+
+	// PodSecurityLevel is from the pod-security-level flag
+	PodSecurityLevel string
+
+	// CertExpiryThreshold is from the cert-expiry-threshold flag
+	CertExpiryThreshold time.Duration
+
+	// ProbeKubeletCerts is from the probe-kubelet-certs flag
+	ProbeKubeletCerts bool
+
+	// EtcdQuotaBytes is from the etcd-quota-bytes flag
+	EtcdQuotaBytes int64
+
+	// RolloutStuckThreshold is from the rollout-stuck-threshold flag
+	RolloutStuckThreshold time.Duration
+
+	// ClockSkewThreshold is from the clock-skew-threshold flag
+	ClockSkewThreshold time.Duration
+
+	// DiskPressureMargin is from the disk-pressure-margin flag
+	DiskPressureMargin int
+
+	// PVCUsageThreshold is from the pvc-usage-threshold flag
+	PVCUsageThreshold int
+
+	// PVCResizeThreshold is from the pvc-resize-threshold flag
+	PVCResizeThreshold time.Duration
+
+	// PVCPendingThreshold is from the pvc-pending-threshold flag
+	PVCPendingThreshold time.Duration
+
+	// StatefulSetGracePeriod is from the statefulset-grace-period flag
+	StatefulSetGracePeriod time.Duration
+
+	// VolumeAttachmentThreshold is from the volume-attachment-threshold flag
+	VolumeAttachmentThreshold time.Duration
+
+	// InitContainerThreshold is from the init-container-threshold flag
+	InitContainerThreshold time.Duration
+
+	// ImagePullThreshold is from the image-pull-threshold flag
+	ImagePullThreshold time.Duration
+
+	// CronJobStaleMultiple is from the cronjob-stale-multiple flag
+	CronJobStaleMultiple int
+
+	// RequireCronJobTimeZone is from the require-cronjob-timezone flag
+	RequireCronJobTimeZone bool
+
+	// PodGracePeriod is from the pod-grace-period flag
+	PodGracePeriod time.Duration
+
+	// TransientNamespaces is from the transient-namespaces flag
+	TransientNamespaces []string
+
+	// SecretFileMountNamespaces is from the secret-file-mount-namespaces flag
+	SecretFileMountNamespaces []string
+
+	// ZeroDowntimeNamespaces is from the zero-downtime-namespaces flag
+	ZeroDowntimeNamespaces []string
+
+	// NodeProblemConditions is from the node-problem-conditions flag
+	NodeProblemConditions []string
+
+	// RequiredLabels is from the required-labels flag
+	RequiredLabels []string
+
+	// ShowSuppressed is from the show-suppressed flag
+	ShowSuppressed bool
+
+	// GroupBy is from the group-by flag
+	GroupBy string
+
+	// SortNamespacesBy is from the sort-namespaces-by flag
+	SortNamespacesBy string
+
+	// DisabledProblems is from the disable-problem flag
+	DisabledProblems map[string]bool
+
+	// Distro is from the distro flag, empty when it should be detected
+	Distro Distro
+
+	// Local is from the local flag
+	Local bool
+
+	// Profiles are read from the profiles-file flag, or from the
+	// profiles-configmap flag on every scan
+	Profiles *Profiles
+
+	// ProfilesConfigMap is from the profiles-configmap flag
+	ProfilesConfigMap string
+
+	// ProfilesConfigMapKey is from the profiles-configmap-key flag
+	ProfilesConfigMapKey string
+
+	// profileConfigs caches the config of each profile, see forNamespace
+	profileConfigs map[string]*Config
+
+	// IgnoreOwnedBy is from the ignore-owned-by flag
+	IgnoreOwnedBy []OwnerFilter
+
+	// DisabledOperatorPresets is from the disable-operator-preset flag
+	DisabledOperatorPresets map[string]bool
+
+	// AlertmanagerURL is from the alertmanager-url flag
+	AlertmanagerURL string
+
+	// BackstageFile is from the backstage-file flag
+	BackstageFile string
+
+	// BackstageURL is from the backstage-url flag
+	BackstageURL string
+
+	// BackstageToken is from the backstage-token flag
+	BackstageToken string
+
+	// BackstageMappings is from the backstage-mapping flag
+	BackstageMappings []BackstageMapping
+
+	// DatadogAPIKey is from the datadog-api-key flag
+	DatadogAPIKey string
+
+	// DatadogURL is from the datadog-url flag
+	DatadogURL string
+
+	// DatadogTags is from the datadog-tags flag
+	DatadogTags []string
+
+	// DatadogStateFile is from the datadog-state-file flag
+	DatadogStateFile string
+
+	// GrafanaURL is from the grafana-url flag
+	GrafanaURL string
+
+	// GrafanaToken is from the grafana-token flag
+	GrafanaToken string
+
+	// GrafanaDashboard is from the grafana-dashboard-uid flag
+	GrafanaDashboard string
+
+	// GrafanaTags is from the grafana-tags flag
+	GrafanaTags []string
+
+	// GrafanaStateFile is from the grafana-state-file flag
+	GrafanaStateFile string
+
+	// DesktopNotify is from the desktop-notify flag
+	DesktopNotify bool
+
+	// NotifyNamespaces is from the desktop-notify-namespace flag
+	NotifyNamespaces []string
+
+	// NotifyCommand is from the desktop-notify-command flag
+	NotifyCommand string
+
+	// NotifyStateFile is from the desktop-notify-state-file flag
+	NotifyStateFile string
+
+	// ReportDir is from the report-dir flag
+	ReportDir string
+
+	// ReportRetention is from the report-retention flag
+	ReportRetention time.Duration
+
+	// PublishProblems is from the publish-problems flag
+	PublishProblems bool
+
+	// ProblemsNamespace is from the publish-problems-namespace flag
+	ProblemsNamespace string
+
+	// OTLPEndpoint is from the otlp-endpoint flag
+	OTLPEndpoint string
+
+	// OTLPHeaders is from the otlp-header flag
+	OTLPHeaders map[string]string
+
+	// HistoryFile is from the history-file flag
+	HistoryFile string
+
+	// ResultFile is from the result-file flag
+	ResultFile string
+
+	// LowMemory is from the low-memory flag. It lowers the cost of each
+	// object, and drops every object once the checks have run. They are
+	// still all held until then because problems compare objects against
+	// each other.
+	LowMemory bool
+
+	// Verbose is from the verbose flag
+	Verbose bool
+
+	// Output is from the output flag
+	Output string
+
+	// FailOnIncomplete is from the fail-on-incomplete flag
+	FailOnIncomplete bool
+
+	// MinSeverity is from the min-severity flag, nil reports every
+	// severity
+	MinSeverity *Severity
+
+	// SeverityOverrides is from the severity flag
+	SeverityOverrides map[string]Severity
+
+	// CacheDetections is from the cache-detections flag
+	CacheDetections bool
+
+	// DetectionCacheFile is from the detection-cache-file flag
+	DetectionCacheFile string
+
+	// Shard is from the shard flag, nil when the whole cluster is checked
+	Shard *Shard
+
+	// Kube is from the kubeconfig and context flags
+	Kube kube.Options
+
+	// DeniedPermissions are the permissions the RBAC preflight found
+	// missing, keyed by Permission.String()
+	DeniedPermissions map[string]bool
+
+	// Cluster contains all of the resources being checked, it is
+	// filled in before any problems are checked
+	Cluster *Cluster
 }
 
 // ResourceProblem is a problem with a resource, e.g. a pod
@@ -133,21 +1181,103 @@ type ResourceProblem struct {
 }
 
 // getPodsWithProblems creates a list of problems i/r/t pods
-func (o *Options) getPodsWithProblems(ctx context.Context, pod *corev1.Pod) ([]Resource, bool) {
+// EDIT: Take in the problems to check so lint can check extra problems
+func (o *Options) getPodsWithProblems(ctx context.Context, pod *corev1.Pod, podProblems []Problem) ([]Resource, bool) {
+	return o.getResourcesWithProblems(ctx, pod, "pod", podProblems)
+}
+
+// EDIT: New function
+// getHPAsWithProblems creates a list of problem HPAs
+func (o *Options) getHPAsWithProblems(ctx context.Context, hpa *v1.HorizontalPodAutoscaler) ([]Resource, bool) {
+	return o.getResourcesWithProblems(ctx, hpa, "HPA", enabledHPAProblems)
+}
+
+// EDIT: New function, generalized from getPodsWithProblems
+// getResourcesWithProblems creates a list of problems i/r/t any resource
+func (o *Options) getResourcesWithProblems(ctx context.Context, obj runtime.Object, resourceType string,
+	resourceProblems []Problem) ([]Resource, bool) {
 	problems := make([]Resource, 0)
 
-	// defaultProblem is a problem that for the pod with prefilled
-	// information, use this when you create a problem for a pod
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return problems, false
+	}
+
+	if o.cfg.ignoredByOwner(accessor, resourceType) {
+		return problems, false
+	}
+
+	// defaultProblem is a problem that for the resource with prefilled
+	// information, use this when you create a problem for a resource
 	defaultProblem := Resource{
-		Owner: pod.Labels["reporting_team"],
-		Name:  fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
-		Type:  "pod",
+		Owner:  accessor.GetLabels()[ownerLabel],
+		Name:   fmt.Sprintf("%s/%s", accessor.GetNamespace(), accessor.GetName()),
+		Type:   resourceType,
+		Labels: accessor.GetLabels(),
+	}
+
+	if created := accessor.GetCreationTimestamp(); !created.IsZero() {
+		defaultProblem.CreatedAt = &created.Time
+	}
+	if pod, ok := obj.(*corev1.Pod); ok {
+		if restarted, ok := lastRestart(pod); ok {
+			defaultProblem.LastRestartAt = &restarted
+		}
+		defaultProblem.Node = pod.Spec.NodeName
+		defaultProblem.Images = podImages(pod)
+	}
+
+	// Cluster scoped resources, e.g. nodes, don't have a namespace
+	if accessor.GetNamespace() == "" {
+		defaultProblem.Name = accessor.GetName()
+	}
+
+	// Fall back to the team that owns the namespace
+	if defaultProblem.Owner == "" && o.cfg.Cluster != nil {
+		if ns := o.cfg.Cluster.GetNamespace(accessor.GetNamespace()); ns != nil {
+			defaultProblem.Owner = ns.Labels[ownerLabel]
+		}
 	}
 
-	// check if the pod has a problem from the enabled problems
-	for _, problem := range enabledPodProblems {
+	// EDIT: Check with the profile the namespace is mapped to, if any
+	namespace := accessor.GetNamespace()
+	if ns, ok := obj.(*corev1.Namespace); ok {
+		namespace = ns.Name
+	}
+	cfg := o.cfg.forNamespace(namespace)
+
+	// EDIT: Reuse the problems cached from the last run for objects that
+	// haven't changed since, for problems that only look at the object
+	// itself
+	cached, unchanged := o.detections.lookup(resourceType, accessor)
+	defer func() { o.detections.record(resourceType, accessor, problems) }()
+
+	// check if the resource has a problem from the enabled problems
+	for _, problem := range resourceProblems {
+		if cfg.DisabledProblems[problem.ID] {
+			continue
+		}
+
+		if unchanged && cachedProblems[problem.ID] {
+			for _, c := range cached {
+				if c.ID != problem.ID {
+					continue
+				}
+				p := defaultProblem
+				p.ProblemID = problem.ID
+				p.ProblemDetails = c.Details
+				p.Severity = cfg.problemSeverity(&problem, c.Warning)
+				p.Warning = !p.Severity.AtLeast(SeverityError)
+				problems = append(problems, p)
+			}
+			continue
+		}
+
 		// Pass in Config
-		resourceDetails, warning, occurring := problem.Detector(ctx, pod, o.cfg)
+		// EDIT: Measure how long the detector took
+		start := time.Now()
+		resourceDetails, warning, occurring := problem.Detector(ctx, obj, cfg)
+		o.recordDetector(problem.ID, time.Since(start))
 		if !occurring {
 			continue
 		}
@@ -155,114 +1285,504 @@ func (o *Options) getPodsWithProblems(ctx context.Context, pod *corev1.Pod) ([]R
 		p := defaultProblem
 		p.ProblemID = problem.ID
 		p.ProblemDetails = resourceDetails
-		p.Warning = warning
+		// EDIT: Problems can be more or less severe than errors
+		p.Severity = cfg.problemSeverity(&problem, warning)
+		p.Warning = !p.Severity.AtLeast(SeverityError)
 		problems = append(problems, p)
 	}
 
 	return problems, len(problems) > 0
 }
 
+// Run runs the devenv debug command
+func (o *Options) Run(ctx context.Context) error {
+	// EDIT: Use the kubeconfig and context flags instead of the devenv
+	k, err := o.cfg.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	return kube.ExplainError(o.RunWithClient(ctx, k))
+}
+
+// EDIT: New function, split out of Run so that the client can be injected
+// RunWithClient checks the cluster the given client talks to for problems
+// and reports them
+func (o *Options) RunWithClient(ctx context.Context, k kubernetes.Interface) error {
+	resourceProblems, err := o.Scan(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	// EDIT: Warn when the number of problems spiked since recent runs,
+	// shards find different problems so each has its own history.
+	// Interrupted scans would skew the baseline.
+	cluster := clusterHost(k)
+	o.cluster = cluster
+	o.client = k
+	if o.cfg.Shard != nil {
+		cluster += " shard " + o.cfg.Shard.String()
+	}
+	if !o.interrupted {
+		o.checkHistory(cluster, resourceProblems)
+	}
+
+	// EDIT: Reporting moved into printReport so it can be shared with lint
+	return o.printReport(ctx, resourceProblems)
+}
+
+// EDIT: New function, split out of Run
+// Scan lists the cluster the given client talks to and checks it for problems
+func (o *Options) Scan(ctx context.Context, k kubernetes.Interface) ([]Resource, error) {
+	o.interrupted = false
+	o.related = false
+	o.resetDetectorStats()
+
+	// EDIT: Profiles kept in a ConfigMap can change between scans
+	if err := o.loadProfilesConfigMap(ctx, k); err != nil {
+		return nil, err
+	}
+
+	// EDIT: Skip checks that need permissions the user doesn't have
+	o.preflight(ctx, k)
+
+	// EDIT: Listing moved into ListCluster, keep track of the resources
+	// for problems that need them
+	var err error
+	o.cfg.Cluster, err = ListCluster(ctx, k, o.cfg)
+	listInterrupted := errors.Is(err, ErrScanInterrupted)
+	if err != nil && !listInterrupted {
+		return nil, err
+	}
+
+	// EDIT: Check what was listed before the scan was interrupted, which
+	// is quick compared to listing
+	checkCtx := ctx
+	if listInterrupted {
+		checkCtx = context.Background()
+		fmt.Fprintf(o.out, "Interrupted while listing the cluster, only checking the resources that were listed: %s\n",
+			strings.Join(o.cfg.Cluster.Listed, ", "))
+	}
+
+	// EDIT: Adjust the checks to the distribution the cluster runs
+	o.applyDistro(o.cfg.Cluster.Distro)
+
+	// EDIT: Note that only part of the cluster is checked
+	if o.cfg.Shard != nil {
+		fmt.Fprintf(o.out, "Checking shard %s of the cluster's namespaces\n", o.cfg.Shard)
+	}
+
+	// EDIT: Only check objects that changed since the problems found for
+	// them were cached
+	o.loadDetectionCache(clusterHost(k))
+
+	bold.Fprintf(o.out, "Checking for problems ... ")
+	resourceProblems := o.checkCluster(checkCtx, o.cfg.Cluster)
+	bold.Fprintln(o.out, "done")
+
+	// EDIT: Report what was found so far when interrupted, e.g. by Ctrl-C
+	o.interrupted = listInterrupted || ctx.Err() != nil
+
+	o.saveDetectionCache(clusterHost(k))
+
+	// EDIT: Only keep the problems that were found, relating them first
+	// as that needs the objects they were found on
+	if o.cfg.LowMemory {
+		o.relateProblems(resourceProblems)
+		o.cfg.Cluster.release()
+	}
+
+	return resourceProblems, nil
+}
+
 // EDIT: New function
-// getHPAsWithProblems creates a list of problem HPAs
-func (o *Options) getHPAsWithProblems(ctx context.Context, hpa *v1.HorizontalPodAutoscaler) ([]Resource, bool) {
-	problems := make([]Resource, 0)
+// discoveryClient returns the REST client used for requests that aren't
+// for a resource, e.g. /metrics. Fake clientsets used in tests don't
+// have one.
+func discoveryClient(k kubernetes.Interface) (rest.Interface, bool) {
+	rc := k.Discovery().RESTClient()
+	if c, ok := rc.(*rest.RESTClient); rc == nil || (ok && c == nil) {
+		return nil, false
+	}
+	return rc, true
+}
 
-	// defaultProblem is a problem that for the pod with prefilled
-	// information, use this when you create a problem for a pod
-	defaultProblem := Resource{
-		Owner: hpa.Labels["reporting_team"],
-		Name:  fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Name),
-		Type:  "HPA",
+// EDIT: New function
+// clusterHost returns the host of the API server the client talks to,
+// used to tell clusters apart
+func clusterHost(k kubernetes.Interface) string {
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return ""
 	}
+	return rc.Get().URL().Host
+}
 
-	// check if the pod has a problem from the enabled problems
-	for _, problem := range enabledHPAProblems {
-		resourceDetails, warning, occurring := problem.Detector(ctx, hpa, o.cfg)
-		if !occurring {
-			continue
+// EDIT: New function, split out of Run
+// checkCluster checks every resource in the cluster for problems
+func (o *Options) checkCluster(ctx context.Context, c *Cluster) []Resource {
+	resourceProblems := []Resource{}
+	check := func(obj runtime.Object, resourceType string, problems []Problem) {
+		// EDIT: Stop checking once the scan was interrupted
+		if ctx.Err() != nil {
+			return
 		}
+		// EDIT: Other instances check resources outside of the shard
+		if !o.cfg.Shard.ownsObject(obj) {
+			return
+		}
+		if rs, is := o.getResourcesWithProblems(ctx, obj, resourceType, problems); is {
+			resourceProblems = append(resourceProblems, rs...)
+		}
+	}
 
-		p := defaultProblem
-		p.ProblemID = problem.ID
-		p.ProblemDetails = resourceDetails
-		p.Warning = warning
-		problems = append(problems, p)
+	transient := 0
+	for i := range c.Pods {
+		if reason, ok := o.cfg.transientPod(&c.Pods[i]); ok {
+			o.log.WithField("pod", c.Pods[i].Namespace+"/"+c.Pods[i].Name).Debugf("Skipping pod %s", reason)
+			transient++
+			continue
+		}
+		check(&c.Pods[i], "pod", enabledPodProblems)
+	}
+	if transient != 0 {
+		o.log.Infof("Skipped %d pods in expected transient states", transient)
+	}
+	for i := range c.HPAs {
+		check(&c.HPAs[i], "HPA", enabledHPAProblems)
+	}
+	for i := range c.HPAsV2 {
+		check(&c.HPAsV2[i], "HPA", enabledHPAMetricProblems)
+	}
+	for i := range c.Services {
+		check(&c.Services[i], "service", enabledServiceProblems)
+	}
+	for i := range c.StatefulSets {
+		check(&c.StatefulSets[i], "StatefulSet", enabledStatefulSetProblems)
+	}
+	for i := range c.Deployments {
+		check(&c.Deployments[i], "Deployment", enabledDeploymentProblems)
+	}
+	for i := range c.DaemonSets {
+		check(&c.DaemonSets[i], "DaemonSet", enabledDaemonSetProblems)
+	}
+	for i := range c.Secrets {
+		check(&c.Secrets[i], "secret", enabledSecretProblems)
+	}
+	for i := range c.Jobs {
+		check(&c.Jobs[i], "Job", enabledJobProblems)
+	}
+	for i := range c.CronJobs {
+		check(&c.CronJobs[i], "CronJob", enabledCronJobProblems)
+	}
+	for i := range c.Nodes {
+		check(&c.Nodes[i], "node", enabledNodeProblems)
+	}
+	for i := range c.PersistentVolumeClaims {
+		check(&c.PersistentVolumeClaims[i], "PVC", enabledPVCProblems)
+	}
+	for i := range c.StorageClasses {
+		check(&c.StorageClasses[i], "StorageClass", enabledStorageClassProblems)
+	}
+	for i := range c.CSIDrivers {
+		check(&c.CSIDrivers[i], "CSIDriver", enabledCSIDriverProblems)
+	}
+	for i := range c.VolumeAttachments {
+		check(&c.VolumeAttachments[i], "VolumeAttachment", enabledVolumeAttachmentProblems)
+	}
+	for i := range c.Namespaces {
+		check(&c.Namespaces[i], "namespace", enabledNamespaceProblems)
+	}
+	for i := range c.Roles {
+		check(&c.Roles[i], "Role", enabledRBACProblems)
+	}
+	for i := range c.ClusterRoles {
+		check(&c.ClusterRoles[i], "ClusterRole", enabledRBACProblems)
+	}
+	for i := range c.APIServices {
+		check(&c.APIServices[i], "APIService", enabledAPIServiceProblems)
+	}
+	for i := range c.FlowSchemas {
+		check(&c.FlowSchemas[i], "FlowSchema", enabledFlowSchemaProblems)
+	}
+	for i := range c.PriorityLevels {
+		check(&c.PriorityLevels[i], "PriorityLevelConfiguration", enabledPriorityLevelProblems)
+	}
+	for i := range c.Rollouts {
+		check(&c.Rollouts[i], "Rollout", enabledRolloutProblems)
+	}
+	for i := range c.Canaries {
+		check(&c.Canaries[i], "Canary", enabledCanaryProblems)
+	}
+	for i := range c.OperatorResources {
+		check(&c.OperatorResources[i], c.OperatorResources[i].GetKind(), enabledOperatorProblems)
+	}
+	for i := range c.PolicyReports {
+		check(&c.PolicyReports[i], "PolicyReport", enabledPolicyReportProblems)
+	}
+	for i := range c.ClusterPolicyReports {
+		check(&c.ClusterPolicyReports[i], "ClusterPolicyReport", enabledPolicyReportProblems)
+	}
+	for i := range c.VulnerabilityReports {
+		check(&c.VulnerabilityReports[i], "VulnerabilityReport", enabledVulnerabilityReportProblems)
+	}
+	if c.ControlPlane != nil {
+		check(c.ControlPlane, "control plane", enabledControlPlaneProblems)
+	}
+	for i := range c.ImagePulls {
+		check(&c.ImagePulls[i], "image", enabledImageProblems)
+	}
+	for i := range c.Taints {
+		check(&c.Taints[i], "taint", enabledTaintProblems)
 	}
 
-	return problems, len(problems) > 0
+	return resourceProblems
 }
 
-// Run runs the devenv debug command
-func (o *Options) Run(ctx context.Context) error { //nolint:funlen // Why: Best we can get currently
-	//nolint:errcheck // Why: We handle errors
-	k, err := kube.GetKubeClient()
-	if err != nil {
-		return errors.Wrap(err, "failed to get kubernetes client (is the devenv running?)")
+// EDIT: New function
+// checkHistory records the problems found against a cluster and prints a
+// banner if there are significantly more than in recent runs. History is
+// best effort, failing to read or write it doesn't fail the run.
+func (o *Options) checkHistory(cluster string, resourceProblems []Resource) {
+	if o.cfg.HistoryFile == "" {
+		return
 	}
 
-	pods, err := k.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	h, err := LoadHistory(o.cfg.HistoryFile)
 	if err != nil {
-		return errors.Wrap(err, "failed to list pods")
+		o.log.WithError(err).Warn("failed to load history")
+		return
 	}
 
-	// EDIT: Get HPAs
-	HPAs, err := k.AutoscalingV1().HorizontalPodAutoscalers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return errors.Wrap(err, "failed to list hpas")
+	entry := HistoryEntry{
+		Time:       time.Now().UTC(),
+		Problems:   len(resourceProblems),
+		ProblemIDs: make(map[string]int),
+		Owners:     make(map[string]int),
+	}
+	for i := range resourceProblems {
+		r := &resourceProblems[i]
+		if !r.Warning {
+			entry.Errors++
+		}
+		entry.ProblemIDs[r.ProblemID]++
+		if r.Owner != "" {
+			entry.Owners[r.Owner]++
+		}
 	}
 
-	bold.Printf("Checking for problems ... ")
-	resourceProblems := []Resource{}
+	if baseline, ok := h.Baseline(cluster); ok && IsSpike(baseline, entry.Problems) {
+		fmt.Fprintln(o.out, "")
+		fmt.Fprintln(o.out, color.New(color.Bold, color.BgRed).Sprintf(
+			" 🚨 Problems jumped from ~%d to %d since recent runs, a bad deploy or infra event may have just happened ",
+			baseline, entry.Problems,
+		))
+	}
 
-	for i := range pods.Items {
-		p := &pods.Items[i]
-		if rs, is := o.getPodsWithProblems(ctx, p); is {
-			resourceProblems = append(resourceProblems, rs...)
+	h.Add(cluster, entry)
+	if err := h.Save(o.cfg.HistoryFile); err != nil {
+		o.log.WithError(err).Warn("failed to save history")
+	}
+}
+
+// EDIT: New function, split out of Run
+// printReport prints the problems that were found and exits non-zero
+// if there were any
+func (o *Options) printReport(ctx context.Context, resourceProblems []Resource) error { //nolint:funlen // Why: Best we can get currently
+	// EDIT: Show how long each detector took last
+	defer o.printDetectorMetrics()
+
+	// EDIT: Only report root causes unless asked otherwise
+	if !o.related {
+		o.relateProblems(resourceProblems)
+	}
+	causes := o.causes
+	suppressed := 0
+	if !o.cfg.ShowSuppressed {
+		resourceProblems, suppressed = rootCauses(resourceProblems)
+	}
+	resourceProblems = consolidateContainerProblems(resourceProblems)
+
+	// EDIT: Leave out problems below the min-severity flag
+	resourceProblems, below := o.cfg.filterBySeverity(resourceProblems)
+	o.recordCounts(resourceProblems, suppressed)
+
+	// EDIT: Keep the output stable, resources are listed in no particular order
+	sort.SliceStable(resourceProblems, func(i, j int) bool {
+		return resourceProblems[i].Name < resourceProblems[j].Name
+	})
+
+	// EDIT: Show which problems on-call is already alerted about
+	o.annotateAlerts(ctx, resourceProblems)
+
+	report := ReportFromResources(resourceProblems)
+
+	// EDIT: Write the report in a machine-readable format instead of
+	// printing it
+	if o.cfg.Output == OutputJSON || o.cfg.Output == OutputYAML {
+		if !o.interrupted {
+			if err := o.exportToBackstage(ctx, &report); err != nil {
+				return err
+			}
+			if err := o.exportToDatadog(ctx, &report, o.cluster); err != nil {
+				return err
+			}
+			if err := o.exportToGrafana(ctx, &report, o.cluster); err != nil {
+				return err
+			}
+			if err := o.notifyDesktop(ctx, &report, o.cluster); err != nil {
+				return err
+			}
+			if err := o.exportToOTLP(ctx, &report, o.cluster); err != nil {
+				return err
+			}
+			if err := o.storeReport(&report, suppressed); err != nil {
+				return err
+			}
+			if err := o.publishProblems(ctx, &report); err != nil {
+				return err
+			}
+		}
+		if err := o.writeMachineReport(&report, suppressed); err != nil {
+			return err
 		}
+		if o.interrupted {
+			return ErrScanInterrupted
+		}
+		return o.incompleteError(problemsFoundError(resourceProblems))
 	}
 
-	// EDIT: Check HPAs
-	for i := range HPAs.Items {
-		h := &HPAs.Items[i]
-		if rs, is := o.getHPAsWithProblems(ctx, h); is {
-			resourceProblems = append(resourceProblems, rs...)
+	// EDIT: Partial reports are only printed, exporting them would hide
+	// problems in resources that weren't checked
+	if o.interrupted {
+		fmt.Fprintln(o.out)
+		fmt.Fprintln(o.out, color.New(color.Bold, color.BgYellow).Sprint(
+			" ⚠️  Scan interrupted, only problems found in the resources checked until then are reported ",
+		))
+		fmt.Fprintln(o.out)
+	}
+
+	// EDIT: Call out resources that weren't checked before the problems
+	o.printIncomplete()
+
+	// EDIT: Export to Backstage, even when no problems were found
+	if !o.interrupted {
+		if err := o.exportToBackstage(ctx, &report); err != nil {
+			return err
+		}
+		if err := o.exportToDatadog(ctx, &report, o.cluster); err != nil {
+			return err
+		}
+		if err := o.exportToGrafana(ctx, &report, o.cluster); err != nil {
+			return err
+		}
+		if err := o.notifyDesktop(ctx, &report, o.cluster); err != nil {
+			return err
+		}
+		if err := o.exportToOTLP(ctx, &report, o.cluster); err != nil {
+			return err
+		}
+		if err := o.storeReport(&report, suppressed); err != nil {
+			return err
+		}
+		if err := o.publishProblems(ctx, &report); err != nil {
+			return err
 		}
 	}
 
-	bold.Println("done")
-	if len(resourceProblems) == 0 {
-		fmt.Println("Everything looks good 🎉")
+	if len(resourceProblems) == 0 && o.interrupted {
+		fmt.Fprintln(o.out, "No problems found before the scan was interrupted")
+		return ErrScanInterrupted
+	} else if len(resourceProblems) == 0 && below > 0 {
+		fmt.Fprintf(o.out, "No problems at least as severe as %s, %d less severe problems were left out 🎉\n", *o.cfg.MinSeverity, below)
+		return nil
+	} else if len(resourceProblems) == 0 && len(o.incomplete()) != 0 {
+		fmt.Fprintln(o.out)
+		fmt.Fprintln(o.out, "No problems found in the resources that were listed")
+		return o.incompleteError(nil)
+	} else if len(resourceProblems) == 0 {
+		fmt.Fprintln(o.out, "Everything looks good 🎉")
 		return nil
 	}
 
-	fmt.Println("")
-	bold.Println("⛔️  Problems found (format: namespace/name <problem>):")
+	// EDIT: Lead with the likely root causes
+	o.printLikelyCauses(causes)
+
+	// EDIT: Problems can be grouped by node or namespace as well
+	switch o.cfg.GroupBy {
+	case GroupByNode:
+		o.printByNode(&report)
+	case GroupByNamespace:
+		o.printByNamespace(&report)
+	default:
+		o.printByProblem(&report)
+	}
+
+	fmt.Fprintln(o.out)
+	bold.Fprintln(o.out, "💡  More information/help:")
+	tw := tabwriter.NewWriter(o.out, 1, 0, 1, ' ', 0)
+	for _, id := range sortedProblemIDs(report.ByProblem()) {
+		p := report.GetProblemByID(id)
+		if p == nil {
+			continue
+		}
+
+		helpURL := p.HelpURL
+		if helpURL == "" {
+			helpURL = "https://github.com/getoutreach/devenv/wiki/" + id
+		}
+		fmt.Fprintln(tw, "    -", bold.Sprint(id)+":\t", underline.Sprintf(helpURL))
+	}
+	tw.Flush()
+
+	// EDIT: Let the user know that symptoms were hidden
+	if suppressed > 0 {
+		fmt.Fprintln(o.out)
+		fmt.Fprintf(o.out, "%d problems caused by the problems above were hidden, use --show-suppressed to see them\n", suppressed)
+	}
+	if below > 0 {
+		fmt.Fprintln(o.out)
+		fmt.Fprintf(o.out, "%d problems less severe than %s were left out\n", below, *o.cfg.MinSeverity)
+	}
+
+	if o.interrupted {
+		return ErrScanInterrupted
+	}
+	return problemsFoundError(resourceProblems)
+}
+
+// EDIT: Split out of printReport
+// printByProblem prints the problems in a report grouped by problem, errors
+// first
+func (o *Options) printByProblem(report *Report) {
+	fmt.Fprintln(o.out, "")
+	bold.Fprintln(o.out, "⛔️  Problems found (format: namespace/name <problem>):")
 
-	report := ReportFromResources(resourceProblems)
-	byProblem := report.ByProblem()
 	bySeverity := report.BySeverity()
 
-	for severity, problems := range bySeverity {
-		for id, resources := range problems {
+	// EDIT: Print the most severe problems first, and problems in a stable
+	// order
+	for i := len(severities) - 1; i >= 0; i-- {
+		severity := severities[i]
+		problems := bySeverity[severity]
+		for _, id := range sortedProblemIDs(problems) {
+			resources := problems[id]
 			p := report.GetProblemByID(id)
 			if p == nil {
 				continue
 			}
 
-			fmt.Println("")
+			fmt.Fprintln(o.out, "")
 			plural := ""
 			if len(resources) > 1 {
 				plural = "s"
 			}
 
 			// Get a color based on the severity
-			var colorFn func(string, ...interface{}) string = color.HiRedString
-			if severity == SeverityWarning {
-				colorFn = color.HiYellowString
-			}
+			colorFn := severity.colorFn()
 
 			// Print the problem
-			fmt.Printf("    %s %s\n",
+			fmt.Fprintf(o.out, "    %s %s\n",
 				colorFn("%s: %s", id, p.ShortDescription),
 				bold.Sprintf("[%d occurrence%s]",
 					len(resources),
@@ -271,41 +1791,42 @@ func (o *Options) Run(ctx context.Context) error { //nolint:funlen // Why: Best
 			)
 
 			// Use a tabwriter so that the output is aligned
-			tw := tabwriter.NewWriter(os.Stdout, 1, 0, 1, ' ', 0)
+			tw := tabwriter.NewWriter(o.out, 1, 0, 1, ' ', 0)
 			for _, r := range resources {
 				resourceMessage := bold.Sprint(r.Name)
-				if r.ProblemDetails != "" {
-					resourceMessage += ":\t" + r.ProblemDetails
+				// EDIT: Show where the resource came from, e.g. a manifest file
+				if r.Source != "" {
+					resourceMessage += fmt.Sprintf(" (%s)", r.Source)
+				}
+				if details := suppressedDetails(r); details != "" {
+					resourceMessage += ":\t" + details
 				}
 				if r.Owner != "" {
 					resourceMessage += fmt.Sprintf(" (owned by %s)", r.Owner)
 				}
+				resourceMessage += resourceContext(r, time.Now())
 
 				// Print the resource(s) that have the problem of this type
 				fmt.Fprintln(tw, "    -", resourceMessage)
+
+				// EDIT: Print the problems consolidated into this one
+				for i := range r.Related {
+					fmt.Fprintf(tw, "        ↳ %s:\t%s\n", r.Related[i].ProblemID, suppressedDetails(&r.Related[i]))
+				}
 			}
 			tw.Flush()
 		}
 	}
+}
 
-	fmt.Println()
-	bold.Println("💡  More information/help:")
-	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 1, ' ', 0)
+// EDIT: New function
+// sortedProblemIDs returns the problem IDs of a map of resources by
+// problem ID in a stable order
+func sortedProblemIDs(byProblem map[string][]*Resource) []string {
+	ids := make([]string, 0, len(byProblem))
 	for id := range byProblem {
-		p := report.GetProblemByID(id)
-		if p == nil {
-			continue
-		}
-
-		helpURL := p.HelpURL
-		if helpURL == "" {
-			helpURL = "https://github.com/getoutreach/devenv/wiki/" + id
-		}
-		fmt.Fprintln(tw, "    -", bold.Sprint(id)+":\t", underline.Sprintf(helpURL))
+		ids = append(ids, id)
 	}
-	tw.Flush()
-
-	os.Exit(1)
-
-	return nil
+	sort.Strings(ids)
+	return ids
 }
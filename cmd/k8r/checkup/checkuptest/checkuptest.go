@@ -0,0 +1,104 @@
+// Description: This file contains code for running problems against
+// fixtures in tests
+
+// Package checkuptest provides fixtures and helpers for testing the
+// detectors of checkup problems, e.g. in table-driven tests.
+package checkuptest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Result is the result of running a problem's detector
+type Result struct {
+	// Details are the resource specific details of the problem
+	Details string
+
+	// Warning is true if the problem is only a warning
+	Warning bool
+
+	// Occurring is true if the problem was detected
+	Occurring bool
+}
+
+// NewConfig returns a Config with the defaults of the checkup flags,
+// checking against the given cluster. Nothing is written anywhere but the
+// report or sent anywhere, even when the environment configures it.
+func NewConfig(cluster *checkup.Cluster) *checkup.Config {
+	cfg, err := checkup.DefaultConfig()
+	if err != nil {
+		panic(fmt.Sprintf("the checkup flag defaults are invalid: %v", err))
+	}
+	cfg.Cluster = cluster
+
+	cfg.HistoryFile, cfg.DetectionCacheFile = "", ""
+	cfg.DatadogStateFile, cfg.GrafanaStateFile, cfg.NotifyStateFile = "", "", ""
+	cfg.BackstageToken, cfg.DatadogAPIKey, cfg.GrafanaToken = "", "", ""
+	cfg.OTLPEndpoint, cfg.OTLPHeaders = "", nil
+	return cfg
+}
+
+// Run runs a problem's detector against an object
+func Run(p checkup.Problem, obj runtime.Object, cfg *checkup.Config) Result {
+	details, warning, occurring := p.Detector(context.Background(), obj, cfg)
+	return Result{Details: details, Warning: warning, Occurring: occurring}
+}
+
+// Case is a test case for a problem's detector
+type Case struct {
+	// Name is the name of the test case
+	Name string
+
+	// Object is the object the detector is run against
+	Object runtime.Object
+
+	// Config is the Config the detector is run with, when nil a default
+	// Config is used with a cluster containing only Object
+	Config *checkup.Config
+
+	// Occurring is whether the problem is expected to be detected
+	Occurring bool
+
+	// Warning is whether the problem is expected to be a warning, only
+	// checked when the problem is expected to be detected
+	Warning bool
+
+	// DetailsContain is a string the details are expected to contain,
+	// only checked when it is set
+	DetailsContain string
+}
+
+// RunCases runs a problem's detector against every case as a subtest
+func RunCases(t *testing.T, p checkup.Problem, cases []Case) {
+	t.Helper()
+
+	for i := range cases {
+		tc := &cases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			cfg := tc.Config
+			if cfg == nil {
+				cfg = NewConfig(NewCluster(tc.Object))
+			}
+
+			got := Run(p, tc.Object, cfg)
+			if got.Occurring != tc.Occurring {
+				t.Fatalf("%s: got occurring %v, want %v (details: %q)", p.ID, got.Occurring, tc.Occurring, got.Details)
+			}
+			if !tc.Occurring {
+				return
+			}
+			if got.Warning != tc.Warning {
+				t.Errorf("%s: got warning %v, want %v", p.ID, got.Warning, tc.Warning)
+			}
+			if !strings.Contains(got.Details, tc.DetailsContain) {
+				t.Errorf("%s: got details %q, want them to contain %q", p.ID, got.Details, tc.DetailsContain)
+			}
+		})
+	}
+}
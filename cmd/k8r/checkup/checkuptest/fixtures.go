@@ -0,0 +1,1147 @@
+// Description: This file contains builders for Kubernetes objects in
+// various broken states
+
+package checkuptest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DefaultNamespace is the namespace fixtures are created in
+const DefaultNamespace = "default"
+
+// DefaultContainer is the name of the container pods are created with
+const DefaultContainer = "app"
+
+// Timestamp is used for timestamps on fixtures that detectors don't compare
+// against the current time, so that output including them is stable
+var Timestamp = metav1.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// NewCluster returns a Cluster containing the given objects, workloads
+// other than pods are only added as themselves
+func NewCluster(objs ...runtime.Object) *checkup.Cluster {
+	c := &checkup.Cluster{}
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.Pod:
+			c.Pods = append(c.Pods, *o)
+		case *v1.HorizontalPodAutoscaler:
+			c.HPAs = append(c.HPAs, *o)
+		case *autoscalingv2.HorizontalPodAutoscaler:
+			c.HPAsV2 = append(c.HPAsV2, *o)
+		case *corev1.ConfigMap:
+			c.ConfigMaps = append(c.ConfigMaps, *o)
+		case *corev1.Secret:
+			c.Secrets = append(c.Secrets, *o)
+		case *corev1.Service:
+			c.Services = append(c.Services, *o)
+		case *appsv1.StatefulSet:
+			c.StatefulSets = append(c.StatefulSets, *o)
+		case *appsv1.Deployment:
+			c.Deployments = append(c.Deployments, *o)
+		case *appsv1.DaemonSet:
+			c.DaemonSets = append(c.DaemonSets, *o)
+		case *batchv1.Job:
+			c.Jobs = append(c.Jobs, *o)
+		case *batchv1.CronJob:
+			c.CronJobs = append(c.CronJobs, *o)
+		case *corev1.Namespace:
+			c.Namespaces = append(c.Namespaces, *o)
+		case *corev1.ServiceAccount:
+			c.ServiceAccounts = append(c.ServiceAccounts, *o)
+		case *corev1.Node:
+			c.Nodes = append(c.Nodes, *o)
+		case *coordinationv1.Lease:
+			c.NodeLeases = append(c.NodeLeases, *o)
+		case *corev1.Event:
+			if o.InvolvedObject.Kind == "PersistentVolumeClaim" {
+				c.PVCEvents = append(c.PVCEvents, *o)
+			} else {
+				c.NodeEvents = append(c.NodeEvents, *o)
+			}
+		case *corev1.PersistentVolumeClaim:
+			c.PersistentVolumeClaims = append(c.PersistentVolumeClaims, *o)
+		case *storagev1.StorageClass:
+			c.StorageClasses = append(c.StorageClasses, *o)
+		case *storagev1.CSIDriver:
+			c.CSIDrivers = append(c.CSIDrivers, *o)
+		case *storagev1.CSINode:
+			c.CSINodes = append(c.CSINodes, *o)
+		case *storagev1.VolumeAttachment:
+			c.VolumeAttachments = append(c.VolumeAttachments, *o)
+		case *policyv1.PodDisruptionBudget:
+			c.PodDisruptionBudgets = append(c.PodDisruptionBudgets, *o)
+		case *rbacv1.Role:
+			c.Roles = append(c.Roles, *o)
+		case *rbacv1.ClusterRole:
+			c.ClusterRoles = append(c.ClusterRoles, *o)
+		case *checkup.APIService:
+			c.APIServices = append(c.APIServices, *o)
+		case *checkup.FlowSchema:
+			c.FlowSchemas = append(c.FlowSchemas, *o)
+		case *checkup.PriorityLevelConfiguration:
+			c.PriorityLevels = append(c.PriorityLevels, *o)
+		case *checkup.ControlPlane:
+			c.ControlPlane = o
+		case *checkup.ImagePulls:
+			c.ImagePulls = append(c.ImagePulls, *o)
+		case *checkup.Taint:
+			c.Taints = append(c.Taints, *o)
+		case *unstructured.Unstructured:
+			switch o.GetKind() {
+			case checkup.ArgoRollouts.Kind:
+				c.Rollouts = append(c.Rollouts, *o)
+			case checkup.ArgoAnalysisRuns.Kind:
+				c.AnalysisRuns = append(c.AnalysisRuns, *o)
+			case checkup.FlaggerCanaries.Kind:
+				c.Canaries = append(c.Canaries, *o)
+			case checkup.PolicyReports.Kind:
+				c.PolicyReports = append(c.PolicyReports, *o)
+			case checkup.ClusterPolicyReports.Kind:
+				c.ClusterPolicyReports = append(c.ClusterPolicyReports, *o)
+			case checkup.TrivyVulnerabilityReports.Kind:
+				c.VulnerabilityReports = append(c.VulnerabilityReports, *o)
+			default:
+				c.OperatorResources = append(c.OperatorResources, *o)
+			}
+		}
+	}
+	return c
+}
+
+// objectMeta returns the metadata for a namespaced fixture
+func objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: DefaultNamespace,
+		UID:       types.UID(DefaultNamespace + "/" + name),
+	}
+}
+
+// PodOption changes a pod built by NewPod
+type PodOption func(*corev1.Pod)
+
+// NewPod returns a healthy running pod with a single ready container
+// named DefaultContainer
+func NewPod(name string, opts ...PodOption) *corev1.Pod {
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: DefaultContainer, Image: "example.com/app:1.0.0"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  DefaultContainer,
+				Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}
+	for _, opt := range opts {
+		opt(pod)
+	}
+	return pod
+}
+
+// container returns the container with the given name, adding it if the
+// pod doesn't have it yet
+func container(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name, Image: "example.com/" + name + ":1.0.0"})
+	return &pod.Spec.Containers[len(pod.Spec.Containers)-1]
+}
+
+// containerStatus returns the status of the container with the given
+// name, adding the container if the pod doesn't have it yet
+func containerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	container(pod, name)
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == name {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{Name: name, Ready: true})
+	return &pod.Status.ContainerStatuses[len(pod.Status.ContainerStatuses)-1]
+}
+
+// InNamespace puts the pod in a namespace
+func InNamespace(namespace string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Namespace = namespace
+		pod.UID = types.UID(namespace + "/" + pod.Name)
+	}
+}
+
+// WithLabels sets labels on the pod
+func WithLabels(labels map[string]string) PodOption {
+	return func(pod *corev1.Pod) {
+		if pod.Labels == nil {
+			pod.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			pod.Labels[k] = v
+		}
+	}
+}
+
+// OnNode schedules the pod on a node
+func OnNode(node string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Spec.NodeName = node
+	}
+}
+
+// OwnedBy sets the controller of the pod, e.g. a ReplicaSet
+func OwnedBy(kind, name string) PodOption {
+	return func(pod *corev1.Pod) {
+		controller := true
+		pod.OwnerReferences = append(pod.OwnerReferences, metav1.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       name,
+			UID:        types.UID(kind + "/" + name),
+			Controller: &controller,
+		})
+	}
+}
+
+// WithImage sets the image of a container
+func WithImage(name, image string) PodOption {
+	return func(pod *corev1.Pod) {
+		container(pod, name).Image = image
+	}
+}
+
+// WithSpec changes the pod's spec, for anything the other options don't cover
+func WithSpec(fn func(*corev1.PodSpec)) PodOption {
+	return func(pod *corev1.Pod) {
+		fn(&pod.Spec)
+	}
+}
+
+// CrashLoopBackOff puts a container into a crash loop
+func CrashLoopBackOff(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := containerStatus(pod, name)
+		cs.Ready = false
+		cs.RestartCount = 5
+		cs.State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+		cs.LastTerminationState = corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error", Message: "exit status 1"},
+		}
+	}
+}
+
+// CreatedAt sets when the pod was created
+func CreatedAt(t time.Time) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.CreationTimestamp = metav1.NewTime(t)
+	}
+}
+
+// RestartedAt sets when a container that has restarted last finished,
+// i.e. when it restarted
+func RestartedAt(name string, t time.Time) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := containerStatus(pod, name)
+		if cs.LastTerminationState.Terminated == nil {
+			cs.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}
+		}
+		cs.LastTerminationState.Terminated.FinishedAt = metav1.NewTime(t)
+	}
+}
+
+// ImagePullBackOff makes a container fail to pull its image
+func ImagePullBackOff(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := containerStatus(pod, name)
+		cs.Ready = false
+		cs.State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}
+	}
+}
+
+// ImagePullFailing makes a container wait to pull its image with the
+// given reason, e.g. ErrImageNeverPull, and message
+func ImagePullFailing(name, reason, message string) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := containerStatus(pod, name)
+		cs.Ready = false
+		cs.State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}}
+	}
+}
+
+// OOMKilled makes a container currently be OOM killed
+func OOMKilled(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := containerStatus(pod, name)
+		cs.Ready = false
+		cs.State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}}
+	}
+}
+
+// RecentlyOOMKilled makes a container's last termination an OOM kill
+func RecentlyOOMKilled(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := containerStatus(pod, name)
+		cs.RestartCount++
+		cs.LastTerminationState = corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled", FinishedAt: metav1.Now()},
+		}
+	}
+}
+
+// NotReady makes a container not ready
+func NotReady(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		containerStatus(pod, name).Ready = false
+	}
+}
+
+// WithResourceLimit sets a resource limit on a container, e.g. nvidia.com/gpu
+func WithResourceLimit(name string, resourceName corev1.ResourceName, quantity string) PodOption {
+	return func(pod *corev1.Pod) {
+		c := container(pod, name)
+		if c.Resources.Limits == nil {
+			c.Resources.Limits = make(corev1.ResourceList)
+		}
+		c.Resources.Limits[resourceName] = resource.MustParse(quantity)
+	}
+}
+
+// Restarts sets how many times a container has restarted
+func Restarts(name string, count int32) PodOption {
+	return func(pod *corev1.Pod) {
+		containerStatus(pod, name).RestartCount = count
+	}
+}
+
+// Pending makes the pod pending with its containers waiting to start
+func Pending(message string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Status.Phase = corev1.PodPending
+		for i := range pod.Status.ContainerStatuses {
+			cs := &pod.Status.ContainerStatuses[i]
+			cs.Ready = false
+			cs.State = corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating", Message: message},
+			}
+		}
+	}
+}
+
+// initializing makes the pod pending on the given init container, which
+// is added if the pod doesn't have it yet
+func initializing(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	pod.Status.Phase = corev1.PodPending
+	for i := range pod.Status.ContainerStatuses {
+		cs := &pod.Status.ContainerStatuses[i]
+		cs.Ready = false
+		cs.State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}
+	}
+
+	image := "example.com/" + name + ":1.0.0"
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{Name: name, Image: image})
+	pod.Status.InitContainerStatuses = append(pod.Status.InitContainerStatuses, corev1.ContainerStatus{Name: name, Image: image})
+	return &pod.Status.InitContainerStatuses[len(pod.Status.InitContainerStatuses)-1]
+}
+
+// InitContainerRunning makes the pod pending on an init container that
+// has been running since the given time
+func InitContainerRunning(name string, since time.Time) PodOption {
+	return func(pod *corev1.Pod) {
+		cs := initializing(pod, name)
+		cs.State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(since)}}
+	}
+}
+
+// InitContainerRetrying makes the pod pending on an init container that
+// has failed the given number of times since the pod started at the given
+// time
+func InitContainerRetrying(name string, restarts int32, started time.Time) PodOption {
+	return func(pod *corev1.Pod) {
+		start := metav1.NewTime(started)
+		pod.Status.StartTime = &start
+
+		cs := initializing(pod, name)
+		cs.RestartCount = restarts
+		cs.State = corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+		}
+		cs.LastTerminationState = corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"},
+		}
+	}
+}
+
+// Unschedulable makes the pod pending because the scheduler couldn't
+// find a node for it
+func Unschedulable(message string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Spec.NodeName = ""
+		pod.Status.Phase = corev1.PodPending
+		pod.Status.ContainerStatuses = nil
+		pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: message,
+		})
+	}
+}
+
+// WithHostPort makes a container bind a TCP port on its node
+func WithHostPort(name string, port int32) PodOption {
+	return func(pod *corev1.Pod) {
+		c := container(pod, name)
+		c.Ports = append(c.Ports, corev1.ContainerPort{ContainerPort: port, HostPort: port, Protocol: corev1.ProtocolTCP})
+	}
+}
+
+// WithSecretEnv makes a container consume a Secret as environment variables
+func WithSecretEnv(name, secret string) PodOption {
+	return func(pod *corev1.Pod) {
+		c := container(pod, name)
+		c.EnvFrom = append(c.EnvFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret}},
+		})
+	}
+}
+
+// WithConfigMapVolume mounts a ConfigMap into the pod
+func WithConfigMapVolume(configMap string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: configMap,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap}},
+			},
+		})
+	}
+}
+
+// WithPVCVolume mounts a PersistentVolumeClaim into the pod
+func WithPVCVolume(name, claim string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		})
+	}
+}
+
+// NewHPA returns an HPA with the given current and max replicas
+func NewHPA(name string, current, maxReplicas int32) *v1.HorizontalPodAutoscaler {
+	return &v1.HorizontalPodAutoscaler{
+		TypeMeta:   metav1.TypeMeta{Kind: "HorizontalPodAutoscaler", APIVersion: "autoscaling/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: v1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v1.CrossVersionObjectReference{Kind: "Deployment", Name: name, APIVersion: "apps/v1"},
+			MaxReplicas:    maxReplicas,
+		},
+		Status: v1.HorizontalPodAutoscalerStatus{CurrentReplicas: current, DesiredReplicas: current},
+	}
+}
+
+// NewHPAV2 returns an autoscaling/v2 HPA scaling on the given metrics
+func NewHPAV2(name string, metrics ...autoscalingv2.MetricSpec) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta:   metav1.TypeMeta{Kind: "HorizontalPodAutoscaler", APIVersion: "autoscaling/v2"},
+		ObjectMeta: objectMeta(name),
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: name, APIVersion: "apps/v1"},
+			MaxReplicas:    10,
+			Metrics:        metrics,
+		},
+	}
+}
+
+// PodsMetric returns a custom metric averaged across the target's pods
+func PodsMetric(name string) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricSource{Metric: autoscalingv2.MetricIdentifier{Name: name}},
+	}
+}
+
+// ExternalMetric returns an external metric
+func ExternalMetric(name string) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type:     autoscalingv2.ExternalMetricSourceType,
+		External: &autoscalingv2.ExternalMetricSource{Metric: autoscalingv2.MetricIdentifier{Name: name}},
+	}
+}
+
+// HPAFailedGetMetric sets the HPA's ScalingActive condition as the HPA
+// controller does when it can't read a metric
+func HPAFailedGetMetric(hpa *autoscalingv2.HorizontalPodAutoscaler, reason, message string) *autoscalingv2.HorizontalPodAutoscaler {
+	hpa.Status.Conditions = append(hpa.Status.Conditions, autoscalingv2.HorizontalPodAutoscalerCondition{
+		Type:    autoscalingv2.ScalingActive,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	return hpa
+}
+
+// NodeOption changes a node built by NewNode
+type NodeOption func(*corev1.Node)
+
+// NewNode returns a ready node
+func NewNode(name string, opts ...NodeOption) *corev1.Node {
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{Kind: "Node", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{
+				Type:   corev1.NodeReady,
+				Status: corev1.ConditionTrue,
+				Reason: "KubeletReady",
+			}},
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+		},
+	}
+	for _, opt := range opts {
+		opt(node)
+	}
+	return node
+}
+
+// NodeNotReady makes the node not ready
+func NodeNotReady(message string) NodeOption {
+	return func(node *corev1.Node) {
+		for i := range node.Status.Conditions {
+			c := &node.Status.Conditions[i]
+			if c.Type == corev1.NodeReady {
+				c.Status = corev1.ConditionUnknown
+				c.Reason = "NodeStatusUnknown"
+				c.Message = message
+				c.LastTransitionTime = Timestamp
+			}
+		}
+	}
+}
+
+// NewNodeLease returns the lease kubelet renews for a node, last renewed
+// at the given time
+func NewNodeLease(node string, renewed time.Time) *coordinationv1.Lease {
+	renewTime := metav1.NewMicroTime(renewed)
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: node, Namespace: corev1.NamespaceNodeLease, UID: types.UID("lease/" + node)},
+		Spec:       coordinationv1.LeaseSpec{HolderIdentity: &node, RenewTime: &renewTime},
+	}
+}
+
+// NodeCondition adds a condition to the node, e.g. one set by
+// node-problem-detector
+func NodeCondition(conditionType corev1.NodeConditionType, status corev1.ConditionStatus, reason, message string) NodeOption {
+	return func(node *corev1.Node) {
+		node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: Timestamp,
+		})
+	}
+}
+
+// NodeAllocatable sets the allocatable amount of a resource on the node
+func NodeAllocatable(resourceName corev1.ResourceName, quantity string) NodeOption {
+	return func(node *corev1.Node) {
+		if node.Status.Allocatable == nil {
+			node.Status.Allocatable = make(corev1.ResourceList)
+		}
+		node.Status.Allocatable[resourceName] = resource.MustParse(quantity)
+	}
+}
+
+// NodeCapacity sets the capacity of a resource on the node
+func NodeCapacity(resourceName corev1.ResourceName, quantity string) NodeOption {
+	return func(node *corev1.Node) {
+		if node.Status.Capacity == nil {
+			node.Status.Capacity = make(corev1.ResourceList)
+		}
+		node.Status.Capacity[resourceName] = resource.MustParse(quantity)
+	}
+}
+
+// NodeImages adds images of the given sizes in bytes to the node status
+func NodeImages(sizes ...int64) NodeOption {
+	return func(node *corev1.Node) {
+		for i, size := range sizes {
+			node.Status.Images = append(node.Status.Images, corev1.ContainerImage{
+				Names:     []string{fmt.Sprintf("registry.example.com/image-%d:latest", i)},
+				SizeBytes: size,
+			})
+		}
+	}
+}
+
+// NodeLabels sets labels on the node
+func NodeLabels(labels map[string]string) NodeOption {
+	return func(node *corev1.Node) {
+		node.Labels = labels
+	}
+}
+
+// ServiceOption changes a Service built by NewService
+type ServiceOption func(*corev1.Service)
+
+// NewService returns a ClusterIP Service selecting pods with the given
+// labels and serving port 80
+func NewService(name string, selector map[string]string, opts ...ServiceOption) *corev1.Service {
+	svc := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: corev1.ServiceSpec{
+			Selector:        selector,
+			ClusterIP:       "10.96.0.10",
+			SessionAffinity: corev1.ServiceAffinityNone,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+// Headless makes the Service headless
+func Headless() ServiceOption {
+	return func(svc *corev1.Service) {
+		svc.Spec.ClusterIP = corev1.ClusterIPNone
+	}
+}
+
+// WithNodePort exposes the Service's first port on a node port
+func WithNodePort(port int32) ServiceOption {
+	return func(svc *corev1.Service) {
+		svc.Spec.Type = corev1.ServiceTypeNodePort
+		svc.Spec.Ports[0].NodePort = port
+	}
+}
+
+// WithTargetPort changes the target port of the Service's first port
+func WithTargetPort(port int) ServiceOption {
+	return func(svc *corev1.Service) {
+		svc.Spec.Ports[0].TargetPort = intstr.FromInt(port)
+	}
+}
+
+// WithClientIPAffinity enables ClientIP session affinity
+func WithClientIPAffinity() ServiceOption {
+	return func(svc *corev1.Service) {
+		svc.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+	}
+}
+
+// NewStatefulSet returns a StatefulSet whose pods have the given labels
+func NewStatefulSet(name, serviceName string, labels map[string]string) *appsv1.StatefulSet {
+	replicas := int32(1)
+	return &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{Kind: "StatefulSet", APIVersion: "apps/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: serviceName,
+			Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: DefaultContainer, Image: "example.com/app:1.0.0"}},
+				},
+			},
+		},
+	}
+}
+
+// StatefulSetReplicas sets how many replicas the StatefulSet wants and
+// how many of them are ready, as observed by the StatefulSet controller
+func StatefulSetReplicas(sts *appsv1.StatefulSet, replicas, ready int32) *appsv1.StatefulSet {
+	sts.Spec.Replicas = &replicas
+	sts.Status.ObservedGeneration = 1
+	sts.Status.Replicas = replicas
+	sts.Status.ReadyReplicas = ready
+	return sts
+}
+
+// NewDeployment returns a Deployment with the given number of replicas
+// and update strategy
+func NewDeployment(name string, replicas int32, strategy appsv1.DeploymentStrategy) *appsv1.Deployment {
+	labels := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Strategy: strategy,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: DefaultContainer, Image: "example.com/app:1.0.0"}},
+				},
+			},
+		},
+	}
+}
+
+// DeploymentProgressDeadlineExceeded sets the Deployment's Progressing
+// condition as the Deployment controller does when a rollout times out,
+// with some of the replicas updated
+func DeploymentProgressDeadlineExceeded(d *appsv1.Deployment, updated int32) *appsv1.Deployment {
+	d.Status.UpdatedReplicas = updated
+	d.Status.AvailableReplicas = *d.Spec.Replicas
+	d.Status.Conditions = append(d.Status.Conditions, appsv1.DeploymentCondition{
+		Type:           appsv1.DeploymentProgressing,
+		Status:         corev1.ConditionFalse,
+		Reason:         "ProgressDeadlineExceeded",
+		Message:        fmt.Sprintf("ReplicaSet %q has timed out progressing.", d.Name+"-5d4f8c7b9"),
+		LastUpdateTime: Timestamp,
+	})
+	return d
+}
+
+// NewDaemonSet returns a DaemonSet running the given image, with the given
+// number of pods desired and ready
+func NewDaemonSet(name, image string, desired, ready int32) *appsv1.DaemonSet {
+	labels := map[string]string{"app": name}
+	return &appsv1.DaemonSet{
+		TypeMeta:   metav1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: DefaultContainer, Image: image}},
+				},
+			},
+		},
+		Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: desired,
+			CurrentNumberScheduled: desired,
+			NumberReady:            ready,
+			NumberAvailable:        ready,
+			NumberUnavailable:      desired - ready,
+		},
+	}
+}
+
+// NewJob returns a running Job with the given backoff limit
+func NewJob(name string, backoffLimit int32) *batchv1.Job {
+	return &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{Kind: "Job", APIVersion: "batch/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+					Containers:    []corev1.Container{{Name: DefaultContainer, Image: "example.com/app:1.0.0"}},
+				},
+			},
+		},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+}
+
+// NewCronJob returns a CronJob with the given schedule that last
+// succeeded at the given time, or never when it is nil
+func NewCronJob(name, schedule string, lastSuccess *metav1.Time) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{Kind: "CronJob", APIVersion: "batch/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: batchv1.CronJobSpec{
+			Schedule: schedule,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyOnFailure,
+							Containers:    []corev1.Container{{Name: DefaultContainer, Image: "example.com/app:1.0.0"}},
+						},
+					},
+				},
+			},
+		},
+		Status: batchv1.CronJobStatus{LastSuccessfulTime: lastSuccess},
+	}
+}
+
+// NewSecret returns an Opaque Secret with the given data
+func NewSecret(name string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: objectMeta(name),
+		Type:       corev1.SecretTypeOpaque,
+		Data:       make(map[string][]byte, len(data)),
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+// NewConfigMap returns an empty ConfigMap
+func NewConfigMap(name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: objectMeta(name),
+	}
+}
+
+// NewNamespace returns a namespace with the given labels
+func NewNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name), Labels: labels},
+	}
+}
+
+// NewPodDisruptionBudget returns a PodDisruptionBudget selecting pods
+// with the given labels
+func NewPodDisruptionBudget(name string, selector map[string]string) *policyv1.PodDisruptionBudget {
+	minAvailable := intstr.FromInt(1)
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{Kind: "PodDisruptionBudget", APIVersion: "policy/v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: selector},
+		},
+	}
+}
+
+// NewNodeEvent returns an event recorded for a node
+func NewNodeEvent(node, reason string, at metav1.Time) *corev1.Event {
+	return &corev1.Event{
+		TypeMeta:       metav1.TypeMeta{Kind: "Event", APIVersion: "v1"},
+		ObjectMeta:     objectMeta(node + "." + reason),
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: node},
+		Reason:         reason,
+		Type:           corev1.EventTypeWarning,
+		LastTimestamp:  at,
+	}
+}
+
+// NewPodEvent returns an event the kubelet on the node recorded for a pod
+func NewPodEvent(pod, node, reason, message string, at metav1.Time) *corev1.Event {
+	return &corev1.Event{
+		TypeMeta:   metav1.TypeMeta{Kind: "Event", APIVersion: "v1"},
+		ObjectMeta: objectMeta(pod + "." + reason),
+		InvolvedObject: corev1.ObjectReference{
+			Kind: "Pod", Namespace: DefaultNamespace, Name: pod, UID: types.UID(DefaultNamespace + "/" + pod),
+		},
+		Source:        corev1.EventSource{Component: "kubelet", Host: node},
+		Reason:        reason,
+		Message:       message,
+		Type:          corev1.EventTypeNormal,
+		LastTimestamp: at,
+	}
+}
+
+// NewRole returns a Role with the given rules
+func NewRole(name string, rules ...rbacv1.PolicyRule) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{Kind: "Role", APIVersion: "rbac.authorization.k8s.io/v1"},
+		ObjectMeta: objectMeta(name),
+		Rules:      rules,
+	}
+}
+
+// NewClusterRole returns a ClusterRole with the given rules
+func NewClusterRole(name string, rules ...rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{Kind: "ClusterRole", APIVersion: "rbac.authorization.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+		Rules:      rules,
+	}
+}
+
+// NewAPIService returns an available APIService for the group version,
+// served by a Service in the kube-system namespace
+func NewAPIService(group, version string) *checkup.APIService {
+	return &checkup.APIService{
+		TypeMeta:   metav1.TypeMeta{Kind: "APIService", APIVersion: "apiregistration.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: version + "." + group, UID: types.UID(version + "." + group)},
+		Spec: checkup.APIServiceSpec{
+			Service: &checkup.APIServiceReference{Namespace: metav1.NamespaceSystem, Name: group},
+			Group:   group,
+			Version: version,
+		},
+		Status: checkup.APIServiceStatus{
+			Conditions: []checkup.APIServiceCondition{{Type: "Available", Status: "True", Reason: "Passed"}},
+		},
+	}
+}
+
+// NewFlowSchema returns a FlowSchema that puts the requests of a group
+// in a priority level with the given matching precedence
+func NewFlowSchema(name, group, priorityLevel string, precedence int32) *checkup.FlowSchema {
+	return &checkup.FlowSchema{
+		TypeMeta:   metav1.TypeMeta{Kind: "FlowSchema", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta3"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("flowschema/" + name)},
+		Spec: checkup.FlowSchemaSpec{
+			PriorityLevelConfiguration: checkup.PriorityLevelReference{Name: priorityLevel},
+			MatchingPrecedence:         precedence,
+			Rules: []checkup.FlowSchemaRule{{
+				Subjects: []checkup.FlowSchemaSubject{{Kind: "Group", Group: &checkup.FlowSchemaSubjectName{Name: group}}},
+				ResourceRules: []checkup.FlowSchemaResourceRule{{
+					Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"},
+				}},
+			}},
+		},
+	}
+}
+
+// NewPriorityLevel returns a limited priority level with the given
+// concurrency shares that queues requests when it is busy, or an exempt
+// one when shares is 0
+func NewPriorityLevel(name string, shares int32) *checkup.PriorityLevelConfiguration {
+	pl := &checkup.PriorityLevelConfiguration{
+		TypeMeta:   metav1.TypeMeta{Kind: "PriorityLevelConfiguration", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta3"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("prioritylevelconfiguration/" + name)},
+		Spec:       checkup.PriorityLevelSpec{Type: "Exempt"},
+	}
+	if shares != 0 {
+		pl.Spec = checkup.PriorityLevelSpec{
+			Type: "Limited",
+			Limited: &checkup.LimitedPriorityLevel{
+				NominalConcurrencyShares: &shares,
+				LimitResponse:            checkup.LimitResponse{Type: "Queue"},
+			},
+		}
+	}
+	return pl
+}
+
+// APIServiceUnavailable makes the APIService unavailable
+func APIServiceUnavailable(svc *checkup.APIService, reason, message string) *checkup.APIService {
+	svc.Status.Conditions[0].Status = "False"
+	svc.Status.Conditions[0].Reason = reason
+	svc.Status.Conditions[0].Message = message
+	return svc
+}
+
+// NewCustomResource returns a custom resource of the given type with the
+// given spec and status, either of which can be nil
+func NewCustomResource(r *checkup.CustomResource, name string, spec, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	if status != nil {
+		u.Object["status"] = status
+	}
+	u.SetAPIVersion(r.GroupVersion())
+	u.SetKind(r.Kind)
+	u.SetNamespace(DefaultNamespace)
+	u.SetName(name)
+	u.SetUID(types.UID(name))
+	return u
+}
+
+// NewRollout returns an Argo Rollout that entered the given phase at the
+// given time with the given message
+func NewRollout(name, phase string, since time.Time, message string) *unstructured.Unstructured {
+	status := map[string]interface{}{"phase": phase, "message": message}
+	switch phase {
+	case "Paused":
+		status["pauseConditions"] = []interface{}{
+			map[string]interface{}{"reason": "CanaryPauseStep", "startTime": since.UTC().Format(time.RFC3339)},
+		}
+	case "Degraded":
+		status["conditions"] = []interface{}{
+			map[string]interface{}{
+				"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded",
+				"lastTransitionTime": since.UTC().Format(time.RFC3339),
+			},
+		}
+	}
+	return NewCustomResource(&checkup.ArgoRollouts, name, nil, status)
+}
+
+// NewAnalysisRun returns an Argo Rollouts AnalysisRun owned by the given
+// Rollout, created at the given time, with the given phase and message
+func NewAnalysisRun(name, rollout, phase, message string, created time.Time) *unstructured.Unstructured {
+	u := NewCustomResource(&checkup.ArgoAnalysisRuns, name, nil, map[string]interface{}{"phase": phase, "message": message})
+	u.SetCreationTimestamp(metav1.NewTime(created))
+	u.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: checkup.ArgoRollouts.GroupVersion(), Kind: checkup.ArgoRollouts.Kind, Name: rollout},
+	})
+	return u
+}
+
+// NewCanary returns a Flagger Canary in the given phase, with the given
+// number of failed checks and Promoted condition message
+func NewCanary(name, phase string, failedChecks int64, message string) *unstructured.Unstructured {
+	return NewCustomResource(&checkup.FlaggerCanaries, name, nil, map[string]interface{}{
+		"phase":        phase,
+		"failedChecks": failedChecks,
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Promoted", "status": "False", "message": message},
+		},
+	})
+}
+
+// PVCOption changes a PersistentVolumeClaim built by NewPVC
+type PVCOption func(*corev1.PersistentVolumeClaim)
+
+// NewPVC returns a PersistentVolumeClaim bound to a volume of the given
+// size, provisioned with the given StorageClass
+func NewPVC(name, storageClass, size string, opts ...PVCOption) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta:   metav1.TypeMeta{Kind: "PersistentVolumeClaim", APIVersion: "v1"},
+		ObjectMeta: objectMeta(name),
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &storageClass,
+			VolumeName:       "pvc-" + name,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Phase:    corev1.ClaimBound,
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+		},
+	}
+	for _, opt := range opts {
+		opt(pvc)
+	}
+	return pvc
+}
+
+// PVCRequests changes how much storage the PVC requests without changing
+// the size of its volume, as when it is resized
+func PVCRequests(size string) PVCOption {
+	return func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse(size)
+	}
+}
+
+// PVCCondition sets a true condition on the PVC, e.g. Resizing, since
+// the given time
+func PVCCondition(conditionType corev1.PersistentVolumeClaimConditionType, since time.Time) PVCOption {
+	return func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{
+			Type:               conditionType,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(since),
+		})
+	}
+}
+
+// NewPVCEvent returns a warning event recorded for a PVC
+func NewPVCEvent(pvc, reason, message string, at time.Time) *corev1.Event {
+	return &corev1.Event{
+		TypeMeta:   metav1.TypeMeta{Kind: "Event", APIVersion: "v1"},
+		ObjectMeta: objectMeta(pvc + "." + reason),
+		InvolvedObject: corev1.ObjectReference{
+			Kind: "PersistentVolumeClaim", Namespace: DefaultNamespace, Name: pvc, UID: types.UID(DefaultNamespace + "/" + pvc),
+		},
+		Reason:        reason,
+		Message:       message,
+		Type:          corev1.EventTypeWarning,
+		LastTimestamp: metav1.NewTime(at),
+	}
+}
+
+// NewStorageClass returns a StorageClass for the given provisioner
+func NewStorageClass(name, provisioner string, allowExpansion bool) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		TypeMeta:             metav1.TypeMeta{Kind: "StorageClass", APIVersion: "storage.k8s.io/v1"},
+		ObjectMeta:           metav1.ObjectMeta{Name: name, UID: types.UID("storageclass/" + name)},
+		Provisioner:          provisioner,
+		AllowVolumeExpansion: &allowExpansion,
+	}
+}
+
+// DefaultStorageClass marks the StorageClass as the default
+func DefaultStorageClass(sc *storagev1.StorageClass) *storagev1.StorageClass {
+	sc.Annotations = map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}
+	return sc
+}
+
+// NewCSIDriver returns a CSIDriver registered with the given name
+func NewCSIDriver(name string) *storagev1.CSIDriver {
+	return &storagev1.CSIDriver{
+		TypeMeta:   metav1.TypeMeta{Kind: "CSIDriver", APIVersion: "storage.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("csidriver/" + name)},
+	}
+}
+
+// PVCPending makes the PVC Pending without a volume
+func PVCPending() PVCOption {
+	return func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Spec.VolumeName = ""
+		pvc.Status = corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending}
+	}
+}
+
+// PVCWithoutStorageClass leaves the PVC's storageClassName unset, so it
+// gets the default StorageClass
+func PVCWithoutStorageClass() PVCOption {
+	return func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Spec.StorageClassName = nil
+	}
+}
+
+// NewCSINode returns the CSINode of a node with the given drivers
+// registered
+func NewCSINode(node string, drivers ...string) *storagev1.CSINode {
+	csiNode := &storagev1.CSINode{
+		TypeMeta:   metav1.TypeMeta{Kind: "CSINode", APIVersion: "storage.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: node, UID: types.UID("csinode/" + node)},
+	}
+	for _, d := range drivers {
+		csiNode.Spec.Drivers = append(csiNode.Spec.Drivers, storagev1.CSINodeDriver{Name: d, NodeID: node})
+	}
+	return csiNode
+}
+
+// NewVolumeAttachment returns a VolumeAttachment of a PersistentVolume to
+// a node created at the given time, that isn't attached yet
+func NewVolumeAttachment(pv, node, attacher string, created time.Time) *storagev1.VolumeAttachment {
+	return &storagev1.VolumeAttachment{
+		TypeMeta:   metav1.TypeMeta{Kind: "VolumeAttachment", APIVersion: "storage.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "csi-" + pv, UID: types.UID("volumeattachment/" + pv), CreationTimestamp: metav1.NewTime(created)},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: attacher,
+			NodeName: node,
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pv},
+		},
+	}
+}
@@ -0,0 +1,94 @@
+// Description: This file contains code for detecting nodes whose clocks
+// are skewed from the rest of the cluster, from the times kubelet renews
+// its node lease with
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// minClockSkewNodes is how many nodes have to have leases for the rest of
+// the cluster to be compared against, with fewer a skewed node would skew
+// the reference too
+const minClockSkewNodes = 3
+
+// clusterClock returns the median time the node leases were last renewed
+// at. Kubelet renews its lease every 10 seconds with its own clock, so on
+// nodes with correct clocks the renew times are within seconds of each
+// other and the median is close to the time of the scan.
+func clusterClock(leases []coordinationv1.Lease) (time.Time, bool) {
+	renewed := make([]time.Time, 0, len(leases))
+	for i := range leases {
+		if t := leases[i].Spec.RenewTime; t != nil {
+			renewed = append(renewed, t.Time)
+		}
+	}
+	if len(renewed) < minClockSkewNodes {
+		return time.Time{}, false
+	}
+
+	sort.Slice(renewed, func(i, j int) bool { return renewed[i].Before(renewed[j]) })
+	return renewed[len(renewed)/2], true
+}
+
+// nodeLease returns the lease of a node
+func nodeLease(node *corev1.Node, leases []coordinationv1.Lease) (*coordinationv1.Lease, bool) {
+	for i := range leases {
+		if leases[i].Name == node.Name {
+			return &leases[i], true
+		}
+	}
+	return nil, false
+}
+
+// ProblemNodeClockSkew is a problem with a ready node whose lease was
+// renewed at a time that is far from when the other nodes renewed theirs.
+// A lease renewed in the future can only come from a clock that is
+// ahead. One renewed long ago on a node that is still ready comes from a
+// clock that is behind, as the node would have been marked not ready if
+// kubelet had stopped renewing it.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/NodeClockSkew
+var ProblemNodeClockSkew = Problem{
+	ID:               "NodeClockSkew",
+	ShortDescription: "A node's clock appears skewed from the rest of the cluster, which breaks TLS and leader election",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/NodeClockSkew",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok || cfg.Cluster == nil || cfg.ClockSkewThreshold <= 0 {
+			return "", false, false
+		}
+		if ready, ok := nodeCondition(node, corev1.NodeReady); !ok || ready.Status != corev1.ConditionTrue {
+			return "", false, false
+		}
+
+		lease, ok := nodeLease(node, cfg.Cluster.NodeLeases)
+		if !ok || lease.Spec.RenewTime == nil {
+			return "", false, false
+		}
+		reference, ok := clusterClock(cfg.Cluster.NodeLeases)
+		if !ok {
+			return "", false, false
+		}
+
+		skew := lease.Spec.RenewTime.Sub(reference)
+		direction := "ahead of"
+		if skew < 0 {
+			skew, direction = -skew, "behind"
+		}
+		if skew < cfg.ClockSkewThreshold {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("Node's clock appears to be %s %s the other nodes: kubelet renewed its lease at %s, "+
+			"while most nodes renewed theirs around %s, check that NTP/chrony is running and synced on the node",
+			skew.Round(time.Second), direction, lease.Spec.RenewTime.Format(time.RFC3339), reference.Format(time.RFC3339)), true, true
+	},
+}
@@ -0,0 +1,49 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNodeClockSkew(t *testing.T) {
+	now := checkuptest.Timestamp.Time
+	node := checkuptest.NewNode("node-1")
+	notReady := checkuptest.NewNode("node-1", checkuptest.NodeNotReady("Kubelet stopped posting node status."))
+
+	// withLeases returns a config with node-1's lease renewed at the given
+	// offset and three other nodes that renewed theirs in the past seconds
+	withLeases := func(offset time.Duration, others int) *checkup.Config {
+		objs := []runtime.Object{node, checkuptest.NewNodeLease("node-1", now.Add(offset))}
+		for i, name := range []string{"node-2", "node-3", "node-4"}[:others] {
+			objs = append(objs, checkuptest.NewNodeLease(name, now.Add(-time.Duration(i)*3*time.Second)))
+		}
+		return checkuptest.NewConfig(checkuptest.NewCluster(objs...))
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemNodeClockSkew, []checkuptest.Case{
+		{Name: "in sync", Object: node, Config: withLeases(-5*time.Second, 3)},
+		{Name: "no lease", Object: node},
+		{Name: "too few nodes", Object: node, Config: withLeases(10*time.Minute, 1)},
+		{Name: "not ready", Object: notReady, Config: withLeases(-10*time.Minute, 3)},
+		{
+			Name:           "ahead",
+			Object:         node,
+			Config:         withLeases(5*time.Minute, 3),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Node's clock appears to be 5m0s ahead of the other nodes",
+		},
+		{
+			Name:           "behind",
+			Object:         node,
+			Config:         withLeases(-10*time.Minute, 3),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Node's clock appears to be 9m57s behind the other nodes",
+		},
+	})
+}
@@ -0,0 +1,1026 @@
+// Description: This file contains code for keeping track of the
+// resources that are checked in a run
+
+package checkup
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Cluster contains all of the resources that are being checked, this is
+// used by problems that need to look at related resources, e.g. the
+// ConfigMaps that a pod references
+type Cluster struct {
+	// Pods are all of the pods, when linting these are built from the
+	// pod templates of workloads as well
+	Pods []corev1.Pod
+
+	// HPAs are all of the horizontal pod autoscalers
+	HPAs []v1.HorizontalPodAutoscaler
+
+	// HPAsV2 are all of the horizontal pod autoscalers in autoscaling/v2,
+	// which includes the metrics they scale on, this is empty when the
+	// cluster doesn't serve autoscaling/v2
+	HPAsV2 []autoscalingv2.HorizontalPodAutoscaler
+
+	// HPAMetricErrors are why custom and external metrics HPAs scale on
+	// couldn't be read, keyed by namespace/name/metric
+	HPAMetricErrors map[string]string
+
+	// InitContainerLogs are the last log line of init containers that are
+	// stuck, keyed by namespace/pod/container
+	InitContainerLogs map[string]string
+
+	// ConfigMaps are all of the ConfigMaps
+	ConfigMaps []corev1.ConfigMap
+
+	// Secrets are all of the Secrets
+	Secrets []corev1.Secret
+
+	// Services are all of the Services
+	Services []corev1.Service
+
+	// StatefulSets are all of the StatefulSets
+	StatefulSets []appsv1.StatefulSet
+
+	// Deployments are all of the Deployments
+	Deployments []appsv1.Deployment
+
+	// DaemonSets are all of the DaemonSets
+	DaemonSets []appsv1.DaemonSet
+
+	// Jobs are all of the Jobs
+	Jobs []batchv1.Job
+
+	// Namespaces are all of the namespaces
+	Namespaces []corev1.Namespace
+
+	// ServiceAccounts are all of the ServiceAccounts
+	ServiceAccounts []corev1.ServiceAccount
+
+	// Nodes are all of the nodes
+	Nodes []corev1.Node
+
+	// NodeLeases are the leases kubelet renews for each node
+	NodeLeases []coordinationv1.Lease
+
+	// CronJobs are all of the CronJobs
+	CronJobs []batchv1.CronJob
+
+	// NodeEvents are the events that were recorded for nodes
+	NodeEvents []corev1.Event
+
+	// PersistentVolumeClaims are all of the PersistentVolumeClaims
+	PersistentVolumeClaims []corev1.PersistentVolumeClaim
+
+	// PVCEvents are the events that were recorded for
+	// PersistentVolumeClaims
+	PVCEvents []corev1.Event
+
+	// StorageClasses are all of the StorageClasses
+	StorageClasses []storagev1.StorageClass
+
+	// CSIDrivers are all of the CSIDrivers
+	CSIDrivers []storagev1.CSIDriver
+
+	// CSINodes are the CSI drivers registered on each node
+	CSINodes []storagev1.CSINode
+
+	// VolumeAttachments are all of the VolumeAttachments
+	VolumeAttachments []storagev1.VolumeAttachment
+
+	// ImagePulls are the image pulls timed from pod events, by image
+	ImagePulls []ImagePulls
+
+	// Taints are the taints on nodes, by key
+	Taints []Taint
+
+	// PodDisruptionBudgets are all of the PodDisruptionBudgets
+	PodDisruptionBudgets []policyv1.PodDisruptionBudget
+
+	// CertificateSigningRequests are all of the CertificateSigningRequests
+	CertificateSigningRequests []certificatesv1.CertificateSigningRequest
+
+	// Roles are all of the Roles
+	Roles []rbacv1.Role
+
+	// ClusterRoles are all of the ClusterRoles
+	ClusterRoles []rbacv1.ClusterRole
+
+	// APIServices are all of the APIServices
+	APIServices []APIService
+
+	// FlowSchemas are all of the priority and fairness FlowSchemas
+	FlowSchemas []FlowSchema
+
+	// PriorityLevels are all of the priority and fairness
+	// PriorityLevelConfigurations
+	PriorityLevels []PriorityLevelConfiguration
+
+	// Rollouts are all of the Argo Rollouts, when Argo Rollouts is installed
+	Rollouts []unstructured.Unstructured
+
+	// AnalysisRuns are all of the Argo Rollouts AnalysisRuns
+	AnalysisRuns []unstructured.Unstructured
+
+	// Canaries are all of the Flagger Canaries, when Flagger is installed
+	Canaries []unstructured.Unstructured
+
+	// OperatorResources are the custom resources of operators that have
+	// an OperatorPreset, when the operator is installed
+	OperatorResources []unstructured.Unstructured
+
+	// PolicyReports are the PolicyReports written by policy engines like
+	// Kyverno, when their CRD is installed
+	PolicyReports []unstructured.Unstructured
+
+	// ClusterPolicyReports are the PolicyReports for cluster scoped
+	// resources
+	ClusterPolicyReports []unstructured.Unstructured
+
+	// VulnerabilityReports are the Trivy Operator VulnerabilityReports,
+	// when Trivy Operator is installed
+	VulnerabilityReports []unstructured.Unstructured
+
+	// KafkaUnderReplicatedPartitions are the number of under replicated
+	// partitions each Strimzi Kafka broker leads, keyed by namespace/name
+	// of the pod, for brokers that expose metrics
+	KafkaUnderReplicatedPartitions map[string]int
+
+	// KubeletCertificates are the serving certificates of kubelets, keyed
+	// by node name, when they were probed
+	KubeletCertificates map[string]*x509.Certificate
+
+	// NodeStats are the filesystem stats and eviction thresholds of
+	// kubelets, keyed by node name, for the kubelets that could be read
+	// through the node proxy
+	NodeStats map[string]*NodeStats
+
+	// ControlPlane is the health of the control plane, if it was gathered
+	ControlPlane *ControlPlane
+
+	// Distro is the Kubernetes distribution the cluster runs
+	Distro Distro
+
+	// Incomplete are the resources that couldn't be listed, so weren't
+	// checked
+	Incomplete []ListFailure
+
+	// Listed are the resources that were listed, which are the only ones
+	// checked when the scan was interrupted while listing
+	Listed []string
+
+	// pods indexes the pods for problems that look them up for every
+	// resource, it is built the first time it is needed
+	pods     *podIndex
+	podsOnce sync.Once
+
+	// objects indexes the nodes, namespaces and Services by name for
+	// lookups, it is built the first time it is needed
+	objects     *objectIndex
+	objectsOnce sync.Once
+}
+
+// objectIndex is the resources of a cluster other than pods that problems
+// look up by name
+type objectIndex struct {
+	// nodes are the nodes keyed by name
+	nodes map[string]*corev1.Node
+
+	// namespaces are the namespaces keyed by name
+	namespaces map[string]*corev1.Namespace
+
+	// services are the Services keyed by namespace/name
+	services map[string]*corev1.Service
+}
+
+// objectIndex returns the index of the nodes, namespaces and Services,
+// building it on first use. It must only be used once they were listed.
+func (c *Cluster) objectIndex() *objectIndex {
+	c.objectsOnce.Do(func() {
+		idx := &objectIndex{
+			nodes:      make(map[string]*corev1.Node, len(c.Nodes)),
+			namespaces: make(map[string]*corev1.Namespace, len(c.Namespaces)),
+			services:   make(map[string]*corev1.Service, len(c.Services)),
+		}
+		for i := range c.Nodes {
+			idx.nodes[c.Nodes[i].Name] = &c.Nodes[i]
+		}
+		for i := range c.Namespaces {
+			idx.namespaces[c.Namespaces[i].Name] = &c.Namespaces[i]
+		}
+		for i := range c.Services {
+			svc := &c.Services[i]
+			idx.services[svc.Namespace+"/"+svc.Name] = svc
+		}
+		c.objects = idx
+	})
+	return c.objects
+}
+
+// podIndex is the pods of a cluster indexed by what problems look them up
+// by, so that they aren't scanned again for every resource
+type podIndex struct {
+	// byName are the pods keyed by namespace/name, the name problems are
+	// reported on
+	byName map[string]*corev1.Pod
+
+	// byNamespace are the pods in each namespace
+	byNamespace map[string][]*corev1.Pod
+
+	// byController are the pods of each controller, keyed by its UID
+	byController map[types.UID][]*corev1.Pod
+
+	// byService are the pods each Service selects, keyed by
+	// namespace/name of the Service
+	byService map[string][]*corev1.Pod
+
+	// services are the Services that select each pod, keyed by
+	// namespace/name of the pod
+	services map[string][]*corev1.Service
+}
+
+// podIndex returns the index of the pods, building it on first use. It
+// must only be used once the pods and Services were listed.
+func (c *Cluster) podIndex() *podIndex {
+	c.podsOnce.Do(func() {
+		idx := &podIndex{
+			byName:       make(map[string]*corev1.Pod, len(c.Pods)),
+			byNamespace:  make(map[string][]*corev1.Pod),
+			byController: make(map[types.UID][]*corev1.Pod),
+			byService:    make(map[string][]*corev1.Pod),
+			services:     make(map[string][]*corev1.Service),
+		}
+		for i := range c.Pods {
+			p := &c.Pods[i]
+			idx.byName[p.Namespace+"/"+p.Name] = p
+			idx.byNamespace[p.Namespace] = append(idx.byNamespace[p.Namespace], p)
+			if owner := metav1.GetControllerOf(p); owner != nil {
+				idx.byController[owner.UID] = append(idx.byController[owner.UID], p)
+			}
+		}
+		for i := range c.Services {
+			svc := &c.Services[i]
+			if len(svc.Spec.Selector) == 0 {
+				continue
+			}
+			sel := labels.SelectorFromSet(svc.Spec.Selector)
+			key := svc.Namespace + "/" + svc.Name
+			for _, p := range idx.byNamespace[svc.Namespace] {
+				if sel.Matches(labels.Set(p.Labels)) {
+					idx.byService[key] = append(idx.byService[key], p)
+					podKey := p.Namespace + "/" + p.Name
+					idx.services[podKey] = append(idx.services[podKey], svc)
+				}
+			}
+		}
+		c.pods = idx
+	})
+	return c.pods
+}
+
+// listConcurrency is the number of kinds of resources that are listed at
+// the same time
+const listConcurrency = 8
+
+// ListCluster lists all of the resources that problems are checked against,
+// resources the RBAC preflight found can't be listed are skipped. They and
+// the resources that failed to list are recorded in Incomplete. Kinds of
+// resources are listed concurrently.
+func ListCluster(ctx context.Context, k kubernetes.Interface, cfg *Config) (*Cluster, error) {
+	c := &Cluster{}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(listConcurrency)
+
+	// incomplete records that the resource couldn't be listed in the
+	// namespace, once for resources that are listed more than once, e.g.
+	// events
+	var mu sync.Mutex
+	incomplete := func(resource, namespace string, err error, reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, f := range c.Incomplete {
+			if f.Resource == resource && f.Namespace == namespace {
+				return
+			}
+		}
+		c.Incomplete = append(c.Incomplete, ListFailure{Resource: resource, Namespace: namespace, Reason: reason, err: err})
+	}
+
+	// tolerate runs fn, recording that the resource couldn't be listed
+	// when it fails instead of failing the scan, e.g. when the user isn't
+	// allowed to or the API server timed out. Only interruptions and
+	// credentials the cluster rejects, which would fail every other list
+	// too, stop the scan. fn must only set fields of the cluster once it
+	// listed everything, so that an interrupted list leaves nothing half
+	// listed behind.
+	tolerate := func(resource string, fn func(ctx context.Context) error) {
+		g.Go(func() error {
+			err := fn(gctx)
+			if err != nil && (ctx.Err() != nil || fatalListError(err)) {
+				return err
+			}
+			if err != nil {
+				incomplete(resource, metav1.NamespaceAll, err, errors.Cause(err).Error())
+				return nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			c.Listed = append(c.Listed, resource)
+			return nil
+		})
+	}
+
+	// list lists a kind of resource with fn when the permission is allowed,
+	// fn must only set fields of the cluster no other fn sets
+	builtin := make(map[string]bool)
+	list := func(p Permission, fn func(ctx context.Context) error) {
+		if !cfg.allowed(p) {
+			incomplete(p.resource(), metav1.NamespaceAll, nil, listForbidden)
+			return
+		}
+		builtin[p.resource()] = true
+		tolerate(p.resource(), fn)
+	}
+
+	// Sharded scans only list namespaced resources in the namespaces the
+	// shard owns, so the namespaces are listed before anything else
+	namespaces := []string{metav1.NamespaceAll}
+	if cfg.Shard != nil {
+		if !cfg.allowed(Permission{Verb: "list", Resource: "namespaces"}) {
+			return nil, errors.New("sharding a scan needs permission to list namespaces")
+		}
+		all, err := k.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list namespaces")
+		}
+		c.Namespaces = all.Items
+		c.Listed = append(c.Listed, "namespaces")
+		builtin["namespaces"] = true
+		namespaces = cfg.Shard.namespaces(c.Namespaces)
+	} else {
+		list(Permission{Verb: "list", Resource: "namespaces"}, func(ctx context.Context) error {
+			all, err := k.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list namespaces")
+			}
+			c.Namespaces = all.Items
+			return nil
+		})
+	}
+
+	// inNamespaces calls fn for each namespace namespaced resources are
+	// listed in. A namespace the resource couldn't be listed in is
+	// recorded and the others are still listed, only interruptions and
+	// rejected credentials are returned.
+	inNamespaces := func(resource string, fn func(namespace string) error) error {
+		for _, ns := range namespaces {
+			err := fn(ns)
+			if err != nil && (ctx.Err() != nil || fatalListError(err)) {
+				return err
+			}
+			if err != nil {
+				incomplete(resource, ns, err, errors.Cause(err).Error())
+			}
+		}
+		return nil
+	}
+
+	// listClusterScoped lists a kind of resource only the first shard
+	// checks, cluster scoped resources other checks look up, e.g. nodes,
+	// are listed by every shard with list instead
+	listClusterScoped := func(p Permission, fn func(ctx context.Context) error) {
+		if cfg.Shard.owns("") {
+			list(p, fn)
+		}
+	}
+
+	list(Permission{Verb: "list", Resource: "pods"}, func(ctx context.Context) error {
+		var listed []corev1.Pod
+		err := inNamespaces("pods", func(ns string) error {
+			if cfg.LowMemory {
+				return errors.Wrap(listPaged(ctx, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+					return k.CoreV1().Pods(ns).List(ctx, opts)
+				}, func(obj runtime.Object) {
+					listed = append(listed, *obj.(*corev1.Pod))
+				}), "failed to list pods")
+			}
+
+			pods, err := k.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list pods")
+			}
+			listed = append(listed, pods.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.Pods = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers"}, func(ctx context.Context) error {
+		var listed []v1.HorizontalPodAutoscaler
+		err := inNamespaces("horizontalpodautoscalers.autoscaling", func(ns string) error {
+			hpas, err := k.AutoscalingV1().HorizontalPodAutoscalers(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list hpas")
+			}
+			listed = append(listed, hpas.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.HPAs = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Resource: "services"}, func(ctx context.Context) error {
+		var listed []corev1.Service
+		err := inNamespaces("services", func(ns string) error {
+			services, err := k.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list services")
+			}
+			listed = append(listed, services.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.Services = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "apps", Resource: "statefulsets"}, func(ctx context.Context) error {
+		var listed []appsv1.StatefulSet
+		err := inNamespaces("statefulsets.apps", func(ns string) error {
+			statefulSets, err := k.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list statefulsets")
+			}
+			listed = append(listed, statefulSets.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.StatefulSets = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "apps", Resource: "deployments"}, func(ctx context.Context) error {
+		var listed []appsv1.Deployment
+		err := inNamespaces("deployments.apps", func(ns string) error {
+			deployments, err := k.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list deployments")
+			}
+			listed = append(listed, deployments.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.Deployments = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "apps", Resource: "daemonsets"}, func(ctx context.Context) error {
+		var listed []appsv1.DaemonSet
+		err := inNamespaces("daemonsets.apps", func(ns string) error {
+			daemonSets, err := k.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list daemonsets")
+			}
+			listed = append(listed, daemonSets.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.DaemonSets = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "batch", Resource: "jobs"}, func(ctx context.Context) error {
+		var listed []batchv1.Job
+		err := inNamespaces("jobs.batch", func(ns string) error {
+			if cfg.LowMemory {
+				return errors.Wrap(listPaged(ctx, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+					return k.BatchV1().Jobs(ns).List(ctx, opts)
+				}, func(obj runtime.Object) {
+					listed = append(listed, *obj.(*batchv1.Job))
+				}), "failed to list jobs")
+			}
+
+			jobs, err := k.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list jobs")
+			}
+			listed = append(listed, jobs.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.Jobs = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "batch", Resource: "cronjobs"}, func(ctx context.Context) error {
+		var listed []batchv1.CronJob
+		err := inNamespaces("cronjobs.batch", func(ns string) error {
+			cronJobs, err := k.BatchV1().CronJobs(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list cronjobs")
+			}
+			listed = append(listed, cronJobs.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.CronJobs = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Resource: "secrets"}, func(ctx context.Context) error {
+		var listed []corev1.Secret
+		err := inNamespaces("secrets", func(ns string) error {
+			if cfg.LowMemory {
+				return errors.Wrap(listPaged(ctx, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+					return k.CoreV1().Secrets(ns).List(ctx, opts)
+				}, func(obj runtime.Object) {
+					listed = append(listed, *obj.(*corev1.Secret))
+				}), "failed to list secrets")
+			}
+
+			secrets, err := k.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list secrets")
+			}
+			listed = append(listed, secrets.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.Secrets = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Resource: "serviceaccounts"}, func(ctx context.Context) error {
+		var listed []corev1.ServiceAccount
+		err := inNamespaces("serviceaccounts", func(ns string) error {
+			serviceAccounts, err := k.CoreV1().ServiceAccounts(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list serviceaccounts")
+			}
+			listed = append(listed, serviceAccounts.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.ServiceAccounts = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Resource: "nodes"}, func(ctx context.Context) error {
+		nodes, err := k.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list nodes")
+		}
+		c.Nodes = nodes.Items
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: "coordination.k8s.io", Resource: "leases"}, func(ctx context.Context) error {
+		leases, err := k.CoordinationV1().Leases(corev1.NamespaceNodeLease).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list node leases")
+		}
+		c.NodeLeases = leases.Items
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Resource: "events"}, func(ctx context.Context) error {
+		if cfg.LowMemory {
+			var listed []corev1.Event
+			err := listPaged(ctx, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+				opts.FieldSelector = "involvedObject.kind=Node"
+				return k.CoreV1().Events(metav1.NamespaceAll).List(ctx, opts)
+			}, func(obj runtime.Object) {
+				listed = append(listed, *obj.(*corev1.Event))
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to list node events")
+			}
+			c.NodeEvents = listed
+			return nil
+		}
+
+		nodeEvents, err := k.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			FieldSelector: "involvedObject.kind=Node",
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to list node events")
+		}
+		c.NodeEvents = nodeEvents.Items
+		return nil
+	})
+
+	list(Permission{Verb: "list", Resource: "persistentvolumeclaims"}, func(ctx context.Context) error {
+		var listed []corev1.PersistentVolumeClaim
+		err := inNamespaces("persistentvolumeclaims", func(ns string) error {
+			pvcs, err := k.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list persistentvolumeclaims")
+			}
+			listed = append(listed, pvcs.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.PersistentVolumeClaims = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Resource: "events"}, func(ctx context.Context) error {
+		var listed []corev1.Event
+		err := inNamespaces("events", func(ns string) error {
+			pvcEvents, err := k.CoreV1().Events(ns).List(ctx, metav1.ListOptions{
+				FieldSelector: "involvedObject.kind=PersistentVolumeClaim",
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to list persistentvolumeclaim events")
+			}
+			listed = append(listed, pvcEvents.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.PVCEvents = listed
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "storage.k8s.io", Resource: "storageclasses"}, func(ctx context.Context) error {
+		classes, err := k.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list storageclasses")
+		}
+		c.StorageClasses = classes.Items
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: "storage.k8s.io", Resource: "csidrivers"}, func(ctx context.Context) error {
+		drivers, err := k.StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list csidrivers")
+		}
+		c.CSIDrivers = drivers.Items
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: "storage.k8s.io", Resource: "csinodes"}, func(ctx context.Context) error {
+		csiNodes, err := k.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list csinodes")
+		}
+		c.CSINodes = csiNodes.Items
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: "storage.k8s.io", Resource: "volumeattachments"}, func(ctx context.Context) error {
+		attachments, err := k.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list volumeattachments")
+		}
+		c.VolumeAttachments = attachments.Items
+		return nil
+	})
+
+	// Image pulls are summarized by image across the whole cluster, which
+	// the first shard checks
+	listClusterScoped(Permission{Verb: "list", Resource: "events"}, func(ctx context.Context) error {
+		pullEvents := make([]corev1.Event, 0)
+		for _, reason := range []string{pullingReason, pulledReason} {
+			events, err := k.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+				FieldSelector: "involvedObject.kind=Pod,reason=" + reason,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to list image pull events")
+			}
+			for i := range events.Items {
+				// Filter again in case the field selector wasn't applied
+				if events.Items[i].Reason == reason {
+					pullEvents = append(pullEvents, events.Items[i])
+				}
+			}
+		}
+		c.ImagePulls = imagePulls(pullEvents)
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "policy", Resource: "poddisruptionbudgets"}, func(ctx context.Context) error {
+		var listed []policyv1.PodDisruptionBudget
+		err := inNamespaces("poddisruptionbudgets.policy", func(ns string) error {
+			pdbs, err := k.PolicyV1().PodDisruptionBudgets(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list poddisruptionbudgets")
+			}
+			listed = append(listed, pdbs.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.PodDisruptionBudgets = listed
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: "certificates.k8s.io", Resource: "certificatesigningrequests"}, func(ctx context.Context) error {
+		csrs, err := k.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list certificatesigningrequests")
+		}
+		c.CertificateSigningRequests = csrs.Items
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "rbac.authorization.k8s.io", Resource: "roles"}, func(ctx context.Context) error {
+		var listed []rbacv1.Role
+		err := inNamespaces("roles.rbac.authorization.k8s.io", func(ns string) error {
+			roles, err := k.RbacV1().Roles(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list roles")
+			}
+			listed = append(listed, roles.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.Roles = listed
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}, func(ctx context.Context) error {
+		clusterRoles, err := k.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list clusterroles")
+		}
+		c.ClusterRoles = clusterRoles.Items
+		return nil
+	})
+
+	list(Permission{Verb: "list", Group: "apiregistration.k8s.io", Resource: "apiservices"}, func(ctx context.Context) error {
+		apiServices, err := listAPIServices(ctx, k)
+		if err != nil {
+			return err
+		}
+		c.APIServices = apiServices
+		return nil
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: flowControlGroup, Resource: "flowschemas"}, func(ctx context.Context) error {
+		return listFlowControl(ctx, k, "flowschemas", &c.FlowSchemas)
+	})
+
+	listClusterScoped(Permission{Verb: "list", Group: flowControlGroup, Resource: "prioritylevelconfigurations"}, func(ctx context.Context) error {
+		return listFlowControl(ctx, k, "prioritylevelconfigurations", &c.PriorityLevels)
+	})
+
+	// autoscaling/v2 was added in Kubernetes 1.23
+	list(Permission{Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers"}, func(ctx context.Context) error {
+		var listed []autoscalingv2.HorizontalPodAutoscaler
+		err := inNamespaces("horizontalpodautoscalers.autoscaling", func(ns string) error {
+			hpas, err := k.AutoscalingV2().HorizontalPodAutoscalers(ns).List(ctx, metav1.ListOptions{})
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, "failed to list autoscaling/v2 hpas")
+			}
+			listed = append(listed, hpas.Items...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.HPAsV2 = listed
+		return nil
+	})
+
+	for _, l := range []struct {
+		resource *CustomResource
+		into     *[]unstructured.Unstructured
+	}{
+		{&ArgoRollouts, &c.Rollouts},
+		{&ArgoAnalysisRuns, &c.AnalysisRuns},
+		{&FlaggerCanaries, &c.Canaries},
+		{&PolicyReports, &c.PolicyReports},
+		{&ClusterPolicyReports, &c.ClusterPolicyReports},
+		{&TrivyVulnerabilityReports, &c.VulnerabilityReports},
+	} {
+		l := l
+
+		// ClusterPolicyReports are the only cluster scoped custom resources
+		in := namespaces
+		if l.resource == &ClusterPolicyReports {
+			if !cfg.Shard.owns("") {
+				continue
+			}
+			in = []string{metav1.NamespaceAll}
+		}
+		tolerate(l.resource.String(), func(ctx context.Context) error {
+			items, err := listCustomResources(ctx, k, l.resource, in)
+			if err != nil {
+				return err
+			}
+			*l.into = items
+			return nil
+		})
+	}
+
+	operatorResources := make([][]unstructured.Unstructured, len(OperatorPresets))
+	for i := range OperatorPresets {
+		p, into := &OperatorPresets[i], &operatorResources[i]
+		if cfg.DisabledOperatorPresets[p.Name] {
+			continue
+		}
+		tolerate(p.Resource.String(), func(ctx context.Context) error {
+			items, err := listCustomResources(ctx, k, &p.Resource, namespaces)
+			if err != nil {
+				return err
+			}
+			*into = items
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	reached := reachedAPIServer(builtin, c.Listed, c.Incomplete, len(namespaces))
+	sortListFailures(c.Incomplete)
+	c.Listed = listedResources(c.Listed, c.Incomplete)
+	for _, items := range operatorResources {
+		c.OperatorResources = append(c.OperatorResources, items...)
+	}
+
+	// Keep what was listed before the scan was interrupted so it can still
+	// be checked, nothing else can be gathered
+	if err != nil {
+		return c, errors.Wrap(ErrScanInterrupted, "interrupted while listing the cluster")
+	}
+
+	// A scan that couldn't list anything, e.g. because the API server
+	// can't be reached, has nothing to report as incomplete
+	if !reached && len(c.Incomplete) != 0 {
+		return nil, c.Incomplete[0].error()
+	}
+
+	// Everything else is gathered from the resources that were listed
+	c.HPAMetricErrors = gatherHPAMetricErrors(ctx, k, c)
+	c.KafkaUnderReplicatedPartitions = gatherKafkaUnderReplicatedPartitions(ctx, k, c)
+
+	// Nodes are only checked by the first shard, the others only need the
+	// stats of the nodes their pods run on
+	if cfg.ProbeKubeletCerts && cfg.Shard.owns("") {
+		c.KubeletCertificates = probeKubeletCertificates(ctx, c.Nodes)
+	}
+
+	if cfg.allowed(Permission{Verb: "get", Resource: "nodes", Subresource: "proxy"}) {
+		nodes := c.Nodes
+		if !cfg.Shard.owns("") {
+			nodes = podNodes(c.Nodes, c.Pods)
+		}
+		c.NodeStats = gatherNodeStats(ctx, k, nodes)
+	}
+
+	if cfg.allowed(Permission{Verb: "get", Resource: "pods", Subresource: "log"}) {
+		c.InitContainerLogs = gatherInitContainerLogs(ctx, k, c.Pods, cfg.InitContainerThreshold)
+	}
+
+	if cfg.Shard.owns("") {
+		c.ControlPlane = gatherControlPlane(ctx, k, c.Pods)
+		if cfg.allowed(Permission{Verb: "get", NonResourceURL: "/metrics"}) {
+			c.ControlPlane.APIFlow = gatherAPIFlow(ctx, k, c.FlowSchemas)
+		}
+	}
+	c.Distro = gatherDistro(k, cfg, c.Nodes)
+
+	// Taints are summarized against the pods of every namespace, which no
+	// shard lists, so sharded scans don't check them
+	if cfg.Shard == nil {
+		c.Taints = gatherTaints(c.Nodes, c.Pods)
+	}
+
+	return c, nil
+}
+
+// HasConfigMap returns true if a ConfigMap exists with the given name
+func (c *Cluster) HasConfigMap(namespace, name string) bool {
+	for i := range c.ConfigMaps {
+		if c.ConfigMaps[i].Namespace == namespace && c.ConfigMaps[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSecret returns true if a Secret exists with the given name
+func (c *Cluster) HasSecret(namespace, name string) bool {
+	for i := range c.Secrets {
+		if c.Secrets[i].Namespace == namespace && c.Secrets[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectPods returns the pods in a namespace that match the given
+// label selector, an empty selector matches nothing
+func (c *Cluster) SelectPods(namespace string, selector map[string]string) []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0)
+	if len(selector) == 0 {
+		return pods
+	}
+
+	sel := labels.SelectorFromSet(selector)
+	for _, p := range c.podIndex().byNamespace[namespace] {
+		if sel.Matches(labels.Set(p.Labels)) {
+			pods = append(pods, p)
+		}
+	}
+	return pods
+}
+
+// ServicePods returns the pods a Service selects
+func (c *Cluster) ServicePods(svc *corev1.Service) []*corev1.Pod {
+	return c.podIndex().byService[svc.Namespace+"/"+svc.Name]
+}
+
+// PodServices returns the Services that select a pod
+func (c *Cluster) PodServices(pod *corev1.Pod) []*corev1.Service {
+	return c.podIndex().services[pod.Namespace+"/"+pod.Name]
+}
+
+// ControlledPods returns the pods whose controller has the given UID
+func (c *Cluster) ControlledPods(uid types.UID) []*corev1.Pod {
+	return c.podIndex().byController[uid]
+}
+
+// GetService returns the Service with the given name, if it exists
+func (c *Cluster) GetService(namespace, name string) (*corev1.Service, bool) {
+	svc, ok := c.objectIndex().services[namespace+"/"+name]
+	return svc, ok
+}
+
+// GetNamespace returns the namespace with the given name, or nil if it
+// doesn't exist
+func (c *Cluster) GetNamespace(name string) *corev1.Namespace {
+	return c.objectIndex().namespaces[name]
+}
+
+// GetNode returns the node with the given name, or nil if it doesn't exist
+func (c *Cluster) GetNode(name string) *corev1.Node {
+	return c.objectIndex().nodes[name]
+}
+
+// GetPod returns the pod with the given namespace and name, or nil if it
+// doesn't exist
+func (c *Cluster) GetPod(namespace, name string) *corev1.Pod {
+	return c.podIndex().byName[namespace+"/"+name]
+}
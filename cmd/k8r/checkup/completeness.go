@@ -0,0 +1,148 @@
+// Description: This file contains code for reporting the resources that
+// couldn't be listed, so that a partial scan isn't mistaken for a
+// healthy cluster
+
+package checkup
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// listForbidden is the reason resources the RBAC preflight found the
+// permission to list missing for weren't listed
+const listForbidden = "forbidden by RBAC"
+
+// ListFailure is a kind of resource that couldn't be listed
+type ListFailure struct {
+	// Resource is the resource with its group, e.g. statefulsets.apps
+	Resource string
+
+	// Namespace is the namespace that couldn't be listed, empty when the
+	// resource couldn't be listed in any namespace
+	Namespace string
+
+	// Reason is why the resource couldn't be listed
+	Reason string
+
+	// err is the error listing failed with, if any
+	err error
+}
+
+// String returns the resource and the namespace it couldn't be listed in,
+// e.g. pods in payments
+func (f *ListFailure) String() string {
+	if f.Namespace == "" {
+		return f.Resource
+	}
+	return f.Resource + " in " + f.Namespace
+}
+
+// error returns the error listing failed with
+func (f *ListFailure) error() error {
+	if f.err == nil {
+		return errors.Errorf("failed to list %s: %s", f, f.Reason)
+	}
+	return errors.Wrapf(f.err, "failed to list %s", f)
+}
+
+// fatalListError returns true if err fails the whole scan instead of
+// leaving it incomplete, which is when the cluster rejected the
+// credentials and every other list would fail the same way
+func fatalListError(err error) bool {
+	return apierrors.IsUnauthorized(err)
+}
+
+// sortListFailures sorts failures by resource and namespace, resources are
+// listed concurrently
+func sortListFailures(failures []ListFailure) {
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Resource != failures[j].Resource {
+			return failures[i].Resource < failures[j].Resource
+		}
+		return failures[i].Namespace < failures[j].Namespace
+	})
+}
+
+// reachedAPIServer returns true if any of the built-in resources was
+// listed in at least one namespace. Custom resources don't count, they
+// look uninstalled when discovery fails.
+func reachedAPIServer(builtin map[string]bool, listed []string, failures []ListFailure, namespaces int) bool {
+	failed := make(map[string]int, len(failures))
+	for i := range failures {
+		failed[failures[i].Resource]++
+	}
+	for _, r := range listed {
+		if builtin[r] && failed[r] < namespaces {
+			return true
+		}
+	}
+	return false
+}
+
+// listedResources sorts the resources that were listed and drops
+// duplicates, e.g. events which are listed once for each kind of object
+// they are about. Resources that also failed to list are left out, as
+// only part of them was listed.
+func listedResources(listed []string, failures []ListFailure) []string {
+	failed := make(map[string]bool, len(failures))
+	for i := range failures {
+		failed[failures[i].Resource] = true
+	}
+
+	sort.Strings(listed)
+	out := make([]string, 0, len(listed))
+	for i, r := range listed {
+		if (i == 0 || r != listed[i-1]) && !failed[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// incomplete returns the resources that couldn't be listed in the scan
+func (o *Options) incomplete() []ListFailure {
+	if o.cfg.Cluster == nil {
+		return nil
+	}
+	return o.cfg.Cluster.Incomplete
+}
+
+// printIncomplete prints the resources that couldn't be listed, which
+// problems may be hiding in
+func (o *Options) printIncomplete() {
+	// Resources the RBAC preflight skipped were already reported as
+	// missing permissions
+	failures := make([]ListFailure, 0)
+	for _, f := range o.incomplete() {
+		if f.Reason != listForbidden {
+			failures = append(failures, f)
+		}
+	}
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, color.New(color.Bold, color.FgYellow).Sprint(
+		"⚠️  Scan incomplete, these resources couldn't be listed and weren't checked:"))
+	tw := tabwriter.NewWriter(o.out, 1, 0, 1, ' ', 0)
+	for i := range failures {
+		fmt.Fprintf(tw, "    - %s:\t%s\n", &failures[i], failures[i].Reason)
+	}
+	tw.Flush()
+}
+
+// incompleteError returns ErrScanIncomplete when resources couldn't be
+// listed and the fail-on-incomplete flag is set, err otherwise
+func (o *Options) incompleteError(err error) error {
+	if err != nil || !o.cfg.FailOnIncomplete || len(o.incomplete()) == 0 {
+		return err
+	}
+	return ErrScanIncomplete
+}
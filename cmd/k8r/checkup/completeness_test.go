@@ -0,0 +1,192 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestScanIncomplete(t *testing.T) {
+	forbidden := func(r schema.GroupResource) error { return apierrors.NewForbidden(r, "", errors.New("no access")) }
+	timeout := func(schema.GroupResource) error { return apierrors.NewTimeoutError("etcdserver: request timed out", 0) }
+
+	tests := []struct {
+		name             string
+		failing          map[string]func(schema.GroupResource) error
+		failOnIncomplete bool
+		wantErr          error
+		want             []string
+	}{
+		{
+			name:    "forbidden",
+			failing: map[string]func(schema.GroupResource) error{"statefulsets": forbidden, "events": forbidden},
+			want:    []string{"statefulsets.apps is forbidden: no access"},
+		},
+		{
+			name:             "fail on incomplete",
+			failing:          map[string]func(schema.GroupResource) error{"statefulsets": forbidden, "events": forbidden},
+			failOnIncomplete: true,
+			wantErr:          checkup.ErrScanIncomplete,
+			want:             []string{"statefulsets.apps is forbidden: no access"},
+		},
+		{
+			name:    "timeout",
+			failing: map[string]func(schema.GroupResource) error{"statefulsets": timeout},
+			want:    []string{"statefulsets.apps:", "etcdserver: request timed out"},
+		},
+		{
+			name:             "pods forbidden",
+			failing:          map[string]func(schema.GroupResource) error{"pods": forbidden},
+			failOnIncomplete: true,
+			wantErr:          checkup.ErrScanIncomplete,
+			want:             []string{"pods is forbidden: no access"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			k := newClientset([]runtime.Object{checkuptest.NewPod("api")}, nil)
+			k.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				r := action.GetResource().GroupResource()
+				if fail, ok := tt.failing[r.Resource]; ok {
+					return true, nil, fail(r)
+				}
+				return false, nil, nil
+			})
+
+			cfg := checkuptest.NewConfig(nil)
+			cfg.FailOnIncomplete = tt.failOnIncomplete
+
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(cfg, &out)
+
+			err := o.RunWithClient(context.Background(), k)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RunWithClient() error = %v, expected %v", err, tt.wantErr)
+			}
+			for _, want := range append([]string{
+				"Scan incomplete",
+				"No problems found in the resources that were listed",
+			}, tt.want...) {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output = %q, expected it to contain %q", out.String(), want)
+				}
+			}
+			// Events are listed once for each kind of object they are about
+			if n := strings.Count(out.String(), "- events:"); tt.failing["events"] != nil && n != 1 {
+				t.Errorf("output = %q, expected events to be listed as incomplete once, not %d times", out.String(), n)
+			}
+			if strings.Contains(out.String(), "Everything looks good") {
+				t.Errorf("output = %q, expected an incomplete scan not to look good", out.String())
+			}
+		})
+	}
+}
+
+func TestScanIncompleteNamespace(t *testing.T) {
+	objs := []runtime.Object{
+		checkuptest.NewNamespace(checkuptest.DefaultNamespace, nil),
+		checkuptest.NewNamespace("payments", nil),
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+		checkuptest.NewPod("ledger", checkuptest.InNamespace("payments"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+	k := newClientset(objs, nil)
+	k.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "payments" {
+			return true, nil, apierrors.NewTimeoutError("etcdserver: request timed out", 0)
+		}
+		return false, nil, nil
+	})
+
+	// Sharded scans list namespaced resources one namespace at a time
+	cfg := checkuptest.NewConfig(nil)
+	cfg.Shard = &checkup.Shard{Index: 1, Count: 1}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	err := o.RunWithClient(context.Background(), k)
+	if !errors.Is(err, checkup.ErrProblemsFound) {
+		t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+	}
+	for _, want := range []string{"default/api", "pods in payments:", "etcdserver: request timed out"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, expected it to contain %q", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "payments/ledger") {
+		t.Errorf("output = %q, expected the pods in payments not to be checked", out.String())
+	}
+}
+
+func TestScanListFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		err      error
+		wantErr  string
+	}{
+		{
+			name:     "unauthorized",
+			resource: "statefulsets",
+			err:      apierrors.NewUnauthorized("token expired"),
+			wantErr:  "the cluster rejected the credentials in your kubeconfig",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			k := newClientset([]runtime.Object{checkuptest.NewPod("api")}, nil)
+			k.PrependReactor("list", tt.resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, tt.err
+			})
+
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(checkuptest.NewConfig(nil), &out)
+
+			err := kube.ExplainError(o.RunWithClient(context.Background(), k))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("RunWithClient() error = %v, expected it to contain %q", err, tt.wantErr)
+			}
+			if strings.Contains(out.String(), "Scan incomplete") {
+				t.Errorf("output = %q, expected a failed scan not to be reported", out.String())
+			}
+		})
+	}
+}
+
+func TestScanUnreachable(t *testing.T) {
+	// Nothing listens on port 1, so every list is refused
+	k, err := kubernetes.NewForConfig(&rest.Config{Host: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatalf("NewForConfig() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(checkuptest.NewConfig(nil), &out)
+
+	err = o.RunWithClient(context.Background(), k)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("RunWithClient() error = %v, expected it to contain %q", err, "connection refused")
+	}
+	if strings.Contains(out.String(), "Scan incomplete") {
+		t.Errorf("output = %q, expected a scan that listed nothing not to be reported", out.String())
+	}
+}
@@ -0,0 +1,78 @@
+// Description: This file contains code for consolidating the problems
+// found on a single pod into one entry
+
+package checkup
+
+// containerProblemPriority are the problems that describe the state of a
+// pod's containers, in the order they are preferred as the main problem
+// when a pod has several of them. A container that is OOM killed over and
+// over shows up under all of these at once.
+var containerProblemPriority = []string{
+	ProblemPodCrashLoopBackOff.ID,
+	ProblemPodImagePullBackOff.ID,
+	ProblemPodOOMKilled.ID,
+	ProblemPodNotReady.ID,
+	ProblemHighRestarts.ID,
+}
+
+// consolidateContainerProblems merges the container problems found on the
+// same pod into a single entry for the highest priority problem, the rest
+// are kept as related problems of that entry
+func consolidateContainerProblems(resources []Resource) []Resource {
+	priority := make(map[string]int, len(containerProblemPriority))
+	for i, id := range containerProblemPriority {
+		priority[id] = i
+	}
+
+	// Find the highest priority problem for each resource
+	type key struct{ typ, name, source string }
+	primary := make(map[key]int)
+	for i := range resources {
+		r := &resources[i]
+		p, ok := priority[r.ProblemID]
+		if !ok {
+			continue
+		}
+
+		k := key{r.Type, r.Name, r.Source}
+		if j, ok := primary[k]; !ok || p < priority[resources[j].ProblemID] {
+			primary[k] = i
+		}
+	}
+
+	consolidated := make([]Resource, 0, len(resources))
+	for i := range resources {
+		r := &resources[i]
+		if _, ok := priority[r.ProblemID]; !ok || primary[key{r.Type, r.Name, r.Source}] == i {
+			consolidated = append(consolidated, *r)
+		}
+	}
+
+	// Attach everything else to the entry for its resource
+	index := make(map[key]int)
+	for i := range consolidated {
+		r := &consolidated[i]
+		if _, ok := priority[r.ProblemID]; ok {
+			index[key{r.Type, r.Name, r.Source}] = i
+		}
+	}
+	for i := range resources {
+		r := &resources[i]
+		k := key{r.Type, r.Name, r.Source}
+		if _, ok := priority[r.ProblemID]; !ok || primary[k] == i {
+			continue
+		}
+
+		entry := &consolidated[index[k]]
+		entry.Related = append(entry.Related, *r)
+
+		// The entry is as severe as the most severe of its problems, so
+		// the min-severity flag doesn't leave out the ones it merged
+		if sev := r.GetSeverity(); !entry.GetSeverity().AtLeast(sev) {
+			entry.Severity = sev
+		}
+		entry.Warning = entry.Warning && r.Warning
+	}
+
+	return consolidated
+}
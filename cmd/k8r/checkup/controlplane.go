@@ -0,0 +1,222 @@
+// Description: This file contains code for gathering the health of
+// control plane components that aren't represented by Kubernetes objects
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// etcd constants
+const (
+	// etcdMetricsPort is the port kubeadm configures etcd to serve
+	// metrics on over plain http
+	etcdMetricsPort = "2381"
+
+	// etcdComponentLabel is the label kubeadm puts on etcd static pods
+	etcdComponentLabel = "component"
+)
+
+// ControlPlane is the health of the control plane, it is checked for
+// problems like any other resource
+type ControlPlane struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	// EtcdReady is true if the API server reported etcd as ready
+	EtcdReady bool
+
+	// EtcdReadyMessage is the output of the API server's etcd
+	// readiness check when it isn't ready
+	EtcdReadyMessage string
+
+	// EtcdMembers are the etcd members that metrics were found for
+	EtcdMembers []EtcdMember
+
+	// APIFlow is how the API server queues k8r's own requests, nil when
+	// it couldn't be read
+	APIFlow *APIFlow
+}
+
+// DeepCopyObject implements runtime.Object
+func (c *ControlPlane) DeepCopyObject() runtime.Object {
+	out := *c
+	c.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.EtcdMembers = append([]EtcdMember(nil), c.EtcdMembers...)
+	if c.APIFlow != nil {
+		flow := *c.APIFlow
+		out.APIFlow = &flow
+	}
+	return &out
+}
+
+// EtcdMember is the health of a single etcd member, any value that
+// couldn't be read is left as 0
+type EtcdMember struct {
+	// Name is the name of the member, e.g. the etcd pod or the
+	// endpoint the API server talks to
+	Name string
+
+	// DBSizeBytes is the size of the etcd database
+	DBSizeBytes float64
+
+	// QuotaBytes is the backend quota of the etcd database
+	QuotaBytes float64
+
+	// WALFsyncP99Seconds is the 99th percentile duration of WAL fsyncs
+	WALFsyncP99Seconds float64
+
+	// HasLeader is false if the member reported that it has no leader
+	HasLeader bool
+}
+
+// parseMetrics parses a Prometheus text exposition
+func parseMetrics(body []byte) (map[string]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(bytes.NewReader(body))
+}
+
+// metricValue returns the value of a gauge, counter or untyped metric
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Untyped != nil:
+		return m.Untyped.GetValue()
+	}
+	return 0
+}
+
+// metricLabel returns the value of a label on a metric
+func metricLabel(m *dto.Metric, name string) string {
+	for _, l := range m.Label {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+// histogramQuantile estimates a quantile of a histogram from its buckets
+func histogramQuantile(q float64, h *dto.Histogram) float64 {
+	if h == nil || h.GetSampleCount() == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.GetSampleCount())
+	for _, b := range h.Bucket {
+		if float64(b.GetCumulativeCount()) >= rank && !math.IsInf(b.GetUpperBound(), 1) {
+			return b.GetUpperBound()
+		}
+	}
+
+	// The quantile falls in the +Inf bucket, the best we can do is the
+	// largest finite bound
+	if n := len(h.Bucket); n > 0 {
+		return h.Bucket[n-1].GetUpperBound()
+	}
+	return 0
+}
+
+// etcdMemberFromMetrics reads the health of an etcd member from the
+// metrics that etcd itself exposes
+func etcdMemberFromMetrics(name string, families map[string]*dto.MetricFamily) EtcdMember {
+	member := EtcdMember{Name: name, HasLeader: true}
+	if f, ok := families["etcd_mvcc_db_total_size_in_bytes"]; ok && len(f.Metric) > 0 {
+		member.DBSizeBytes = metricValue(f.Metric[0])
+	}
+	if f, ok := families["etcd_server_quota_backend_bytes"]; ok && len(f.Metric) > 0 {
+		member.QuotaBytes = metricValue(f.Metric[0])
+	}
+	if f, ok := families["etcd_disk_wal_fsync_duration_seconds"]; ok && len(f.Metric) > 0 {
+		member.WALFsyncP99Seconds = histogramQuantile(0.99, f.Metric[0].Histogram)
+	}
+	if f, ok := families["etcd_server_has_leader"]; ok && len(f.Metric) > 0 {
+		member.HasLeader = metricValue(f.Metric[0]) != 0
+	}
+	return member
+}
+
+// gatherControlPlane gathers the health of the control plane. Everything
+// here is best effort, managed control planes usually don't expose etcd
+// at all, so anything that can't be read is left empty.
+func gatherControlPlane(ctx context.Context, k kubernetes.Interface, pods []corev1.Pod) *ControlPlane {
+	cp := &ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "control-plane"},
+		EtcdReady:  true,
+	}
+
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return cp
+	}
+
+	body, err := rc.Get().AbsPath("/readyz/etcd").Do(ctx).Raw()
+	if err != nil {
+		cp.EtcdReady = false
+		cp.EtcdReadyMessage = strings.TrimSpace(string(body))
+		if cp.EtcdReadyMessage == "" {
+			cp.EtcdReadyMessage = err.Error()
+		}
+	}
+
+	// Self-hosted etcd, e.g. kubeadm, exposes its own metrics which are
+	// the most detailed source
+	for i := range pods {
+		p := &pods[i]
+		if p.Namespace != metav1.NamespaceSystem || p.Labels[etcdComponentLabel] != "etcd" || p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		proxy := k.CoreV1().Pods(p.Namespace).ProxyGet("http", p.Name, etcdMetricsPort, "/metrics", nil)
+		if proxy == nil {
+			continue
+		}
+		body, err := proxy.DoRaw(ctx)
+		if err != nil {
+			continue
+		}
+		families, err := parseMetrics(body)
+		if err != nil {
+			continue
+		}
+		cp.EtcdMembers = append(cp.EtcdMembers, etcdMemberFromMetrics(p.Name, families))
+	}
+	if len(cp.EtcdMembers) > 0 {
+		return cp
+	}
+
+	// Otherwise fall back to the database size the API server reports
+	// for each of its etcd endpoints
+	body, err = rc.Get().AbsPath("/metrics").Do(ctx).Raw()
+	if err != nil {
+		return cp
+	}
+	families, err := parseMetrics(body)
+	if err != nil {
+		return cp
+	}
+	if f, ok := families["apiserver_storage_db_total_size_in_bytes"]; ok {
+		for _, m := range f.Metric {
+			cp.EtcdMembers = append(cp.EtcdMembers, EtcdMember{
+				Name:        metricLabel(m, "endpoint"),
+				DBSizeBytes: metricValue(m),
+				HasLeader:   true,
+			})
+		}
+	}
+
+	return cp
+}
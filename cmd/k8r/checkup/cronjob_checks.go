@@ -0,0 +1,400 @@
+// Description: This file contains code for problems with CronJobs, which
+// fail silently as nothing is watching for runs that didn't happen
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// scheduleSamples is the number of runs the interval of a schedule is
+// measured over, irregular schedules use the longest gap between them
+const scheduleSamples = 8
+
+// cronSchedule parses the schedule of a CronJob in its time zone, which is
+// the controller manager's, usually UTC, when it isn't set
+func cronSchedule(cj *batchv1.CronJob) (cron.Schedule, error) {
+	spec := cj.Spec.Schedule
+	if cj.Spec.TimeZone != nil && *cj.Spec.TimeZone != "" {
+		spec = "CRON_TZ=" + *cj.Spec.TimeZone + " " + spec
+	}
+	return cron.ParseStandard(spec)
+}
+
+// scheduleGaps returns the shortest and longest time between the runs of a
+// schedule after the given time, 0 if it doesn't run
+func scheduleGaps(s cron.Schedule, after time.Time) (shortest, longest time.Duration) {
+	last := s.Next(after)
+	for i := 0; i < scheduleSamples && !last.IsZero(); i++ {
+		next := s.Next(last)
+		if next.IsZero() {
+			break
+		}
+		gap := next.Sub(last)
+		if gap > longest {
+			longest = gap
+		}
+		if shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		last = next
+	}
+	return shortest, longest
+}
+
+// cronJobJobs returns the Jobs a CronJob created, newest first
+func cronJobJobs(cj *batchv1.CronJob, jobs []batchv1.Job) []*batchv1.Job {
+	owned := make([]*batchv1.Job, 0)
+	for i := range jobs {
+		j := &jobs[i]
+		if j.Namespace != cj.Namespace {
+			continue
+		}
+		if ref, ok := ownerRef(j.OwnerReferences); ok && ref.Kind == "CronJob" && ref.Name == cj.Name {
+			owned = append(owned, j)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].CreationTimestamp.After(owned[j].CreationTimestamp.Time)
+	})
+	return owned
+}
+
+// jobFailure returns the Failed condition of a Job, if it failed
+func jobFailure(job *batchv1.Job) (*batchv1.JobCondition, bool) {
+	for i := range job.Status.Conditions {
+		c := &job.Status.Conditions[i]
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// lastSuccess formats when a CronJob last succeeded
+func lastSuccess(cj *batchv1.CronJob) string {
+	if cj.Status.LastSuccessfulTime == nil {
+		return "it has never succeeded"
+	}
+	t := cj.Status.LastSuccessfulTime.Time
+	return fmt.Sprintf("it last succeeded at %s (%s ago)", t.UTC().Format(time.RFC3339), time.Since(t).Round(time.Minute))
+}
+
+// ProblemCronJobStale is a problem with a CronJob whose most recent Job
+// failed, or that hasn't succeeded for several of its schedule's intervals
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobStale
+var ProblemCronJobStale = Problem{
+	ID:               "CronJobStale",
+	ShortDescription: "A CronJob's last run failed or it hasn't succeeded for several scheduled runs",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobStale",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cj, ok := obj.(*batchv1.CronJob)
+		if !ok || (cj.Spec.Suspend != nil && *cj.Spec.Suspend) {
+			return "", false, false
+		}
+
+		if cfg.Cluster != nil {
+			if jobs := cronJobJobs(cj, cfg.Cluster.Jobs); len(jobs) != 0 {
+				if failed, ok := jobFailure(jobs[0]); ok {
+					details := fmt.Sprintf("Last run %s failed (%s", jobs[0].Name, failed.Reason)
+					if failed.Message != "" {
+						details += ": " + failed.Message
+					}
+					return details + fmt.Sprintf("), schedule %q, %s", cj.Spec.Schedule, lastSuccess(cj)), false, true
+				}
+			}
+		}
+
+		// Schedules that can't be parsed or never fire have no interval
+		schedule, err := cronSchedule(cj)
+		if err != nil || cfg.CronJobStaleMultiple <= 0 {
+			return "", false, false
+		}
+
+		since := cj.CreationTimestamp.Time
+		if cj.Status.LastSuccessfulTime != nil {
+			since = cj.Status.LastSuccessfulTime.Time
+		}
+		_, interval := scheduleGaps(schedule, since)
+		if since.IsZero() || interval == 0 {
+			return "", false, false
+		}
+
+		allowed := time.Duration(cfg.CronJobStaleMultiple) * interval
+		if time.Since(since) <= allowed {
+			return "", false, false
+		}
+		return fmt.Sprintf("No successful run in over %d scheduled intervals of %s, schedule %q, %s",
+			cfg.CronJobStaleMultiple, interval, cj.Spec.Schedule, lastSuccess(cj)), false, true
+	},
+}
+
+// ProblemCronJobScheduleInvalid is a problem with a CronJob whose schedule
+// can't be parsed or never fires, e.g. on February 30th, which the API
+// server accepts
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobScheduleInvalid
+var ProblemCronJobScheduleInvalid = Problem{
+	ID:               "CronJobScheduleInvalid",
+	ShortDescription: "A CronJob's schedule is invalid or never fires, e.g. on a day that doesn't exist",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobScheduleInvalid",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cj, ok := obj.(*batchv1.CronJob)
+		if !ok || (cj.Spec.Suspend != nil && *cj.Spec.Suspend) {
+			return "", false, false
+		}
+
+		schedule, err := cronSchedule(cj)
+		if err != nil {
+			return fmt.Sprintf("Schedule %q can't be parsed, so the CronJob never runs: %v", cj.Spec.Schedule, err), true, true
+		}
+
+		// The cron library gives up looking for the next run after five
+		// years, e.g. for 0 0 30 2 *
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Sprintf("Schedule %q never fires, the days and months it lists never occur together",
+				cj.Spec.Schedule), true, true
+		}
+		return "", false, false
+	},
+}
+
+// longestRun returns the longest time one of the Jobs ran or, for active
+// Jobs, has been running
+func longestRun(jobs []*batchv1.Job) (time.Duration, *batchv1.Job) {
+	var longest time.Duration
+	var job *batchv1.Job
+	for _, j := range jobs {
+		if j.Status.StartTime == nil {
+			continue
+		}
+		end := time.Now()
+		switch {
+		case j.Status.CompletionTime != nil:
+			end = j.Status.CompletionTime.Time
+		case jobFinished(j):
+			continue
+		}
+		if d := end.Sub(j.Status.StartTime.Time); d > longest {
+			longest, job = d, j
+		}
+	}
+	return longest, job
+}
+
+// overlapConsequences are what happens when a run is due while the last
+// one is still running, by concurrency policy
+var overlapConsequences = map[batchv1.ConcurrencyPolicy]string{
+	batchv1.AllowConcurrent:   "runs overlap and pile up",
+	batchv1.ForbidConcurrent:  "runs are skipped while the last one is still running",
+	batchv1.ReplaceConcurrent: "runs are killed before they finish by the next one",
+}
+
+// ProblemCronJobOverlap is a problem with a CronJob whose runs take longer
+// than the time between them, so they overlap, are skipped or are killed
+// depending on its concurrency policy
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobOverlap
+var ProblemCronJobOverlap = Problem{
+	ID:               "CronJobOverlap",
+	ShortDescription: "A CronJob's runs take longer than its schedule's interval, so runs overlap or are skipped",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobOverlap",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cj, ok := obj.(*batchv1.CronJob)
+		if !ok || cfg.Cluster == nil || (cj.Spec.Suspend != nil && *cj.Spec.Suspend) {
+			return "", false, false
+		}
+
+		schedule, err := cronSchedule(cj)
+		if err != nil {
+			return "", false, false
+		}
+		interval, _ := scheduleGaps(schedule, time.Now())
+		longest, job := longestRun(cronJobJobs(cj, cfg.Cluster.Jobs))
+		if interval == 0 || job == nil || longest < interval {
+			return "", false, false
+		}
+
+		policy := cj.Spec.ConcurrencyPolicy
+		if policy == "" {
+			policy = batchv1.AllowConcurrent
+		}
+		return fmt.Sprintf("Run %s took %s but schedule %q runs every %s, with concurrencyPolicy %s %s, "+
+			"schedule it less often or make the Job faster",
+			job.Name, longest.Round(time.Second), cj.Spec.Schedule, interval, policy, overlapConsequences[policy]), true, true
+	},
+}
+
+// ProblemCronJobMissingTimeZone is a problem with a CronJob that doesn't
+// set a time zone when the require-cronjob-timezone flag is set, its
+// schedule then follows the controller manager's time zone
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobMissingTimeZone
+var ProblemCronJobMissingTimeZone = Problem{
+	ID:               "CronJobMissingTimeZone",
+	ShortDescription: "A CronJob doesn't set timeZone, so its schedule follows the control plane's time zone",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobMissingTimeZone",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cj, ok := obj.(*batchv1.CronJob)
+		if !ok || !cfg.RequireCronJobTimeZone || (cj.Spec.TimeZone != nil && *cj.Spec.TimeZone != "") {
+			return "", false, false
+		}
+		return fmt.Sprintf("Schedule %q has no timeZone, so it runs in the control plane's time zone, usually UTC, "+
+			"and shifts if that changes, set spec.timeZone, e.g. Etc/UTC", cj.Spec.Schedule), true, true
+	},
+}
+
+// cronJobMaxMissedRuns is how many missed runs the CronJob controller
+// tolerates when a CronJob has no startingDeadlineSeconds, with more it
+// stops scheduling the CronJob
+const cronJobMaxMissedRuns = 100
+
+// minMissedRuns is how many scheduled runs a CronJob has to be behind for
+// it to be reported, a single one can be a run that is just being created
+const minMissedRuns = 2
+
+// minFailedRuns is how many runs in a row have to have failed for the
+// failures to be reported as more than a one-off
+const minFailedRuns = 2
+
+// scheduledRuns returns how many runs a schedule has after one time up to
+// and including another, and the last of them. Counting stops after
+// limit runs.
+func scheduledRuns(s cron.Schedule, after, until time.Time, limit int) (int, time.Time) {
+	var last time.Time
+	n := 0
+	for next := s.Next(after); !next.IsZero() && !next.After(until) && n < limit; next = s.Next(next) {
+		last = next
+		n++
+	}
+	return n, last
+}
+
+// activeJobs returns the Jobs of a CronJob that haven't finished
+func activeJobs(jobs []*batchv1.Job) []*batchv1.Job {
+	active := make([]*batchv1.Job, 0)
+	for _, j := range jobs {
+		if !jobFinished(j) {
+			active = append(active, j)
+		}
+	}
+	return active
+}
+
+// ProblemCronJobMissedSchedule is a problem with a CronJob whose last
+// scheduled run is several of its schedule's runs behind, because the
+// CronJob controller isn't creating its Jobs. The details name the likely
+// reason, e.g. a run that never finishes blocking the next ones under
+// concurrencyPolicy Forbid.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobMissedSchedule
+var ProblemCronJobMissedSchedule = Problem{
+	ID:               "CronJobMissedSchedule",
+	ShortDescription: "A CronJob hasn't been scheduled for several of its scheduled runs",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobMissedSchedule",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cj, ok := obj.(*batchv1.CronJob)
+		if !ok || (cj.Spec.Suspend != nil && *cj.Spec.Suspend) {
+			return "", false, false
+		}
+		schedule, err := cronSchedule(cj)
+		if err != nil {
+			return "", false, false
+		}
+
+		last := cj.CreationTimestamp.Time
+		scheduled := "it has never been scheduled"
+		if cj.Status.LastScheduleTime != nil {
+			last = cj.Status.LastScheduleTime.Time
+			scheduled = fmt.Sprintf("it was last scheduled at %s", last.UTC().Format(time.RFC3339))
+		}
+		if last.IsZero() {
+			return "", false, false
+		}
+
+		missed, expected := scheduledRuns(schedule, last, time.Now(), cronJobMaxMissedRuns+1)
+		if missed < minMissedRuns {
+			return "", false, false
+		}
+		count := fmt.Sprintf("%d", missed)
+		if missed > cronJobMaxMissedRuns {
+			count = fmt.Sprintf("over %d", cronJobMaxMissedRuns)
+		}
+		details := fmt.Sprintf("Missed %s scheduled runs, schedule %q should have last run at %s but %s",
+			count, cj.Spec.Schedule, expected.UTC().Format(time.RFC3339), scheduled)
+
+		var active []*batchv1.Job
+		if cfg.Cluster != nil {
+			active = activeJobs(cronJobJobs(cj, cfg.Cluster.Jobs))
+		}
+		switch {
+		case len(active) != 0 && cj.Spec.ConcurrencyPolicy == batchv1.ForbidConcurrent:
+			details += fmt.Sprintf(", run %s is still active and concurrencyPolicy Forbid skips runs until it finishes", active[0].Name)
+		case missed > cronJobMaxMissedRuns && cj.Spec.StartingDeadlineSeconds == nil:
+			details += fmt.Sprintf(", the CronJob controller stops scheduling CronJobs that missed over %d runs, "+
+				"set spec.startingDeadlineSeconds so it only looks back that far", cronJobMaxMissedRuns)
+		case cj.Spec.StartingDeadlineSeconds != nil:
+			details += fmt.Sprintf(", runs that can't start within startingDeadlineSeconds (%ds) are skipped, "+
+				"check that the CronJob controller in kube-controller-manager is running", *cj.Spec.StartingDeadlineSeconds)
+		default:
+			details += ", check that the CronJob controller in kube-controller-manager is running"
+		}
+		return details, false, true
+	},
+}
+
+// ProblemCronJobRunsFailing is a problem with a CronJob whose runs keep
+// failing: every finished run that is still kept failed, and enough runs
+// were scheduled since it last succeeded that this isn't a one-off
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobRunsFailing
+var ProblemCronJobRunsFailing = Problem{
+	ID:               "CronJobRunsFailing",
+	ShortDescription: "A CronJob's recent runs have all failed",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CronJobRunsFailing",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cj, ok := obj.(*batchv1.CronJob)
+		if !ok || cfg.Cluster == nil || (cj.Spec.Suspend != nil && *cj.Spec.Suspend) {
+			return "", false, false
+		}
+
+		failed := make([]string, 0)
+		for _, j := range cronJobJobs(cj, cfg.Cluster.Jobs) {
+			if !jobFinished(j) {
+				continue
+			}
+			c, ok := jobFailure(j)
+			if !ok {
+				return "", false, false
+			}
+			failed = append(failed, fmt.Sprintf("%s (%s)", j.Name, c.Reason))
+		}
+		if len(failed) == 0 {
+			return "", false, false
+		}
+
+		// Only failedJobsHistoryLimit failed Jobs are kept, one by
+		// default, so the runs scheduled since the last success are
+		// counted from the schedule
+		runs := len(failed)
+		if schedule, err := cronSchedule(cj); err == nil && cj.Status.LastScheduleTime != nil {
+			since := cj.CreationTimestamp.Time
+			if cj.Status.LastSuccessfulTime != nil {
+				since = cj.Status.LastSuccessfulTime.Time
+			}
+			if n, _ := scheduledRuns(schedule, since, cj.Status.LastScheduleTime.Time, cronJobMaxMissedRuns); n > runs {
+				runs = n
+			}
+		}
+		if runs < minFailedRuns {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("%d runs were scheduled since the last success and every run that is kept failed: %s, schedule %q, %s",
+			runs, strings.Join(failed, ", "), cj.Spec.Schedule, lastSuccess(cj)), false, true
+	},
+}
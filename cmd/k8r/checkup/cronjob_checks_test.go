@@ -0,0 +1,244 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func ago(d time.Duration) *metav1.Time {
+	t := metav1.NewTime(time.Now().Add(-d))
+	return &t
+}
+
+func TestCronJobStale(t *testing.T) {
+	suspended := checkuptest.NewCronJob("report", "0 * * * *", ago(5*time.Hour))
+	yes := true
+	suspended.Spec.Suspend = &yes
+
+	neverSucceeded := checkuptest.NewCronJob("report", "0 * * * *", nil)
+	neverSucceeded.CreationTimestamp = *ago(48 * time.Hour)
+
+	withFailedRun := checkuptest.NewCronJob("report", "0 * * * *", ago(30*time.Minute))
+	failed := checkuptest.NewJob("report-28000001", 6)
+	failed.CreationTimestamp = *ago(10 * time.Minute)
+	failed.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "report"}}
+	failed.Status.Conditions = []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit",
+	}}
+	succeeded := checkuptest.NewJob("report-28000000", 6)
+	succeeded.CreationTimestamp = *ago(70 * time.Minute)
+	succeeded.OwnerReferences = failed.OwnerReferences
+
+	checkuptest.RunCases(t, checkup.ProblemCronJobStale, []checkuptest.Case{
+		{Name: "recent success", Object: checkuptest.NewCronJob("report", "0 * * * *", ago(30*time.Minute))},
+		{Name: "suspended", Object: suspended},
+		{Name: "daily within interval", Object: checkuptest.NewCronJob("report", "@daily", ago(30*time.Hour))},
+		{
+			Name:           "stale",
+			Object:         checkuptest.NewCronJob("report", "0 * * * *", ago(5*time.Hour)),
+			Occurring:      true,
+			DetailsContain: `No successful run in over 3 scheduled intervals of 1h0m0s, schedule "0 * * * *", it last succeeded at`,
+		},
+		{
+			Name:           "never succeeded",
+			Object:         neverSucceeded,
+			Occurring:      true,
+			DetailsContain: "it has never succeeded",
+		},
+		{
+			Name:           "last run failed",
+			Object:         withFailedRun,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(withFailedRun, succeeded, failed)),
+			Occurring:      true,
+			DetailsContain: `Last run report-28000001 failed (BackoffLimitExceeded: Job has reached the specified backoff limit), schedule "0 * * * *"`,
+		},
+	})
+}
+
+func TestCronJobScheduleInvalid(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemCronJobScheduleInvalid, []checkuptest.Case{
+		{Name: "valid", Object: checkuptest.NewCronJob("report", "*/5 * * * *", nil)},
+		{
+			Name:           "unparseable",
+			Object:         checkuptest.NewCronJob("report", "0 25 * * *", nil),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: `Schedule "0 25 * * *" can't be parsed`,
+		},
+		{
+			Name:           "february 30th",
+			Object:         checkuptest.NewCronJob("report", "0 0 30 2 *", nil),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: `Schedule "0 0 30 2 *" never fires`,
+		},
+	})
+}
+
+func TestCronJobOverlap(t *testing.T) {
+	run := func(name string, took time.Duration, active bool) *batchv1.Job {
+		j := checkuptest.NewJob(name, 6)
+		j.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "sync"}}
+		j.Status.StartTime = ago(took + time.Minute)
+		if !active {
+			j.Status.CompletionTime = ago(time.Minute)
+		}
+		return j
+	}
+	forbid := checkuptest.NewCronJob("sync", "*/5 * * * *", nil)
+	forbid.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+
+	cases := []struct {
+		name    string
+		cronJob *batchv1.CronJob
+		job     *batchv1.Job
+		want    string
+	}{
+		{name: "fast", cronJob: forbid, job: run("sync-1", time.Minute, false)},
+		{
+			name:    "slow under Forbid",
+			cronJob: forbid,
+			job:     run("sync-1", 12*time.Minute, false),
+			want:    `Run sync-1 took 12m0s but schedule "*/5 * * * *" runs every 5m0s, with concurrencyPolicy Forbid runs are skipped`,
+		},
+		{
+			name:    "still running under Allow",
+			cronJob: checkuptest.NewCronJob("sync", "*/5 * * * *", nil),
+			job:     run("sync-1", 20*time.Minute, true),
+			want:    "with concurrencyPolicy Allow runs overlap and pile up",
+		},
+	}
+	for _, tc := range cases {
+		checkuptest.RunCases(t, checkup.ProblemCronJobOverlap, []checkuptest.Case{{
+			Name:           tc.name,
+			Object:         tc.cronJob,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(tc.cronJob, tc.job)),
+			Occurring:      tc.want != "",
+			Warning:        true,
+			DetailsContain: tc.want,
+		}})
+	}
+}
+
+func TestCronJobMissingTimeZone(t *testing.T) {
+	utc := "Etc/UTC"
+	withTimeZone := checkuptest.NewCronJob("report", "0 6 * * *", nil)
+	withTimeZone.Spec.TimeZone = &utc
+
+	required := checkuptest.NewConfig(nil)
+	required.RequireCronJobTimeZone = true
+
+	checkuptest.RunCases(t, checkup.ProblemCronJobMissingTimeZone, []checkuptest.Case{
+		{Name: "not required", Object: checkuptest.NewCronJob("report", "0 6 * * *", nil)},
+		{Name: "set", Object: withTimeZone, Config: required},
+		{
+			Name:           "missing",
+			Object:         checkuptest.NewCronJob("report", "0 6 * * *", nil),
+			Config:         required,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: `Schedule "0 6 * * *" has no timeZone`,
+		},
+	})
+}
+
+func TestCronJobMissedSchedule(t *testing.T) {
+	// scheduled returns a CronJob that runs every 10 minutes and was last
+	// scheduled the given time ago
+	scheduled := func(last time.Duration) *batchv1.CronJob {
+		cj := checkuptest.NewCronJob("sync", "*/10 * * * *", nil)
+		cj.CreationTimestamp = *ago(24 * time.Hour)
+		cj.Status.LastScheduleTime = ago(last)
+		return cj
+	}
+	stuck := scheduled(45 * time.Minute)
+	stuck.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+	active := checkuptest.NewJob("sync-28000000", 6)
+	active.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "sync"}}
+
+	neverScheduled := checkuptest.NewCronJob("sync", "*/10 * * * *", nil)
+	neverScheduled.CreationTimestamp = *ago(24 * time.Hour)
+
+	checkuptest.RunCases(t, checkup.ProblemCronJobMissedSchedule, []checkuptest.Case{
+		{Name: "on schedule", Object: scheduled(5 * time.Minute)},
+		{Name: "no status", Object: checkuptest.NewCronJob("sync", "*/10 * * * *", nil)},
+		{
+			Name:           "behind",
+			Object:         scheduled(45 * time.Minute),
+			Occurring:      true,
+			DetailsContain: `scheduled runs, schedule "*/10 * * * *" should have last run at`,
+		},
+		{
+			Name:           "blocked by an active run",
+			Object:         stuck,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(stuck, active)),
+			Occurring:      true,
+			DetailsContain: "run sync-28000000 is still active and concurrencyPolicy Forbid skips runs until it finishes",
+		},
+		{
+			Name:           "too many missed runs",
+			Object:         neverScheduled,
+			Occurring:      true,
+			DetailsContain: "Missed over 100 scheduled runs",
+		},
+	})
+}
+
+func TestCronJobRunsFailing(t *testing.T) {
+	// run returns a finished Job of the CronJob, failed or succeeded
+	run := func(name string, created time.Duration, failed bool) *batchv1.Job {
+		j := checkuptest.NewJob(name, 6)
+		j.CreationTimestamp = *ago(created)
+		j.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "report"}}
+		j.Status.Active = 0
+		c := batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}
+		if failed {
+			c = batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}
+		}
+		j.Status.Conditions = []batchv1.JobCondition{c}
+		return j
+	}
+	cronJob := func(lastSuccess, lastSchedule time.Duration) *batchv1.CronJob {
+		cj := checkuptest.NewCronJob("report", "0 * * * *", ago(lastSuccess))
+		cj.Status.LastScheduleTime = ago(lastSchedule)
+		return cj
+	}
+
+	oneFailure := cronJob(70*time.Minute, 10*time.Minute)
+	failing := cronJob(5*time.Hour+10*time.Minute, 10*time.Minute)
+	failingKept := cronJob(10*time.Minute, 10*time.Minute)
+
+	checkuptest.RunCases(t, checkup.ProblemCronJobRunsFailing, []checkuptest.Case{
+		{
+			Name:   "one failure after a success",
+			Object: oneFailure,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(oneFailure, run("report-2", 10*time.Minute, true), run("report-1", 70*time.Minute, false))),
+		},
+		{
+			Name:   "mixed",
+			Object: failingKept,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(failingKept, run("report-2", 10*time.Minute, false), run("report-1", 70*time.Minute, true))),
+		},
+		{
+			Name:           "failing since the last success",
+			Object:         failing,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(failing, run("report-5", 10*time.Minute, true))),
+			Occurring:      true,
+			DetailsContain: "5 runs were scheduled since the last success and every run that is kept failed: report-5 (BackoffLimitExceeded)",
+		},
+		{
+			Name:   "all kept runs failed",
+			Object: failingKept,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(failingKept, run("report-2", 10*time.Minute, true),
+				run("report-1", 70*time.Minute, true))),
+			Occurring:      true,
+			DetailsContain: "report-2 (BackoffLimitExceeded), report-1 (BackoffLimitExceeded)",
+		},
+	})
+}
@@ -0,0 +1,271 @@
+// Description: This file contains code for problems with the storage
+// control plane, CSI drivers and the VolumeAttachments they work through,
+// tied back to the workloads whose volumes they affect
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// csiNodePluginSuffixes are how the DaemonSets of CSI node plugins are
+// usually named, e.g. ebs-csi-node, what comes before is shared with the
+// driver's controller, e.g. ebs-csi-controller
+var csiNodePluginSuffixes = []string{"-node", "-nodeplugin", "-node-plugin", "-plugin"}
+
+// mentionsCSIDriver returns true if a pod template refers to a CSI driver
+// by name, node plugins mount the driver's directory under
+// /var/lib/kubelet/plugins and controllers usually pass it as an argument
+func mentionsCSIDriver(spec *corev1.PodSpec, driver string) bool {
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil && strings.Contains(v.HostPath.Path, driver) {
+			return true
+		}
+	}
+	for _, c := range spec.Containers {
+		if strings.Contains(strings.Join(c.Args, " "), driver) || strings.Contains(strings.Join(c.Command, " "), driver) {
+			return true
+		}
+		for _, env := range c.Env {
+			if strings.Contains(env.Value, driver) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// csiDriverWorkload is a DaemonSet, Deployment or StatefulSet that runs
+// part of a CSI driver
+type csiDriverWorkload struct {
+	kind, namespace, name string
+	ready, desired        int32
+}
+
+// csiDriverWorkloads returns the workloads that run a CSI driver, the
+// ones that refer to it and the ones in the same namespace named like
+// its node plugin DaemonSet
+func csiDriverWorkloads(driver string, c *Cluster) []csiDriverWorkload {
+	workloads := make([]csiDriverWorkload, 0)
+	prefixes := make(map[string]bool)
+	for i := range c.DaemonSets {
+		ds := &c.DaemonSets[i]
+		if !mentionsCSIDriver(&ds.Spec.Template.Spec, driver) {
+			continue
+		}
+		workloads = append(workloads, csiDriverWorkload{
+			kind: "DaemonSet", namespace: ds.Namespace, name: ds.Name,
+			ready: ds.Status.NumberReady, desired: ds.Status.DesiredNumberScheduled,
+		})
+		for _, suffix := range csiNodePluginSuffixes {
+			if strings.HasSuffix(ds.Name, suffix) {
+				prefixes[ds.Namespace+"/"+strings.TrimSuffix(ds.Name, suffix)+"-"] = true
+				break
+			}
+		}
+	}
+
+	// belongs returns true if a controller is part of the driver
+	belongs := func(namespace, name string, spec *corev1.PodSpec) bool {
+		if mentionsCSIDriver(spec, driver) {
+			return true
+		}
+		for prefix := range prefixes {
+			if strings.HasPrefix(namespace+"/"+name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range c.Deployments {
+		d := &c.Deployments[i]
+		if belongs(d.Namespace, d.Name, &d.Spec.Template.Spec) {
+			workloads = append(workloads, csiDriverWorkload{
+				kind: "Deployment", namespace: d.Namespace, name: d.Name,
+				ready: d.Status.ReadyReplicas, desired: int32(deploymentReplicas(d)),
+			})
+		}
+	}
+	for i := range c.StatefulSets {
+		sts := &c.StatefulSets[i]
+		if belongs(sts.Namespace, sts.Name, &sts.Spec.Template.Spec) {
+			replicas := int32(1)
+			if sts.Spec.Replicas != nil {
+				replicas = *sts.Spec.Replicas
+			}
+			workloads = append(workloads, csiDriverWorkload{
+				kind: "StatefulSet", namespace: sts.Namespace, name: sts.Name,
+				ready: sts.Status.ReadyReplicas, desired: replicas,
+			})
+		}
+	}
+	return workloads
+}
+
+// pvcDriver returns the provisioner of the StorageClass a PVC was
+// provisioned with, which is the CSI driver for CSI volumes
+func pvcDriver(pvc *corev1.PersistentVolumeClaim, c *Cluster) string {
+	if sc, ok := pvcStorageClass(pvc, c.StorageClasses); ok {
+		return sc.Provisioner
+	}
+	return ""
+}
+
+// volumeUsers returns the pods that mount the PVCs matching fn, by node,
+// as namespace/name of the pod followed by the workload that owns it
+func volumeUsers(c *Cluster, fn func(pvc *corev1.PersistentVolumeClaim) bool) map[string][]string {
+	matching := make(map[string]bool)
+	for i := range c.PersistentVolumeClaims {
+		if pvc := &c.PersistentVolumeClaims[i]; fn(pvc) {
+			matching[pvc.Namespace+"/"+pvc.Name] = true
+		}
+	}
+
+	users := make(map[string][]string)
+	for i := range c.Pods {
+		p := &c.Pods[i]
+		for _, v := range p.Spec.Volumes {
+			if v.PersistentVolumeClaim == nil || !matching[p.Namespace+"/"+v.PersistentVolumeClaim.ClaimName] {
+				continue
+			}
+			user := p.Namespace + "/" + p.Name
+			if kind, owner := rootOwner(p, "Pod", c); kind != "Pod" {
+				user += fmt.Sprintf(" (%s %s)", kind, owner)
+			}
+			users[p.Spec.NodeName] = append(users[p.Spec.NodeName], user)
+			break
+		}
+	}
+	for node := range users {
+		sort.Strings(users[node])
+	}
+	return users
+}
+
+// csiNodeHasDriver returns true if a node's CSINode lists the driver as
+// registered, or false if it doesn't or the node has no CSINode
+func csiNodeHasDriver(node, driver string, csiNodes []storagev1.CSINode) bool {
+	for i := range csiNodes {
+		if csiNodes[i].Name != node {
+			continue
+		}
+		for _, d := range csiNodes[i].Spec.Drivers {
+			if d.Name == driver {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProblemCSIDriverUnhealthy is a problem with a CSI driver whose node
+// plugin or controller isn't fully ready, or that isn't registered on
+// nodes where pods mount its volumes, so those volumes can't be
+// provisioned, attached or mounted
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CSIDriverUnhealthy
+var ProblemCSIDriverUnhealthy = Problem{
+	ID:               "CSIDriverUnhealthy",
+	ShortDescription: "A CSI driver isn't ready or isn't registered on nodes that need it, so its volumes can't be attached or mounted",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CSIDriverUnhealthy",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		driver, ok := obj.(*storagev1.CSIDriver)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+		c := cfg.Cluster
+
+		findings := make([]string, 0)
+		for _, w := range csiDriverWorkloads(driver.Name, c) {
+			if w.ready < w.desired {
+				findings = append(findings, fmt.Sprintf("%s %s/%s has %d/%d pods ready", w.kind, w.namespace, w.name, w.ready, w.desired))
+			}
+		}
+
+		// Nodes only register the drivers whose node plugin runs on them,
+		// so only nodes with pods that mount the driver's volumes matter
+		users := volumeUsers(c, func(pvc *corev1.PersistentVolumeClaim) bool { return pvcDriver(pvc, c) == driver.Name })
+		nodes := make([]string, 0, len(users))
+		for node := range users {
+			if node != "" && !csiNodeHasDriver(node, driver.Name, c.CSINodes) {
+				nodes = append(nodes, node)
+			}
+		}
+		sort.Strings(nodes)
+		for _, node := range nodes {
+			findings = append(findings, fmt.Sprintf("it isn't registered on node %s, where %s mount its volumes",
+				node, strings.Join(users[node], ", ")))
+		}
+
+		if len(findings) == 0 {
+			return "", false, false
+		}
+		return strings.Join(findings, "; "), false, true
+	},
+}
+
+// ProblemVolumeAttachmentStuck is a problem with a VolumeAttachment that
+// hasn't attached, or hasn't detached since it was deleted, within the
+// volume attachment threshold. Pods that mount the volume can't start
+// until it is attached, and a volume that doesn't detach can't be
+// attached to another node.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/VolumeAttachmentStuck
+var ProblemVolumeAttachmentStuck = Problem{
+	ID:               "VolumeAttachmentStuck",
+	ShortDescription: "A volume has been attaching to or detaching from a node for a long time",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/VolumeAttachmentStuck",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		va, ok := obj.(*storagev1.VolumeAttachment)
+		if !ok || cfg.VolumeAttachmentThreshold <= 0 {
+			return "", false, false
+		}
+
+		var details string
+		var volumeErr *storagev1.VolumeError
+		switch {
+		case va.DeletionTimestamp != nil:
+			since := time.Since(va.DeletionTimestamp.Time)
+			if !va.Status.Attached || since < cfg.VolumeAttachmentThreshold {
+				return "", false, false
+			}
+			details = fmt.Sprintf("Volume has been detaching from node %s for %s", va.Spec.NodeName, since.Round(time.Minute))
+			volumeErr = va.Status.DetachError
+		case !va.Status.Attached:
+			since := time.Since(va.CreationTimestamp.Time)
+			if since < cfg.VolumeAttachmentThreshold {
+				return "", false, false
+			}
+			details = fmt.Sprintf("Volume has been attaching to node %s for %s", va.Spec.NodeName, since.Round(time.Minute))
+			volumeErr = va.Status.AttachError
+		default:
+			return "", false, false
+		}
+
+		details += fmt.Sprintf(" by %s", va.Spec.Attacher)
+		if volumeErr != nil && volumeErr.Message != "" {
+			details += ", the last error was: " + volumeErr.Message
+		}
+
+		if pv := va.Spec.Source.PersistentVolumeName; pv != nil && cfg.Cluster != nil {
+			users := volumeUsers(cfg.Cluster, func(pvc *corev1.PersistentVolumeClaim) bool { return pvc.Spec.VolumeName == *pv })
+			affected := make([]string, 0)
+			for _, pods := range users {
+				affected = append(affected, pods...)
+			}
+			sort.Strings(affected)
+			if len(affected) != 0 {
+				details += fmt.Sprintf(", it is volume %s used by %s", *pv, strings.Join(affected, ", "))
+			} else {
+				details += fmt.Sprintf(", it is volume %s", *pv)
+			}
+		}
+		return details, false, true
+	},
+}
@@ -0,0 +1,89 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCSIDriverUnhealthy(t *testing.T) {
+	const driver = "ebs.csi.aws.com"
+	node := checkuptest.NewDaemonSet("ebs-csi-node", "public.ecr.aws/ebs-csi-driver/aws-ebs-csi-driver:v1.20.0", 2, 2)
+	node.Spec.Template.Spec.Volumes = []corev1.Volume{{
+		Name:         "plugin-dir",
+		VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/kubelet/plugins/" + driver + "/"}},
+	}}
+	controller := checkuptest.NewDeployment("ebs-csi-controller", 2, appsv1.DeploymentStrategy{})
+	controller.Status.ReadyReplicas = 0
+
+	// objects are a cluster where db-0 on node-1 mounts a volume of the
+	// driver
+	objects := func(extra ...runtime.Object) *checkup.Config {
+		objs := append([]runtime.Object{
+			checkuptest.NewCSIDriver(driver),
+			checkuptest.NewStorageClass("gp3", driver, true),
+			checkuptest.NewPVC("data-db-0", "gp3", "10Gi"),
+			checkuptest.NewPod("db-0", checkuptest.OnNode("node-1"), checkuptest.OwnedBy("StatefulSet", "db"), checkuptest.WithPVCVolume("data", "data-db-0")),
+		}, extra...)
+		return checkuptest.NewConfig(checkuptest.NewCluster(objs...))
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemCSIDriverUnhealthy, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewCSIDriver(driver), Config: objects(node, checkuptest.NewCSINode("node-1", driver))},
+		{
+			Name:           "controller not ready",
+			Object:         checkuptest.NewCSIDriver(driver),
+			Config:         objects(node, controller, checkuptest.NewCSINode("node-1", driver)),
+			Occurring:      true,
+			DetailsContain: "Deployment default/ebs-csi-controller has 0/2 pods ready",
+		},
+		{
+			Name:           "not registered on node",
+			Object:         checkuptest.NewCSIDriver(driver),
+			Config:         objects(node, checkuptest.NewCSINode("node-1", "efs.csi.aws.com")),
+			Occurring:      true,
+			DetailsContain: "it isn't registered on node node-1, where default/db-0 (StatefulSet db) mount its volumes",
+		},
+	})
+}
+
+func TestVolumeAttachmentStuck(t *testing.T) {
+	const driver = "ebs.csi.aws.com"
+	hourAgo := time.Now().Add(-time.Hour)
+	attaching := checkuptest.NewVolumeAttachment("pvc-data-db-0", "node-1", driver, hourAgo)
+	attaching.Status.AttachError = &storagev1.VolumeError{Message: "rpc error: volume is attached to another node"}
+	detaching := checkuptest.NewVolumeAttachment("pvc-data-db-0", "node-1", driver, hourAgo)
+	detaching.Status.Attached = true
+	detaching.DeletionTimestamp = &metav1.Time{Time: hourAgo}
+	attached := checkuptest.NewVolumeAttachment("pvc-data-db-0", "node-1", driver, hourAgo)
+	attached.Status.Attached = true
+	withPod := checkuptest.NewConfig(checkuptest.NewCluster(
+		checkuptest.NewPVC("data-db-0", "gp3", "10Gi"),
+		checkuptest.NewPod("db-0", checkuptest.OnNode("node-1"), checkuptest.WithPVCVolume("data", "data-db-0")),
+	))
+
+	checkuptest.RunCases(t, checkup.ProblemVolumeAttachmentStuck, []checkuptest.Case{
+		{Name: "attached", Object: attached},
+		{Name: "just created", Object: checkuptest.NewVolumeAttachment("pvc-data-db-0", "node-1", driver, time.Now())},
+		{
+			Name:           "attaching",
+			Object:         attaching,
+			Config:         withPod,
+			Occurring:      true,
+			DetailsContain: "Volume has been attaching to node node-1 for 1h0m0s by ebs.csi.aws.com, the last error was: rpc error: volume is attached to another node, it is volume pvc-data-db-0 used by default/db-0",
+		},
+		{
+			Name:           "detaching",
+			Object:         detaching,
+			Occurring:      true,
+			DetailsContain: "Volume has been detaching from node node-1 for 1h0m0s",
+		},
+	})
+}
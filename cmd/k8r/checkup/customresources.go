@@ -0,0 +1,102 @@
+// Description: This file contains code for listing custom resources of
+// operators and controllers that checkup has problems for, when their
+// CRDs are installed
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CustomResource is a type of custom resource defined by a CRD
+type CustomResource struct {
+	// Group is the API group of the resource
+	Group string
+
+	// Version is the API version of the resource
+	Version string
+
+	// Resource is the plural name of the resource, e.g. rollouts
+	Resource string
+
+	// Kind is the kind of the resource, e.g. Rollout
+	Kind string
+}
+
+// GroupVersion returns the group version of the resource, e.g. argoproj.io/v1alpha1
+func (r *CustomResource) GroupVersion() string {
+	return r.Group + "/" + r.Version
+}
+
+// String returns the resource with its group, e.g. rollouts.argoproj.io
+func (r *CustomResource) String() string {
+	return r.Resource + "." + r.Group
+}
+
+// installed returns true if the API server serves the resource
+func (r *CustomResource) installed(k kubernetes.Interface) bool {
+	resources, err := k.Discovery().ServerResourcesForGroupVersion(r.GroupVersion())
+	if err != nil {
+		return false
+	}
+	for i := range resources.APIResources {
+		if resources.APIResources[i].Name == r.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// listCustomResources lists the custom resources of the given type in the
+// given namespaces, metav1.NamespaceAll lists them across all namespaces.
+// Nothing is returned when the CRD isn't installed or the resources can't
+// be listed due to RBAC, as they are optional.
+func listCustomResources(ctx context.Context, k kubernetes.Interface, r *CustomResource,
+	namespaces []string) ([]unstructured.Unstructured, error) {
+	if !r.installed(k) {
+		return nil, nil
+	}
+
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return nil, nil
+	}
+
+	items := make([]unstructured.Unstructured, 0)
+	for _, ns := range namespaces {
+		path := []string{"/apis", r.Group, r.Version, r.Resource}
+		if ns != metav1.NamespaceAll {
+			path = []string{"/apis", r.Group, r.Version, "namespaces", ns, r.Resource}
+		}
+		body, err := rc.Get().AbsPath(path...).Do(ctx).Raw()
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %s.%s", r.Resource, r.Group)
+		}
+
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s.%s", r.Resource, r.Group)
+		}
+
+		for _, obj := range list.Items {
+			u := unstructured.Unstructured{Object: obj}
+			// Items in lists don't always include their type
+			u.SetAPIVersion(r.GroupVersion())
+			u.SetKind(r.Kind)
+			items = append(items, u)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,193 @@
+// Description: This file contains code for problems with how DaemonSets
+// roll out, which replace a per-node agent on every node at once, and
+// DaemonSets that aren't running on the nodes they should
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// criticalMaxUnavailablePercent is the share of nodes above which a
+// critical DaemonSet's maxUnavailable is flagged
+const criticalMaxUnavailablePercent = 25
+
+// criticalPriorityClasses are the priority classes of pods the cluster
+// can't run without
+var criticalPriorityClasses = map[string]bool{
+	"system-node-critical":    true,
+	"system-cluster-critical": true,
+}
+
+// criticalDaemonSet returns true if the DaemonSet runs a per-node agent
+// the cluster needs, e.g. the CNI or kube-proxy
+func criticalDaemonSet(ds *appsv1.DaemonSet) bool {
+	return ds.Namespace == metav1.NamespaceSystem || criticalPriorityClasses[ds.Spec.Template.Spec.PriorityClassName]
+}
+
+// daemonSetUpdateStrategy returns the DaemonSet's update strategy type,
+// which defaults to RollingUpdate
+func daemonSetUpdateStrategy(ds *appsv1.DaemonSet) appsv1.DaemonSetUpdateStrategyType {
+	if ds.Spec.UpdateStrategy.Type == "" {
+		return appsv1.RollingUpdateDaemonSetStrategyType
+	}
+	return ds.Spec.UpdateStrategy.Type
+}
+
+// ProblemDaemonSetStaleOnDelete is a problem with a DaemonSet using the
+// OnDelete update strategy whose pods still run an old template, changes
+// to it only reach a node once its pod is deleted by hand
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetStaleOnDelete
+var ProblemDaemonSetStaleOnDelete = Problem{
+	ID:               "DaemonSetStaleOnDelete",
+	ShortDescription: "A DaemonSet uses the OnDelete update strategy and some of its pods still run an old template",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetStaleOnDelete",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		ds, ok := obj.(*appsv1.DaemonSet)
+		if !ok || daemonSetUpdateStrategy(ds) != appsv1.OnDeleteDaemonSetStrategyType {
+			return "", false, false
+		}
+
+		// The counts are for an older template until the controller has
+		// seen the latest one
+		if ds.Status.ObservedGeneration < ds.Generation {
+			return "", false, false
+		}
+		desired, updated := ds.Status.DesiredNumberScheduled, ds.Status.UpdatedNumberScheduled
+		if updated >= desired {
+			return "", false, false
+		}
+		return fmt.Sprintf("%d/%d pods run an old template because the update strategy is OnDelete, "+
+			"delete them node by node to roll out the change or switch to RollingUpdate", desired-updated, desired), true, true
+	},
+}
+
+// ProblemDaemonSetMaxUnavailableHigh is a problem with a DaemonSet running
+// a critical per-node agent, e.g. the CNI, whose maxUnavailable lets a
+// rollout replace it on a large share of nodes at once, so a bad rollout
+// takes those nodes out together
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetMaxUnavailableHigh
+var ProblemDaemonSetMaxUnavailableHigh = Problem{
+	ID:               "DaemonSetMaxUnavailableHigh",
+	ShortDescription: "A critical DaemonSet's maxUnavailable lets a rollout take it down on many nodes at once",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetMaxUnavailableHigh",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		ds, ok := obj.(*appsv1.DaemonSet)
+		if !ok || !criticalDaemonSet(ds) || daemonSetUpdateStrategy(ds) != appsv1.RollingUpdateDaemonSetStrategyType {
+			return "", false, false
+		}
+		ru := ds.Spec.UpdateStrategy.RollingUpdate
+		desired := int(ds.Status.DesiredNumberScheduled)
+		if ru == nil || ru.MaxUnavailable == nil || desired < 2 {
+			return "", false, false
+		}
+
+		// The DaemonSet controller rounds percentages up
+		unavailable, err := intstr.GetScaledValueFromIntOrPercent(ru.MaxUnavailable, desired, true)
+		if err != nil || unavailable <= 1 || unavailable*100 <= desired*criticalMaxUnavailablePercent {
+			return "", false, false
+		}
+		if unavailable > desired {
+			unavailable = desired
+		}
+		return fmt.Sprintf("maxUnavailable %s lets a rollout replace this critical agent on %d of %d nodes at once, "+
+			"a bad rollout would take them all out, lower it to 1 or a small percentage",
+			ru.MaxUnavailable.String(), unavailable, desired), true, true
+	},
+}
+
+// usesHostPorts returns true if any container of the pod spec binds a
+// port on the node
+func usesHostPorts(spec *corev1.PodSpec) bool {
+	for i := range spec.Containers {
+		for _, p := range spec.Containers[i].Ports {
+			if p.HostPort != 0 || (spec.HostNetwork && p.ContainerPort != 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProblemDaemonSetSurgeHostPort is a problem with a DaemonSet that surges
+// during rollouts while binding host ports, the new pod can't start next
+// to the old one on the same node so the rollout never progresses
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetSurgeHostPort
+var ProblemDaemonSetSurgeHostPort = Problem{
+	ID:               "DaemonSetSurgeHostPort",
+	ShortDescription: "A DaemonSet surges during rollouts but binds host ports, so new pods can't start next to old ones",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetSurgeHostPort",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		ds, ok := obj.(*appsv1.DaemonSet)
+		if !ok || daemonSetUpdateStrategy(ds) != appsv1.RollingUpdateDaemonSetStrategyType {
+			return "", false, false
+		}
+		ru := ds.Spec.UpdateStrategy.RollingUpdate
+		if ru == nil || ru.MaxSurge == nil || !usesHostPorts(&ds.Spec.Template.Spec) {
+			return "", false, false
+		}
+		if surge, err := intstr.GetScaledValueFromIntOrPercent(ru.MaxSurge, int(ds.Status.DesiredNumberScheduled), true); err != nil || surge == 0 {
+			return "", false, false
+		}
+		return fmt.Sprintf("maxSurge %s starts the new pod before stopping the old one on each node, but the pods bind "+
+			"host ports so the new one can't be scheduled and the rollout stalls, use maxUnavailable instead",
+			ru.MaxSurge.String()), true, true
+	},
+}
+
+// ProblemDaemonSetPodsUnavailable is a problem with a DaemonSet that
+// doesn't have a ready pod on every node it should run on, or runs pods on
+// nodes it shouldn't. Pods younger than the pod grace period aren't
+// counted, so nodes that just joined aren't reported. Device plugins are
+// checked by DevicePluginUnhealthy instead.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetPodsUnavailable
+var ProblemDaemonSetPodsUnavailable = Problem{
+	ID:               "DaemonSetPodsUnavailable",
+	ShortDescription: "A DaemonSet isn't ready on every node it should run on, so its per-node agent is missing there",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DaemonSetPodsUnavailable",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		ds, ok := obj.(*appsv1.DaemonSet)
+		if !ok || isDevicePlugin(ds) {
+			return "", false, false
+		}
+
+		desired := ds.Status.DesiredNumberScheduled
+		unscheduled := desired - ds.Status.CurrentNumberScheduled
+		notReady := make([]string, 0)
+		if cfg.Cluster != nil {
+			for _, p := range daemonSetPods(ds, cfg.Cluster.Pods) {
+				if podReady(p) || p.Spec.NodeName == "" || time.Since(p.CreationTimestamp.Time) < cfg.PodGracePeriod {
+					continue
+				}
+				notReady = append(notReady, p.Spec.NodeName)
+			}
+			sort.Strings(notReady)
+		}
+		if unscheduled <= 0 && len(notReady) == 0 && ds.Status.NumberMisscheduled == 0 {
+			return "", false, false
+		}
+
+		details := []string{fmt.Sprintf("%d/%d pods ready", ds.Status.NumberReady, desired)}
+		if unscheduled > 0 {
+			details = append(details, fmt.Sprintf("%d node(s) have no pod scheduled", unscheduled))
+		}
+		if len(notReady) != 0 {
+			details = append(details, fmt.Sprintf("%d node(s) have a pod that isn't ready: %s", len(notReady), strings.Join(notReady, ", ")))
+		}
+		if ds.Status.NumberMisscheduled > 0 {
+			details = append(details, fmt.Sprintf("%d node(s) run a pod they shouldn't, check the DaemonSet's node selector and "+
+				"tolerations against the nodes' labels and taints", ds.Status.NumberMisscheduled))
+		}
+		return strings.Join(details, ", "), false, true
+	},
+}
@@ -0,0 +1,126 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// rollingUpdate returns a DaemonSet with the given maxUnavailable and
+// maxSurge
+func rollingUpdate(ds *appsv1.DaemonSet, maxUnavailable, maxSurge intstr.IntOrString) *appsv1.DaemonSet {
+	ds.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+		Type:          appsv1.RollingUpdateDaemonSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: &maxUnavailable, MaxSurge: &maxSurge},
+	}
+	return ds
+}
+
+func TestDaemonSetStaleOnDelete(t *testing.T) {
+	onDelete := func(updated int32) *appsv1.DaemonSet {
+		ds := checkuptest.NewDaemonSet("node-exporter", "prom/node-exporter:v1.6.0", 4, 4)
+		ds.Spec.UpdateStrategy.Type = appsv1.OnDeleteDaemonSetStrategyType
+		ds.Status.UpdatedNumberScheduled = updated
+		return ds
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemDaemonSetStaleOnDelete, []checkuptest.Case{
+		{Name: "up to date", Object: onDelete(4)},
+		{
+			Name:           "stale pods",
+			Object:         onDelete(1),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "3/4 pods run an old template because the update strategy is OnDelete",
+		},
+		{Name: "rolling update", Object: checkuptest.NewDaemonSet("node-exporter", "prom/node-exporter:v1.6.0", 4, 4)},
+	})
+}
+
+func TestDaemonSetMaxUnavailableHigh(t *testing.T) {
+	critical := func(maxUnavailable intstr.IntOrString) *appsv1.DaemonSet {
+		ds := rollingUpdate(checkuptest.NewDaemonSet("calico-node", "calico/node:v3.26.0", 10, 10),
+			maxUnavailable, intstr.FromInt(0))
+		ds.Spec.Template.Spec.PriorityClassName = "system-node-critical"
+		return ds
+	}
+	agent := rollingUpdate(checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 10, 10),
+		intstr.FromString("100%"), intstr.FromInt(0))
+
+	checkuptest.RunCases(t, checkup.ProblemDaemonSetMaxUnavailableHigh, []checkuptest.Case{
+		{Name: "one at a time", Object: critical(intstr.FromInt(1))},
+		{Name: "small percentage", Object: critical(intstr.FromString("20%"))},
+		{
+			Name:           "every node",
+			Object:         critical(intstr.FromString("100%")),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "maxUnavailable 100% lets a rollout replace this critical agent on 10 of 10 nodes at once",
+		},
+		{Name: "not critical", Object: agent},
+	})
+}
+
+func TestDaemonSetSurgeHostPort(t *testing.T) {
+	surging := func(hostPort int32) *appsv1.DaemonSet {
+		ds := rollingUpdate(checkuptest.NewDaemonSet("ingress-nginx", "registry.k8s.io/ingress-nginx/controller:v1.8.0", 3, 3),
+			intstr.FromInt(0), intstr.FromInt(1))
+		ds.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 80, HostPort: hostPort}}
+		return ds
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemDaemonSetSurgeHostPort, []checkuptest.Case{
+		{Name: "no host port", Object: surging(0)},
+		{
+			Name:           "host port",
+			Object:         surging(80),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "maxSurge 1 starts the new pod before stopping the old one on each node",
+		},
+	})
+}
+
+func TestDaemonSetPodsUnavailable(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour)
+	pod := func(name, node string, created time.Time, opts ...checkuptest.PodOption) runtime.Object {
+		return checkuptest.NewPod(name, append(opts, checkuptest.OwnedBy("DaemonSet", "fluent-bit"), checkuptest.OnNode(node), checkuptest.CreatedAt(created))...)
+	}
+	withPods := func(pods ...runtime.Object) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(pods...))
+	}
+	unscheduled := checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 3, 2)
+	unscheduled.Status.CurrentNumberScheduled = 2
+	misscheduled := checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 2, 2)
+	misscheduled.Status.NumberMisscheduled = 1
+	devicePlugin := checkuptest.NewDaemonSet("nvidia-device-plugin", "nvcr.io/nvidia/k8s-device-plugin:v0.14.0", 2, 2)
+	devicePlugin.Status.NumberMisscheduled = 1
+
+	checkuptest.RunCases(t, checkup.ProblemDaemonSetPodsUnavailable, []checkuptest.Case{
+		{Name: "all ready", Object: checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 3, 3), Config: withPods()},
+		{
+			Name:   "new node",
+			Object: checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 2, 1),
+			Config: withPods(pod("fluent-bit-a", "node-1", hourAgo), pod("fluent-bit-b", "node-2", time.Now(), checkuptest.NotReady(checkuptest.DefaultContainer))),
+		},
+		{
+			Name:   "pod not ready",
+			Object: checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 2, 1),
+			Config: withPods(
+				pod("fluent-bit-a", "node-1", hourAgo),
+				pod("fluent-bit-b", "node-2", hourAgo, checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			),
+			Occurring:      true,
+			DetailsContain: "1/2 pods ready, 1 node(s) have a pod that isn't ready: node-2",
+		},
+		{Name: "unscheduled", Object: unscheduled, Config: withPods(), Occurring: true, DetailsContain: "2/3 pods ready, 1 node(s) have no pod scheduled"},
+		{Name: "misscheduled", Object: misscheduled, Config: withPods(), Occurring: true, DetailsContain: "1 node(s) run a pod they shouldn't"},
+		{Name: "device plugin", Object: devicePlugin, Config: withPods()},
+	})
+}
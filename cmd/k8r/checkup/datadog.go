@@ -0,0 +1,184 @@
+// Description: This file contains code for sending problems to Datadog,
+// as gauges of problem counts and events for new errors
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// datadog constants
+const (
+	// DefaultDatadogURL is the API of the default Datadog site
+	DefaultDatadogURL = "https://api.datadoghq.com"
+
+	// datadogGauge is the type of gauge metrics in the v2 series API
+	datadogGauge = 3
+)
+
+// datadogPoint is a value of a metric at a time
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// datadogSeries is a metric sent to the v2 series API
+type datadogSeries struct {
+	Metric string         `json:"metric"`
+	Type   int            `json:"type"`
+	Points []datadogPoint `json:"points"`
+	Tags   []string       `json:"tags,omitempty"`
+}
+
+// datadogEvent is an event sent to the v1 events API
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key"`
+	SourceTypeName string   `json:"source_type_name"`
+	Tags           []string `json:"tags,omitempty"`
+}
+
+// DefaultDatadogStateFile returns where the errors sent to Datadog are
+// recorded by default, or an empty string if there is no cache directory
+func DefaultDatadogStateFile() string {
+	return defaultNotifiedFile("datadog")
+}
+
+// datadogTags returns the tags of a resource's problem, on top of the
+// tags every metric and event gets
+func datadogTags(r *Resource, common []string) []string {
+	tags := append(append([]string{}, common...), "problem_id:"+r.ProblemID, "severity:"+r.GetSeverity().String())
+	if i := strings.Index(r.Name, "/"); i >= 0 {
+		tags = append(tags, "namespace:"+r.Name[:i])
+	}
+	if r.Owner != "" {
+		tags = append(tags, "team:"+r.Owner)
+	}
+	return tags
+}
+
+// datadogMetrics returns a k8r.problems gauge per problem, namespace,
+// team and severity, and the total number of problems as
+// k8r.problems.total so that it drops to zero once they are resolved
+func datadogMetrics(resources []Resource, common []string, now time.Time) []datadogSeries {
+	counts := make(map[string]int)
+	tagsByKey := make(map[string][]string)
+	for i := range resources {
+		tags := datadogTags(&resources[i], common)
+		key := strings.Join(tags, ",")
+		counts[key]++
+		tagsByKey[key] = tags
+	}
+
+	point := func(v int) []datadogPoint {
+		return []datadogPoint{{Timestamp: now.Unix(), Value: float64(v)}}
+	}
+	series := []datadogSeries{{Metric: "k8r.problems.total", Type: datadogGauge, Points: point(len(resources)), Tags: common}}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series = append(series, datadogSeries{Metric: "k8r.problems", Type: datadogGauge, Points: point(counts[key]), Tags: tagsByKey[key]})
+	}
+	return series
+}
+
+// postDatadog sends a request body to a Datadog API
+func (o *Options) postDatadog(ctx context.Context, path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal datadog request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(o.cfg.DatadogURL, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to create datadog request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", o.cfg.DatadogAPIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send to datadog")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to send to datadog: got status %s", resp.Status)
+	}
+	return nil
+}
+
+// exportToDatadog sends the problem counts in a report to Datadog, and an
+// event for each error that wasn't found in the last run against the
+// cluster
+func (o *Options) exportToDatadog(ctx context.Context, report *Report, cluster string) error {
+	if o.cfg.DatadogAPIKey == "" {
+		return nil
+	}
+
+	common := append([]string{}, o.cfg.DatadogTags...)
+	if cluster != "" {
+		common = append(common, "cluster:"+cluster)
+	}
+	if err := o.postDatadog(ctx, "/api/v2/series", map[string]interface{}{
+		"series": datadogMetrics(report.Resources, common, time.Now()),
+	}); err != nil {
+		return err
+	}
+
+	state, err := loadNotifiedErrors(o.cfg.DatadogStateFile)
+	if err != nil {
+		return err
+	}
+	sent := make(map[string]bool, len(state[cluster]))
+	for _, key := range state[cluster] {
+		sent[key] = true
+	}
+
+	errs := make([]string, 0)
+	for i := range report.Resources {
+		r := &report.Resources[i]
+		if r.GetSeverity().rank() < SeverityError.rank() {
+			continue
+		}
+		key := notifiedKey(r)
+		errs = append(errs, key)
+		if sent[key] {
+			continue
+		}
+
+		text := r.ProblemDetails
+		if p := report.GetProblemByID(r.ProblemID); p != nil {
+			text = fmt.Sprintf("%s\n\n%s\n\n%s", p.ShortDescription, r.ProblemDetails, p.HelpURL)
+		}
+		if err := o.postDatadog(ctx, "/api/v1/events", &datadogEvent{
+			Title:          fmt.Sprintf("k8r: %s on %s %s", r.ProblemID, r.Type, r.Name),
+			Text:           text,
+			AlertType:      "error",
+			AggregationKey: key,
+			SourceTypeName: "kubernetes",
+			Tags:           datadogTags(r, common),
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Errors that were resolved are forgotten, so they are sent again if
+	// they come back
+	state[cluster] = errs
+	return state.save(o.cfg.DatadogStateFile)
+}
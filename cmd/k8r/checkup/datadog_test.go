@@ -0,0 +1,71 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDatadog(t *testing.T) {
+	var events []string
+	var series []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body bytes.Buffer
+		body.ReadFrom(r.Body)
+		switch r.URL.Path {
+		case "/api/v2/series":
+			series = body.Bytes()
+		case "/api/v1/events":
+			var e struct {
+				Title string `json:"title"`
+			}
+			json.Unmarshal(body.Bytes(), &e)
+			events = append(events, e.Title)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	objs := []runtime.Object{
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+	cfg := checkuptest.NewConfig(nil)
+	cfg.DatadogAPIKey = "key"
+	cfg.DatadogURL = srv.URL
+	cfg.DatadogTags = []string{"env:test"}
+	cfg.DatadogStateFile = filepath.Join(t.TempDir(), "datadog.json")
+
+	// Errors are only sent as events the first time they are found
+	for run, want := range []int{1, 0} {
+		events = nil
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &bytes.Buffer{})
+		if err := o.RunWithClient(context.Background(), newClientset(objs, nil)); err != checkup.ErrProblemsFound { //nolint:errorlint // Why: Checking the exact error
+			t.Fatalf("run %d: RunWithClient() error = %v, expected %v", run, err, checkup.ErrProblemsFound)
+		}
+		if len(events) != want || (want != 0 && !strings.Contains(events[0], "PodNotReady on pod default/api")) {
+			t.Errorf("run %d: sent events %q, expected %d", run, events, want)
+		}
+		if !strings.Contains(string(series), `"metric":"k8r.problems.total"`) ||
+			!strings.Contains(string(series), `"metric":"k8r.problems"`) ||
+			!strings.Contains(string(series), `"problem_id:PodNotReady"`) ||
+			!strings.Contains(string(series), `"namespace:default"`) ||
+			!strings.Contains(string(series), `"env:test"`) {
+			t.Errorf("run %d: sent series %s, expected a k8r.problems gauge for PodNotReady", run, series)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Description: This file contains code for problems with how Deployments
+// roll out, strategies that take pods down before replacing them and
+// rollouts that stopped making progress
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultRollingUpdate is what maxSurge and maxUnavailable default to
+var defaultRollingUpdate = intstr.FromString("25%")
+
+// deploymentReplicas returns the number of replicas of a Deployment, which
+// defaults to 1
+func deploymentReplicas(d *appsv1.Deployment) int {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return int(*d.Spec.Replicas)
+}
+
+// rollingUpdateBounds returns how many pods a Deployment's rolling update
+// adds above and takes away below its replicas, rounded the way the
+// Deployment controller does
+func rollingUpdateBounds(d *appsv1.Deployment) (surge, unavailable int, err error) {
+	maxSurge, maxUnavailable := &defaultRollingUpdate, &defaultRollingUpdate
+	if ru := d.Spec.Strategy.RollingUpdate; ru != nil {
+		if ru.MaxSurge != nil {
+			maxSurge = ru.MaxSurge
+		}
+		if ru.MaxUnavailable != nil {
+			maxUnavailable = ru.MaxUnavailable
+		}
+	}
+
+	replicas := deploymentReplicas(d)
+	if surge, err = intstr.GetScaledValueFromIntOrPercent(maxSurge, replicas, true); err != nil {
+		return 0, 0, err
+	}
+	if unavailable, err = intstr.GetScaledValueFromIntOrPercent(maxUnavailable, replicas, false); err != nil {
+		return 0, 0, err
+	}
+
+	// A rollout that could neither add nor remove pods would never
+	// progress, so the controller allows one pod to be unavailable
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+	if unavailable > replicas {
+		unavailable = replicas
+	}
+	return surge, unavailable, nil
+}
+
+// ProblemDeploymentRecreateZeroDowntime is a problem with a Deployment in
+// one of the zero-downtime-namespaces that uses the Recreate strategy,
+// which stops every pod before starting the new ones
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DeploymentRecreateZeroDowntime
+var ProblemDeploymentRecreateZeroDowntime = Problem{
+	ID:               "DeploymentRecreateZeroDowntime",
+	ShortDescription: "A Deployment in a zero-downtime namespace uses the Recreate strategy, so every deploy is an outage",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DeploymentRecreateZeroDowntime",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		d, ok := obj.(*appsv1.Deployment)
+		if !ok || d.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType ||
+			!matchesNamespace(d.Namespace, cfg.ZeroDowntimeNamespaces) {
+			return "", false, false
+		}
+		return fmt.Sprintf("Strategy Recreate stops all %d replicas before starting new ones, so each deploy is an outage "+
+			"in a namespace that must have zero downtime, use RollingUpdate unless the pods can't run side by side, "+
+			"e.g. because they share a ReadWriteOnce volume", deploymentReplicas(d)), true, true
+	},
+}
+
+// ProblemDeploymentRollingUpdateUnavailable is a problem with a Deployment
+// whose rolling update takes every pod, or half of them without surging,
+// down at once, so each deploy dips its availability
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DeploymentRollingUpdateUnavailable
+var ProblemDeploymentRollingUpdateUnavailable = Problem{
+	ID:               "DeploymentRollingUpdateUnavailable",
+	ShortDescription: "A Deployment's rolling update lets so many pods be unavailable that every deploy dips its availability",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DeploymentRollingUpdateUnavailable",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		d, ok := obj.(*appsv1.Deployment)
+		if !ok || (d.Spec.Strategy.Type != "" && d.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType) {
+			return "", false, false
+		}
+		replicas := deploymentReplicas(d)
+		surge, unavailable, err := rollingUpdateBounds(d)
+		if err != nil || replicas == 0 {
+			return "", false, false
+		}
+
+		switch {
+		case unavailable >= replicas:
+			return fmt.Sprintf("maxUnavailable lets every replica (%d) be down at once during a rollout, which is as "+
+				"disruptive as Recreate, lower maxUnavailable and raise maxSurge instead", replicas), true, true
+		case surge == 0 && unavailable*2 >= replicas:
+			return fmt.Sprintf("maxSurge is 0 and maxUnavailable lets %d of %d replicas be down at once, so capacity "+
+				"drops by at least half during each rollout, allow a surge or lower maxUnavailable", unavailable, replicas), true, true
+		}
+		return "", false, false
+	},
+}
+
+// deploymentProgressDeadlineExceeded is the reason the Deployment
+// controller sets on the Progressing condition when a rollout took longer
+// than spec.progressDeadlineSeconds
+const deploymentProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+// ProblemDeploymentRolloutStuck is a problem with a Deployment whose
+// rollout exceeded its progress deadline. The pods of the previous
+// ReplicaSet keep running, so nothing else looks wrong until the
+// Deployment is checked.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DeploymentRolloutStuck
+var ProblemDeploymentRolloutStuck = Problem{
+	ID:               "DeploymentRolloutStuck",
+	ShortDescription: "A Deployment's rollout exceeded its progress deadline, the new version isn't rolling out",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DeploymentRolloutStuck",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		d, ok := obj.(*appsv1.Deployment)
+		if !ok {
+			return "", false, false
+		}
+		for _, c := range d.Status.Conditions {
+			if c.Type != appsv1.DeploymentProgressing || c.Status != corev1.ConditionFalse || c.Reason != deploymentProgressDeadlineExceeded {
+				continue
+			}
+
+			details := fmt.Sprintf("Rollout stopped progressing: %s, %d of %d replicas are updated and %d are available",
+				strings.TrimSuffix(c.Message, "."), d.Status.UpdatedReplicas, deploymentReplicas(d), d.Status.AvailableReplicas)
+			if !c.LastUpdateTime.IsZero() {
+				details += fmt.Sprintf(", stuck since %s", c.LastUpdateTime.UTC().Format(time.RFC3339))
+			}
+			return details + ", check the events and pods of the newest ReplicaSet", false, true
+		}
+		return "", false, false
+	},
+}
@@ -0,0 +1,78 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// rolling returns a RollingUpdate strategy with the given bounds
+func rolling(maxSurge, maxUnavailable intstr.IntOrString) appsv1.DeploymentStrategy {
+	return appsv1.DeploymentStrategy{
+		Type:          appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable},
+	}
+}
+
+func TestDeploymentRecreateZeroDowntime(t *testing.T) {
+	recreate := checkuptest.NewDeployment("api", 3, appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType})
+	zeroDowntime := checkuptest.NewConfig(nil)
+	zeroDowntime.ZeroDowntimeNamespaces = []string{"def*"}
+
+	checkuptest.RunCases(t, checkup.ProblemDeploymentRecreateZeroDowntime, []checkuptest.Case{
+		{Name: "namespace not zero-downtime", Object: recreate},
+		{
+			Name:           "zero-downtime namespace",
+			Object:         recreate,
+			Config:         zeroDowntime,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Strategy Recreate stops all 3 replicas before starting new ones",
+		},
+		{Name: "rolling update", Object: checkuptest.NewDeployment("api", 3, appsv1.DeploymentStrategy{}), Config: zeroDowntime},
+	})
+}
+
+func TestDeploymentRollingUpdateUnavailable(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemDeploymentRollingUpdateUnavailable, []checkuptest.Case{
+		{Name: "defaults", Object: checkuptest.NewDeployment("api", 4, appsv1.DeploymentStrategy{})},
+		{Name: "surge only", Object: checkuptest.NewDeployment("api", 4, rolling(intstr.FromInt(1), intstr.FromInt(0)))},
+		{
+			Name:           "all unavailable",
+			Object:         checkuptest.NewDeployment("api", 4, rolling(intstr.FromString("25%"), intstr.FromString("100%"))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "maxUnavailable lets every replica (4) be down at once",
+		},
+		{
+			Name:           "no surge",
+			Object:         checkuptest.NewDeployment("api", 4, rolling(intstr.FromInt(0), intstr.FromInt(2))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "maxSurge is 0 and maxUnavailable lets 2 of 4 replicas be down at once",
+		},
+		{
+			Name:           "single replica without surge",
+			Object:         checkuptest.NewDeployment("api", 1, rolling(intstr.FromInt(0), intstr.FromInt(0))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "maxUnavailable lets every replica (1) be down at once",
+		},
+		{Name: "recreate", Object: checkuptest.NewDeployment("api", 4, appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType})},
+	})
+}
+
+func TestDeploymentRolloutStuck(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemDeploymentRolloutStuck, []checkuptest.Case{
+		{Name: "progressing", Object: checkuptest.NewDeployment("api", 3, appsv1.DeploymentStrategy{})},
+		{
+			Name:           "progress deadline exceeded",
+			Object:         checkuptest.DeploymentProgressDeadlineExceeded(checkuptest.NewDeployment("api", 3, appsv1.DeploymentStrategy{}), 1),
+			Occurring:      true,
+			DetailsContain: `ReplicaSet "api-5d4f8c7b9" has timed out progressing, 1 of 3 replicas are updated and 3 are available, stuck since 2022-06-01T12:00:00Z`,
+		},
+	})
+}
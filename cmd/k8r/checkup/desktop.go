@@ -0,0 +1,139 @@
+// Description: This file contains code for showing a desktop notification
+// when errors are found that weren't found by the last run, used with the
+// desktop-notify flag to keep k8r running in a loop in a corner terminal
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxNotifiedLines is the most errors listed in one notification, the
+// others are counted
+const maxNotifiedLines = 5
+
+// DefaultDesktopNotifyStateFile returns where the errors shown in desktop
+// notifications are recorded by default, or an empty string if there is
+// no cache directory
+func DefaultDesktopNotifyStateFile() string {
+	return defaultNotifiedFile("desktop")
+}
+
+// desktopNotifyCommand returns the command that shows a notification with
+// the title and message appended as arguments, notify-send on Linux and
+// osascript on macOS, unless the desktop-notify-command flag is set
+func (c *Config) desktopNotifyCommand() ([]string, error) {
+	if c.NotifyCommand != "" {
+		return strings.Fields(c.NotifyCommand), nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		// The title and message are passed as arguments so they don't
+		// have to be escaped in the script
+		return []string{"osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+		}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"notify-send"}, nil
+	}
+	return nil, fmt.Errorf("desktop notifications aren't supported on %s, pass --desktop-notify-command", runtime.GOOS)
+}
+
+// notifiedText returns how an error is listed in a notification
+func notifiedText(r *Resource) string {
+	return fmt.Sprintf("%s on %s %s", r.ProblemID, r.Type, r.Name)
+}
+
+// desktopNotification returns the title and message of a notification
+// about new errors
+func desktopNotification(cluster string, news []*Resource) (title, message string) {
+	title = fmt.Sprintf("k8r: %d new errors", len(news))
+	if len(news) == 1 {
+		title = "k8r: new error"
+	}
+	if cluster != "" {
+		title += " in " + cluster
+	}
+
+	lines := make([]string, 0, maxNotifiedLines+1)
+	for i, r := range news {
+		if i == maxNotifiedLines {
+			lines = append(lines, fmt.Sprintf("and %d more", len(news)-maxNotifiedLines))
+			break
+		}
+		lines = append(lines, notifiedText(r))
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// subscribed returns true if the desktop notifications are about the
+// namespace of the resource, resources without one are only notified
+// about when every namespace is
+func (c *Config) subscribed(r *Resource) bool {
+	if len(c.NotifyNamespaces) == 0 {
+		return true
+	}
+	i := strings.Index(r.Name, "/")
+	return i >= 0 && matchesNamespace(r.Name[:i], c.NotifyNamespaces)
+}
+
+// notifyDesktop shows a desktop notification about the errors in a report
+// that weren't found in the last run against the cluster, errors that were
+// resolved since are notified about again if they come back
+func (o *Options) notifyDesktop(ctx context.Context, report *Report, cluster string) error {
+	if !o.cfg.DesktopNotify {
+		return nil
+	}
+
+	state, err := loadNotifiedErrors(o.cfg.NotifyStateFile)
+	if err != nil {
+		return err
+	}
+	notified := make(map[string]bool, len(state[cluster]))
+	for _, key := range state[cluster] {
+		notified[key] = true
+	}
+
+	errs := make([]string, 0)
+	found := make(map[string]bool)
+	news := make([]*Resource, 0)
+	for i := range report.Resources {
+		r := &report.Resources[i]
+		if !r.GetSeverity().AtLeast(SeverityError) || !o.cfg.subscribed(r) {
+			continue
+		}
+		key := notifiedKey(r)
+		if found[key] {
+			continue
+		}
+		errs = append(errs, key)
+		found[key] = true
+		if !notified[key] {
+			news = append(news, r)
+		}
+	}
+
+	if len(news) != 0 {
+		command, err := o.cfg.desktopNotifyCommand()
+		if err != nil {
+			return err
+		}
+		title, message := desktopNotification(cluster, news)
+		args := append(append([]string{}, command[1:]...), title, message)
+		cmd := exec.CommandContext(ctx, command[0], args...) //nolint:gosec // Why: The command is the user's own notifier
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "failed to show desktop notification: %s", strings.TrimSpace(string(out)))
+		}
+	}
+
+	state[cluster] = errs
+	return state.save(o.cfg.NotifyStateFile)
+}
@@ -0,0 +1,59 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDesktopNotify(t *testing.T) {
+	dir := t.TempDir()
+	notifications := filepath.Join(dir, "notifications")
+	script := filepath.Join(dir, "notify.sh")
+	if err := os.WriteFile(script, []byte(`printf '%s|%s\n' "$1" "$2" >> "`+notifications+`"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.DesktopNotify = true
+	cfg.NotifyNamespaces = []string{"pay*"}
+	cfg.NotifyCommand = "sh " + script
+	cfg.NotifyStateFile = filepath.Join(dir, "desktop.json")
+
+	// Only new errors in subscribed namespaces are notified about, and
+	// again once they come back after being resolved
+	notReady := checkuptest.NotReady(checkuptest.DefaultContainer)
+	broken := []runtime.Object{
+		checkuptest.NewPod("api", notReady, checkuptest.InNamespace("payments")),
+		checkuptest.NewPod("api", notReady),
+	}
+	ready := []runtime.Object{checkuptest.NewPod("api", checkuptest.InNamespace("payments"))}
+	for run, tc := range []struct {
+		objs    []runtime.Object
+		wantErr error
+		want    string
+	}{
+		{broken, checkup.ErrProblemsFound, "k8r: new error|PodNotReady on pod payments/api\n"},
+		{broken, checkup.ErrProblemsFound, ""},
+		{ready, nil, ""},
+		{broken, checkup.ErrProblemsFound, "k8r: new error|PodNotReady on pod payments/api\n"},
+	} {
+		os.Remove(notifications)
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &bytes.Buffer{})
+		if err := o.RunWithClient(context.Background(), newClientset(tc.objs, nil)); err != tc.wantErr { //nolint:errorlint // Why: Checking the exact error
+			t.Fatalf("run %d: RunWithClient() error = %v, expected %v", run, err, tc.wantErr)
+		}
+		got, _ := os.ReadFile(notifications)
+		if string(got) != tc.want {
+			t.Errorf("run %d: notified %q, expected %q", run, got, tc.want)
+		}
+	}
+}
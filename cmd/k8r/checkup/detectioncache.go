@@ -0,0 +1,283 @@
+// Description: This file contains code for caching the problems found for
+// each object between runs, so that runs with the cache-detections flag
+// only check objects that changed since the last run. The whole cluster is
+// still listed every run, only checking is saved.
+
+package checkup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// detectionCacheMaxAge is how long the problems cached by a run are reused
+// for, after which every object is checked again in case detectors
+// changed, e.g. because k8r was upgraded
+const detectionCacheMaxAge = 30 * time.Minute
+
+// cachedProblems are the problems whose results are reused for objects
+// that haven't changed since they were cached. Only problems that look at
+// nothing but the object itself and the config are reused, problems that
+// look at other objects, e.g. the pods a Service selects, or at how much
+// time passed, e.g. how long a PVC has been pending, can change without
+// the object changing so they are always checked again.
+var cachedProblems = map[string]bool{
+	ProblemAPIServiceUnavailable.ID:              true,
+	ProblemCronJobMissingTimeZone.ID:             true,
+	ProblemDaemonSetStaleOnDelete.ID:             true,
+	ProblemDaemonSetMaxUnavailableHigh.ID:        true,
+	ProblemDaemonSetSurgeHostPort.ID:             true,
+	ProblemDeploymentRecreateZeroDowntime.ID:     true,
+	ProblemDeploymentRollingUpdateUnavailable.ID: true,
+	ProblemDeploymentRolloutStuck.ID:             true,
+	ProblemHighRestarts.ID:                       true,
+	ProblemKyvernoPolicyFailed.ID:                true,
+	ProblemMissingRequiredLabels.ID:              true,
+	ProblemNodeGPUNotAllocatable.ID:              true,
+	ProblemNodeNotReady.ID:                       true,
+	ProblemNodeProblemDetected.ID:                true,
+	ProblemPodCrashLoopBackOff.ID:                true,
+	ProblemPodDNSNoNameservers.ID:                true,
+	ProblemPodDNSSearchAmplification.ID:          true,
+	ProblemPodHostPortUnschedulable.ID:           true,
+	ProblemPodPending.ID:                         true,
+	ProblemPodImagePullBackOff.ID:                true,
+	ProblemPodNotReady.ID:                        true,
+	ProblemPodOOMKilled.ID:                       true,
+	ProblemPodSidecarBlocksJob.ID:                true,
+	ProblemPodSidecarNotReady.ID:                 true,
+	ProblemPolarisCheckFailed.ID:                 true,
+	ProblemRBACWildcardGrant.ID:                  true,
+	ProblemSecretAsEnvVar.ID:                     true,
+	ProblemSecretCloudCredentials.ID:             true,
+	ProblemTrivyVulnerabilities.ID:               true,
+}
+
+// CachedProblem is a problem found for an object in a previous run
+type CachedProblem struct {
+	// ID is the ID of the problem
+	ID string `json:"id"`
+
+	// Details are the details the problem was reported with
+	Details string `json:"details,omitempty"`
+
+	// Warning is true if the problem was a warning
+	Warning bool `json:"warning,omitempty"`
+}
+
+// CachedObject is an object checked in a previous run
+type CachedObject struct {
+	// ResourceVersion is the resourceVersion the object was checked at
+	ResourceVersion string `json:"resourceVersion"`
+
+	// Problems are the problems found for the object
+	Problems []CachedProblem `json:"problems,omitempty"`
+}
+
+// ClusterDetections is the problems cached by the last run against a
+// cluster
+type ClusterDetections struct {
+	// Time is when the run happened
+	Time time.Time `json:"time"`
+
+	// Checks is a hash of the config the objects were checked with, the
+	// cache isn't reused when it changed
+	Checks string `json:"checks"`
+
+	// Objects are the objects by type and UID
+	Objects map[string]CachedObject `json:"objects"`
+}
+
+// DetectionCache is the problems cached by the last run, keyed by the
+// cluster it was run against
+type DetectionCache map[string]*ClusterDetections
+
+// defaultDetectionCacheFile returns where the cache is stored by default,
+// or an empty string if there is no cache directory
+func defaultDetectionCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "k8r", "detections.json")
+}
+
+// LoadDetectionCache reads the cache from a file, a file that doesn't
+// exist yet is an empty cache
+func LoadDetectionCache(path string) (DetectionCache, error) {
+	inv := make(DetectionCache)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return inv, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read detection cache")
+	}
+
+	if err := json.Unmarshal(b, &inv); err != nil {
+		return nil, errors.Wrap(err, "failed to parse detection cache")
+	}
+	return inv, nil
+}
+
+// Save writes the cache to a file
+func (inv DetectionCache) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create detection cache directory")
+	}
+
+	b, err := json.Marshal(inv)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal detection cache")
+	}
+
+	return errors.Wrap(os.WriteFile(path, b, 0o600), "failed to write detection cache")
+}
+
+// checksHash returns a hash of the parts of the config that change which
+// problems are found, so that changing e.g. a threshold invalidates the
+// cache
+func (c *Config) checksHash() string {
+	cfg := *c
+	cfg.Cluster = nil
+	cfg.profileConfigs = nil
+	cfg.Kube = kube.Options{}
+	cfg.BackstageFile, cfg.BackstageURL, cfg.BackstageToken, cfg.BackstageMappings = "", "", "", nil
+	cfg.HistoryFile, cfg.ResultFile, cfg.DetectionCacheFile = "", "", ""
+	cfg.ReportDir, cfg.ReportRetention = "", 0
+	cfg.PublishProblems, cfg.ProblemsNamespace = false, ""
+	cfg.DesktopNotify, cfg.NotifyNamespaces, cfg.NotifyCommand, cfg.NotifyStateFile = false, nil, "", ""
+	cfg.GroupBy, cfg.SortNamespacesBy, cfg.ShowSuppressed = "", "", false
+	cfg.LowMemory, cfg.CacheDetections, cfg.Shard, cfg.Verbose = false, false, nil, false
+	cfg.MinSeverity, cfg.Output, cfg.FailOnIncomplete = nil, "", false
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		// Never matches, so the cache isn't reused
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// detectionCacheKey returns the key an object is stored under, objects that
+// didn't come from a cluster, e.g. the control plane, have no key
+func detectionCacheKey(resourceType string, obj metav1.Object) (string, bool) {
+	if obj.GetUID() == "" || obj.GetResourceVersion() == "" {
+		return "", false
+	}
+	return resourceType + "/" + string(obj.GetUID()), true
+}
+
+// detections are the problems cached for the objects of a run
+type detections struct {
+	// previous are the objects checked in the last run, nil when it
+	// can't be reused
+	previous map[string]CachedObject
+
+	// current are the objects checked in this run
+	current *ClusterDetections
+
+	// reused is the number of objects whose problems were reused
+	reused int
+}
+
+// newDetections starts caching a run's problems, reusing the last run
+// against the cluster if it is recent and used the same config
+func newDetections(inv DetectionCache, cluster, checks string, now time.Time) *detections {
+	i := &detections{
+		current: &ClusterDetections{Time: now.UTC(), Checks: checks, Objects: make(map[string]CachedObject)},
+	}
+	if last, ok := inv[cluster]; ok && checks != "" && last.Checks == checks && now.Sub(last.Time) < detectionCacheMaxAge {
+		i.previous = last.Objects
+	}
+	return i
+}
+
+// lookup returns the problems found for the object in the last run if it
+// hasn't changed since
+func (i *detections) lookup(resourceType string, obj metav1.Object) ([]CachedProblem, bool) {
+	if i == nil {
+		return nil, false
+	}
+	key, ok := detectionCacheKey(resourceType, obj)
+	if !ok {
+		return nil, false
+	}
+	last, ok := i.previous[key]
+	if !ok || last.ResourceVersion != obj.GetResourceVersion() {
+		return nil, false
+	}
+
+	i.current.Objects[key] = last
+	i.reused++
+	return last.Problems, true
+}
+
+// record records the problems found for the object in this run
+func (i *detections) record(resourceType string, obj metav1.Object, problems []Resource) {
+	if i == nil {
+		return
+	}
+	key, ok := detectionCacheKey(resourceType, obj)
+	if !ok {
+		return
+	}
+
+	o := CachedObject{ResourceVersion: obj.GetResourceVersion()}
+	for j := range problems {
+		if !cachedProblems[problems[j].ProblemID] {
+			continue
+		}
+		o.Problems = append(o.Problems, CachedProblem{
+			ID:      problems[j].ProblemID,
+			Details: problems[j].ProblemDetails,
+			Warning: problems[j].Warning,
+		})
+	}
+	i.current.Objects[key] = o
+}
+
+// EDIT: New function
+// loadDetectionCache starts caching the problems found in the cluster when
+// the cache-detections flag is set. The cache is best effort, failing to
+// read it checks every object.
+func (o *Options) loadDetectionCache(cluster string) {
+	o.detections = nil
+	if !o.cfg.CacheDetections || o.cfg.DetectionCacheFile == "" {
+		return
+	}
+
+	inv, err := LoadDetectionCache(o.cfg.DetectionCacheFile)
+	if err != nil {
+		o.log.WithError(err).Warn("failed to load detection cache, checking every object")
+		inv = make(DetectionCache)
+	}
+	o.detections = newDetections(inv, cluster, o.cfg.checksHash(), time.Now())
+}
+
+// saveDetectionCache caches the problems found for the objects checked in
+// this run for the next run
+func (o *Options) saveDetectionCache(cluster string) {
+	if o.detections == nil {
+		return
+	}
+	o.log.Infof("Reused the problems of %d unchanged objects from the last run", o.detections.reused)
+
+	inv, err := LoadDetectionCache(o.cfg.DetectionCacheFile)
+	if err != nil {
+		inv = make(DetectionCache)
+	}
+	inv[cluster] = o.detections.current
+	if err := inv.Save(o.cfg.DetectionCacheFile); err != nil {
+		o.log.WithError(err).Warn("failed to save detection cache")
+	}
+}
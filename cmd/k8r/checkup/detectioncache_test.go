@@ -0,0 +1,124 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDetectionCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detections.json")
+	version := func(uid, rv string) checkuptest.PodOption {
+		return func(p *corev1.Pod) {
+			p.UID = types.UID(uid)
+			p.ResourceVersion = rv
+		}
+	}
+	notReady := checkuptest.NotReady(checkuptest.DefaultContainer)
+
+	scan := func(objs ...runtime.Object) map[string]string {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.CacheDetections = true
+		cfg.DetectionCacheFile = path
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+
+		resources, err := o.Scan(context.Background(), newClientset(objs, nil))
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		details := make(map[string]string)
+		for i := range resources {
+			details[resources[i].Name+" "+resources[i].ProblemID] = resources[i].ProblemDetails
+		}
+		return details
+	}
+
+	scan(
+		checkuptest.NewPod("api", notReady, version("api-uid", "1")),
+		checkuptest.NewPod("worker", notReady, version("worker-uid", "1")),
+	)
+
+	// Mark the recorded problems so reused ones can be told apart
+	inv, err := checkup.LoadDetectionCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range inv {
+		for key, obj := range c.Objects {
+			for i := range obj.Problems {
+				obj.Problems[i].Details = "from the last run"
+			}
+			c.Objects[key] = obj
+		}
+	}
+	if err := inv.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got := scan(
+		checkuptest.NewPod("api", notReady, version("api-uid", "1")),
+		checkuptest.NewPod("worker", notReady, version("worker-uid", "2")),
+		checkuptest.NewPod("new", notReady, version("new-uid", "1")),
+	)
+	if got["default/api PodNotReady"] != "from the last run" {
+		t.Errorf("unchanged pod details = %q, expected the problem from the last run", got["default/api PodNotReady"])
+	}
+	for _, name := range []string{"default/worker PodNotReady", "default/new PodNotReady"} {
+		details, ok := got[name]
+		if !ok || details == "from the last run" {
+			t.Errorf("%s details = %q, expected the pod to be checked again", name, details)
+		}
+	}
+}
+
+func TestDetectionCacheRechecksRelatedObjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detections.json")
+	svc := checkuptest.NewService("web", map[string]string{"app": "web"}, func(s *corev1.Service) {
+		s.UID = "web-uid"
+		s.ResourceVersion = "1"
+	})
+	labeled := checkuptest.NewPod("web-a", checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.WithLabels(map[string]string{"app": "web"}))
+	unlabeled := checkuptest.NewPod("web-b", checkuptest.OwnedBy("ReplicaSet", "web"))
+
+	scan := func(objs ...runtime.Object) []checkup.Resource {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.CacheDetections = true
+		cfg.DetectionCacheFile = path
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+
+		resources, err := o.Scan(context.Background(), newClientset(objs, nil))
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		return resources
+	}
+
+	for _, r := range scan(svc, labeled) {
+		if r.ProblemID == checkup.ProblemServicePartialWorkload.ID {
+			t.Fatalf("found %s before the workload changed", r.ProblemID)
+		}
+	}
+
+	// The Service is unchanged but now only selects some of the pods
+	found := false
+	for _, r := range scan(svc, labeled, unlabeled) {
+		found = found || r.ProblemID == checkup.ProblemServicePartialWorkload.ID
+	}
+	if !found {
+		t.Errorf("expected %s to be checked again for the unchanged Service", checkup.ProblemServicePartialWorkload.ID)
+	}
+}
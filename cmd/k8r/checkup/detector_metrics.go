@@ -0,0 +1,81 @@
+// Description: This file contains code for measuring how long each problem
+// detector takes, shown with the verbose flag to find slow checks
+
+package checkup
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// DetectorMetrics is how much work a problem's detector did in a run
+type DetectorMetrics struct {
+	// ID is the ID of the problem
+	ID string `json:"id"`
+
+	// Evaluated is the number of resources the detector was run against
+	Evaluated int `json:"evaluated"`
+
+	// DurationSeconds is how long the detector took in total
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// detectorStat is the running total of a detector's metrics
+type detectorStat struct {
+	evaluated int
+	duration  time.Duration
+}
+
+// resetDetectorStats starts measuring detectors from scratch
+func (o *Options) resetDetectorStats() {
+	o.detectorStats = make(map[string]*detectorStat)
+}
+
+// recordDetector records that a detector ran against a resource for d
+func (o *Options) recordDetector(id string, d time.Duration) {
+	if o.detectorStats == nil {
+		o.resetDetectorStats()
+	}
+	s, ok := o.detectorStats[id]
+	if !ok {
+		s = &detectorStat{}
+		o.detectorStats[id] = s
+	}
+	s.evaluated++
+	s.duration += d
+}
+
+// detectorMetrics returns the metrics of every detector that ran, slowest
+// first
+func (o *Options) detectorMetrics() []DetectorMetrics {
+	metrics := make([]DetectorMetrics, 0, len(o.detectorStats))
+	for id, s := range o.detectorStats {
+		metrics = append(metrics, DetectorMetrics{ID: id, Evaluated: s.evaluated, DurationSeconds: s.duration.Seconds()})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].DurationSeconds != metrics[j].DurationSeconds {
+			return metrics[i].DurationSeconds > metrics[j].DurationSeconds
+		}
+		return metrics[i].ID < metrics[j].ID
+	})
+	return metrics
+}
+
+// printDetectorMetrics prints how long each detector took when the
+// verbose flag is set
+func (o *Options) printDetectorMetrics() {
+	if !o.cfg.Verbose || len(o.detectorStats) == 0 {
+		return
+	}
+
+	fmt.Fprintln(o.out)
+	bold.Fprintln(o.out, "⏱  Detector timings (slowest first):")
+	tw := tabwriter.NewWriter(o.out, 1, 0, 2, ' ', 0)
+	for _, m := range o.detectorMetrics() {
+		d := time.Duration(m.DurationSeconds * float64(time.Second))
+		fmt.Fprintf(tw, "    %s\t%s\t%d resources\n", m.ID, d.Round(time.Microsecond), m.Evaluated)
+	}
+	tw.Flush()
+}
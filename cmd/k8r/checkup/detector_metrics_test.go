@@ -0,0 +1,42 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDetectorMetrics(t *testing.T) {
+	objs := []runtime.Object{
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+		checkuptest.NewPod("worker"),
+		checkuptest.NewPod("web"),
+	}
+
+	for _, verbose := range []bool{false, true} {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.Verbose = verbose
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+		if err := o.RunWithClient(context.Background(), newClientset(objs, nil)); err != checkup.ErrProblemsFound {
+			t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+		}
+
+		shown := strings.Contains(out.String(), "Detector timings")
+		if shown != verbose {
+			t.Errorf("verbose = %v, timings shown = %v", verbose, shown)
+		}
+		if verbose && !regexp.MustCompile(`PodNotReady\s+\S+\s+3 resources`).MatchString(out.String()) {
+			t.Errorf("output = %q, expected PodNotReady to have checked 3 resources", out.String())
+		}
+	}
+}
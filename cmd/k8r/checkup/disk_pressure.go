@@ -0,0 +1,126 @@
+// Description: This file contains code for detecting nodes whose
+// filesystems are filling up towards kubelet's eviction thresholds, before
+// the DiskPressure condition is set and pods start being evicted
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// defaultEvictionHard are kubelet's default hard eviction thresholds for
+// filesystems, used when its configuration couldn't be read
+var defaultEvictionHard = map[string]string{
+	"nodefs.available":  "10%",
+	"imagefs.available": "15%",
+}
+
+// evictionThreshold returns how many bytes of a filesystem of the given
+// capacity kubelet starts evicting pods below, for an eviction signal
+// such as nodefs.available
+func evictionThreshold(evictionHard map[string]string, signal string, capacity uint64) uint64 {
+	v, ok := evictionHard[signal]
+	if !ok {
+		v = defaultEvictionHard[signal]
+	}
+
+	if strings.HasSuffix(v, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		if err != nil {
+			return 0
+		}
+		return uint64(float64(capacity) * percent / 100)
+	}
+	q, err := resource.ParseQuantity(v)
+	if err != nil || q.Sign() < 0 {
+		return 0
+	}
+	return uint64(q.Value())
+}
+
+// gib formats bytes in GiB
+func gib(b uint64) string {
+	return fmt.Sprintf("%.1fGiB", float64(b)/(1<<30))
+}
+
+// diskPressureFinding returns why a filesystem is close to its eviction
+// threshold, or false if it isn't within the margin, which is a
+// percentage of its capacity
+func diskPressureFinding(name string, available, capacity, threshold uint64, margin int) (string, bool) {
+	if capacity == 0 || available >= threshold+capacity*uint64(margin)/100 {
+		return "", false
+	}
+	return fmt.Sprintf("%s has %s (%.0f%%) of %s available, kubelet starts evicting pods below %s (%.0f%%)",
+		name, gib(available), float64(available)/float64(capacity)*100, gib(capacity),
+		gib(threshold), float64(threshold)/float64(capacity)*100), true
+}
+
+// ProblemNodeDiskPressurePredicted is a problem with a node whose node or
+// image filesystem is within a margin of kubelet's eviction threshold but
+// doesn't have the DiskPressure condition yet. Usage comes from the
+// kubelet stats summary when it can be read, otherwise the size of the
+// images the node status lists is compared to the node's ephemeral
+// storage, which underestimates usage.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/NodeDiskPressurePredicted
+var ProblemNodeDiskPressurePredicted = Problem{
+	ID:               "NodeDiskPressurePredicted",
+	ShortDescription: "A node's disk is close to kubelet's eviction threshold, pods will be evicted once it is reached",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/NodeDiskPressurePredicted",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok || cfg.DiskPressureMargin <= 0 {
+			return "", false, false
+		}
+		// Nodes under disk pressure are already evicting pods
+		if c, ok := nodeCondition(node, corev1.NodeDiskPressure); ok && c.Status == corev1.ConditionTrue {
+			return "", false, false
+		}
+
+		var stats *NodeStats
+		if cfg.Cluster != nil {
+			stats = cfg.Cluster.NodeStats[node.Name]
+		}
+
+		findings := make([]string, 0, 2)
+		if stats != nil && stats.NodeFs != nil {
+			fs := stats.NodeFs
+			threshold := evictionThreshold(stats.EvictionHard, "nodefs.available", fs.CapacityBytes)
+			if f, ok := diskPressureFinding("nodefs", fs.AvailableBytes, fs.CapacityBytes, threshold, cfg.DiskPressureMargin); ok {
+				findings = append(findings, f)
+			}
+
+			// The image filesystem is the node filesystem unless the
+			// container runtime has one of its own
+			if fs := stats.ImageFs; fs != nil && *fs != *stats.NodeFs {
+				threshold := evictionThreshold(stats.EvictionHard, "imagefs.available", fs.CapacityBytes)
+				if f, ok := diskPressureFinding("imagefs", fs.AvailableBytes, fs.CapacityBytes, threshold, cfg.DiskPressureMargin); ok {
+					findings = append(findings, f)
+				}
+			}
+		} else if capacity, ok := node.Status.Capacity[corev1.ResourceEphemeralStorage]; ok && capacity.Value() > 0 {
+			var images uint64
+			for _, image := range node.Status.Images {
+				images += uint64(image.SizeBytes)
+			}
+			total := uint64(capacity.Value())
+			if images < total {
+				threshold := evictionThreshold(nil, "imagefs.available", total)
+				if f, ok := diskPressureFinding("Ephemeral storage", total-images, total, threshold, cfg.DiskPressureMargin); ok {
+					findings = append(findings, f+", counting only the images in the node status")
+				}
+			}
+		}
+
+		if len(findings) == 0 {
+			return "", false, false
+		}
+		return strings.Join(findings, "; ") + ", clean up unused images or grow the node's disk", true, true
+	},
+}
@@ -0,0 +1,62 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNodeDiskPressurePredicted(t *testing.T) {
+	const gib = 1 << 30
+	node := checkuptest.NewNode("node-1")
+	pressure := checkuptest.NewNode("node-1", checkuptest.NodeCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, "KubeletHasDiskPressure", ""))
+
+	// withStats returns a config with kubelet stats for node-1
+	withStats := func(stats *checkup.NodeStats) *checkup.Config {
+		cluster := checkuptest.NewCluster()
+		cluster.NodeStats = map[string]*checkup.NodeStats{"node-1": stats}
+		return checkuptest.NewConfig(cluster)
+	}
+	fs := func(available, capacity uint64) *checkup.FsStats {
+		return &checkup.FsStats{AvailableBytes: available * gib, CapacityBytes: capacity * gib}
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemNodeDiskPressurePredicted, []checkuptest.Case{
+		{Name: "no stats", Object: node},
+		{Name: "plenty of space", Object: node, Config: withStats(&checkup.NodeStats{NodeFs: fs(50, 100), ImageFs: fs(50, 100)})},
+		{Name: "already under pressure", Object: pressure, Config: withStats(&checkup.NodeStats{NodeFs: fs(5, 100)})},
+		{
+			Name:           "nodefs close to threshold",
+			Object:         node,
+			Config:         withStats(&checkup.NodeStats{NodeFs: fs(15, 100), ImageFs: fs(15, 100)}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "nodefs has 15.0GiB (15%) of 100.0GiB available, kubelet starts evicting pods below 10.0GiB (10%)",
+		},
+		{
+			Name:           "separate imagefs",
+			Object:         node,
+			Config:         withStats(&checkup.NodeStats{NodeFs: fs(50, 100), ImageFs: fs(40, 200)}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "imagefs has 40.0GiB (20%) of 200.0GiB available, kubelet starts evicting pods below 30.0GiB (15%)",
+		},
+		{
+			Name:   "configured threshold",
+			Object: node,
+			Config: withStats(&checkup.NodeStats{
+				NodeFs:       fs(15, 100),
+				EvictionHard: map[string]string{"nodefs.available": "1Gi"},
+			}),
+		},
+		{
+			Name:           "images in node status",
+			Object:         checkuptest.NewNode("node-1", checkuptest.NodeCapacity(corev1.ResourceEphemeralStorage, "100Gi"), checkuptest.NodeImages(50*gib, 30*gib)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "counting only the images in the node status",
+		},
+	})
+}
@@ -0,0 +1,267 @@
+// Description: This file contains code for detecting the Kubernetes
+// distribution a cluster runs and adjusting the checks to it
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Distro is the Kubernetes distribution a cluster runs
+type Distro string
+
+// Distributions that are detected
+const (
+	// DistroGeneric is any distribution that isn't detected, nothing is
+	// adjusted for it
+	DistroGeneric Distro = "generic"
+
+	// DistroEKS is Amazon Elastic Kubernetes Service
+	DistroEKS Distro = "eks"
+
+	// DistroGKE is Google Kubernetes Engine
+	DistroGKE Distro = "gke"
+
+	// DistroAKS is Azure Kubernetes Service
+	DistroAKS Distro = "aks"
+
+	// DistroK3s is k3s
+	DistroK3s Distro = "k3s"
+
+	// DistroKind is kind
+	DistroKind Distro = "kind"
+
+	// DistroMinikube is minikube
+	DistroMinikube Distro = "minikube"
+)
+
+// distros are the values the distro flag accepts
+var distros = []Distro{DistroGeneric, DistroEKS, DistroGKE, DistroAKS, DistroK3s, DistroKind, DistroMinikube}
+
+// distroNames are the names distributions are shown with in the report
+var distroNames = map[Distro]string{
+	DistroGeneric:  "generic",
+	DistroEKS:      "EKS",
+	DistroGKE:      "GKE",
+	DistroAKS:      "AKS",
+	DistroK3s:      "k3s",
+	DistroKind:     "kind",
+	DistroMinikube: "minikube",
+}
+
+// distroVersions are substrings of the API server's version that only
+// the distribution uses, e.g. v1.27.3-eks-a5565ad
+var distroVersions = map[string]Distro{
+	"-eks-": DistroEKS,
+	"-gke.": DistroGKE,
+	"+k3s":  DistroK3s,
+}
+
+// distroNodeLabels are node labels that only the distribution sets
+var distroNodeLabels = map[string]Distro{
+	"eks.amazonaws.com/nodegroup":    DistroEKS,
+	"eks.amazonaws.com/compute-type": DistroEKS,
+	"cloud.google.com/gke-nodepool":  DistroGKE,
+	"kubernetes.azure.com/cluster":   DistroAKS,
+	"minikube.k8s.io/name":           DistroMinikube,
+}
+
+// distroProviderIDs are prefixes of node provider IDs that only the
+// distribution uses
+var distroProviderIDs = map[string]Distro{
+	"kind://": DistroKind,
+	"k3s://":  DistroK3s,
+}
+
+// managedDistros are distributions whose control plane is run by the
+// provider, so problems with it can't be fixed by users
+var managedDistros = map[Distro]bool{
+	DistroEKS: true,
+	DistroGKE: true,
+	DistroAKS: true,
+}
+
+// managedControlPlaneProblems are problems that are skipped on managed
+// control planes, etcd isn't reachable or actionable there. The database
+// size is still checked as the API server reports it.
+var managedControlPlaneProblems = []Problem{
+	ProblemEtcdUnhealthy,
+	ProblemEtcdSlowFsync,
+}
+
+// ParseDistro validates the value of the distro flag, an empty value
+// means the distribution is detected
+func ParseDistro(s string) (Distro, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(distros))
+	for _, d := range distros {
+		if Distro(strings.ToLower(s)) == d {
+			return d, nil
+		}
+		names = append(names, string(d))
+	}
+	return "", fmt.Errorf("invalid --distro %q, expected one of %s", s, strings.Join(names, ", "))
+}
+
+// String returns the name of the distribution as shown in the report
+func (d Distro) String() string {
+	if name, ok := distroNames[d]; ok {
+		return name
+	}
+	return string(d)
+}
+
+// detectDistro detects the distribution from the API server's version
+// and the labels and provider IDs of nodes
+func detectDistro(version string, nodes []corev1.Node) Distro {
+	for s, d := range distroVersions {
+		if strings.Contains(version, s) {
+			return d
+		}
+	}
+
+	for i := range nodes {
+		n := &nodes[i]
+		for label, d := range distroNodeLabels {
+			if _, ok := n.Labels[label]; ok {
+				return d
+			}
+		}
+		for prefix, d := range distroProviderIDs {
+			if strings.HasPrefix(n.Spec.ProviderID, prefix) {
+				return d
+			}
+		}
+	}
+
+	return DistroGeneric
+}
+
+// gatherDistro returns the distribution the cluster runs, the distro
+// flag is used instead when set
+func gatherDistro(k kubernetes.Interface, cfg *Config, nodes []corev1.Node) Distro {
+	if cfg.Distro != "" {
+		return cfg.Distro
+	}
+
+	var version string
+	if info, err := k.Discovery().ServerVersion(); err == nil {
+		version = info.GitVersion
+	}
+	return detectDistro(version, nodes)
+}
+
+// distroAddon is a component the distribution runs on nodes that the
+// cluster doesn't work without. Addons without pods aren't reported, they
+// can be replaced, e.g. aws-node by Cilium.
+type distroAddon struct {
+	// Name is the name of the addon
+	Name string
+
+	// Selector selects the addon's pods in kube-system
+	Selector map[string]string
+
+	// Impact is what breaks when the addon is unhealthy
+	Impact string
+}
+
+// distroAddons are the addons checked for each distribution
+var distroAddons = map[Distro][]distroAddon{
+	DistroEKS: {{
+		Name:     "aws-node",
+		Selector: map[string]string{"k8s-app": "aws-node"},
+		Impact:   "pods on its nodes can't get IP addresses",
+	}},
+	DistroGKE: {{
+		Name:     "konnectivity-agent",
+		Selector: map[string]string{"k8s-app": "konnectivity-agent"},
+		Impact:   "kubectl logs/exec, port-forward and webhooks fail",
+	}},
+	DistroAKS: {{
+		Name:     "konnectivity-agent",
+		Selector: map[string]string{"app": "konnectivity-agent"},
+		Impact:   "kubectl logs/exec, port-forward and webhooks fail",
+	}},
+}
+
+// addonHealth returns the number of ready pods of the addon and the
+// nodes its pods aren't ready on
+func addonHealth(a *distroAddon, c *Cluster) (total, ready int, notReadyOn []string) {
+	pods := c.SelectPods(metav1.NamespaceSystem, a.Selector)
+	for _, p := range pods {
+		if podReady(p) {
+			ready++
+		} else if p.Spec.NodeName != "" {
+			notReadyOn = append(notReadyOn, p.Spec.NodeName)
+		}
+	}
+	sort.Strings(notReadyOn)
+	return len(pods), ready, notReadyOn
+}
+
+// ProblemDistroAddonUnhealthy is a problem with a component the Kubernetes
+// distribution runs on nodes, e.g. the EKS VPC CNI, not being ready
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DistroAddonUnhealthy
+var ProblemDistroAddonUnhealthy = Problem{
+	ID:               "DistroAddonUnhealthy",
+	ShortDescription: "A component of the Kubernetes distribution, e.g. aws-node on EKS, isn't ready",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DistroAddonUnhealthy",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		if _, ok := obj.(*ControlPlane); !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		addons := distroAddons[cfg.Cluster.Distro]
+		details := make([]string, 0)
+		for i := range addons {
+			a := &addons[i]
+			total, ready, notReadyOn := addonHealth(a, cfg.Cluster)
+			switch {
+			case ready < total && len(notReadyOn) != 0:
+				details = append(details, fmt.Sprintf("%s is ready on %d/%d pods, not on %s, %s",
+					a.Name, ready, total, strings.Join(notReadyOn, ", "), a.Impact))
+			case ready < total:
+				details = append(details, fmt.Sprintf("%s is ready on %d/%d pods, %s", a.Name, ready, total, a.Impact))
+			}
+		}
+		if len(details) == 0 {
+			return "", false, false
+		}
+
+		return strings.Join(details, "; "), false, true
+	},
+}
+
+// applyDistro adjusts the checks to the distribution the cluster runs and
+// notes it in the report
+func (o *Options) applyDistro(d Distro) {
+	if d == DistroGeneric || d == "" {
+		return
+	}
+
+	if !managedDistros[d] {
+		fmt.Fprintf(o.out, "Cluster distribution: %s\n", d)
+		return
+	}
+
+	if o.cfg.DisabledProblems == nil {
+		o.cfg.DisabledProblems = make(map[string]bool)
+	}
+	ids := make([]string, 0, len(managedControlPlaneProblems))
+	for _, p := range managedControlPlaneProblems {
+		o.cfg.DisabledProblems[p.ID] = true
+		ids = append(ids, p.ID)
+	}
+	fmt.Fprintf(o.out, "Cluster distribution: %s, skipping checks of its managed control plane (%s)\n", d, strings.Join(ids, ", "))
+}
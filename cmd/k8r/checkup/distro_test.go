@@ -0,0 +1,110 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+)
+
+func TestDistroDetected(t *testing.T) {
+	awsNode := func(name, node string, opts ...checkuptest.PodOption) *corev1.Pod {
+		opts = append(opts, checkuptest.InNamespace(metav1.NamespaceSystem), checkuptest.OnNode(node),
+			checkuptest.WithLabels(map[string]string{"k8s-app": "aws-node"}))
+		return checkuptest.NewPod(name, opts...)
+	}
+	kindNode := func(n *corev1.Node) { n.Spec.ProviderID = "kind://docker/dev/dev-control-plane" }
+
+	tests := []struct {
+		name        string
+		version     string
+		distro      checkup.Distro
+		objs        []runtime.Object
+		wantHeader  string
+		wantProblem bool
+	}{
+		{
+			name:    "eks from version",
+			version: "v1.27.3-eks-a5565ad",
+			objs: []runtime.Object{
+				checkuptest.NewNode("node-1"),
+				checkuptest.NewNode("node-2"),
+				awsNode("aws-node-a", "node-1"),
+				awsNode("aws-node-b", "node-2", checkuptest.NotReady(checkuptest.DefaultContainer)),
+			},
+			wantHeader:  "Cluster distribution: EKS, skipping checks of its managed control plane (EtcdUnhealthy, EtcdSlowFsync)",
+			wantProblem: true,
+		},
+		{
+			name:    "gke from node labels",
+			version: "v1.27.3",
+			objs: []runtime.Object{
+				checkuptest.NewNode("node-1", checkuptest.NodeLabels(map[string]string{"cloud.google.com/gke-nodepool": "default"})),
+			},
+			wantHeader: "Cluster distribution: GKE",
+		},
+		{
+			name:       "kind from provider ID",
+			version:    "v1.27.3",
+			objs:       []runtime.Object{checkuptest.NewNode("dev-control-plane", kindNode)},
+			wantHeader: "Cluster distribution: kind\n",
+		},
+		{
+			name:    "generic overrides detection",
+			version: "v1.27.3-eks-a5565ad",
+			distro:  checkup.DistroGeneric,
+			objs: []runtime.Object{
+				checkuptest.NewNode("node-1"),
+				awsNode("aws-node-a", "node-1", checkuptest.NotReady(checkuptest.DefaultContainer)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			k := newClientset(tt.objs, nil)
+			k.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: tt.version}
+
+			cfg := checkuptest.NewConfig(nil)
+			cfg.Distro = tt.distro
+
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(cfg, &out)
+
+			resources, err := o.Scan(context.Background(), k)
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			if tt.wantHeader == "" && strings.Contains(out.String(), "Cluster distribution") {
+				t.Errorf("output = %q, expected no distribution", out.String())
+			}
+			if tt.wantHeader != "" && !strings.Contains(out.String(), tt.wantHeader) {
+				t.Errorf("output = %q, expected it to contain %q", out.String(), tt.wantHeader)
+			}
+
+			var got bool
+			for i := range resources {
+				if resources[i].ProblemID == checkup.ProblemDistroAddonUnhealthy.ID {
+					got = true
+					if !strings.Contains(resources[i].ProblemDetails, "aws-node is ready on 1/2 pods, not on node-2") {
+						t.Errorf("ProblemDetails = %q, expected the node aws-node isn't ready on", resources[i].ProblemDetails)
+					}
+				}
+			}
+			if got != tt.wantProblem {
+				t.Errorf("DistroAddonUnhealthy reported = %v, expected %v", got, tt.wantProblem)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Description: This file contains code for problems related to pod DNS
+// configuration
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// dns constants
+const (
+	// defaultClusterNdots is the ndots value kubelet configures for
+	// pods using cluster DNS
+	defaultClusterNdots = 5
+
+	// clusterSearchDomains is the number of search domains kubelet
+	// configures for pods using cluster DNS, e.g. <ns>.svc.cluster.local,
+	// svc.cluster.local and cluster.local
+	clusterSearchDomains = 3
+
+	// highNdots is the ndots value at which names with dots in them
+	// are still looked up in every search domain first
+	highNdots = 5
+
+	// manySearchDomains is the number of search domains at which a
+	// high ndots causes a lookup to fan out into many queries
+	manySearchDomains = 4
+)
+
+// usesClusterDNS returns true if the pod resolves names through cluster DNS
+func usesClusterDNS(pod *corev1.Pod) bool {
+	switch pod.Spec.DNSPolicy {
+	case corev1.DNSClusterFirst, "":
+		return !pod.Spec.HostNetwork
+	case corev1.DNSClusterFirstWithHostNet:
+		return true
+	case corev1.DNSDefault, corev1.DNSNone:
+		return false
+	}
+	return false
+}
+
+// podNdots returns the effective ndots option for a pod
+func podNdots(pod *corev1.Pod) int {
+	ndots := 1
+	if usesClusterDNS(pod) {
+		ndots = defaultClusterNdots
+	}
+
+	if pod.Spec.DNSConfig != nil {
+		for _, opt := range pod.Spec.DNSConfig.Options {
+			if opt.Name != "ndots" || opt.Value == nil {
+				continue
+			}
+			if n, err := strconv.Atoi(*opt.Value); err == nil {
+				ndots = n
+			}
+		}
+	}
+
+	return ndots
+}
+
+// ProblemPodDNSSearchAmplification is a problem with a pod whose DNS
+// configuration combines a high ndots with many search domains, turning
+// every external lookup into many queries
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodDNSSearchAmplification
+var ProblemPodDNSSearchAmplification = Problem{
+	ID:               "PodDNSSearchAmplification",
+	ShortDescription: "A pod's DNS config combines a high ndots with many search domains, causing slow lookups",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodDNSSearchAmplification",
+	Severity:         SeverityInfo,
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return "", false, false
+		}
+
+		searches := 0
+		if usesClusterDNS(pod) {
+			searches = clusterSearchDomains
+		}
+		if pod.Spec.DNSConfig != nil {
+			searches += len(pod.Spec.DNSConfig.Searches)
+		}
+
+		ndots := podNdots(pod)
+		if ndots >= highNdots && searches >= manySearchDomains {
+			return fmt.Sprintf("ndots is %d with %d search domains, external names are tried in every search domain first",
+				ndots, searches,
+			), true, true
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemPodDNSNoNameservers is a problem with a pod that uses the None
+// DNS policy without configuring any nameservers
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodDNSNoNameservers
+var ProblemPodDNSNoNameservers = Problem{
+	ID:               "PodDNSNoNameservers",
+	ShortDescription: "A pod uses dnsPolicy None without any nameservers, so it can't resolve names",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodDNSNoNameservers",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return "", false, false
+		}
+
+		if pod.Spec.DNSPolicy != corev1.DNSNone {
+			return "", false, false
+		}
+
+		if pod.Spec.DNSConfig == nil || len(pod.Spec.DNSConfig.Nameservers) == 0 {
+			return "dnsPolicy is None but dnsConfig.nameservers is empty", false, true
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemPodHostAliasConflict is a problem with a pod whose hostAliases
+// shadow names that would otherwise be resolved by cluster DNS
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodHostAliasConflict
+var ProblemPodHostAliasConflict = Problem{
+	ID:               "PodHostAliasConflict",
+	ShortDescription: "A pod's hostAliases shadow names that are resolved by cluster DNS",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodHostAliasConflict",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return "", false, false
+		}
+
+		for _, alias := range pod.Spec.HostAliases {
+			for _, hostname := range alias.Hostnames {
+				name := strings.TrimSuffix(strings.ToLower(hostname), ".")
+				if strings.HasSuffix(name, ".svc") || strings.Contains(name, ".svc.") || strings.HasSuffix(name, ".cluster.local") {
+					return fmt.Sprintf("hostAlias %s (%s) shadows a cluster DNS name", hostname, alias.IP), true, true
+				}
+
+				// A bare name matching a Service in the namespace would
+				// otherwise resolve to that Service
+				if cfg != nil && cfg.Cluster != nil {
+					if _, ok := cfg.Cluster.GetService(pod.Namespace, name); ok {
+						return fmt.Sprintf("hostAlias %s (%s) shadows the Service %s", hostname, alias.IP, name), true, true
+					}
+				}
+			}
+		}
+
+		return "", false, false
+	},
+}
@@ -0,0 +1,79 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodDNSSearchAmplification(t *testing.T) {
+	extraSearches := checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+		spec.DNSConfig = &corev1.PodDNSConfig{Searches: []string{"corp.example.com"}}
+	})
+	lowNdots := checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+		two := "2"
+		spec.DNSConfig.Options = []corev1.PodDNSConfigOption{{Name: "ndots", Value: &two}}
+	})
+
+	checkuptest.RunCases(t, checkup.ProblemPodDNSSearchAmplification, []checkuptest.Case{
+		{Name: "cluster defaults", Object: checkuptest.NewPod("web")},
+		{
+			Name:           "extra search domain",
+			Object:         checkuptest.NewPod("web", extraSearches),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "ndots is 5 with 4 search domains",
+		},
+		{Name: "lowered ndots", Object: checkuptest.NewPod("web", extraSearches, lowNdots)},
+	})
+}
+
+func TestPodDNSNoNameservers(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodDNSNoNameservers, []checkuptest.Case{
+		{Name: "cluster first", Object: checkuptest.NewPod("web")},
+		{
+			Name: "none without nameservers",
+			Object: checkuptest.NewPod("web", checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+				spec.DNSPolicy = corev1.DNSNone
+			})),
+			Occurring: true,
+		},
+		{
+			Name: "none with nameservers",
+			Object: checkuptest.NewPod("web", checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+				spec.DNSPolicy = corev1.DNSNone
+				spec.DNSConfig = &corev1.PodDNSConfig{Nameservers: []string{"1.1.1.1"}}
+			})),
+		},
+	})
+}
+
+func TestPodHostAliasConflict(t *testing.T) {
+	withAlias := func(hostname string) checkuptest.PodOption {
+		return checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+			spec.HostAliases = []corev1.HostAlias{{IP: "10.0.0.5", Hostnames: []string{hostname}}}
+		})
+	}
+
+	shadowsService := checkuptest.NewPod("web", withAlias("db"))
+	checkuptest.RunCases(t, checkup.ProblemPodHostAliasConflict, []checkuptest.Case{
+		{Name: "external name", Object: checkuptest.NewPod("web", withAlias("legacy.example.com"))},
+		{
+			Name:           "cluster name",
+			Object:         checkuptest.NewPod("web", withAlias("db.default.svc.cluster.local")),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "shadows a cluster DNS name",
+		},
+		{
+			Name:           "service name",
+			Object:         shadowsService,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(shadowsService, checkuptest.NewService("db", nil))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "shadows the Service db",
+		},
+	})
+}
@@ -0,0 +1,129 @@
+// Description: This file contains code for problems related to etcd
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// etcd thresholds
+const (
+	// etcdDBSizeWarningRatio is the ratio of the quota at which the
+	// database size is reported as a warning
+	etcdDBSizeWarningRatio = 0.8
+
+	// etcdDBSizeErrorRatio is the ratio of the quota at which the
+	// database size is reported as an error, etcd raises a NOSPACE
+	// alarm and stops accepting writes once the quota is hit
+	etcdDBSizeErrorRatio = 0.95
+
+	// etcdSlowFsync is the 99th percentile WAL fsync duration above
+	// which etcd is considered to have slow disks, etcd recommends
+	// staying under 10ms
+	etcdSlowFsync = 10 * time.Millisecond
+)
+
+// ProblemEtcdUnhealthy is a problem with etcd not being ready or a member
+// not having a leader
+// https://github.com/Ashvin-Ranjan/k8r/wiki/EtcdUnhealthy
+var ProblemEtcdUnhealthy = Problem{
+	ID:               "EtcdUnhealthy",
+	ShortDescription: "etcd is not ready or has no leader, which degrades the whole cluster",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/EtcdUnhealthy",
+	Severity:         SeverityCritical,
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		cp, ok := obj.(*ControlPlane)
+		if !ok {
+			return "", false, false
+		}
+
+		if !cp.EtcdReady {
+			return fmt.Sprintf("The API server reports etcd as not ready: %s", cp.EtcdReadyMessage), false, true
+		}
+
+		for i := range cp.EtcdMembers {
+			if !cp.EtcdMembers[i].HasLeader {
+				return fmt.Sprintf("etcd member %s has no leader", cp.EtcdMembers[i].Name), false, true
+			}
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemEtcdDBSizeNearQuota is a problem with an etcd database that is
+// approaching its backend quota
+// https://github.com/Ashvin-Ranjan/k8r/wiki/EtcdDBSizeNearQuota
+var ProblemEtcdDBSizeNearQuota = Problem{
+	ID:               "EtcdDBSizeNearQuota",
+	ShortDescription: "The etcd database is approaching its quota, after which all writes are rejected",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/EtcdDBSizeNearQuota",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cp, ok := obj.(*ControlPlane)
+		if !ok {
+			return "", false, false
+		}
+
+		details := make([]string, 0)
+		warning := true
+		for i := range cp.EtcdMembers {
+			m := &cp.EtcdMembers[i]
+
+			quota := m.QuotaBytes
+			if quota == 0 {
+				quota = float64(cfg.EtcdQuotaBytes)
+			}
+			if quota == 0 || m.DBSizeBytes == 0 {
+				continue
+			}
+
+			ratio := m.DBSizeBytes / quota
+			if ratio < etcdDBSizeWarningRatio {
+				continue
+			}
+			if ratio >= etcdDBSizeErrorRatio {
+				warning = false
+			}
+			details = append(details, fmt.Sprintf("%s is %.0f%% of its quota (%.0fMiB)", m.Name, ratio*100, m.DBSizeBytes/(1<<20)))
+		}
+		if len(details) == 0 {
+			return "", false, false
+		}
+
+		return strings.Join(details, ", "), warning, true
+	},
+}
+
+// ProblemEtcdSlowFsync is a problem with etcd members whose disks are too
+// slow, which shows up as slow API requests and leader elections
+// https://github.com/Ashvin-Ranjan/k8r/wiki/EtcdSlowFsync
+var ProblemEtcdSlowFsync = Problem{
+	ID:               "EtcdSlowFsync",
+	ShortDescription: "etcd WAL fsyncs are slow, which slows down every write in the cluster",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/EtcdSlowFsync",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		cp, ok := obj.(*ControlPlane)
+		if !ok {
+			return "", false, false
+		}
+
+		details := make([]string, 0)
+		for i := range cp.EtcdMembers {
+			m := &cp.EtcdMembers[i]
+			p99 := time.Duration(m.WALFsyncP99Seconds * float64(time.Second))
+			if p99 > etcdSlowFsync {
+				details = append(details, fmt.Sprintf("%s p99 WAL fsync is %s", m.Name, p99))
+			}
+		}
+		if len(details) == 0 {
+			return "", false, false
+		}
+
+		return strings.Join(details, ", "), true, true
+	},
+}
@@ -0,0 +1,71 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+// controlPlane returns a healthy control plane with the given etcd members
+func controlPlane(members ...checkup.EtcdMember) *checkup.ControlPlane {
+	return &checkup.ControlPlane{EtcdReady: true, EtcdMembers: members}
+}
+
+func TestEtcdUnhealthy(t *testing.T) {
+	notReady := controlPlane()
+	notReady.EtcdReady = false
+	notReady.EtcdReadyMessage = "etcd check failed"
+
+	checkuptest.RunCases(t, checkup.ProblemEtcdUnhealthy, []checkuptest.Case{
+		{Name: "healthy", Object: controlPlane(checkup.EtcdMember{Name: "etcd-0", HasLeader: true})},
+		{Name: "not ready", Object: notReady, Occurring: true, DetailsContain: "etcd check failed"},
+		{
+			Name:           "no leader",
+			Object:         controlPlane(checkup.EtcdMember{Name: "etcd-0"}),
+			Occurring:      true,
+			DetailsContain: "etcd-0 has no leader",
+		},
+	})
+}
+
+func TestEtcdDBSizeNearQuota(t *testing.T) {
+	const mib = 1 << 20
+
+	checkuptest.RunCases(t, checkup.ProblemEtcdDBSizeNearQuota, []checkuptest.Case{
+		{Name: "small", Object: controlPlane(checkup.EtcdMember{Name: "etcd-0", DBSizeBytes: 100 * mib, QuotaBytes: 1000 * mib})},
+		{
+			Name:           "near quota",
+			Object:         controlPlane(checkup.EtcdMember{Name: "etcd-0", DBSizeBytes: 850 * mib, QuotaBytes: 1000 * mib}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "etcd-0 is 85% of its quota",
+		},
+		{
+			Name:           "at quota",
+			Object:         controlPlane(checkup.EtcdMember{Name: "etcd-0", DBSizeBytes: 990 * mib, QuotaBytes: 1000 * mib}),
+			Occurring:      true,
+			DetailsContain: "99%",
+		},
+		{
+			Name:           "default quota",
+			Object:         controlPlane(checkup.EtcdMember{Name: "etcd-0", DBSizeBytes: 1800 * mib}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "88%",
+		},
+	})
+}
+
+func TestEtcdSlowFsync(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemEtcdSlowFsync, []checkuptest.Case{
+		{Name: "fast", Object: controlPlane(checkup.EtcdMember{Name: "etcd-0", WALFsyncP99Seconds: 0.004})},
+		{
+			Name:           "slow",
+			Object:         controlPlane(checkup.EtcdMember{Name: "etcd-0", WALFsyncP99Seconds: 0.128}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "etcd-0 p99 WAL fsync is 128ms",
+		},
+	})
+}
@@ -198,6 +198,12 @@ var ProblemPodPending = Problem{
 			return "", false, false
 		}
 
+		// EDIT: Pods the scheduler couldn't place have no container
+		// statuses yet, e.g. pods waiting for a PVC
+		if c, ok := podCondition(pod, corev1.PodScheduled); ok && c.Status == corev1.ConditionFalse {
+			return fmt.Sprintf("Pod can't be scheduled: %s", c.Message), false, true
+		}
+
 		// Check if the pod has any containers that are not ready
 		for i := range pod.Status.ContainerStatuses {
 			cs := &pod.Status.ContainerStatuses[i]
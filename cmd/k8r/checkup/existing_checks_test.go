@@ -0,0 +1,83 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestPodCrashLoopBackOff(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodCrashLoopBackOff, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewPod("web")},
+		{
+			Name:           "crash looping",
+			Object:         checkuptest.NewPod("web", checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			Occurring:      true,
+			DetailsContain: "Container app in a crash loop backoff state",
+		},
+		{Name: "not a pod", Object: checkuptest.NewNode("node-1")},
+	})
+}
+
+func TestPodNotReady(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodNotReady, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewPod("web")},
+		{
+			Name:           "not ready",
+			Object:         checkuptest.NewPod("web", checkuptest.NotReady(checkuptest.DefaultContainer)),
+			Occurring:      true,
+			DetailsContain: "Container app is not ready",
+		},
+		{Name: "pending", Object: checkuptest.NewPod("web", checkuptest.Pending("creating"))},
+	})
+}
+
+func TestPodImagePullBackOff(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodImagePullBackOff, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewPod("web")},
+		{
+			Name:           "image pull backoff",
+			Object:         checkuptest.NewPod("web", checkuptest.ImagePullBackOff(checkuptest.DefaultContainer)),
+			Occurring:      true,
+			DetailsContain: "(example.com/app:1.0.0)",
+		},
+	})
+}
+
+func TestPodOOMKilled(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodOOMKilled, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewPod("web")},
+		{
+			Name:           "oom killed",
+			Object:         checkuptest.NewPod("web", checkuptest.OOMKilled(checkuptest.DefaultContainer)),
+			Occurring:      true,
+			DetailsContain: "ran out of memory",
+		},
+		{
+			Name:           "recently oom killed",
+			Object:         checkuptest.NewPod("web", checkuptest.RecentlyOOMKilled(checkuptest.DefaultContainer)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "was recently killed",
+		},
+	})
+}
+
+func TestPodPending(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodPending, []checkuptest.Case{
+		{Name: "running", Object: checkuptest.NewPod("web")},
+		{
+			Name:           "pending",
+			Object:         checkuptest.NewPod("web", checkuptest.Pending("pulling image")),
+			Occurring:      true,
+			DetailsContain: "pulling image",
+		},
+		{
+			Name:           "unschedulable",
+			Object:         checkuptest.NewPod("web", checkuptest.Unschedulable("0/3 nodes are available: 3 Insufficient cpu")),
+			Occurring:      true,
+			DetailsContain: "Pod can't be scheduled: 0/3 nodes are available: 3 Insufficient cpu",
+		},
+	})
+}
@@ -0,0 +1,319 @@
+// Description: This file contains code for listing the API server's
+// priority and fairness configuration, FlowSchemas and
+// PriorityLevelConfigurations, and for finding how the API server queues
+// k8r's own requests
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// flow control constants
+const (
+	// flowControlGroup is the API group of priority and fairness
+	flowControlGroup = "flowcontrol.apiserver.k8s.io"
+
+	// flowSchemaUIDHeader is the response header the API server names the
+	// FlowSchema a request matched in
+	flowSchemaUIDHeader = "X-Kubernetes-PF-FlowSchema-UID"
+
+	// priorityLevelTypeExempt is the type of priority levels whose
+	// requests are never queued or limited
+	priorityLevelTypeExempt = "Exempt"
+
+	// limitResponseReject is the limit response that rejects requests
+	// when a priority level is busy instead of queuing them
+	limitResponseReject = "Reject"
+)
+
+// flowControlVersions are the versions of the priority and fairness API,
+// newest first. The fields that are decoded are the same in all of them
+// except for the concurrency shares, which were renamed in v1beta3.
+var flowControlVersions = []string{"v1", "v1beta3", "v1beta2"}
+
+// FlowSchema sorts requests into priority levels. client-go's types only
+// cover older versions of the API, so only the fields problems are
+// checked against are decoded, from any version.
+type FlowSchema struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FlowSchemaSpec   `json:"spec"`
+	Status FlowSchemaStatus `json:"status"`
+}
+
+// FlowSchemaSpec is the spec of a FlowSchema
+type FlowSchemaSpec struct {
+	// PriorityLevelConfiguration is the priority level requests that
+	// match are put in
+	PriorityLevelConfiguration PriorityLevelReference `json:"priorityLevelConfiguration"`
+
+	// MatchingPrecedence orders the FlowSchemas, requests are matched by
+	// the one with the lowest precedence whose rules match
+	MatchingPrecedence int32 `json:"matchingPrecedence,omitempty"`
+
+	// Rules describe which requests match
+	Rules []FlowSchemaRule `json:"rules,omitempty"`
+}
+
+// PriorityLevelReference is a reference to a PriorityLevelConfiguration
+type PriorityLevelReference struct {
+	Name string `json:"name"`
+}
+
+// FlowSchemaRule matches the requests of any of its subjects that match
+// any of its resource rules
+type FlowSchemaRule struct {
+	Subjects      []FlowSchemaSubject      `json:"subjects"`
+	ResourceRules []FlowSchemaResourceRule `json:"resourceRules,omitempty"`
+}
+
+// FlowSchemaSubject is a user, group or service account requests are
+// matched by
+type FlowSchemaSubject struct {
+	Kind           string                 `json:"kind"`
+	User           *FlowSchemaSubjectName `json:"user,omitempty"`
+	Group          *FlowSchemaSubjectName `json:"group,omitempty"`
+	ServiceAccount *FlowSchemaSubjectName `json:"serviceAccount,omitempty"`
+}
+
+// FlowSchemaSubjectName is the name of a subject, service accounts also
+// have a namespace
+type FlowSchemaSubjectName struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// FlowSchemaResourceRule matches requests for resources
+type FlowSchemaResourceRule struct {
+	Verbs     []string `json:"verbs"`
+	APIGroups []string `json:"apiGroups"`
+	Resources []string `json:"resources"`
+}
+
+// FlowSchemaStatus is the status of a FlowSchema
+type FlowSchemaStatus struct {
+	Conditions []FlowSchemaCondition `json:"conditions,omitempty"`
+}
+
+// FlowSchemaCondition is a condition of a FlowSchema, e.g. Dangling
+type FlowSchemaCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// DeepCopyObject implements runtime.Object
+func (f *FlowSchema) DeepCopyObject() runtime.Object {
+	out := *f
+	f.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Rules = append([]FlowSchemaRule(nil), f.Spec.Rules...)
+	out.Status.Conditions = append([]FlowSchemaCondition(nil), f.Status.Conditions...)
+	return &out
+}
+
+// PriorityLevelConfiguration is a priority level requests are queued and
+// limited in, only the fields problems are checked against are decoded
+type PriorityLevelConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PriorityLevelSpec `json:"spec"`
+}
+
+// PriorityLevelSpec is the spec of a PriorityLevelConfiguration
+type PriorityLevelSpec struct {
+	// Type is Exempt or Limited
+	Type string `json:"type"`
+
+	// Limited is how requests are limited when the type is Limited
+	Limited *LimitedPriorityLevel `json:"limited,omitempty"`
+}
+
+// LimitedPriorityLevel is how the requests of a priority level are limited
+type LimitedPriorityLevel struct {
+	// AssuredConcurrencyShares are the priority level's shares of the API
+	// server's concurrency before v1beta3
+	AssuredConcurrencyShares int32 `json:"assuredConcurrencyShares,omitempty"`
+
+	// NominalConcurrencyShares are the priority level's shares of the
+	// API server's concurrency from v1beta3 on
+	NominalConcurrencyShares *int32 `json:"nominalConcurrencyShares,omitempty"`
+
+	// LimitResponse is what happens to requests when the priority level
+	// is busy
+	LimitResponse LimitResponse `json:"limitResponse"`
+}
+
+// LimitResponse is what happens to requests when a priority level is
+// busy, Queue or Reject
+type LimitResponse struct {
+	Type string `json:"type"`
+}
+
+// Shares returns the priority level's shares of the API server's
+// concurrency, whichever version of the API it was read from
+func (l *LimitedPriorityLevel) Shares() int32 {
+	if l.NominalConcurrencyShares != nil {
+		return *l.NominalConcurrencyShares
+	}
+	return l.AssuredConcurrencyShares
+}
+
+// DeepCopyObject implements runtime.Object
+func (p *PriorityLevelConfiguration) DeepCopyObject() runtime.Object {
+	out := *p
+	p.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if p.Spec.Limited != nil {
+		limited := *p.Spec.Limited
+		out.Spec.Limited = &limited
+	}
+	return &out
+}
+
+// listFlowControl lists a priority and fairness resource into items from
+// the newest version of the API the API server serves. Nothing is listed
+// when it serves none of them.
+func listFlowControl(ctx context.Context, k kubernetes.Interface, resource string, items interface{}) error {
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return nil
+	}
+
+	for _, version := range flowControlVersions {
+		body, err := rc.Get().AbsPath("/apis", flowControlGroup, version, resource).Do(ctx).Raw()
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to list %s.%s", resource, flowControlGroup)
+		}
+
+		list := struct {
+			Items interface{} `json:"items"`
+		}{Items: items}
+		return errors.Wrapf(json.Unmarshal(body, &list), "failed to decode %s.%s", resource, flowControlGroup)
+	}
+	return nil
+}
+
+// APIFlow is how the API server queues k8r's own requests
+type APIFlow struct {
+	// FlowSchema is the FlowSchema k8r's requests match
+	FlowSchema string
+
+	// PriorityLevel is the priority level k8r's requests are put in
+	PriorityLevel string
+
+	// WaitP99Seconds is the 99th percentile time the requests of the
+	// FlowSchema waited in a queue since the API server started
+	WaitP99Seconds float64
+
+	// Queued is how many requests of the FlowSchema are queued now
+	Queued float64
+
+	// Rejected is how many requests of the FlowSchema were rejected since
+	// the API server started
+	Rejected float64
+}
+
+// ownFlowSchema returns the UID of the FlowSchema the API server matched
+// a request of the client in, from the header it names it in
+func ownFlowSchema(ctx context.Context, rc rest.Interface) (string, bool) {
+	c, ok := rc.(*rest.RESTClient)
+	if !ok || c.Client == nil {
+		return "", false
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rc.Get().AbsPath("/version").URL().String(), http.NoBody)
+	if err != nil {
+		return "", false
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	uid := resp.Header.Get(flowSchemaUIDHeader)
+	return uid, uid != ""
+}
+
+// flowSchemaMetrics returns the series of a metric for a FlowSchema
+func flowSchemaMetrics(families map[string]*dto.MetricFamily, name, flowSchema string) []*dto.Metric {
+	f, ok := families[name]
+	if !ok {
+		return nil
+	}
+	series := make([]*dto.Metric, 0)
+	for _, m := range f.Metric {
+		if metricLabel(m, "flow_schema") == flowSchema {
+			series = append(series, m)
+		}
+	}
+	return series
+}
+
+// gatherAPIFlow finds the FlowSchema k8r's requests match and how long
+// requests of it wait in the API server's queues, from the API server's
+// metrics. This is best effort, nil is returned when either can't be
+// read.
+func gatherAPIFlow(ctx context.Context, k kubernetes.Interface, schemas []FlowSchema) *APIFlow {
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return nil
+	}
+	uid, ok := ownFlowSchema(ctx, rc)
+	if !ok {
+		return nil
+	}
+
+	flow := &APIFlow{}
+	for i := range schemas {
+		if string(schemas[i].UID) == uid {
+			flow.FlowSchema = schemas[i].Name
+			flow.PriorityLevel = schemas[i].Spec.PriorityLevelConfiguration.Name
+		}
+	}
+	if flow.FlowSchema == "" {
+		return nil
+	}
+
+	body, err := rc.Get().AbsPath("/metrics").Do(ctx).Raw()
+	if err != nil {
+		return nil
+	}
+	families, err := parseMetrics(body)
+	if err != nil {
+		return nil
+	}
+
+	// Requests that were rejected without being executed have a
+	// histogram of their own, the one of executed requests is used
+	var waited *dto.Histogram
+	for _, m := range flowSchemaMetrics(families, "apiserver_flowcontrol_request_wait_duration_seconds", flow.FlowSchema) {
+		if metricLabel(m, "execute") != "false" && m.Histogram != nil &&
+			(waited == nil || m.Histogram.GetSampleCount() > waited.GetSampleCount()) {
+			waited = m.Histogram
+		}
+	}
+	flow.WaitP99Seconds = histogramQuantile(0.99, waited)
+	for _, m := range flowSchemaMetrics(families, "apiserver_flowcontrol_current_inqueue_requests", flow.FlowSchema) {
+		flow.Queued += metricValue(m)
+	}
+	for _, m := range flowSchemaMetrics(families, "apiserver_flowcontrol_rejected_requests_total", flow.FlowSchema) {
+		flow.Rejected += metricValue(m)
+	}
+	return flow
+}
@@ -0,0 +1,232 @@
+// Description: This file contains code for problems with the API server's
+// priority and fairness configuration that starve controllers of API
+// requests, and with how heavily k8r's own requests are queued
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// flow control problem constants
+const (
+	// autoUpdateAnnotation is the annotation that stops the API server
+	// from restoring the built-in priority and fairness objects to their
+	// defaults when it is false
+	autoUpdateAnnotation = "apf.kubernetes.io/autoupdate-spec"
+
+	// controllerPrecedence is the matching precedence of the built-in
+	// FlowSchemas of kube-controller-manager and kube-scheduler, broad
+	// FlowSchemas below it take their requests
+	controllerPrecedence = 800
+
+	// apiFlowWaitThreshold is how long in seconds k8r's requests can
+	// wait in a queue at the 99th percentile before it is reported
+	apiFlowWaitThreshold = 1.0
+)
+
+// builtinFlowSchemas are the FlowSchemas the API server creates
+var builtinFlowSchemas = map[string]bool{
+	"exempt":                       true,
+	"probes":                       true,
+	"system-leader-election":       true,
+	"endpoint-controller":          true,
+	"workload-leader-election":     true,
+	"system-node-high":             true,
+	"system-nodes":                 true,
+	"kube-controller-manager":      true,
+	"kube-scheduler":               true,
+	"kube-system-service-accounts": true,
+	"service-accounts":             true,
+	"global-default":               true,
+	"catch-all":                    true,
+}
+
+// builtinPriorityLevelShares are the concurrency shares of the limited
+// priority levels the API server creates
+var builtinPriorityLevelShares = map[string]int32{
+	"system":          30,
+	"node-high":       40,
+	"leader-election": 10,
+	"workload-high":   40,
+	"workload-low":    100,
+	"global-default":  20,
+	"catch-all":       5,
+}
+
+// controllerPriorityLevels are the priority levels of the requests of
+// controllers and leader election, which fail when they are rejected
+var controllerPriorityLevels = map[string]bool{
+	"system":          true,
+	"leader-election": true,
+	"workload-high":   true,
+}
+
+// broadSubjects are the groups that match the requests of every
+// controller
+var broadSubjects = map[string]bool{
+	"system:authenticated":               true,
+	"system:serviceaccounts":             true,
+	"system:serviceaccounts:kube-system": true,
+}
+
+// broadSubject returns the subject of a FlowSchema that matches the
+// requests of every controller, if it has one
+func broadSubject(fs *FlowSchema) (string, bool) {
+	for _, rule := range fs.Spec.Rules {
+		for _, s := range rule.Subjects {
+			switch {
+			case s.Group != nil && broadSubjects[s.Group.Name]:
+				return "group " + s.Group.Name, true
+			case s.ServiceAccount != nil && s.ServiceAccount.Name == "*" &&
+				(s.ServiceAccount.Namespace == "*" || s.ServiceAccount.Namespace == "kube-system"):
+				return fmt.Sprintf("service accounts %s/*", s.ServiceAccount.Namespace), true
+			}
+		}
+	}
+	return "", false
+}
+
+// priorityLevel returns the PriorityLevelConfiguration with the given name
+func priorityLevel(name string, levels []PriorityLevelConfiguration) (*PriorityLevelConfiguration, bool) {
+	for i := range levels {
+		if levels[i].Name == name {
+			return &levels[i], true
+		}
+	}
+	return nil, false
+}
+
+// ProblemFlowSchemaMisconfigured is a problem with a FlowSchema that
+// starves controllers or bypasses priority and fairness: one that matches
+// every controller's requests ahead of their own FlowSchemas, one that
+// isn't built in but puts requests in an exempt priority level, or one
+// whose priority level doesn't exist
+// https://github.com/Ashvin-Ranjan/k8r/wiki/FlowSchemaMisconfigured
+var ProblemFlowSchemaMisconfigured = Problem{
+	ID:               "FlowSchemaMisconfigured",
+	ShortDescription: "A FlowSchema takes controllers' API requests, exempts requests from limits or refers to a missing priority level",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/FlowSchemaMisconfigured",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		fs, ok := obj.(*FlowSchema)
+		if !ok || cfg.Cluster == nil || builtinFlowSchemas[fs.Name] {
+			return "", false, false
+		}
+		level := fs.Spec.PriorityLevelConfiguration.Name
+
+		pl, ok := priorityLevel(level, cfg.Cluster.PriorityLevels)
+		if !ok {
+			details := fmt.Sprintf("Priority level %s doesn't exist", level)
+			for _, c := range fs.Status.Conditions {
+				if c.Type == "Dangling" && c.Status == "True" && c.Message != "" {
+					details += ", " + c.Message
+				}
+			}
+			return details + ", so the API server skips the FlowSchema", true, true
+		}
+
+		subject, broad := broadSubject(fs)
+		if pl.Spec.Type == priorityLevelTypeExempt {
+			details := fmt.Sprintf("Requests that match are put in exempt priority level %s, so they are never queued or limited "+
+				"and a misbehaving client can overload the API server", level)
+			if broad {
+				return details + fmt.Sprintf(", and the FlowSchema matches %s", subject), false, true
+			}
+			return details + ", exempt only requests that must never wait, like the built-in exempt FlowSchema does", true, true
+		}
+
+		if broad && fs.Spec.MatchingPrecedence < controllerPrecedence && !controllerPriorityLevels[level] {
+			return fmt.Sprintf("FlowSchema matches %s with precedence %d, ahead of the built-in FlowSchemas of "+
+				"kube-controller-manager and kube-scheduler at %d, so their requests compete in priority level %s "+
+				"instead of workload-high, raise its matchingPrecedence or narrow its subjects",
+				subject, fs.Spec.MatchingPrecedence, controllerPrecedence, level), false, true
+		}
+		return "", false, false
+	},
+}
+
+// ProblemPriorityLevelStarved is a problem with a built-in priority level
+// that was given fewer concurrency shares than its default, or that
+// controllers and leader election use and rejects requests when it is busy
+// instead of queuing them. The API server restores the built-in priority
+// levels unless their autoupdate annotation is false, so these are
+// deliberate changes.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PriorityLevelStarved
+var ProblemPriorityLevelStarved = Problem{
+	ID:               "PriorityLevelStarved",
+	ShortDescription: "A built-in priority level was lowered or rejects requests, which starves the clients that use it",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PriorityLevelStarved",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pl, ok := obj.(*PriorityLevelConfiguration)
+		if !ok || pl.Spec.Limited == nil {
+			return "", false, false
+		}
+		defaultShares, builtin := builtinPriorityLevelShares[pl.Name]
+		if !builtin {
+			return "", false, false
+		}
+
+		findings := make([]string, 0, 2)
+		if shares := pl.Spec.Limited.Shares(); shares < defaultShares {
+			findings = append(findings, fmt.Sprintf("has %d concurrency shares, less than the default of %d", shares, defaultShares))
+		}
+		if controllerPriorityLevels[pl.Name] && pl.Spec.Limited.LimitResponse.Type == limitResponseReject {
+			findings = append(findings, "rejects requests when it is busy instead of queuing them, "+
+				"so controllers' requests and leader election renewals fail under load")
+		}
+		if len(findings) == 0 {
+			return "", false, false
+		}
+
+		// Only the FlowSchemas that put requests in the priority level are
+		// starved
+		schemas := make([]string, 0)
+		if cfg.Cluster != nil {
+			for i := range cfg.Cluster.FlowSchemas {
+				if fs := &cfg.Cluster.FlowSchemas[i]; fs.Spec.PriorityLevelConfiguration.Name == pl.Name {
+					schemas = append(schemas, fs.Name)
+				}
+			}
+		}
+		details := "Priority level " + strings.Join(findings, ", and ")
+		if len(schemas) != 0 {
+			sort.Strings(schemas)
+			details += ", it serves FlowSchemas " + strings.Join(schemas, ", ")
+		}
+		if pl.Annotations[autoUpdateAnnotation] == "false" {
+			details += fmt.Sprintf(", set %s to true to restore its defaults", autoUpdateAnnotation)
+		}
+		return details, true, true
+	},
+}
+
+// ProblemAPIRequestsQueued is a problem with the control plane when the
+// requests of the FlowSchema k8r's own requests match wait long in the
+// API server's queues or are rejected, which slows down scans and means
+// other clients in the same priority level are throttled too
+// https://github.com/Ashvin-Ranjan/k8r/wiki/APIRequestsQueued
+var ProblemAPIRequestsQueued = Problem{
+	ID:               "APIRequestsQueued",
+	ShortDescription: "The API server queues or rejects requests like k8r's own, so clients in the same priority level are throttled",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/APIRequestsQueued",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		cp, ok := obj.(*ControlPlane)
+		if !ok || cp.APIFlow == nil {
+			return "", false, false
+		}
+		flow := cp.APIFlow
+		if flow.WaitP99Seconds < apiFlowWaitThreshold && flow.Rejected == 0 {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("k8r's requests match FlowSchema %s in priority level %s, whose requests waited up to %gs in a queue "+
+			"at the 99th percentile and %.0f were rejected since the API server started, %.0f are queued now, "+
+			"give the priority level more concurrency shares or find the clients that flood it",
+			flow.FlowSchema, flow.PriorityLevel, flow.WaitP99Seconds, flow.Rejected, flow.Queued), true, true
+	},
+}
@@ -0,0 +1,123 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFlowSchemaMisconfigured(t *testing.T) {
+	levels := []runtime.Object{
+		checkuptest.NewPriorityLevel("exempt", 0),
+		checkuptest.NewPriorityLevel("workload-low", 100),
+		checkuptest.NewPriorityLevel("batch", 10),
+	}
+	// withLevels returns a config with the priority levels and the
+	// FlowSchema
+	withLevels := func(fs *checkup.FlowSchema) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(append([]runtime.Object{fs}, levels...)...))
+	}
+
+	narrow := checkuptest.NewFlowSchema("batch-jobs", "batch-runners", "batch", 1000)
+	catchAll := checkuptest.NewFlowSchema("everyone-batch", "system:authenticated", "batch", 500)
+	exemptTeam := checkuptest.NewFlowSchema("ops", "ops-team", "exempt", 300)
+	exemptAll := checkuptest.NewFlowSchema("no-limits", "system:serviceaccounts", "exempt", 300)
+	dangling := checkuptest.NewFlowSchema("reports", "reporting", "reports", 1000)
+	dangling.Status.Conditions = []checkup.FlowSchemaCondition{{
+		Type: "Dangling", Status: "True", Message: "This FlowSchema references the PriorityLevelConfiguration object named \"reports\" but there is no such object",
+	}}
+	builtin := checkuptest.NewFlowSchema("exempt", "system:masters", "exempt", 1)
+
+	checkuptest.RunCases(t, checkup.ProblemFlowSchemaMisconfigured, []checkuptest.Case{
+		{Name: "narrow", Object: narrow, Config: withLevels(narrow)},
+		{Name: "built in", Object: builtin, Config: withLevels(builtin)},
+		{
+			Name:           "takes controllers' requests",
+			Object:         catchAll,
+			Config:         withLevels(catchAll),
+			Occurring:      true,
+			DetailsContain: "FlowSchema matches group system:authenticated with precedence 500, ahead of the built-in FlowSchemas",
+		},
+		{
+			Name:           "exempts a team",
+			Object:         exemptTeam,
+			Config:         withLevels(exemptTeam),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Requests that match are put in exempt priority level exempt, so they are never queued or limited",
+		},
+		{
+			Name:           "exempts every service account",
+			Object:         exemptAll,
+			Config:         withLevels(exemptAll),
+			Occurring:      true,
+			DetailsContain: "and the FlowSchema matches group system:serviceaccounts",
+		},
+		{
+			Name:           "dangling",
+			Object:         dangling,
+			Config:         withLevels(dangling),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Priority level reports doesn't exist, This FlowSchema references",
+		},
+	})
+}
+
+func TestPriorityLevelStarved(t *testing.T) {
+	lowered := checkuptest.NewPriorityLevel("catch-all", 1)
+	lowered.Annotations = map[string]string{"apf.kubernetes.io/autoupdate-spec": "false"}
+	rejecting := checkuptest.NewPriorityLevel("leader-election", 10)
+	rejecting.Spec.Limited.LimitResponse.Type = "Reject"
+	cluster := checkuptest.NewCluster(lowered, checkuptest.NewFlowSchema("catch-all", "system:unauthenticated", "catch-all", 10000))
+
+	checkuptest.RunCases(t, checkup.ProblemPriorityLevelStarved, []checkuptest.Case{
+		{Name: "default", Object: checkuptest.NewPriorityLevel("workload-high", 40)},
+		{Name: "custom", Object: checkuptest.NewPriorityLevel("batch", 1)},
+		{Name: "exempt", Object: checkuptest.NewPriorityLevel("exempt", 0)},
+		{
+			Name:      "catch-all lowered",
+			Object:    lowered,
+			Config:    checkuptest.NewConfig(cluster),
+			Occurring: true,
+			Warning:   true,
+			DetailsContain: "Priority level has 1 concurrency shares, less than the default of 5, it serves FlowSchemas catch-all, " +
+				"set apf.kubernetes.io/autoupdate-spec to true to restore its defaults",
+		},
+		{
+			Name:           "leader election rejects",
+			Object:         rejecting,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Priority level rejects requests when it is busy instead of queuing them",
+		},
+	})
+}
+
+func TestAPIRequestsQueued(t *testing.T) {
+	withFlow := func(flow checkup.APIFlow) *checkup.ControlPlane {
+		return &checkup.ControlPlane{EtcdReady: true, APIFlow: &flow}
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemAPIRequestsQueued, []checkuptest.Case{
+		{Name: "unknown", Object: controlPlane()},
+		{Name: "fast", Object: withFlow(checkup.APIFlow{FlowSchema: "global-default", PriorityLevel: "global-default", WaitP99Seconds: 0.1})},
+		{
+			Name:      "queued",
+			Object:    withFlow(checkup.APIFlow{FlowSchema: "global-default", PriorityLevel: "global-default", WaitP99Seconds: 5, Queued: 12}),
+			Occurring: true,
+			Warning:   true,
+			DetailsContain: "k8r's requests match FlowSchema global-default in priority level global-default, whose requests waited up to 5s " +
+				"in a queue at the 99th percentile and 0 were rejected since the API server started, 12 are queued now",
+		},
+		{
+			Name:           "rejected",
+			Object:         withFlow(checkup.APIFlow{FlowSchema: "service-accounts", PriorityLevel: "workload-low", Rejected: 3}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "3 were rejected",
+		},
+	})
+}
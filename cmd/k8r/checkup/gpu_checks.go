@@ -0,0 +1,213 @@
+// Description: This file contains code for problems related to scheduling
+// pods that request GPUs and other extended resources
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// gpuHardwareLabels are node labels that node feature discovery, the GPU
+// operator or cloud providers set on nodes with GPUs, mapped to the
+// extended resource the GPUs should be advertised as by a device plugin
+var gpuHardwareLabels = map[string]corev1.ResourceName{
+	"nvidia.com/gpu.present":                      "nvidia.com/gpu",
+	"feature.node.kubernetes.io/pci-10de.present": "nvidia.com/gpu",
+	"cloud.google.com/gke-accelerator":            "nvidia.com/gpu",
+	"feature.node.kubernetes.io/pci-1002.present": "amd.com/gpu",
+}
+
+// isExtendedResource returns true if the resource is an extended resource,
+// i.e. one advertised by a device plugin or an administrator rather than
+// by Kubernetes itself
+func isExtendedResource(name corev1.ResourceName) bool {
+	n := string(name)
+	i := strings.Index(n, "/")
+	if i == -1 || strings.HasPrefix(n, corev1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	domain := n[:i]
+	return domain != "kubernetes.io" && !strings.HasSuffix(domain, ".kubernetes.io")
+}
+
+// podExtendedResources returns the extended resources the containers of a
+// pod request, sorted by name
+func podExtendedResources(pod *corev1.Pod) []corev1.ResourceName {
+	seen := make(map[corev1.ResourceName]bool)
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for i := range containers {
+		// Extended resources can't be overcommitted, so they are often
+		// only set as limits which the requests default to
+		for _, list := range []corev1.ResourceList{containers[i].Resources.Requests, containers[i].Resources.Limits} {
+			for name, q := range list {
+				if isExtendedResource(name) && !q.IsZero() {
+					seen[name] = true
+				}
+			}
+		}
+	}
+
+	resources := make([]corev1.ResourceName, 0, len(seen))
+	for name := range seen {
+		resources = append(resources, name)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+	return resources
+}
+
+// nodeAdvertises returns true if the node has a non-zero amount of the
+// resource allocatable
+func nodeAdvertises(node *corev1.Node, name corev1.ResourceName) bool {
+	q, ok := node.Status.Allocatable[name]
+	return ok && !q.IsZero()
+}
+
+// isDevicePlugin returns true if a DaemonSet looks like it runs a device
+// plugin, e.g. the NVIDIA device plugin
+func isDevicePlugin(ds *appsv1.DaemonSet) bool {
+	if strings.Contains(ds.Name, "device-plugin") {
+		return true
+	}
+	for i := range ds.Spec.Template.Spec.Containers {
+		if strings.Contains(ds.Spec.Template.Spec.Containers[i].Image, "device-plugin") {
+			return true
+		}
+	}
+	return false
+}
+
+// daemonSetPods returns the pods owned by a DaemonSet
+func daemonSetPods(ds *appsv1.DaemonSet, pods []corev1.Pod) []*corev1.Pod {
+	owned := make([]*corev1.Pod, 0)
+	for i := range pods {
+		p := &pods[i]
+		if p.Namespace != ds.Namespace {
+			continue
+		}
+		for _, ref := range p.OwnerReferences {
+			if ref.Kind == "DaemonSet" && ref.Name == ds.Name {
+				owned = append(owned, p)
+			}
+		}
+	}
+	return owned
+}
+
+// ProblemPodExtendedResourceUnavailable is a problem with a pod that is
+// pending because it requests an extended resource, e.g. nvidia.com/gpu,
+// that no node advertises
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodExtendedResourceUnavailable
+var ProblemPodExtendedResourceUnavailable = Problem{
+	ID:               "PodExtendedResourceUnavailable",
+	ShortDescription: "A pod can't be scheduled because no node advertises an extended resource it requests, e.g. a GPU",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodExtendedResourceUnavailable",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Status.Phase != corev1.PodPending || pod.Spec.NodeName != "" {
+			return "", false, false
+		}
+		// Without nodes, e.g. when linting, we can't tell what is advertised
+		if cfg.Cluster == nil || len(cfg.Cluster.Nodes) == 0 {
+			return "", false, false
+		}
+
+		missing := make([]string, 0)
+		for _, name := range podExtendedResources(pod) {
+			advertised := false
+			for i := range cfg.Cluster.Nodes {
+				if nodeAdvertises(&cfg.Cluster.Nodes[i], name) {
+					advertised = true
+					break
+				}
+			}
+			if !advertised {
+				missing = append(missing, string(name))
+			}
+		}
+
+		if len(missing) == 0 {
+			return "", false, false
+		}
+		return fmt.Sprintf("Requests %s but no node advertises it, check that nodes with the hardware exist and their device plugin is running",
+			strings.Join(missing, ", ")), false, true
+	},
+}
+
+// ProblemDevicePluginUnhealthy is a problem with a device plugin DaemonSet
+// that doesn't have a ready pod on every node it should run on, those
+// nodes stop advertising their devices
+// https://github.com/Ashvin-Ranjan/k8r/wiki/DevicePluginUnhealthy
+var ProblemDevicePluginUnhealthy = Problem{
+	ID:               "DevicePluginUnhealthy",
+	ShortDescription: "A device plugin DaemonSet isn't ready on every node, so their devices, e.g. GPUs, can't be scheduled",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/DevicePluginUnhealthy",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		ds, ok := obj.(*appsv1.DaemonSet)
+		if !ok || !isDevicePlugin(ds) {
+			return "", false, false
+		}
+
+		desired, ready := ds.Status.DesiredNumberScheduled, ds.Status.NumberReady
+		if ready >= desired && ds.Status.NumberUnavailable == 0 {
+			return "", false, false
+		}
+
+		details := fmt.Sprintf("Device plugin has %d/%d pods ready", ready, desired)
+		if cfg.Cluster != nil {
+			nodes := make([]string, 0)
+			for _, p := range daemonSetPods(ds, cfg.Cluster.Pods) {
+				if !podReady(p) && p.Spec.NodeName != "" {
+					nodes = append(nodes, p.Spec.NodeName)
+				}
+			}
+			if len(nodes) != 0 {
+				sort.Strings(nodes)
+				details += fmt.Sprintf(", it isn't ready on node(s) %s", strings.Join(nodes, ", "))
+			}
+		}
+		return details, false, true
+	},
+}
+
+// ProblemNodeGPUNotAllocatable is a problem with a node that is labeled as
+// having GPUs but doesn't advertise any, usually because the device plugin
+// or driver isn't working on it
+// https://github.com/Ashvin-Ranjan/k8r/wiki/NodeGPUNotAllocatable
+var ProblemNodeGPUNotAllocatable = Problem{
+	ID:               "NodeGPUNotAllocatable",
+	ShortDescription: "A node has GPU hardware but no GPUs allocatable",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/NodeGPUNotAllocatable",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return "", false, false
+		}
+
+		labels := make([]string, 0)
+		for label := range gpuHardwareLabels {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		for _, label := range labels {
+			value, ok := node.Labels[label]
+			if !ok || value == "" || value == "false" {
+				continue
+			}
+
+			resource := gpuHardwareLabels[label]
+			if !nodeAdvertises(node, resource) {
+				return fmt.Sprintf("Node is labeled %s=%s but has no %s allocatable, check the GPU driver and device plugin on it",
+					label, value, resource), false, true
+			}
+		}
+		return "", false, false
+	},
+}
@@ -0,0 +1,71 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestPodExtendedResourceUnavailable(t *testing.T) {
+	pending := checkuptest.NewPod("train",
+		checkuptest.Pending(""), checkuptest.WithResourceLimit(checkuptest.DefaultContainer, "nvidia.com/gpu", "1"))
+	cpuNode := checkuptest.NewNode("cpu-1")
+	gpuNode := checkuptest.NewNode("gpu-1", checkuptest.NodeAllocatable("nvidia.com/gpu", "4"))
+
+	checkuptest.RunCases(t, checkup.ProblemPodExtendedResourceUnavailable, []checkuptest.Case{
+		{
+			Name:   "advertised",
+			Object: pending,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(pending, cpuNode, gpuNode)),
+		},
+		{
+			Name:           "not advertised",
+			Object:         pending,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(pending, cpuNode)),
+			Occurring:      true,
+			DetailsContain: "Requests nvidia.com/gpu but no node advertises it",
+		},
+		{
+			Name:   "no extended resources",
+			Object: checkuptest.NewPod("web", checkuptest.Pending("")),
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(cpuNode)),
+		},
+		{Name: "no nodes", Object: pending},
+	})
+}
+
+func TestDevicePluginUnhealthy(t *testing.T) {
+	plugin := checkuptest.NewDaemonSet("nvidia-device-plugin", "nvcr.io/nvidia/k8s-device-plugin:v0.14.0", 2, 1)
+	broken := checkuptest.NewPod("nvidia-device-plugin-abc", checkuptest.OnNode("gpu-2"),
+		checkuptest.OwnedBy("DaemonSet", "nvidia-device-plugin"), checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer))
+
+	checkuptest.RunCases(t, checkup.ProblemDevicePluginUnhealthy, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewDaemonSet("nvidia-device-plugin", "nvcr.io/nvidia/k8s-device-plugin:v0.14.0", 2, 2)},
+		{
+			Name:           "not ready on a node",
+			Object:         plugin,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(plugin, broken)),
+			Occurring:      true,
+			DetailsContain: "Device plugin has 1/2 pods ready, it isn't ready on node(s) gpu-2",
+		},
+		{Name: "not a device plugin", Object: checkuptest.NewDaemonSet("fluent-bit", "fluent/fluent-bit:2.0", 2, 1)},
+	})
+}
+
+func TestNodeGPUNotAllocatable(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemNodeGPUNotAllocatable, []checkuptest.Case{
+		{Name: "no GPUs", Object: checkuptest.NewNode("cpu-1")},
+		{
+			Name: "GPUs allocatable",
+			Object: checkuptest.NewNode("gpu-1", checkuptest.NodeLabels(map[string]string{"nvidia.com/gpu.present": "true"}),
+				checkuptest.NodeAllocatable("nvidia.com/gpu", "4")),
+		},
+		{
+			Name:           "GPUs not allocatable",
+			Object:         checkuptest.NewNode("gpu-1", checkuptest.NodeLabels(map[string]string{"cloud.google.com/gke-accelerator": "nvidia-tesla-t4"})),
+			Occurring:      true,
+			DetailsContain: "Node is labeled cloud.google.com/gke-accelerator=nvidia-tesla-t4 but has no nvidia.com/gpu allocatable",
+		},
+	})
+}
@@ -0,0 +1,158 @@
+// Description: This file contains code for posting Grafana annotations
+// when errors are found or resolved, so that they show up on the
+// timelines of existing dashboards
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// grafanaTimeout is how long posting an annotation to Grafana can take
+// before the checkup gives up on it
+const grafanaTimeout = 30 * time.Second
+
+// grafanaAnnotation is an annotation sent to the Grafana HTTP API, it is
+// shown on every dashboard that queries its tags when there is no
+// dashboard
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	Time         int64    `json:"time"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// DefaultGrafanaStateFile returns where the errors annotated in Grafana
+// are recorded by default, or an empty string if there is no cache
+// directory
+func DefaultGrafanaStateFile() string {
+	return defaultNotifiedFile("grafana")
+}
+
+// grafanaTags returns the tags of an annotation about an error, which is
+// the key it's recorded by, on top of the tags every annotation gets
+func grafanaTags(key, change string, common []string) []string {
+	tags := append(append([]string{}, common...), "k8r", change)
+	if parts := strings.SplitN(key, "|", 3); len(parts) == 3 {
+		tags = append(tags, "problem_id:"+parts[0])
+		if i := strings.Index(parts[2], "/"); i >= 0 {
+			tags = append(tags, "namespace:"+parts[2][:i])
+		}
+	}
+	return tags
+}
+
+// grafanaText returns the text of an annotation about an error, which is
+// the key it's recorded by
+func grafanaText(key, change string) string {
+	parts := strings.SplitN(key, "|", 3)
+	if len(parts) != 3 {
+		return fmt.Sprintf("k8r: %s %s", key, change)
+	}
+	return fmt.Sprintf("k8r: %s on %s %s %s", parts[0], parts[1], parts[2], change)
+}
+
+// postGrafanaAnnotation sends an annotation to the Grafana HTTP API
+func (o *Options) postGrafanaAnnotation(ctx context.Context, annotation *grafanaAnnotation) error {
+	b, err := json.Marshal(annotation)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal grafana annotation")
+	}
+
+	// A Grafana that doesn't respond shouldn't hang the whole checkup
+	ctx, cancel := context.WithTimeout(ctx, grafanaTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(o.cfg.GrafanaURL, "/")+"/api/annotations", bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to create grafana request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.cfg.GrafanaToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.cfg.GrafanaToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send to grafana")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to send to grafana: got status %s", resp.Status)
+	}
+	return nil
+}
+
+// exportToGrafana posts an annotation for each error in a report that
+// wasn't found in the last run against the cluster, and for each error of
+// the last run that was resolved since
+func (o *Options) exportToGrafana(ctx context.Context, report *Report, cluster string) error {
+	if o.cfg.GrafanaURL == "" {
+		return nil
+	}
+
+	common := append([]string{}, o.cfg.GrafanaTags...)
+	if cluster != "" {
+		common = append(common, "cluster:"+cluster)
+	}
+
+	state, err := loadNotifiedErrors(o.cfg.GrafanaStateFile)
+	if err != nil {
+		return err
+	}
+	annotated := make(map[string]bool, len(state[cluster]))
+	for _, key := range state[cluster] {
+		annotated[key] = true
+	}
+
+	now := time.Now().UnixMilli()
+	post := func(key, change string) error {
+		return o.postGrafanaAnnotation(ctx, &grafanaAnnotation{
+			DashboardUID: o.cfg.GrafanaDashboard,
+			Time:         now,
+			Tags:         grafanaTags(key, change, common),
+			Text:         grafanaText(key, change),
+		})
+	}
+
+	errs := make([]string, 0)
+	found := make(map[string]bool)
+	for i := range report.Resources {
+		r := &report.Resources[i]
+		if !r.GetSeverity().AtLeast(SeverityError) {
+			continue
+		}
+		key := notifiedKey(r)
+		if found[key] {
+			continue
+		}
+		errs = append(errs, key)
+		found[key] = true
+		if annotated[key] {
+			continue
+		}
+		if err := post(key, "detected"); err != nil {
+			return err
+		}
+	}
+	for _, key := range state[cluster] {
+		if found[key] {
+			continue
+		}
+		if err := post(key, "resolved"); err != nil {
+			return err
+		}
+	}
+
+	state[cluster] = errs
+	return state.save(o.cfg.GrafanaStateFile)
+}
@@ -0,0 +1,68 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGrafana(t *testing.T) {
+	var annotations []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Path != "/api/annotations" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var a struct {
+			DashboardUID string `json:"dashboardUID"`
+			Text         string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&a)
+		if a.DashboardUID != "cluster-health" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		annotations = append(annotations, a.Text)
+	}))
+	defer srv.Close()
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.GrafanaURL = srv.URL
+	cfg.GrafanaToken = "token"
+	cfg.GrafanaDashboard = "cluster-health"
+	cfg.GrafanaStateFile = filepath.Join(t.TempDir(), "grafana.json")
+
+	// Errors are annotated when they are first found and once resolved
+	notReady := []runtime.Object{checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer))}
+	ready := []runtime.Object{checkuptest.NewPod("api")}
+	for run, tc := range []struct {
+		objs    []runtime.Object
+		wantErr error
+		want    []string
+	}{
+		{notReady, checkup.ErrProblemsFound, []string{"k8r: PodNotReady on pod default/api detected"}},
+		{notReady, checkup.ErrProblemsFound, nil},
+		{ready, nil, []string{"k8r: PodNotReady on pod default/api resolved"}},
+		{ready, nil, nil},
+	} {
+		annotations = nil
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &bytes.Buffer{})
+		if err := o.RunWithClient(context.Background(), newClientset(tc.objs, nil)); err != tc.wantErr { //nolint:errorlint // Why: Checking the exact error
+			t.Fatalf("run %d: RunWithClient() error = %v, expected %v", run, err, tc.wantErr)
+		}
+		if !reflect.DeepEqual(annotations, tc.want) {
+			t.Errorf("run %d: posted annotations %q, expected %q", run, annotations, tc.want)
+		}
+	}
+}
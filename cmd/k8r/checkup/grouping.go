@@ -0,0 +1,292 @@
+// Description: This file contains code for grouping the problems in the
+// report by something other than the problem, e.g. by node or namespace
+
+package checkup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Ways the problems in the report can be grouped
+const (
+	// GroupByProblem groups problems by their ID, this is the default
+	GroupByProblem = "problem"
+
+	// GroupByNode groups problems by the node the pod they were found on
+	// is scheduled on
+	GroupByNode = "node"
+
+	// GroupByNamespace groups problems by the namespace of the resource
+	// they were found on, with a health score for each namespace
+	GroupByNamespace = "namespace"
+)
+
+// groupings are the values the group-by flag accepts
+var groupings = []string{GroupByProblem, GroupByNode, GroupByNamespace}
+
+// Orders namespaces can be sorted in when grouping by namespace
+const (
+	// SortByScore sorts namespaces by their health score, worst first,
+	// this is the default
+	SortByScore = "score"
+
+	// SortByName sorts namespaces by name
+	SortByName = "name"
+)
+
+// namespaceSorts are the values the sort-namespaces-by flag accepts
+var namespaceSorts = []string{SortByScore, SortByName}
+
+// unscheduledGroup is the group of pods that aren't scheduled on a node
+const unscheduledGroup = "(unscheduled)"
+
+// clusterScopedGroup is the group of resources that aren't in a namespace
+const clusterScopedGroup = "(cluster-scoped)"
+
+// Weights of the problems in a namespace that are subtracted from its
+// health score, which starts at maxHealthScore and can't go below zero
+const (
+	maxHealthScore = 100
+	criticalWeight = 25
+	errorWeight    = 10
+	warningWeight  = 3
+	infoWeight     = 1
+)
+
+// concentrationThreshold is the share of pod problems on a single node
+// above which the node is called out as the likely cause
+const concentrationThreshold = 0.5
+
+// parseGroupBy validates the value of the group-by flag
+func parseGroupBy(s string) (string, error) {
+	for _, g := range groupings {
+		if s == g {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --group-by %q, expected one of %s", s, strings.Join(groupings, ", "))
+}
+
+// parseNamespaceSort validates the value of the sort-namespaces-by flag
+func parseNamespaceSort(s string) (string, error) {
+	for _, order := range namespaceSorts {
+		if s == order {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --sort-namespaces-by %q, expected one of %s", s, strings.Join(namespaceSorts, ", "))
+}
+
+// resourceGroup is the problems in a group
+type resourceGroup struct {
+	// Name is the name of the group, e.g. the name of a node
+	Name string
+
+	// Resources are the problems in the group
+	Resources []*Resource
+
+	// Pods is the number of problems found on pods
+	Pods int
+
+	// Critical, Errors, Warnings and Info are the number of problems of
+	// each severity, symptoms of other problems aren't counted
+	Critical int
+	Errors   int
+	Warnings int
+	Info     int
+}
+
+// HealthScore returns the health score of the group, maxHealthScore
+// when there are no problems and lower the more and worse the problems
+// are, down to zero
+func (g *resourceGroup) HealthScore() int {
+	score := maxHealthScore - g.Critical*criticalWeight - g.Errors*errorWeight -
+		g.Warnings*warningWeight - g.Info*infoWeight
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// resourceNamespace returns the namespace of the resource a problem was
+// found on, or clusterScopedGroup when it isn't in a namespace
+func resourceNamespace(r *Resource) string {
+	if r.Type == "namespace" {
+		return r.Name
+	}
+	if i := strings.Index(r.Name, "/"); i > 0 {
+		return r.Name[:i]
+	}
+	return clusterScopedGroup
+}
+
+// groupByNamespace groups problems by the namespace of the resource they
+// were found on, problems found on a namespace are grouped with the
+// resources in it. The groups are sorted by sortBy, ties by name, and by
+// score when it isn't set.
+func groupByNamespace(resources []Resource, sortBy string) []resourceGroup {
+	byNamespace := make(map[string]*resourceGroup)
+	for i := range resources {
+		r := &resources[i]
+
+		namespace := resourceNamespace(r)
+		g, ok := byNamespace[namespace]
+		if !ok {
+			g = &resourceGroup{Name: namespace}
+			byNamespace[namespace] = g
+		}
+		g.Resources = append(g.Resources, r)
+
+		if r.SuppressedBy != "" {
+			continue
+		}
+		switch r.GetSeverity() {
+		case SeverityCritical:
+			g.Critical++
+		case SeverityError:
+			g.Errors++
+		case SeverityWarning:
+			g.Warnings++
+		case SeverityInfo:
+			g.Info++
+		}
+	}
+
+	groups := make([]resourceGroup, 0, len(byNamespace))
+	for _, g := range byNamespace {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if sortBy != SortByName {
+			if a, b := groups[i].HealthScore(), groups[j].HealthScore(); a != b {
+				return a < b
+			}
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// groupByNode groups problems by the node the pod they were found on is
+// scheduled on, problems found on a node are grouped with its pods. The
+// groups are sorted by the number of pod problems, most first, and
+// problems that aren't on a node are returned separately.
+func groupByNode(resources []Resource) (groups []resourceGroup, other []*Resource) {
+	byNode := make(map[string]*resourceGroup)
+	for i := range resources {
+		r := &resources[i]
+
+		var node string
+		switch {
+		case r.Type == "node":
+			node = r.Name
+		case r.Type == "pod" && r.Node != "":
+			node = r.Node
+		case r.Type == "pod":
+			node = unscheduledGroup
+		default:
+			other = append(other, r)
+			continue
+		}
+
+		g, ok := byNode[node]
+		if !ok {
+			g = &resourceGroup{Name: node}
+			byNode[node] = g
+		}
+		g.Resources = append(g.Resources, r)
+		if r.Type == "pod" {
+			g.Pods++
+		}
+	}
+
+	for _, g := range byNode {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Pods != groups[j].Pods {
+			return groups[i].Pods > groups[j].Pods
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, other
+}
+
+// printGroup prints the problems in a group, one per line
+func (o *Options) printGroup(name string, resources []*Resource) {
+	fmt.Fprintln(o.out, "")
+	plural := ""
+	if len(resources) > 1 {
+		plural = "s"
+	}
+	fmt.Fprintf(o.out, "    %s %s\n", bold.Sprint(name), bold.Sprintf("[%d problem%s]", len(resources), plural))
+
+	tw := tabwriter.NewWriter(o.out, 1, 0, 1, ' ', 0)
+	for _, r := range resources {
+		colorFn := r.GetSeverity().colorFn()
+		fmt.Fprintf(tw, "    - %s %s:\t%s%s\n", bold.Sprint(r.Name), colorFn(r.ProblemID), suppressedDetails(r), resourceContext(r, time.Now()))
+	}
+	tw.Flush()
+}
+
+// printByNode prints the problems in a report grouped by node, calling
+// out a node when most pod problems are on it
+func (o *Options) printByNode(report *Report) {
+	groups, other := groupByNode(report.Resources)
+
+	fmt.Fprintln(o.out, "")
+	bold.Fprintln(o.out, "⛔️  Problems found by node (format: namespace/name <problem>):")
+
+	total := 0
+	for i := range groups {
+		total += groups[i].Pods
+	}
+	if len(groups) != 0 && groups[0].Name != unscheduledGroup && total > 1 {
+		if share := float64(groups[0].Pods) / float64(total); share > concentrationThreshold {
+			fmt.Fprintln(o.out, "")
+			fmt.Fprintln(o.out, color.New(color.Bold, color.FgYellow).Sprintf(
+				"⚠️  %.0f%% of pod problems (%d/%d) are on node %s, check the node before its pods",
+				share*100, groups[0].Pods, total, groups[0].Name))
+		}
+	}
+
+	for i := range groups {
+		o.printGroup(groups[i].Name, groups[i].Resources)
+	}
+	if len(other) != 0 {
+		o.printGroup("Not on a node", other)
+	}
+}
+
+// printByNamespace prints a health score for each namespace followed by
+// the problems in the report grouped by namespace
+func (o *Options) printByNamespace(report *Report) {
+	groups := groupByNamespace(report.Resources, o.cfg.SortNamespacesBy)
+
+	fmt.Fprintln(o.out, "")
+	bold.Fprintf(o.out, "🩺  Namespace health (%d is healthy):\n", maxHealthScore)
+	tw := tabwriter.NewWriter(o.out, 1, 0, 1, ' ', 0)
+	for i := range groups {
+		g := &groups[i]
+
+		colorFn := color.HiRedString
+		if g.Critical == 0 && g.Errors == 0 {
+			colorFn = color.HiYellowString
+		}
+		fmt.Fprintf(tw, "    - %s:\t%s\t(%d critical, %d errors, %d warnings, %d info)\n",
+			bold.Sprint(g.Name), colorFn("%d", g.HealthScore()), g.Critical, g.Errors, g.Warnings, g.Info)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(o.out, "")
+	bold.Fprintln(o.out, "⛔️  Problems found by namespace (format: namespace/name <problem>):")
+	for i := range groups {
+		o.printGroup(groups[i].Name, groups[i].Resources)
+	}
+}
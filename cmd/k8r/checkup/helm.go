@@ -0,0 +1,40 @@
+// Description: This file contains code for rendering Helm charts so
+// that they can be linted
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// helmReleaseName is the release name charts are rendered with
+const helmReleaseName = "k8r-lint"
+
+// RenderHelmChart renders a Helm chart with `helm template` and returns
+// the manifests it produced
+func RenderHelmChart(ctx context.Context, chart string, valuesFiles []string) ([]Manifest, error) {
+	if _, err := exec.LookPath("helm"); err != nil {
+		return nil, errors.Wrap(err, "helm is required to lint helm charts")
+	}
+
+	args := []string{"template", helmReleaseName, chart}
+	for _, v := range valuesFiles {
+		args = append(args, "--values", v)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "helm", args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render helm chart %s: %s", chart, strings.TrimSpace(stderr.String()))
+	}
+
+	return ParseManifests(chart, out)
+}
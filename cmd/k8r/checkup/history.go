@@ -0,0 +1,161 @@
+// Description: This file contains code for keeping a history of problem
+// counts across runs so that sudden spikes can be called out
+
+package checkup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// history constants
+const (
+	// historyMaxEntries is how many runs are kept per cluster
+	historyMaxEntries = 2000
+
+	// historyMaxAge is how long runs are kept for, long enough to rank
+	// problems over the past month
+	historyMaxAge = 31 * 24 * time.Hour
+
+	// historyBaselineRuns is how many of the most recent runs the
+	// baseline is calculated from
+	historyBaselineRuns = 5
+
+	// spikeRatio is how many times the baseline the problem count has to
+	// be for it to be considered a spike
+	spikeRatio = 2.0
+
+	// spikeMinIncrease is the smallest increase over the baseline that is
+	// considered a spike, so that going from 1 to 2 problems isn't one
+	spikeMinIncrease = 5
+)
+
+// HistoryEntry is the result of a single run
+type HistoryEntry struct {
+	// Time is when the run happened
+	Time time.Time `json:"time"`
+
+	// Problems is how many problems were found
+	Problems int `json:"problems"`
+
+	// Errors is how many of the problems were errors, not warnings
+	Errors int `json:"errors"`
+
+	// ProblemIDs is how many times each problem was found, by ID
+	ProblemIDs map[string]int `json:"problemIds,omitempty"`
+
+	// Owners is how many problems each team owns, problems without an
+	// owner aren't counted
+	Owners map[string]int `json:"owners,omitempty"`
+}
+
+// History is the history of runs, keyed by the cluster they were run against
+type History map[string][]HistoryEntry
+
+// DefaultHistoryFile returns where history is stored by default, or an
+// empty string if there is no cache directory
+func DefaultHistoryFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "k8r", "history.json")
+}
+
+// LoadHistory reads the history from a file, a file that doesn't exist
+// yet is an empty history
+func LoadHistory(path string) (History, error) {
+	h := make(History)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read history")
+	}
+
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, errors.Wrap(err, "failed to parse history")
+	}
+	return h, nil
+}
+
+// Save writes the history to a file
+func (h History) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create history directory")
+	}
+
+	b, err := json.Marshal(h)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal history")
+	}
+
+	return errors.Wrap(os.WriteFile(path, b, 0o600), "failed to write history")
+}
+
+// Add records a run against a cluster, only the most recent runs from
+// the past month are kept
+func (h History) Add(cluster string, e HistoryEntry) {
+	entries := append(h[cluster], e)
+	if len(entries) > historyMaxEntries {
+		entries = entries[len(entries)-historyMaxEntries:]
+	}
+
+	cutoff := e.Time.Add(-historyMaxAge)
+	for len(entries) > 1 && entries[0].Time.Before(cutoff) {
+		entries = entries[1:]
+	}
+	h[cluster] = entries
+}
+
+// Since returns the runs against clusters whose name contains the given
+// string, all clusters when it is empty, that happened after the given
+// time, oldest first
+func (h History) Since(cluster string, after time.Time) []HistoryEntry {
+	entries := make([]HistoryEntry, 0)
+	for name, runs := range h {
+		if !strings.Contains(name, cluster) {
+			continue
+		}
+		for i := range runs {
+			if runs[i].Time.After(after) {
+				entries = append(entries, runs[i])
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries
+}
+
+// Baseline returns the median problem count of the most recent runs
+// against a cluster, or false if there are no previous runs
+func (h History) Baseline(cluster string) (int, bool) {
+	entries := h[cluster]
+	if len(entries) == 0 {
+		return 0, false
+	}
+	if len(entries) > historyBaselineRuns {
+		entries = entries[len(entries)-historyBaselineRuns:]
+	}
+
+	counts := make([]int, 0, len(entries))
+	for i := range entries {
+		counts = append(counts, entries[i].Problems)
+	}
+	sort.Ints(counts)
+
+	return counts[len(counts)/2], true
+}
+
+// IsSpike returns true if the problem count jumped significantly
+// compared to the baseline
+func IsSpike(baseline, problems int) bool {
+	return problems-baseline >= spikeMinIncrease && float64(problems) >= float64(baseline)*spikeRatio
+}
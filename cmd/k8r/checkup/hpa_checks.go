@@ -0,0 +1,206 @@
+// Description: This file contains code for problems related to the
+// metrics HPAs scale on
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// metrics API groups
+const (
+	// customMetricsGroup is the API group serving metrics about
+	// Kubernetes objects, e.g. from prometheus-adapter
+	customMetricsGroup = "custom.metrics.k8s.io"
+
+	// externalMetricsGroup is the API group serving metrics that aren't
+	// about Kubernetes objects, e.g. queue lengths
+	externalMetricsGroup = "external.metrics.k8s.io"
+)
+
+// hpaMetric is a custom or external metric an HPA scales on
+type hpaMetric struct {
+	// Group is the API group the metric is served by
+	Group string
+
+	// Name is the name of the metric
+	Name string
+
+	// Path is the path the metric's values are read from, relative to
+	// the group version
+	Path string
+}
+
+// hpaMetrics returns the custom and external metrics an HPA scales on
+func hpaMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) []hpaMetric {
+	metrics := make([]hpaMetric, 0)
+	for i := range hpa.Spec.Metrics {
+		m := &hpa.Spec.Metrics[i]
+		switch m.Type {
+		case autoscalingv2.PodsMetricSourceType:
+			if m.Pods == nil {
+				continue
+			}
+			metrics = append(metrics, hpaMetric{
+				Group: customMetricsGroup,
+				Name:  m.Pods.Metric.Name,
+				Path:  path.Join("namespaces", hpa.Namespace, "pods", "*", m.Pods.Metric.Name),
+			})
+		case autoscalingv2.ObjectMetricSourceType:
+			if m.Object == nil {
+				continue
+			}
+			gvk := schema.FromAPIVersionAndKind(m.Object.DescribedObject.APIVersion, m.Object.DescribedObject.Kind)
+			resource, _ := meta.UnsafeGuessKindToResource(gvk)
+			metrics = append(metrics, hpaMetric{
+				Group: customMetricsGroup,
+				Name:  m.Object.Metric.Name,
+				Path:  path.Join("namespaces", hpa.Namespace, resource.Resource, m.Object.DescribedObject.Name, m.Object.Metric.Name),
+			})
+		case autoscalingv2.ExternalMetricSourceType:
+			if m.External == nil {
+				continue
+			}
+			p := path.Join("namespaces", hpa.Namespace, m.External.Metric.Name)
+			if sel, err := metav1.LabelSelectorAsSelector(m.External.Metric.Selector); err == nil && !sel.Empty() {
+				p += "?labelSelector=" + url.QueryEscape(sel.String())
+			}
+			metrics = append(metrics, hpaMetric{Group: externalMetricsGroup, Name: m.External.Metric.Name, Path: p})
+		}
+	}
+	return metrics
+}
+
+// metricsAPIService returns an APIService serving the metrics API group,
+// preferring one that is available
+func metricsAPIService(group string, apiServices []APIService) (*APIService, bool) {
+	var found *APIService
+	for i := range apiServices {
+		svc := &apiServices[i]
+		if svc.Spec.Group != group {
+			continue
+		}
+		if c, ok := svc.Available(); ok && c.Status == string(corev1.ConditionTrue) {
+			return svc, true
+		}
+		found = svc
+	}
+	return found, found != nil
+}
+
+// hpaMetricKey is the key of an HPA's metric in Cluster.HPAMetricErrors
+func hpaMetricKey(hpa *autoscalingv2.HorizontalPodAutoscaler, metric string) string {
+	return fmt.Sprintf("%s/%s/%s", hpa.Namespace, hpa.Name, metric)
+}
+
+// gatherHPAMetricErrors reads the current value of every custom and
+// external metric HPAs scale on, returning why reading each metric failed
+// keyed by hpaMetricKey. Metrics that are served by an API that isn't
+// available aren't read, and reading is best effort otherwise, e.g.
+// metrics that can't be read due to RBAC are skipped.
+func gatherHPAMetricErrors(ctx context.Context, k kubernetes.Interface, c *Cluster) map[string]string {
+	metricErrors := make(map[string]string)
+
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return metricErrors
+	}
+
+	for i := range c.HPAsV2 {
+		if ctx.Err() != nil {
+			break
+		}
+		hpa := &c.HPAsV2[i]
+		for _, m := range hpaMetrics(hpa) {
+			svc, ok := metricsAPIService(m.Group, c.APIServices)
+			if !ok {
+				continue
+			}
+			if cond, ok := svc.Available(); !ok || cond.Status != string(corev1.ConditionTrue) {
+				continue
+			}
+
+			body, err := rc.Get().AbsPath("/apis", m.Group, svc.Spec.Version).Suffix(m.Path).Do(ctx).Raw()
+			if apierrors.IsForbidden(err) {
+				continue
+			}
+			if err != nil {
+				metricErrors[hpaMetricKey(hpa, m.Name)] = err.Error()
+				continue
+			}
+
+			var values struct {
+				Items []json.RawMessage `json:"items"`
+			}
+			if err := json.Unmarshal(body, &values); err != nil {
+				continue
+			}
+			if len(values.Items) == 0 {
+				metricErrors[hpaMetricKey(hpa, m.Name)] = "no values were returned"
+			}
+		}
+	}
+
+	return metricErrors
+}
+
+// ProblemHPAMetricUnavailable is a problem with an HPA that scales on a
+// custom or external metric that can't be read, the HPA can't scale and
+// stays at its current replica count
+// https://github.com/Ashvin-Ranjan/k8r/wiki/HPAMetricUnavailable
+var ProblemHPAMetricUnavailable = Problem{
+	ID:               "HPAMetricUnavailable",
+	ShortDescription: "An HPA scales on a custom or external metric that can't be read, so it is stuck at its current replica count",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/HPAMetricUnavailable",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		hpa, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		reasons := make([]string, 0)
+		for _, m := range hpaMetrics(hpa) {
+			svc, ok := metricsAPIService(m.Group, cfg.Cluster.APIServices)
+			if !ok {
+				reasons = append(reasons, fmt.Sprintf("metric %s needs the %s API, which isn't registered", m.Name, m.Group))
+				continue
+			}
+			if c, ok := svc.Available(); !ok || c.Status != string(corev1.ConditionTrue) {
+				reasons = append(reasons, fmt.Sprintf("metric %s is served by %s, which is unavailable", m.Name, svc.Name))
+				continue
+			}
+			if msg, ok := cfg.Cluster.HPAMetricErrors[hpaMetricKey(hpa, m.Name)]; ok {
+				reasons = append(reasons, fmt.Sprintf("metric %s can't be read: %s", m.Name, msg))
+			}
+		}
+
+		// The HPA controller reports metrics it failed to read as well
+		if len(reasons) == 0 {
+			for i := range hpa.Status.Conditions {
+				c := &hpa.Status.Conditions[i]
+				if c.Type == autoscalingv2.ScalingActive && c.Status == corev1.ConditionFalse && strings.HasPrefix(c.Reason, "FailedGet") {
+					reasons = append(reasons, fmt.Sprintf("%s: %s", c.Reason, c.Message))
+				}
+			}
+		}
+
+		if len(reasons) == 0 {
+			return "", false, false
+		}
+		return fmt.Sprintf("HPA %s", strings.Join(reasons, "; ")), false, true
+	},
+}
@@ -0,0 +1,58 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestHPAMetricUnavailable(t *testing.T) {
+	custom := checkuptest.NewAPIService("custom.metrics.k8s.io", "v1beta2")
+	external := checkuptest.NewAPIService("external.metrics.k8s.io", "v1beta1")
+	unavailable := checkuptest.APIServiceUnavailable(checkuptest.NewAPIService("custom.metrics.k8s.io", "v1beta2"),
+		"FailedDiscoveryCheck", "no response from https://10.0.0.2:443")
+
+	queueHPA := checkuptest.NewHPAV2("worker", checkuptest.ExternalMetric("queue_depth"))
+	noValues := checkuptest.NewCluster(custom, external)
+	noValues.HPAMetricErrors = map[string]string{"default/worker/queue_depth": "no values were returned"}
+
+	checkuptest.RunCases(t, checkup.ProblemHPAMetricUnavailable, []checkuptest.Case{
+		{
+			Name:   "custom metric served",
+			Object: checkuptest.NewHPAV2("web", checkuptest.PodsMetric("http_requests")),
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(custom)),
+		},
+		{
+			Name:           "custom metrics API not registered",
+			Object:         checkuptest.NewHPAV2("web", checkuptest.PodsMetric("http_requests")),
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(external)),
+			Occurring:      true,
+			DetailsContain: "metric http_requests needs the custom.metrics.k8s.io API, which isn't registered",
+		},
+		{
+			Name:           "custom metrics API unavailable",
+			Object:         checkuptest.NewHPAV2("web", checkuptest.PodsMetric("http_requests")),
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(unavailable)),
+			Occurring:      true,
+			DetailsContain: "metric http_requests is served by v1beta2.custom.metrics.k8s.io, which is unavailable",
+		},
+		{
+			Name:           "external metric without values",
+			Object:         queueHPA,
+			Config:         checkuptest.NewConfig(noValues),
+			Occurring:      true,
+			DetailsContain: "metric queue_depth can't be read: no values were returned",
+		},
+		{
+			Name: "HPA controller failed to get metric",
+			Object: checkuptest.HPAFailedGetMetric(checkuptest.NewHPAV2("web", checkuptest.PodsMetric("http_requests")),
+				"FailedGetPodsMetric", "unable to get metric http_requests"),
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(custom)),
+			Occurring:      true,
+			DetailsContain: "FailedGetPodsMetric: unable to get metric http_requests",
+		},
+		{Name: "resource metrics only", Object: checkuptest.NewHPAV2("web"), Config: checkuptest.NewConfig(checkuptest.NewCluster())},
+		{Name: "not an HPA", Object: checkuptest.NewNode("node-1"), Config: checkuptest.NewConfig(checkuptest.NewCluster())},
+	})
+}
@@ -0,0 +1,168 @@
+// Description: This file contains code for timing image pulls from the
+// events the kubelet records, so that images that slow down every rollout
+// and scale-up are found
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Reasons of the events the kubelet records around image pulls
+const (
+	// pullingReason is the reason of the event recorded when a pull starts
+	pullingReason = "Pulling"
+
+	// pulledReason is the reason of the event recorded when a pull
+	// finished, or when the image was already on the node
+	pulledReason = "Pulled"
+)
+
+// pulledMessage matches the message of a Pulled event for an image that
+// was actually pulled, e.g. Successfully pulled image "nginx:1.25" in
+// 2.011s (2.011s including waiting). Older kubelets leave out the time.
+var pulledMessage = regexp.MustCompile(`^Successfully pulled image "([^"]+)"(?: in ([0-9.a-zµ]+))?`)
+
+// pullingMessage matches the message of a Pulling event, e.g. Pulling
+// image "nginx:1.25"
+var pullingMessage = regexp.MustCompile(`^Pulling image "([^"]+)"`)
+
+// ImagePull is a single pull of an image by the kubelet
+type ImagePull struct {
+	// Pod is the namespace/name of the pod the image was pulled for
+	Pod string
+
+	// Node is the node the image was pulled on
+	Node string
+
+	// Duration is how long the pull took
+	Duration time.Duration
+}
+
+// ImagePulls are the pulls of an image that events were found for, it is
+// checked for problems like any other resource
+type ImagePulls struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	// Pulls are the pulls of the image, slowest first
+	Pulls []ImagePull
+}
+
+// DeepCopyObject implements runtime.Object
+func (i *ImagePulls) DeepCopyObject() runtime.Object {
+	out := *i
+	i.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Pulls = append([]ImagePull(nil), i.Pulls...)
+	return &out
+}
+
+// eventTime returns when an event was last recorded
+func eventTime(e *corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	return e.EventTime.Time
+}
+
+// imagePulls times the image pulls from the Pulling and Pulled events of
+// pods. The kubelet reports how long a pull took in the Pulled event, for
+// older kubelets the time between the two events is used instead.
+func imagePulls(events []corev1.Event) []ImagePulls {
+	// started are when pulls started by pod UID and image
+	started := make(map[string]time.Time)
+	for i := range events {
+		e := &events[i]
+		if e.Reason != pullingReason || e.InvolvedObject.Kind != "Pod" {
+			continue
+		}
+		if m := pullingMessage.FindStringSubmatch(e.Message); m != nil {
+			started[string(e.InvolvedObject.UID)+" "+m[1]] = eventTime(e)
+		}
+	}
+
+	byImage := make(map[string][]ImagePull)
+	for i := range events {
+		e := &events[i]
+		if e.Reason != pulledReason || e.InvolvedObject.Kind != "Pod" {
+			continue
+		}
+		m := pulledMessage.FindStringSubmatch(e.Message)
+		if m == nil {
+			continue
+		}
+
+		image := m[1]
+		d, err := time.ParseDuration(m[2])
+		if err != nil {
+			start, ok := started[string(e.InvolvedObject.UID)+" "+image]
+			if !ok || !eventTime(e).After(start) {
+				continue
+			}
+			d = eventTime(e).Sub(start)
+		}
+
+		byImage[image] = append(byImage[image], ImagePull{
+			Pod:      e.InvolvedObject.Namespace + "/" + e.InvolvedObject.Name,
+			Node:     e.Source.Host,
+			Duration: d,
+		})
+	}
+
+	pulls := make([]ImagePulls, 0, len(byImage))
+	for image, p := range byImage {
+		sort.Slice(p, func(i, j int) bool { return p[i].Duration > p[j].Duration })
+		pulls = append(pulls, ImagePulls{ObjectMeta: metav1.ObjectMeta{Name: image}, Pulls: p})
+	}
+	sort.Slice(pulls, func(i, j int) bool { return pulls[i].Name < pulls[j].Name })
+	return pulls
+}
+
+// ProblemImagePullSlow is a problem with an image that takes long to pull,
+// which slows down every rollout and scale-up of the pods using it
+// https://github.com/Ashvin-Ranjan/k8r/wiki/ImagePullSlow
+var ProblemImagePullSlow = Problem{
+	ID:               "ImagePullSlow",
+	ShortDescription: "An image takes long to pull, slowing down every rollout and scale-up that uses it",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/ImagePullSlow",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pulls, ok := obj.(*ImagePulls)
+		if !ok || cfg.ImagePullThreshold <= 0 {
+			return "", false, false
+		}
+
+		slow := 0
+		nodes := make(map[string]bool)
+		for _, p := range pulls.Pulls {
+			if p.Duration > cfg.ImagePullThreshold {
+				slow++
+				nodes[p.Node] = true
+			}
+		}
+		if slow == 0 {
+			return "", false, false
+		}
+
+		slowest := pulls.Pulls[0]
+		details := fmt.Sprintf("Image took longer than %s to pull %d/%d time(s), up to %s for pod %s",
+			cfg.ImagePullThreshold, slow, len(pulls.Pulls), slowest.Duration.Round(time.Second), slowest.Pod)
+		if slowest.Node != "" {
+			details += " on node " + slowest.Node
+		}
+		if len(nodes) > 1 {
+			details += fmt.Sprintf(", it was slow on %d nodes so pre-pulling it with a DaemonSet or making it smaller "+
+				"would speed up rollouts and scale-ups", len(nodes))
+		} else {
+			details += ", making it smaller or pre-pulling it with a DaemonSet would speed up rollouts and scale-ups"
+		}
+		return details, true, true
+	},
+}
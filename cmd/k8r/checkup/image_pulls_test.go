@@ -0,0 +1,91 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestImagePullSlow(t *testing.T) {
+	at := checkuptest.Timestamp
+	later := metav1.NewTime(at.Add(4 * time.Minute))
+	tests := []struct {
+		name        string
+		objs        []runtime.Object
+		wantDetails string
+	}{
+		{
+			name: "fast",
+			objs: []runtime.Object{
+				checkuptest.NewPodEvent("api", "node-1", "Pulled",
+					`Successfully pulled image "example.com/api:1.0.0" in 3.2s (3.2s including waiting)`, at),
+			},
+		},
+		{
+			name: "already present",
+			objs: []runtime.Object{
+				checkuptest.NewPodEvent("api", "node-1", "Pulled",
+					`Container image "example.com/api:1.0.0" already present on machine`, at),
+			},
+		},
+		{
+			name: "slow on several nodes",
+			objs: []runtime.Object{
+				checkuptest.NewPodEvent("api-a", "node-1", "Pulled",
+					`Successfully pulled image "example.com/api:1.0.0" in 3m12.5s (3m12.5s including waiting)`, at),
+				checkuptest.NewPodEvent("api-b", "node-2", "Pulled",
+					`Successfully pulled image "example.com/api:1.0.0" in 2m30s (2m40s including waiting)`, at),
+				checkuptest.NewPodEvent("api-c", "node-3", "Pulled",
+					`Successfully pulled image "example.com/api:1.0.0" in 1.5s (1.5s including waiting)`, at),
+			},
+			wantDetails: "Image took longer than 2m0s to pull 2/3 time(s), up to 3m13s for pod default/api-a on node node-1, " +
+				"it was slow on 2 nodes",
+		},
+		{
+			name: "timed from events",
+			objs: []runtime.Object{
+				checkuptest.NewPodEvent("api", "node-1", "Pulling", `Pulling image "example.com/api:1.0.0"`, at),
+				checkuptest.NewPodEvent("api", "node-1", "Pulled", `Successfully pulled image "example.com/api:1.0.0"`, later),
+			},
+			wantDetails: "Image took longer than 2m0s to pull 1/1 time(s), up to 4m0s for pod default/api on node node-1, " +
+				"making it smaller",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(checkuptest.NewConfig(nil), &out)
+
+			resources, err := o.Scan(context.Background(), newClientset(tt.objs, nil))
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			var got *checkup.Resource
+			for i := range resources {
+				if resources[i].ProblemID == checkup.ProblemImagePullSlow.ID {
+					got = &resources[i]
+				}
+			}
+			switch {
+			case tt.wantDetails == "" && got != nil:
+				t.Errorf("ImagePullSlow reported for %s: %q", got.Name, got.ProblemDetails)
+			case tt.wantDetails != "" && got == nil:
+				t.Errorf("ImagePullSlow not reported")
+			case got != nil && (got.Name != "example.com/api:1.0.0" || !strings.Contains(got.ProblemDetails, tt.wantDetails)):
+				t.Errorf("ImagePullSlow reported for %s: %q, expected example.com/api:1.0.0 with %q",
+					got.Name, got.ProblemDetails, tt.wantDetails)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Description: This file contains code for problems related to init
+// containers
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// stuckInitContainer is an init container that has been running or
+// retrying for too long
+type stuckInitContainer struct {
+	// Status is the status of the init container
+	Status *corev1.ContainerStatus
+
+	// Since is when the init container started running, or when the pod
+	// started if it has been retrying
+	Since time.Time
+
+	// Retrying is true if the init container has failed at least once
+	Retrying bool
+}
+
+// findStuckInitContainer returns the init container that is blocking a pod
+// from starting, if it has been running or retrying for longer than the
+// threshold
+func findStuckInitContainer(pod *corev1.Pod, threshold time.Duration) (*stuckInitContainer, bool) {
+	if pod.Status.Phase != corev1.PodPending {
+		return nil, false
+	}
+
+	// Init containers run in order, so the first one that hasn't
+	// completed is the one blocking the pod
+	for i := range pod.Status.InitContainerStatuses {
+		cs := &pod.Status.InitContainerStatuses[i]
+		if t := cs.State.Terminated; t != nil && t.ExitCode == 0 {
+			continue
+		}
+
+		stuck := &stuckInitContainer{Status: cs, Retrying: cs.RestartCount > 0 || cs.State.Terminated != nil}
+		switch {
+		case stuck.Retrying && pod.Status.StartTime != nil:
+			stuck.Since = pod.Status.StartTime.Time
+		case cs.State.Running != nil:
+			stuck.Since = cs.State.Running.StartedAt.Time
+		}
+		if stuck.Since.IsZero() || time.Since(stuck.Since) < threshold {
+			return nil, false
+		}
+		return stuck, true
+	}
+	return nil, false
+}
+
+// initContainerLogKey is the key of an init container in
+// Cluster.InitContainerLogs
+func initContainerLogKey(pod *corev1.Pod, container string) string {
+	return fmt.Sprintf("%s/%s/%s", pod.Namespace, pod.Name, container)
+}
+
+// gatherInitContainerLogs returns the last log line of every stuck init
+// container, keyed by initContainerLogKey. This is best effort, logs that
+// can't be read are skipped.
+func gatherInitContainerLogs(ctx context.Context, k kubernetes.Interface, pods []corev1.Pod, threshold time.Duration) map[string]string {
+	logs := make(map[string]string)
+	for i := range pods {
+		if ctx.Err() != nil {
+			break
+		}
+		p := &pods[i]
+		stuck, ok := findStuckInitContainer(p, threshold)
+		if !ok {
+			continue
+		}
+
+		tail := int64(1)
+		opts := &corev1.PodLogOptions{Container: stuck.Status.Name, TailLines: &tail}
+		// Retrying containers aren't running, the previous run has the
+		// error they failed with
+		if stuck.Status.State.Running == nil && stuck.Status.RestartCount > 0 {
+			opts.Previous = true
+		}
+
+		body, err := k.CoreV1().Pods(p.Namespace).GetLogs(p.Name, opts).DoRaw(ctx)
+		if err != nil {
+			continue
+		}
+		if line := strings.TrimSpace(string(body)); line != "" {
+			logs[initContainerLogKey(p, stuck.Status.Name)] = line
+		}
+	}
+	return logs
+}
+
+// ProblemInitContainerStuck is a problem with a pod whose init container
+// has been running or retrying for longer than the init-container-threshold
+// flag, such pods sit in PodInitializing without any other sign of trouble
+// https://github.com/Ashvin-Ranjan/k8r/wiki/InitContainerStuck
+var ProblemInitContainerStuck = Problem{
+	ID:               "InitContainerStuck",
+	ShortDescription: "A pod's init container has been running or retrying for too long, so the pod never starts",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/InitContainerStuck",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return "", false, false
+		}
+
+		stuck, ok := findStuckInitContainer(pod, cfg.InitContainerThreshold)
+		if !ok {
+			return "", false, false
+		}
+
+		state := "running"
+		if stuck.Retrying {
+			state = fmt.Sprintf("retrying (%d restarts)", stuck.Status.RestartCount)
+		}
+		details := fmt.Sprintf("Init container %s (%s) has been %s for %s",
+			stuck.Status.Name, stuck.Status.Image, state, time.Since(stuck.Since).Round(time.Minute))
+
+		if cfg.Cluster != nil {
+			if line, ok := cfg.Cluster.InitContainerLogs[initContainerLogKey(pod, stuck.Status.Name)]; ok {
+				details += ", last log line: " + line
+			}
+		}
+		return details, false, true
+	},
+}
@@ -0,0 +1,34 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestInitContainerStuck(t *testing.T) {
+	now := time.Now()
+	waiting := checkuptest.NewPod("web", checkuptest.InitContainerRunning("wait-for-db", now.Add(-30*time.Minute)))
+	withLogs := checkuptest.NewCluster(waiting)
+	withLogs.InitContainerLogs = map[string]string{"default/web/wait-for-db": "waiting for db:5432"}
+
+	checkuptest.RunCases(t, checkup.ProblemInitContainerStuck, []checkuptest.Case{
+		{Name: "running", Object: checkuptest.NewPod("web")},
+		{Name: "recently started", Object: checkuptest.NewPod("web", checkuptest.InitContainerRunning("wait-for-db", now.Add(-time.Minute)))},
+		{
+			Name:           "running too long",
+			Object:         waiting,
+			Config:         checkuptest.NewConfig(withLogs),
+			Occurring:      true,
+			DetailsContain: "Init container wait-for-db (example.com/wait-for-db:1.0.0) has been running for 30m0s, last log line: waiting for db:5432",
+		},
+		{
+			Name:           "retrying too long",
+			Object:         checkuptest.NewPod("web", checkuptest.InitContainerRetrying("migrate", 6, now.Add(-time.Hour))),
+			Occurring:      true,
+			DetailsContain: "Init container migrate (example.com/migrate:1.0.0) has been retrying (6 restarts) for 1h0m0s",
+		},
+	})
+}
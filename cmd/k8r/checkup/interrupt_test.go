@@ -0,0 +1,82 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestScanInterrupted(t *testing.T) {
+	objs := []runtime.Object{
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(checkuptest.NewConfig(nil), &out)
+
+	err := o.RunWithClient(ctx, newClientset(objs, nil))
+	if !errors.Is(err, checkup.ErrScanInterrupted) {
+		t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrScanInterrupted)
+	}
+	for _, want := range []string{"Scan interrupted", "No problems found before the scan was interrupted"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, expected it to contain %q", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "Everything looks good") {
+		t.Errorf("output = %q, expected an interrupted scan not to look good", out.String())
+	}
+}
+
+func TestScanInterruptedWhileListing(t *testing.T) {
+	objs := []runtime.Object{
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+
+	// Interrupt the scan once pods were listed, failing every list after
+	// that like a real API server would
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	k := newClientset(objs, nil)
+	k.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Resource == "pods" {
+			cancel()
+			return false, nil, nil
+		}
+		if ctx.Err() != nil {
+			return true, nil, ctx.Err()
+		}
+		return false, nil, nil
+	})
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(checkuptest.NewConfig(nil), &out)
+
+	err := o.RunWithClient(ctx, k)
+	if !errors.Is(err, checkup.ErrScanInterrupted) {
+		t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrScanInterrupted)
+	}
+	for _, want := range []string{
+		"only checking the resources that were listed",
+		"pods",
+		"Scan interrupted",
+		checkup.ProblemPodNotReady.ID,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, expected it to contain %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Description: This file contains code for problems with Jobs that failed
+// or keep failing, which pod checks miss once the failed pods are cleaned
+// up
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// jobBackoffLimit returns how many times a Job's pods may fail before the
+// Job is marked failed
+func jobBackoffLimit(job *batchv1.Job) int32 {
+	if job.Spec.BackoffLimit != nil {
+		return *job.Spec.BackoffLimit
+	}
+	return defaultJobBackoffLimit
+}
+
+// lastPodFailure describes how the most recent failed pod of a Job that
+// is still around failed, e.g. pod report-x7k2p: app exited with code 1
+func lastPodFailure(job *batchv1.Job, pods []corev1.Pod) (string, bool) {
+	var last *corev1.Pod
+	var lastAt time.Time
+	var reason string
+	for _, p := range jobPods(job, pods) {
+		for i := range p.Status.ContainerStatuses {
+			cs := &p.Status.ContainerStatuses[i]
+			t := cs.State.Terminated
+			if t == nil {
+				t = cs.LastTerminationState.Terminated
+			}
+			if t == nil || t.ExitCode == 0 || (last != nil && !t.FinishedAt.After(lastAt)) {
+				continue
+			}
+			last, lastAt = p, t.FinishedAt.Time
+			reason = fmt.Sprintf("%s exited with code %d", cs.Name, t.ExitCode)
+			if t.Reason != "" && t.Reason != "Error" {
+				reason += " (" + t.Reason + ")"
+			}
+		}
+	}
+	if last == nil {
+		return "", false
+	}
+	return fmt.Sprintf("pod %s: %s", last.Name, reason), true
+}
+
+// ProblemJobFailed is a problem with a Job that failed, either with the
+// Failed condition or with more failed pods than its backoff limit, or a
+// warning for a Job that is still running but whose pods have failed and
+// are being retried
+// https://github.com/Ashvin-Ranjan/k8r/wiki/JobFailed
+var ProblemJobFailed = Problem{
+	ID:               "JobFailed",
+	ShortDescription: "A Job failed or its pods keep failing and are being retried",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/JobFailed",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		job, ok := obj.(*batchv1.Job)
+		if !ok {
+			return "", false, false
+		}
+		limit := jobBackoffLimit(job)
+
+		var details string
+		warning := false
+		if failed, ok := jobFailure(job); ok {
+			details = "Job failed (" + failed.Reason
+			if failed.Message != "" {
+				details += ": " + failed.Message
+			}
+			details += ")"
+			if !failed.LastTransitionTime.IsZero() {
+				details += fmt.Sprintf(" %s ago", time.Since(failed.LastTransitionTime.Time).Round(time.Minute))
+			}
+			details += fmt.Sprintf(", %d pods failed with a backoff limit of %d", job.Status.Failed, limit)
+		} else if jobFinished(job) || job.Status.Failed == 0 {
+			return "", false, false
+		} else if job.Status.Failed > limit {
+			details = fmt.Sprintf("Job has %d failed pods, more than its backoff limit of %d, but isn't marked failed", job.Status.Failed, limit)
+		} else {
+			details = fmt.Sprintf("Job is retrying, %d of %d allowed pod failures used", job.Status.Failed, limit)
+			warning = true
+		}
+
+		if cfg.Cluster != nil {
+			if failure, ok := lastPodFailure(job, cfg.Cluster.Pods); ok {
+				details += ", the last failure was " + failure
+			}
+		}
+		if ref, ok := ownerRef(job.OwnerReferences); ok && ref.Kind == "CronJob" {
+			details += fmt.Sprintf(", it is a run of CronJob %s", ref.Name)
+		}
+		return details, warning, true
+	},
+}
+
+// cronJobRun returns true if the symptom was found on a Job the CronJob
+// the cause was found on created
+func cronJobRun(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "CronJob" || symptom.Type != "Job" || c == nil {
+		return false
+	}
+
+	for i := range c.Jobs {
+		job := &c.Jobs[i]
+		if fmt.Sprintf("%s/%s", job.Namespace, job.Name) != symptom.Name {
+			continue
+		}
+		ref, ok := ownerRef(job.OwnerReferences)
+		return ok && ref.Kind == "CronJob" && fmt.Sprintf("%s/%s", job.Namespace, ref.Name) == cause.Name
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJobFailed(t *testing.T) {
+	failed := checkuptest.NewJob("migrate", 2)
+	failed.Status = batchv1.JobStatus{Failed: 3, Conditions: []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded",
+		Message: "Job has reached the specified backoff limit", LastTransitionTime: *ago(20 * time.Minute),
+	}}}
+
+	overLimit := checkuptest.NewJob("migrate", 2)
+	overLimit.Status.Failed = 3
+
+	retrying := checkuptest.NewJob("migrate", 6)
+	retrying.Status.Failed = 2
+
+	succeeded := checkuptest.NewJob("migrate", 6)
+	succeeded.Status = batchv1.JobStatus{Succeeded: 1, Failed: 1, Conditions: []batchv1.JobCondition{{
+		Type: batchv1.JobComplete, Status: corev1.ConditionTrue,
+	}}}
+
+	cron := checkuptest.NewJob("nightly-28000000", 6)
+	cron.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "nightly"}}
+	cron.Status.Failed = 1
+
+	checkuptest.RunCases(t, checkup.ProblemJobFailed, []checkuptest.Case{
+		{Name: "running", Object: checkuptest.NewJob("migrate", 6)},
+		{Name: "succeeded after a retry", Object: succeeded},
+		{
+			Name:           "failed",
+			Object:         failed,
+			Occurring:      true,
+			DetailsContain: "Job failed (BackoffLimitExceeded: Job has reached the specified backoff limit) 20m0s ago, 3 pods failed with a backoff limit of 2",
+		},
+		{
+			Name:           "failed with pod still around",
+			Object:         failed,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(failed, checkuptest.NewPod("migrate-x", checkuptest.OwnedBy("Job", "migrate"), exited(1)))),
+			Occurring:      true,
+			DetailsContain: "the last failure was pod migrate-x: app exited with code 1",
+		},
+		{
+			Name:           "over backoff limit",
+			Object:         overLimit,
+			Occurring:      true,
+			DetailsContain: "Job has 3 failed pods, more than its backoff limit of 2, but isn't marked failed",
+		},
+		{
+			Name:           "retrying",
+			Object:         retrying,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Job is retrying, 2 of 6 allowed pod failures used",
+		},
+		{
+			Name:           "CronJob run",
+			Object:         cron,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "it is a run of CronJob nightly",
+		},
+	})
+}
@@ -0,0 +1,247 @@
+// Description: This file contains code for building kustomizations so
+// that they can be linted, and problems specific to kustomize output
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// generatorKustomize is the Manifest.Generator for kustomize output
+const generatorKustomize = "kustomize"
+
+// enabledKustomizeProblems is a list of problem checkers that are enabled
+// for pods built from kustomize output
+var enabledKustomizeProblems = []Problem{
+	ProblemKustomizeUnresolvedReference,
+	ProblemKustomizeInconsistentImage,
+}
+
+// RenderKustomization builds a kustomization with `kustomize build`, falling
+// back to `kubectl kustomize`, and returns the manifests it produced
+func RenderKustomization(ctx context.Context, dir string) ([]Manifest, error) {
+	name, args := "kustomize", []string{"build", dir}
+	if _, err := exec.LookPath(name); err != nil {
+		name, args = "kubectl", []string{"kustomize", dir}
+		if _, err := exec.LookPath(name); err != nil {
+			return nil, errors.New("kustomize or kubectl is required to lint kustomizations")
+		}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build kustomization %s: %s", dir, strings.TrimSpace(stderr.String()))
+	}
+
+	manifests, err := ParseManifests(dir, out)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines in the build output don't match any file in the
+	// kustomization, so only the kustomization is kept
+	for i := range manifests {
+		manifests[i].Line = 0
+		manifests[i].Generator = generatorKustomize
+	}
+
+	return manifests, nil
+}
+
+// kustomizeHashSuffix matches the hash suffix that kustomize generators
+// add to the names of ConfigMaps and Secrets
+var kustomizeHashSuffix = regexp.MustCompile(`-[a-z0-9]{10}$`)
+
+// generatedName returns the name of the generated object in names that
+// was generated from the same generator as name, if there is one that
+// isn't name itself
+func generatedName(name string, names []string) (string, bool) {
+	base := kustomizeHashSuffix.ReplaceAllString(name, "")
+	for _, n := range names {
+		if n != name && kustomizeHashSuffix.MatchString(n) && kustomizeHashSuffix.ReplaceAllString(n, "") == base {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// podReferences returns the names of the ConfigMaps and Secrets that a
+// pod references through volumes and environment variables
+func podReferences(pod *corev1.Pod) (configMaps, secrets []string) {
+	for i := range pod.Spec.Volumes {
+		v := &pod.Spec.Volumes[i]
+		if v.ConfigMap != nil {
+			configMaps = append(configMaps, v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			secrets = append(secrets, v.Secret.SecretName)
+		}
+		if v.Projected != nil {
+			for j := range v.Projected.Sources {
+				s := &v.Projected.Sources[j]
+				if s.ConfigMap != nil {
+					configMaps = append(configMaps, s.ConfigMap.Name)
+				}
+				if s.Secret != nil {
+					secrets = append(secrets, s.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for i := range containers {
+		c := &containers[i]
+		for j := range c.EnvFrom {
+			if c.EnvFrom[j].ConfigMapRef != nil {
+				configMaps = append(configMaps, c.EnvFrom[j].ConfigMapRef.Name)
+			}
+			if c.EnvFrom[j].SecretRef != nil {
+				secrets = append(secrets, c.EnvFrom[j].SecretRef.Name)
+			}
+		}
+		for j := range c.Env {
+			if c.Env[j].ValueFrom == nil {
+				continue
+			}
+			if c.Env[j].ValueFrom.ConfigMapKeyRef != nil {
+				configMaps = append(configMaps, c.Env[j].ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if c.Env[j].ValueFrom.SecretKeyRef != nil {
+				secrets = append(secrets, c.Env[j].ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+
+	return configMaps, secrets
+}
+
+// imageRepository returns the repository of an image, without its
+// tag or digest
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+	// A colon after the last slash is a tag, before it is a registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// ProblemKustomizeUnresolvedReference is a problem with a workload that
+// references a generated ConfigMap or Secret by a name that isn't in
+// the kustomize build output
+// https://github.com/Ashvin-Ranjan/k8r/wiki/KustomizeUnresolvedReference
+var ProblemKustomizeUnresolvedReference = Problem{
+	ID:               "KustomizeUnresolvedReference",
+	ShortDescription: "A workload references a generated ConfigMap or Secret that kustomize did not resolve",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/KustomizeUnresolvedReference",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		configMapNames := make([]string, 0, len(cfg.Cluster.ConfigMaps))
+		for i := range cfg.Cluster.ConfigMaps {
+			if cfg.Cluster.ConfigMaps[i].Namespace == pod.Namespace {
+				configMapNames = append(configMapNames, cfg.Cluster.ConfigMaps[i].Name)
+			}
+		}
+		secretNames := make([]string, 0, len(cfg.Cluster.Secrets))
+		for i := range cfg.Cluster.Secrets {
+			if cfg.Cluster.Secrets[i].Namespace == pod.Namespace {
+				secretNames = append(secretNames, cfg.Cluster.Secrets[i].Name)
+			}
+		}
+
+		// References to objects that aren't in the output at all are
+		// assumed to be managed outside of the kustomization, only flag
+		// ones where a generated object with the same base name exists
+		configMaps, secrets := podReferences(pod)
+		for _, name := range configMaps {
+			if cfg.Cluster.HasConfigMap(pod.Namespace, name) {
+				continue
+			}
+			if generated, ok := generatedName(name, configMapNames); ok {
+				return fmt.Sprintf("References ConfigMap %s but the build contains %s", name, generated), false, true
+			}
+		}
+		for _, name := range secrets {
+			if cfg.Cluster.HasSecret(pod.Namespace, name) {
+				continue
+			}
+			if generated, ok := generatedName(name, secretNames); ok {
+				return fmt.Sprintf("References Secret %s but the build contains %s", name, generated), false, true
+			}
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemKustomizeInconsistentImage is a problem with a workload that uses
+// a different tag of an image than other workloads in the kustomize build
+// output, which usually means an image override didn't apply everywhere
+// https://github.com/Ashvin-Ranjan/k8r/wiki/KustomizeInconsistentImage
+var ProblemKustomizeInconsistentImage = Problem{
+	ID:               "KustomizeInconsistentImage",
+	ShortDescription: "A workload uses a different tag of an image than other workloads in the same build",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/KustomizeInconsistentImage",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		// images is a map of repository to all of the images of that
+		// repository used in the build
+		images := make(map[string]map[string]struct{})
+		for i := range cfg.Cluster.Pods {
+			p := &cfg.Cluster.Pods[i]
+			for _, c := range append(append([]corev1.Container{}, p.Spec.InitContainers...), p.Spec.Containers...) {
+				repo := imageRepository(c.Image)
+				if images[repo] == nil {
+					images[repo] = make(map[string]struct{})
+				}
+				images[repo][c.Image] = struct{}{}
+			}
+		}
+
+		for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			used := images[imageRepository(c.Image)]
+			if len(used) <= 1 {
+				continue
+			}
+
+			others := make([]string, 0, len(used)-1)
+			for image := range used {
+				if image != c.Image {
+					others = append(others, image)
+				}
+			}
+			sort.Strings(others)
+
+			return fmt.Sprintf("Container %s uses %s while other workloads use %s",
+				c.Name, c.Image, strings.Join(others, ", "),
+			), true, true
+		}
+
+		return "", false, false
+	},
+}
@@ -0,0 +1,42 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestKustomizeUnresolvedReference(t *testing.T) {
+	pod := checkuptest.NewPod("web", checkuptest.WithConfigMapVolume("web-config"))
+
+	checkuptest.RunCases(t, checkup.ProblemKustomizeUnresolvedReference, []checkuptest.Case{
+		{Name: "resolved", Object: pod, Config: checkuptest.NewConfig(checkuptest.NewCluster(pod, checkuptest.NewConfigMap("web-config")))},
+		{Name: "managed elsewhere", Object: pod},
+		{
+			Name:           "generated name",
+			Object:         pod,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(pod, checkuptest.NewConfigMap("web-config-5h2b8fmk9t"))),
+			Occurring:      true,
+			DetailsContain: "but the build contains web-config-5h2b8fmk9t",
+		},
+	})
+}
+
+func TestKustomizeInconsistentImage(t *testing.T) {
+	web := checkuptest.NewPod("web", checkuptest.WithImage(checkuptest.DefaultContainer, "example.com/app:1.2.0"))
+	worker := checkuptest.NewPod("worker", checkuptest.WithImage(checkuptest.DefaultContainer, "example.com/app:1.2.0"))
+	stale := checkuptest.NewPod("worker", checkuptest.WithImage(checkuptest.DefaultContainer, "example.com/app:1.1.0"))
+
+	checkuptest.RunCases(t, checkup.ProblemKustomizeInconsistentImage, []checkuptest.Case{
+		{Name: "consistent", Object: web, Config: checkuptest.NewConfig(checkuptest.NewCluster(web, worker))},
+		{
+			Name:           "inconsistent",
+			Object:         web,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(web, stale)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "uses example.com/app:1.2.0 while other workloads use example.com/app:1.1.0",
+		},
+	})
+}
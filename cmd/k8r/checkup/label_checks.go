@@ -0,0 +1,69 @@
+// Description: This file contains code for problems related to label
+// conventions
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ownerLabel is the label that the owning team of a resource is read from
+const ownerLabel = "reporting_team"
+
+// isSystemNamespace returns true for the namespaces that Kubernetes
+// itself manages, which aren't expected to follow label conventions
+func isSystemNamespace(name string) bool {
+	return strings.HasPrefix(name, "kube-")
+}
+
+// ProblemMissingRequiredLabels is a problem with a workload or namespace
+// that doesn't have all of the labels required by the required-labels flag.
+// Pods a controller created are left to their workload, so that a missing
+// label is reported once rather than for every replica.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/MissingRequiredLabels
+var ProblemMissingRequiredLabels = Problem{
+	ID:               "MissingRequiredLabels",
+	ShortDescription: "A workload or namespace is missing labels that are required by convention",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/MissingRequiredLabels",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		if len(cfg.RequiredLabels) == 0 {
+			return "", false, false
+		}
+
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return "", false, false
+		}
+
+		if _, ok := obj.(*corev1.Pod); ok && metav1.GetControllerOf(accessor) != nil {
+			return "", false, false
+		}
+
+		namespace := accessor.GetNamespace()
+		if _, ok := obj.(*corev1.Namespace); ok {
+			namespace = accessor.GetName()
+		}
+		if isSystemNamespace(namespace) {
+			return "", false, false
+		}
+
+		missing := make([]string, 0)
+		for _, l := range cfg.RequiredLabels {
+			if accessor.GetLabels()[l] == "" {
+				missing = append(missing, l)
+			}
+		}
+		if len(missing) == 0 {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("Missing labels %s", strings.Join(missing, ", ")), true, true
+	},
+}
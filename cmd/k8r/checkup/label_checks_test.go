@@ -0,0 +1,64 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestMissingRequiredLabels(t *testing.T) {
+	required := func() *checkup.Config {
+		cfg := checkuptest.NewConfig(checkuptest.NewCluster())
+		cfg.RequiredLabels = []string{"reporting_team", "app.kubernetes.io/name"}
+		return cfg
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemMissingRequiredLabels, []checkuptest.Case{
+		{Name: "nothing required", Object: checkuptest.NewPod("web")},
+		{
+			Name:   "all labels",
+			Object: checkuptest.NewPod("web", checkuptest.WithLabels(map[string]string{"reporting_team": "payments", "app.kubernetes.io/name": "web"})),
+			Config: required(),
+		},
+		{
+			Name:           "missing on pod",
+			Object:         checkuptest.NewPod("web", checkuptest.WithLabels(map[string]string{"reporting_team": "payments"})),
+			Config:         required(),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Missing labels app.kubernetes.io/name",
+		},
+		{
+			Name:   "pod of a workload",
+			Object: checkuptest.NewPod("web-7d9f", checkuptest.OwnedBy("ReplicaSet", "web-7d9f")),
+			Config: required(),
+		},
+		{
+			Name:           "missing on deployment",
+			Object:         checkuptest.NewDeployment("web", 50, appsv1.DeploymentStrategy{}),
+			Config:         required(),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Missing labels reporting_team, app.kubernetes.io/name",
+		},
+		{
+			Name:           "missing on cronjob",
+			Object:         checkuptest.NewCronJob("backup", "0 * * * *", nil),
+			Config:         required(),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Missing labels reporting_team, app.kubernetes.io/name",
+		},
+		{
+			Name:           "missing on namespace",
+			Object:         checkuptest.NewNamespace("payments", nil),
+			Config:         required(),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "reporting_team, app.kubernetes.io/name",
+		},
+		{Name: "system namespace", Object: checkuptest.NewNamespace("kube-system", nil), Config: required()},
+	})
+}
@@ -0,0 +1,229 @@
+// Description: This file contains the code for the 'k8r lint'
+// command.
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// NewLintCommand creates a new k8r lint command
+func NewLintCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:      "lint",
+		Usage:     "Check local Kubernetes manifests for problems, no cluster needed",
+		ArgsUsage: "[file or directory...]",
+		Action: func(c *cli.Context) error {
+			cfg, err := newConfig(c)
+			if err != nil {
+				return err
+			}
+			o.cfg = cfg
+			o.useOutput()
+
+			return o.runWithResult(func() error {
+				chart := c.String("helm-chart")
+				kustomization := c.String("kustomize")
+				if c.NArg() == 0 && chart == "" && kustomization == "" {
+					return errors.New("expected at least one file or directory, --helm-chart or --kustomize to lint")
+				}
+
+				manifests, err := ReadManifests(c.Args().Slice())
+				if err != nil {
+					return err
+				}
+
+				if chart != "" {
+					rendered, err := RenderHelmChart(c.Context, chart, c.StringSlice("values"))
+					if err != nil {
+						return err
+					}
+					manifests = append(manifests, rendered...)
+				}
+
+				if kustomization != "" {
+					built, err := RenderKustomization(c.Context, kustomization)
+					if err != nil {
+						return err
+					}
+					manifests = append(manifests, built...)
+				}
+
+				return o.Lint(c.Context, manifests)
+			})
+		},
+		Flags: append(newFlags(),
+			&cli.StringFlag{
+				Name:  "helm-chart",
+				Usage: "Renders the given Helm chart with helm template and lints the output",
+			},
+			&cli.StringSliceFlag{
+				Name:  "values",
+				Usage: "Values file to render the Helm chart with, can be passed multiple times",
+			},
+			&cli.StringFlag{
+				Name:  "kustomize",
+				Usage: "Builds the given kustomization and lints the output",
+			},
+		),
+	}
+}
+
+// Lint checks the given manifests for problems and reports them
+func (o *Options) Lint(ctx context.Context, manifests []Manifest) error {
+	o.cfg.Cluster = clusterFromManifests(manifests)
+	o.resetDetectorStats()
+
+	bold.Fprintf(o.out, "Checking %d manifests for problems ... ", len(manifests))
+	resourceProblems := []Resource{}
+
+	for i := range manifests {
+		m := &manifests[i]
+
+		podProblems := enabledPodProblems
+		if m.Generator == generatorKustomize {
+			podProblems = append(append([]Problem{}, enabledPodProblems...), enabledKustomizeProblems...)
+		}
+
+		var rs []Resource
+		if pod, kind, ok := podFromObject(m.Object); ok {
+			// Workloads are checked for required labels on their own
+			// labels rather than their pod template's
+			if workloadLabelKinds[kind] {
+				podProblems = withoutProblem(podProblems, ProblemMissingRequiredLabels)
+			}
+			rs, _ = o.getPodsWithProblems(ctx, pod, podProblems)
+			for j := range rs {
+				rs[j].Type = kind
+			}
+		}
+
+		var more []Resource
+		switch obj := m.Object.(type) {
+		case *v1.HorizontalPodAutoscaler:
+			more, _ = o.getHPAsWithProblems(ctx, obj)
+		case *corev1.Service:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "service", enabledServiceProblems)
+		case *appsv1.StatefulSet:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "StatefulSet", enabledStatefulSetProblems)
+		case *appsv1.Deployment:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "Deployment", enabledDeploymentProblems)
+		case *appsv1.DaemonSet:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "DaemonSet", enabledDaemonSetProblems)
+		case *corev1.Secret:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "secret", enabledSecretProblems)
+		case *corev1.Namespace:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "namespace", enabledNamespaceProblems)
+		case *rbacv1.Role:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "Role", enabledRBACProblems)
+		case *rbacv1.ClusterRole:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "ClusterRole", enabledRBACProblems)
+		case *batchv1.CronJob:
+			more, _ = o.getResourcesWithProblems(ctx, obj, "CronJob", enabledCronJobProblems)
+		}
+		rs = append(rs, more...)
+
+		for j := range rs {
+			rs[j].Name = manifestResourceName(m.Object)
+			rs[j].Source = m.Source()
+		}
+		resourceProblems = append(resourceProblems, rs...)
+	}
+
+	bold.Fprintln(o.out, "done")
+
+	return o.printReport(ctx, resourceProblems)
+}
+
+// workloadLabelKinds are the kinds of workloads ProblemMissingRequiredLabels
+// checks
+var workloadLabelKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+	"CronJob":     true,
+}
+
+// withoutProblem returns the problems other than the given one
+func withoutProblem(problems []Problem, p Problem) []Problem {
+	out := make([]Problem, 0, len(problems))
+	for _, problem := range problems {
+		if problem.ID != p.ID {
+			out = append(out, problem)
+		}
+	}
+	return out
+}
+
+// clusterFromManifests creates a Cluster containing the objects in the
+// given manifests, workloads are added as pods built from their templates
+func clusterFromManifests(manifests []Manifest) *Cluster {
+	c := &Cluster{}
+	for i := range manifests {
+		// Workloads are added as pods as well as being kept as is
+		if pod, _, ok := podFromObject(manifests[i].Object); ok {
+			c.Pods = append(c.Pods, *pod)
+		}
+
+		switch obj := manifests[i].Object.(type) {
+		case *v1.HorizontalPodAutoscaler:
+			c.HPAs = append(c.HPAs, *obj)
+		case *corev1.ConfigMap:
+			c.ConfigMaps = append(c.ConfigMaps, *obj)
+		case *corev1.Secret:
+			c.Secrets = append(c.Secrets, *obj)
+		case *corev1.Service:
+			c.Services = append(c.Services, *obj)
+		case *appsv1.StatefulSet:
+			c.StatefulSets = append(c.StatefulSets, *obj)
+		case *appsv1.Deployment:
+			c.Deployments = append(c.Deployments, *obj)
+		case *appsv1.DaemonSet:
+			c.DaemonSets = append(c.DaemonSets, *obj)
+		case *batchv1.Job:
+			c.Jobs = append(c.Jobs, *obj)
+		case *batchv1.CronJob:
+			c.CronJobs = append(c.CronJobs, *obj)
+		case *corev1.Namespace:
+			c.Namespaces = append(c.Namespaces, *obj)
+		case *corev1.ServiceAccount:
+			c.ServiceAccounts = append(c.ServiceAccounts, *obj)
+		case *policyv1.PodDisruptionBudget:
+			c.PodDisruptionBudgets = append(c.PodDisruptionBudgets, *obj)
+		case *rbacv1.Role:
+			c.Roles = append(c.Roles, *obj)
+		case *rbacv1.ClusterRole:
+			c.ClusterRoles = append(c.ClusterRoles, *obj)
+		}
+	}
+	return c
+}
+
+// manifestResourceName returns the name of the object in a manifest,
+// manifests often don't set a namespace so it is only included when set
+func manifestResourceName(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "unknown"
+	}
+
+	if accessor.GetNamespace() == "" {
+		return accessor.GetName()
+	}
+	return fmt.Sprintf("%s/%s", accessor.GetNamespace(), accessor.GetName())
+}
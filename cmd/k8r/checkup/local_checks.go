@@ -0,0 +1,303 @@
+// Description: This file contains code for problems that are common on
+// local development clusters, e.g. kind and minikube
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// localDistros are distributions that run local development clusters
+var localDistros = map[Distro]bool{
+	DistroKind:     true,
+	DistroMinikube: true,
+}
+
+// localSkippedProblems are problems that are skipped with the local flag,
+// they are about running in production, e.g. availability or policies,
+// rather than the problems developers run into
+var localSkippedProblems = []Problem{
+	ProblemPodSpotOnly,
+	ProblemMaxedOutHPAs,
+	ProblemHPAMetricUnavailable,
+	ProblemPodDNSSearchAmplification,
+	ProblemPodSecurityViolation,
+	ProblemMissingRequiredLabels,
+	ProblemSecretAsEnvVar,
+	ProblemSecretUnused,
+	ProblemRBACWildcardGrant,
+	ProblemNodeCertificateExpiring,
+	ProblemEtcdDBSizeNearQuota,
+	ProblemEtcdSlowFsync,
+}
+
+// localRegistryHosts are registry hosts that only exist on the developer's
+// machine, ports are ignored
+var localRegistryHosts = []string{
+	"localhost",
+	"127.0.0.1",
+	"kind-registry",
+	"registry.localhost",
+	"host.docker.internal",
+	"host.minikube.internal",
+}
+
+// localMemoryMinimum is the memory below which a local cluster is likely
+// to OOM kill pods, Docker Desktop defaults to 2Gi
+var localMemoryMinimum = resource.MustParse("4Gi")
+
+// imagePullFailureReasons are the reasons a container waits for when its
+// image can't be pulled
+var imagePullFailureReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"ErrImageNeverPull": true,
+}
+
+// localCluster returns true if the cluster is a local development cluster
+func (c *Config) localCluster() bool {
+	return c.Local || (c.Cluster != nil && localDistros[c.Cluster.Distro])
+}
+
+// imageRegistry returns the registry host of an image, without the port,
+// or an empty string for images on Docker Hub
+func imageRegistry(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return ""
+	}
+
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return ""
+	}
+	if j := strings.LastIndex(host, ":"); j >= 0 {
+		host = host[:j]
+	}
+	return host
+}
+
+// localRegistry returns true if the registry only exists on the
+// developer's machine
+func localRegistry(host string) bool {
+	if strings.HasSuffix(host, ".local") {
+		return true
+	}
+	for _, h := range localRegistryHosts {
+		if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+// imagePullFailure is a container that can't pull its image
+type imagePullFailure struct {
+	// Container is the container
+	Container *corev1.Container
+
+	// Reason is the reason the container is waiting, e.g. ErrImagePull
+	Reason string
+
+	// Message is the message the container is waiting with
+	Message string
+}
+
+// imagePullFailures returns the containers of a pod that can't pull their
+// image, init containers first
+func imagePullFailures(pod *corev1.Pod) []imagePullFailure {
+	failures := make([]imagePullFailure, 0)
+	for _, l := range []struct {
+		containers []corev1.Container
+		statuses   []corev1.ContainerStatus
+	}{
+		{pod.Spec.InitContainers, pod.Status.InitContainerStatuses},
+		{pod.Spec.Containers, pod.Status.ContainerStatuses},
+	} {
+		for i := range l.statuses {
+			cs := &l.statuses[i]
+			if cs.State.Waiting == nil || !imagePullFailureReasons[cs.State.Waiting.Reason] {
+				continue
+			}
+			for j := range l.containers {
+				if l.containers[j].Name == cs.Name {
+					failures = append(failures, imagePullFailure{
+						Container: &l.containers[j],
+						Reason:    cs.State.Waiting.Reason,
+						Message:   cs.State.Waiting.Message,
+					})
+				}
+			}
+		}
+	}
+	return failures
+}
+
+// imageNotFound returns true if the registry said the image doesn't exist,
+// rather than e.g. failing to connect
+func imageNotFound(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "not found") ||
+		strings.Contains(message, "pull access denied") ||
+		strings.Contains(message, "does not exist")
+}
+
+// alwaysPulled returns true if the kubelet pulls the container's image
+// every time, ignoring images loaded onto the node
+func alwaysPulled(c *corev1.Container) bool {
+	switch c.ImagePullPolicy {
+	case corev1.PullAlways:
+		return true
+	case "":
+		// Images without a tag or tagged latest default to Always
+		tag := ""
+		name := c.Image[strings.LastIndex(c.Image, "/")+1:]
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			tag = name[i+1:]
+		}
+		return !strings.Contains(c.Image, "@") && (tag == "" || tag == "latest")
+	}
+	return false
+}
+
+// loadImageCommand returns how to load an image into the cluster's nodes
+func loadImageCommand(d Distro, image string) string {
+	switch d {
+	case DistroKind:
+		return fmt.Sprintf("run kind load docker-image %s", image)
+	case DistroMinikube:
+		return fmt.Sprintf("run minikube image load %s", image)
+	}
+	return "load it into the cluster's nodes"
+}
+
+// ProblemPodImageNotLoaded is a problem with a pod on a local cluster
+// whose image was built locally but not loaded into the cluster
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodImageNotLoaded
+var ProblemPodImageNotLoaded = Problem{
+	ID:               "PodImageNotLoaded",
+	ShortDescription: "A pod on a local cluster uses an image that wasn't loaded into the cluster",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodImageNotLoaded",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !cfg.localCluster() {
+			return "", false, false
+		}
+
+		var distro Distro
+		if cfg.Cluster != nil {
+			distro = cfg.Cluster.Distro
+		}
+
+		for _, f := range imagePullFailures(pod) {
+			image := f.Container.Image
+			switch {
+			case f.Reason == "ErrImageNeverPull":
+				return fmt.Sprintf("Container %s uses imagePullPolicy Never but image %s isn't on the node, %s",
+					f.Container.Name, image, loadImageCommand(distro, image)), false, true
+			case imageRegistry(image) == "" && imageNotFound(f.Message) && alwaysPulled(f.Container):
+				return fmt.Sprintf("Container %s pulls image %s from Docker Hub where it doesn't exist, "+
+					"set imagePullPolicy to IfNotPresent so that loaded images are used and %s",
+					f.Container.Name, image, loadImageCommand(distro, image)), false, true
+			case imageRegistry(image) == "" && imageNotFound(f.Message):
+				return fmt.Sprintf("Container %s uses image %s which isn't on the node or Docker Hub, %s",
+					f.Container.Name, image, loadImageCommand(distro, image)), false, true
+			}
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemLocalRegistryUnreachable is a problem with a pod on a local
+// cluster that can't pull its image from a registry on the developer's
+// machine
+// https://github.com/Ashvin-Ranjan/k8r/wiki/LocalRegistryUnreachable
+var ProblemLocalRegistryUnreachable = Problem{
+	ID:               "LocalRegistryUnreachable",
+	ShortDescription: "A pod on a local cluster can't pull its image from a local registry",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/LocalRegistryUnreachable",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !cfg.localCluster() {
+			return "", false, false
+		}
+
+		for _, f := range imagePullFailures(pod) {
+			host := imageRegistry(f.Container.Image)
+			if !localRegistry(host) || f.Reason == "ErrImageNeverPull" {
+				continue
+			}
+
+			details := fmt.Sprintf("Container %s can't pull %s from the local registry, check that it is running "+
+				"and that the cluster's nodes can reach it", f.Container.Name, f.Container.Image)
+			if host == "localhost" || host == "127.0.0.1" {
+				details += ", localhost is the node itself inside the cluster so containerd needs a mirror for it"
+			}
+			if f.Message != "" {
+				details += ": " + f.Message
+			}
+			return details, false, true
+		}
+
+		return "", false, false
+	},
+}
+
+// oomKilledContainers returns the number of containers of the pods on the
+// node that are or were last OOM killed
+func oomKilledContainers(node string, pods []corev1.Pod) int {
+	killed := 0
+	for i := range pods {
+		p := &pods[i]
+		if p.Spec.NodeName != node {
+			continue
+		}
+		for j := range p.Status.ContainerStatuses {
+			cs := &p.Status.ContainerStatuses[j]
+			if (cs.State.Terminated != nil && cs.State.Terminated.Reason == "OOMKilled") ||
+				(cs.LastTerminationState.Terminated != nil && cs.LastTerminationState.Terminated.Reason == "OOMKilled") {
+				killed++
+			}
+		}
+	}
+	return killed
+}
+
+// ProblemLocalNodeMemoryLow is a problem with a local cluster's node that
+// has too little memory, so its pods are OOM killed
+// https://github.com/Ashvin-Ranjan/k8r/wiki/LocalNodeMemoryLow
+var ProblemLocalNodeMemoryLow = Problem{
+	ID:               "LocalNodeMemoryLow",
+	ShortDescription: "A local cluster's node has too little memory and its pods are being OOM killed",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/LocalNodeMemoryLow",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !cfg.localCluster() || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		memory, ok := node.Status.Allocatable[corev1.ResourceMemory]
+		if !ok || memory.Cmp(localMemoryMinimum) >= 0 {
+			return "", false, false
+		}
+
+		killed := oomKilledContainers(node.Name, cfg.Cluster.Pods)
+		if killed == 0 {
+			return "", false, false
+		}
+
+		fix := "give Docker more memory, e.g. in Docker Desktop's resource settings"
+		if cfg.Cluster.Distro == DistroMinikube {
+			fix = "recreate the cluster with more memory, e.g. minikube start --memory 8g"
+		}
+		return fmt.Sprintf("Node has %s of memory and %d containers on it were OOM killed, %s",
+			memory.String(), killed, fix), false, true
+	},
+}
@@ -0,0 +1,114 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// localConfig returns a Config for a cluster of the given distribution
+// containing the objects
+func localConfig(distro checkup.Distro, objs ...runtime.Object) *checkup.Config {
+	c := checkuptest.NewCluster(objs...)
+	c.Distro = distro
+	return checkuptest.NewConfig(c)
+}
+
+func TestPodImageNotLoaded(t *testing.T) {
+	withImage := checkuptest.WithImage(checkuptest.DefaultContainer, "myapp:dev")
+	neverPull := checkuptest.NewPod("web", withImage, checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+		spec.Containers[0].ImagePullPolicy = corev1.PullNever
+	}), checkuptest.ImagePullFailing(checkuptest.DefaultContainer, "ErrImageNeverPull", `Container image "myapp:dev" is not present with pull policy of Never`))
+	notFound := checkuptest.NewPod("web", withImage,
+		checkuptest.ImagePullFailing(checkuptest.DefaultContainer, "ErrImagePull", "pull access denied, repository does not exist or may require authorization"))
+	latest := checkuptest.NewPod("web", checkuptest.WithImage(checkuptest.DefaultContainer, "myapp"),
+		checkuptest.ImagePullFailing(checkuptest.DefaultContainer, "ErrImagePull", "failed to resolve reference \"docker.io/library/myapp:latest\": not found"))
+	timeout := checkuptest.NewPod("web", withImage,
+		checkuptest.ImagePullFailing(checkuptest.DefaultContainer, "ErrImagePull", "dial tcp: i/o timeout"))
+
+	checkuptest.RunCases(t, checkup.ProblemPodImageNotLoaded, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewPod("web"), Config: localConfig(checkup.DistroKind)},
+		{
+			Name:           "never pulled on kind",
+			Object:         neverPull,
+			Config:         localConfig(checkup.DistroKind),
+			Occurring:      true,
+			DetailsContain: "run kind load docker-image myapp:dev",
+		},
+		{
+			Name:           "not found on minikube",
+			Object:         notFound,
+			Config:         localConfig(checkup.DistroMinikube),
+			Occurring:      true,
+			DetailsContain: "run minikube image load myapp:dev",
+		},
+		{
+			Name:           "latest is always pulled",
+			Object:         latest,
+			Config:         localConfig(checkup.DistroKind),
+			Occurring:      true,
+			DetailsContain: "set imagePullPolicy to IfNotPresent",
+		},
+		{Name: "network error", Object: timeout, Config: localConfig(checkup.DistroKind)},
+		{Name: "not a local cluster", Object: neverPull, Config: localConfig(checkup.DistroEKS)},
+	})
+}
+
+func TestLocalRegistryUnreachable(t *testing.T) {
+	failing := func(image string) *corev1.Pod {
+		return checkuptest.NewPod("web", checkuptest.WithImage(checkuptest.DefaultContainer, image),
+			checkuptest.ImagePullFailing(checkuptest.DefaultContainer, "ErrImagePull", "connection refused"))
+	}
+
+	local := checkuptest.NewConfig(nil)
+	local.Local = true
+
+	checkuptest.RunCases(t, checkup.ProblemLocalRegistryUnreachable, []checkuptest.Case{
+		{
+			Name:           "localhost",
+			Object:         failing("localhost:5001/myapp:dev"),
+			Config:         local,
+			Occurring:      true,
+			DetailsContain: "containerd needs a mirror for it: connection refused",
+		},
+		{
+			Name:           "kind registry",
+			Object:         failing("kind-registry:5000/myapp:dev"),
+			Config:         localConfig(checkup.DistroKind),
+			Occurring:      true,
+			DetailsContain: "can't pull kind-registry:5000/myapp:dev from the local registry",
+		},
+		{Name: "remote registry", Object: failing("ghcr.io/org/myapp:dev"), Config: local},
+		{Name: "not a local cluster", Object: failing("localhost:5001/myapp:dev"), Config: checkuptest.NewConfig(nil)},
+	})
+}
+
+func TestLocalNodeMemoryLow(t *testing.T) {
+	small := checkuptest.NewNode("kind-control-plane", checkuptest.NodeAllocatable(corev1.ResourceMemory, "2Gi"))
+	large := checkuptest.NewNode("kind-control-plane", checkuptest.NodeAllocatable(corev1.ResourceMemory, "16Gi"))
+	killed := checkuptest.NewPod("web", checkuptest.OnNode("kind-control-plane"), checkuptest.RecentlyOOMKilled(checkuptest.DefaultContainer))
+	healthy := checkuptest.NewPod("web", checkuptest.OnNode("kind-control-plane"))
+
+	checkuptest.RunCases(t, checkup.ProblemLocalNodeMemoryLow, []checkuptest.Case{
+		{
+			Name:           "small node with OOM kills",
+			Object:         small,
+			Config:         localConfig(checkup.DistroKind, small, killed),
+			Occurring:      true,
+			DetailsContain: "Node has 2Gi of memory and 1 containers on it were OOM killed, give Docker more memory",
+		},
+		{
+			Name:           "minikube",
+			Object:         small,
+			Config:         localConfig(checkup.DistroMinikube, small, killed),
+			Occurring:      true,
+			DetailsContain: "minikube start --memory",
+		},
+		{Name: "no OOM kills", Object: small, Config: localConfig(checkup.DistroKind, small, healthy)},
+		{Name: "large node", Object: large, Config: localConfig(checkup.DistroKind, large, killed)},
+		{Name: "not a local cluster", Object: small, Config: localConfig(checkup.DistroGeneric, small, killed)},
+	})
+}
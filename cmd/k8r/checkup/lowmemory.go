@@ -0,0 +1,73 @@
+// Description: This file contains code for scanning huge clusters with
+// less memory, used with the low-memory flag. Objects are listed a page at
+// a time and stripped of fields no problem reads. Every object is kept
+// until the checks have run since problems look at related objects, e.g.
+// the pods a Service selects, after which only the problems found are.
+
+package checkup
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/pager"
+)
+
+// lowMemoryPageSize is the number of objects listed per request with the
+// low-memory flag
+const lowMemoryPageSize = 500
+
+// stripObject drops the parts of an object that no problem looks at but
+// that can be most of its size, e.g. managedFields
+func stripObject(obj metav1.Object) {
+	obj.SetManagedFields(nil)
+
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; !ok {
+		return
+	}
+	stripped := make(map[string]string, len(annotations)-1)
+	for k, v := range annotations {
+		if k != corev1.LastAppliedConfigAnnotation {
+			stripped[k] = v
+		}
+	}
+	obj.SetAnnotations(stripped)
+}
+
+// listPaged lists objects with fn a page at a time, calling each with
+// every object after it was stripped, so that a whole list is never held
+// in memory at once
+func listPaged(ctx context.Context, fn pager.ListPageFunc, each func(obj runtime.Object)) error {
+	p := pager.New(fn)
+	p.PageSize = lowMemoryPageSize
+	return p.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		if accessor, err := meta.Accessor(obj); err == nil {
+			stripObject(accessor)
+		}
+		each(obj)
+		return nil
+	})
+}
+
+// relateProblems suppresses the problems that are symptoms of others and
+// works out their likely root causes, which look at the objects problems
+// were found on so must be done before the cluster is released
+func (o *Options) relateProblems(resources []Resource) {
+	suppressSymptoms(resources, o.cfg.Cluster)
+	o.causes = likelyCauses(resources, o.cfg.Cluster)
+	o.related = true
+}
+
+// release drops every object once problems were detected and related,
+// keeping only what the report says about the scan itself
+func (c *Cluster) release() {
+	*c = Cluster{
+		Distro:     c.Distro,
+		Incomplete: c.Incomplete,
+		Listed:     c.Listed,
+	}
+}
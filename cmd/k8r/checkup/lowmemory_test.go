@@ -0,0 +1,76 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestLowMemory(t *testing.T) {
+	applied := func(p *corev1.Pod) {
+		p.Annotations = map[string]string{corev1.LastAppliedConfigAnnotation: `{"kind":"Pod"}`}
+		p.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+	}
+	objs := []runtime.Object{
+		checkuptest.NewNode("node-1", checkuptest.NodeNotReady("kubelet stopped posting node status")),
+		checkuptest.NewPod("api", applied, checkuptest.Restarts(checkuptest.DefaultContainer, 20)),
+		checkuptest.NewPod("worker", checkuptest.OnNode("node-1"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+		checkuptest.NewJob("migrate", 1),
+	}
+
+	// The report is the same, including the symptoms suppressed because of
+	// the objects that were released
+	scan := func(lowMemory bool) (string, *checkup.Cluster) {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.LowMemory = lowMemory
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+
+		err := o.RunWithClient(context.Background(), newClientset(objs, nil))
+		if !errors.Is(err, checkup.ErrProblemsFound) {
+			t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+		}
+		return out.String(), cfg.Cluster
+	}
+
+	want, _ := scan(false)
+	got, c := scan(true)
+	if !strings.Contains(want, "suppressed") {
+		t.Fatalf("output = %q, expected the pod on the node that isn't ready to be suppressed", want)
+	}
+	if got != want {
+		t.Errorf("low memory scan reported %q, expected %q", got, want)
+	}
+
+	if c.Pods != nil || c.Nodes != nil || c.Jobs != nil || c.Secrets != nil {
+		t.Error("expected every object to be released once problems were found")
+	}
+
+	// Objects are stripped as they are listed
+	cfg := checkuptest.NewConfig(nil)
+	cfg.LowMemory = true
+	listed, err := checkup.ListCluster(context.Background(), newClientset(objs, nil), cfg)
+	if err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	for i := range listed.Pods {
+		p := &listed.Pods[i]
+		if p.ManagedFields != nil {
+			t.Errorf("pod %s kept its managedFields", p.Name)
+		}
+		if _, ok := p.Annotations[corev1.LastAppliedConfigAnnotation]; ok {
+			t.Errorf("pod %s kept its last applied configuration", p.Name)
+		}
+	}
+}
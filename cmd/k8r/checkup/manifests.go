@@ -0,0 +1,223 @@
+// Description: This file contains code for reading Kubernetes objects
+// from local manifest files
+
+package checkup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Manifest is a Kubernetes object that was read from a manifest file
+type Manifest struct {
+	// Object is the decoded Kubernetes object
+	Object runtime.Object
+
+	// File is the file the object was read from
+	File string
+
+	// Line is the line in the file that the object starts at, or
+	// 0 if it isn't known
+	Line int
+
+	// Generator is the tool that rendered the manifest, e.g. kustomize,
+	// or empty if it was read from a file as is
+	Generator string
+}
+
+// Source returns the location of the manifest in the format file:line,
+// or just the file when the line isn't known
+func (m *Manifest) Source() string {
+	if m.Line == 0 {
+		return m.File
+	}
+	return fmt.Sprintf("%s:%d", m.File, m.Line)
+}
+
+// isManifestFile returns true if the file looks like a Kubernetes manifest
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// ReadManifests reads all of the Kubernetes objects from the given files,
+// directories are walked recursively for YAML and JSON files
+func ReadManifests(paths []string) ([]Manifest, error) {
+	manifests := make([]Manifest, 0)
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Only filter on extension when walking a directory, files
+			// that were explicitly passed in are always read
+			if info.IsDir() || (file != path && !isManifestFile(file)) {
+				return nil
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s", file)
+			}
+
+			ms, err := ParseManifests(file, data)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, ms...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifests, nil
+}
+
+// ParseManifests decodes all of the Kubernetes objects in a multi-document
+// YAML (or JSON) file. Objects of kinds that aren't known, e.g. custom
+// resources, are skipped.
+func ParseManifests(file string, data []byte) ([]Manifest, error) {
+	decoder := scheme.Codecs.UniversalDeserializer()
+
+	manifests := make([]Manifest, 0)
+	for _, doc := range splitYAMLDocuments(data) {
+		obj, _, err := decoder.Decode(doc.data, nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to decode object at %s:%d", file, doc.line)
+		}
+
+		m := Manifest{
+			Object: obj,
+			File:   file,
+			Line:   doc.line,
+		}
+
+		// Rendered templates, e.g. from helm template, note the template
+		// each document came from. Lines in the rendered output don't
+		// match the template so only the file is kept.
+		if doc.source != "" {
+			m.File = doc.source
+			m.Line = 0
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	return manifests, nil
+}
+
+// yamlDocument is a single document in a YAML file
+type yamlDocument struct {
+	data   []byte
+	line   int
+	source string
+}
+
+// sourceCommentPrefix is the prefix of the comment helm template adds to
+// each document denoting which template it was rendered from
+const sourceCommentPrefix = "# Source: "
+
+// splitYAMLDocuments splits a YAML file into its documents, keeping track
+// of the line each document's content starts on. Documents that only
+// contain comments or whitespace are dropped.
+func splitYAMLDocuments(data []byte) []yamlDocument {
+	docs := make([]yamlDocument, 0)
+
+	var cur bytes.Buffer
+	start := 0
+	source := ""
+	add := func() {
+		if start != 0 {
+			docs = append(docs, yamlDocument{data: append([]byte(nil), cur.Bytes()...), line: start, source: source})
+		}
+		cur.Reset()
+		start = 0
+		source = ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "---") {
+			add()
+			continue
+		}
+
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, sourceCommentPrefix) {
+			source = strings.TrimPrefix(trimmed, sourceCommentPrefix)
+		}
+		if start == 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			start = line
+		}
+		cur.WriteString(text)
+		cur.WriteByte('\n')
+	}
+	add()
+
+	return docs
+}
+
+// podFromObject returns a pod for the given object, workloads are turned
+// into a pod built from their pod template so that pod problems can be
+// checked against them. The returned kind is the kind of the object.
+func podFromObject(obj runtime.Object) (pod *corev1.Pod, kind string, ok bool) {
+	var meta *metav1.ObjectMeta
+	var template *corev1.PodTemplateSpec
+
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return o, "pod", true
+	case *appsv1.Deployment:
+		meta, template, kind = &o.ObjectMeta, &o.Spec.Template, "Deployment"
+	case *appsv1.StatefulSet:
+		meta, template, kind = &o.ObjectMeta, &o.Spec.Template, "StatefulSet"
+	case *appsv1.DaemonSet:
+		meta, template, kind = &o.ObjectMeta, &o.Spec.Template, "DaemonSet"
+	case *appsv1.ReplicaSet:
+		meta, template, kind = &o.ObjectMeta, &o.Spec.Template, "ReplicaSet"
+	case *batchv1.Job:
+		meta, template, kind = &o.ObjectMeta, &o.Spec.Template, "Job"
+	case *batchv1.CronJob:
+		meta, template, kind = &o.ObjectMeta, &o.Spec.JobTemplate.Spec.Template, "CronJob"
+	default:
+		return nil, "", false
+	}
+
+	pod = &corev1.Pod{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	pod.Name = meta.Name
+	pod.Namespace = meta.Namespace
+
+	// Fall back to the workload's labels so that owner information
+	// is still available when the template doesn't set it
+	if pod.Labels == nil {
+		pod.Labels = meta.Labels
+	}
+
+	return pod, kind, true
+}
@@ -0,0 +1,34 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestMaxedOutHPAs(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemMaxedOutHPAs, []checkuptest.Case{
+		{Name: "room to scale", Object: checkuptest.NewHPA("web", 2, 10)},
+		{
+			Name:           "maxed out",
+			Object:         checkuptest.NewHPA("web", 10, 10),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "10/10 replicas",
+		},
+	})
+}
+
+func TestHighRestarts(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemHighRestarts, []checkuptest.Case{
+		{Name: "below threshold", Object: checkuptest.NewPod("web", checkuptest.Restarts(checkuptest.DefaultContainer, 2))},
+		{
+			Name:           "at threshold",
+			Object:         checkuptest.NewPod("web", checkuptest.Restarts(checkuptest.DefaultContainer, 3)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "restarted 3 time(s)",
+		},
+	})
+}
@@ -0,0 +1,252 @@
+// Description: This file contains code for problems related to nodes
+
+package checkup
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// kubeletProbeTimeout is how long to wait when connecting to a kubelet
+const kubeletProbeTimeout = 5 * time.Second
+
+// nodeAddress returns the address that kubelet can be reached at for a node
+func nodeAddress(node *corev1.Node) (string, bool) {
+	for _, t := range []corev1.NodeAddressType{corev1.NodeInternalIP, corev1.NodeExternalIP, corev1.NodeHostName} {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == t {
+				port := int(node.Status.DaemonEndpoints.KubeletEndpoint.Port)
+				if port == 0 {
+					port = 10250
+				}
+				return net.JoinHostPort(addr.Address, strconv.Itoa(port)), true
+			}
+		}
+	}
+	return "", false
+}
+
+// nodeCondition returns the node's condition of the given type
+func nodeCondition(node *corev1.Node, t corev1.NodeConditionType) (*corev1.NodeCondition, bool) {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == t {
+			return &node.Status.Conditions[i], true
+		}
+	}
+	return nil, false
+}
+
+// probeKubeletCertificates connects to the kubelet of each node and returns
+// the serving certificate it presents. Nodes that can't be reached are
+// skipped.
+func probeKubeletCertificates(ctx context.Context, nodes []corev1.Node) map[string]*x509.Certificate {
+	certs := make(map[string]*x509.Certificate)
+	for i := range nodes {
+		if ctx.Err() != nil {
+			break
+		}
+		addr, ok := nodeAddress(&nodes[i])
+		if !ok {
+			continue
+		}
+
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: kubeletProbeTimeout},
+			// We only want to read the certificate, not trust it
+			Config: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Why: Only reading the certificate
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			continue
+		}
+
+		if state := conn.(*tls.Conn).ConnectionState(); len(state.PeerCertificates) > 0 {
+			certs[nodes[i].Name] = state.PeerCertificates[0]
+		}
+		conn.Close()
+	}
+	return certs
+}
+
+// nodeCSRCertificates returns the newest certificate issued to a node
+// through a CertificateSigningRequest for each kubelet signer
+func nodeCSRCertificates(node *corev1.Node, csrs []certificatesv1.CertificateSigningRequest) map[string]*x509.Certificate {
+	certs := make(map[string]*x509.Certificate)
+	for i := range csrs {
+		csr := &csrs[i]
+		if csr.Spec.Username != "system:node:"+node.Name || len(csr.Status.Certificate) == 0 {
+			continue
+		}
+		if csr.Spec.SignerName != certificatesv1.KubeletServingSignerName &&
+			csr.Spec.SignerName != certificatesv1.KubeAPIServerClientKubeletSignerName {
+			continue
+		}
+
+		block, _ := pem.Decode(csr.Status.Certificate)
+		if block == nil {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		if existing, ok := certs[csr.Spec.SignerName]; !ok || cert.NotAfter.After(existing.NotAfter) {
+			certs[csr.Spec.SignerName] = cert
+		}
+	}
+	return certs
+}
+
+// ProblemNodeCertificateExpiring is a problem with a node whose kubelet
+// serving or client certificate is expired or close to expiring, which
+// takes the node NotReady once it expires
+// https://github.com/Ashvin-Ranjan/k8r/wiki/NodeCertificateExpiring
+var ProblemNodeCertificateExpiring = Problem{
+	ID:               "NodeCertificateExpiring",
+	ShortDescription: "A node's kubelet certificate is expired or about to expire",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/NodeCertificateExpiring",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		certs := nodeCSRCertificates(node, cfg.Cluster.CertificateSigningRequests)
+		if cert, ok := cfg.Cluster.KubeletCertificates[node.Name]; ok {
+			// The certificate the kubelet is actually serving is more
+			// accurate than the last one that was requested
+			certs[certificatesv1.KubeletServingSignerName] = cert
+		}
+
+		now := time.Now()
+		details := make([]string, 0)
+		warning := true
+		for signer, cert := range certs {
+			name := "client"
+			if signer == certificatesv1.KubeletServingSignerName {
+				name = "serving"
+			}
+
+			left := cert.NotAfter.Sub(now)
+			switch {
+			case left <= 0:
+				warning = false
+				details = append(details, fmt.Sprintf("kubelet %s certificate expired at %s", name, cert.NotAfter.Format(time.RFC3339)))
+			case left <= cfg.CertExpiryThreshold:
+				details = append(details, fmt.Sprintf("kubelet %s certificate expires in %s", name, left.Round(time.Hour)))
+			}
+		}
+		if len(details) == 0 {
+			return "", false, false
+		}
+
+		return strings.Join(details, ", "), warning, true
+	},
+}
+
+// ProblemNodeNotReady is a problem with a node that isn't ready, every pod
+// on it is affected
+// https://github.com/Ashvin-Ranjan/k8r/wiki/NodeNotReady
+var ProblemNodeNotReady = Problem{
+	ID:               "NodeNotReady",
+	ShortDescription: "A node is not ready, pods on it can't run or be reached",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/NodeNotReady",
+	Severity:         SeverityCritical,
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return "", false, false
+		}
+
+		for i := range node.Status.Conditions {
+			c := &node.Status.Conditions[i]
+			if c.Type != corev1.NodeReady || c.Status == corev1.ConditionTrue {
+				continue
+			}
+
+			return fmt.Sprintf("Node has been not ready since %s: %s",
+				c.LastTransitionTime.Format(time.RFC3339), c.Message,
+			), false, true
+		}
+
+		return "", false, false
+	},
+}
+
+// nodeProblemConditions are the condition types node-problem-detector
+// sets to true when it detects a problem on a node, mapped to whether the
+// problem is a warning. Problems that break the node outright are errors,
+// ones that come and go, like runtime restarts, are warnings.
+var nodeProblemConditions = map[corev1.NodeConditionType]bool{
+	"KernelDeadlock":              false,
+	"ReadonlyFilesystem":          false,
+	"CorruptDockerOverlay2":       false,
+	"KubeletProblem":              false,
+	"ContainerRuntimeProblem":     false,
+	"FrequentKubeletRestart":      true,
+	"FrequentDockerRestart":       true,
+	"FrequentContainerdRestart":   true,
+	"FrequentUnregisterNetDevice": true,
+}
+
+// ProblemNodeProblemDetected is a problem with a node that
+// node-problem-detector, or another agent reporting node conditions the
+// same way, found a kernel, filesystem or runtime problem on. Extra
+// condition types can be checked with the node-problem-conditions flag.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/NodeProblemDetected
+var ProblemNodeProblemDetected = Problem{
+	ID:               "NodeProblemDetected",
+	ShortDescription: "node-problem-detector reports a kernel, filesystem or runtime problem on a node",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/NodeProblemDetected",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return "", false, false
+		}
+
+		details := make([]string, 0)
+		warning := true
+		for i := range node.Status.Conditions {
+			c := &node.Status.Conditions[i]
+			if c.Status != corev1.ConditionTrue {
+				continue
+			}
+
+			condWarning, known := nodeProblemConditions[c.Type]
+			for _, t := range cfg.NodeProblemConditions {
+				if string(c.Type) == t {
+					known, condWarning = true, false
+				}
+			}
+			if !known {
+				continue
+			}
+
+			warning = warning && condWarning
+			detail := fmt.Sprintf("%s since %s", c.Type, c.LastTransitionTime.Format(time.RFC3339))
+			if c.Message != "" {
+				detail += ": " + c.Message
+			} else if c.Reason != "" {
+				detail += ": " + c.Reason
+			}
+			details = append(details, detail)
+		}
+		if len(details) == 0 {
+			return "", false, false
+		}
+
+		return strings.Join(details, "; "), warning, true
+	},
+}
@@ -0,0 +1,80 @@
+package checkup_test
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNodeCertificateExpiring(t *testing.T) {
+	node := checkuptest.NewNode("node-1")
+	withCert := func(notAfter time.Time) *checkup.Config {
+		cluster := checkuptest.NewCluster(node)
+		cluster.KubeletCertificates = map[string]*x509.Certificate{node.Name: {NotAfter: notAfter}}
+		return checkuptest.NewConfig(cluster)
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemNodeCertificateExpiring, []checkuptest.Case{
+		{Name: "no certificates", Object: node},
+		{Name: "valid", Object: node, Config: withCert(time.Now().Add(365 * 24 * time.Hour))},
+		{
+			Name:           "expiring",
+			Object:         node,
+			Config:         withCert(time.Now().Add(7 * 24 * time.Hour)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "kubelet serving certificate expires in",
+		},
+		{
+			Name:           "expired",
+			Object:         node,
+			Config:         withCert(time.Now().Add(-time.Hour)),
+			Occurring:      true,
+			DetailsContain: "kubelet serving certificate expired",
+		},
+	})
+}
+
+func TestNodeNotReady(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemNodeNotReady, []checkuptest.Case{
+		{Name: "ready", Object: checkuptest.NewNode("node-1")},
+		{
+			Name:           "not ready",
+			Object:         checkuptest.NewNode("node-1", checkuptest.NodeNotReady("Kubelet stopped posting node status.")),
+			Occurring:      true,
+			DetailsContain: "Kubelet stopped posting node status.",
+		},
+	})
+}
+
+func TestNodeProblemDetected(t *testing.T) {
+	custom := checkuptest.NewConfig(nil)
+	custom.NodeProblemConditions = []string{"NTPProblem"}
+	ntp := checkuptest.NewNode("node-1", checkuptest.NodeCondition("NTPProblem", corev1.ConditionTrue, "NTPIsDown", "ntp service is not running"))
+
+	checkuptest.RunCases(t, checkup.ProblemNodeProblemDetected, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewNode("node-1", checkuptest.NodeCondition("KernelDeadlock", corev1.ConditionFalse, "KernelHasNoDeadlock", ""))},
+		{
+			Name:           "frequent restarts",
+			Object:         checkuptest.NewNode("node-1", checkuptest.NodeCondition("FrequentKubeletRestart", corev1.ConditionTrue, "FrequentKubeletRestart", "Found 6 matching logs")),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "FrequentKubeletRestart since 2022-06-01T12:00:00Z: Found 6 matching logs",
+		},
+		{
+			Name: "read-only filesystem",
+			Object: checkuptest.NewNode("node-1",
+				checkuptest.NodeCondition("FrequentContainerdRestart", corev1.ConditionTrue, "FrequentContainerdRestart", ""),
+				checkuptest.NodeCondition("ReadonlyFilesystem", corev1.ConditionTrue, "FilesystemIsReadOnly", "Node condition ReadonlyFilesystem is now: True"),
+			),
+			Occurring:      true,
+			DetailsContain: "FrequentContainerdRestart since 2022-06-01T12:00:00Z: FrequentContainerdRestart; ReadonlyFilesystem since",
+		},
+		{Name: "unknown condition", Object: ntp},
+		{Name: "custom condition", Object: ntp, Config: custom, Occurring: true, DetailsContain: "NTPProblem since 2022-06-01T12:00:00Z: ntp service is not running"},
+	})
+}
@@ -0,0 +1,125 @@
+// Description: This file contains code for reading the stats summary and
+// eviction thresholds of kubelets through the API server's node proxy
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FsStats is how much of a filesystem is in use, from the kubelet stats
+// summary
+type FsStats struct {
+	// AvailableBytes is how much of the filesystem is free
+	AvailableBytes uint64 `json:"availableBytes"`
+
+	// CapacityBytes is the size of the filesystem
+	CapacityBytes uint64 `json:"capacityBytes"`
+}
+
+// NodeStats is the part of a kubelet's stats summary and configuration
+// that checks use
+type NodeStats struct {
+	// NodeFs is the filesystem kubelet keeps pod data and logs on
+	NodeFs *FsStats
+
+	// ImageFs is the filesystem the container runtime keeps images and
+	// writable layers on, which is the node filesystem unless the runtime
+	// has one of its own
+	ImageFs *FsStats
+
+	// PVCs are the volume stats of the PersistentVolumeClaims mounted by
+	// pods on the node, keyed by namespace/name of the claim
+	PVCs map[string]*FsStats
+
+	// EvictionHard are the hard eviction thresholds kubelet is configured
+	// with, e.g. nodefs.available: 10%, empty when its configuration
+	// couldn't be read
+	EvictionHard map[string]string
+}
+
+// kubeletSummary is the part of the kubelet stats summary API response
+// that is read
+type kubeletSummary struct {
+	Node struct {
+		Fs      *FsStats `json:"fs"`
+		Runtime *struct {
+			ImageFs *FsStats `json:"imageFs"`
+		} `json:"runtime"`
+	} `json:"node"`
+	Pods []struct {
+		Volumes []struct {
+			FsStats
+			PVCRef *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// kubeletConfigz is the part of the kubelet configz API response that is
+// read
+type kubeletConfigz struct {
+	KubeletConfig struct {
+		EvictionHard map[string]string `json:"evictionHard"`
+	} `json:"kubeletconfig"`
+}
+
+// gatherNodeStats reads the stats summary and eviction thresholds of the
+// kubelet on each ready node through the node proxy. This is best effort,
+// kubelets that can't be reached are skipped.
+func gatherNodeStats(ctx context.Context, k kubernetes.Interface, nodes []corev1.Node) map[string]*NodeStats {
+	stats := make(map[string]*NodeStats)
+	rc, ok := discoveryClient(k)
+	if !ok {
+		return stats
+	}
+
+	for i := range nodes {
+		if ctx.Err() != nil {
+			break
+		}
+		node := &nodes[i]
+		if ready, ok := nodeCondition(node, corev1.NodeReady); !ok || ready.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		body, err := rc.Get().AbsPath("/api/v1/nodes", node.Name, "proxy/stats/summary").Do(ctx).Raw()
+		if err != nil {
+			continue
+		}
+		var summary kubeletSummary
+		if err := json.Unmarshal(body, &summary); err != nil {
+			continue
+		}
+
+		s := &NodeStats{NodeFs: summary.Node.Fs}
+		if summary.Node.Runtime != nil {
+			s.ImageFs = summary.Node.Runtime.ImageFs
+		}
+		s.PVCs = make(map[string]*FsStats)
+		for _, pod := range summary.Pods {
+			for j := range pod.Volumes {
+				if v := &pod.Volumes[j]; v.PVCRef != nil {
+					s.PVCs[v.PVCRef.Namespace+"/"+v.PVCRef.Name] = &v.FsStats
+				}
+			}
+		}
+
+		// The eviction thresholds are only used to be more precise, the
+		// defaults are used without them
+		if body, err := rc.Get().AbsPath("/api/v1/nodes", node.Name, "proxy/configz").Do(ctx).Raw(); err == nil {
+			var configz kubeletConfigz
+			if json.Unmarshal(body, &configz) == nil {
+				s.EvictionHard = configz.KubeletConfig.EvictionHard
+			}
+		}
+		stats[node.Name] = s
+	}
+	return stats
+}
@@ -0,0 +1,70 @@
+// Description: This file contains code for keeping track of the errors
+// that were sent to a notifier, e.g. Datadog, so that each is only sent
+// once while it lasts
+
+package checkup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// NotifiedErrors are the errors that were sent to a notifier, keyed by the
+// cluster they were found in
+type NotifiedErrors map[string][]string
+
+// defaultNotifiedFile returns where the errors sent to a notifier are
+// recorded by default, or an empty string if there is no cache directory
+func defaultNotifiedFile(notifier string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "k8r", notifier+".json")
+}
+
+// loadNotifiedErrors reads the errors that were sent from a file, a file
+// that doesn't exist yet means none were sent. No file at all is the same.
+func loadNotifiedErrors(path string) (NotifiedErrors, error) {
+	n := make(NotifiedErrors)
+	if path == "" {
+		return n, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return n, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read notified errors")
+	}
+
+	if err := json.Unmarshal(b, &n); err != nil {
+		return nil, errors.Wrap(err, "failed to parse notified errors")
+	}
+	return n, nil
+}
+
+// save writes the errors that were sent to a file, if there is one
+func (n NotifiedErrors) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create notified errors directory")
+	}
+
+	b, err := json.Marshal(n)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal notified errors")
+	}
+
+	return errors.Wrap(os.WriteFile(path, b, 0o600), "failed to write notified errors")
+}
+
+// notifiedKey returns the key an error is recorded by
+func notifiedKey(r *Resource) string {
+	return r.ProblemID + "|" + r.Type + "|" + r.Name
+}
@@ -0,0 +1,297 @@
+// Description: This file contains code for problems related to the custom
+// resources of popular operators, e.g. Kafka or Postgres clusters
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// OperatorPreset maps the status of an operator's custom resource to
+// findings. Presets are enabled automatically when the operator's CRD is
+// installed.
+type OperatorPreset struct {
+	// Name is the name of the preset, used by the disable-operator-preset
+	// flag
+	Name string
+
+	// Resource is the custom resource the preset checks
+	Resource CustomResource
+
+	// Check returns what is wrong with the custom resource, on top of
+	// what its conditions report
+	Check func(u *unstructured.Unstructured, c *Cluster) []string
+}
+
+// OperatorPresets are the presets for the operators checkup knows about
+var OperatorPresets = []OperatorPreset{
+	{
+		Name:     "strimzi-kafka",
+		Resource: CustomResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkas", Kind: "Kafka"},
+		Check:    checkStrimziKafka,
+	},
+	{
+		Name:     "zalando-postgres",
+		Resource: CustomResource{Group: "acid.zalan.do", Version: "v1", Resource: "postgresqls", Kind: "postgresql"},
+		Check:    checkZalandoPostgres,
+	},
+	{
+		Name:     "crunchy-postgres",
+		Resource: CustomResource{Group: "postgres-operator.crunchydata.com", Version: "v1beta1", Resource: "postgresclusters", Kind: "PostgresCluster"},
+		Check:    checkCrunchyPostgres,
+	},
+	{
+		Name:     "redis-operator",
+		Resource: CustomResource{Group: "redis.redis.opstreelabs.in", Version: "v1beta2", Resource: "redisclusters", Kind: "RedisCluster"},
+		Check:    checkRedisCluster,
+	},
+}
+
+// operatorPreset returns the preset for a custom resource, if there is one
+func operatorPreset(u *unstructured.Unstructured) (*OperatorPreset, bool) {
+	for i := range OperatorPresets {
+		p := &OperatorPresets[i]
+		if u.GetAPIVersion() == p.Resource.GroupVersion() && u.GetKind() == p.Resource.Kind {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// unhealthyConditionTypes are condition types that are bad when true,
+// all other conditions are treated as bad when false, e.g. Ready
+var unhealthyConditionTypes = map[string]bool{
+	"Degraded": true,
+	"Failed":   true,
+	"Error":    true,
+	"NotReady": true,
+	"Warning":  true,
+}
+
+// conditionFindings is the generic condition checker, it returns a finding
+// for each condition in the status of a custom resource that reports it
+// is unhealthy, following the conventions most operators use
+func conditionFindings(u *unstructured.Unstructured) []string {
+	findings := make([]string, 0)
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _, _ := unstructured.NestedString(cond, "type")
+		status, _, _ := unstructured.NestedString(cond, "status")
+
+		bad := status == string(metav1.ConditionFalse)
+		if unhealthyConditionTypes[condType] {
+			bad = status == string(metav1.ConditionTrue)
+		}
+		if !bad {
+			continue
+		}
+
+		finding := fmt.Sprintf("%s is %s", condType, status)
+		if reason, _, _ := unstructured.NestedString(cond, "reason"); reason != "" {
+			finding += fmt.Sprintf(" (%s)", reason)
+		}
+		if message, _, _ := unstructured.NestedString(cond, "message"); message != "" {
+			finding += ": " + message
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// strimziBrokerPods returns the broker pods of a Strimzi Kafka cluster
+func strimziBrokerPods(kafka *unstructured.Unstructured, pods []corev1.Pod) []*corev1.Pod {
+	brokers := make([]*corev1.Pod, 0)
+	for i := range pods {
+		p := &pods[i]
+		if p.Namespace == kafka.GetNamespace() && p.Labels["strimzi.io/cluster"] == kafka.GetName() &&
+			p.Labels["strimzi.io/name"] == kafka.GetName()+"-kafka" {
+			brokers = append(brokers, p)
+		}
+	}
+	return brokers
+}
+
+// podReady returns true if a pod is running and all of its containers are
+// ready
+func podReady(p *corev1.Pod) bool {
+	if p.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for i := range p.Status.ContainerStatuses {
+		if !p.Status.ContainerStatuses[i].Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// checkStrimziKafka checks that the broker pods of a Strimzi Kafka cluster
+// are ready and are in sync with the partitions they replicate
+func checkStrimziKafka(kafka *unstructured.Unstructured, c *Cluster) []string {
+	findings := make([]string, 0)
+	for _, p := range strimziBrokerPods(kafka, c.Pods) {
+		if !podReady(p) {
+			findings = append(findings, fmt.Sprintf("Kafka broker pod %s is not ready", p.Name))
+		}
+		if n := c.KafkaUnderReplicatedPartitions[p.Namespace+"/"+p.Name]; n > 0 {
+			findings = append(findings, fmt.Sprintf("Kafka broker pod %s leads %d partitions with replicas out of ISR", p.Name, n))
+		}
+	}
+	return findings
+}
+
+// zalandoFailedStatuses are the statuses of a Zalando Postgres cluster
+// that mean the operator failed to reconcile it
+var zalandoFailedStatuses = map[string]bool{
+	"CreateFailed": true,
+	"UpdateFailed": true,
+	"SyncFailed":   true,
+	"AddFailed":    true,
+	"DeleteFailed": true,
+	"Invalid":      true,
+}
+
+// checkZalandoPostgres checks that the Zalando operator reconciled a
+// Postgres cluster
+func checkZalandoPostgres(pg *unstructured.Unstructured, _ *Cluster) []string {
+	status, _, _ := unstructured.NestedString(pg.Object, "status", "PostgresClusterStatus")
+	if zalandoFailedStatuses[status] {
+		return []string{fmt.Sprintf("Postgres cluster status is %s, check the postgres-operator logs", status)}
+	}
+	return nil
+}
+
+// checkCrunchyPostgres checks that every instance set of a CrunchyData
+// Postgres cluster has all of its replicas ready
+func checkCrunchyPostgres(pg *unstructured.Unstructured, _ *Cluster) []string {
+	findings := make([]string, 0)
+	instances, _, _ := unstructured.NestedSlice(pg.Object, "status", "instances")
+	for _, i := range instances {
+		instance, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(instance, "name")
+		replicas, _, _ := unstructured.NestedInt64(instance, "replicas")
+		ready, _, _ := unstructured.NestedInt64(instance, "readyReplicas")
+		if ready < replicas {
+			findings = append(findings, fmt.Sprintf("Postgres instance set %s has %d/%d replicas ready", name, ready, replicas))
+		}
+	}
+	return findings
+}
+
+// checkRedisCluster checks that a Redis cluster from the OT-Container-Kit
+// redis-operator has bootstrapped and has all of its leaders and followers
+// ready
+func checkRedisCluster(redis *unstructured.Unstructured, _ *Cluster) []string {
+	findings := make([]string, 0)
+
+	state, _, _ := unstructured.NestedString(redis.Object, "status", "state")
+	if state == "Failed" {
+		reason, _, _ := unstructured.NestedString(redis.Object, "status", "reason")
+		findings = append(findings, fmt.Sprintf("Redis cluster failed: %s", reason))
+	}
+
+	size, ok, _ := unstructured.NestedInt64(redis.Object, "spec", "clusterSize")
+	if !ok || state != "Ready" {
+		return findings
+	}
+	leaders, _, _ := unstructured.NestedInt64(redis.Object, "status", "readyLeaderReplicas")
+	followers, _, _ := unstructured.NestedInt64(redis.Object, "status", "readyFollowerReplicas")
+	if leaders < size {
+		findings = append(findings, fmt.Sprintf("Redis cluster has %d/%d leaders ready", leaders, size))
+	}
+	if followers < size {
+		findings = append(findings, fmt.Sprintf("Redis cluster has %d/%d followers ready", followers, size))
+	}
+	return findings
+}
+
+// strimziMetricsPort is the port Strimzi exposes Kafka metrics on when
+// metrics are configured
+const strimziMetricsPort = "9404"
+
+// gatherKafkaUnderReplicatedPartitions reads the number of under
+// replicated partitions each Strimzi Kafka broker leads from its metrics,
+// keyed by namespace/name of the pod. This is best effort, brokers that
+// don't expose metrics are skipped.
+func gatherKafkaUnderReplicatedPartitions(ctx context.Context, k kubernetes.Interface, c *Cluster) map[string]int {
+	partitions := make(map[string]int)
+	for i := range c.OperatorResources {
+		if ctx.Err() != nil {
+			break
+		}
+		kafka := &c.OperatorResources[i]
+		if p, ok := operatorPreset(kafka); !ok || p.Name != "strimzi-kafka" {
+			continue
+		}
+		if _, ok, _ := unstructured.NestedMap(kafka.Object, "spec", "kafka", "metricsConfig"); !ok {
+			continue
+		}
+
+		for _, p := range strimziBrokerPods(kafka, c.Pods) {
+			if p.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			proxy := k.CoreV1().Pods(p.Namespace).ProxyGet("http", p.Name, strimziMetricsPort, "/metrics", nil)
+			if proxy == nil {
+				continue
+			}
+			body, err := proxy.DoRaw(ctx)
+			if err != nil {
+				continue
+			}
+			families, err := parseMetrics(body)
+			if err != nil {
+				continue
+			}
+			if f, ok := families["kafka_server_replicamanager_underreplicatedpartitions"]; ok && len(f.Metric) > 0 {
+				partitions[p.Namespace+"/"+p.Name] = int(metricValue(f.Metric[0]))
+			}
+		}
+	}
+	return partitions
+}
+
+// ProblemOperatorResourceUnhealthy is a problem with a custom resource of
+// an operator that checkup has a preset for, e.g. a Kafka cluster, that
+// the operator reports as unhealthy or that has unhealthy pods
+// https://github.com/Ashvin-Ranjan/k8r/wiki/OperatorResourceUnhealthy
+var ProblemOperatorResourceUnhealthy = Problem{
+	ID:               "OperatorResourceUnhealthy",
+	ShortDescription: "A resource managed by an operator, e.g. a Kafka or Postgres cluster, is unhealthy",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/OperatorResourceUnhealthy",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return "", false, false
+		}
+		preset, ok := operatorPreset(u)
+		if !ok {
+			return "", false, false
+		}
+
+		cluster := cfg.Cluster
+		if cluster == nil {
+			cluster = &Cluster{}
+		}
+		findings := append(conditionFindings(u), preset.Check(u, cluster)...)
+		if len(findings) == 0 {
+			return "", false, false
+		}
+		return fmt.Sprintf("%s %s: %s", u.GetKind(), u.GetName(), strings.Join(findings, "; ")), false, true
+	},
+}
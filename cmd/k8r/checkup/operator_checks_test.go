@@ -0,0 +1,76 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+// operatorResource returns the custom resource of the preset with the
+// given name
+func operatorResource(t *testing.T, preset string) *checkup.CustomResource {
+	t.Helper()
+	for i := range checkup.OperatorPresets {
+		if checkup.OperatorPresets[i].Name == preset {
+			return &checkup.OperatorPresets[i].Resource
+		}
+	}
+	t.Fatalf("no operator preset named %s", preset)
+	return nil
+}
+
+func TestOperatorResourceUnhealthy(t *testing.T) {
+	kafka := operatorResource(t, "strimzi-kafka")
+	brokerLabels := map[string]string{"strimzi.io/cluster": "events", "strimzi.io/name": "events-kafka"}
+
+	healthyKafka := checkuptest.NewCustomResource(kafka, "events", nil, map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+	})
+	notReadyKafka := checkuptest.NewCustomResource(kafka, "events", nil, map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "NotReady", "status": "True", "reason": "TimeoutException", "message": "Exceeded timeout of 300000ms"},
+		},
+	})
+	broker0 := checkuptest.NewPod("events-kafka-0", checkuptest.WithLabels(brokerLabels))
+	broker1 := checkuptest.NewPod("events-kafka-1", checkuptest.WithLabels(brokerLabels), checkuptest.NotReady(checkuptest.DefaultContainer))
+	outOfISR := checkuptest.NewCluster(healthyKafka, broker0)
+	outOfISR.KafkaUnderReplicatedPartitions = map[string]int{"default/events-kafka-0": 3}
+
+	zalando := checkuptest.NewCustomResource(operatorResource(t, "zalando-postgres"), "orders", nil,
+		map[string]interface{}{"PostgresClusterStatus": "SyncFailed"})
+	crunchy := checkuptest.NewCustomResource(operatorResource(t, "crunchy-postgres"), "billing", nil, map[string]interface{}{
+		"instances": []interface{}{map[string]interface{}{"name": "instance1", "replicas": int64(2), "readyReplicas": int64(1)}},
+	})
+	redis := checkuptest.NewCustomResource(operatorResource(t, "redis-operator"), "cache",
+		map[string]interface{}{"clusterSize": int64(3)},
+		map[string]interface{}{"state": "Ready", "readyLeaderReplicas": int64(3), "readyFollowerReplicas": int64(2)})
+
+	checkuptest.RunCases(t, checkup.ProblemOperatorResourceUnhealthy, []checkuptest.Case{
+		{Name: "healthy Kafka", Object: healthyKafka, Config: checkuptest.NewConfig(checkuptest.NewCluster(healthyKafka, broker0))},
+		{
+			Name:           "Kafka not ready",
+			Object:         notReadyKafka,
+			Occurring:      true,
+			DetailsContain: "Kafka events: NotReady is True (TimeoutException): Exceeded timeout of 300000ms",
+		},
+		{
+			Name:           "Kafka broker not ready",
+			Object:         healthyKafka,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(healthyKafka, broker0, broker1)),
+			Occurring:      true,
+			DetailsContain: "Kafka broker pod events-kafka-1 is not ready",
+		},
+		{
+			Name:           "Kafka partitions out of ISR",
+			Object:         healthyKafka,
+			Config:         checkuptest.NewConfig(outOfISR),
+			Occurring:      true,
+			DetailsContain: "Kafka broker pod events-kafka-0 leads 3 partitions with replicas out of ISR",
+		},
+		{Name: "Zalando Postgres sync failed", Object: zalando, Occurring: true, DetailsContain: "Postgres cluster status is SyncFailed"},
+		{Name: "CrunchyData Postgres replica not ready", Object: crunchy, Occurring: true, DetailsContain: "instance set instance1 has 1/2 replicas ready"},
+		{Name: "Redis follower not ready", Object: redis, Occurring: true, DetailsContain: "Redis cluster has 2/3 followers ready"},
+		{Name: "no preset", Object: checkuptest.NewRollout("web", "Healthy", checkuptest.Timestamp.Time, "")},
+	})
+}
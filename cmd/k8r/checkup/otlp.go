@@ -0,0 +1,228 @@
+// Description: This file contains code for exporting problems over OTLP,
+// as a log record per problem and gauges of problem counts, for any
+// OpenTelemetry compatible backend
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// otlpScope is the instrumentation scope records are exported with
+const otlpScope = "github.com/Ashvin-Ranjan/k8r"
+
+// otlpSeverityNumbers are the OpenTelemetry log severity numbers problems
+// are exported with, by their severity
+var otlpSeverityNumbers = map[Severity]int{
+	SeverityInfo:     9,
+	SeverityWarning:  13,
+	SeverityError:    17,
+	SeverityCritical: 21,
+}
+
+// otlpValue is an attribute value in the OTLP JSON encoding
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpAttribute is an attribute in the OTLP JSON encoding
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpLogRecord is a log record in the OTLP JSON encoding, 64-bit
+// integers are encoded as strings
+type otlpLogRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber"`
+	SeverityText   string          `json:"severityText"`
+	Body           otlpValue       `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes"`
+}
+
+// otlpDataPoint is an integer gauge value in the OTLP JSON encoding
+type otlpDataPoint struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsInt        string          `json:"asInt"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+// otlpMetric is a gauge in the OTLP JSON encoding
+type otlpMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Gauge       struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+}
+
+// otlpAttributes returns attributes from key value pairs, leaving out the
+// ones without a value
+func otlpAttributes(kv ...string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			attrs = append(attrs, otlpAttribute{Key: kv[i], Value: otlpValue{StringValue: kv[i+1]}})
+		}
+	}
+	return attrs
+}
+
+// otlpProblemAttributes returns the attributes the count of a resource's
+// problem is grouped by
+func otlpProblemAttributes(r *Resource) []otlpAttribute {
+	namespace := ""
+	if i := strings.Index(r.Name, "/"); i >= 0 {
+		namespace = r.Name[:i]
+	}
+	return otlpAttributes(
+		"k8r.problem.id", r.ProblemID,
+		"k8r.problem.severity", r.GetSeverity().String(),
+		"k8s.namespace.name", namespace,
+		"k8r.owner", r.Owner,
+	)
+}
+
+// otlpResource returns the OTLP resource everything is exported with
+func otlpResource(cluster string) map[string]interface{} {
+	return map[string]interface{}{
+		"attributes": otlpAttributes("service.name", "k8r", "k8r.cluster", cluster),
+	}
+}
+
+// otlpLogs returns a log record for each problem in a report
+func otlpLogs(report *Report, cluster string, now time.Time) map[string]interface{} {
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	records := make([]otlpLogRecord, 0, len(report.Resources))
+	for i := range report.Resources {
+		r := &report.Resources[i]
+		body := r.ProblemDetails
+		if p := report.GetProblemByID(r.ProblemID); p != nil && body == "" {
+			body = p.ShortDescription
+		}
+		records = append(records, otlpLogRecord{
+			TimeUnixNano:   ts,
+			SeverityNumber: otlpSeverityNumbers[r.GetSeverity()],
+			SeverityText:   strings.ToUpper(r.GetSeverity().String()),
+			Body:           otlpValue{StringValue: body},
+			Attributes: append(otlpProblemAttributes(r), otlpAttributes(
+				"k8r.resource.name", r.Name,
+				"k8r.resource.type", r.Type,
+				"k8s.node.name", r.Node,
+			)...),
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource":  otlpResource(cluster),
+			"scopeLogs": []interface{}{map[string]interface{}{"scope": map[string]string{"name": otlpScope}, "logRecords": records}},
+		}},
+	}
+}
+
+// otlpMetrics returns a k8r.problems gauge of the problems in a report,
+// by problem, severity, namespace and owner, and k8r.problems.total
+func otlpMetrics(report *Report, cluster string, now time.Time) map[string]interface{} {
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+
+	counts := make(map[string]int)
+	attrsByKey := make(map[string][]otlpAttribute)
+	for i := range report.Resources {
+		attrs := otlpProblemAttributes(&report.Resources[i])
+		b, _ := json.Marshal(attrs)
+		counts[string(b)]++
+		attrsByKey[string(b)] = attrs
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	problems := otlpMetric{Name: "k8r.problems", Description: "Problems found by k8r checkup", Unit: "{problem}"}
+	for _, key := range keys {
+		problems.Gauge.DataPoints = append(problems.Gauge.DataPoints, otlpDataPoint{
+			TimeUnixNano: ts, AsInt: strconv.Itoa(counts[key]), Attributes: attrsByKey[key],
+		})
+	}
+	total := otlpMetric{Name: "k8r.problems.total", Description: "Problems found by k8r checkup in total", Unit: "{problem}"}
+	total.Gauge.DataPoints = []otlpDataPoint{{TimeUnixNano: ts, AsInt: strconv.Itoa(len(report.Resources))}}
+
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": otlpResource(cluster),
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": otlpScope}, "metrics": []otlpMetric{total, problems},
+			}},
+		}},
+	}
+}
+
+// postOTLP sends a request body to an OTLP/HTTP signal path, e.g. /v1/logs
+func (o *Options) postOTLP(ctx context.Context, path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal otlp request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(o.cfg.OTLPEndpoint, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to create otlp request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range o.cfg.OTLPHeaders {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to export over otlp")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to export over otlp: got status %s", resp.Status)
+	}
+	return nil
+}
+
+// exportToOTLP exports each problem in a report as a log record and the
+// problem counts as metrics to the configured OTLP/HTTP endpoint
+func (o *Options) exportToOTLP(ctx context.Context, report *Report, cluster string) error {
+	if o.cfg.OTLPEndpoint == "" {
+		return nil
+	}
+
+	now := time.Now()
+	if err := o.postOTLP(ctx, "/v1/logs", otlpLogs(report, cluster, now)); err != nil {
+		return err
+	}
+	return o.postOTLP(ctx, "/v1/metrics", otlpMetrics(report, cluster, now))
+}
+
+// ParseOTLPHeaders parses the values of the otlp-header flag, in the
+// format key=value
+func ParseOTLPHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		i := strings.Index(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid --otlp-header %q, expected key=value", v)
+		}
+		headers[strings.TrimSpace(v[:i])] = strings.TrimSpace(v[i+1:])
+	}
+	return headers, nil
+}
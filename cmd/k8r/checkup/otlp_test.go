@@ -0,0 +1,69 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestOTLP(t *testing.T) {
+	bodies := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body bytes.Buffer
+		body.ReadFrom(r.Body)
+		bodies[r.URL.Path] = body.String()
+	}))
+	defer srv.Close()
+
+	headers, err := checkup.ParseOTLPHeaders([]string{"Authorization=Bearer token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkup.ParseOTLPHeaders([]string{"no-value"}); err == nil {
+		t.Error("ParseOTLPHeaders() expected an error for a header without a value")
+	}
+
+	objs := []runtime.Object{
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+	cfg := checkuptest.NewConfig(nil)
+	cfg.OTLPEndpoint = srv.URL
+	cfg.OTLPHeaders = headers
+
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &bytes.Buffer{})
+	if err := o.RunWithClient(context.Background(), newClientset(objs, nil)); err != checkup.ErrProblemsFound { //nolint:errorlint // Why: Checking the exact error
+		t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+	}
+
+	for path, want := range map[string][]string{
+		"/v1/logs": {
+			`"severityText":"ERROR"`,
+			`{"key":"k8r.problem.id","value":{"stringValue":"PodNotReady"}}`,
+			`{"key":"k8r.resource.name","value":{"stringValue":"default/api"}}`,
+		},
+		"/v1/metrics": {
+			`"name":"k8r.problems.total"`,
+			`{"key":"k8s.namespace.name","value":{"stringValue":"default"}}`,
+			`"asInt":"1"`,
+		},
+	} {
+		for _, w := range want {
+			if !strings.Contains(bodies[path], w) {
+				t.Errorf("exported %s = %s, expected it to contain %s", path, bodies[path], w)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Description: This file contains code for writing the report in a
+// machine-readable format, used with the output flag
+
+package checkup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+)
+
+// Formats the report can be written in
+const (
+	// OutputText is the report meant for people, the default
+	OutputText = "text"
+
+	// OutputJSON is the report as JSON, see pkg/report
+	OutputJSON = "json"
+
+	// OutputYAML is the report as YAML, see pkg/report
+	OutputYAML = "yaml"
+)
+
+// outputs are the values the output flag accepts
+var outputs = []string{OutputText, OutputJSON, OutputYAML}
+
+// ParseOutput validates the value of the output flag
+func ParseOutput(s string) (string, error) {
+	if s == "" {
+		return OutputText, nil
+	}
+	for _, o := range outputs {
+		if s == o {
+			return o, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --output %q, expected one of %s", s, strings.Join(outputs, ", "))
+}
+
+// useOutput sends progress to stderr when the report is machine-readable,
+// so that stdout only has the report
+func (o *Options) useOutput() {
+	if o.cfg.Output == OutputJSON || o.cfg.Output == OutputYAML {
+		o.out = os.Stderr
+	}
+}
+
+// finding converts a resource with a problem to its place in the
+// machine-readable report
+func finding(r *Resource) report.Finding {
+	f := report.Finding{
+		ProblemID:     r.ProblemID,
+		Resource:      r.Name,
+		Type:          r.Type,
+		Severity:      r.GetSeverity().String(),
+		Details:       r.ProblemDetails,
+		Owner:         r.Owner,
+		Source:        r.Source,
+		Labels:        r.Labels,
+		SuppressedBy:  r.SuppressedBy,
+		CreatedAt:     r.CreatedAt,
+		LastRestartAt: r.LastRestartAt,
+		Node:          r.Node,
+		Images:        r.Images,
+	}
+	for _, a := range r.Alerts {
+		f.Alerts = append(f.Alerts, report.Alert{Name: a.Name, State: a.State})
+	}
+	for i := range r.Related {
+		f.Related = append(f.Related, finding(&r.Related[i]))
+	}
+	return f
+}
+
+// machineReport converts a report to the machine-readable report
+func (o *Options) machineReport(r *Report, suppressed int) *report.Report {
+	out := &report.Report{
+		SchemaVersion: report.SchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Cluster:       o.cluster,
+		Interrupted:   o.interrupted,
+		Suppressed:    suppressed,
+		Problems:      make([]report.Problem, 0, len(r.Problems)),
+		Findings:      make([]report.Finding, 0, len(r.Resources)),
+	}
+	for i := range r.Problems {
+		p := &r.Problems[i]
+		out.Problems = append(out.Problems, report.Problem{ID: p.ID, Description: p.ShortDescription, HelpURL: p.HelpURL})
+	}
+	for i := range r.Resources {
+		out.Findings = append(out.Findings, finding(&r.Resources[i]))
+	}
+	for _, f := range o.incomplete() {
+		out.Incomplete = append(out.Incomplete, report.Incomplete{Resource: f.Resource, Namespace: f.Namespace, Reason: f.Reason})
+	}
+	return out
+}
+
+// writeMachineReport writes the report in the format of the output flag
+func (o *Options) writeMachineReport(r *Report, suppressed int) error {
+	mr := o.machineReport(r, suppressed)
+	if o.cfg.Output == OutputYAML {
+		return mr.WriteYAML(o.reportOut)
+	}
+	return mr.WriteJSON(o.reportOut)
+}
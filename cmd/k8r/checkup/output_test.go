@@ -0,0 +1,48 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestOutput(t *testing.T) {
+	objs := []runtime.Object{
+		checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+
+	for _, output := range []string{checkup.OutputJSON, checkup.OutputYAML} {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.Output = output
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+
+		err := o.RunWithClient(context.Background(), newClientset(objs, nil))
+		if err != checkup.ErrProblemsFound { //nolint:errorlint // Why: Checking the exact error
+			t.Fatalf("%s: RunWithClient() error = %v, expected %v", output, err, checkup.ErrProblemsFound)
+		}
+
+		// The report follows the progress, which goes to stderr outside of
+		// tests
+		progress := []byte("Checking for problems ... done\n")
+		start := bytes.Index(out.Bytes(), progress) + len(progress)
+		r, err := report.Read(out.Bytes()[start:])
+		if err != nil {
+			t.Fatalf("%s: Read() error = %v, output = %q", output, err, out.String())
+		}
+		if r.SchemaVersion != report.SchemaVersion || len(r.Findings) != 1 || r.Findings[0].ProblemID != "PodNotReady" {
+			t.Errorf("%s: report = %+v, expected PodNotReady for default/api", output, r)
+		}
+		if len(r.Problems) != 1 || r.Problems[0].Description == "" {
+			t.Errorf("%s: problems = %+v, expected PodNotReady with its description", output, r.Problems)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Description: This file contains code for skipping resources by what owns
+// them, e.g. Jobs created by CronJobs or resources managed by Helm
+
+package checkup
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managedByLabel is the label tools that manage resources, e.g. Helm, set
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// OwnerFilter matches resources by their root owner, the last owner in
+// their chain of owner references, or by the tool that manages them
+type OwnerFilter struct {
+	// Kind is the kind of the root owner, e.g. CronJob
+	Kind string
+
+	// Name is the name of the root owner, only set when matching a
+	// specific controller
+	Name string
+
+	// ManagedBy is the value of the app.kubernetes.io/managed-by label,
+	// e.g. Helm
+	ManagedBy string
+}
+
+// ParseOwnerFilter parses a filter in the format kind=Kind,
+// controller=Kind/name or managed-by=tool
+func ParseOwnerFilter(s string) (OwnerFilter, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return OwnerFilter{}, fmt.Errorf("invalid owner filter %q, expected kind=Kind, controller=Kind/name or managed-by=tool", s)
+	}
+
+	switch parts[0] {
+	case "kind":
+		return OwnerFilter{Kind: parts[1]}, nil
+	case "controller":
+		controller := strings.SplitN(parts[1], "/", 2)
+		if len(controller) != 2 || controller[0] == "" || controller[1] == "" {
+			return OwnerFilter{}, fmt.Errorf("invalid owner filter %q, expected controller=Kind/name", s)
+		}
+		return OwnerFilter{Kind: controller[0], Name: controller[1]}, nil
+	case "managed-by":
+		return OwnerFilter{ManagedBy: parts[1]}, nil
+	}
+	return OwnerFilter{}, fmt.Errorf("invalid owner filter %q, expected kind=Kind, controller=Kind/name or managed-by=tool", s)
+}
+
+// Matches returns true if the filter matches a resource with the given
+// root owner and labels
+func (f *OwnerFilter) Matches(kind, name string, labels map[string]string) bool {
+	if f.ManagedBy != "" {
+		return strings.EqualFold(labels[managedByLabel], f.ManagedBy)
+	}
+	if !strings.EqualFold(kind, f.Kind) {
+		return false
+	}
+	return f.Name == "" || f.Name == name
+}
+
+// ownerRef returns the controller of an object, falling back to its first
+// owner when none of them is marked as the controller
+func ownerRef(refs []metav1.OwnerReference) (*metav1.OwnerReference, bool) {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i], true
+		}
+	}
+	if len(refs) != 0 {
+		return &refs[0], true
+	}
+	return nil, false
+}
+
+// rootOwner returns the kind and name of the last owner in the chain of
+// owner references of an object, the object itself when it has no owner.
+// Owners that aren't in the cluster end the chain, except ReplicaSets
+// created by Deployments which are resolved from the pod-template-hash
+// label.
+func rootOwner(obj metav1.Object, kind string, c *Cluster) (string, string) {
+	name, namespace, labels := obj.GetName(), obj.GetNamespace(), obj.GetLabels()
+	refs := obj.GetOwnerReferences()
+
+	// Owner chains are short, this guards against cycles
+	for depth := 0; depth < 5; depth++ {
+		ref, ok := ownerRef(refs)
+		if !ok {
+			break
+		}
+		kind, name, refs = ref.Kind, ref.Name, nil
+
+		switch ref.Kind {
+		case "ReplicaSet":
+			if hash := labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" && strings.HasSuffix(name, "-"+hash) {
+				kind, name = "Deployment", strings.TrimSuffix(name, "-"+hash)
+			}
+		case "Job":
+			if c == nil {
+				continue
+			}
+			for i := range c.Jobs {
+				if c.Jobs[i].Namespace == namespace && c.Jobs[i].Name == name {
+					refs = c.Jobs[i].OwnerReferences
+				}
+			}
+		}
+	}
+	return kind, name
+}
+
+// ignoredByOwner returns true if the object matches any of the filters
+// from the ignore-owned-by flag
+func (c *Config) ignoredByOwner(obj metav1.Object, kind string) bool {
+	if len(c.IgnoreOwnedBy) == 0 {
+		return false
+	}
+
+	rootKind, rootName := rootOwner(obj, kind, c.Cluster)
+	for i := range c.IgnoreOwnedBy {
+		if c.IgnoreOwnedBy[i].Matches(rootKind, rootName, obj.GetLabels()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseOwnerFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    checkup.OwnerFilter
+		wantErr bool
+	}{
+		{in: "kind=CronJob", want: checkup.OwnerFilter{Kind: "CronJob"}},
+		{in: "controller=CronJob/nightly-report", want: checkup.OwnerFilter{Kind: "CronJob", Name: "nightly-report"}},
+		{in: "managed-by=Helm", want: checkup.OwnerFilter{ManagedBy: "Helm"}},
+		{in: "controller=CronJob", wantErr: true},
+		{in: "owner=CronJob", wantErr: true},
+		{in: "CronJob", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := checkup.ParseOwnerFilter(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOwnerFilter(%q) error = %v, expected error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseOwnerFilter(%q) = %+v, expected %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreOwnedBy(t *testing.T) {
+	crashing := checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)
+	fromCronJob := checkuptest.NewJob("report-28000000", 6)
+	fromCronJob.OwnerReferences = checkuptest.NewPod("", checkuptest.OwnedBy("CronJob", "report")).OwnerReferences
+	helm := func(p *corev1.Pod) { p.Labels = map[string]string{"app.kubernetes.io/managed-by": "Helm"} }
+	fromDeployment := func(p *corev1.Pod) { p.Labels = map[string]string{"pod-template-hash": "7d9f"} }
+
+	objs := []runtime.Object{
+		fromCronJob,
+		checkuptest.NewPod("report-28000000-abc", crashing, checkuptest.OwnedBy("Job", fromCronJob.Name)),
+		checkuptest.NewPod("chart", crashing, helm),
+		checkuptest.NewPod("web-7d9f-abc", crashing, fromDeployment, checkuptest.OwnedBy("ReplicaSet", "web-7d9f")),
+		checkuptest.NewPod("api-5c4b-abc", crashing, checkuptest.OwnedBy("ReplicaSet", "api-5c4b")),
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	for _, f := range []string{"kind=CronJob", "managed-by=helm", "controller=Deployment/web"} {
+		filter, err := checkup.ParseOwnerFilter(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.IgnoreOwnedBy = append(cfg.IgnoreOwnedBy, filter)
+	}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	resources, err := o.Scan(context.Background(), newClientset(objs, nil))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for i := range resources {
+		got[resources[i].Name] = true
+	}
+	for name, want := range map[string]bool{
+		"default/report-28000000-abc": false,
+		"default/chart":               false,
+		"default/web-7d9f-abc":        false,
+		"default/api-5c4b-abc":        true,
+	} {
+		if got[name] != want {
+			t.Errorf("problems reported for %s = %v, expected %v", name, got[name], want)
+		}
+	}
+}
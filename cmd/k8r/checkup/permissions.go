@@ -0,0 +1,286 @@
+// Description: This file contains code for checking that the current user
+// has the permissions checkup needs
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is a permission checkup needs to gather the resources
+// problems are checked against
+type Permission struct {
+	// Verb is the verb, e.g. list or get
+	Verb string
+
+	// Group is the API group of the resource, empty for the core group
+	Group string
+
+	// Resource is the resource, e.g. pods
+	Resource string
+
+	// Subresource is the subresource, e.g. proxy
+	Subresource string
+
+	// NonResourceURL is the path of a request that isn't for a resource,
+	// e.g. /metrics, when set Group and Resource are ignored
+	NonResourceURL string
+
+	// Reason is what the permission is used for
+	Reason string
+
+	// Problems are the problems that are skipped without the permission,
+	// as they would be reported wrongly or not at all
+	Problems []Problem
+}
+
+// String returns the permission in the format kubectl auth can-i uses,
+// e.g. list pods or get /metrics
+func (p *Permission) String() string {
+	return fmt.Sprintf("%s %s", p.Verb, p.resource())
+}
+
+// resource returns what the permission is for without the verb, e.g.
+// horizontalpodautoscalers.autoscaling
+func (p *Permission) resource() string {
+	if p.NonResourceURL != "" {
+		return p.NonResourceURL
+	}
+
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	return resource
+}
+
+// RequiredPermissions are the permissions ListCluster uses, resources are
+// listed across all namespaces
+var RequiredPermissions = []Permission{
+	{
+		Verb: "list", Resource: "pods", Reason: "pod checks",
+		Problems: concatProblems(enabledPodProblems, []Problem{
+			ProblemServiceSelectorConflict, ProblemServicePartialWorkload, ProblemServiceNodePortConflict, ProblemSecretUnused,
+			ProblemDistroAddonUnhealthy, ProblemLocalNodeMemoryLow,
+		}),
+	},
+	{
+		Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers", Reason: "HPA checks",
+		Problems: concatProblems(enabledHPAProblems, enabledHPAMetricProblems),
+	},
+	{
+		Verb: "list", Resource: "services", Reason: "service checks",
+		Problems: concatProblems(enabledServiceProblems, []Problem{ProblemPodHostAliasConflict, ProblemStatefulSetServiceInvalid}),
+	},
+	{
+		Verb: "list", Group: "apps", Resource: "statefulsets", Reason: "StatefulSet checks",
+		Problems: enabledStatefulSetProblems,
+	},
+	{
+		Verb: "list", Group: "batch", Resource: "cronjobs", Reason: "CronJob checks",
+		Problems: enabledCronJobProblems,
+	},
+	{
+		Verb: "list", Group: "batch", Resource: "jobs", Reason: "Job checks and skipping Job pods that are retrying",
+		Problems: enabledJobProblems,
+	},
+	{
+		Verb: "list", Group: "apps", Resource: "deployments", Reason: "Deployment checks",
+		Problems: enabledDeploymentProblems,
+	},
+	{
+		Verb: "list", Group: "apps", Resource: "daemonsets", Reason: "DaemonSet checks",
+		Problems: enabledDaemonSetProblems,
+	},
+	{
+		Verb: "list", Resource: "namespaces", Reason: "namespace checks and owners",
+		Problems: []Problem{ProblemPodSecurityViolation},
+	},
+	{
+		Verb: "list", Resource: "secrets", Reason: "secret checks",
+		Problems: enabledSecretProblems,
+	},
+	{
+		Verb: "list", Resource: "serviceaccounts", Reason: "finding secrets used by service accounts",
+		Problems: []Problem{ProblemSecretUnused},
+	},
+	{
+		Verb: "list", Resource: "nodes", Reason: "node checks",
+		Problems: concatProblems(enabledNodeProblems, []Problem{
+			ProblemPodSpotOnly, ProblemPodExtendedResourceUnavailable, ProblemPodMissingOSSelector, ProblemPodTopologyUnsatisfiable,
+			ProblemTaintBlocksPods,
+		}),
+	},
+	{
+		Verb: "list", Resource: "persistentvolumeclaims", Reason: "PVC checks",
+		Problems: enabledPVCProblems,
+	},
+	{
+		Verb: "list", Group: "storage.k8s.io", Resource: "storageclasses", Reason: "StorageClass and PVC expansion checks",
+		Problems: concatProblems(enabledStorageClassProblems, []Problem{ProblemPVCNoDefaultStorageClass}),
+	},
+	{
+		Verb: "list", Group: "storage.k8s.io", Resource: "csidrivers", Reason: "CSI driver checks and finding the provisioners of StorageClasses",
+		Problems: concatProblems(enabledCSIDriverProblems, []Problem{ProblemStorageClassProvisionerMissing}),
+	},
+	{
+		Verb: "list", Group: "storage.k8s.io", Resource: "csinodes", Reason: "CSI driver checks",
+		Problems: enabledCSIDriverProblems,
+	},
+	{
+		Verb: "list", Group: "storage.k8s.io", Resource: "volumeattachments", Reason: "VolumeAttachment checks",
+		Problems: enabledVolumeAttachmentProblems,
+	},
+	{
+		Verb: "list", Group: "coordination.k8s.io", Resource: "leases", Reason: "clock skew checks",
+		Problems: []Problem{ProblemNodeClockSkew},
+	},
+	{
+		Verb: "list", Resource: "events", Reason: "spot reclamation details, image pull times and PVC resize failures",
+		Problems: []Problem{ProblemImagePullSlow},
+	},
+	{
+		Verb: "list", Group: "policy", Resource: "poddisruptionbudgets", Reason: "spot checks",
+		Problems: []Problem{ProblemPodSpotOnly},
+	},
+	{
+		Verb: "list", Group: "rbac.authorization.k8s.io", Resource: "roles", Reason: "RBAC checks",
+		Problems: enabledRBACProblems,
+	},
+	{
+		Verb: "list", Group: "rbac.authorization.k8s.io", Resource: "clusterroles", Reason: "RBAC checks",
+		Problems: enabledRBACProblems,
+	},
+	{
+		Verb: "list", Group: "apiregistration.k8s.io", Resource: "apiservices", Reason: "aggregated API checks",
+		Problems: concatProblems(enabledAPIServiceProblems, enabledHPAMetricProblems),
+	},
+	{
+		Verb: "list", Group: flowControlGroup, Resource: "flowschemas", Reason: "API priority and fairness checks",
+		Problems: concatProblems(enabledFlowSchemaProblems, []Problem{ProblemAPIRequestsQueued}),
+	},
+	{
+		Verb: "list", Group: flowControlGroup, Resource: "prioritylevelconfigurations", Reason: "API priority and fairness checks",
+		Problems: concatProblems(enabledPriorityLevelProblems, enabledFlowSchemaProblems),
+	},
+	{Verb: "list", Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Reason: "certificate checks without --probe-kubelet-certs"},
+	{Verb: "get", Resource: "pods", Subresource: "log", Reason: "logs of stuck init containers"},
+	{Verb: "get", Resource: "nodes", Subresource: "proxy", Reason: "kubelet filesystem and volume stats"},
+	{
+		Verb: "get", Resource: "pods", Subresource: "proxy", Reason: "etcd metrics",
+		Problems: []Problem{ProblemEtcdSlowFsync},
+	},
+	{
+		Verb: "get", NonResourceURL: "/readyz/etcd", Reason: "etcd health",
+		Problems: []Problem{ProblemEtcdUnhealthy},
+	},
+	{
+		Verb: "get", NonResourceURL: "/metrics", Reason: "etcd database size on managed control planes and queuing of k8r's requests",
+		Problems: []Problem{ProblemAPIRequestsQueued},
+	},
+}
+
+// PermissionResult is whether the current user has a permission
+type PermissionResult struct {
+	Permission
+
+	// Allowed is true if the user has the permission
+	Allowed bool
+}
+
+// CheckPermissions checks which of the given permissions the current user
+// has with a SelfSubjectAccessReview for each
+func CheckPermissions(ctx context.Context, k kubernetes.Interface, perms []Permission) ([]PermissionResult, error) {
+	results := make([]PermissionResult, 0, len(perms))
+	for i := range perms {
+		p := &perms[i]
+
+		review := &authorizationv1.SelfSubjectAccessReview{}
+		if p.NonResourceURL != "" {
+			review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+				Path: p.NonResourceURL,
+				Verb: p.Verb,
+			}
+		} else {
+			review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+				Namespace:   metav1.NamespaceAll,
+				Verb:        p.Verb,
+				Group:       p.Group,
+				Resource:    p.Resource,
+				Subresource: p.Subresource,
+			}
+		}
+
+		resp, err := k.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check if allowed to %s", p.String())
+		}
+		results = append(results, PermissionResult{Permission: *p, Allowed: resp.Status.Allowed})
+	}
+
+	return results, nil
+}
+
+// allowed returns false if the RBAC preflight found the permission missing
+func (c *Config) allowed(p Permission) bool {
+	return !c.DeniedPermissions[p.String()]
+}
+
+// preflight checks the permissions checkup needs before scanning, problems
+// that need missing permissions are disabled and resources that can't be
+// listed are skipped instead of failing part way through the scan
+func (o *Options) preflight(ctx context.Context, k kubernetes.Interface) {
+	results, err := CheckPermissions(ctx, k, RequiredPermissions)
+	if err != nil {
+		o.log.WithError(err).Warn("Failed to check permissions, continuing without checking them")
+		return
+	}
+
+	missing := make([]PermissionResult, 0)
+	for i := range results {
+		if !results[i].Allowed {
+			missing = append(missing, results[i])
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	if o.cfg.DisabledProblems == nil {
+		o.cfg.DisabledProblems = make(map[string]bool)
+	}
+	o.cfg.DeniedPermissions = make(map[string]bool)
+
+	fmt.Fprintln(o.out, color.New(color.Bold, color.FgYellow).Sprint("⚠️  Missing permissions, these checks will be skipped:"))
+	tw := tabwriter.NewWriter(o.out, 1, 0, 1, ' ', 0)
+	for i := range missing {
+		p := &missing[i]
+		o.cfg.DeniedPermissions[p.String()] = true
+
+		ids := make([]string, 0, len(p.Problems))
+		for _, problem := range p.Problems {
+			o.cfg.DisabledProblems[problem.ID] = true
+			ids = append(ids, problem.ID)
+		}
+
+		skipped := p.Reason
+		if len(ids) != 0 {
+			skipped += fmt.Sprintf(" (%s)", strings.Join(ids, ", "))
+		}
+		fmt.Fprintf(tw, "    - %s:\t%s\n", p.String(), skipped)
+	}
+	tw.Flush()
+	fmt.Fprintln(o.out, "")
+}
@@ -0,0 +1,92 @@
+// Description: This file contains code for problems related to Pod
+// Security Admission
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	psaapi "k8s.io/pod-security-admission/api"
+	"k8s.io/pod-security-admission/policy"
+)
+
+// podSecurityEvaluator evaluates pods against the Pod Security Standards,
+// this is the same evaluator the Pod Security Admission controller uses
+var podSecurityEvaluator, podSecurityEvaluatorErr = policy.NewEvaluator(policy.DefaultChecks())
+
+// podSecurityLevel returns the Pod Security Standards level, and version,
+// that pods in a namespace should be evaluated against. The configured
+// level takes precedence, otherwise the enforce label of the namespace is
+// used, falling back to the audit and warn labels which are commonly set
+// ahead of turning on enforcement.
+func podSecurityLevel(ns *corev1.Namespace, cfg *Config) (psaapi.LevelVersion, bool) {
+	lv := psaapi.LevelVersion{Level: psaapi.LevelPrivileged, Version: psaapi.LatestVersion()}
+
+	if cfg.PodSecurityLevel != "" {
+		level, err := psaapi.ParseLevel(cfg.PodSecurityLevel)
+		if err != nil {
+			return lv, false
+		}
+		lv.Level = level
+		return lv, true
+	}
+
+	if ns == nil {
+		return lv, false
+	}
+
+	labels := [][2]string{
+		{psaapi.EnforceLevelLabel, psaapi.EnforceVersionLabel},
+		{psaapi.AuditLevelLabel, psaapi.AuditVersionLabel},
+		{psaapi.WarnLevelLabel, psaapi.WarnVersionLabel},
+	}
+	for _, l := range labels {
+		level, err := psaapi.ParseLevel(ns.Labels[l[0]])
+		if err != nil {
+			continue
+		}
+		lv.Level = level
+
+		if v, ok := ns.Labels[l[1]]; ok {
+			if version, err := psaapi.ParseVersion(v); err == nil {
+				lv.Version = version
+			}
+		}
+		return lv, true
+	}
+
+	return lv, false
+}
+
+// ProblemPodSecurityViolation is a problem with a pod that would be
+// rejected if the Pod Security Standards level labeled on its namespace,
+// or the configured level, were enforced
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodSecurityViolation
+var ProblemPodSecurityViolation = Problem{
+	ID:               "PodSecurityViolation",
+	ShortDescription: "A pod would be rejected if its namespace's Pod Security Standards level were enforced",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodSecurityViolation",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil || podSecurityEvaluatorErr != nil {
+			return "", false, false
+		}
+
+		lv, ok := podSecurityLevel(cfg.Cluster.GetNamespace(pod.Namespace), cfg)
+		if !ok || lv.Level == psaapi.LevelPrivileged {
+			return "", false, false
+		}
+
+		result := policy.AggregateCheckResults(podSecurityEvaluator.EvaluatePod(lv, &pod.ObjectMeta, &pod.Spec))
+		if result.Allowed {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("Violates %s:%s: %s (%s)",
+			lv.Level, lv.Version.String(), result.ForbiddenReason(), result.ForbiddenDetail(),
+		), true, true
+	},
+}
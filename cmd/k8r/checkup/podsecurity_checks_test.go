@@ -0,0 +1,55 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodSecurityViolation(t *testing.T) {
+	privileged := checkuptest.NewPod("web", checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+		yes := true
+		spec.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &yes}
+	}))
+	plain := checkuptest.NewPod("web")
+
+	withNamespace := func(pod *corev1.Pod, labels map[string]string) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(pod, checkuptest.NewNamespace(pod.Namespace, labels)))
+	}
+	baseline := map[string]string{"pod-security.kubernetes.io/enforce": "baseline"}
+	restricted := map[string]string{"pod-security.kubernetes.io/warn": "restricted"}
+
+	flagged := checkuptest.NewConfig(checkuptest.NewCluster(plain))
+	flagged.PodSecurityLevel = "restricted"
+
+	checkuptest.RunCases(t, checkup.ProblemPodSecurityViolation, []checkuptest.Case{
+		{Name: "unlabeled namespace", Object: privileged},
+		{Name: "baseline allows plain pod", Object: plain, Config: withNamespace(plain, baseline)},
+		{
+			Name:           "baseline rejects privileged",
+			Object:         privileged,
+			Config:         withNamespace(privileged, baseline),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Violates baseline",
+		},
+		{
+			Name:           "restricted warn label",
+			Object:         plain,
+			Config:         withNamespace(plain, restricted),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Violates restricted",
+		},
+		{
+			Name:           "level flag",
+			Object:         plain,
+			Config:         flagged,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Violates restricted",
+		},
+	})
+}
@@ -0,0 +1,191 @@
+// Description: This file contains code for problems related to host
+// ports and node ports
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// hostPort is a port that a pod binds on its node
+type hostPort struct {
+	Container string
+	IP        string
+	Port      int32
+	Protocol  corev1.Protocol
+}
+
+// conflicts returns true if both host ports can't be bound on the same node
+func (hp *hostPort) conflicts(other *hostPort) bool {
+	if hp.Port != other.Port || hp.Protocol != other.Protocol {
+		return false
+	}
+
+	// An empty IP or 0.0.0.0 binds every address on the node
+	wildcard := func(ip string) bool { return ip == "" || ip == "0.0.0.0" }
+	return wildcard(hp.IP) || wildcard(other.IP) || hp.IP == other.IP
+}
+
+// podHostPorts returns all of the host ports that a pod requests
+func podHostPorts(pod *corev1.Pod) []hostPort {
+	ports := make([]hostPort, 0)
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		for j := range c.Ports {
+			p := &c.Ports[j]
+			if p.HostPort == 0 {
+				continue
+			}
+
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			ports = append(ports, hostPort{Container: c.Name, IP: p.HostIP, Port: p.HostPort, Protocol: protocol})
+		}
+	}
+	return ports
+}
+
+// isPodActive returns true if the pod is, or will be, holding resources
+// on its node
+func isPodActive(pod *corev1.Pod) bool {
+	return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
+
+// ProblemPodHostPortConflict is a problem with a pod that requests a host
+// port that another pod on the same node is already using
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodHostPortConflict
+var ProblemPodHostPortConflict = Problem{
+	ID:               "PodHostPortConflict",
+	ShortDescription: "A pod requests a host port that another pod on the same node uses",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodHostPortConflict",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil || pod.Spec.NodeName == "" || !isPodActive(pod) {
+			return "", false, false
+		}
+
+		ports := podHostPorts(pod)
+		if len(ports) == 0 {
+			return "", false, false
+		}
+
+		for i := range cfg.Cluster.Pods {
+			other := &cfg.Cluster.Pods[i]
+			if other.UID == pod.UID || other.Spec.NodeName != pod.Spec.NodeName || !isPodActive(other) {
+				continue
+			}
+
+			otherPorts := podHostPorts(other)
+			for j := range ports {
+				for k := range otherPorts {
+					if ports[j].conflicts(&otherPorts[k]) {
+						return fmt.Sprintf("Container %s host port %d/%s is also used by %s/%s on node %s",
+							ports[j].Container, ports[j].Port, ports[j].Protocol, other.Namespace, other.Name, pod.Spec.NodeName,
+						), false, true
+					}
+				}
+			}
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemPodHostPortUnschedulable is a problem with a pod that is pending
+// because no node has the host ports it requests free
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodHostPortUnschedulable
+var ProblemPodHostPortUnschedulable = Problem{
+	ID:               "PodHostPortUnschedulable",
+	ShortDescription: "A pod can't be scheduled because the host ports it requests are in use",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodHostPortUnschedulable",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Status.Phase != corev1.PodPending {
+			return "", false, false
+		}
+
+		ports := podHostPorts(pod)
+		if len(ports) == 0 {
+			return "", false, false
+		}
+
+		for i := range pod.Status.Conditions {
+			c := &pod.Status.Conditions[i]
+			if c.Type != corev1.PodScheduled || c.Status != corev1.ConditionFalse {
+				continue
+			}
+
+			// This is the message the scheduler's NodePorts plugin uses
+			if strings.Contains(c.Message, "free ports") {
+				requested := make([]string, 0, len(ports))
+				for j := range ports {
+					requested = append(requested, fmt.Sprintf("%d/%s", ports[j].Port, ports[j].Protocol))
+				}
+				return fmt.Sprintf("Requests host port(s) %s: %s", strings.Join(requested, ", "), c.Message), false, true
+			}
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemServiceNodePortConflict is a problem with a Service that uses a
+// node port that is also used by another Service or by a pod's host port
+// https://github.com/Ashvin-Ranjan/k8r/wiki/ServiceNodePortConflict
+var ProblemServiceNodePortConflict = Problem{
+	ID:               "ServiceNodePortConflict",
+	ShortDescription: "A Service uses a node port that another Service or a pod's host port also uses",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/ServiceNodePortConflict",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		svc, ok := obj.(*corev1.Service)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		for i := range svc.Spec.Ports {
+			p := &svc.Spec.Ports[i]
+			if p.NodePort == 0 {
+				continue
+			}
+
+			for j := range cfg.Cluster.Services {
+				other := &cfg.Cluster.Services[j]
+				if other.Namespace == svc.Namespace && other.Name == svc.Name {
+					continue
+				}
+				for k := range other.Spec.Ports {
+					if other.Spec.Ports[k].NodePort == p.NodePort && other.Spec.Ports[k].Protocol == p.Protocol {
+						return fmt.Sprintf("Node port %d/%s is also used by Service %s/%s",
+							p.NodePort, p.Protocol, other.Namespace, other.Name,
+						), false, true
+					}
+				}
+			}
+
+			// kube-proxy and a host port binding the same port on a node
+			// will fight over traffic to it
+			for j := range cfg.Cluster.Pods {
+				pod := &cfg.Cluster.Pods[j]
+				if !isPodActive(pod) {
+					continue
+				}
+				for _, hp := range podHostPorts(pod) {
+					if hp.Port == p.NodePort && hp.Protocol == p.Protocol {
+						return fmt.Sprintf("Node port %d/%s is also used as a host port by %s/%s",
+							p.NodePort, p.Protocol, pod.Namespace, pod.Name,
+						), true, true
+					}
+				}
+			}
+		}
+
+		return "", false, false
+	},
+}
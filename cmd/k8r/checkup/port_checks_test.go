@@ -0,0 +1,70 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestPodHostPortConflict(t *testing.T) {
+	a := checkuptest.NewPod("a", checkuptest.OnNode("node-1"), checkuptest.WithHostPort(checkuptest.DefaultContainer, 8080))
+	b := checkuptest.NewPod("b", checkuptest.OnNode("node-1"), checkuptest.WithHostPort(checkuptest.DefaultContainer, 8080))
+	c := checkuptest.NewPod("c", checkuptest.OnNode("node-2"), checkuptest.WithHostPort(checkuptest.DefaultContainer, 8080))
+
+	checkuptest.RunCases(t, checkup.ProblemPodHostPortConflict, []checkuptest.Case{
+		{Name: "alone", Object: a},
+		{
+			Name:           "same node",
+			Object:         a,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(a, b)),
+			Occurring:      true,
+			DetailsContain: "also used by default/b on node node-1",
+		},
+		{Name: "different nodes", Object: a, Config: checkuptest.NewConfig(checkuptest.NewCluster(a, c))},
+	})
+}
+
+func TestPodHostPortUnschedulable(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodHostPortUnschedulable, []checkuptest.Case{
+		{Name: "scheduled", Object: checkuptest.NewPod("web", checkuptest.WithHostPort(checkuptest.DefaultContainer, 8080))},
+		{
+			Name: "no free ports",
+			Object: checkuptest.NewPod("web",
+				checkuptest.WithHostPort(checkuptest.DefaultContainer, 8080),
+				checkuptest.Unschedulable("0/3 nodes are available: 3 node(s) didn't have free ports for the requested pod ports."),
+			),
+			Occurring:      true,
+			DetailsContain: "8080/TCP",
+		},
+		{
+			Name:   "unschedulable for another reason",
+			Object: checkuptest.NewPod("web", checkuptest.WithHostPort(checkuptest.DefaultContainer, 8080), checkuptest.Unschedulable("0/3 nodes are available: Insufficient cpu.")),
+		},
+	})
+}
+
+func TestServiceNodePortConflict(t *testing.T) {
+	a := checkuptest.NewService("a", nil, checkuptest.WithNodePort(30080))
+	b := checkuptest.NewService("b", nil, checkuptest.WithNodePort(30080))
+	hostPort := checkuptest.NewPod("agent", checkuptest.WithHostPort(checkuptest.DefaultContainer, 30080))
+
+	checkuptest.RunCases(t, checkup.ProblemServiceNodePortConflict, []checkuptest.Case{
+		{Name: "alone", Object: a},
+		{
+			Name:           "another service",
+			Object:         a,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(a, b)),
+			Occurring:      true,
+			DetailsContain: "also used by Service default/b",
+		},
+		{
+			Name:           "host port",
+			Object:         a,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(a, hostPort)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "host port by default/agent",
+		},
+	})
+}
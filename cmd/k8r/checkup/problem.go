@@ -9,6 +9,7 @@ package checkup
 
 import (
 	"context"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -22,6 +23,12 @@ const (
 	SeverityError Severity = iota
 	// SeverityWarning is a warning
 	SeverityWarning
+	// EDIT: Add severities below warnings and above errors
+	// SeverityInfo is worth knowing about but not acting on
+	SeverityInfo
+	// SeverityCritical is an error that needs attention right away, e.g.
+	// something worth paging for
+	SeverityCritical
 )
 
 // Problem is a problem that was found in the devenv environment
@@ -38,6 +45,11 @@ type Problem struct {
 	// the problem. Defaults to the devenv wki/ID.
 	HelpURL string
 
+	// EDIT: Let problems be more or less severe than errors
+	// Severity is the severity the problem is reported with when it
+	// isn't a warning. Defaults to SeverityError.
+	Severity Severity
+
 	// Detector is a function that detects if this problem exists.
 	Detector func(context.Context, runtime.Object, *Config) (resourceSpecificReason string, warning, isOccurring bool)
 }
@@ -67,6 +79,50 @@ type Resource struct {
 	// causing a problem _now_. This is usually used for problems that
 	// previously occurred or aren't otherwise currently occurring.
 	Warning bool
+
+	// EDIT: Add severities beyond error and warning
+	// Severity is the severity of the problem, see GetSeverity. Info
+	// problems are warnings as well, they aren't causing a problem now.
+	Severity Severity
+
+	// Source is where the resource was read from when it did not
+	// come from a cluster, e.g. a manifest file and line.
+	Source string
+
+	// Labels are the labels of the resource, used to map the resource
+	// to other systems, e.g. Backstage entities.
+	Labels map[string]string
+
+	// SuppressedBy is the root cause problem that explains this one, if
+	// this problem is a symptom of another.
+	SuppressedBy string
+
+	// Related are other problems with the same resource that were
+	// consolidated into this one, e.g. HighRestarts for a pod that is
+	// in a crash loop.
+	Related []Resource
+
+	// EDIT: Add when the resource was created and last restarted, recency
+	// is the first thing responders ask about
+	// CreatedAt is when the resource was created, if known.
+	CreatedAt *time.Time `json:",omitempty"`
+
+	// LastRestartAt is when a container of the pod last restarted, if it
+	// has restarted.
+	LastRestartAt *time.Time `json:",omitempty"`
+
+	// EDIT: Add where pods run
+	// Node is the node the pod is scheduled on, if it is scheduled.
+	Node string `json:",omitempty"`
+
+	// Images are the images of the pod's containers.
+	Images []string `json:",omitempty"`
+
+	// EDIT: Add the alerts about the resource, so that problems can be
+	// told apart from what on-call already knows about
+	// Alerts are the Alertmanager alerts about the resource, if an
+	// Alertmanager is configured.
+	Alerts []Alert `json:",omitempty"`
 }
 
 // Report is a report of problems that were found in
@@ -114,19 +170,18 @@ func (r *Report) BySeverity() map[Severity]map[string][]*Resource {
 	rtrn := make(map[Severity]map[string][]*Resource)
 
 	// initialize the map
-	rtrn[SeverityError] = make(map[string][]*Resource)
-	rtrn[SeverityWarning] = make(map[string][]*Resource)
+	// EDIT: Include every severity
+	for _, severity := range severities {
+		rtrn[severity] = make(map[string][]*Resource)
+	}
 
 	for i := range r.Problems {
 		problem := &r.Problems[i]
 		for j := range r.Resources {
 			resource := &r.Resources[j]
 			if resource.ProblemID == problem.ID {
-				if !resource.Warning {
-					rtrn[SeverityError][problem.ID] = append(rtrn[SeverityError][problem.ID], resource)
-				} else {
-					rtrn[SeverityWarning][problem.ID] = append(rtrn[SeverityWarning][problem.ID], resource)
-				}
+				severity := resource.GetSeverity()
+				rtrn[severity][problem.ID] = append(rtrn[severity][problem.ID], resource)
 			}
 		}
 	}
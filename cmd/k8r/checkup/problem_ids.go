@@ -0,0 +1,131 @@
+// Description: This file contains code for the namespace problem IDs live
+// in, so that problems from other sources, e.g. plugins, can't be
+// confused with the ones built into k8r
+
+package checkup
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CoreSource is the source of the problems built into k8r. Their IDs are
+// used without it, e.g. PodCrashLoopBackOff is core/PodCrashLoopBackOff.
+const CoreSource = "core"
+
+// reservedSources are sources that only k8r itself can register
+// problems from
+var reservedSources = []string{CoreSource, "k8r", "k8s", "kubernetes"}
+
+// problem ID formats
+var (
+	// problemNameRegexp matches the name of a problem, e.g. PodNotReady
+	problemNameRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+	// problemSourceRegexp matches the source of a problem, e.g.
+	// plugin.acme
+	problemSourceRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*(\.[a-z][a-z0-9-]*)*$`)
+)
+
+// SplitProblemID returns the source and name of a problem ID, IDs without
+// a source are core problems
+func SplitProblemID(id string) (source, name string) {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return CoreSource, id
+}
+
+// QualifiedProblemID returns the ID of the problem from the source, e.g.
+// plugin.acme/FooCheck. Core problems keep their short IDs so reports
+// don't change.
+func QualifiedProblemID(source, name string) string {
+	if source == CoreSource {
+		return name
+	}
+	return source + "/" + name
+}
+
+// CanonicalProblemID returns the ID a problem is reported with, e.g.
+// PodNotReady for core/PodNotReady
+func CanonicalProblemID(id string) string {
+	return QualifiedProblemID(SplitProblemID(id))
+}
+
+// ProblemRegistry keeps the problems of every source and makes sure their
+// IDs don't collide
+type ProblemRegistry struct {
+	// problems are the problems by their lowercase ID, IDs that only
+	// differ in case are ambiguous
+	problems map[string]*Problem
+}
+
+// NewProblemRegistry returns an empty registry
+func NewProblemRegistry() *ProblemRegistry {
+	return &ProblemRegistry{problems: make(map[string]*Problem)}
+}
+
+// Register registers problems from a source, their IDs are qualified with
+// it. Reserved sources can't be registered from outside k8r.
+func (r *ProblemRegistry) Register(source string, problems ...Problem) error {
+	for _, reserved := range reservedSources {
+		if source == reserved {
+			return fmt.Errorf("problem source %q is reserved", source)
+		}
+	}
+	return r.register(source, problems)
+}
+
+// register registers problems from any source, including reserved ones
+func (r *ProblemRegistry) register(source string, problems []Problem) error {
+	if !problemSourceRegexp.MatchString(source) {
+		return fmt.Errorf("invalid problem source %q, expected e.g. plugin.acme", source)
+	}
+
+	for i := range problems {
+		p := problems[i]
+		idSource, name := SplitProblemID(p.ID)
+		if strings.Contains(p.ID, "/") && idSource != source {
+			return fmt.Errorf("problem %s can't be registered from source %s", p.ID, source)
+		}
+		if !problemNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid problem ID %q, expected e.g. PodNotReady", p.ID)
+		}
+
+		p.ID = QualifiedProblemID(source, name)
+		key := strings.ToLower(p.ID)
+		if existing, ok := r.problems[key]; ok {
+			// The same problem is checked against more than one kind
+			// of resource, e.g. MissingRequiredLabels
+			if existing.ID == p.ID && existing.ShortDescription == p.ShortDescription {
+				continue
+			}
+			return fmt.Errorf("problem ID %s collides with %s", p.ID, existing.ID)
+		}
+		r.problems[key] = &p
+	}
+	return nil
+}
+
+// Lookup returns the problem with the ID, qualified or not
+func (r *ProblemRegistry) Lookup(id string) (*Problem, bool) {
+	p, ok := r.problems[strings.ToLower(CanonicalProblemID(id))]
+	if !ok || p.ID != CanonicalProblemID(id) {
+		return nil, false
+	}
+	return p, true
+}
+
+// coreProblems are the problems built into k8r
+var coreProblems = mustRegisterCore(enabledProblems)
+
+// mustRegisterCore returns a registry of the core problems, colliding
+// IDs are a bug
+func mustRegisterCore(problems []Problem) *ProblemRegistry {
+	r := NewProblemRegistry()
+	if err := r.register(CoreSource, problems); err != nil {
+		panic(err)
+	}
+	return r
+}
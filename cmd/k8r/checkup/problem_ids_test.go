@@ -0,0 +1,80 @@
+package checkup_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+)
+
+func TestProblemRegistry(t *testing.T) {
+	foo := checkup.Problem{ID: "FooCheck", ShortDescription: "Foo is broken"}
+
+	tests := []struct {
+		name     string
+		source   string
+		problems []checkup.Problem
+		wantErr  string
+	}{
+		{name: "plugin", source: "plugin.acme", problems: []checkup.Problem{foo}},
+		{
+			name:     "qualified",
+			source:   "plugin.acme",
+			problems: []checkup.Problem{{ID: "plugin.acme/FooCheck", ShortDescription: "Foo is broken"}},
+		},
+		{name: "reserved", source: checkup.CoreSource, problems: []checkup.Problem{foo}, wantErr: "reserved"},
+		{name: "invalid source", source: "Acme", problems: []checkup.Problem{foo}, wantErr: "invalid problem source"},
+		{
+			name:     "invalid name",
+			source:   "plugin.acme",
+			problems: []checkup.Problem{{ID: "foo-check"}},
+			wantErr:  "invalid problem ID",
+		},
+		{
+			name:     "other source",
+			source:   "plugin.acme",
+			problems: []checkup.Problem{{ID: "plugin.other/FooCheck"}},
+			wantErr:  "can't be registered from source plugin.acme",
+		},
+		{
+			name:     "collision",
+			source:   "plugin.acme",
+			problems: []checkup.Problem{foo, {ID: "FOOCheck", ShortDescription: "Something else"}},
+			wantErr:  "plugin.acme/FOOCheck collides with plugin.acme/FooCheck",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := checkup.NewProblemRegistry()
+			err := r.Register(tt.source, tt.problems...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Register() error = %v, expected it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if p, ok := r.Lookup("plugin.acme/FooCheck"); !ok || p.ID != "plugin.acme/FooCheck" {
+				t.Errorf("Lookup() = %v, %v, expected the problem with its qualified ID", p, ok)
+			}
+			if _, ok := r.Lookup("FooCheck"); ok {
+				t.Error("Lookup() found a plugin problem by its core ID")
+			}
+		})
+	}
+}
+
+func TestCanonicalProblemID(t *testing.T) {
+	for id, want := range map[string]string{
+		"PodNotReady":          "PodNotReady",
+		"core/PodNotReady":     "PodNotReady",
+		"plugin.acme/FooCheck": "plugin.acme/FooCheck",
+	} {
+		if got := checkup.CanonicalProblemID(id); got != want {
+			t.Errorf("CanonicalProblemID(%q) = %q, expected %q", id, got, want)
+		}
+	}
+}
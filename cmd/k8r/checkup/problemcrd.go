@@ -0,0 +1,234 @@
+// Description: This file contains code for publishing the problems found
+// as Problem custom resources, used with the publish-problems flag, so
+// that controllers, policies and kubectl can see them
+
+package checkup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// problemResource is the Problem custom resource defined by
+// deploy/problem-crd.yaml
+var problemResource = CustomResource{Group: "k8r.io", Version: "v1alpha1", Resource: "problems", Kind: "Problem"}
+
+// Labels set on every Problem, Problems are only ever changed by the k8r
+// that published them
+const (
+	// problemManagedByLabel marks Problems published by k8r
+	problemManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// problemShardLabel is the shard that published the Problem, Problems
+	// published by unsharded scans don't have it
+	problemShardLabel = "k8r.io/shard"
+
+	// problemIDLabel is the ID of the problem, to select Problems by it
+	problemIDLabel = "k8r.io/problem-id"
+
+	// problemSeverityLabel is the severity of the problem
+	problemSeverityLabel = "k8r.io/severity"
+)
+
+// unsafeLabelChars are the characters replaced in label values and
+// object names, e.g. the slash of qualified problem IDs
+var unsafeLabelChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// problemSpec is the spec of a Problem
+type problemSpec struct {
+	ProblemID    string `json:"problemID"`
+	Severity     string `json:"severity"`
+	ResourceType string `json:"resourceType"`
+	Resource     string `json:"resource"`
+	Description  string `json:"description,omitempty"`
+	Details      string `json:"details,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	HelpURL      string `json:"helpURL,omitempty"`
+	SuppressedBy string `json:"suppressedBy,omitempty"`
+}
+
+// problemObject is a Problem custom resource
+type problemObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec problemSpec `json:"spec"`
+}
+
+// problemObjectList is a list of Problem custom resources
+type problemObjectList struct {
+	Items []problemObject `json:"items"`
+}
+
+// labelValue returns s as a valid label value
+func labelValue(s string) string {
+	s = strings.Trim(unsafeLabelChars.ReplaceAllString(s, "_"), "_.-")
+	if len(s) > 63 {
+		s = strings.Trim(s[:63], "_.-")
+	}
+	return s
+}
+
+// problemObjectName returns the name of the Problem about a resource, the
+// hash keeps names unique when the readable part is cut short
+func problemObjectName(r *Resource) string {
+	name := r.Name
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	readable := strings.ToLower(unsafeLabelChars.ReplaceAllString(r.Type+"-"+name+"-"+r.ProblemID, "-"))
+	if len(readable) > 50 {
+		readable = readable[:50]
+	}
+	sum := sha256.Sum256([]byte(r.ProblemID + "|" + r.Type + "|" + r.Name))
+	return strings.Trim(readable, "-.") + "-" + hex.EncodeToString(sum[:4])
+}
+
+// problemSelector returns the label selector of the Problems this run
+// publishes, so that shards leave each other's Problems alone
+func (o *Options) problemSelector() string {
+	selector := problemManagedByLabel + "=k8r"
+	if o.cfg.Shard != nil {
+		return selector + "," + problemShardLabel + "=" + labelValue(o.cfg.Shard.String())
+	}
+	return selector + ",!" + problemShardLabel
+}
+
+// problemObjects returns the Problems for the problems in a report, keyed
+// by namespace and name
+func (o *Options) problemObjects(report *Report) map[string]*problemObject {
+	objs := make(map[string]*problemObject, len(report.Resources))
+	for i := range report.Resources {
+		r := &report.Resources[i]
+
+		// Problems with cluster scoped resources, e.g. nodes, are kept in
+		// the publish-problems-namespace
+		namespace := o.cfg.ProblemsNamespace
+		if i := strings.Index(r.Name, "/"); i >= 0 {
+			namespace = r.Name[:i]
+		}
+
+		obj := &problemObject{
+			TypeMeta: metav1.TypeMeta{APIVersion: problemResource.GroupVersion(), Kind: problemResource.Kind},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      problemObjectName(r),
+				Namespace: namespace,
+				Labels: map[string]string{
+					problemManagedByLabel: "k8r",
+					problemIDLabel:        labelValue(r.ProblemID),
+					problemSeverityLabel:  r.GetSeverity().String(),
+				},
+			},
+			Spec: problemSpec{
+				ProblemID:    r.ProblemID,
+				Severity:     r.GetSeverity().String(),
+				ResourceType: r.Type,
+				Resource:     r.Name,
+				Details:      r.ProblemDetails,
+				Owner:        r.Owner,
+				SuppressedBy: r.SuppressedBy,
+			},
+		}
+		if o.cfg.Shard != nil {
+			obj.Labels[problemShardLabel] = labelValue(o.cfg.Shard.String())
+		}
+		if p := report.GetProblemByID(r.ProblemID); p != nil {
+			obj.Spec.Description = p.ShortDescription
+			obj.Spec.HelpURL = p.HelpURL
+		}
+		objs[namespace+"/"+obj.Name] = obj
+	}
+	return objs
+}
+
+// problemPath returns the API path of the Problems in a namespace, or of
+// one Problem when name is set
+func problemPath(namespace, name string) []string {
+	path := []string{"/apis", problemResource.Group, problemResource.Version, "namespaces", namespace, problemResource.Resource}
+	if name != "" {
+		path = append(path, name)
+	}
+	return path
+}
+
+// publishProblems creates a Problem for each problem in the report,
+// updates the ones that changed and deletes the ones that were resolved
+func (o *Options) publishProblems(ctx context.Context, report *Report) error {
+	if !o.cfg.PublishProblems || o.client == nil {
+		return nil
+	}
+	rc, ok := discoveryClient(o.client)
+	if !ok {
+		return errors.New("failed to publish problems: the client can't make requests")
+	}
+
+	body, err := rc.Get().AbsPath("/apis", problemResource.Group, problemResource.Version, problemResource.Resource).
+		Param("labelSelector", o.problemSelector()).Do(ctx).Raw()
+	if apierrors.IsNotFound(err) {
+		return errors.New("failed to publish problems: the Problem CRD isn't installed, apply deploy/problem-crd.yaml")
+	} else if err != nil {
+		return errors.Wrap(err, "failed to list published problems")
+	}
+	var list problemObjectList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return errors.Wrap(err, "failed to decode published problems")
+	}
+	published := make(map[string]*problemObject, len(list.Items))
+	for i := range list.Items {
+		published[list.Items[i].Namespace+"/"+list.Items[i].Name] = &list.Items[i]
+	}
+
+	objs := o.problemObjects(report)
+	created, updated, deleted := 0, 0, 0
+	for key, obj := range objs {
+		old, ok := published[key]
+		if ok && old.Spec == obj.Spec && reflect.DeepEqual(old.Labels, obj.Labels) {
+			continue
+		}
+
+		req := rc.Post().AbsPath(problemPath(obj.Namespace, "")...)
+		if ok {
+			obj.ResourceVersion = old.ResourceVersion
+			req = rc.Put().AbsPath(problemPath(obj.Namespace, obj.Name)...)
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode problem %s", key)
+		}
+		if err := req.SetHeader("Content-Type", "application/json").Body(b).Do(ctx).Error(); err != nil {
+			return errors.Wrapf(err, "failed to publish problem %s", key)
+		}
+		if ok {
+			updated++
+		} else {
+			created++
+		}
+	}
+
+	// Problems with resources that couldn't be listed may not be resolved
+	if len(o.incomplete()) != 0 {
+		o.log.Infof("Published problems: %d created, %d updated, none deleted as the scan is incomplete", created, updated)
+		return nil
+	}
+	for key, old := range published {
+		if _, ok := objs[key]; ok {
+			continue
+		}
+		err := rc.Delete().AbsPath(problemPath(old.Namespace, old.Name)...).Do(ctx).Error()
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete resolved problem %s", key)
+		}
+		deleted++
+	}
+	o.log.Infof("Published problems: %d created, %d updated, %d deleted", created, updated, deleted)
+	return nil
+}
@@ -0,0 +1,142 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// problemsAPIServer is an API server that serves pods and stores the
+// Problems published to it, every other list is empty
+type problemsAPIServer struct {
+	mu       sync.Mutex
+	pods     corev1.PodList
+	problems map[string]map[string]interface{}
+	writes   int
+}
+
+func (s *problemsAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	const problems = "/apis/k8r.io/v1alpha1/"
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/selfsubjectaccessreviews"):
+		_, _ = w.Write([]byte(`{"status":{"allowed":true}}`))
+	case r.URL.Path == "/api/v1/pods":
+		_ = json.NewEncoder(w).Encode(&s.pods)
+	case r.URL.Path == problems+"problems" && r.Method == http.MethodGet:
+		if r.URL.Query().Get("labelSelector") != "app.kubernetes.io/managed-by=k8r,!k8r.io/shard" {
+			http.Error(w, "unexpected selector", http.StatusBadRequest)
+			return
+		}
+		items := make([]map[string]interface{}, 0, len(s.problems))
+		for _, obj := range s.problems {
+			items = append(items, obj)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case strings.HasPrefix(r.URL.Path, problems+"namespaces/"):
+		s.writeProblem(w, r)
+	default:
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}
+}
+
+// writeProblem creates, replaces or deletes a Problem
+func (s *problemsAPIServer) writeProblem(w http.ResponseWriter, r *http.Request) {
+	s.writes++
+	if r.Method == http.MethodDelete {
+		delete(s.problems, path.Base(r.URL.Path))
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := obj["metadata"].(map[string]interface{})["name"].(string)
+	s.problems[name] = obj
+	_, _ = w.Write(b)
+}
+
+func TestPublishProblems(t *testing.T) {
+	pod := checkuptest.NewPod("api", checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer))
+	s := &problemsAPIServer{
+		pods: corev1.PodList{Items: []corev1.Pod{*pod}},
+		problems: map[string]map[string]interface{}{
+			"resolved": {
+				"metadata": map[string]interface{}{
+					"name": "resolved", "namespace": "default",
+					"labels": map[string]interface{}{"app.kubernetes.io/managed-by": "k8r"},
+				},
+				"spec": map[string]interface{}{"problemID": "PodPending"},
+			},
+		},
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	// The preflight makes more requests than the default rate limit allows
+	// without waiting
+	k, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL, QPS: 1000, Burst: 1000})
+	if err != nil {
+		t.Fatalf("NewForConfig() error = %v", err)
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.PublishProblems = true
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	run := func() {
+		t.Helper()
+		if err := o.RunWithClient(context.Background(), k); !errors.Is(err, checkup.ErrProblemsFound) {
+			t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+		}
+	}
+	run()
+
+	if _, ok := s.problems["resolved"]; ok || len(s.problems) != 1 {
+		t.Fatalf("published problems = %v, expected the resolved one to be replaced by the crash loop", s.problems)
+	}
+	for _, obj := range s.problems {
+		meta := obj["metadata"].(map[string]interface{})
+		spec := obj["spec"].(map[string]interface{})
+		if obj["kind"] != "Problem" || meta["namespace"] != "default" ||
+			spec["problemID"] != checkup.ProblemPodCrashLoopBackOff.ID || spec["resource"] != "default/api" {
+			t.Errorf("published problem = %v, expected the crash loop of default/api", obj)
+		}
+	}
+
+	// Problems that didn't change aren't written again
+	s.writes = 0
+	run()
+	if s.writes != 0 {
+		t.Errorf("second run wrote %d problems, expected none", s.writes)
+	}
+}
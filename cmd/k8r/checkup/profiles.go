@@ -0,0 +1,281 @@
+// Description: This file contains code for check profiles, which change
+// the checks and thresholds used for the namespaces mapped to them
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultProfilesConfigMapKey is the key of the profiles-configmap the
+// profiles are read from by default
+const defaultProfilesConfigMapKey = "profiles.yaml"
+
+// Profile overrides the checks and thresholds passed as flags for the
+// namespaces mapped to it, fields that aren't set are left as is
+type Profile struct {
+	// RestartThreshold overrides the restart-threshold flag
+	RestartThreshold *int `json:"restartThreshold,omitempty"`
+
+	// PodSecurityLevel overrides the pod-security-level flag
+	PodSecurityLevel string `json:"podSecurityLevel,omitempty"`
+
+	// InitContainerThreshold overrides the init-container-threshold flag
+	InitContainerThreshold *metav1.Duration `json:"initContainerThreshold,omitempty"`
+
+	// RolloutStuckThreshold overrides the rollout-stuck-threshold flag
+	RolloutStuckThreshold *metav1.Duration `json:"rolloutStuckThreshold,omitempty"`
+
+	// PodGracePeriod overrides the pod-grace-period flag
+	PodGracePeriod *metav1.Duration `json:"podGracePeriod,omitempty"`
+
+	// RequiredLabels overrides the required-labels flag
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+
+	// DisabledProblems are problems that aren't checked in addition to
+	// the ones passed to the disable-problem flag
+	DisabledProblems []string `json:"disabledProblems,omitempty"`
+
+	// EnabledProblems are problems that are checked even when they were
+	// passed to the disable-problem flag
+	EnabledProblems []string `json:"enabledProblems,omitempty"`
+}
+
+// ProfileMapping maps namespaces to a profile by name or by label, a
+// namespace matches when it matches either
+type ProfileMapping struct {
+	// Profile is the name of the profile
+	Profile string `json:"profile"`
+
+	// Names are glob patterns matching namespace names
+	Names []string `json:"names,omitempty"`
+
+	// Labels are labels the namespace must all have
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Profiles are the profiles read from the profiles-file or
+// profiles-configmap flag
+type Profiles struct {
+	// Profiles are the profiles by name
+	Profiles map[string]Profile `json:"profiles"`
+
+	// Namespaces maps namespaces to profiles, the first mapping that
+	// matches a namespace is used
+	Namespaces []ProfileMapping `json:"namespaces"`
+}
+
+// ReadProfiles reads profiles from a YAML or JSON file and checks that
+// the profiles and problems they refer to exist
+func ReadProfiles(path string) (*Profiles, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read profiles file")
+	}
+	return parseProfiles(b, "profiles file "+path)
+}
+
+// parseProfiles parses YAML or JSON profiles read from source and checks
+// that the profiles and problems they refer to exist
+func parseProfiles(b []byte, source string) (*Profiles, error) {
+	var p Profiles
+	if err := yaml.UnmarshalStrict(b, &p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", source)
+	}
+
+	for name := range p.Profiles {
+		profile := p.Profiles[name]
+		for _, id := range append(append([]string{}, profile.DisabledProblems...), profile.EnabledProblems...) {
+			if !knownProblem(id) {
+				return nil, fmt.Errorf("profile %s refers to unknown problem %s", name, id)
+			}
+		}
+	}
+	for i := range p.Namespaces {
+		if _, ok := p.Profiles[p.Namespaces[i].Profile]; !ok {
+			return nil, fmt.Errorf("namespaces are mapped to unknown profile %q", p.Namespaces[i].Profile)
+		}
+	}
+
+	return &p, nil
+}
+
+// parseConfigMapRef parses a reference to a ConfigMap as namespace/name
+func parseConfigMapRef(ref string) (namespace, name string, err error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid configmap %q, expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ReadProfilesConfigMap reads profiles from a key of a ConfigMap, given as
+// namespace/name, and checks that the profiles and problems they refer to
+// exist
+func ReadProfilesConfigMap(ctx context.Context, k kubernetes.Interface, ref, key string) (*Profiles, error) {
+	namespace, name, err := parseConfigMapRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err := k.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read profiles configmap %s", ref)
+	}
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("profiles configmap %s has no key %s", ref, key)
+	}
+	return parseProfiles([]byte(data), fmt.Sprintf("profiles configmap %s key %s", ref, key))
+}
+
+// loadProfilesConfigMap reads the profiles from the profiles-configmap,
+// it's read on every scan so that changing it takes effect on the next
+// one without redeploying k8r
+func (o *Options) loadProfilesConfigMap(ctx context.Context, k kubernetes.Interface) error {
+	if o.cfg.ProfilesConfigMap == "" {
+		return nil
+	}
+
+	profiles, err := ReadProfilesConfigMap(ctx, k, o.cfg.ProfilesConfigMap, o.cfg.ProfilesConfigMapKey)
+	if err != nil {
+		return err
+	}
+	o.cfg.Profiles = profiles
+	o.cfg.profileConfigs = nil
+	return nil
+}
+
+// knownProblem returns true if a problem with the ID exists, qualified
+// or not
+func knownProblem(id string) bool {
+	_, ok := coreProblems.Lookup(id)
+	return ok
+}
+
+// matches returns true if the namespace is mapped to the profile
+func (m *ProfileMapping) matches(namespace string, labels map[string]string) bool {
+	if matchesNamespace(namespace, m.Names) {
+		return true
+	}
+	if len(m.Labels) == 0 {
+		return false
+	}
+	for k, v := range m.Labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// profile returns the name of the profile the namespace is mapped to
+func (c *Config) profile(namespace string) (string, bool) {
+	if c.Profiles == nil || namespace == "" {
+		return "", false
+	}
+
+	var labels map[string]string
+	if c.Cluster != nil {
+		if ns := c.Cluster.GetNamespace(namespace); ns != nil {
+			labels = ns.Labels
+		}
+	}
+
+	for i := range c.Profiles.Namespaces {
+		if c.Profiles.Namespaces[i].matches(namespace, labels) {
+			return c.Profiles.Namespaces[i].Profile, true
+		}
+	}
+	return "", false
+}
+
+// forNamespace returns the config to check resources in the namespace
+// with, the config itself when the namespace isn't mapped to a profile
+func (c *Config) forNamespace(namespace string) *Config {
+	name, ok := c.profile(namespace)
+	if !ok {
+		return c
+	}
+
+	// The cached config is stale once a new cluster was listed
+	if cfg, ok := c.profileConfigs[name]; ok && cfg.Cluster == c.Cluster {
+		return cfg
+	}
+	if c.profileConfigs == nil {
+		c.profileConfigs = make(map[string]*Config)
+	}
+
+	p := c.Profiles.Profiles[name]
+	cfg := c.withProfile(&p)
+	c.profileConfigs[name] = cfg
+	return cfg
+}
+
+// withProfile returns a copy of the config with the profile's overrides
+func (c *Config) withProfile(p *Profile) *Config {
+	cfg := *c
+	cfg.Profiles = nil
+	cfg.profileConfigs = nil
+
+	if p.RestartThreshold != nil {
+		cfg.RestartThreshold = *p.RestartThreshold
+	}
+	if p.PodSecurityLevel != "" {
+		cfg.PodSecurityLevel = p.PodSecurityLevel
+	}
+	if p.InitContainerThreshold != nil {
+		cfg.InitContainerThreshold = p.InitContainerThreshold.Duration
+	}
+	if p.RolloutStuckThreshold != nil {
+		cfg.RolloutStuckThreshold = p.RolloutStuckThreshold.Duration
+	}
+	if p.PodGracePeriod != nil {
+		cfg.PodGracePeriod = p.PodGracePeriod.Duration
+	}
+	if p.RequiredLabels != nil {
+		cfg.RequiredLabels = p.RequiredLabels
+	}
+
+	cfg.DisabledProblems = make(map[string]bool, len(c.DisabledProblems))
+	for id, disabled := range c.DisabledProblems {
+		cfg.DisabledProblems[id] = disabled
+	}
+	for _, id := range p.DisabledProblems {
+		cfg.DisabledProblems[CanonicalProblemID(id)] = true
+	}
+	for _, id := range p.EnabledProblems {
+		// Problems disabled by the RBAC preflight can't be checked
+		id = CanonicalProblemID(id)
+		if !c.deniedProblem(id) {
+			delete(cfg.DisabledProblems, id)
+		}
+	}
+
+	return &cfg
+}
+
+// deniedProblem returns true if the problem needs a permission the RBAC
+// preflight found missing
+func (c *Config) deniedProblem(id string) bool {
+	for i := range RequiredPermissions {
+		p := &RequiredPermissions[i]
+		if c.allowed(*p) {
+			continue
+		}
+		for _, problem := range p.Problems {
+			if problem.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
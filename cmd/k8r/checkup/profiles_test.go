@@ -0,0 +1,180 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const profilesFile = `profiles:
+  prod-strict:
+    restartThreshold: 1
+  dev-relaxed:
+    restartThreshold: 10
+    disabledProblems: [PodNotReady]
+namespaces:
+- profile: prod-strict
+  names: ["prod-*"]
+- profile: dev-relaxed
+  labels: {env: dev}
+`
+
+func writeProfiles(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{name: "valid", contents: profilesFile},
+		{
+			name:     "unknown profile",
+			contents: "profiles: {}\nnamespaces:\n- profile: missing\n  names: [prod]\n",
+			wantErr:  `unknown profile "missing"`,
+		},
+		{
+			name:     "unknown problem",
+			contents: "profiles:\n  dev:\n    disabledProblems: [NotAProblem]\n",
+			wantErr:  "unknown problem NotAProblem",
+		},
+		{
+			name:     "unknown field",
+			contents: "profiles:\n  dev:\n    restartThreshhold: 10\n",
+			wantErr:  "failed to parse profiles file",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := checkup.ReadProfiles(writeProfiles(t, tt.contents))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ReadProfiles() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReadProfiles() error = %v, expected it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfilesApplied(t *testing.T) {
+	profiles, err := checkup.ReadProfiles(writeProfiles(t, profilesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarts := checkuptest.Restarts(checkuptest.DefaultContainer, 5)
+	notReady := checkuptest.NotReady(checkuptest.DefaultContainer)
+	objs := []runtime.Object{
+		checkuptest.NewNamespace("prod-payments", nil),
+		checkuptest.NewNamespace("sandbox", map[string]string{"env": "dev"}),
+		checkuptest.NewPod("api", restarts, notReady),
+		checkuptest.NewPod("api", restarts, notReady, checkuptest.InNamespace("prod-payments")),
+		checkuptest.NewPod("api", restarts, notReady, checkuptest.InNamespace("sandbox")),
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.RestartThreshold = 6
+	cfg.Profiles = profiles
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	resources, err := o.Scan(context.Background(), newClientset(objs, nil))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for i := range resources {
+		got[resources[i].Name+" "+resources[i].ProblemID] = true
+	}
+	for problem, want := range map[string]bool{
+		"default/api HighRestarts":       false,
+		"default/api PodNotReady":        true,
+		"prod-payments/api HighRestarts": true,
+		"prod-payments/api PodNotReady":  true,
+		"sandbox/api HighRestarts":       false,
+		"sandbox/api PodNotReady":        false,
+	} {
+		if got[problem] != want {
+			t.Errorf("%s reported = %v, expected %v", problem, got[problem], want)
+		}
+	}
+}
+
+func TestProfilesConfigMap(t *testing.T) {
+	profiles := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "profiles", Namespace: "k8r"},
+		Data:       map[string]string{"profiles.yaml": profilesFile},
+	}
+	objs := []runtime.Object{
+		profiles,
+		checkuptest.NewNamespace("prod-payments", nil),
+		checkuptest.NewPod("api", checkuptest.Restarts(checkuptest.DefaultContainer, 5), checkuptest.InNamespace("prod-payments")),
+	}
+	k := newClientset(objs, nil)
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.RestartThreshold = 6
+	cfg.ProfilesConfigMap = "k8r/profiles"
+	cfg.ProfilesConfigMapKey = "profiles.yaml"
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	highRestarts := func() bool {
+		t.Helper()
+		resources, err := o.Scan(context.Background(), k)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		for i := range resources {
+			if resources[i].ProblemID == checkup.ProblemHighRestarts.ID {
+				return true
+			}
+		}
+		return false
+	}
+	if !highRestarts() {
+		t.Error("Scan() didn't use the prod-strict profile from the configmap")
+	}
+
+	// Changes to the ConfigMap are picked up by the next scan
+	profiles.Data["profiles.yaml"] = strings.ReplaceAll(profilesFile, "restartThreshold: 1\n", "restartThreshold: 10\n")
+	if _, err := k.CoreV1().ConfigMaps("k8r").Update(context.Background(), profiles, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if highRestarts() {
+		t.Error("Scan() didn't pick up the changed profiles from the configmap")
+	}
+
+	cfg.ProfilesConfigMapKey = "missing.yaml"
+	if _, err := o.Scan(context.Background(), k); err == nil || !strings.Contains(err.Error(), "has no key missing.yaml") {
+		t.Errorf("Scan() error = %v, expected the missing key to be reported", err)
+	}
+}
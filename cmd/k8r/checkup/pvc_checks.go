@@ -0,0 +1,318 @@
+// Description: This file contains code for problems related to
+// PersistentVolumeClaims, how full they are and how resizing them went
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PVC event reasons
+const (
+	// volumeResizeFailedReason is the reason the resizer records when
+	// expanding a volume failed
+	volumeResizeFailedReason = "VolumeResizeFailed"
+
+	// externalExpandingReason is the reason the PVC controller records
+	// when it leaves expanding a volume to the CSI driver's resizer
+	externalExpandingReason = "ExternalExpanding"
+)
+
+// pvcEvents returns the events recorded for a PVC, newest first
+func pvcEvents(pvc *corev1.PersistentVolumeClaim, events []corev1.Event) []corev1.Event {
+	matching := make([]corev1.Event, 0)
+	for i := range events {
+		e := &events[i]
+		if e.InvolvedObject.Kind == "PersistentVolumeClaim" && e.InvolvedObject.Namespace == pvc.Namespace &&
+			e.InvolvedObject.Name == pvc.Name {
+			matching = append(matching, *e)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool { return eventTime(&matching[j]).Before(eventTime(&matching[i])) })
+	return matching
+}
+
+// pvcStorageClass returns the StorageClass a PVC was provisioned with
+func pvcStorageClass(pvc *corev1.PersistentVolumeClaim, classes []storagev1.StorageClass) (*storagev1.StorageClass, bool) {
+	if pvc.Spec.StorageClassName == nil {
+		return nil, false
+	}
+	for i := range classes {
+		if classes[i].Name == *pvc.Spec.StorageClassName {
+			return &classes[i], true
+		}
+	}
+	return nil, false
+}
+
+// pvcCondition returns the PVC's condition of the given type if it is true
+func pvcCondition(pvc *corev1.PersistentVolumeClaim, t corev1.PersistentVolumeClaimConditionType) (*corev1.PersistentVolumeClaimCondition, bool) {
+	for i := range pvc.Status.Conditions {
+		if c := &pvc.Status.Conditions[i]; c.Type == t && c.Status == corev1.ConditionTrue {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// pvcExpansionRequested returns true if more storage is requested for a
+// bound PVC than its volume has
+func pvcExpansionRequested(pvc *corev1.PersistentVolumeClaim) bool {
+	requested, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity, hasCapacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	return ok && hasCapacity && pvc.Status.Phase == corev1.ClaimBound && requested.Cmp(capacity) > 0
+}
+
+// pvcMountedBy returns the names of the pods that mount a PVC
+func pvcMountedBy(pvc *corev1.PersistentVolumeClaim, pods []corev1.Pod) []string {
+	names := make([]string, 0)
+	for i := range pods {
+		p := &pods[i]
+		if p.Namespace != pvc.Namespace {
+			continue
+		}
+		for _, v := range p.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == pvc.Name {
+				names = append(names, p.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// ProblemPVCAlmostFull is a problem with a pod that mounts a
+// PersistentVolumeClaim that is more than the usage threshold full,
+// according to the volume stats of the kubelet on the pod's node. It is
+// reported on the pod so that it is attributed to the workload and owner
+// that writes to the volume.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PVCAlmostFull
+var ProblemPVCAlmostFull = Problem{
+	ID:               "PVCAlmostFull",
+	ShortDescription: "A PersistentVolumeClaim mounted by the pod is almost full",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PVCAlmostFull",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil || cfg.PVCUsageThreshold <= 0 {
+			return "", false, false
+		}
+		stats, ok := cfg.Cluster.NodeStats[pod.Spec.NodeName]
+		if !ok {
+			return "", false, false
+		}
+
+		details := make([]string, 0)
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim == nil {
+				continue
+			}
+			fs, ok := stats.PVCs[pod.Namespace+"/"+v.PersistentVolumeClaim.ClaimName]
+			if !ok || fs.CapacityBytes == 0 {
+				continue
+			}
+
+			used := 100 - float64(fs.AvailableBytes)/float64(fs.CapacityBytes)*100
+			if used < float64(cfg.PVCUsageThreshold) {
+				continue
+			}
+			details = append(details, fmt.Sprintf("PVC %s (volume %s) is %.0f%% full, %s of %s left",
+				v.PersistentVolumeClaim.ClaimName, v.Name, used, gib(fs.AvailableBytes), gib(fs.CapacityBytes)))
+		}
+
+		if len(details) == 0 {
+			return "", false, false
+		}
+		return strings.Join(details, "; ") + ", expand the claim or clean up data before writes start failing", false, true
+	},
+}
+
+// ProblemPVCResizeStuck is a problem with a PVC that has been resizing,
+// or waiting for its filesystem to be resized, for longer than the resize
+// threshold
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PVCResizeStuck
+var ProblemPVCResizeStuck = Problem{
+	ID:               "PVCResizeStuck",
+	ShortDescription: "A PersistentVolumeClaim has been resizing for a long time, its new size isn't usable yet",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PVCResizeStuck",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok || cfg.PVCResizeThreshold <= 0 {
+			return "", false, false
+		}
+		cluster := cfg.Cluster
+		if cluster == nil {
+			cluster = &Cluster{}
+		}
+
+		if c, ok := pvcCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending); ok {
+			since := time.Since(c.LastTransitionTime.Time)
+			if since < cfg.PVCResizeThreshold {
+				return "", false, false
+			}
+			details := fmt.Sprintf("The volume was expanded %s ago but its filesystem hasn't been resized", since.Round(time.Minute))
+			if pods := pvcMountedBy(pvc, cluster.Pods); len(pods) == 0 {
+				details += ", no pod mounts the claim and kubelet only resizes the filesystem when one does, start a pod that mounts it"
+			} else {
+				details += fmt.Sprintf(", it is mounted by %s, if the CSI driver doesn't support online expansion restart the pods", strings.Join(pods, ", "))
+			}
+			return details, true, true
+		}
+
+		if c, ok := pvcCondition(pvc, corev1.PersistentVolumeClaimResizing); ok {
+			since := time.Since(c.LastTransitionTime.Time)
+			if since < cfg.PVCResizeThreshold {
+				return "", false, false
+			}
+			details := fmt.Sprintf("The volume has been resizing for %s", since.Round(time.Minute))
+			for _, e := range pvcEvents(pvc, cluster.PVCEvents) {
+				if e.Reason == volumeResizeFailedReason {
+					details += ", the last attempt failed: " + e.Message
+					break
+				}
+			}
+			return details + ", check the logs of the CSI driver's resizer", true, true
+		}
+		return "", false, false
+	},
+}
+
+// ProblemPVCExpansionFailed is a problem with a bound PVC that requests
+// more storage than its volume has, without a resize in progress, because
+// expanding it failed or nothing is able to expand it
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PVCExpansionFailed
+var ProblemPVCExpansionFailed = Problem{
+	ID:               "PVCExpansionFailed",
+	ShortDescription: "A PersistentVolumeClaim was resized but its volume wasn't expanded",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PVCExpansionFailed",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok || !pvcExpansionRequested(pvc) {
+			return "", false, false
+		}
+		// Resizes in progress are checked by PVCResizeStuck
+		if _, ok := pvcCondition(pvc, corev1.PersistentVolumeClaimResizing); ok {
+			return "", false, false
+		}
+		if _, ok := pvcCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending); ok {
+			return "", false, false
+		}
+		cluster := cfg.Cluster
+		if cluster == nil {
+			cluster = &Cluster{}
+		}
+
+		requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+		details := fmt.Sprintf("%s is requested but the volume is %s", requested.String(), capacity.String())
+
+		if sc, ok := pvcStorageClass(pvc, cluster.StorageClasses); ok && (sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion) {
+			return details + fmt.Sprintf(", StorageClass %s doesn't allow volume expansion, set allowVolumeExpansion if its provisioner supports it", sc.Name), false, true
+		}
+
+		for _, e := range pvcEvents(pvc, cluster.PVCEvents) {
+			switch {
+			case e.Reason == volumeResizeFailedReason:
+				return details + ", expanding it failed: " + e.Message, false, true
+			case e.Reason == externalExpandingReason && cfg.PVCResizeThreshold > 0 && time.Since(eventTime(&e)) >= cfg.PVCResizeThreshold:
+				return details + fmt.Sprintf(", it was handed to the CSI driver to expand %s ago but nothing picked it up, "+
+					"check that the driver supports expansion and runs the external-resizer sidecar", time.Since(eventTime(&e)).Round(time.Minute)), false, true
+			}
+		}
+		return "", false, false
+	},
+}
+
+// ProblemPVCPending is a problem with a PVC that has been Pending for
+// longer than the pending threshold, so the pods that mount it can't
+// start. PVCs of a WaitForFirstConsumer StorageClass aren't provisioned
+// until a pod uses them and are only reported once one does.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PVCPending
+var ProblemPVCPending = Problem{
+	ID:               "PVCPending",
+	ShortDescription: "A PersistentVolumeClaim is stuck Pending, so pods that mount it can't start",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PVCPending",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok || cfg.Cluster == nil || cfg.PVCPendingThreshold <= 0 || pvc.Status.Phase != corev1.ClaimPending {
+			return "", false, false
+		}
+		since := time.Since(pvc.CreationTimestamp.Time)
+		if pvc.CreationTimestamp.IsZero() || since < cfg.PVCPendingThreshold {
+			return "", false, false
+		}
+
+		pods := pvcMountedBy(pvc, cfg.Cluster.Pods)
+		details := fmt.Sprintf("Claim has been Pending for %s", since.Round(time.Minute))
+		switch sc, ok := pvcStorageClass(pvc, cfg.Cluster.StorageClasses); {
+		case ok:
+			firstConsumer := sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+			if firstConsumer && len(pods) == 0 {
+				return "", false, false
+			}
+			details += fmt.Sprintf(", StorageClass %s (provisioner %s)", sc.Name, sc.Provisioner)
+			if firstConsumer {
+				details += " binds once a pod using it is scheduled"
+			}
+		case pvc.Spec.StorageClassName == nil:
+			details += ", it has no StorageClass"
+		case *pvc.Spec.StorageClassName == "":
+			details += ", it waits for a matching PersistentVolume created by hand"
+		case len(cfg.Cluster.StorageClasses) != 0:
+			details += fmt.Sprintf(", StorageClass %s doesn't exist", *pvc.Spec.StorageClassName)
+		default:
+			details += fmt.Sprintf(", StorageClass %s", *pvc.Spec.StorageClassName)
+		}
+
+		// The latest event is the most recent attempt to provision or
+		// bind the volume
+		if events := pvcEvents(pvc, cfg.Cluster.PVCEvents); len(events) != 0 {
+			details += fmt.Sprintf(", the last event was %s: %s", events[0].Reason, strings.TrimSuffix(events[0].Message, "."))
+		}
+		if len(pods) != 0 {
+			details += ", pods waiting for it: " + strings.Join(pods, ", ")
+		}
+		return details, false, true
+	},
+}
+
+// podMountsPendingPVC returns true if the symptom was found on a pod that
+// mounts the PVC the cause was found on
+func podMountsPendingPVC(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "PVC" || symptom.Type != "pod" || c == nil {
+		return false
+	}
+
+	namespace, name := splitResourceName(cause.Name)
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.Namespace, pvc.Name = namespace, name
+	for _, pod := range pvcMountedBy(pvc, c.Pods) {
+		if namespace+"/"+pod == symptom.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// pvcOfStorageClass returns true if the symptom was found on a PVC of the
+// StorageClass the cause was found on
+func pvcOfStorageClass(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "StorageClass" || symptom.Type != "PVC" || c == nil {
+		return false
+	}
+
+	for i := range c.PersistentVolumeClaims {
+		pvc := &c.PersistentVolumeClaims[i]
+		if pvc.Namespace+"/"+pvc.Name == symptom.Name {
+			return pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName == cause.Name
+		}
+	}
+	return false
+}
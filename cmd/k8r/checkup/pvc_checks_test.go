@@ -0,0 +1,204 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPVCAlmostFull(t *testing.T) {
+	const gib = 1 << 30
+	pod := checkuptest.NewPod("db-0", checkuptest.OnNode("node-1"), checkuptest.WithPVCVolume("data", "data-db-0"))
+
+	// withUsage returns a config where data-db-0 has the given GiB of 10
+	// available
+	withUsage := func(available uint64) *checkup.Config {
+		cluster := checkuptest.NewCluster()
+		cluster.NodeStats = map[string]*checkup.NodeStats{"node-1": {
+			PVCs: map[string]*checkup.FsStats{
+				"default/data-db-0": {AvailableBytes: available * gib, CapacityBytes: 10 * gib},
+			},
+		}}
+		return checkuptest.NewConfig(cluster)
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemPVCAlmostFull, []checkuptest.Case{
+		{Name: "no stats", Object: pod},
+		{Name: "plenty of space", Object: pod, Config: withUsage(5)},
+		{Name: "no pvc", Object: checkuptest.NewPod("db-0", checkuptest.OnNode("node-1")), Config: withUsage(1)},
+		{
+			Name:           "almost full",
+			Object:         pod,
+			Config:         withUsage(1),
+			Occurring:      true,
+			DetailsContain: "PVC data-db-0 (volume data) is 90% full, 1.0GiB of 10.0GiB left",
+		},
+	})
+}
+
+func TestPVCResizeStuck(t *testing.T) {
+	longAgo := time.Now().Add(-2 * time.Hour)
+	pending := checkuptest.NewPVC("data-db-0", "ssd", "20Gi", checkuptest.PVCCondition(corev1.PersistentVolumeClaimFileSystemResizePending, longAgo))
+	resizing := checkuptest.NewPVC("data-db-0", "ssd", "10Gi", checkuptest.PVCRequests("20Gi"), checkuptest.PVCCondition(corev1.PersistentVolumeClaimResizing, longAgo))
+
+	checkuptest.RunCases(t, checkup.ProblemPVCResizeStuck, []checkuptest.Case{
+		{Name: "not resizing", Object: checkuptest.NewPVC("data-db-0", "ssd", "10Gi")},
+		{
+			Name:   "recently resized",
+			Object: checkuptest.NewPVC("data-db-0", "ssd", "20Gi", checkuptest.PVCCondition(corev1.PersistentVolumeClaimFileSystemResizePending, time.Now())),
+		},
+		{
+			Name:           "filesystem resize pending without pods",
+			Object:         pending,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "no pod mounts the claim",
+		},
+		{
+			Name:           "filesystem resize pending with pods",
+			Object:         pending,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(checkuptest.NewPod("db-0", checkuptest.WithPVCVolume("data", "data-db-0")))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "it is mounted by db-0",
+		},
+		{
+			Name:   "resizing",
+			Object: resizing,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(
+				checkuptest.NewPVCEvent("data-db-0", "VolumeResizeFailed", "resize volume failed: quota exceeded", longAgo),
+			)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "The volume has been resizing for 2h0m0s, the last attempt failed: resize volume failed: quota exceeded",
+		},
+	})
+}
+
+func TestPVCExpansionFailed(t *testing.T) {
+	resized := checkuptest.NewPVC("data-db-0", "ssd", "10Gi", checkuptest.PVCRequests("20Gi"))
+	withObjects := func(objs ...runtime.Object) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(objs...))
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemPVCExpansionFailed, []checkuptest.Case{
+		{Name: "not resized", Object: checkuptest.NewPVC("data-db-0", "ssd", "10Gi")},
+		{Name: "resize just requested", Object: resized, Config: withObjects(checkuptest.NewStorageClass("ssd", "ebs.csi.aws.com", true))},
+		{
+			Name:           "expansion not allowed",
+			Object:         resized,
+			Config:         withObjects(checkuptest.NewStorageClass("ssd", "ebs.csi.aws.com", false)),
+			Occurring:      true,
+			DetailsContain: "20Gi is requested but the volume is 10Gi, StorageClass ssd doesn't allow volume expansion",
+		},
+		{
+			Name:           "resize failed",
+			Object:         resized,
+			Config:         withObjects(checkuptest.NewPVCEvent("data-db-0", "VolumeResizeFailed", "rpc error: volume is in use", time.Now())),
+			Occurring:      true,
+			DetailsContain: "expanding it failed: rpc error: volume is in use",
+		},
+		{
+			Name:   "no resizer",
+			Object: resized,
+			Config: withObjects(checkuptest.NewPVCEvent("data-db-0", "ExternalExpanding",
+				"waiting for an external controller to expand this PVC", time.Now().Add(-time.Hour))),
+			Occurring:      true,
+			DetailsContain: "nothing picked it up",
+		},
+	})
+}
+
+func TestPVCPending(t *testing.T) {
+	// pending returns a Pending PVC of the class created the given time ago
+	pending := func(class string, age time.Duration) *corev1.PersistentVolumeClaim {
+		pvc := checkuptest.NewPVC("data", class, "10Gi", checkuptest.PVCPending())
+		pvc.CreationTimestamp = *ago(age)
+		return pvc
+	}
+	ssd := checkuptest.NewStorageClass("ssd", "ebs.csi.aws.com", true)
+	waitForConsumer := checkuptest.NewStorageClass("local", "rancher.io/local-path", false)
+	mode := storagev1.VolumeBindingWaitForFirstConsumer
+	waitForConsumer.VolumeBindingMode = &mode
+	pod := checkuptest.NewPod("db-0", checkuptest.WithPVCVolume("data", "data"))
+
+	withCluster := func(objs ...runtime.Object) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(objs...))
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemPVCPending, []checkuptest.Case{
+		{Name: "bound", Object: checkuptest.NewPVC("data", "ssd", "10Gi"), Config: withCluster(ssd)},
+		{Name: "just created", Object: pending("ssd", time.Minute), Config: withCluster(ssd)},
+		{Name: "waiting for a consumer", Object: pending("local", time.Hour), Config: withCluster(waitForConsumer)},
+		{
+			Name:   "provisioning failed",
+			Object: pending("ssd", 20*time.Minute),
+			Config: withCluster(ssd, pod, checkuptest.NewPVCEvent("data", "ProvisioningFailed",
+				"failed to provision volume with StorageClass \"ssd\": UnauthorizedOperation.", time.Now().Add(-time.Minute))),
+			Occurring: true,
+			DetailsContain: "Claim has been Pending for 20m0s, StorageClass ssd (provisioner ebs.csi.aws.com), " +
+				"the last event was ProvisioningFailed: failed to provision volume with StorageClass \"ssd\": UnauthorizedOperation, pods waiting for it: db-0",
+		},
+		{
+			Name:           "consumer can't be scheduled",
+			Object:         pending("local", time.Hour),
+			Config:         withCluster(waitForConsumer, pod),
+			Occurring:      true,
+			DetailsContain: "StorageClass local (provisioner rancher.io/local-path) binds once a pod using it is scheduled",
+		},
+		{
+			Name:           "missing class",
+			Object:         pending("fast", time.Hour),
+			Config:         withCluster(ssd),
+			Occurring:      true,
+			DetailsContain: "StorageClass fast doesn't exist",
+		},
+	})
+}
+
+func TestPVCPendingSuppressesPodPending(t *testing.T) {
+	pvc := checkuptest.NewPVC("data", "ssd", "10Gi", checkuptest.PVCPending())
+	pvc.CreationTimestamp = *ago(20 * time.Minute)
+	objs := []runtime.Object{
+		checkuptest.NewNode("node-1"),
+		checkuptest.NewStorageClass("ssd", "ebs.csi.aws.com", true),
+		checkuptest.NewCSIDriver("ebs.csi.aws.com"),
+		pvc,
+		checkuptest.NewPod("db-0", checkuptest.WithPVCVolume("data", "data"),
+			checkuptest.Unschedulable("0/1 nodes are available: pod has unbound immediate PersistentVolumeClaims")),
+	}
+
+	for _, showSuppressed := range []bool{false, true} {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.ShowSuppressed = showSuppressed
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+		if err := o.RunWithClient(context.Background(), newClientset(objs, nil)); err == nil {
+			t.Fatal("RunWithClient() error = nil, expected problems")
+		}
+
+		want := []string{"- default/data: Claim has been Pending", "1 problems caused by the problems above were hidden"}
+		if showSuppressed {
+			want = []string{"- default/db-0: Pod can't be scheduled", "(caused by PVCPending on default/data)"}
+		}
+		for _, s := range want {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("show suppressed %v: output = %q, expected it to contain %q", showSuppressed, out.String(), s)
+			}
+		}
+		if !showSuppressed && strings.Contains(out.String(), "default/db-0") {
+			t.Errorf("output = %q, expected PodPending to be hidden", out.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Description: This file contains code for problems related to RBAC
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// rbacDefaultsLabel is the label Kubernetes sets on the roles it creates
+// itself, e.g. cluster-admin
+const rbacDefaultsLabel = "kubernetes.io/bootstrapping"
+
+// isDefaultRole returns true for roles that Kubernetes creates itself,
+// these grant wildcards by design
+func isDefaultRole(meta *metav1.ObjectMeta) bool {
+	return meta.Labels[rbacDefaultsLabel] == "rbac-defaults" ||
+		strings.HasPrefix(meta.Name, "system:") ||
+		isSystemNamespace(meta.Namespace)
+}
+
+// hasWildcard returns true if the values contain the RBAC wildcard
+func hasWildcard(values []string) bool {
+	for _, v := range values {
+		if v == rbacv1.VerbAll {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemRBACWildcardGrant is a problem with a Role or ClusterRole that
+// grants a wildcard, which grants more than intended and grows to include
+// any resources or verbs added to the cluster later
+// https://github.com/Ashvin-Ranjan/k8r/wiki/RBACWildcardGrant
+var ProblemRBACWildcardGrant = Problem{
+	ID:               "RBACWildcardGrant",
+	ShortDescription: "A Role or ClusterRole grants access with a wildcard, including anything added to the cluster later",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/RBACWildcardGrant",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		var meta *metav1.ObjectMeta
+		var rules []rbacv1.PolicyRule
+		switch r := obj.(type) {
+		case *rbacv1.Role:
+			meta, rules = &r.ObjectMeta, r.Rules
+		case *rbacv1.ClusterRole:
+			// Aggregated roles get their rules from other roles, which
+			// are reported themselves
+			if r.AggregationRule != nil {
+				return "", false, false
+			}
+			meta, rules = &r.ObjectMeta, r.Rules
+		default:
+			return "", false, false
+		}
+
+		if isDefaultRole(meta) {
+			return "", false, false
+		}
+
+		grants := make([]string, 0)
+		for i := range rules {
+			rule := &rules[i]
+
+			wildcards := make([]string, 0)
+			if hasWildcard(rule.Verbs) {
+				wildcards = append(wildcards, "verbs")
+			}
+			if hasWildcard(rule.APIGroups) {
+				wildcards = append(wildcards, "API groups")
+			}
+			if hasWildcard(rule.Resources) {
+				wildcards = append(wildcards, "resources")
+			}
+			if hasWildcard(rule.NonResourceURLs) {
+				wildcards = append(wildcards, "non-resource URLs")
+			}
+			if len(wildcards) != 0 {
+				grants = append(grants, fmt.Sprintf("rule %d grants all %s", i+1, strings.Join(wildcards, ", ")))
+			}
+		}
+		if len(grants) == 0 {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("Role %s", strings.Join(grants, "; ")), true, true
+	},
+}
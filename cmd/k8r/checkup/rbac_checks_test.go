@@ -0,0 +1,42 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestRBACWildcardGrant(t *testing.T) {
+	readPods := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}
+	allPods := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"*"}}
+	everything := rbacv1.PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}
+
+	bootstrapped := checkuptest.NewClusterRole("cluster-admin", everything)
+	bootstrapped.Labels = map[string]string{"kubernetes.io/bootstrapping": "rbac-defaults"}
+
+	aggregated := checkuptest.NewClusterRole("monitoring", everything)
+	aggregated.AggregationRule = &rbacv1.AggregationRule{}
+
+	checkuptest.RunCases(t, checkup.ProblemRBACWildcardGrant, []checkuptest.Case{
+		{Name: "explicit", Object: checkuptest.NewRole("reader", readPods)},
+		{
+			Name:           "wildcard verbs",
+			Object:         checkuptest.NewRole("pod-admin", readPods, allPods),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "rule 2 grants all verbs",
+		},
+		{
+			Name:           "cluster role",
+			Object:         checkuptest.NewClusterRole("deployer", everything),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "rule 1 grants all verbs, API groups, resources",
+		},
+		{Name: "default role", Object: bootstrapped},
+		{Name: "system role", Object: checkuptest.NewClusterRole("system:controller:foo", everything)},
+		{Name: "aggregated", Object: aggregated},
+	})
+}
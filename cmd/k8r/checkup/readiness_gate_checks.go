@@ -0,0 +1,122 @@
+// Description: This file contains code for problems with pods whose
+// containers are ready but that are held back by readiness gates
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// readinessGateHints are hints for the controllers that set well-known
+// readiness gates, by condition type prefix
+var readinessGateHints = map[string]string{
+	"target-health.elbv2.k8s.aws/": "the AWS Load Balancer Controller sets it once the pod is healthy in its " +
+		"target group, check the controller's logs and the TargetGroupBinding",
+	"target-health.alb.ingress.k8s.aws/": "the AWS Load Balancer Controller sets it once the pod is healthy in its " +
+		"target group, check the controller's logs and the TargetGroupBinding",
+	"cloud.google.com/load-balancer-neg-ready": "the GKE NEG controller sets it once the pod is healthy in its " +
+		"network endpoint group, check the load balancer's health check",
+}
+
+// unmetReadinessGate is a readiness gate whose condition isn't true
+type unmetReadinessGate struct {
+	// ConditionType is the condition type of the gate
+	ConditionType corev1.PodConditionType
+
+	// Condition is the pod's condition for the gate, nil if it was never
+	// set
+	Condition *corev1.PodCondition
+
+	// Since is when the gate was last seen unmet
+	Since time.Time
+}
+
+// podCondition returns the pod's condition of the given type
+func podCondition(pod *corev1.Pod, t corev1.PodConditionType) (*corev1.PodCondition, bool) {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == t {
+			return &pod.Status.Conditions[i], true
+		}
+	}
+	return nil, false
+}
+
+// findUnmetReadinessGate returns the first readiness gate of a running pod
+// whose containers are ready that isn't true. Gates that were never set
+// are unmet since the containers became ready.
+func findUnmetReadinessGate(pod *corev1.Pod) (*unmetReadinessGate, bool) {
+	if pod.Status.Phase != corev1.PodRunning || len(pod.Spec.ReadinessGates) == 0 {
+		return nil, false
+	}
+	containersReady, ok := podCondition(pod, corev1.ContainersReady)
+	if !ok || containersReady.Status != corev1.ConditionTrue {
+		return nil, false
+	}
+
+	for _, g := range pod.Spec.ReadinessGates {
+		c, ok := podCondition(pod, g.ConditionType)
+		switch {
+		case ok && c.Status == corev1.ConditionTrue:
+			continue
+		case ok && !c.LastTransitionTime.IsZero():
+			return &unmetReadinessGate{ConditionType: g.ConditionType, Condition: c, Since: c.LastTransitionTime.Time}, true
+		default:
+			return &unmetReadinessGate{ConditionType: g.ConditionType, Condition: c, Since: containersReady.LastTransitionTime.Time}, true
+		}
+	}
+	return nil, false
+}
+
+// readinessGateHint returns the hint for a well-known readiness gate
+func readinessGateHint(t corev1.PodConditionType) (string, bool) {
+	for prefix, hint := range readinessGateHints {
+		if strings.HasPrefix(string(t), prefix) {
+			return hint, true
+		}
+	}
+	return "", false
+}
+
+// ProblemPodReadinessGateUnmet is a problem with a pod whose containers are
+// ready but that isn't ready because a readiness gate, e.g. an ALB target
+// group binding, never became true. PodNotReady only looks at containers
+// so it doesn't report these.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodReadinessGateUnmet
+var ProblemPodReadinessGateUnmet = Problem{
+	ID:               "PodReadinessGateUnmet",
+	ShortDescription: "A pod's containers are ready but a readiness gate isn't, so it receives no traffic",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodReadinessGateUnmet",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return "", false, false
+		}
+
+		gate, ok := findUnmetReadinessGate(pod)
+		if !ok || gate.Since.IsZero() || time.Since(gate.Since) < cfg.PodGracePeriod {
+			return "", false, false
+		}
+
+		var details string
+		if gate.Condition == nil {
+			details = fmt.Sprintf("Readiness gate %s was never set in the %s since the containers became ready",
+				gate.ConditionType, time.Since(gate.Since).Round(time.Minute))
+		} else {
+			details = fmt.Sprintf("Readiness gate %s has been %s for %s",
+				gate.ConditionType, gate.Condition.Status, time.Since(gate.Since).Round(time.Minute))
+			if gate.Condition.Reason != "" || gate.Condition.Message != "" {
+				details += fmt.Sprintf(" (%s: %s)", gate.Condition.Reason, gate.Condition.Message)
+			}
+		}
+		if hint, ok := readinessGateHint(gate.ConditionType); ok {
+			details += ", " + hint
+		}
+		return details, false, true
+	},
+}
@@ -0,0 +1,53 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// readinessGate adds a readiness gate to a pod whose containers became
+// ready at the given time, with the gate's condition if status is set
+func readinessGate(gate corev1.PodConditionType, status corev1.ConditionStatus, since time.Time) checkuptest.PodOption {
+	return func(p *corev1.Pod) {
+		p.Spec.ReadinessGates = append(p.Spec.ReadinessGates, corev1.PodReadinessGate{ConditionType: gate})
+		p.Status.Conditions = append(p.Status.Conditions, corev1.PodCondition{
+			Type: corev1.ContainersReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(since),
+		})
+		if status != "" {
+			p.Status.Conditions = append(p.Status.Conditions, corev1.PodCondition{
+				Type: gate, Status: status, LastTransitionTime: metav1.NewTime(since),
+				Reason: "Elb.RegistrationInProgress", Message: "Target registration is in progress",
+			})
+		}
+	}
+}
+
+func TestPodReadinessGateUnmet(t *testing.T) {
+	alb := corev1.PodConditionType("target-health.elbv2.k8s.aws/k8s-default-web-6b1c2f")
+	custom := corev1.PodConditionType("example.com/warmed-up")
+	old := time.Now().Add(-30 * time.Minute)
+
+	checkuptest.RunCases(t, checkup.ProblemPodReadinessGateUnmet, []checkuptest.Case{
+		{Name: "no gates", Object: checkuptest.NewPod("web")},
+		{Name: "gate met", Object: checkuptest.NewPod("web", readinessGate(alb, corev1.ConditionTrue, old))},
+		{Name: "gate recently unmet", Object: checkuptest.NewPod("web", readinessGate(alb, corev1.ConditionFalse, time.Now()))},
+		{
+			Name:           "alb gate unmet",
+			Object:         checkuptest.NewPod("web", readinessGate(alb, corev1.ConditionFalse, old)),
+			Occurring:      true,
+			DetailsContain: "Readiness gate " + string(alb) + " has been False for 30m0s (Elb.RegistrationInProgress: Target registration is in progress), the AWS Load Balancer Controller",
+		},
+		{
+			Name:           "gate never set",
+			Object:         checkuptest.NewPod("web", readinessGate(custom, "", old)),
+			Occurring:      true,
+			DetailsContain: "Readiness gate example.com/warmed-up was never set in the 30m0s since the containers became ready",
+		},
+		{Name: "containers not ready", Object: checkuptest.NewPod("web", checkuptest.NotReady(checkuptest.DefaultContainer))},
+	})
+}
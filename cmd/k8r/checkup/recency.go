@@ -0,0 +1,85 @@
+// Description: This file contains code for showing where resources with
+// problems run and how long ago they were created and restarted
+
+package checkup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// recentRestartWindow is how recently a pod has to have restarted to be
+// marked as recently restarted
+const recentRestartWindow = 10 * time.Minute
+
+// lastRestart returns when a container of the pod last restarted, which is
+// when its previous run finished
+func lastRestart(pod *corev1.Pod) (time.Time, bool) {
+	var last time.Time
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		cs := &statuses[i]
+		if cs.RestartCount == 0 || cs.LastTerminationState.Terminated == nil {
+			continue
+		}
+		if finished := cs.LastTerminationState.Terminated.FinishedAt.Time; finished.After(last) {
+			last = finished
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// podImages returns the distinct images of a pod's containers, in the
+// order the containers are declared
+func podImages(pod *corev1.Pod) []string {
+	images := make([]string, 0, len(pod.Spec.Containers))
+	seen := make(map[string]bool)
+	for i := range pod.Spec.Containers {
+		if image := pod.Spec.Containers[i].Image; image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// resourceContext returns the node and images of a pod, its age, how long
+// ago it last restarted and the alerts about it, formatted to be appended
+// to it in the report, or nothing when none of them are known
+func resourceContext(r *Resource, now time.Time) string {
+	parts := make([]string, 0, 4)
+	if r.Node != "" {
+		parts = append(parts, "node "+r.Node)
+	}
+	if len(r.Images) != 0 {
+		parts = append(parts, "image "+strings.Join(r.Images, ", "))
+	}
+	if r.CreatedAt != nil {
+		parts = append(parts, "age "+duration.HumanDuration(now.Sub(*r.CreatedAt)))
+	}
+	if r.LastRestartAt != nil {
+		since := now.Sub(*r.LastRestartAt)
+		restarted := fmt.Sprintf("restarted %s ago", duration.HumanDuration(since))
+		if since < recentRestartWindow {
+			restarted += " " + color.HiYellowString("(recent)")
+		}
+		parts = append(parts, restarted)
+	}
+	for _, a := range r.Alerts {
+		state := a.State
+		if state == AlertFiring {
+			state = color.HiRedString(state)
+		}
+		parts = append(parts, fmt.Sprintf("alert %s %s", a.Name, state))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", strings.Join(parts, ", "))
+}
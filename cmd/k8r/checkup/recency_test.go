@@ -0,0 +1,45 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestReportContext(t *testing.T) {
+	color.NoColor = true
+	now := time.Now()
+
+	objs := []runtime.Object{
+		checkuptest.NewPod("web", checkuptest.OnNode("node-1"), checkuptest.CreatedAt(now.Add(-3*time.Hour)),
+			checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer),
+			checkuptest.RestartedAt(checkuptest.DefaultContainer, now.Add(-5*time.Minute))),
+		checkuptest.NewPod("worker", checkuptest.CreatedAt(now.Add(-3*time.Hour)),
+			checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer),
+			checkuptest.RestartedAt(checkuptest.DefaultContainer, now.Add(-2*time.Hour))),
+	}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(checkuptest.NewConfig(nil), &out)
+	if err := o.RunWithClient(context.Background(), newClientset(objs, nil)); err == nil {
+		t.Fatal("RunWithClient() expected problems to be found")
+	}
+
+	for _, want := range []string{
+		"[node node-1, image example.com/app:1.0.0, age 3h, restarted 5m ago (recent)]",
+		"[image example.com/app:1.0.0, age 3h, restarted 120m ago]",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
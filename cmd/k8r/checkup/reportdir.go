@@ -0,0 +1,120 @@
+// Description: This file contains code for keeping the report of every
+// run in a directory, used with the report-dir flag, so that reports of
+// many clusters can be aggregated later, e.g. by k8r fleet
+
+package checkup
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+	"github.com/pkg/errors"
+)
+
+// Reports kept in the report directory are named
+// k8r-<cluster>-<time>.json.gz
+const (
+	reportFilePrefix = "k8r-"
+	reportFileExt    = ".json.gz"
+)
+
+// defaultReportRetention is how long reports are kept in the report
+// directory by default
+const defaultReportRetention = 30 * 24 * time.Hour
+
+// unsafeFileNameChars are the characters replaced in the cluster part of
+// a report's file name, e.g. the colon before the port of the API server
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// reportFileName returns the name of the file the report of a run against
+// the cluster at the given time is stored in
+func reportFileName(cluster string, at time.Time) string {
+	if cluster == "" {
+		cluster = "local"
+	}
+	cluster = strings.Trim(unsafeFileNameChars.ReplaceAllString(cluster, "_"), "_")
+	return reportFilePrefix + cluster + "-" + at.UTC().Format("20060102T150405Z") + reportFileExt
+}
+
+// isReportFile returns true if name is the name of a file reports are
+// stored in
+func isReportFile(name string) bool {
+	return strings.HasPrefix(name, reportFilePrefix) && strings.HasSuffix(name, reportFileExt)
+}
+
+// writeReportFile writes a report to a file as gzipped JSON
+func writeReportFile(path string, r *report.Report) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "failed to create report file")
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	if err := r.WriteJSON(gz); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return errors.Wrap(err, "failed to compress report")
+	}
+	return errors.Wrap(f.Close(), "failed to write report file")
+}
+
+// pruneReports deletes the reports in dir that were written longer than
+// retention ago, a retention of zero keeps every report
+func pruneReports(dir string, retention time.Duration, now time.Time) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read report directory")
+	}
+	for _, e := range entries {
+		if e.IsDir() || !isReportFile(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// Deleted by another run pruning the same directory
+			continue
+		}
+		if now.Sub(info.ModTime()) <= retention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to delete old report")
+		}
+	}
+	return nil
+}
+
+// storeReport writes the report of the run to the report directory, and
+// deletes the reports that are older than the retention
+func (o *Options) storeReport(r *Report, suppressed int) error {
+	if o.cfg.ReportDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(o.cfg.ReportDir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create report directory")
+	}
+
+	// Shards of the same cluster write their reports at the same time
+	cluster := o.cluster
+	if o.cfg.Shard != nil {
+		cluster += " shard " + o.cfg.Shard.String()
+	}
+
+	mr := o.machineReport(r, suppressed)
+	path := filepath.Join(o.cfg.ReportDir, reportFileName(cluster, mr.GeneratedAt))
+	if err := writeReportFile(path, mr); err != nil {
+		return err
+	}
+	return pruneReports(o.cfg.ReportDir, o.cfg.ReportRetention, time.Now())
+}
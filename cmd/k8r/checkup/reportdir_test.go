@@ -0,0 +1,84 @@
+package checkup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestReportDir(t *testing.T) {
+	dir := t.TempDir()
+
+	// Reports older than the retention are deleted, other files are left
+	old := time.Now().Add(-31 * 24 * time.Hour)
+	for _, name := range []string{"k8r-old-20200101T000000Z.json.gz", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.ReportDir = dir
+	cfg.ReportRetention = 30 * 24 * time.Hour
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	objs := []runtime.Object{checkuptest.NewPod("api", checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer))}
+	err := o.RunWithClient(context.Background(), newClientset(objs, nil))
+	if !errors.Is(err, checkup.ErrProblemsFound) {
+		t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || !strings.HasPrefix(names[0], "k8r-local-") || !strings.HasSuffix(names[0], ".json.gz") ||
+		names[1] != "notes.txt" {
+		t.Fatalf("report directory has %v, expected a new report and notes.txt", names)
+	}
+
+	f, err := os.Open(filepath.Join(dir, names[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	b, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := report.Read(b)
+	if err != nil {
+		t.Fatalf("report.Read() error = %v", err)
+	}
+	if len(r.Findings) != 1 || r.Findings[0].ProblemID != checkup.ProblemPodCrashLoopBackOff.ID {
+		t.Errorf("report findings = %v, expected the crash loop", r.Findings)
+	}
+}
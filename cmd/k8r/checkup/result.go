@@ -0,0 +1,131 @@
+// Description: This file contains code for writing a machine-readable
+// summary of a run, for CI steps that don't want to parse the report
+
+package checkup
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Result is the summary of a run written to the result-file flag
+type Result struct {
+	// ExitCode is the code the command exits with
+	ExitCode int `json:"exitCode"`
+
+	// Error is the error the command failed with, other than finding
+	// problems
+	Error string `json:"error,omitempty"`
+
+	// DurationSeconds is how long the run took
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	// Errors is the number of problems reported that aren't warnings
+	Errors int `json:"errors"`
+
+	// Warnings is the number of warnings reported
+	Warnings int `json:"warnings"`
+
+	// Critical is the number of errors reported that are critical
+	Critical int `json:"critical"`
+
+	// Info is the number of warnings reported that are only informational
+	Info int `json:"info"`
+
+	// Suppressed is the number of problems that were hidden as symptoms
+	// of other problems
+	Suppressed int `json:"suppressed"`
+
+	// SkippedChecks are the IDs of the problems that weren't checked,
+	// e.g. because they were disabled or permissions were missing
+	SkippedChecks []string `json:"skippedChecks"`
+
+	// Shard is the shard that was checked, results of every shard are
+	// merged to get the results of the whole cluster
+	Shard string `json:"shard,omitempty"`
+
+	// Incomplete are the resources that couldn't be listed, e.g.
+	// statefulsets.apps
+	Incomplete []string `json:"incomplete"`
+
+	// Detectors are how long each problem's detector took, slowest first
+	Detectors []DetectorMetrics `json:"detectors"`
+}
+
+// recordCounts records the number of problems reported for the result
+func (o *Options) recordCounts(reported []Resource, suppressed int) {
+	o.result.Errors, o.result.Warnings, o.result.Critical, o.result.Info = 0, 0, 0, 0
+	for i := range reported {
+		if reported[i].Warning {
+			o.result.Warnings++
+		} else {
+			o.result.Errors++
+		}
+		switch reported[i].GetSeverity() {
+		case SeverityCritical:
+			o.result.Critical++
+		case SeverityInfo:
+			o.result.Info++
+		}
+	}
+	o.result.Suppressed = suppressed
+}
+
+// runWithResult runs fn, writing its result to the result-file flag, and
+// exits non-zero if problems were found
+func (o *Options) runWithResult(fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if err := o.WriteResult(start, err); err != nil {
+		return err
+	}
+	return exitOnProblems(err)
+}
+
+// WriteResult writes the result of a run that started at start and
+// returned err to the result-file flag, if it was set
+func (o *Options) WriteResult(start time.Time, err error) error {
+	if o.cfg == nil || o.cfg.ResultFile == "" {
+		return nil
+	}
+
+	result := o.result
+	result.DurationSeconds = time.Since(start).Seconds()
+	switch {
+	case errors.Is(err, ErrCriticalProblemsFound):
+		result.ExitCode = criticalExitCode
+	case errors.Is(err, ErrProblemsFound):
+		result.ExitCode = 1
+	case err != nil:
+		result.ExitCode = 1
+		result.Error = err.Error()
+	}
+
+	result.SkippedChecks = make([]string, 0)
+	for id, disabled := range o.cfg.DisabledProblems {
+		if disabled {
+			result.SkippedChecks = append(result.SkippedChecks, id)
+		}
+	}
+	sort.Strings(result.SkippedChecks)
+
+	if o.cfg.Shard != nil {
+		result.Shard = o.cfg.Shard.String()
+	}
+	result.Detectors = o.detectorMetrics()
+
+	result.Incomplete = make([]string, 0)
+	for _, f := range o.incomplete() {
+		result.Incomplete = append(result.Incomplete, f.String())
+	}
+
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode result")
+	}
+	return errors.Wrap(os.WriteFile(o.cfg.ResultFile, append(b, '\n'), 0o600), "failed to write result file")
+}
@@ -0,0 +1,65 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWriteResult(t *testing.T) {
+	objs := []runtime.Object{
+		checkuptest.NewNode("node-1"),
+		checkuptest.NewNode("node-2", checkuptest.NodeNotReady("kubelet stopped posting node status")),
+		checkuptest.NewPod("web", checkuptest.OnNode("node-1"), checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+		checkuptest.NewPod("cache", checkuptest.OnNode("node-2"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+		checkuptest.NewHPA("web", 5, 5),
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.ResultFile = filepath.Join(t.TempDir(), "result.json")
+	cfg.DisabledProblems = map[string]bool{"HighRestarts": true}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	start := time.Now()
+	err := o.RunWithClient(context.Background(), newClientset(objs, []string{"list secrets"}))
+	if err := o.WriteResult(start, err); err != nil {
+		t.Fatalf("WriteResult() error = %v", err)
+	}
+
+	b, err := os.ReadFile(cfg.ResultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got checkup.Result
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// The node being down is critical
+	if got.ExitCode != 2 || got.Error != "" {
+		t.Errorf("ExitCode, Error = %d, %q, expected 2 without an error", got.ExitCode, got.Error)
+	}
+	if got.Errors != 2 || got.Warnings != 1 || got.Suppressed != 2 || got.Critical != 1 {
+		t.Errorf("Errors, Warnings, Suppressed, Critical = %d, %d, %d, %d, expected 2, 1, 2, 1",
+			got.Errors, got.Warnings, got.Suppressed, got.Critical)
+	}
+	if want := []string{"HighRestarts", "SecretCloudCredentials", "SecretUnused"}; !reflect.DeepEqual(got.SkippedChecks, want) {
+		t.Errorf("SkippedChecks = %v, expected %v", got.SkippedChecks, want)
+	}
+	if got.DurationSeconds <= 0 {
+		t.Errorf("DurationSeconds = %v, expected it to be positive", got.DurationSeconds)
+	}
+}
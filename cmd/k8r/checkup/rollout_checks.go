@@ -0,0 +1,218 @@
+// Description: This file contains code for problems related to progressive
+// delivery with Argo Rollouts and Flagger
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Custom resources of Argo Rollouts and Flagger
+var (
+	// ArgoRollouts are Argo Rollouts, which replace Deployments with
+	// blue/green and canary strategies
+	ArgoRollouts = CustomResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts", Kind: "Rollout"}
+
+	// ArgoAnalysisRuns are runs of the analysis Argo Rollouts does during
+	// a rollout to decide whether to promote or abort it
+	ArgoAnalysisRuns = CustomResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "analysisruns", Kind: "AnalysisRun"}
+
+	// FlaggerCanaries are Flagger Canaries, which progressively shift
+	// traffic to a new version of a Deployment
+	FlaggerCanaries = CustomResource{Group: "flagger.app", Version: "v1beta1", Resource: "canaries", Kind: "Canary"}
+)
+
+// canaryFailureThreshold is how many failed analyses make a canary's
+// analysis count as failing repeatedly rather than being a one off
+const canaryFailureThreshold = 2
+
+// nestedTime returns the time at the given fields of an object, if set
+func nestedTime(obj map[string]interface{}, fields ...string) (time.Time, bool) {
+	s, ok, _ := unstructured.NestedString(obj, fields...)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// statusCondition returns the condition of the given type from the
+// status of an object, if it has one
+func statusCondition(obj *unstructured.Unstructured, conditionType string) (map[string]interface{}, bool) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if ok && cond["type"] == conditionType {
+			return cond, true
+		}
+	}
+	return nil, false
+}
+
+// rolloutPhase returns the phase of a Rollout and when it entered it,
+// the time is zero when it isn't known. Rollouts created by Argo Rollouts
+// versions before the phase was added are given one from their conditions.
+func rolloutPhase(rollout *unstructured.Unstructured) (phase string, since time.Time) {
+	phase, _, _ = unstructured.NestedString(rollout.Object, "status", "phase")
+
+	pauses, _, _ := unstructured.NestedSlice(rollout.Object, "status", "pauseConditions")
+	progressing, hasProgressing := statusCondition(rollout, "Progressing")
+	if phase == "" {
+		switch {
+		case hasProgressing && progressing["reason"] == "ProgressDeadlineExceeded":
+			phase = "Degraded"
+		case len(pauses) != 0:
+			phase = "Paused"
+		}
+	}
+
+	switch phase {
+	case "Paused":
+		// The rollout has been paused since its earliest pause
+		for _, p := range pauses {
+			pause, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if t, ok := nestedTime(pause, "startTime"); ok && (since.IsZero() || t.Before(since)) {
+				since = t
+			}
+		}
+	case "Degraded":
+		if hasProgressing {
+			since, _ = nestedTime(progressing, "lastTransitionTime")
+		}
+	}
+	return phase, since
+}
+
+// failedAnalysisRuns returns the failed AnalysisRuns of a Rollout, newest
+// first
+func failedAnalysisRuns(rollout *unstructured.Unstructured, analysisRuns []unstructured.Unstructured) []*unstructured.Unstructured {
+	failed := make([]*unstructured.Unstructured, 0)
+	for i := range analysisRuns {
+		run := &analysisRuns[i]
+		if run.GetNamespace() != rollout.GetNamespace() {
+			continue
+		}
+
+		owned := false
+		for _, ref := range run.GetOwnerReferences() {
+			if ref.Kind == ArgoRollouts.Kind && ref.Name == rollout.GetName() {
+				owned = true
+			}
+		}
+		phase, _, _ := unstructured.NestedString(run.Object, "status", "phase")
+		if owned && (phase == "Failed" || phase == "Error") {
+			failed = append(failed, run)
+		}
+	}
+
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[j].GetCreationTimestamp().Time.Before(failed[i].GetCreationTimestamp().Time)
+	})
+	return failed
+}
+
+// analysisRunMessage returns why an AnalysisRun failed, preferring the
+// message of the run and falling back to the first failed metric
+func analysisRunMessage(run *unstructured.Unstructured) string {
+	if msg, _, _ := unstructured.NestedString(run.Object, "status", "message"); msg != "" {
+		return msg
+	}
+
+	metrics, _, _ := unstructured.NestedSlice(run.Object, "status", "metricResults")
+	for _, m := range metrics {
+		metric, ok := m.(map[string]interface{})
+		if !ok || (metric["phase"] != "Failed" && metric["phase"] != "Error") {
+			continue
+		}
+		if msg, _, _ := unstructured.NestedString(metric, "message"); msg != "" {
+			return fmt.Sprintf("metric %v: %s", metric["name"], msg)
+		}
+		return fmt.Sprintf("metric %v %v", metric["name"], metric["phase"])
+	}
+	return "no message"
+}
+
+// ProblemRolloutStuck is a problem with an Argo Rollout that has been
+// degraded or paused for longer than the rollout-stuck-threshold flag,
+// leaving the new version partially rolled out
+// https://github.com/Ashvin-Ranjan/k8r/wiki/RolloutStuck
+var ProblemRolloutStuck = Problem{
+	ID:               "RolloutStuck",
+	ShortDescription: "A Rollout has been degraded or paused for too long",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/RolloutStuck",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		rollout, ok := obj.(*unstructured.Unstructured)
+		if !ok || rollout.GetKind() != ArgoRollouts.Kind {
+			return "", false, false
+		}
+
+		phase, since := rolloutPhase(rollout)
+		if phase != "Degraded" && phase != "Paused" {
+			return "", false, false
+		}
+		if since.IsZero() || time.Since(since) < cfg.RolloutStuckThreshold {
+			return "", false, false
+		}
+
+		details := fmt.Sprintf("Rollout has been %s for %s", phase, time.Since(since).Round(time.Minute))
+		if msg, _, _ := unstructured.NestedString(rollout.Object, "status", "message"); msg != "" {
+			details += ": " + msg
+		}
+
+		// Paused rollouts may be waiting on a manual promotion on purpose
+		return details, phase == "Paused", true
+	},
+}
+
+// ProblemCanaryAnalysisFailing is a problem with an Argo Rollout or a
+// Flagger Canary whose analysis has failed repeatedly, usually because the
+// new version is unhealthy or the metrics the analysis queries are broken
+// https://github.com/Ashvin-Ranjan/k8r/wiki/CanaryAnalysisFailing
+var ProblemCanaryAnalysisFailing = Problem{
+	ID:               "CanaryAnalysisFailing",
+	ShortDescription: "A canary's analysis has failed repeatedly",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/CanaryAnalysisFailing",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return "", false, false
+		}
+
+		switch u.GetKind() {
+		case ArgoRollouts.Kind:
+			if cfg.Cluster == nil {
+				return "", false, false
+			}
+			failed := failedAnalysisRuns(u, cfg.Cluster.AnalysisRuns)
+			if len(failed) < canaryFailureThreshold {
+				return "", false, false
+			}
+			return fmt.Sprintf("Rollout has %d failed analysis runs, the latest (%s) failed with: %s",
+				len(failed), failed[0].GetName(), analysisRunMessage(failed[0])), false, true
+		case FlaggerCanaries.Kind:
+			phase, _, _ := unstructured.NestedString(u.Object, "status", "phase")
+			failedChecks, _, _ := unstructured.NestedInt64(u.Object, "status", "failedChecks")
+			if phase != "Failed" && failedChecks < canaryFailureThreshold {
+				return "", false, false
+			}
+
+			msg := "no message"
+			if cond, ok := statusCondition(u, "Promoted"); ok {
+				if m, _, _ := unstructured.NestedString(cond, "message"); m != "" {
+					msg = m
+				}
+			}
+			return fmt.Sprintf("Canary is %s with %d failed checks: %s", phase, failedChecks, msg), false, true
+		}
+		return "", false, false
+	},
+}
@@ -0,0 +1,64 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestRolloutStuck(t *testing.T) {
+	now := time.Now()
+
+	checkuptest.RunCases(t, checkup.ProblemRolloutStuck, []checkuptest.Case{
+		{Name: "healthy", Object: checkuptest.NewRollout("web", "Healthy", now.Add(-24*time.Hour), "")},
+		{Name: "recently degraded", Object: checkuptest.NewRollout("web", "Degraded", now.Add(-10*time.Minute), "")},
+		{
+			Name:           "degraded",
+			Object:         checkuptest.NewRollout("web", "Degraded", now.Add(-3*time.Hour), "ProgressDeadlineExceeded: ReplicaSet web-7d9f has timed out progressing."),
+			Occurring:      true,
+			DetailsContain: "Rollout has been Degraded for 3h0m0s: ProgressDeadlineExceeded",
+		},
+		{
+			Name:           "paused",
+			Object:         checkuptest.NewRollout("web", "Paused", now.Add(-2*time.Hour), "CanaryPauseStep"),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Rollout has been Paused for 2h0m0s",
+		},
+		{Name: "not a Rollout", Object: checkuptest.NewCanary("web", "Failed", 3, "")},
+	})
+}
+
+func TestCanaryAnalysisFailing(t *testing.T) {
+	now := time.Now()
+	rollout := checkuptest.NewRollout("web", "Degraded", now, "")
+	older := checkuptest.NewAnalysisRun("web-1", "web", "Failed", "metric error-rate assessed Failed", now.Add(-time.Hour))
+	newer := checkuptest.NewAnalysisRun("web-2", "web", "Failed", "metric latency assessed Failed", now)
+	succeeded := checkuptest.NewAnalysisRun("web-3", "web", "Successful", "", now)
+	other := checkuptest.NewAnalysisRun("api-1", "api", "Failed", "", now)
+
+	checkuptest.RunCases(t, checkup.ProblemCanaryAnalysisFailing, []checkuptest.Case{
+		{
+			Name:   "one failed analysis run",
+			Object: rollout,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(rollout, newer, succeeded, other)),
+		},
+		{
+			Name:           "repeatedly failed analysis runs",
+			Object:         rollout,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(rollout, older, newer, succeeded)),
+			Occurring:      true,
+			DetailsContain: "Rollout has 2 failed analysis runs, the latest (web-2) failed with: metric latency assessed Failed",
+		},
+		{Name: "canary progressing", Object: checkuptest.NewCanary("web", "Progressing", 1, "")},
+		{
+			Name:           "canary failed",
+			Object:         checkuptest.NewCanary("web", "Failed", 5, "Canary analysis failed, Deployment scaled to zero."),
+			Occurring:      true,
+			DetailsContain: "Canary is Failed with 5 failed checks: Canary analysis failed",
+		},
+		{Name: "not a canary", Object: checkuptest.NewNode("node-1")},
+	})
+}
@@ -0,0 +1,186 @@
+// Description: This file contains code for ranking the most likely root
+// causes of the problems found, so the report leads with the headline
+
+package checkup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxLikelyCauses is the number of likely root causes shown
+const maxLikelyCauses = 5
+
+// imagePullProblems are the problems of pods failing to pull images, used
+// to spot registry outages
+var imagePullProblems = map[string]bool{
+	ProblemPodImagePullBackOff.ID:      true,
+	ProblemLocalRegistryUnreachable.ID: true,
+}
+
+// likelyCause is a likely root cause of some of the problems found
+type likelyCause struct {
+	// Summary describes the cause
+	Summary string
+
+	// Affected is the number of problems the cause explains
+	Affected int
+}
+
+// splitResourceName splits the name of a namespaced resource in the
+// report into its namespace and name
+func splitResourceName(s string) (namespace, name string) {
+	if i := strings.Index(s, "/"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+// pluralize returns the noun for n things, e.g. 2 pods
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// likelyCauses ranks the most likely root causes of the problems, which
+// must have had their symptoms suppressed already. Causes are problems
+// that explain others, workloads with several failing pods, e.g. one
+// bad deploy, and registries several workloads fail to pull from. Only
+// causes that explain more than one problem are returned, most first.
+func likelyCauses(resources []Resource, c *Cluster) []likelyCause {
+	causes := make([]likelyCause, 0)
+
+	// Problems that explain problems with other resources, e.g. a node
+	// that is down
+	symptoms := make(map[string]int)
+	for i := range resources {
+		r := &resources[i]
+		if r.SuppressedBy != "" && !strings.HasSuffix(r.SuppressedBy, " on "+r.Name) {
+			symptoms[r.SuppressedBy]++
+		}
+	}
+	for i := range resources {
+		r := &resources[i]
+		if r.SuppressedBy != "" {
+			continue
+		}
+		if n := symptoms[fmt.Sprintf("%s on %s", r.ProblemID, r.Name)]; n > 0 {
+			causes = append(causes, likelyCause{
+				Summary:  fmt.Sprintf("%s on %s %s, explains %s", r.ProblemID, r.Type, r.Name, pluralize(n, "other problem")),
+				Affected: n + 1,
+			})
+		}
+	}
+	if c == nil {
+		return rankCauses(causes)
+	}
+
+	// Workloads with several failing pods, e.g. one bad deploy, and
+	// registries that several workloads fail to pull from
+	workloadPods := make(map[string]map[string]bool)
+	workloadProblems := make(map[string]map[string]bool)
+	registryPods := make(map[string]map[string]bool)
+	registryWorkloads := make(map[string]map[string]bool)
+	for i := range resources {
+		r := &resources[i]
+		if r.Type != "pod" || r.SuppressedBy != "" || r.Warning {
+			continue
+		}
+		pod := c.GetPod(splitResourceName(r.Name))
+		if pod == nil {
+			continue
+		}
+
+		kind, name := rootOwner(pod, "Pod", c)
+		if kind == "Pod" {
+			continue
+		}
+		workload := fmt.Sprintf("%s %s/%s", kind, pod.Namespace, name)
+		if workloadPods[workload] == nil {
+			workloadPods[workload] = make(map[string]bool)
+			workloadProblems[workload] = make(map[string]bool)
+		}
+		workloadPods[workload][r.Name] = true
+		workloadProblems[workload][r.ProblemID] = true
+
+		if !imagePullProblems[r.ProblemID] {
+			continue
+		}
+		for _, f := range imagePullFailures(pod) {
+			registry := imageRegistry(f.Container.Image)
+			if registry == "" {
+				registry = "docker.io"
+			}
+			if registryPods[registry] == nil {
+				registryPods[registry] = make(map[string]bool)
+				registryWorkloads[registry] = make(map[string]bool)
+			}
+			registryPods[registry][r.Name] = true
+			registryWorkloads[registry][workload] = true
+		}
+	}
+
+	for workload, pods := range workloadPods {
+		if len(pods) < 2 {
+			continue
+		}
+		causes = append(causes, likelyCause{
+			Summary: fmt.Sprintf("%s has %s with problems (%s), check its latest rollout",
+				workload, pluralize(len(pods), "pod"), strings.Join(sortedKeys(workloadProblems[workload]), ", ")),
+			Affected: len(pods),
+		})
+	}
+	for registry, pods := range registryPods {
+		if len(registryWorkloads[registry]) < 2 {
+			continue
+		}
+		causes = append(causes, likelyCause{
+			Summary: fmt.Sprintf("registry %s, %s of %s can't pull images from it",
+				registry, pluralize(len(pods), "pod"), pluralize(len(registryWorkloads[registry]), "workload")),
+			Affected: len(pods),
+		})
+	}
+
+	return rankCauses(causes)
+}
+
+// rankCauses sorts causes by the number of problems they explain and
+// keeps the top maxLikelyCauses
+func rankCauses(causes []likelyCause) []likelyCause {
+	sort.Slice(causes, func(i, j int) bool {
+		if causes[i].Affected != causes[j].Affected {
+			return causes[i].Affected > causes[j].Affected
+		}
+		return causes[i].Summary < causes[j].Summary
+	})
+	if len(causes) > maxLikelyCauses {
+		causes = causes[:maxLikelyCauses]
+	}
+	return causes
+}
+
+// sortedKeys returns the keys of a set, sorted
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printLikelyCauses prints the likely root causes, if there are any
+func (o *Options) printLikelyCauses(causes []likelyCause) {
+	if len(causes) == 0 {
+		return
+	}
+
+	fmt.Fprintln(o.out, "")
+	bold.Fprintln(o.out, "🔎  Likely root causes:")
+	for i := range causes {
+		fmt.Fprintf(o.out, "    %d. %s\n", i+1, causes[i].Summary)
+	}
+}
@@ -0,0 +1,58 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestLikelyRootCauses(t *testing.T) {
+	color.NoColor = true
+
+	deployment := func(name, hash string) []checkuptest.PodOption {
+		return []checkuptest.PodOption{
+			checkuptest.OwnedBy("ReplicaSet", name+"-"+hash),
+			checkuptest.WithLabels(map[string]string{"pod-template-hash": hash}),
+		}
+	}
+	crashing := checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)
+	pulling := checkuptest.ImagePullBackOff(checkuptest.DefaultContainer)
+	objs := []runtime.Object{
+		checkuptest.NewNode("node-1"),
+		checkuptest.NewNode("node-2", checkuptest.NodeNotReady("kubelet stopped posting node status")),
+		checkuptest.NewPod("web-a", append(deployment("web", "7d9f"), crashing)...),
+		checkuptest.NewPod("web-b", append(deployment("web", "7d9f"), crashing)...),
+		checkuptest.NewPod("web-c", append(deployment("web", "7d9f"), crashing)...),
+		checkuptest.NewPod("api-a", append(deployment("api", "5c4b"), pulling,
+			checkuptest.WithImage(checkuptest.DefaultContainer, "ghcr.io/org/api:1.0.0"))...),
+		checkuptest.NewPod("worker-a", append(deployment("worker", "9a8e"), pulling,
+			checkuptest.WithImage(checkuptest.DefaultContainer, "ghcr.io/org/worker:1.0.0"))...),
+		checkuptest.NewPod("cache", checkuptest.OnNode("node-2"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+		checkuptest.NewPod("db", checkuptest.OnNode("node-2"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+	}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(checkuptest.NewConfig(nil), &out)
+	if err := o.RunWithClient(context.Background(), newClientset(objs, nil)); err == nil {
+		t.Fatal("RunWithClient() error = nil, expected problems")
+	}
+
+	want := strings.Join([]string{
+		"🔎  Likely root causes:",
+		"    1. Deployment default/web has 3 pods with problems (PodCrashLoopBackOff), check its latest rollout",
+		"    2. NodeNotReady on node node-2, explains 2 other problems",
+		"    3. registry ghcr.io, 2 pods of 2 workloads can't pull images from it",
+		"",
+	}, "\n")
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output = %s\nexpected it to contain:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,205 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// scanCases are the fixture clusters scanned by TestScan, the golden
+// files for each are named after the case
+var scanCases = []struct {
+	name    string
+	objs    []runtime.Object
+	denied  []string
+	groupBy string
+	healthy bool
+}{
+	{
+		name: "healthy",
+		objs: []runtime.Object{
+			checkuptest.NewNamespace(checkuptest.DefaultNamespace, map[string]string{"reporting_team": "platform"}),
+			checkuptest.NewNode("node-1"),
+			checkuptest.NewPod("web", checkuptest.OnNode("node-1")),
+			checkuptest.NewService("web", map[string]string{"app": "web"}),
+		},
+		healthy: true,
+	},
+	{
+		name: "broken",
+		objs: []runtime.Object{
+			checkuptest.NewNamespace(checkuptest.DefaultNamespace, map[string]string{"reporting_team": "platform"}),
+			checkuptest.NewNode("node-1"),
+			checkuptest.NewNode("node-2", checkuptest.NodeNotReady("kubelet stopped posting node status")),
+			checkuptest.NewPod("web", checkuptest.OnNode("node-1"), checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("worker", checkuptest.OnNode("node-1"), checkuptest.ImagePullBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("cache", checkuptest.OnNode("node-2"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+			checkuptest.NewHPA("web", 5, 5),
+		},
+	},
+	{
+		name: "broken-by-node",
+		objs: []runtime.Object{
+			checkuptest.NewNamespace(checkuptest.DefaultNamespace, map[string]string{"reporting_team": "platform"}),
+			checkuptest.NewNode("node-1"),
+			checkuptest.NewNode("node-2"),
+			checkuptest.NewPod("web", checkuptest.OnNode("node-1"), checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("worker", checkuptest.OnNode("node-1"), checkuptest.ImagePullBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("api", checkuptest.OnNode("node-1"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("cache", checkuptest.OnNode("node-2"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("queued", checkuptest.Pending("")),
+			checkuptest.NewHPA("web", 5, 5),
+		},
+		groupBy: checkup.GroupByNode,
+	},
+	{
+		name: "broken-by-namespace",
+		objs: []runtime.Object{
+			checkuptest.NewNamespace(checkuptest.DefaultNamespace, map[string]string{"reporting_team": "platform"}),
+			checkuptest.NewNamespace("payments", map[string]string{"reporting_team": "payments"}),
+			checkuptest.NewNode("node-1"),
+			checkuptest.NewPod("web", checkuptest.OnNode("node-1"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("api", checkuptest.InNamespace("payments"), checkuptest.OnNode("node-1"), checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewPod("worker", checkuptest.InNamespace("payments"), checkuptest.OnNode("node-1"), checkuptest.ImagePullBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewHPA("web", 5, 5),
+			// Only a critical problem is cluster-scoped
+			checkuptest.NewNode("node-2", checkuptest.NodeNotReady("kubelet stopped posting node status")),
+		},
+		groupBy: checkup.GroupByNamespace,
+	},
+	{
+		name: "forbidden",
+		objs: []runtime.Object{
+			checkuptest.NewNode("node-1"),
+			checkuptest.NewPod("web", checkuptest.OnNode("node-1"), checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			checkuptest.NewStatefulSet("db", "db", map[string]string{"app": "db"}),
+			checkuptest.NewSecret("unused", map[string]string{"key": "value"}),
+		},
+		denied: []string{"list services", "list secrets", "get /readyz/etcd"},
+	},
+}
+
+// newClientset returns a fake clientset containing the objects, where the
+// user has every permission other than the denied ones
+func newClientset(objs []runtime.Object, denied []string) *fake.Clientset {
+	k := fake.NewSimpleClientset(objs...)
+	k.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+
+		var p checkup.Permission
+		if attrs := review.Spec.NonResourceAttributes; attrs != nil {
+			p = checkup.Permission{Verb: attrs.Verb, NonResourceURL: attrs.Path}
+		} else {
+			attrs := review.Spec.ResourceAttributes
+			p = checkup.Permission{Verb: attrs.Verb, Group: attrs.Group, Resource: attrs.Resource, Subresource: attrs.Subresource}
+		}
+
+		review = review.DeepCopy()
+		review.Status.Allowed = true
+		for _, d := range denied {
+			if p.String() == d {
+				review.Status.Allowed = false
+			}
+		}
+		return true, review, nil
+	})
+	return k
+}
+
+// TestScan runs a full scan against fake clusters and compares the
+// report and the problems found against golden files
+func TestScan(t *testing.T) {
+	color.NoColor = true
+
+	for _, tc := range scanCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			newConfig := func() *checkup.Config {
+				cfg := checkuptest.NewConfig(nil)
+				cfg.GroupBy = tc.groupBy
+				return cfg
+			}
+
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(newConfig(), &out)
+
+			k := newClientset(tc.objs, tc.denied)
+			resources, err := o.Scan(context.Background(), k)
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			b, err := json.MarshalIndent(sortResources(resources), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, tc.name+".json", append(b, '\n'))
+
+			out.Reset()
+			o.Configure(newConfig(), &out)
+			err = o.RunWithClient(context.Background(), newClientset(tc.objs, tc.denied))
+			if tc.healthy && err != nil {
+				t.Fatalf("RunWithClient() error = %v, expected none", err)
+			}
+			if !tc.healthy && !errors.Is(err, checkup.ErrProblemsFound) {
+				t.Fatalf("RunWithClient() error = %v, expected %v", err, checkup.ErrProblemsFound)
+			}
+			compareGolden(t, tc.name+".txt", out.Bytes())
+		})
+	}
+}
+
+// sortResources sorts resources by name and problem, the fake clientset
+// lists objects in no particular order
+func sortResources(resources []checkup.Resource) []checkup.Resource {
+	sort.SliceStable(resources, func(i, j int) bool {
+		if resources[i].Name != resources[j].Name {
+			return resources[i].Name < resources[j].Name
+		}
+		return resources[i].ProblemID < resources[j].ProblemID
+	})
+	return resources
+}
+
+// compareGolden compares got against the golden file with the given
+// name, the file is written instead when -update is passed
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run go test with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s (run go test with -update if this is expected)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
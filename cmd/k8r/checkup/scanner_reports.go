@@ -0,0 +1,251 @@
+// Description: This file contains code for importing the reports other
+// scanners leave in the cluster, Kyverno and Polaris PolicyReports and
+// Trivy Operator VulnerabilityReports, as findings of their own
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Custom resources of the scanners whose reports are imported
+var (
+	// PolicyReports are the namespaced reports of the Kubernetes Policy
+	// WG format that Kyverno writes, and that Polaris results are
+	// converted to by its PolicyReport adapter
+	PolicyReports = CustomResource{Group: "wgpolicyk8s.io", Version: "v1alpha2", Resource: "policyreports", Kind: "PolicyReport"}
+
+	// ClusterPolicyReports are PolicyReports for cluster scoped resources
+	ClusterPolicyReports = CustomResource{Group: "wgpolicyk8s.io", Version: "v1alpha2", Resource: "clusterpolicyreports", Kind: "ClusterPolicyReport"}
+
+	// TrivyVulnerabilityReports are the reports Trivy Operator writes for
+	// the image of each container of a workload
+	TrivyVulnerabilityReports = CustomResource{Group: "aquasecurity.github.io", Version: "v1alpha1", Resource: "vulnerabilityreports", Kind: "VulnerabilityReport"}
+)
+
+// maxScannerExamples is the number of policy results or vulnerabilities
+// listed in a finding
+const maxScannerExamples = 3
+
+// policyResult is a result in a PolicyReport that didn't pass
+type policyResult struct {
+	// Policy and Rule are the policy and the rule of it that was checked
+	Policy, Rule string
+
+	// Result is fail or warn
+	Result string
+
+	// Severity is the severity the policy gave the result, if any
+	Severity string
+
+	// Message is why the result didn't pass
+	Message string
+
+	// Resources are the kind and namespace/name of the resources the
+	// result is for
+	Resources []string
+}
+
+// String returns the result as it is shown in findings
+func (r *policyResult) String() string {
+	s := r.Policy
+	if r.Rule != "" && r.Rule != r.Policy {
+		s += "/" + r.Rule
+	}
+	if len(r.Resources) != 0 {
+		s += " on " + strings.Join(r.Resources, ", ")
+	}
+	if r.Message != "" {
+		s += ": " + r.Message
+	}
+	return s
+}
+
+// reportResource returns a resource referenced by a report as its kind
+// and namespace/name, e.g. Deployment web/api
+func reportResource(ref map[string]interface{}) string {
+	kind, _, _ := unstructured.NestedString(ref, "kind")
+	name, _, _ := unstructured.NestedString(ref, "name")
+	if ns, _, _ := unstructured.NestedString(ref, "namespace"); ns != "" {
+		name = ns + "/" + name
+	}
+	return strings.TrimSpace(kind + " " + name)
+}
+
+// failedPolicyResults returns the results of a PolicyReport from the given
+// source, e.g. kyverno, that failed or warned. Results without a source
+// are attributed to the tool that manages the report.
+func failedPolicyResults(report *unstructured.Unstructured, source string) []policyResult {
+	// The scope is the resource the whole report is for, newer versions of
+	// Kyverno write a report per resource and leave it out of the results
+	var scope []string
+	if ref, ok, _ := unstructured.NestedMap(report.Object, "scope"); ok {
+		scope = []string{reportResource(ref)}
+	}
+
+	results, _, _ := unstructured.NestedSlice(report.Object, "results")
+	failed := make([]policyResult, 0)
+	for _, r := range results {
+		result, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		resultSource, _, _ := unstructured.NestedString(result, "source")
+		if resultSource == "" {
+			resultSource = report.GetLabels()["app.kubernetes.io/managed-by"]
+		}
+		if !strings.EqualFold(resultSource, source) {
+			continue
+		}
+
+		status, _, _ := unstructured.NestedString(result, "result")
+		if status != "fail" && status != "warn" {
+			continue
+		}
+
+		pr := policyResult{Result: status, Resources: scope}
+		pr.Policy, _, _ = unstructured.NestedString(result, "policy")
+		pr.Rule, _, _ = unstructured.NestedString(result, "rule")
+		pr.Severity, _, _ = unstructured.NestedString(result, "severity")
+		pr.Message, _, _ = unstructured.NestedString(result, "message")
+		if refs, ok, _ := unstructured.NestedSlice(result, "resources"); ok && len(refs) != 0 {
+			pr.Resources = make([]string, 0, len(refs))
+			for _, ref := range refs {
+				if m, ok := ref.(map[string]interface{}); ok {
+					pr.Resources = append(pr.Resources, reportResource(m))
+				}
+			}
+		}
+		failed = append(failed, pr)
+	}
+	return failed
+}
+
+// policyReportDetector returns a detector for the failed results of a
+// PolicyReport from the given source. Findings are errors when a result
+// failed with a high or critical severity, and warnings otherwise.
+func policyReportDetector(source, tool string) func(context.Context, runtime.Object, *Config) (string, bool, bool) {
+	return func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || (u.GetKind() != PolicyReports.Kind && u.GetKind() != ClusterPolicyReports.Kind) {
+			return "", false, false
+		}
+		failed := failedPolicyResults(u, source)
+		if len(failed) == 0 {
+			return "", false, false
+		}
+
+		warning := true
+		examples := make([]string, 0, maxScannerExamples)
+		for i := range failed {
+			if failed[i].Result == "fail" && (failed[i].Severity == "high" || failed[i].Severity == "critical") {
+				warning = false
+			}
+			if i < maxScannerExamples {
+				examples = append(examples, failed[i].String())
+			}
+		}
+		if len(failed) > maxScannerExamples {
+			examples = append(examples, fmt.Sprintf("and %d more", len(failed)-maxScannerExamples))
+		}
+		return fmt.Sprintf("%s reported %d policy result(s) that didn't pass: %s",
+			tool, len(failed), strings.Join(examples, "; ")), warning, true
+	}
+}
+
+// ProblemKyvernoPolicyFailed is a problem with a PolicyReport that has
+// Kyverno policy results that failed or warned
+// https://github.com/Ashvin-Ranjan/k8r/wiki/KyvernoPolicyFailed
+var ProblemKyvernoPolicyFailed = Problem{
+	ID:               "KyvernoPolicyFailed",
+	ShortDescription: "Kyverno reports resources that fail its policies",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/KyvernoPolicyFailed",
+	Detector:         policyReportDetector("kyverno", "Kyverno"),
+}
+
+// ProblemPolarisCheckFailed is a problem with a PolicyReport that has
+// Polaris check results that failed or warned
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PolarisCheckFailed
+var ProblemPolarisCheckFailed = Problem{
+	ID:               "PolarisCheckFailed",
+	ShortDescription: "Polaris reports resources that fail its checks",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PolarisCheckFailed",
+	Detector:         policyReportDetector("polaris", "Polaris"),
+}
+
+// vulnerabilityReportTarget returns the container and workload a Trivy
+// Operator VulnerabilityReport is for, from its labels
+func vulnerabilityReportTarget(report *unstructured.Unstructured) string {
+	l := report.GetLabels()
+	target := fmt.Sprintf("%s %s/%s", l["trivy-operator.resource.kind"], l["trivy-operator.resource.namespace"], l["trivy-operator.resource.name"])
+	if l["trivy-operator.resource.kind"] == "" {
+		target = report.GetNamespace() + "/" + report.GetName()
+	}
+	if container := l["trivy-operator.container.name"]; container != "" {
+		target = fmt.Sprintf("container %s of %s", container, target)
+	}
+	return target
+}
+
+// ProblemTrivyVulnerabilities is a problem with a Trivy Operator
+// VulnerabilityReport that found critical or high vulnerabilities in an
+// image. It is an error when any of them are critical.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/TrivyVulnerabilities
+var ProblemTrivyVulnerabilities = Problem{
+	ID:               "TrivyVulnerabilities",
+	ShortDescription: "Trivy found critical or high vulnerabilities in an image",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/TrivyVulnerabilities",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || u.GetKind() != TrivyVulnerabilityReports.Kind {
+			return "", false, false
+		}
+		critical, _, _ := unstructured.NestedInt64(u.Object, "report", "summary", "criticalCount")
+		high, _, _ := unstructured.NestedInt64(u.Object, "report", "summary", "highCount")
+		if critical == 0 && high == 0 {
+			return "", false, false
+		}
+
+		image, _, _ := unstructured.NestedString(u.Object, "report", "artifact", "repository")
+		if tag, _, _ := unstructured.NestedString(u.Object, "report", "artifact", "tag"); tag != "" {
+			image += ":" + tag
+		}
+		if registry, _, _ := unstructured.NestedString(u.Object, "report", "registry", "server"); registry != "" {
+			image = registry + "/" + image
+		}
+
+		vulnerabilities, _, _ := unstructured.NestedSlice(u.Object, "report", "vulnerabilities")
+		examples := make([]string, 0, maxScannerExamples)
+		for _, severity := range []string{"CRITICAL", "HIGH"} {
+			for _, v := range vulnerabilities {
+				vuln, ok := v.(map[string]interface{})
+				if !ok || len(examples) == maxScannerExamples {
+					continue
+				}
+				if s, _, _ := unstructured.NestedString(vuln, "severity"); s != severity {
+					continue
+				}
+				id, _, _ := unstructured.NestedString(vuln, "vulnerabilityID")
+				pkg, _, _ := unstructured.NestedString(vuln, "resource")
+				example := fmt.Sprintf("%s in %s", id, pkg)
+				if fixed, _, _ := unstructured.NestedString(vuln, "fixedVersion"); fixed != "" {
+					example += " (fixed in " + fixed + ")"
+				}
+				examples = append(examples, example)
+			}
+		}
+
+		details := fmt.Sprintf("Trivy found %d critical and %d high vulnerabilities in image %s of %s",
+			critical, high, image, vulnerabilityReportTarget(u))
+		if len(examples) != 0 {
+			details += ": " + strings.Join(examples, ", ")
+		}
+		return details, critical == 0, true
+	},
+}
@@ -0,0 +1,109 @@
+package checkup_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// policyReport returns a PolicyReport with a result for each of the given
+// source, result and severity triples
+func policyReport(results ...[3]string) *unstructured.Unstructured {
+	items := make([]interface{}, 0, len(results))
+	for _, r := range results {
+		items = append(items, map[string]interface{}{
+			"source": r[0], "result": r[1], "severity": r[2],
+			"policy": "require-requests", "rule": "check-cpu", "message": "CPU requests are required",
+			"resources": []interface{}{
+				map[string]interface{}{"kind": "Deployment", "namespace": checkuptest.DefaultNamespace, "name": "api"},
+			},
+		})
+	}
+	u := checkuptest.NewCustomResource(&checkup.PolicyReports, "polr-api", nil, nil)
+	u.Object["results"] = items
+	return u
+}
+
+func TestPolicyReports(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemKyvernoPolicyFailed, []checkuptest.Case{
+		{Name: "passed", Object: policyReport([3]string{"kyverno", "pass", "medium"})},
+		{Name: "other source", Object: policyReport([3]string{"polaris", "fail", "high"})},
+		{
+			Name:           "failed",
+			Object:         policyReport([3]string{"kyverno", "fail", "medium"}, [3]string{"kyverno", "pass", "high"}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Kyverno reported 1 policy result(s) that didn't pass: require-requests/check-cpu on Deployment default/api: CPU requests are required",
+		},
+		{
+			Name:           "failed with a high severity",
+			Object:         policyReport([3]string{"kyverno", "warn", "low"}, [3]string{"kyverno", "fail", "high"}),
+			Occurring:      true,
+			DetailsContain: "Kyverno reported 2 policy result(s)",
+		},
+	})
+
+	checkuptest.RunCases(t, checkup.ProblemPolarisCheckFailed, []checkuptest.Case{
+		{Name: "other source", Object: policyReport([3]string{"kyverno", "fail", "high"})},
+		{
+			Name:           "warned",
+			Object:         policyReport([3]string{"polaris", "warn", ""}),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Polaris reported 1 policy result(s)",
+		},
+	})
+}
+
+// vulnerabilityReport returns a Trivy Operator VulnerabilityReport for
+// container app of Deployment api with the given vulnerability severities
+func vulnerabilityReport(severities ...string) *unstructured.Unstructured {
+	counts := map[string]int64{}
+	vulns := make([]interface{}, 0, len(severities))
+	for i, s := range severities {
+		counts[s]++
+		vulns = append(vulns, map[string]interface{}{
+			"vulnerabilityID": fmt.Sprintf("CVE-2022-%04d", i+1), "severity": s,
+			"resource": "openssl", "fixedVersion": "3.0.7",
+		})
+	}
+	u := checkuptest.NewCustomResource(&checkup.TrivyVulnerabilityReports, "replicaset-api-app", nil, nil)
+	u.SetLabels(map[string]string{
+		"trivy-operator.resource.kind":      "ReplicaSet",
+		"trivy-operator.resource.namespace": checkuptest.DefaultNamespace,
+		"trivy-operator.resource.name":      "api-7f9c",
+		"trivy-operator.container.name":     "app",
+	})
+	u.Object["report"] = map[string]interface{}{
+		"artifact": map[string]interface{}{"repository": "library/nginx", "tag": "1.19"},
+		"registry": map[string]interface{}{"server": "index.docker.io"},
+		"summary": map[string]interface{}{
+			"criticalCount": counts["CRITICAL"], "highCount": counts["HIGH"], "mediumCount": counts["MEDIUM"],
+		},
+		"vulnerabilities": vulns,
+	}
+	return u
+}
+
+func TestTrivyVulnerabilities(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemTrivyVulnerabilities, []checkuptest.Case{
+		{Name: "only medium", Object: vulnerabilityReport("MEDIUM", "MEDIUM")},
+		{
+			Name:      "high",
+			Object:    vulnerabilityReport("MEDIUM", "HIGH"),
+			Occurring: true,
+			Warning:   true,
+			DetailsContain: "Trivy found 0 critical and 1 high vulnerabilities in image index.docker.io/library/nginx:1.19 " +
+				"of container app of ReplicaSet default/api-7f9c: CVE-2022-0002 in openssl (fixed in 3.0.7)",
+		},
+		{
+			Name:           "critical",
+			Object:         vulnerabilityReport("HIGH", "CRITICAL"),
+			Occurring:      true,
+			DetailsContain: "CVE-2022-0002 in openssl (fixed in 3.0.7), CVE-2022-0001 in openssl",
+		},
+	})
+}
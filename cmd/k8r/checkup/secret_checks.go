@@ -0,0 +1,186 @@
+// Description: This file contains code for problems related to Secrets
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// cloudCredentialPatterns are patterns matching long-lived cloud provider
+// credentials, keyed by a description of the credential
+var cloudCredentialPatterns = map[string]*regexp.Regexp{
+	"AWS access key":                 regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+	"GCP service account key":        regexp.MustCompile(`"type"\s*:\s*"service_account"`),
+	"Azure storage account key":      regexp.MustCompile(`AccountKey=[A-Za-z0-9+/=]{40,}`),
+	"Azure service principal secret": regexp.MustCompile(`"clientSecret"\s*:\s*"[^"]+"`),
+}
+
+// matchesNamespace returns true if the namespace matches any of the
+// given glob patterns
+func matchesNamespace(namespace string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, namespace); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// podSecretEnvVars returns the Secrets a pod consumes as environment variables
+func podSecretEnvVars(pod *corev1.Pod) []string {
+	secrets := make([]string, 0)
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for i := range containers {
+		c := &containers[i]
+		for j := range c.EnvFrom {
+			if c.EnvFrom[j].SecretRef != nil {
+				secrets = append(secrets, c.EnvFrom[j].SecretRef.Name)
+			}
+		}
+		for j := range c.Env {
+			if c.Env[j].ValueFrom != nil && c.Env[j].ValueFrom.SecretKeyRef != nil {
+				secrets = append(secrets, c.Env[j].ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	return secrets
+}
+
+// isSecretUsed returns true if the Secret is referenced by any pod or
+// ServiceAccount in the cluster
+func isSecretUsed(secret *corev1.Secret, c *Cluster) bool {
+	for i := range c.Pods {
+		pod := &c.Pods[i]
+		if pod.Namespace != secret.Namespace {
+			continue
+		}
+
+		_, secrets := podReferences(pod)
+		for _, ref := range pod.Spec.ImagePullSecrets {
+			secrets = append(secrets, ref.Name)
+		}
+		for _, name := range secrets {
+			if name == secret.Name {
+				return true
+			}
+		}
+	}
+
+	for i := range c.ServiceAccounts {
+		sa := &c.ServiceAccounts[i]
+		if sa.Namespace != secret.Namespace {
+			continue
+		}
+		for _, ref := range sa.Secrets {
+			if ref.Name == secret.Name {
+				return true
+			}
+		}
+		for _, ref := range sa.ImagePullSecrets {
+			if ref.Name == secret.Name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ProblemSecretAsEnvVar is a problem with a pod that consumes a Secret
+// as environment variables in a namespace where Secrets must be mounted
+// as files
+// https://github.com/Ashvin-Ranjan/k8r/wiki/SecretAsEnvVar
+var ProblemSecretAsEnvVar = Problem{
+	ID:               "SecretAsEnvVar",
+	ShortDescription: "A pod consumes a Secret as environment variables where Secrets must be mounted as files",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/SecretAsEnvVar",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !matchesNamespace(pod.Namespace, cfg.SecretFileMountNamespaces) {
+			return "", false, false
+		}
+
+		secrets := podSecretEnvVars(pod)
+		if len(secrets) == 0 {
+			return "", false, false
+		}
+
+		return fmt.Sprintf("Consumes Secret(s) %s as environment variables", strings.Join(secrets, ", ")), true, true
+	},
+}
+
+// ProblemSecretCloudCredentials is a problem with an Opaque Secret that
+// contains what looks like a long-lived cloud provider credential
+// https://github.com/Ashvin-Ranjan/k8r/wiki/SecretCloudCredentials
+var ProblemSecretCloudCredentials = Problem{
+	ID:               "SecretCloudCredentials",
+	ShortDescription: "A Secret contains what looks like a long-lived cloud provider credential",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/SecretCloudCredentials",
+	Detector: func(ctx context.Context, obj runtime.Object, _ *Config) (string, bool, bool) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Type != corev1.SecretTypeOpaque {
+			return "", false, false
+		}
+
+		// stringData is only ever set on manifests, the API server
+		// merges it into data
+		data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+		for key, value := range secret.Data {
+			data[key] = value
+		}
+		for key, value := range secret.StringData {
+			data[key] = []byte(value)
+		}
+
+		found := make([]string, 0)
+		for key, value := range data {
+			for kind, pattern := range cloudCredentialPatterns {
+				if pattern.Match(value) {
+					// Never include the value itself in the output
+					found = append(found, fmt.Sprintf("%s in key %s", kind, key))
+				}
+			}
+		}
+		if len(found) == 0 {
+			return "", false, false
+		}
+		sort.Strings(found)
+
+		return fmt.Sprintf("Looks like it contains a %s, prefer workload identity", strings.Join(found, ", ")), true, true
+	},
+}
+
+// ProblemSecretUnused is a problem with an Opaque Secret that isn't
+// referenced by any pod or ServiceAccount
+// https://github.com/Ashvin-Ranjan/k8r/wiki/SecretUnused
+var ProblemSecretUnused = Problem{
+	ID:               "SecretUnused",
+	ShortDescription: "A Secret is not used by any pod or ServiceAccount",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/SecretUnused",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		// Other types of Secrets are commonly consumed by things other
+		// than pods, e.g. TLS Secrets by ingress controllers
+		if secret.Type != corev1.SecretTypeOpaque {
+			return "", false, false
+		}
+
+		if isSecretUsed(secret, cfg.Cluster) {
+			return "", false, false
+		}
+
+		return "Not referenced by any pod or ServiceAccount", true, true
+	},
+}
@@ -0,0 +1,135 @@
+// Description: This file contains code for problems related to services
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// servicePortConflict returns a description of how the ports of two
+// Services that select the same pods disagree, if they do
+func servicePortConflict(a, b *corev1.Service) (string, bool) {
+	for i := range a.Spec.Ports {
+		ap := &a.Spec.Ports[i]
+		for j := range b.Spec.Ports {
+			bp := &b.Spec.Ports[j]
+			if ap.Port != bp.Port {
+				continue
+			}
+
+			if ap.Protocol != bp.Protocol {
+				return fmt.Sprintf("port %d uses %s while %s uses %s", ap.Port, ap.Protocol, b.Name, bp.Protocol), true
+			}
+			if ap.TargetPort.String() != bp.TargetPort.String() {
+				return fmt.Sprintf("port %d targets %s while %s targets %s",
+					ap.Port, ap.TargetPort.String(), b.Name, bp.TargetPort.String(),
+				), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ProblemServiceSelectorConflict is a problem with a Service that selects
+// some of the same pods as another Service but with conflicting session
+// affinity or port semantics, which splits traffic in surprising ways
+// https://github.com/Ashvin-Ranjan/k8r/wiki/ServiceSelectorConflict
+var ProblemServiceSelectorConflict = Problem{
+	ID:               "ServiceSelectorConflict",
+	ShortDescription: "A Service selects the same pods as another Service with conflicting session affinity or ports",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/ServiceSelectorConflict",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		svc, ok := obj.(*corev1.Service)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		// Count the pods the Service shares with each other Service from
+		// the Services that select each of its pods
+		overlap := make(map[*corev1.Service]int)
+		others := make([]*corev1.Service, 0)
+		for _, p := range cfg.Cluster.ServicePods(svc) {
+			for _, other := range cfg.Cluster.PodServices(p) {
+				if other.Name == svc.Name {
+					continue
+				}
+				if overlap[other] == 0 {
+					others = append(others, other)
+				}
+				overlap[other]++
+			}
+		}
+		sort.Slice(others, func(i, j int) bool {
+			return others[i].Name < others[j].Name
+		})
+
+		for _, other := range others {
+			if svc.Spec.SessionAffinity != other.Spec.SessionAffinity {
+				return fmt.Sprintf("Selects %d pod(s) also selected by %s, but uses session affinity %s while %s uses %s",
+					overlap[other], other.Name, svc.Spec.SessionAffinity, other.Name, other.Spec.SessionAffinity,
+				), true, true
+			}
+
+			if conflict, ok := servicePortConflict(svc, other); ok {
+				return fmt.Sprintf("Selects %d pod(s) also selected by %s, but %s", overlap[other], other.Name, conflict), true, true
+			}
+		}
+
+		return "", false, false
+	},
+}
+
+// ProblemServicePartialWorkload is a problem with a Service whose selector
+// only matches some of the pods of a workload, e.g. because a label is
+// only set on a subset of them
+// https://github.com/Ashvin-Ranjan/k8r/wiki/ServicePartialWorkload
+var ProblemServicePartialWorkload = Problem{
+	ID:               "ServicePartialWorkload",
+	ShortDescription: "A Service only selects some of the pods of a workload",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/ServicePartialWorkload",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		svc, ok := obj.(*corev1.Service)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		selected := make(map[string]struct{})
+		owners := make(map[types.UID]*metav1.OwnerReference)
+		for _, p := range cfg.Cluster.ServicePods(svc) {
+			selected[p.Name] = struct{}{}
+			if owner := metav1.GetControllerOf(p); owner != nil {
+				owners[owner.UID] = owner
+			}
+		}
+
+		// Compare the pods that were selected against all of the pods
+		// owned by the same controllers
+		for uid, owner := range owners {
+			total, matched := 0, 0
+			for _, p := range cfg.Cluster.ControlledPods(uid) {
+				if p.Namespace != svc.Namespace {
+					continue
+				}
+
+				total++
+				if _, ok := selected[p.Name]; ok {
+					matched++
+				}
+			}
+
+			if matched < total {
+				return fmt.Sprintf("Selects %d of %d pods owned by %s %s", matched, total, owner.Kind, owner.Name), true, true
+			}
+		}
+
+		return "", false, false
+	},
+}
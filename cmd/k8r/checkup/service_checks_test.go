@@ -0,0 +1,60 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+)
+
+func TestServiceSelectorConflict(t *testing.T) {
+	app := map[string]string{"app": "web"}
+	pod := checkuptest.NewPod("web-1", checkuptest.WithLabels(app))
+	svc := checkuptest.NewService("web", app)
+
+	checkuptest.RunCases(t, checkup.ProblemServiceSelectorConflict, []checkuptest.Case{
+		{Name: "alone", Object: svc, Config: checkuptest.NewConfig(checkuptest.NewCluster(svc, pod))},
+		{
+			Name:   "consistent",
+			Object: svc,
+			Config: checkuptest.NewConfig(checkuptest.NewCluster(svc, pod, checkuptest.NewService("web-internal", app))),
+		},
+		{
+			Name:           "session affinity",
+			Object:         svc,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(svc, pod, checkuptest.NewService("web-sticky", app, checkuptest.WithClientIPAffinity()))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "session affinity",
+		},
+		{
+			Name:           "target port",
+			Object:         svc,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(svc, pod, checkuptest.NewService("web-alt", app, checkuptest.WithTargetPort(9090)))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "port 80 targets 8080 while web-alt targets 9090",
+		},
+	})
+}
+
+func TestServicePartialWorkload(t *testing.T) {
+	canary := map[string]string{"app": "web", "track": "canary"}
+	a := checkuptest.NewPod("web-a", checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.WithLabels(canary))
+	b := checkuptest.NewPod("web-b", checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.WithLabels(map[string]string{"app": "web"}))
+
+	all := checkuptest.NewService("web", map[string]string{"app": "web"})
+	partial := checkuptest.NewService("web-canary", canary)
+
+	checkuptest.RunCases(t, checkup.ProblemServicePartialWorkload, []checkuptest.Case{
+		{Name: "whole workload", Object: all, Config: checkuptest.NewConfig(checkuptest.NewCluster(all, a, b))},
+		{
+			Name:           "part of workload",
+			Object:         partial,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(partial, a, b)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Selects 1 of 2 pods owned by ReplicaSet web",
+		},
+	})
+}
@@ -0,0 +1,165 @@
+// Description: This file contains code for the severities problems are
+// reported with and filtering the report by them
+
+package checkup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// severities are the severities from least to most severe
+var severities = []Severity{SeverityInfo, SeverityWarning, SeverityError, SeverityCritical}
+
+// severityNames are the names severities are shown and passed as flags with
+var severityNames = map[Severity]string{
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityError:    "error",
+	SeverityCritical: "critical",
+}
+
+// ParseSeverity parses the name of a severity, e.g. critical
+func ParseSeverity(s string) (Severity, error) {
+	names := make([]string, 0, len(severities))
+	for _, sev := range severities {
+		if strings.EqualFold(s, severityNames[sev]) {
+			return sev, nil
+		}
+		names = append(names, severityNames[sev])
+	}
+	return SeverityError, fmt.Errorf("invalid severity %q, expected one of %s", s, strings.Join(names, ", "))
+}
+
+// ParseSeverityOverride parses a value of the severity flag, in the format
+// problem=severity, e.g. NodeNotReady=critical
+func ParseSeverityOverride(s string) (string, Severity, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return "", SeverityError, fmt.Errorf("invalid --severity %q, expected problem=severity", s)
+	}
+
+	id := CanonicalProblemID(s[:i])
+	if !knownProblem(id) {
+		return "", SeverityError, fmt.Errorf("invalid --severity %q, unknown problem %s", s, id)
+	}
+	sev, err := ParseSeverity(s[i+1:])
+	if err != nil {
+		return "", SeverityError, err
+	}
+	return id, sev, nil
+}
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText marshals the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText unmarshals a severity from its name
+func (s *Severity) UnmarshalText(b []byte) error {
+	sev, err := ParseSeverity(string(b))
+	if err != nil {
+		return err
+	}
+	*s = sev
+	return nil
+}
+
+// rank orders severities from least to most severe, the values of the
+// constants aren't ordered as error was the original default
+func (s Severity) rank() int {
+	for i, sev := range severities {
+		if sev == s {
+			return i
+		}
+	}
+	return len(severities)
+}
+
+// AtLeast returns true if the severity is as or more severe than min
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+// colorFn returns the function problems of the severity are colored with
+func (s Severity) colorFn() func(string, ...interface{}) string {
+	switch s {
+	case SeverityCritical:
+		return color.New(color.Bold, color.FgHiWhite, color.BgRed).SprintfFunc()
+	case SeverityWarning:
+		return color.HiYellowString
+	case SeverityInfo:
+		return color.HiCyanString
+	}
+	return color.HiRedString
+}
+
+// GetSeverity returns the severity of the resource's problem, resources
+// without one are errors unless they are warnings
+func (r *Resource) GetSeverity() Severity {
+	switch {
+	case r.Severity != SeverityError:
+		return r.Severity
+	case r.Warning:
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+// problemSeverity returns the severity a problem is reported with, the
+// severity flag overrides the problem's own. Problems the detector
+// reported as warnings are at most warnings.
+func (c *Config) problemSeverity(p *Problem, warning bool) Severity {
+	sev := p.Severity
+	if override, ok := c.SeverityOverrides[p.ID]; ok {
+		sev = override
+	}
+	if warning && sev.AtLeast(SeverityWarning) {
+		return SeverityWarning
+	}
+	return sev
+}
+
+// filterBySeverity returns the resources whose problems are at least the
+// min-severity flag, and the number that were left out
+func (c *Config) filterBySeverity(resources []Resource) (filtered []Resource, below int) {
+	if c.MinSeverity == nil {
+		return resources, 0
+	}
+
+	filtered = make([]Resource, 0, len(resources))
+	for i := range resources {
+		if resources[i].GetSeverity().AtLeast(*c.MinSeverity) {
+			filtered = append(filtered, resources[i])
+		} else {
+			below++
+		}
+	}
+	return filtered, below
+}
+
+// problemsFoundError returns the error the commands exit with for the
+// problems that were reported, reports with only info problems succeed
+func problemsFoundError(reported []Resource) error {
+	var err error
+	for i := range reported {
+		switch reported[i].GetSeverity() {
+		case SeverityCritical:
+			return ErrCriticalProblemsFound
+		case SeverityInfo:
+		default:
+			err = ErrProblemsFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSeverities(t *testing.T) {
+	info, warning, critical := checkup.SeverityInfo, checkup.SeverityWarning, checkup.SeverityCritical
+	objs := []runtime.Object{
+		checkuptest.NewNode("node-1"),
+		checkuptest.NewPod("api", checkuptest.OnNode("node-1"), checkuptest.NotReady(checkuptest.DefaultContainer)),
+		checkuptest.NewPod("worker", checkuptest.OnNode("node-1"), checkuptest.Restarts(checkuptest.DefaultContainer, 5)),
+	}
+
+	tests := []struct {
+		name        string
+		objs        []runtime.Object
+		minSeverity *checkup.Severity
+		overrides   map[string]checkup.Severity
+		wantErr     error
+		want        []string
+		wantMissing []string
+	}{
+		{
+			name:    "defaults",
+			wantErr: checkup.ErrProblemsFound,
+			want:    []string{"PodNotReady", "HighRestarts"},
+		},
+		{
+			name:      "critical override",
+			overrides: map[string]checkup.Severity{"PodNotReady": critical},
+			wantErr:   checkup.ErrCriticalProblemsFound,
+			want:      []string{"PodNotReady"},
+		},
+		{
+			name:        "min severity",
+			minSeverity: &warning,
+			overrides:   map[string]checkup.Severity{"HighRestarts": info},
+			wantErr:     checkup.ErrProblemsFound,
+			want:        []string{"PodNotReady", "1 problems less severe than warning were left out"},
+			wantMissing: []string{"HighRestarts"},
+		},
+		{
+			// HighRestarts is merged into the pod's PodNotReady entry,
+			// which keeps it from being left out
+			name: "min severity after consolidating",
+			objs: []runtime.Object{
+				checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer), checkuptest.Restarts(checkuptest.DefaultContainer, 5)),
+			},
+			minSeverity: &warning,
+			overrides:   map[string]checkup.Severity{"PodNotReady": info},
+			wantErr:     checkup.ErrProblemsFound,
+			want:        []string{"PodNotReady", "HighRestarts"},
+			wantMissing: []string{"problems less severe than warning were left out"},
+		},
+		{
+			name:      "only info",
+			overrides: map[string]checkup.Severity{"PodNotReady": info, "HighRestarts": info},
+			want:      []string{"PodNotReady", "HighRestarts"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := checkuptest.NewConfig(nil)
+			cfg.MinSeverity = tt.minSeverity
+			cfg.SeverityOverrides = tt.overrides
+
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(cfg, &out)
+
+			clusterObjs := objs
+			if tt.objs != nil {
+				clusterObjs = tt.objs
+			}
+			err := o.RunWithClient(context.Background(), newClientset(clusterObjs, nil))
+			if err != tt.wantErr { //nolint:errorlint // Why: Checking the exact error
+				t.Errorf("RunWithClient() error = %v, expected %v", err, tt.wantErr)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("output = %q, expected it to contain %q", out.String(), s)
+				}
+			}
+			for _, s := range tt.wantMissing {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("output = %q, expected it not to contain %q", out.String(), s)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSeverityOverride(t *testing.T) {
+	id, sev, err := checkup.ParseSeverityOverride("NodeNotReady=Critical")
+	if err != nil || id != "NodeNotReady" || sev != checkup.SeverityCritical {
+		t.Errorf("ParseSeverityOverride() = %q, %v, %v, expected NodeNotReady, critical", id, sev, err)
+	}
+	for _, s := range []string{"NodeNotReady", "NotAProblem=info", "NodeNotReady=urgent"} {
+		if _, _, err := checkup.ParseSeverityOverride(s); err == nil {
+			t.Errorf("ParseSeverityOverride(%q) expected an error", s)
+		}
+	}
+}
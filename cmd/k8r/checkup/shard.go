@@ -0,0 +1,118 @@
+// Description: This file contains code for splitting a scan of a cluster
+// across multiple k8r instances by namespace, used with the shard flag
+
+package checkup
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Shard is the part of the cluster's namespaces an instance scans
+type Shard struct {
+	// Index is the shard the instance scans, starting at 1
+	Index int
+
+	// Count is the number of shards the namespaces are split into
+	Count int
+}
+
+// ParseShard parses the value of the shard flag, in the format
+// index/count, e.g. 2/5
+func ParseShard(s string) (*Shard, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid --shard %q, expected index/count, e.g. 2/5", s)
+	}
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid --shard %q, index must be a number", s)
+	}
+	count, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid --shard %q, count must be a number", s)
+	}
+	if count < 1 || index < 1 || index > count {
+		return nil, fmt.Errorf("invalid --shard %q, index must be between 1 and the count", s)
+	}
+
+	return &Shard{Index: index, Count: count}, nil
+}
+
+// String returns the shard in the format of the shard flag
+func (s *Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// owns returns true if resources in the namespace are scanned by the
+// shard. Namespaces are hashed so every instance agrees on the split
+// without talking to each other, cluster scoped resources, e.g. nodes,
+// are scanned by the first shard.
+func (s *Shard) owns(namespace string) bool {
+	if s == nil {
+		return true
+	}
+	if namespace == "" {
+		return s.Index == 1
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(namespace)) //nolint:errcheck // Why: Writing to a hash never fails
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+// namespaces returns the namespaces the shard lists namespaced resources
+// in, the ones it owns. The first shard also lists kube-system, which the
+// checks of the control plane and the distribution's addons look at.
+func (s *Shard) namespaces(all []corev1.Namespace) []string {
+	namespaces := make([]string, 0)
+	for i := range all {
+		ns := all[i].Name
+		if s.owns(ns) || (s.owns("") && ns == metav1.NamespaceSystem) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// podNodes returns the nodes the pods run on
+func podNodes(nodes []corev1.Node, pods []corev1.Pod) []corev1.Node {
+	running := make(map[string]bool)
+	for i := range pods {
+		running[pods[i].Spec.NodeName] = true
+	}
+
+	out := make([]corev1.Node, 0)
+	for i := range nodes {
+		if running[nodes[i].Name] {
+			out = append(out, nodes[i])
+		}
+	}
+	return out
+}
+
+// ownsObject returns true if the object is scanned by the shard,
+// namespaces belong to the shard that scans the resources in them
+func (s *Shard) ownsObject(obj runtime.Object) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return s.owns("")
+	}
+
+	namespace := accessor.GetNamespace()
+	if ns, ok := obj.(*corev1.Namespace); ok {
+		namespace = ns.Name
+	}
+	return s.owns(namespace)
+}
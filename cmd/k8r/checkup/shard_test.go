@@ -0,0 +1,138 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *checkup.Shard
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "2/5", want: &checkup.Shard{Index: 2, Count: 5}},
+		{value: "1/1", want: &checkup.Shard{Index: 1, Count: 1}},
+		{value: "0/5", wantErr: true},
+		{value: "6/5", wantErr: true},
+		{value: "2", wantErr: true},
+		{value: "a/5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := checkup.ParseShard(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseShard(%q) error = %v, expected error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ParseShard(%q) = %v, expected %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestShardsCoverCluster(t *testing.T) {
+	notReady := checkuptest.NotReady(checkuptest.DefaultContainer)
+	objs := []runtime.Object{checkuptest.NewNode("node-1", checkuptest.NodeNotReady("kubelet stopped posting node status"))}
+	for i := 0; i < 10; i++ {
+		ns := fmt.Sprintf("team-%d", i)
+		objs = append(objs, checkuptest.NewNamespace(ns, nil), checkuptest.NewPod("api", notReady, checkuptest.InNamespace(ns)))
+	}
+
+	scan := func(shard *checkup.Shard) []checkup.Resource {
+		cfg := checkuptest.NewConfig(nil)
+		cfg.Shard = shard
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+
+		resources, err := o.Scan(context.Background(), newClientset(objs, nil))
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if shard != nil && !strings.Contains(out.String(), "Checking shard "+shard.String()) {
+			t.Errorf("output = %q, expected it to name the shard", out.String())
+		}
+		return resources
+	}
+
+	want := make(map[string]bool)
+	for _, r := range scan(nil) {
+		want[r.Name+" "+r.ProblemID] = true
+	}
+
+	got := make(map[string]int)
+	for i := 1; i <= 3; i++ {
+		resources := scan(&checkup.Shard{Index: i, Count: 3})
+		if len(resources) == 0 {
+			t.Errorf("shard %d/3 found no problems, expected namespaces to be spread across shards", i)
+		}
+		for _, r := range resources {
+			got[r.Name+" "+r.ProblemID]++
+		}
+	}
+
+	for problem := range want {
+		if got[problem] != 1 {
+			t.Errorf("%s found by %d shards, expected exactly 1", problem, got[problem])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("shards found %d problems, expected %d", len(got), len(want))
+	}
+}
+
+func TestShardListsOwnNamespaces(t *testing.T) {
+	objs := []runtime.Object{checkuptest.NewNode("node-1")}
+	for i := 0; i < 10; i++ {
+		ns := fmt.Sprintf("team-%d", i)
+		objs = append(objs, checkuptest.NewNamespace(ns, nil), checkuptest.NewPod("api", checkuptest.InNamespace(ns)))
+	}
+
+	// listedBy is the shards that listed pods in each namespace
+	listedBy := make(map[string][]string)
+	for i := 1; i <= 3; i++ {
+		shard := &checkup.Shard{Index: i, Count: 3}
+		cfg := checkuptest.NewConfig(nil)
+		cfg.Shard = shard
+
+		var out bytes.Buffer
+		o := checkup.NewOptions(logrus.New())
+		o.Configure(cfg, &out)
+
+		k := newClientset(objs, nil)
+		if _, err := o.Scan(context.Background(), k); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		for _, action := range k.Actions() {
+			if action.GetVerb() != "list" {
+				continue
+			}
+			switch resource, ns := action.GetResource().Resource, action.GetNamespace(); {
+			case resource == "pods" && ns == "":
+				t.Errorf("shard %s listed pods in every namespace, expected only the namespaces it owns", shard)
+			case resource == "pods":
+				listedBy[ns] = append(listedBy[ns], shard.String())
+			case resource == "clusterroles" && i != 1:
+				t.Errorf("shard %s listed clusterroles, expected only shard 1 to list cluster scoped resources", shard)
+			}
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		ns := fmt.Sprintf("team-%d", i)
+		if len(listedBy[ns]) != 1 {
+			t.Errorf("pods in %s listed by shards %v, expected exactly 1", ns, listedBy[ns])
+		}
+	}
+}
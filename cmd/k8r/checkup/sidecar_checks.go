@@ -0,0 +1,310 @@
+// Description: This file contains code for problems with sidecar
+// containers, both native sidecars and conventional ones like istio-proxy
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// nativeSidecarHint is the workaround for conventional sidecars keeping
+// Job pods running that works for any sidecar
+const nativeSidecarHint = "run it as a native sidecar, an init container with restartPolicy: Always " +
+	"on Kubernetes 1.28+, so that it is stopped once the other containers exit"
+
+// sidecar is a well-known conventional sidecar, one that runs as a regular
+// container next to the app
+type sidecar struct {
+	// Names are the container names the sidecar is injected as
+	Names []string
+
+	// NotReadyHint is a hint for when the sidecar isn't ready
+	NotReadyHint string
+
+	// ShutdownHint is a hint for stopping the sidecar when the app exits
+	ShutdownHint string
+}
+
+// knownSidecars are the conventional sidecars that are recognized by name
+var knownSidecars = []sidecar{
+	{
+		Names:        []string{"istio-proxy"},
+		NotReadyHint: "check that istiod is reachable, and set holdApplicationUntilProxyStarts so the app waits for the proxy",
+		ShutdownHint: "enable Istio's native sidecars (ENABLE_NATIVE_SIDECARS) or have the app call " +
+			"curl -X POST localhost:15020/quitquitquit when it is done",
+	},
+	{
+		Names:        []string{"linkerd-proxy"},
+		NotReadyHint: "check that the linkerd control plane is reachable, and use linkerd-await so the app waits for the proxy",
+		ShutdownHint: "set config.alpha.linkerd.io/proxy-enable-native-sidecar or have the app run under " +
+			"linkerd-await --shutdown",
+	},
+	{
+		Names:        []string{"cloud-sql-proxy", "cloudsql-proxy"},
+		NotReadyHint: "check the proxy's logs for IAM or instance connection name errors",
+		ShutdownHint: "start the proxy with --quitquitquit and have the app call " +
+			"curl -X POST localhost:9091/quitquitquit when it is done",
+	},
+	{
+		Names:        []string{"vault-agent"},
+		NotReadyHint: "check the agent's logs for Vault authentication errors",
+		ShutdownHint: "set vault.hashicorp.com/agent-pre-populate-only so the agent only runs as an init container",
+	},
+}
+
+// knownSidecar returns the well-known sidecar a container is, if any
+func knownSidecar(name string) (*sidecar, bool) {
+	for i := range knownSidecars {
+		for _, n := range knownSidecars[i].Names {
+			if n == name {
+				return &knownSidecars[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// nativeSidecars returns the statuses of the native sidecars of a running
+// pod. client-go doesn't know about an init container's restartPolicy yet,
+// but classic init containers have all exited once the pod is running so
+// any init container that is still running is a native sidecar.
+func nativeSidecars(pod *corev1.Pod) []*corev1.ContainerStatus {
+	sidecars := make([]*corev1.ContainerStatus, 0)
+	if pod.Status.Phase != corev1.PodRunning {
+		return sidecars
+	}
+	for i := range pod.Status.InitContainerStatuses {
+		cs := &pod.Status.InitContainerStatuses[i]
+		if cs.State.Running != nil {
+			sidecars = append(sidecars, cs)
+		}
+	}
+	return sidecars
+}
+
+// appContainers returns the statuses of the containers of a pod that
+// aren't conventional sidecars
+func appContainers(pod *corev1.Pod) []*corev1.ContainerStatus {
+	apps := make([]*corev1.ContainerStatus, 0, len(pod.Status.ContainerStatuses))
+	for i := range pod.Status.ContainerStatuses {
+		if _, ok := knownSidecar(pod.Status.ContainerStatuses[i].Name); !ok {
+			apps = append(apps, &pod.Status.ContainerStatuses[i])
+		}
+	}
+	return apps
+}
+
+// anyReady returns true if any of the containers is ready
+func anyReady(statuses []*corev1.ContainerStatus) bool {
+	for _, cs := range statuses {
+		if cs.Ready {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemPodSidecarNotReady is a problem with a pod whose sidecar, native
+// or conventional, isn't ready while its app container is, so the app
+// can't reach anything through the sidecar
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodSidecarNotReady
+var ProblemPodSidecarNotReady = Problem{
+	ID:               "PodSidecarNotReady",
+	ShortDescription: "A pod's sidecar isn't ready while its app container is, e.g. istio-proxy not started",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodSidecarNotReady",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Status.Phase != corev1.PodRunning || !anyReady(appContainers(pod)) {
+			return "", false, false
+		}
+
+		for _, cs := range nativeSidecars(pod) {
+			if !cs.Ready {
+				return fmt.Sprintf("Native sidecar %s isn't ready while the app container is, check its readiness "+
+					"probe, native sidecars are restarted in place and don't block the pod from starting", cs.Name), false, true
+			}
+		}
+		for i := range pod.Status.ContainerStatuses {
+			cs := &pod.Status.ContainerStatuses[i]
+			s, ok := knownSidecar(cs.Name)
+			if ok && !cs.Ready {
+				return fmt.Sprintf("Sidecar %s isn't ready while the app container is, %s", cs.Name, s.NotReadyHint), false, true
+			}
+		}
+		return "", false, false
+	},
+}
+
+// blockingSidecars returns the conventional sidecars that keep a Job's
+// pod running after its other containers exited
+func blockingSidecars(pod *corev1.Pod) []*corev1.ContainerStatus {
+	if pod.Status.Phase != corev1.PodRunning || !ownedByKind(pod, "Job") {
+		return nil
+	}
+
+	apps := appContainers(pod)
+	if len(apps) == 0 {
+		return nil
+	}
+	for _, cs := range apps {
+		if cs.State.Terminated == nil {
+			return nil
+		}
+	}
+
+	sidecars := make([]*corev1.ContainerStatus, 0)
+	for i := range pod.Status.ContainerStatuses {
+		cs := &pod.Status.ContainerStatuses[i]
+		if _, ok := knownSidecar(cs.Name); ok && cs.State.Running != nil {
+			sidecars = append(sidecars, cs)
+		}
+	}
+	return sidecars
+}
+
+// ownedByKind returns true if the pod's controller is of the given kind
+func ownedByKind(pod *corev1.Pod, kind string) bool {
+	ref, ok := ownerRef(pod.OwnerReferences)
+	return ok && ref.Kind == kind
+}
+
+// ProblemPodSidecarBlocksJob is a problem with a Job's pod whose
+// containers exited but that keeps running because a conventional sidecar
+// doesn't exit with them, so the Job never completes
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodSidecarBlocksJob
+var ProblemPodSidecarBlocksJob = Problem{
+	ID:               "PodSidecarBlocksJob",
+	ShortDescription: "A Job's pod keeps running after its containers exited because a sidecar doesn't exit",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodSidecarBlocksJob",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return "", false, false
+		}
+
+		sidecars := blockingSidecars(pod)
+		if len(sidecars) == 0 {
+			return "", false, false
+		}
+
+		exited := make([]string, 0)
+		for _, cs := range appContainers(pod) {
+			exited = append(exited, cs.Name)
+		}
+		names := make([]string, 0, len(sidecars))
+		for _, cs := range sidecars {
+			names = append(names, cs.Name)
+		}
+
+		hint := nativeSidecarHint
+		if s, ok := knownSidecar(sidecars[0].Name); ok {
+			hint = s.ShutdownHint + ", or " + nativeSidecarHint
+		}
+		return fmt.Sprintf("Containers %s exited but sidecar %s keeps the pod running so the Job never completes, %s",
+			strings.Join(exited, ", "), strings.Join(names, ", "), hint), false, true
+	},
+}
+
+// jobPods returns the pods a Job created
+func jobPods(job *batchv1.Job, pods []corev1.Pod) []*corev1.Pod {
+	owned := make([]*corev1.Pod, 0)
+	for i := range pods {
+		p := &pods[i]
+		if p.Namespace != job.Namespace {
+			continue
+		}
+		if ref, ok := ownerRef(p.OwnerReferences); ok && ref.Kind == "Job" && ref.Name == job.Name {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// ProblemJobBlockedBySidecar is a problem with a Job whose main containers
+// exited but whose pod stays running because a long-lived sidecar doesn't
+// exit, which wastes the node and hides whether the Job failed
+// https://github.com/Ashvin-Ranjan/k8r/wiki/JobBlockedBySidecar
+var ProblemJobBlockedBySidecar = Problem{
+	ID:               "JobBlockedBySidecar",
+	ShortDescription: "A Job never finishes because a sidecar keeps its pod running after the main container exited",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/JobBlockedBySidecar",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		job, ok := obj.(*batchv1.Job)
+		if !ok || cfg.Cluster == nil || jobFinished(job) {
+			return "", false, false
+		}
+
+		for _, p := range jobPods(job, cfg.Cluster.Pods) {
+			sidecars := blockingSidecars(p)
+			if len(sidecars) == 0 {
+				continue
+			}
+
+			// The last container to exit is when the Job should have
+			// finished
+			var finished time.Time
+			failed := make([]string, 0)
+			for _, cs := range appContainers(p) {
+				t := cs.State.Terminated
+				if t.FinishedAt.After(finished) {
+					finished = t.FinishedAt.Time
+				}
+				if t.ExitCode != 0 {
+					failed = append(failed, fmt.Sprintf("%s (exit code %d)", cs.Name, t.ExitCode))
+				}
+			}
+
+			outcome := "exited successfully"
+			if len(failed) != 0 {
+				outcome = "failed, " + strings.Join(failed, ", ") + ","
+			}
+			details := fmt.Sprintf("Pod %s %s", p.Name, outcome)
+			if !finished.IsZero() {
+				details += fmt.Sprintf(" %s ago", time.Since(finished).Round(time.Minute))
+			}
+			consequence := "so the Job never finishes and its node stays in use"
+			if len(failed) != 0 {
+				consequence = "so the Job never finishes, its node stays in use and the failure isn't reported"
+			}
+			details += fmt.Sprintf(" but sidecar %s is holding it open, %s", sidecars[0].Name, consequence)
+			if ref, ok := ownerRef(job.OwnerReferences); ok && ref.Kind == "CronJob" {
+				details += fmt.Sprintf(", CronJob %s won't notice its runs fail", ref.Name)
+			}
+
+			hint := nativeSidecarHint
+			if s, ok := knownSidecar(sidecars[0].Name); ok {
+				hint = s.ShutdownHint + ", or " + nativeSidecarHint
+			}
+			return details + ", " + hint, false, true
+		}
+		return "", false, false
+	},
+}
+
+// jobPod returns true if the symptom was found on a pod the Job the cause
+// was found on created
+func jobPod(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "Job" || symptom.Type != "pod" || c == nil {
+		return false
+	}
+
+	for i := range c.Jobs {
+		job := &c.Jobs[i]
+		if fmt.Sprintf("%s/%s", job.Namespace, job.Name) != cause.Name {
+			continue
+		}
+		for _, p := range jobPods(job, c.Pods) {
+			if fmt.Sprintf("%s/%s", p.Namespace, p.Name) == symptom.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// withSidecar adds a conventional sidecar container to a pod
+func withSidecar(name string, ready bool, state corev1.ContainerState) checkuptest.PodOption {
+	return func(p *corev1.Pod) {
+		p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: name, Image: "example.com/" + name + ":1.0.0"})
+		p.Status.ContainerStatuses = append(p.Status.ContainerStatuses, corev1.ContainerStatus{Name: name, Ready: ready, State: state})
+	}
+}
+
+// withNativeSidecar adds a running native sidecar init container to a pod
+func withNativeSidecar(name string, ready bool) checkuptest.PodOption {
+	return func(p *corev1.Pod) {
+		p.Spec.InitContainers = append(p.Spec.InitContainers, corev1.Container{Name: name, Image: "example.com/" + name + ":1.0.0"})
+		p.Status.InitContainerStatuses = append(p.Status.InitContainerStatuses, corev1.ContainerStatus{
+			Name: name, Ready: ready, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+	}
+}
+
+// exited marks the app container of a pod as exited with the exit code
+func exited(code int32) checkuptest.PodOption {
+	return func(p *corev1.Pod) {
+		for i := range p.Status.ContainerStatuses {
+			cs := &p.Status.ContainerStatuses[i]
+			if cs.Name == checkuptest.DefaultContainer {
+				cs.Ready = false
+				cs.State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code}}
+			}
+		}
+	}
+}
+
+var running = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+
+func TestPodSidecarNotReady(t *testing.T) {
+	checkuptest.RunCases(t, checkup.ProblemPodSidecarNotReady, []checkuptest.Case{
+		{Name: "no sidecar", Object: checkuptest.NewPod("api")},
+		{Name: "sidecar ready", Object: checkuptest.NewPod("api", withSidecar("istio-proxy", true, running))},
+		{
+			Name:           "istio-proxy not ready",
+			Object:         checkuptest.NewPod("api", withSidecar("istio-proxy", false, running)),
+			Occurring:      true,
+			DetailsContain: "Sidecar istio-proxy isn't ready while the app container is, check that istiod is reachable",
+		},
+		{
+			Name:           "native sidecar not ready",
+			Object:         checkuptest.NewPod("api", withNativeSidecar("log-shipper", false)),
+			Occurring:      true,
+			DetailsContain: "Native sidecar log-shipper isn't ready while the app container is",
+		},
+		{
+			Name:   "app not ready either",
+			Object: checkuptest.NewPod("api", checkuptest.NotReady(checkuptest.DefaultContainer), withSidecar("istio-proxy", false, running)),
+		},
+		{Name: "unknown container not ready", Object: checkuptest.NewPod("api", withSidecar("worker", false, running))},
+	})
+}
+
+func TestPodSidecarBlocksJob(t *testing.T) {
+	job := checkuptest.OwnedBy("Job", "migrate")
+
+	checkuptest.RunCases(t, checkup.ProblemPodSidecarBlocksJob, []checkuptest.Case{
+		{Name: "app running", Object: checkuptest.NewPod("migrate-x", job, withSidecar("istio-proxy", true, running))},
+		{Name: "not a Job", Object: checkuptest.NewPod("api", exited(0), withSidecar("istio-proxy", true, running))},
+		{Name: "no sidecar", Object: checkuptest.NewPod("migrate-x", job, exited(0))},
+		{
+			Name:           "istio-proxy keeps it running",
+			Object:         checkuptest.NewPod("migrate-x", job, exited(0), withSidecar("istio-proxy", true, running)),
+			Occurring:      true,
+			DetailsContain: "Containers app exited but sidecar istio-proxy keeps the pod running so the Job never completes, enable Istio's native sidecars",
+		},
+		{
+			Name:           "cloud-sql-proxy keeps it running",
+			Object:         checkuptest.NewPod("migrate-x", job, exited(1), withSidecar("cloud-sql-proxy", true, running)),
+			Occurring:      true,
+			DetailsContain: "start the proxy with --quitquitquit",
+		},
+	})
+}
+
+func TestJobBlockedBySidecar(t *testing.T) {
+	owned := checkuptest.OwnedBy("Job", "migrate")
+	cron := checkuptest.NewJob("nightly-28000000", 6)
+	cron.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "nightly"}}
+
+	cases := []struct {
+		name string
+		job  *batchv1.Job
+		pods []runtime.Object
+		want string
+	}{
+		{
+			name: "app running",
+			job:  checkuptest.NewJob("migrate", 6),
+			pods: []runtime.Object{checkuptest.NewPod("migrate-x", owned, withSidecar("istio-proxy", true, running))},
+		},
+		{
+			name: "succeeded but held open",
+			job:  checkuptest.NewJob("migrate", 6),
+			pods: []runtime.Object{checkuptest.NewPod("migrate-x", owned, exited(0), withSidecar("istio-proxy", true, running))},
+			want: "Pod migrate-x exited successfully but sidecar istio-proxy is holding it open, so the Job never finishes",
+		},
+		{
+			name: "failure hidden from CronJob",
+			job:  cron,
+			pods: []runtime.Object{checkuptest.NewPod("nightly-28000000-x", checkuptest.OwnedBy("Job", "nightly-28000000"),
+				exited(2), withSidecar("cloud-sql-proxy", true, running))},
+			want: "Pod nightly-28000000-x failed, app (exit code 2), but sidecar cloud-sql-proxy is holding it open, " +
+				"so the Job never finishes, its node stays in use and the failure isn't reported, CronJob nightly won't notice its runs fail",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		cfg := checkuptest.NewConfig(checkuptest.NewCluster(append(tc.pods, tc.job)...))
+		checkuptest.RunCases(t, checkup.ProblemJobBlockedBySidecar, []checkuptest.Case{
+			{Name: tc.name, Object: tc.job, Config: cfg, Occurring: tc.want != "", DetailsContain: tc.want},
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Description: This file contains code for problems related to spot
+// and preemptible nodes
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// spotNodeLabels are the well-known node labels that cloud providers and
+// node provisioners use to mark spot/preemptible nodes, mapped to the
+// value that means the node is spot
+var spotNodeLabels = map[string]string{
+	"eks.amazonaws.com/capacityType":        "SPOT",
+	"karpenter.sh/capacity-type":            "spot",
+	"cloud.google.com/gke-spot":             "true",
+	"cloud.google.com/gke-preemptible":      "true",
+	"kubernetes.azure.com/scalesetpriority": "spot",
+	"node.kubernetes.io/lifecycle":          "spot",
+	"lifecycle":                             "Ec2Spot",
+	"cloud.google.com/gke-provisioning":     "spot",
+	"alpha.eksctl.io/instance-lifecycle":    "spot",
+}
+
+// spotReclamationReasons are the node event reasons that node termination
+// handlers and provisioners emit when a spot node is being reclaimed
+var spotReclamationReasons = map[string]bool{
+	"SpotInterruption":        true,
+	"SpotInterrupted":         true,
+	"PreemptScheduled":        true,
+	"RebalanceRecommendation": true,
+}
+
+// spotCorrelationWindow is how soon after a spot reclamation a pod has to
+// have been created for it to be considered a replacement
+const spotCorrelationWindow = 10 * time.Minute
+
+// isSpotValue returns true if the value of a spot label marks a node as spot
+func isSpotValue(key, value string) bool {
+	want, ok := spotNodeLabels[key]
+	return ok && strings.EqualFold(want, value)
+}
+
+// isSpotNode returns true if the node has one of the well-known spot labels
+func isSpotNode(node *corev1.Node) bool {
+	for k, v := range node.Labels {
+		if isSpotValue(k, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPodPinnedToSpot returns true if the pod's node selector or required
+// node affinity only allows it to be scheduled on spot nodes
+func isPodPinnedToSpot(pod *corev1.Pod) bool {
+	for k, v := range pod.Spec.NodeSelector {
+		if isSpotValue(k, v) {
+			return true
+		}
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+
+	// Terms are ORed, so every one of them has to require spot
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return false
+	}
+	for i := range terms {
+		requiresSpot := false
+		for _, expr := range terms[i].MatchExpressions {
+			if expr.Operator != corev1.NodeSelectorOpIn || len(expr.Values) == 0 {
+				continue
+			}
+
+			allSpot := true
+			for _, v := range expr.Values {
+				allSpot = allSpot && isSpotValue(expr.Key, v)
+			}
+			if allSpot {
+				requiresSpot = true
+				break
+			}
+		}
+		if !requiresSpot {
+			return false
+		}
+	}
+	return true
+}
+
+// podSiblings returns all of the pods that share the pod's controller,
+// including the pod itself
+func podSiblings(pod *corev1.Pod, c *Cluster) []*corev1.Pod {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return []*corev1.Pod{pod}
+	}
+
+	siblings := make([]*corev1.Pod, 0)
+	for i := range c.Pods {
+		p := &c.Pods[i]
+		if ref := metav1.GetControllerOf(p); ref != nil && ref.UID == owner.UID {
+			siblings = append(siblings, p)
+		}
+	}
+	return siblings
+}
+
+// hasPodDisruptionBudget returns true if a PodDisruptionBudget selects the pod
+func hasPodDisruptionBudget(pod *corev1.Pod, c *Cluster) bool {
+	for i := range c.PodDisruptionBudgets {
+		pdb := &c.PodDisruptionBudgets[i]
+		if pdb.Namespace != pod.Namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+
+		sel, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || sel.Empty() {
+			continue
+		}
+		if sel.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// recentSpotReclamation returns the spot reclamation event that the pod
+// was most likely created to replace
+func recentSpotReclamation(pod *corev1.Pod, c *Cluster) (*corev1.Event, bool) {
+	created := pod.CreationTimestamp.Time
+	for i := range c.NodeEvents {
+		e := &c.NodeEvents[i]
+		if !spotReclamationReasons[e.Reason] {
+			continue
+		}
+
+		at := e.LastTimestamp.Time
+		if at.IsZero() {
+			at = e.EventTime.Time
+		}
+		if !at.After(created) && created.Sub(at) <= spotCorrelationWindow {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// ProblemPodSpotOnly is a problem with a workload that only runs on spot
+// or preemptible nodes without anything protecting it from those nodes
+// being reclaimed
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodSpotOnly
+var ProblemPodSpotOnly = Problem{
+	ID:               "PodSpotOnly",
+	ShortDescription: "A workload only runs on spot nodes with a single replica or no PodDisruptionBudget, so node reclamation takes it down",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodSpotOnly",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil || !isPodActive(pod) {
+			return "", false, false
+		}
+
+		nodes := make(map[string]*corev1.Node)
+		for i := range cfg.Cluster.Nodes {
+			nodes[cfg.Cluster.Nodes[i].Name] = &cfg.Cluster.Nodes[i]
+		}
+
+		siblings := podSiblings(pod, cfg.Cluster)
+		pinned := isPodPinnedToSpot(pod)
+
+		// Pods that aren't pinned to spot may still only have landed on
+		// spot nodes, e.g. when spot is the only capacity available
+		if !pinned {
+			if pod.Spec.NodeName == "" {
+				return "", false, false
+			}
+			for _, p := range siblings {
+				node, ok := nodes[p.Spec.NodeName]
+				if !ok || !isSpotNode(node) {
+					return "", false, false
+				}
+			}
+		}
+
+		reasons := make([]string, 0)
+
+		// Replicas can only be counted for running pods, manifests only
+		// contain the pod template
+		if pod.Spec.NodeName != "" && len(siblings) < 2 {
+			reasons = append(reasons, "has a single replica")
+		}
+		if !hasPodDisruptionBudget(pod, cfg.Cluster) {
+			reasons = append(reasons, "has no PodDisruptionBudget")
+		}
+		if len(reasons) == 0 {
+			return "", false, false
+		}
+
+		where := "runs only on spot nodes"
+		if pinned {
+			where = "is pinned to spot nodes"
+		}
+		details := fmt.Sprintf("Pod %s and %s", where, strings.Join(reasons, " and "))
+
+		if e, ok := recentSpotReclamation(pod, cfg.Cluster); ok {
+			details += fmt.Sprintf(", it was likely rescheduled after node %s was reclaimed (%s)", e.InvolvedObject.Name, e.Reason)
+		}
+
+		return details, true, true
+	},
+}
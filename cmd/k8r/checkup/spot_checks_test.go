@@ -0,0 +1,67 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodSpotOnly(t *testing.T) {
+	app := map[string]string{"app": "web"}
+	spot := checkuptest.NewNode("spot-1", checkuptest.NodeLabels(map[string]string{"karpenter.sh/capacity-type": "spot"}))
+	onDemand := checkuptest.NewNode("on-demand-1")
+
+	single := checkuptest.NewPod("web-1", checkuptest.WithLabels(app), checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.OnNode(spot.Name))
+	second := checkuptest.NewPod("web-2", checkuptest.WithLabels(app), checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.OnNode(spot.Name))
+	mixed := checkuptest.NewPod("web-2", checkuptest.WithLabels(app), checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.OnNode(onDemand.Name))
+	pdb := checkuptest.NewPodDisruptionBudget("web", app)
+
+	pinned := checkuptest.NewPod("web-1", checkuptest.WithLabels(app), checkuptest.WithSpec(func(spec *corev1.PodSpec) {
+		spec.NodeSelector = map[string]string{"cloud.google.com/gke-spot": "true"}
+	}))
+
+	replaced := checkuptest.NewPod("web-1", checkuptest.WithLabels(app), checkuptest.OwnedBy("ReplicaSet", "web"), checkuptest.OnNode(spot.Name))
+	replaced.CreationTimestamp = metav1.Now()
+	reclaimed := checkuptest.NewNodeEvent("spot-0", "SpotInterruption", metav1.NewTime(time.Now().Add(-2*time.Minute)))
+
+	checkuptest.RunCases(t, checkup.ProblemPodSpotOnly, []checkuptest.Case{
+		{Name: "on demand", Object: checkuptest.NewPod("web-1", checkuptest.OnNode(onDemand.Name))},
+		{
+			Name:           "single replica",
+			Object:         single,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(single, spot, pdb)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "runs only on spot nodes and has a single replica",
+		},
+		{
+			Name:           "no pdb",
+			Object:         single,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(single, second, spot)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "has no PodDisruptionBudget",
+		},
+		{Name: "protected", Object: single, Config: checkuptest.NewConfig(checkuptest.NewCluster(single, second, spot, pdb))},
+		{Name: "mixed nodes", Object: single, Config: checkuptest.NewConfig(checkuptest.NewCluster(single, mixed, spot, onDemand))},
+		{
+			Name:           "pinned manifest",
+			Object:         pinned,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "is pinned to spot nodes and has no PodDisruptionBudget",
+		},
+		{
+			Name:           "replaced after reclamation",
+			Object:         replaced,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(replaced, spot, reclaimed)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "node spot-0 was reclaimed (SpotInterruption)",
+		},
+	})
+}
@@ -0,0 +1,148 @@
+// Description: This file contains code for problems related to StatefulSets
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ProblemStatefulSetServiceInvalid is a problem with a StatefulSet whose
+// serviceName doesn't point to a headless Service selecting its pods,
+// which breaks the stable DNS names of its pods
+// https://github.com/Ashvin-Ranjan/k8r/wiki/StatefulSetServiceInvalid
+var ProblemStatefulSetServiceInvalid = Problem{
+	ID:               "StatefulSetServiceInvalid",
+	ShortDescription: "A StatefulSet's serviceName doesn't point to a headless Service that selects its pods",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/StatefulSetServiceInvalid",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		sts, ok := obj.(*appsv1.StatefulSet)
+		if !ok || cfg.Cluster == nil {
+			return "", false, false
+		}
+
+		if sts.Spec.ServiceName == "" {
+			return "serviceName is not set", false, true
+		}
+
+		svc, ok := cfg.Cluster.GetService(sts.Namespace, sts.Spec.ServiceName)
+		if !ok {
+			return fmt.Sprintf("Service %s does not exist", sts.Spec.ServiceName), false, true
+		}
+
+		if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+			return fmt.Sprintf("Service %s is not headless, its clusterIP must be None", svc.Name), false, true
+		}
+
+		if len(svc.Spec.Selector) == 0 ||
+			!labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(sts.Spec.Template.Labels)) {
+			return fmt.Sprintf("Service %s does not select the StatefulSet's pods", svc.Name), false, true
+		}
+
+		return "", false, false
+	},
+}
+
+// statefulSetPods returns the pods owned by a StatefulSet
+func statefulSetPods(sts *appsv1.StatefulSet, pods []corev1.Pod) []*corev1.Pod {
+	owned := make([]*corev1.Pod, 0)
+	for i := range pods {
+		p := &pods[i]
+		if p.Namespace != sts.Namespace {
+			continue
+		}
+		if ref, ok := ownerRef(p.OwnerReferences); ok && ref.Kind == "StatefulSet" && ref.Name == sts.Name {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// podNotReadySince returns when a pod stopped being ready, or when it was
+// created if it never was
+func podNotReadySince(p *corev1.Pod) time.Time {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status != corev1.ConditionTrue && !c.LastTransitionTime.IsZero() {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return p.CreationTimestamp.Time
+}
+
+// ProblemStatefulSetReplicasNotReady is a problem with a StatefulSet that
+// has had fewer ready replicas than it wants for longer than the grace
+// period. How long is worked out from its pods: when the not ready ones
+// stopped being ready, or when the newest pod was created if pods are
+// missing, as pods are created in order.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/StatefulSetReplicasNotReady
+var ProblemStatefulSetReplicasNotReady = Problem{
+	ID:               "StatefulSetReplicasNotReady",
+	ShortDescription: "A StatefulSet has had fewer ready replicas than it wants for a long time",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/StatefulSetReplicasNotReady",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		// StatefulSets the controller hasn't seen yet, e.g. in manifests,
+		// have no status to compare against
+		sts, ok := obj.(*appsv1.StatefulSet)
+		if !ok || cfg.Cluster == nil || sts.Status.ObservedGeneration == 0 {
+			return "", false, false
+		}
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		if sts.Status.ReadyReplicas >= replicas {
+			return "", false, false
+		}
+
+		pods := statefulSetPods(sts, cfg.Cluster.Pods)
+		notReady := make([]string, 0)
+		since := sts.CreationTimestamp.Time
+		if len(pods) > 0 {
+			since = time.Time{}
+		}
+		for _, p := range pods {
+			if !podReady(p) {
+				notReady = append(notReady, p.Name)
+				if t := podNotReadySince(p); since.IsZero() || t.Before(since) {
+					since = t
+				}
+			}
+		}
+		if len(notReady) == 0 {
+			for _, p := range pods {
+				if p.CreationTimestamp.After(since) {
+					since = p.CreationTimestamp.Time
+				}
+			}
+		}
+		if time.Since(since) < cfg.StatefulSetGracePeriod {
+			return "", false, false
+		}
+
+		details := fmt.Sprintf("%d of %d replicas are ready", sts.Status.ReadyReplicas, replicas)
+		if !since.IsZero() {
+			details += fmt.Sprintf(" for %s", time.Since(since).Round(time.Minute))
+		}
+		if len(notReady) != 0 {
+			details += fmt.Sprintf(", not ready: %s", strings.Join(notReady, ", "))
+		}
+		if missing := int(replicas) - len(pods); missing > 0 {
+			details += fmt.Sprintf(", pods missing: %d", missing)
+		}
+		if sts.Status.UpdateRevision != "" && sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+			details += fmt.Sprintf(", stuck updating to revision %s with %d of %d replicas updated",
+				sts.Status.UpdateRevision, sts.Status.UpdatedReplicas, replicas)
+		}
+		if sts.Spec.PodManagementPolicy != appsv1.ParallelPodManagement {
+			details += ", pods are created and updated one at a time so the first pod that doesn't become ready blocks the rest"
+		}
+		return details, false, true
+	},
+}
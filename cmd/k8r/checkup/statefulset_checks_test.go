@@ -0,0 +1,79 @@
+package checkup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestStatefulSetServiceInvalid(t *testing.T) {
+	app := map[string]string{"app": "db"}
+	sts := checkuptest.NewStatefulSet("db", "db", app)
+	withService := func(opts ...checkuptest.ServiceOption) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(sts, checkuptest.NewService("db", app, opts...)))
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemStatefulSetServiceInvalid, []checkuptest.Case{
+		{Name: "headless service", Object: sts, Config: withService(checkuptest.Headless())},
+		{Name: "no service name", Object: checkuptest.NewStatefulSet("db", "", app), Occurring: true, DetailsContain: "serviceName is not set"},
+		{Name: "missing service", Object: sts, Occurring: true, DetailsContain: "does not exist"},
+		{Name: "not headless", Object: sts, Config: withService(), Occurring: true, DetailsContain: "is not headless"},
+		{
+			Name:           "wrong selector",
+			Object:         sts,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(sts, checkuptest.NewService("db", map[string]string{"app": "other"}, checkuptest.Headless()))),
+			Occurring:      true,
+			DetailsContain: "does not select",
+		},
+	})
+}
+
+func TestStatefulSetReplicasNotReady(t *testing.T) {
+	app := map[string]string{"app": "db"}
+	sts := func(replicas, ready int32) *appsv1.StatefulSet {
+		return checkuptest.StatefulSetReplicas(checkuptest.NewStatefulSet("db", "db", app), replicas, ready)
+	}
+	pod := func(name string, created time.Time, opts ...checkuptest.PodOption) *corev1.Pod {
+		return checkuptest.NewPod(name, append(opts, checkuptest.OwnedBy("StatefulSet", "db"), checkuptest.CreatedAt(created))...)
+	}
+	hourAgo := time.Now().Add(-time.Hour)
+	withPods := func(pods ...runtime.Object) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(pods...))
+	}
+
+	updating := sts(3, 1)
+	updating.Status.CurrentRevision, updating.Status.UpdateRevision, updating.Status.UpdatedReplicas = "db-6c7d8", "db-9f4b2", 1
+
+	checkuptest.RunCases(t, checkup.ProblemStatefulSetReplicasNotReady, []checkuptest.Case{
+		{Name: "all ready", Object: sts(3, 3), Config: withPods()},
+		{Name: "not observed", Object: checkuptest.NewStatefulSet("db", "db", app), Config: withPods()},
+		{
+			Name:   "within grace period",
+			Object: sts(2, 1),
+			Config: withPods(pod("db-0", hourAgo), pod("db-1", time.Now(), checkuptest.NotReady(checkuptest.DefaultContainer))),
+		},
+		{
+			Name:   "stuck updating",
+			Object: updating,
+			Config: withPods(
+				pod("db-0", hourAgo),
+				pod("db-1", hourAgo),
+				pod("db-2", hourAgo, checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)),
+			),
+			Occurring:      true,
+			DetailsContain: "1 of 3 replicas are ready for 1h0m0s, not ready: db-2, stuck updating to revision db-9f4b2 with 1 of 3 replicas updated",
+		},
+		{
+			Name:           "missing pods",
+			Object:         sts(3, 2),
+			Config:         withPods(pod("db-0", hourAgo), pod("db-1", hourAgo)),
+			Occurring:      true,
+			DetailsContain: "2 of 3 replicas are ready for 1h0m0s, pods missing: 1",
+		},
+	})
+}
@@ -0,0 +1,175 @@
+// Description: This file contains code for problems with how
+// StorageClasses are configured, which show up as PVCs that stay Pending
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// default StorageClass annotations, the beta one is still honoured
+const (
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// noProvisioner is the provisioner of StorageClasses for volumes that are
+// created by hand, e.g. local volumes
+const noProvisioner = "kubernetes.io/no-provisioner"
+
+// isDefaultStorageClass returns true if PVCs that don't set a class get
+// the StorageClass
+func isDefaultStorageClass(sc *storagev1.StorageClass) bool {
+	return sc.Annotations[defaultStorageClassAnnotation] == "true" || sc.Annotations[betaDefaultStorageClassAnnotation] == "true"
+}
+
+// defaultStorageClasses returns the names of the default StorageClasses
+func defaultStorageClasses(classes []storagev1.StorageClass) []string {
+	names := make([]string, 0)
+	for i := range classes {
+		if isDefaultStorageClass(&classes[i]) {
+			names = append(names, classes[i].Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// provisionerRunning returns true if a provisioner is registered as a CSI
+// driver or a running pod looks like it runs it, from the provisioner
+// name or the last part of it being in a container's image, arguments or
+// environment, e.g. rancher/local-path-provisioner for rancher.io/local-path
+func provisionerRunning(provisioner string, c *Cluster) bool {
+	for i := range c.CSIDrivers {
+		if c.CSIDrivers[i].Name == provisioner {
+			return true
+		}
+	}
+
+	names := []string{provisioner}
+	if i := strings.LastIndex(provisioner, "/"); i >= 0 && i < len(provisioner)-1 {
+		names = append(names, provisioner[i+1:])
+	}
+	mentions := func(s string) bool {
+		for _, name := range names {
+			if strings.Contains(s, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range c.Pods {
+		p := &c.Pods[i]
+		if p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range p.Spec.Containers {
+			if mentions(container.Image) || mentions(strings.Join(container.Args, " ")) || mentions(strings.Join(container.Command, " ")) {
+				return true
+			}
+			for _, env := range container.Env {
+				if mentions(env.Value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ProblemPVCNoDefaultStorageClass is a problem with a Pending PVC that
+// doesn't set a StorageClass in a cluster without a default one, so no
+// volume is provisioned for it
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PVCNoDefaultStorageClass
+var ProblemPVCNoDefaultStorageClass = Problem{
+	ID:               "PVCNoDefaultStorageClass",
+	ShortDescription: "A Pending PersistentVolumeClaim doesn't set a StorageClass and the cluster has no default one",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PVCNoDefaultStorageClass",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok || cfg.Cluster == nil || pvc.Status.Phase != corev1.ClaimPending || pvc.Spec.StorageClassName != nil {
+			return "", false, false
+		}
+		if len(defaultStorageClasses(cfg.Cluster.StorageClasses)) != 0 {
+			return "", false, false
+		}
+
+		names := make([]string, 0, len(cfg.Cluster.StorageClasses))
+		for i := range cfg.Cluster.StorageClasses {
+			names = append(names, cfg.Cluster.StorageClasses[i].Name)
+		}
+		details := "The claim doesn't set storageClassName and no StorageClass is marked as the default, " +
+			"so it only binds to a PersistentVolume without a class that was created by hand"
+		if len(names) != 0 {
+			sort.Strings(names)
+			details += fmt.Sprintf(", set storageClassName to one of %s or annotate one with %s=true",
+				strings.Join(names, ", "), defaultStorageClassAnnotation)
+		}
+		return details, false, true
+	},
+}
+
+// ProblemStorageClassMultipleDefaults is a problem with a default
+// StorageClass when another one is the default as well. Older API servers
+// reject PVCs that don't set a class, newer ones give them the most
+// recently created default, which may not be the one that was meant.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/StorageClassMultipleDefaults
+var ProblemStorageClassMultipleDefaults = Problem{
+	ID:               "StorageClassMultipleDefaults",
+	ShortDescription: "More than one StorageClass is marked as the default",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/StorageClassMultipleDefaults",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		sc, ok := obj.(*storagev1.StorageClass)
+		if !ok || cfg.Cluster == nil || !isDefaultStorageClass(sc) {
+			return "", false, false
+		}
+		defaults := defaultStorageClasses(cfg.Cluster.StorageClasses)
+		if len(defaults) < 2 {
+			return "", false, false
+		}
+		return fmt.Sprintf("StorageClasses %s are all marked as the default, PVCs without a class are rejected "+
+			"or get whichever was created last depending on the Kubernetes version, remove %s from all but one",
+			strings.Join(defaults, ", "), defaultStorageClassAnnotation), true, true
+	},
+}
+
+// ProblemStorageClassProvisionerMissing is a problem with a StorageClass
+// whose provisioner isn't built into Kubernetes, isn't a registered CSI
+// driver and doesn't appear to be run by any pod, so PVCs of the class
+// stay Pending
+// https://github.com/Ashvin-Ranjan/k8r/wiki/StorageClassProvisionerMissing
+var ProblemStorageClassProvisionerMissing = Problem{
+	ID:               "StorageClassProvisionerMissing",
+	ShortDescription: "Nothing appears to run a StorageClass's provisioner, so its PVCs won't be provisioned",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/StorageClassProvisionerMissing",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		sc, ok := obj.(*storagev1.StorageClass)
+		if !ok || cfg.Cluster == nil || sc.Provisioner == noProvisioner || strings.HasPrefix(sc.Provisioner, "kubernetes.io/") {
+			return "", false, false
+		}
+		if provisionerRunning(sc.Provisioner, cfg.Cluster) {
+			return "", false, false
+		}
+
+		details := fmt.Sprintf("Provisioner %s isn't a registered CSIDriver and no running pod appears to run it", sc.Provisioner)
+		pending := 0
+		for i := range cfg.Cluster.PersistentVolumeClaims {
+			pvc := &cfg.Cluster.PersistentVolumeClaims[i]
+			if pvc.Status.Phase == corev1.ClaimPending && pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName == sc.Name {
+				pending++
+			}
+		}
+		if pending != 0 {
+			details += fmt.Sprintf(", Pending PVCs of the class: %d", pending)
+		}
+		return details + ", install the driver or fix the provisioner name", true, true
+	},
+}
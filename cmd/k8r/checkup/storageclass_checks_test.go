@@ -0,0 +1,70 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPVCNoDefaultStorageClass(t *testing.T) {
+	pending := checkuptest.NewPVC("data", "", "10Gi", checkuptest.PVCPending(), checkuptest.PVCWithoutStorageClass())
+	withClasses := func(objs ...runtime.Object) *checkup.Config {
+		return checkuptest.NewConfig(checkuptest.NewCluster(objs...))
+	}
+
+	checkuptest.RunCases(t, checkup.ProblemPVCNoDefaultStorageClass, []checkuptest.Case{
+		{
+			Name:   "default class",
+			Object: pending,
+			Config: withClasses(checkuptest.DefaultStorageClass(checkuptest.NewStorageClass("standard", "pd.csi.storage.gke.io", true))),
+		},
+		{Name: "class set", Object: checkuptest.NewPVC("data", "ssd", "10Gi", checkuptest.PVCPending()), Config: withClasses()},
+		{Name: "bound", Object: checkuptest.NewPVC("data", "", "10Gi", checkuptest.PVCWithoutStorageClass()), Config: withClasses()},
+		{
+			Name:           "no default class",
+			Object:         pending,
+			Config:         withClasses(checkuptest.NewStorageClass("ssd", "pd.csi.storage.gke.io", true), checkuptest.NewStorageClass("hdd", "pd.csi.storage.gke.io", true)),
+			Occurring:      true,
+			DetailsContain: "set storageClassName to one of hdd, ssd",
+		},
+	})
+}
+
+func TestStorageClassMultipleDefaults(t *testing.T) {
+	standard := checkuptest.DefaultStorageClass(checkuptest.NewStorageClass("standard", "pd.csi.storage.gke.io", true))
+	ssd := checkuptest.DefaultStorageClass(checkuptest.NewStorageClass("ssd", "pd.csi.storage.gke.io", true))
+
+	checkuptest.RunCases(t, checkup.ProblemStorageClassMultipleDefaults, []checkuptest.Case{
+		{Name: "one default", Object: standard, Config: checkuptest.NewConfig(checkuptest.NewCluster(standard))},
+		{
+			Name:           "two defaults",
+			Object:         standard,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(standard, ssd)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "StorageClasses ssd, standard are all marked as the default",
+		},
+	})
+}
+
+func TestStorageClassProvisionerMissing(t *testing.T) {
+	ebs := checkuptest.NewStorageClass("gp3", "ebs.csi.aws.com", true)
+	localPath := checkuptest.NewStorageClass("local-path", "rancher.io/local-path", false)
+	provisioner := checkuptest.NewPod("local-path-provisioner", checkuptest.WithImage(checkuptest.DefaultContainer, "rancher/local-path-provisioner:v0.0.24"))
+
+	checkuptest.RunCases(t, checkup.ProblemStorageClassProvisionerMissing, []checkuptest.Case{
+		{Name: "csi driver", Object: ebs, Config: checkuptest.NewConfig(checkuptest.NewCluster(checkuptest.NewCSIDriver("ebs.csi.aws.com")))},
+		{Name: "provisioner pod", Object: localPath, Config: checkuptest.NewConfig(checkuptest.NewCluster(provisioner))},
+		{Name: "in-tree", Object: checkuptest.NewStorageClass("local", "kubernetes.io/no-provisioner", false), Config: checkuptest.NewConfig(checkuptest.NewCluster())},
+		{
+			Name:           "missing",
+			Object:         ebs,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(checkuptest.NewPVC("data", "gp3", "10Gi", checkuptest.PVCPending()))),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Provisioner ebs.csi.aws.com isn't a registered CSIDriver and no running pod appears to run it, Pending PVCs of the class: 1",
+		},
+	})
+}
@@ -0,0 +1,143 @@
+// Description: This file contains code for suppressing problems that are
+// symptoms of another problem, so that reports show root causes
+
+package checkup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SuppressionRule declares that one problem causes another, when both
+// are found on related resources the symptom is suppressed
+type SuppressionRule struct {
+	// Cause is the ID of the problem that is the root cause
+	Cause string
+
+	// Symptom is the ID of the problem that is suppressed
+	Symptom string
+
+	// Related returns true if the cause was found on a resource that
+	// explains the symptom
+	Related func(cause, symptom *Resource, c *Cluster) bool
+}
+
+// sameResource returns true if both problems were found on the same resource
+func sameResource(cause, symptom *Resource, _ *Cluster) bool {
+	return cause.Type == symptom.Type && cause.Name == symptom.Name
+}
+
+// podOnNode returns true if the symptom was found on a pod running on the
+// node the cause was found on
+func podOnNode(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "node" || symptom.Type != "pod" || c == nil {
+		return false
+	}
+
+	p, ok := c.podIndex().byName[symptom.Name]
+	return ok && p.Spec.NodeName == cause.Name
+}
+
+// devicePluginOnNode returns true if the symptom was found on a node that
+// the device plugin DaemonSet the cause was found on isn't ready on
+func devicePluginOnNode(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "DaemonSet" || symptom.Type != "node" || c == nil {
+		return false
+	}
+
+	namespace, name := splitResourceName(cause.Name)
+	for _, p := range c.podIndex().byNamespace[namespace] {
+		if p.Spec.NodeName != symptom.Name || podReady(p) {
+			continue
+		}
+		for _, ref := range p.OwnerReferences {
+			if ref.Kind == "DaemonSet" && ref.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// podBlockedByTaint returns true if the symptom was found on a pod that the
+// taint the cause was found on keeps off of nodes
+func podBlockedByTaint(cause, symptom *Resource, c *Cluster) bool {
+	if cause.Type != "taint" || symptom.Type != "pod" || c == nil {
+		return false
+	}
+
+	p, ok := c.podIndex().byName[symptom.Name]
+	return ok && blockedByTaint(p, cause.Name)
+}
+
+// suppressionRules are the known causal relationships between problems
+var suppressionRules = []SuppressionRule{
+	{Cause: ProblemNodeNotReady.ID, Symptom: ProblemPodNotReady.ID, Related: podOnNode},
+	{Cause: ProblemPodImagePullBackOff.ID, Symptom: ProblemPodNotReady.ID, Related: sameResource},
+	{Cause: ProblemPodCrashLoopBackOff.ID, Symptom: ProblemPodNotReady.ID, Related: sameResource},
+	{Cause: ProblemPodOOMKilled.ID, Symptom: ProblemPodCrashLoopBackOff.ID, Related: sameResource},
+	{Cause: ProblemInitContainerStuck.ID, Symptom: ProblemPodNotReady.ID, Related: sameResource},
+	{Cause: ProblemDevicePluginUnhealthy.ID, Symptom: ProblemNodeGPUNotAllocatable.ID, Related: devicePluginOnNode},
+	{Cause: ProblemPodImageNotLoaded.ID, Symptom: ProblemPodImagePullBackOff.ID, Related: sameResource},
+	{Cause: ProblemPodImageNotLoaded.ID, Symptom: ProblemPodNotReady.ID, Related: sameResource},
+	{Cause: ProblemLocalRegistryUnreachable.ID, Symptom: ProblemPodImagePullBackOff.ID, Related: sameResource},
+	{Cause: ProblemLocalNodeMemoryLow.ID, Symptom: ProblemPodOOMKilled.ID, Related: podOnNode},
+	{Cause: ProblemPodSidecarNotReady.ID, Symptom: ProblemPodNotReady.ID, Related: sameResource},
+	{Cause: ProblemJobBlockedBySidecar.ID, Symptom: ProblemPodSidecarBlocksJob.ID, Related: jobPod},
+	{Cause: ProblemCronJobStale.ID, Symptom: ProblemJobFailed.ID, Related: cronJobRun},
+	{Cause: ProblemCronJobRunsFailing.ID, Symptom: ProblemJobFailed.ID, Related: cronJobRun},
+	{Cause: ProblemCronJobRunsFailing.ID, Symptom: ProblemCronJobStale.ID, Related: sameResource},
+	{Cause: ProblemCronJobMissedSchedule.ID, Symptom: ProblemCronJobStale.ID, Related: sameResource},
+	{Cause: ProblemPVCNoDefaultStorageClass.ID, Symptom: ProblemPVCPending.ID, Related: sameResource},
+	{Cause: ProblemStorageClassProvisionerMissing.ID, Symptom: ProblemPVCPending.ID, Related: pvcOfStorageClass},
+	{Cause: ProblemPVCPending.ID, Symptom: ProblemPodPending.ID, Related: podMountsPendingPVC},
+	{Cause: ProblemPodImagePullBackOff.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemPodImageNotLoaded.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemLocalRegistryUnreachable.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemInitContainerStuck.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemPodHostPortUnschedulable.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemPodTopologyUnsatisfiable.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemPodExtendedResourceUnavailable.ID, Symptom: ProblemPodPending.ID, Related: sameResource},
+	{Cause: ProblemTaintBlocksPods.ID, Symptom: ProblemPodPending.ID, Related: podBlockedByTaint},
+}
+
+// suppressSymptoms marks every problem that is explained by another
+// problem as suppressed, recording what it was suppressed by
+func suppressSymptoms(resources []Resource, c *Cluster) {
+	byProblem := make(map[string][]*Resource)
+	for i := range resources {
+		byProblem[resources[i].ProblemID] = append(byProblem[resources[i].ProblemID], &resources[i])
+	}
+
+	for _, rule := range suppressionRules {
+		for _, symptom := range byProblem[rule.Symptom] {
+			for _, cause := range byProblem[rule.Cause] {
+				if !rule.Related(cause, symptom, c) {
+					continue
+				}
+
+				symptom.SuppressedBy = fmt.Sprintf("%s on %s", cause.ProblemID, cause.Name)
+				break
+			}
+		}
+	}
+}
+
+// rootCauses returns the problems that weren't suppressed and how many were
+func rootCauses(resources []Resource) ([]Resource, int) {
+	roots := make([]Resource, 0, len(resources))
+	for i := range resources {
+		if resources[i].SuppressedBy == "" {
+			roots = append(roots, resources[i])
+		}
+	}
+	return roots, len(resources) - len(roots)
+}
+
+// suppressedDetails appends what suppressed a problem to its details
+func suppressedDetails(r *Resource) string {
+	if r.SuppressedBy == "" {
+		return r.ProblemDetails
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (caused by %s)", r.ProblemDetails, r.SuppressedBy))
+}
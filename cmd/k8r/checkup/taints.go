@@ -0,0 +1,206 @@
+// Description: This file contains code for summarizing the taints on
+// nodes against the pending pods that don't tolerate them, so that node
+// pools that are isolated on purpose can be told apart from lockouts
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// nodeLifecycleTaintPrefix is the prefix of the taints the node lifecycle
+// controller manages, e.g. node.kubernetes.io/not-ready, which are
+// reported by the node checks instead
+const nodeLifecycleTaintPrefix = "node.kubernetes.io/"
+
+// maxTaintExamples is the number of nodes and pods listed for a taint
+const maxTaintExamples = 3
+
+// Taint is a taint key that is on nodes, with the pods that tolerate it
+// and the pending pods it keeps from being scheduled, it is checked for
+// problems like any other resource
+type Taint struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	// Taints are the distinct taints with this key, e.g. with different
+	// values or effects
+	Taints []corev1.Taint
+
+	// Nodes are the names of the schedulable nodes with the taint
+	Nodes []string
+
+	// SchedulableNodes is the number of nodes that aren't cordoned
+	SchedulableNodes int
+
+	// Tolerating are the namespace/name of the pods that tolerate the
+	// taint by key, not counting DaemonSet pods and pods that tolerate
+	// every taint
+	Tolerating []string
+
+	// Blocked are the namespace/name of the pending pods the scheduler
+	// couldn't place because they don't tolerate the taint
+	Blocked []string
+}
+
+// DeepCopyObject implements runtime.Object
+func (t *Taint) DeepCopyObject() runtime.Object {
+	out := *t
+	t.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Taints = append([]corev1.Taint(nil), t.Taints...)
+	out.Nodes = append([]string(nil), t.Nodes...)
+	out.Tolerating = append([]string(nil), t.Tolerating...)
+	out.Blocked = append([]string(nil), t.Blocked...)
+	return &out
+}
+
+// toleratesAll returns true if the pod tolerates every one of the taints
+func toleratesAll(pod *corev1.Pod, taints []corev1.Taint) bool {
+	for i := range taints {
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(&taints[i]) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// toleratesEverything returns true if the pod has a toleration without a
+// key, which tolerates every taint
+func toleratesEverything(pod *corev1.Pod) bool {
+	for i := range pod.Spec.Tolerations {
+		t := &pod.Spec.Tolerations[i]
+		if t.Key == "" && t.Operator == corev1.TolerationOpExists {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedByTaint returns true if the scheduler reported that the taint key
+// kept the pod off of nodes, e.g. 1 node(s) had untolerated taint
+// {dedicated: gpu}
+func blockedByTaint(pod *corev1.Pod, key string) bool {
+	if !unschedulable(pod) {
+		return false
+	}
+	c, _ := podCondition(pod, corev1.PodScheduled)
+	return strings.Contains(c.Message, "{"+key+":") || strings.Contains(c.Message, "{"+key+"}")
+}
+
+// gatherTaints groups the NoSchedule and NoExecute taints on nodes by key,
+// with the pods that tolerate them and the pending pods they block
+func gatherTaints(nodes []corev1.Node, pods []corev1.Pod) []Taint {
+	byKey := make(map[string]*Taint)
+	schedulable := 0
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Spec.Unschedulable {
+			continue
+		}
+		schedulable++
+		for _, t := range n.Spec.Taints {
+			if t.Effect == corev1.TaintEffectPreferNoSchedule || strings.HasPrefix(t.Key, nodeLifecycleTaintPrefix) {
+				continue
+			}
+			taint, ok := byKey[t.Key]
+			if !ok {
+				taint = &Taint{ObjectMeta: metav1.ObjectMeta{Name: t.Key}}
+				byKey[t.Key] = taint
+			}
+			if len(taint.Nodes) == 0 || taint.Nodes[len(taint.Nodes)-1] != n.Name {
+				taint.Nodes = append(taint.Nodes, n.Name)
+			}
+
+			seen := false
+			for j := range taint.Taints {
+				if taint.Taints[j].MatchTaint(&t) && taint.Taints[j].Value == t.Value {
+					seen = true
+				}
+			}
+			if !seen {
+				taint.Taints = append(taint.Taints, corev1.Taint{Key: t.Key, Value: t.Value, Effect: t.Effect})
+			}
+		}
+	}
+
+	taints := make([]Taint, 0, len(byKey))
+	for _, taint := range byKey {
+		taint.SchedulableNodes = schedulable
+		for i := range pods {
+			p := &pods[i]
+			name := fmt.Sprintf("%s/%s", p.Namespace, p.Name)
+			switch {
+			case blockedByTaint(p, taint.Name) && !toleratesAll(p, taint.Taints):
+				taint.Blocked = append(taint.Blocked, name)
+			case p.Spec.NodeName != "" && !ownedByKind(p, "DaemonSet") && !toleratesEverything(p) && toleratesAll(p, taint.Taints):
+				taint.Tolerating = append(taint.Tolerating, name)
+			}
+		}
+		sort.Strings(taint.Nodes)
+		sort.Strings(taint.Tolerating)
+		sort.Strings(taint.Blocked)
+		taints = append(taints, *taint)
+	}
+	sort.Slice(taints, func(i, j int) bool { return taints[i].Name < taints[j].Name })
+	return taints
+}
+
+// taintExamples returns up to maxTaintExamples of the names
+func taintExamples(names []string) string {
+	if len(names) > maxTaintExamples {
+		return strings.Join(names[:maxTaintExamples], ", ") + fmt.Sprintf(" and %d more", len(names)-maxTaintExamples)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ProblemTaintBlocksPods is a problem with a node taint that keeps pending
+// pods from being scheduled because they don't tolerate it. It is a
+// warning when other pods tolerate the taint, which is what dedicated node
+// pools look like, and an error when it is on every node or nothing
+// tolerates it.
+// https://github.com/Ashvin-Ranjan/k8r/wiki/TaintBlocksPods
+var ProblemTaintBlocksPods = Problem{
+	ID:               "TaintBlocksPods",
+	ShortDescription: "A node taint keeps pending pods that don't tolerate it from being scheduled",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/TaintBlocksPods",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		taint, ok := obj.(*Taint)
+		if !ok || len(taint.Blocked) == 0 {
+			return "", false, false
+		}
+
+		values := make([]string, 0, len(taint.Taints))
+		for i := range taint.Taints {
+			values = append(values, taint.Taints[i].ToString())
+		}
+		details := fmt.Sprintf("Taint %s is on %d of %d schedulable nodes (%s) and %d pending pod(s) don't tolerate it (%s)",
+			strings.Join(values, ", "), len(taint.Nodes), taint.SchedulableNodes, taintExamples(taint.Nodes),
+			len(taint.Blocked), taintExamples(taint.Blocked))
+
+		switch {
+		case len(taint.Nodes) >= taint.SchedulableNodes:
+			return details + ", it is on every node so pods without a toleration can't run anywhere, " +
+				"remove it from the nodes that should take general workloads", false, true
+		case len(taint.Tolerating) == 0:
+			return details + ", no pod tolerates it so it is likely left over, e.g. from node setup or maintenance, " +
+				"remove it or add a toleration to the pods meant for those nodes", false, true
+		}
+		return details + fmt.Sprintf(", %d pod(s) tolerate it (%s) so this looks like a dedicated node pool, "+
+			"add a toleration only if the pending pods are meant to run there",
+			len(taint.Tolerating), taintExamples(taint.Tolerating)), true, true
+	},
+}
@@ -0,0 +1,88 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// dedicatedGPU taints a node with dedicated=gpu:NoSchedule
+func dedicatedGPU(node *corev1.Node) {
+	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule})
+}
+
+// tolerating makes a pod tolerate the dedicated taint
+func tolerating(spec *corev1.PodSpec) {
+	spec.Tolerations = append(spec.Tolerations, corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists})
+}
+
+func TestTaintBlocksPods(t *testing.T) {
+	blocked := checkuptest.NewPod("web", checkuptest.Unschedulable(
+		"0/2 nodes are available: 1 node(s) had untolerated taint {dedicated: gpu}, 1 Insufficient cpu."))
+	train := checkuptest.NewPod("train", checkuptest.OnNode("gpu-1"), checkuptest.WithSpec(tolerating))
+	tests := []struct {
+		name        string
+		objs        []runtime.Object
+		wantWarning bool
+		wantDetails string
+	}{
+		{
+			name: "nothing blocked",
+			objs: []runtime.Object{checkuptest.NewNode("gpu-1", dedicatedGPU), checkuptest.NewNode("cpu-1"), train},
+		},
+		{
+			name:        "dedicated pool",
+			objs:        []runtime.Object{checkuptest.NewNode("gpu-1", dedicatedGPU), checkuptest.NewNode("cpu-1"), train, blocked},
+			wantWarning: true,
+			wantDetails: "Taint dedicated=gpu:NoSchedule is on 1 of 2 schedulable nodes (gpu-1) and 1 pending pod(s) " +
+				"don't tolerate it (default/web), 1 pod(s) tolerate it (default/train) so this looks like a dedicated node pool",
+		},
+		{
+			name:        "left over",
+			objs:        []runtime.Object{checkuptest.NewNode("gpu-1", dedicatedGPU), checkuptest.NewNode("cpu-1"), blocked},
+			wantDetails: "no pod tolerates it so it is likely left over",
+		},
+		{
+			name:        "every node",
+			objs:        []runtime.Object{checkuptest.NewNode("gpu-1", dedicatedGPU), checkuptest.NewNode("gpu-2", dedicatedGPU), train, blocked},
+			wantDetails: "it is on every node so pods without a toleration can't run anywhere",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			o := checkup.NewOptions(logrus.New())
+			o.Configure(checkuptest.NewConfig(nil), &out)
+
+			resources, err := o.Scan(context.Background(), newClientset(tt.objs, nil))
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			var got *checkup.Resource
+			for i := range resources {
+				if resources[i].ProblemID == checkup.ProblemTaintBlocksPods.ID {
+					got = &resources[i]
+				}
+			}
+			switch {
+			case tt.wantDetails == "" && got != nil:
+				t.Errorf("TaintBlocksPods reported for %s: %q", got.Name, got.ProblemDetails)
+			case tt.wantDetails != "" && got == nil:
+				t.Errorf("TaintBlocksPods not reported")
+			case got != nil && (got.Name != "dedicated" || got.Warning != tt.wantWarning ||
+				!strings.Contains(got.ProblemDetails, tt.wantDetails)):
+				t.Errorf("TaintBlocksPods reported for %s (warning %v): %q, expected dedicated (warning %v) with %q",
+					got.Name, got.Warning, got.ProblemDetails, tt.wantWarning, tt.wantDetails)
+			}
+		})
+	}
+}
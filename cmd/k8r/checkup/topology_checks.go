@@ -0,0 +1,228 @@
+// Description: This file contains code for problems with pending pods
+// whose required anti-affinity or topology spread constraints can't be
+// met by any node in the cluster as it is
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// maxTopologyExamples is the number of pods listed as taking up topology
+// domains
+const maxTopologyExamples = 3
+
+// unschedulable returns true if the scheduler couldn't find a node for the
+// pod
+func unschedulable(pod *corev1.Pod) bool {
+	if pod.Spec.NodeName != "" || pod.Status.Phase != corev1.PodPending {
+		return false
+	}
+	c, ok := podCondition(pod, corev1.PodScheduled)
+	return ok && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable
+}
+
+// eligibleNodes returns the nodes a pod could be scheduled on going by its
+// nodeSelector, which is also what topology spread counts pods on. Taints
+// and node affinity aren't considered.
+func eligibleNodes(pod *corev1.Pod, nodes []corev1.Node) map[string]*corev1.Node {
+	selector := labels.SelectorFromSet(pod.Spec.NodeSelector)
+	eligible := make(map[string]*corev1.Node)
+	for i := range nodes {
+		n := &nodes[i]
+		if !n.Spec.Unschedulable && selector.Matches(labels.Set(n.Labels)) {
+			eligible[n.Name] = n
+		}
+	}
+	return eligible
+}
+
+// topologyDomains returns the values of a topology key across nodes
+func topologyDomains(nodes map[string]*corev1.Node, key string) map[string]bool {
+	domains := make(map[string]bool)
+	for _, n := range nodes {
+		if v, ok := n.Labels[key]; ok {
+			domains[v] = true
+		}
+	}
+	return domains
+}
+
+// placedPods returns the pods that are running, or about to, on one of the
+// given nodes and aren't going away, keyed by the domain of their node
+func placedPods(pods []corev1.Pod, nodes map[string]*corev1.Node, key string,
+	matches func(*corev1.Pod) bool) map[string][]*corev1.Pod {
+	placed := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		p := &pods[i]
+		if p.Spec.NodeName == "" || p.DeletionTimestamp != nil ||
+			p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed || !matches(p) {
+			continue
+		}
+		n, ok := nodes[p.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		if v, ok := n.Labels[key]; ok {
+			placed[v] = append(placed[v], p)
+		}
+	}
+	return placed
+}
+
+// termNamespaces returns whether a pod affinity term applies to pods in a
+// namespace, which is the pod's own namespace unless the term lists
+// namespaces or selects them
+func termNamespaces(pod *corev1.Pod, term *corev1.PodAffinityTerm, c *Cluster) func(string) bool {
+	namespaces := make(map[string]bool)
+	for _, ns := range term.Namespaces {
+		namespaces[ns] = true
+	}
+	if term.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(term.NamespaceSelector)
+		if err == nil {
+			for i := range c.Namespaces {
+				if selector.Matches(labels.Set(c.Namespaces[i].Labels)) {
+					namespaces[c.Namespaces[i].Name] = true
+				}
+			}
+		}
+	} else if len(term.Namespaces) == 0 {
+		namespaces[pod.Namespace] = true
+	}
+	return func(ns string) bool { return namespaces[ns] }
+}
+
+// podNames returns up to maxTopologyExamples names of the pods in the
+// domains, sorted
+func podNames(placed map[string][]*corev1.Pod) string {
+	names := make([]string, 0)
+	for _, pods := range placed {
+		for _, p := range pods {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > maxTopologyExamples {
+		return strings.Join(names[:maxTopologyExamples], ", ") + fmt.Sprintf(" and %d more", len(names)-maxTopologyExamples)
+	}
+	return strings.Join(names, ", ")
+}
+
+// unsatisfiableAntiAffinity explains why a pod's required anti-affinity
+// can't be met on any eligible node, if it can't
+func unsatisfiableAntiAffinity(pod *corev1.Pod, nodes map[string]*corev1.Node, c *Cluster) (string, bool) {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		return "", false
+	}
+
+	for i := range pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		domains := topologyDomains(nodes, term.TopologyKey)
+		if len(domains) == 0 {
+			return fmt.Sprintf("Required podAntiAffinity uses topologyKey %s but no node it can run on has that label",
+				term.TopologyKey), true
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			continue
+		}
+		inNamespace := termNamespaces(pod, term, c)
+		placed := placedPods(c.Pods, nodes, term.TopologyKey, func(p *corev1.Pod) bool {
+			return p.UID != pod.UID && inNamespace(p.Namespace) && selector.Matches(labels.Set(p.Labels))
+		})
+		if len(placed) < len(domains) {
+			continue
+		}
+		return fmt.Sprintf("Required podAntiAffinity on %s can never be satisfied with the current topology: "+
+			"all %d %s domain(s) the pod can run on already run a pod matching %s (%s), add nodes in a new domain, "+
+			"lower the replicas or use preferredDuringSchedulingIgnoredDuringExecution",
+			term.TopologyKey, len(domains), term.TopologyKey, selector, podNames(placed)), true
+	}
+	return "", false
+}
+
+// unsatisfiableTopologySpread explains why a pod's DoNotSchedule topology
+// spread constraints can't be met on any eligible node, if they can't
+func unsatisfiableTopologySpread(pod *corev1.Pod, nodes map[string]*corev1.Node, c *Cluster) (string, bool) {
+	for i := range pod.Spec.TopologySpreadConstraints {
+		tsc := &pod.Spec.TopologySpreadConstraints[i]
+		if tsc.WhenUnsatisfiable != corev1.DoNotSchedule {
+			continue
+		}
+
+		domains := topologyDomains(nodes, tsc.TopologyKey)
+		if len(domains) == 0 {
+			return fmt.Sprintf("topologySpreadConstraint on %s can never be satisfied with the current topology: "+
+				"no node the pod can run on has that label", tsc.TopologyKey), true
+		}
+
+		// Placing the pod in the domain with the fewest matching pods
+		// always keeps the skew at or below 1, unless there are fewer
+		// domains than minDomains, which counts the missing ones as empty
+		if tsc.MinDomains == nil || len(domains) >= int(*tsc.MinDomains) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(tsc.LabelSelector)
+		if err != nil {
+			continue
+		}
+		placed := placedPods(c.Pods, nodes, tsc.TopologyKey, func(p *corev1.Pod) bool {
+			return p.UID != pod.UID && p.Namespace == pod.Namespace && selector.Matches(labels.Set(p.Labels))
+		})
+
+		fits := false
+		for d := range domains {
+			if len(placed[d])+1 <= int(tsc.MaxSkew) {
+				fits = true
+				break
+			}
+		}
+		if fits {
+			continue
+		}
+		return fmt.Sprintf("topologySpreadConstraint on %s can never be satisfied with the current topology: "+
+			"minDomains is %d but the pod can only run in %d %s domain(s), each of which already has %d or more "+
+			"matching pods with maxSkew %d, add nodes in more domains or lower minDomains",
+			tsc.TopologyKey, *tsc.MinDomains, len(domains), tsc.TopologyKey, tsc.MaxSkew, tsc.MaxSkew), true
+	}
+	return "", false
+}
+
+// ProblemPodTopologyUnsatisfiable is a problem with a pending pod whose
+// required podAntiAffinity or topologySpreadConstraints can't be met by
+// any node it could run on, so it won't be scheduled until the cluster's
+// topology changes
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodTopologyUnsatisfiable
+var ProblemPodTopologyUnsatisfiable = Problem{
+	ID:               "PodTopologyUnsatisfiable",
+	ShortDescription: "A pending pod's anti-affinity or topology spread constraints can never be met by the current nodes",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodTopologyUnsatisfiable",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil || len(cfg.Cluster.Nodes) == 0 || !unschedulable(pod) {
+			return "", false, false
+		}
+
+		nodes := eligibleNodes(pod, cfg.Cluster.Nodes)
+		if len(nodes) == 0 {
+			return "", false, false
+		}
+		if details, ok := unsatisfiableAntiAffinity(pod, nodes, cfg.Cluster); ok {
+			return details, false, true
+		}
+		if details, ok := unsatisfiableTopologySpread(pod, nodes, cfg.Cluster); ok {
+			return details, false, true
+		}
+		return "", false, false
+	},
+}
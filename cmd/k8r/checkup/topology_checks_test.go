@@ -0,0 +1,91 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const zoneKey = "topology.kubernetes.io/zone"
+
+// zoneNode returns a node in the given zone
+func zoneNode(name, zone string) *corev1.Node {
+	return checkuptest.NewNode(name, checkuptest.NodeLabels(map[string]string{
+		corev1.LabelHostname: name,
+		zoneKey:              zone,
+	}))
+}
+
+// apiPod returns a pod of the api app on the given node, pending when
+// node is empty
+func apiPod(name, node string, spec func(*corev1.PodSpec)) *corev1.Pod {
+	opts := []checkuptest.PodOption{checkuptest.WithLabels(map[string]string{"app": "api"}), checkuptest.WithSpec(spec)}
+	if node == "" {
+		opts = append(opts, checkuptest.Unschedulable("0/2 nodes are available"))
+	} else {
+		opts = append(opts, checkuptest.OnNode(node))
+	}
+	return checkuptest.NewPod(name, opts...)
+}
+
+func TestPodTopologyUnsatisfiable(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}
+	antiAffinity := func(key string) func(*corev1.PodSpec) {
+		return func(spec *corev1.PodSpec) {
+			spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+					TopologyKey: key, LabelSelector: selector,
+				}},
+			}}
+		}
+	}
+	spread := func(minDomains int32) func(*corev1.PodSpec) {
+		return func(spec *corev1.PodSpec) {
+			spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{
+				MaxSkew: 1, TopologyKey: zoneKey, WhenUnsatisfiable: corev1.DoNotSchedule,
+				LabelSelector: selector, MinDomains: &minDomains,
+			}}
+		}
+	}
+
+	a, b := zoneNode("node-a", "us-east-1a"), zoneNode("node-b", "us-east-1b")
+	hostnameAnti := antiAffinity(corev1.LabelHostname)
+	pending := apiPod("api-2", "", hostnameAnti)
+	full := checkuptest.NewCluster(a, b, apiPod("api-0", "node-a", hostnameAnti), apiPod("api-1", "node-b", hostnameAnti), pending)
+	room := checkuptest.NewCluster(a, b, apiPod("api-0", "node-a", hostnameAnti), pending)
+
+	missingKey := apiPod("api-2", "", antiAffinity("example.com/rack"))
+	spreadPending := apiPod("api-2", "", spread(3))
+	spreadCluster := checkuptest.NewCluster(a, b, apiPod("api-0", "node-a", spread(3)), apiPod("api-1", "node-b", spread(3)), spreadPending)
+	spreadFits := apiPod("api-2", "", spread(2))
+
+	checkuptest.RunCases(t, checkup.ProblemPodTopologyUnsatisfiable, []checkuptest.Case{
+		{
+			Name:           "every host taken",
+			Object:         pending,
+			Config:         checkuptest.NewConfig(full),
+			Occurring:      true,
+			DetailsContain: "all 2 kubernetes.io/hostname domain(s) the pod can run on already run a pod matching app=api (api-0, api-1)",
+		},
+		{Name: "a host is free", Object: pending, Config: checkuptest.NewConfig(room)},
+		{
+			Name:           "topology key on no node",
+			Object:         missingKey,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(a, b, missingKey)),
+			Occurring:      true,
+			DetailsContain: "uses topologyKey example.com/rack but no node it can run on has that label",
+		},
+		{
+			Name:           "fewer zones than minDomains",
+			Object:         spreadPending,
+			Config:         checkuptest.NewConfig(spreadCluster),
+			Occurring:      true,
+			DetailsContain: "minDomains is 3 but the pod can only run in 2 topology.kubernetes.io/zone domain(s)",
+		},
+		{Name: "enough zones", Object: spreadFits, Config: checkuptest.NewConfig(checkuptest.NewCluster(a, b, spreadFits))},
+		{Name: "running", Object: apiPod("api-0", "node-a", hostnameAnti), Config: checkuptest.NewConfig(full)},
+	})
+}
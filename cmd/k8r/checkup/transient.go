@@ -0,0 +1,84 @@
+// Description: This file contains code for skipping pods that are in
+// states that are expected to be transient, e.g. pods that were just
+// deployed and haven't become ready yet
+
+package checkup
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultJobBackoffLimit is the number of retries a Job gets when it
+// doesn't set spec.backoffLimit
+const defaultJobBackoffLimit = 6
+
+// jobFinished returns true if the Job has completed or failed
+func jobFinished(job *batchv1.Job) bool {
+	for i := range job.Status.Conditions {
+		c := &job.Status.Conditions[i]
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// podJob returns the Job that owns a pod, if it is owned by one
+func podJob(pod *corev1.Pod, c *Cluster) (*batchv1.Job, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind != "Job" {
+			continue
+		}
+		for i := range c.Jobs {
+			if c.Jobs[i].Namespace == pod.Namespace && c.Jobs[i].Name == ref.Name {
+				return &c.Jobs[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// inJobRetryWindow returns true if a pod of a Job that restarts its
+// containers on failure has failed fewer times than the Job allows, the
+// Job is expected to retry these failures on its own
+func inJobRetryWindow(pod *corev1.Pod, c *Cluster) bool {
+	if pod.Spec.RestartPolicy != corev1.RestartPolicyOnFailure {
+		return false
+	}
+	job, ok := podJob(pod, c)
+	if !ok || jobFinished(job) {
+		return false
+	}
+
+	limit := int32(defaultJobBackoffLimit)
+	if job.Spec.BackoffLimit != nil {
+		limit = *job.Spec.BackoffLimit
+	}
+
+	restarts := int32(0)
+	for i := range pod.Status.ContainerStatuses {
+		restarts += pod.Status.ContainerStatuses[i].RestartCount
+	}
+	return restarts < limit
+}
+
+// transientPod returns why a pod's problems are expected to be transient
+// and shouldn't be reported, if they are
+func (c *Config) transientPod(pod *corev1.Pod) (string, bool) {
+	grace := c.forNamespace(pod.Namespace).PodGracePeriod
+	switch {
+	case matchesNamespace(pod.Namespace, c.TransientNamespaces):
+		return "in a transient namespace", true
+	case grace > 0 && !pod.CreationTimestamp.IsZero() && time.Since(pod.CreationTimestamp.Time) < grace:
+		return "younger than the grace period", true
+	case inJobRetryWindow(pod, c.Cluster):
+		return "retrying within its Job's backoff limit", true
+	}
+	return "", false
+}
@@ -0,0 +1,62 @@
+package checkup_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestTransientPodsSkipped(t *testing.T) {
+	crashing := checkuptest.CrashLoopBackOff(checkuptest.DefaultContainer)
+	young := func(p *corev1.Pod) { p.CreationTimestamp = metav1.NewTime(time.Now().Add(-30 * time.Second)) }
+	onFailure := func(p *corev1.Pod) { p.Spec.RestartPolicy = corev1.RestartPolicyOnFailure }
+
+	objs := []runtime.Object{
+		checkuptest.NewPod("broken", crashing),
+		checkuptest.NewPod("deploying", crashing, young),
+		checkuptest.NewPod("runner", crashing, checkuptest.InNamespace("ci-runners")),
+		checkuptest.NewJob("migrate", 6),
+		checkuptest.NewPod("migrate-abc", crashing, onFailure, checkuptest.OwnedBy("Job", "migrate"),
+			checkuptest.Restarts(checkuptest.DefaultContainer, 2)),
+		checkuptest.NewJob("backfill", 1),
+		checkuptest.NewPod("backfill-abc", crashing, onFailure, checkuptest.OwnedBy("Job", "backfill"),
+			checkuptest.Restarts(checkuptest.DefaultContainer, 2)),
+	}
+
+	cfg := checkuptest.NewConfig(nil)
+	cfg.PodGracePeriod = 2 * time.Minute
+	cfg.TransientNamespaces = []string{"ci-*"}
+
+	var out bytes.Buffer
+	o := checkup.NewOptions(logrus.New())
+	o.Configure(cfg, &out)
+
+	resources, err := o.Scan(context.Background(), newClientset(objs, nil))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for i := range resources {
+		got[resources[i].Name] = true
+	}
+	for name, want := range map[string]bool{
+		"default/broken":       true,
+		"default/deploying":    false,
+		"ci-runners/runner":    false,
+		"default/migrate-abc":  false,
+		"default/backfill-abc": true,
+	} {
+		if got[name] != want {
+			t.Errorf("problems reported for %s = %v, expected %v", name, got[name], want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Description: This file contains code for problems related to clusters
+// with both Linux and Windows nodes
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// windowsBuildLabel is the label kubelet sets on Windows nodes with the
+// Windows build they run, needed to schedule containers built for it
+const windowsBuildLabel = "node.kubernetes.io/windows-build"
+
+// failedOnWrongOSReasons are the reasons containers fail to start on a
+// node of the wrong OS, the image can't be pulled or run on it
+var failedOnWrongOSReasons = map[string]bool{
+	"ErrImagePull":         true,
+	"ImagePullBackOff":     true,
+	"CreateContainerError": true,
+	"CrashLoopBackOff":     true,
+}
+
+// nodeOS returns the OS of a node, from its label or what kubelet reports
+func nodeOS(node *corev1.Node) string {
+	if os := node.Labels[corev1.LabelOSStable]; os != "" {
+		return os
+	}
+	return node.Status.NodeInfo.OperatingSystem
+}
+
+// mixedOS returns true if the cluster has both Linux and Windows nodes
+func mixedOS(nodes []corev1.Node) bool {
+	linux, windows := false, false
+	for i := range nodes {
+		switch nodeOS(&nodes[i]) {
+		case "linux":
+			linux = true
+		case "windows":
+			windows = true
+		}
+	}
+	return linux && windows
+}
+
+// hasNoScheduleTaint returns true if the node repels pods that don't
+// tolerate its taints
+func hasNoScheduleTaint(node *corev1.Node) bool {
+	for i := range node.Spec.Taints {
+		if e := node.Spec.Taints[i].Effect; e == corev1.TaintEffectNoSchedule || e == corev1.TaintEffectNoExecute {
+			return true
+		}
+	}
+	return false
+}
+
+// podSelectsOS returns true if the pod is constrained to nodes of an OS
+func podSelectsOS(pod *corev1.Pod) bool {
+	if pod.Spec.OS != nil {
+		return true
+	}
+	if _, ok := pod.Spec.NodeSelector[corev1.LabelOSStable]; ok {
+		return true
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	// Every term has to constrain the OS, as terms are ORed
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	for i := range terms {
+		selects := false
+		for _, expr := range terms[i].MatchExpressions {
+			if expr.Key == corev1.LabelOSStable {
+				selects = true
+			}
+		}
+		if !selects {
+			return false
+		}
+	}
+	return len(terms) != 0
+}
+
+// ProblemPodMissingOSSelector is a problem with a pod on a cluster with
+// both Linux and Windows nodes that doesn't select an OS, so it can be
+// scheduled on a node its images can't run on
+// https://github.com/Ashvin-Ranjan/k8r/wiki/PodMissingOSSelector
+var ProblemPodMissingOSSelector = Problem{
+	ID:               "PodMissingOSSelector",
+	ShortDescription: "A pod on a mixed Linux/Windows cluster has no OS node selector",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/PodMissingOSSelector",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || cfg.Cluster == nil || !mixedOS(cfg.Cluster.Nodes) || podSelectsOS(pod) {
+			return "", false, false
+		}
+
+		// Pods that landed on a Windows node without asking for one are
+		// almost always Linux pods that can't start there
+		if node := cfg.Cluster.GetNode(pod.Spec.NodeName); node != nil && nodeOS(node) == "windows" {
+			for i := range pod.Status.ContainerStatuses {
+				cs := &pod.Status.ContainerStatuses[i]
+				if cs.State.Waiting != nil && failedOnWrongOSReasons[cs.State.Waiting.Reason] {
+					return fmt.Sprintf("Pod has no %s node selector, landed on Windows node %s and container %s is failing with %s",
+						corev1.LabelOSStable, node.Name, cs.Name, cs.State.Waiting.Reason), false, true
+				}
+			}
+		}
+
+		// Otherwise it is only a matter of time if Windows nodes aren't
+		// tainted to keep other pods off them
+		untainted := make([]string, 0)
+		for i := range cfg.Cluster.Nodes {
+			node := &cfg.Cluster.Nodes[i]
+			if nodeOS(node) == "windows" && !hasNoScheduleTaint(node) {
+				untainted = append(untainted, node.Name)
+			}
+		}
+		if len(untainted) == 0 {
+			return "", false, false
+		}
+		sort.Strings(untainted)
+		return fmt.Sprintf("Pod has no %s node selector and Windows node(s) %s aren't tainted, it can be scheduled on a node of the wrong OS",
+			corev1.LabelOSStable, strings.Join(untainted, ", ")), true, true
+	},
+}
+
+// ProblemWindowsNodeMisconfigured is a problem with a Windows node that is
+// missing the labels or taint that pods are scheduled onto it with
+// https://github.com/Ashvin-Ranjan/k8r/wiki/WindowsNodeMisconfigured
+var ProblemWindowsNodeMisconfigured = Problem{
+	ID:               "WindowsNodeMisconfigured",
+	ShortDescription: "A Windows node is missing labels or a taint that scheduling on mixed clusters relies on",
+	HelpURL:          "https://github.com/Ashvin-Ranjan/k8r/wiki/WindowsNodeMisconfigured",
+	Detector: func(ctx context.Context, obj runtime.Object, cfg *Config) (string, bool, bool) {
+		node, ok := obj.(*corev1.Node)
+		if !ok || node.Status.NodeInfo.OperatingSystem != "windows" {
+			return "", false, false
+		}
+
+		reasons := make([]string, 0)
+		if os := node.Labels[corev1.LabelOSStable]; os != "windows" {
+			reasons = append(reasons, fmt.Sprintf("label %s is %q instead of \"windows\"", corev1.LabelOSStable, os))
+		}
+		if node.Labels[windowsBuildLabel] == "" {
+			reasons = append(reasons, fmt.Sprintf("label %s is missing", windowsBuildLabel))
+		}
+		if cfg.Cluster != nil && mixedOS(cfg.Cluster.Nodes) && !hasNoScheduleTaint(node) {
+			reasons = append(reasons, "it has no NoSchedule taint to keep Linux pods off it, e.g. os=windows:NoSchedule")
+		}
+
+		if len(reasons) == 0 {
+			return "", false, false
+		}
+		return fmt.Sprintf("Windows node %s", strings.Join(reasons, ", ")), true, true
+	},
+}
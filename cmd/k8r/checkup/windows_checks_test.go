@@ -0,0 +1,84 @@
+package checkup_test
+
+import (
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup/checkuptest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// newWindowsNode returns a Windows node with the labels kubelet sets
+func newWindowsNode(name string, opts ...checkuptest.NodeOption) *corev1.Node {
+	opts = append([]checkuptest.NodeOption{
+		checkuptest.NodeLabels(map[string]string{
+			"kubernetes.io/os":                 "windows",
+			"node.kubernetes.io/windows-build": "10.0.17763",
+		}),
+		func(n *corev1.Node) { n.Status.NodeInfo.OperatingSystem = "windows" },
+	}, opts...)
+	return checkuptest.NewNode(name, opts...)
+}
+
+// tainted taints a Windows node so that only Windows pods are scheduled on it
+func tainted(n *corev1.Node) {
+	n.Spec.Taints = append(n.Spec.Taints, corev1.Taint{Key: "os", Value: "windows", Effect: corev1.TaintEffectNoSchedule})
+}
+
+func TestPodMissingOSSelector(t *testing.T) {
+	linux := checkuptest.NewNode("linux-1", checkuptest.NodeLabels(map[string]string{"kubernetes.io/os": "linux"}))
+	windows := newWindowsNode("win-1")
+	taintedWindows := newWindowsNode("win-1", tainted)
+
+	onWindows := checkuptest.NewPod("web", checkuptest.OnNode("win-1"), checkuptest.ImagePullBackOff(checkuptest.DefaultContainer))
+	selectsOS := checkuptest.NewPod("web", checkuptest.WithSpec(func(s *corev1.PodSpec) {
+		s.NodeSelector = map[string]string{"kubernetes.io/os": "linux"}
+	}))
+
+	checkuptest.RunCases(t, checkup.ProblemPodMissingOSSelector, []checkuptest.Case{
+		{Name: "linux only", Object: checkuptest.NewPod("web"), Config: checkuptest.NewConfig(checkuptest.NewCluster(linux))},
+		{Name: "selects OS", Object: selectsOS, Config: checkuptest.NewConfig(checkuptest.NewCluster(linux, windows))},
+		{Name: "windows nodes tainted", Object: checkuptest.NewPod("web"), Config: checkuptest.NewConfig(checkuptest.NewCluster(linux, taintedWindows))},
+		{
+			Name:           "windows nodes not tainted",
+			Object:         checkuptest.NewPod("web"),
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(linux, windows)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "Windows node(s) win-1 aren't tainted",
+		},
+		{
+			Name:           "failing on a windows node",
+			Object:         onWindows,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(linux, taintedWindows)),
+			Occurring:      true,
+			DetailsContain: "landed on Windows node win-1 and container app is failing with ImagePullBackOff",
+		},
+	})
+}
+
+func TestWindowsNodeMisconfigured(t *testing.T) {
+	linux := checkuptest.NewNode("linux-1", checkuptest.NodeLabels(map[string]string{"kubernetes.io/os": "linux"}))
+	windows := newWindowsNode("win-1")
+	unlabeled := checkuptest.NewNode("win-1", func(n *corev1.Node) { n.Status.NodeInfo.OperatingSystem = "windows" }, tainted)
+
+	checkuptest.RunCases(t, checkup.ProblemWindowsNodeMisconfigured, []checkuptest.Case{
+		{Name: "linux", Object: linux},
+		{Name: "windows only cluster", Object: windows},
+		{
+			Name:           "missing taint",
+			Object:         windows,
+			Config:         checkuptest.NewConfig(checkuptest.NewCluster(linux, windows)),
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: "it has no NoSchedule taint",
+		},
+		{
+			Name:           "missing labels",
+			Object:         unlabeled,
+			Occurring:      true,
+			Warning:        true,
+			DetailsContain: `label kubernetes.io/os is "" instead of "windows", label node.kubernetes.io/windows-build is missing`,
+		},
+	})
+}
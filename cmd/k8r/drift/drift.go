@@ -0,0 +1,255 @@
+// Description: This file contains the code for the 'k8r drift'
+// command.
+
+// Package drift implements a 'k8r drift' command that compares the
+// workloads in two namespaces, e.g. staging and production.
+package drift
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// Options contains options for the drift command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Namespaces are the two namespaces being compared
+	Namespaces [2]string
+
+	// Kube is the kubeconfig and context to compare the namespaces in
+	Kube kube.Options
+}
+
+// NewOptions contains options for the drift command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new drift command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:      "drift",
+		Usage:     "Compare the workloads in two namespaces, e.g. staging and production",
+		ArgsUsage: "--compare <namespace> <namespace>",
+		Action: func(c *cli.Context) error {
+			if c.String("compare") == "" || c.NArg() != 1 {
+				return errors.New("expected two namespaces to compare, e.g. k8r drift --compare staging production")
+			}
+			o.Namespaces = [2]string{c.String("compare"), c.Args().First()}
+			o.Kube = kube.OptionsFromFlags(c)
+			return o.Run(c.Context)
+		},
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "compare",
+				Usage: "Namespace to compare against the namespace passed as an argument",
+			},
+		}, kube.Flags()...),
+	}
+}
+
+// Workload is the part of a workload that is compared between namespaces
+type Workload struct {
+	// Kind is the kind of the workload, e.g. Deployment
+	Kind string
+
+	// Name is the name of the workload
+	Name string
+
+	// Replicas is the desired number of replicas, or nil if the kind
+	// doesn't have replicas, e.g. DaemonSets
+	Replicas *int32
+
+	// Spec is the workload's pod template
+	Spec corev1.PodSpec
+}
+
+// key is used to match equivalent workloads across namespaces
+func (w *Workload) key() string {
+	return w.Kind + "/" + w.Name
+}
+
+// listWorkloads lists all of the workloads in a namespace, keyed by kind
+// and name
+func listWorkloads(ctx context.Context, k kubernetes.Interface, namespace string) (map[string]*Workload, error) {
+	workloads := make(map[string]*Workload)
+	add := func(w *Workload) {
+		workloads[w.key()] = w
+	}
+
+	deployments, err := k.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list deployments")
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		add(&Workload{Kind: "Deployment", Name: d.Name, Replicas: d.Spec.Replicas, Spec: d.Spec.Template.Spec})
+	}
+
+	statefulSets, err := k.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets")
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		add(&Workload{Kind: "StatefulSet", Name: s.Name, Replicas: s.Spec.Replicas, Spec: s.Spec.Template.Spec})
+	}
+
+	daemonSets, err := k.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list daemonsets")
+	}
+	for i := range daemonSets.Items {
+		d := &daemonSets.Items[i]
+		add(&Workload{Kind: "DaemonSet", Name: d.Name, Spec: d.Spec.Template.Spec})
+	}
+
+	return workloads, nil
+}
+
+// envNames returns the names of the environment variables set on a
+// container, values are left out as they often differ on purpose or
+// contain secrets
+func envNames(c *corev1.Container) string {
+	names := make([]string, 0, len(c.Env))
+	for i := range c.Env {
+		names = append(names, c.Env[i].Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// resourceString formats a container's resource requests and limits
+func resourceString(r *corev1.ResourceRequirements) string {
+	format := func(l corev1.ResourceList) string {
+		parts := make([]string, 0, len(l))
+		for name, q := range l {
+			parts = append(parts, fmt.Sprintf("%s=%s", name, q.String()))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprintf("requests[%s] limits[%s]", format(r.Requests), format(r.Limits))
+}
+
+// Diff returns the differences between two equivalent workloads
+func Diff(a, b *Workload) []string {
+	diffs := make([]string, 0)
+	differ := func(what, x, y string) {
+		if x != y {
+			diffs = append(diffs, fmt.Sprintf("%s: %q != %q", what, x, y))
+		}
+	}
+
+	if a.Replicas != nil && b.Replicas != nil && *a.Replicas != *b.Replicas {
+		diffs = append(diffs, fmt.Sprintf("replicas: %d != %d", *a.Replicas, *b.Replicas))
+	}
+
+	containers := make(map[string]*corev1.Container)
+	for i := range b.Spec.Containers {
+		containers[b.Spec.Containers[i].Name] = &b.Spec.Containers[i]
+	}
+	for i := range a.Spec.Containers {
+		ca := &a.Spec.Containers[i]
+		cb, ok := containers[ca.Name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("container %s only exists in the first namespace", ca.Name))
+			continue
+		}
+		delete(containers, ca.Name)
+
+		differ(fmt.Sprintf("container %s image", ca.Name), ca.Image, cb.Image)
+		differ(fmt.Sprintf("container %s env", ca.Name), envNames(ca), envNames(cb))
+		differ(fmt.Sprintf("container %s resources", ca.Name), resourceString(&ca.Resources), resourceString(&cb.Resources))
+	}
+	for name := range containers {
+		diffs = append(diffs, fmt.Sprintf("container %s only exists in the second namespace", name))
+	}
+
+	sort.Strings(diffs)
+	return diffs
+}
+
+// Run runs the drift command
+func (o *Options) Run(ctx context.Context) error {
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	a, err := listWorkloads(ctx, k, o.Namespaces[0])
+	if err != nil {
+		return kube.ExplainError(err)
+	}
+	b, err := listWorkloads(ctx, k, o.Namespaces[1])
+	if err != nil {
+		return kube.ExplainError(err)
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	drifted := 0
+	for _, key := range keys {
+		wa, inA := a[key]
+		wb, inB := b[key]
+
+		var diffs []string
+		switch {
+		case !inB:
+			diffs = []string{"only exists in " + o.Namespaces[0]}
+		case !inA:
+			diffs = []string{"only exists in " + o.Namespaces[1]}
+		default:
+			diffs = Diff(wa, wb)
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+
+		drifted++
+		fmt.Println(bold.Sprint(key))
+		for _, d := range diffs {
+			fmt.Println("    -", color.HiYellowString(d))
+		}
+	}
+
+	if drifted == 0 {
+		fmt.Printf("No drift between %s and %s 🎉\n", o.Namespaces[0], o.Namespaces[1])
+		return nil
+	}
+
+	fmt.Println()
+	bold.Printf("%d of %d workloads drifted between %s and %s\n", drifted, len(keys), o.Namespaces[0], o.Namespaces[1])
+	return nil
+}
@@ -0,0 +1,166 @@
+// Description: This file contains the code for the 'k8r fleet' command.
+
+// Package fleet implements a 'k8r fleet' command that summarizes the
+// latest 'k8r checkup' reports of many clusters: the findings in each
+// cluster, the problems found in all of them and the findings of each team
+// across clusters, for teams operating many clusters.
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Formats the summary can be written in
+const (
+	// FormatText is a table per section
+	FormatText = "text"
+
+	// FormatJSON is the Summary as JSON
+	FormatJSON = "json"
+)
+
+// Options contains options for the fleet command
+type Options struct {
+	log logrus.FieldLogger
+
+	// ReportDirs are the directories the reports are read from
+	ReportDirs []string
+
+	// Format is one of the Format constants
+	Format string
+
+	// OutputFile is where the summary is written, stdout when empty
+	OutputFile string
+}
+
+// NewOptions contains options for the fleet command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new fleet command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "fleet",
+		Usage: "Summarize the latest 'k8r checkup' reports of many clusters, kept with --report-dir",
+		Action: func(c *cli.Context) error {
+			o.ReportDirs = c.StringSlice("report-dir")
+			o.Format = c.String("format")
+			o.OutputFile = c.String("output-file")
+			return o.Run(c.Context)
+		},
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "report-dir",
+				Usage:    "Directory of reports written by k8r checkup --report-dir or --output json or yaml, can be passed more than once",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Format to write the summary in, one of text or json",
+				Value: FormatText,
+			},
+			&cli.StringFlag{
+				Name:  "output-file",
+				Usage: "File to write the summary to, defaults to stdout",
+			},
+		},
+	}
+}
+
+// WriteJSON writes the summary as indented JSON
+func (s *Summary) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode fleet summary")
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// severityCounts returns the counts of each severity as tab separated
+// columns
+func severityCounts(bySeverity map[string]int) string {
+	counts := make([]string, 0, len(severities))
+	for _, sev := range severities {
+		counts = append(counts, fmt.Sprint(bySeverity[sev]))
+	}
+	return strings.Join(counts, "\t")
+}
+
+// WriteText writes the summary as a table per section
+func (s *Summary) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 1, 0, 2, ' ', 0)
+	header := strings.ToUpper(strings.Join(severities, "\t"))
+
+	fmt.Fprintf(tw, "CLUSTER\tREPORT FROM\tFINDINGS\t%s\n", header)
+	for i := range s.Clusters {
+		c := &s.Clusters[i]
+		cluster := c.Cluster
+		if c.Incomplete {
+			cluster += " (incomplete)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", cluster, c.GeneratedAt.UTC().Format("2006-01-02 15:04 MST"),
+			c.Findings, severityCounts(c.BySeverity))
+	}
+
+	if len(s.Common) != 0 {
+		fmt.Fprintf(tw, "\nPROBLEM IN ALL CLUSTERS\tFINDINGS\n")
+		for i := range s.Common {
+			fmt.Fprintf(tw, "%s\t%d\n", s.Common[i].ProblemID, s.Common[i].Findings)
+		}
+	}
+
+	if len(s.Teams) != 0 {
+		fmt.Fprintf(tw, "\nTEAM\tCLUSTERS\tFINDINGS\t%s\n", header)
+		for i := range s.Teams {
+			t := &s.Teams[i]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", t.Team, t.Clusters, t.Findings, severityCounts(t.BySeverity))
+		}
+	}
+	return tw.Flush()
+}
+
+// Run runs the fleet command
+func (o *Options) Run(ctx context.Context) error {
+	reports, err := ReadLatest(o.ReportDirs)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no reports found in %s", strings.Join(o.ReportDirs, ", "))
+	}
+	s := Summarize(reports)
+
+	out := io.Writer(os.Stdout)
+	if o.OutputFile != "" {
+		f, err := os.Create(o.OutputFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to create fleet summary file")
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch o.Format {
+	case FormatText:
+		return s.WriteText(out)
+	case FormatJSON:
+		return s.WriteJSON(out)
+	}
+	return fmt.Errorf("unknown format %q, expected text or json", o.Format)
+}
@@ -0,0 +1,278 @@
+// Description: This file contains the code for reading the reports kept
+// by 'k8r checkup --report-dir' and summarizing them across clusters.
+
+package fleet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+	"github.com/pkg/errors"
+)
+
+// unowned is the team findings without an owner are counted under
+const unowned = "unowned"
+
+// reportTimeSuffix is the end of the name of a report kept by
+// 'k8r checkup --report-dir', after the cluster it's about
+const reportTimeSuffix = "-20060102T150405Z.json.gz"
+
+// shardWindow is how long before the latest report of a cluster the
+// reports of the other shards of the same scan have to be written, older
+// reports are left over from before the cluster was sharded differently
+const shardWindow = time.Hour
+
+// severities are the severities in the order they are written
+var severities = []string{report.SeverityCritical, report.SeverityError, report.SeverityWarning, report.SeverityInfo}
+
+// ClusterSummary is the summary of the latest report of a cluster
+type ClusterSummary struct {
+	// Cluster is the API server host of the cluster
+	Cluster string `json:"cluster"`
+
+	// GeneratedAt is when the oldest report summarized for the cluster
+	// was written, there is one report per shard of sharded scans
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// Findings is the number of findings in the cluster
+	Findings int `json:"findings"`
+
+	// BySeverity is the number of findings by severity
+	BySeverity map[string]int `json:"bySeverity"`
+
+	// Incomplete is true if the report doesn't cover the whole cluster,
+	// because the scan was interrupted or resources couldn't be listed
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// CommonProblem is a problem that was found in every cluster
+type CommonProblem struct {
+	// ProblemID is the ID of the problem
+	ProblemID string `json:"problemID"`
+
+	// Findings is the number of findings of the problem across clusters
+	Findings int `json:"findings"`
+}
+
+// TeamSummary is the findings a team owns across clusters
+type TeamSummary struct {
+	// Team is the owner of the findings, unowned for findings without one
+	Team string `json:"team"`
+
+	// Clusters is the number of clusters the team has findings in
+	Clusters int `json:"clusters"`
+
+	// Findings is the number of findings the team owns
+	Findings int `json:"findings"`
+
+	// BySeverity is the number of findings by severity
+	BySeverity map[string]int `json:"bySeverity"`
+}
+
+// Summary is the summary of the latest reports of many clusters
+type Summary struct {
+	// Clusters are the clusters, most findings first
+	Clusters []ClusterSummary `json:"clusters"`
+
+	// Common are the problems found in every cluster, most findings first
+	Common []CommonProblem `json:"common"`
+
+	// Teams are the teams with findings, most findings first
+	Teams []TeamSummary `json:"teams"`
+}
+
+// readReportFile reads a JSON or YAML report, which may be gzipped
+func readReportFile(path string) (*report.Report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read report")
+	}
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress report %s", path)
+		}
+		if b, err = io.ReadAll(gz); err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress report %s", path)
+		}
+	}
+	r, err := report.Read(b)
+	return r, errors.Wrapf(err, "failed to read report %s", path)
+}
+
+// isReportFile returns true if name looks like a report written with
+// --output json or yaml, or kept by --report-dir
+func isReportFile(name string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportSource returns what a report is the latest report of. Reports kept
+// by --report-dir are named after the cluster and the shard they cover,
+// other reports are the reports of their cluster.
+func reportSource(name string, r *report.Report) string {
+	if strings.HasPrefix(name, "k8r-") && strings.HasSuffix(name, ".json.gz") && len(name) > len("k8r-")+len(reportTimeSuffix) {
+		return name[:len(name)-len(reportTimeSuffix)]
+	}
+	if r.Cluster != "" {
+		return r.Cluster
+	}
+	return name
+}
+
+// ReadLatest reads the latest report of each cluster from the given
+// directories, and of each shard of clusters that are scanned in shards.
+// Reports written long before the latest report of their cluster are
+// left out. Files that aren't reports are skipped.
+func ReadLatest(dirs []string) ([]*report.Report, error) {
+	latest := make(map[string]*report.Report)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read report directory")
+		}
+		for _, e := range entries {
+			if e.IsDir() || !isReportFile(e.Name()) {
+				continue
+			}
+			r, err := readReportFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			source := reportSource(e.Name(), r)
+			if r.Cluster == "" {
+				r.Cluster = strings.TrimPrefix(source, "k8r-")
+			}
+			if l, ok := latest[source]; !ok || r.GeneratedAt.After(l.GeneratedAt) {
+				latest[source] = r
+			}
+		}
+	}
+
+	newest := make(map[string]time.Time)
+	for _, r := range latest {
+		if r.GeneratedAt.After(newest[r.Cluster]) {
+			newest[r.Cluster] = r.GeneratedAt
+		}
+	}
+	reports := make([]*report.Report, 0, len(latest))
+	for _, r := range latest {
+		if newest[r.Cluster].Sub(r.GeneratedAt) <= shardWindow {
+			reports = append(reports, r)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Cluster != reports[j].Cluster {
+			return reports[i].Cluster < reports[j].Cluster
+		}
+		return reports[i].GeneratedAt.Before(reports[j].GeneratedAt)
+	})
+	return reports, nil
+}
+
+// Summarize summarizes the reports of many clusters, reports of the same
+// cluster are the reports of its shards. Findings that are symptoms of
+// other findings aren't counted.
+func Summarize(reports []*report.Report) *Summary {
+	byCluster := make(map[string]*ClusterSummary)
+	byTeam := make(map[string]*TeamSummary)
+	teamClusters := make(map[string]map[string]bool)
+	problemClusters := make(map[string]map[string]bool)
+	problemFindings := make(map[string]int)
+
+	for _, r := range reports {
+		c, ok := byCluster[r.Cluster]
+		if !ok {
+			c = &ClusterSummary{Cluster: r.Cluster, GeneratedAt: r.GeneratedAt, BySeverity: make(map[string]int)}
+			byCluster[r.Cluster] = c
+		}
+		if r.GeneratedAt.Before(c.GeneratedAt) {
+			c.GeneratedAt = r.GeneratedAt
+		}
+		c.Incomplete = c.Incomplete || r.Interrupted || len(r.Incomplete) != 0
+
+		for i := range r.Findings {
+			f := &r.Findings[i]
+			if f.SuppressedBy != "" {
+				continue
+			}
+			c.Findings++
+			c.BySeverity[f.Severity]++
+
+			if problemClusters[f.ProblemID] == nil {
+				problemClusters[f.ProblemID] = make(map[string]bool)
+			}
+			problemClusters[f.ProblemID][r.Cluster] = true
+			problemFindings[f.ProblemID]++
+
+			team := f.Owner
+			if team == "" {
+				team = unowned
+			}
+			t, ok := byTeam[team]
+			if !ok {
+				t = &TeamSummary{Team: team, BySeverity: make(map[string]int)}
+				byTeam[team] = t
+				teamClusters[team] = make(map[string]bool)
+			}
+			t.Findings++
+			t.BySeverity[f.Severity]++
+			teamClusters[team][r.Cluster] = true
+		}
+	}
+
+	s := &Summary{
+		Clusters: make([]ClusterSummary, 0, len(byCluster)),
+		Common:   make([]CommonProblem, 0),
+		Teams:    make([]TeamSummary, 0, len(byTeam)),
+	}
+	for _, c := range byCluster {
+		s.Clusters = append(s.Clusters, *c)
+	}
+	sort.Slice(s.Clusters, func(i, j int) bool {
+		if s.Clusters[i].Findings != s.Clusters[j].Findings {
+			return s.Clusters[i].Findings > s.Clusters[j].Findings
+		}
+		return s.Clusters[i].Cluster < s.Clusters[j].Cluster
+	})
+
+	// A problem is only common when there is more than one cluster to
+	// compare
+	if len(byCluster) > 1 {
+		for id, clusters := range problemClusters {
+			if len(clusters) == len(byCluster) {
+				s.Common = append(s.Common, CommonProblem{ProblemID: id, Findings: problemFindings[id]})
+			}
+		}
+	}
+	sort.Slice(s.Common, func(i, j int) bool {
+		if s.Common[i].Findings != s.Common[j].Findings {
+			return s.Common[i].Findings > s.Common[j].Findings
+		}
+		return s.Common[i].ProblemID < s.Common[j].ProblemID
+	})
+
+	for team, t := range byTeam {
+		t.Clusters = len(teamClusters[team])
+		s.Teams = append(s.Teams, *t)
+	}
+	sort.Slice(s.Teams, func(i, j int) bool {
+		if s.Teams[i].Findings != s.Teams[j].Findings {
+			return s.Teams[i].Findings > s.Teams[j].Findings
+		}
+		return s.Teams[i].Team < s.Teams[j].Team
+	})
+	return s
+}
@@ -0,0 +1,115 @@
+package fleet_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/fleet"
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+)
+
+var now = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// writeReport writes a report to dir, gzipped if the name ends in .gz
+func writeReport(t *testing.T, dir, name string, r *report.Report) {
+	t.Helper()
+	r.SchemaVersion = report.SchemaVersion
+
+	var b bytes.Buffer
+	if filepath.Ext(name) == ".gz" {
+		gz := gzip.NewWriter(&b)
+		if err := r.WriteJSON(gz); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else if err := r.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFleet(t *testing.T) {
+	dir := t.TempDir()
+	crash := report.Finding{ProblemID: "PodCrashLoopBackOff", Type: "pod", Resource: "web/api",
+		Severity: report.SeverityError, Owner: "payments"}
+	notReady := report.Finding{ProblemID: "NodeNotReady", Type: "node", Resource: "node-1", Severity: report.SeverityCritical}
+	limits := report.Finding{ProblemID: "NoLimits", Type: "pod", Resource: "web/api",
+		Severity: report.SeverityWarning, Owner: "payments"}
+	pending := report.Finding{ProblemID: "PodPending", Type: "pod", Resource: "web/api",
+		Severity: report.SeverityError, Owner: "payments", SuppressedBy: "PodCrashLoopBackOff"}
+
+	// Only the latest report of prod is summarized, and both shards of its
+	// latest scan are
+	writeReport(t, dir, "k8r-prod-20220530T120000Z.json.gz", &report.Report{
+		GeneratedAt: now.Add(-48 * time.Hour), Cluster: "prod",
+		Findings: []report.Finding{crash, notReady, limits},
+	})
+	writeReport(t, dir, "k8r-prod_shard_1_2-20220601T120000Z.json.gz", &report.Report{
+		GeneratedAt: now, Cluster: "prod", Findings: []report.Finding{crash, pending},
+	})
+	writeReport(t, dir, "k8r-prod_shard_2_2-20220601T120100Z.json.gz", &report.Report{
+		GeneratedAt: now.Add(time.Minute), Cluster: "prod", Findings: []report.Finding{limits},
+	})
+	writeReport(t, dir, "staging.json", &report.Report{
+		GeneratedAt: now, Cluster: "staging", Incomplete: []report.Incomplete{{Resource: "secrets", Reason: "forbidden"}},
+		Findings: []report.Finding{crash, notReady},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a report"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := fleet.ReadLatest([]string{dir})
+	if err != nil {
+		t.Fatalf("ReadLatest() error = %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("ReadLatest() returned %d reports, expected the 2 shards of prod and staging", len(reports))
+	}
+
+	s := fleet.Summarize(reports)
+	want := &fleet.Summary{
+		Clusters: []fleet.ClusterSummary{
+			{Cluster: "prod", GeneratedAt: now, Findings: 2,
+				BySeverity: map[string]int{report.SeverityError: 1, report.SeverityWarning: 1}},
+			{Cluster: "staging", GeneratedAt: now, Findings: 2, Incomplete: true,
+				BySeverity: map[string]int{report.SeverityCritical: 1, report.SeverityError: 1}},
+		},
+		Common: []fleet.CommonProblem{{ProblemID: "PodCrashLoopBackOff", Findings: 2}},
+		Teams: []fleet.TeamSummary{
+			{Team: "payments", Clusters: 2, Findings: 3,
+				BySeverity: map[string]int{report.SeverityError: 2, report.SeverityWarning: 1}},
+			{Team: "unowned", Clusters: 1, Findings: 1, BySeverity: map[string]int{report.SeverityCritical: 1}},
+		},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Summarize() = %+v, expected %+v", s, want)
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `CLUSTER               REPORT FROM           FINDINGS  CRITICAL  ERROR  WARNING  INFO
+prod                  2022-06-01 12:00 UTC  2         0         1      1        0
+staging (incomplete)  2022-06-01 12:00 UTC  2         1         1      0        0
+
+PROBLEM IN ALL CLUSTERS  FINDINGS
+PodCrashLoopBackOff      2
+
+TEAM      CLUSTERS  FINDINGS  CRITICAL  ERROR  WARNING  INFO
+payments  2         3         0         2      1        0
+unowned   1         1         1         0      0        0
+`
+	if b.String() != wantText {
+		t.Errorf("WriteText() = \n%s\nexpected\n%s", b.String(), wantText)
+	}
+}
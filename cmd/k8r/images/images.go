@@ -0,0 +1,362 @@
+// Description: This file contains the code for the 'k8r images' command.
+
+// Package images implements a 'k8r images' command that lists the
+// container images in use and, with --sizes, how large they are on the
+// nodes, since multi-GB images slow down autoscaling.
+package images
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// maxConsumers is the number of consumers shown for each image, the rest
+// are counted
+const maxConsumers = 3
+
+// Options contains options for the images command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Sizes is true if images are sorted by their size on the nodes
+	Sizes bool
+
+	// Top is the number of images shown, 0 for all of them
+	Top int
+
+	// Kube is the kubeconfig and context of the cluster to list images in
+	Kube kube.Options
+}
+
+// NewOptions contains options for the images command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new images command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "images",
+		Usage: "List the container images in use and the workloads using them",
+		Action: func(c *cli.Context) error {
+			o.Sizes = c.Bool("sizes")
+			o.Top = c.Int("top")
+			o.Kube = kube.OptionsFromFlags(c)
+			return o.Run(c.Context)
+		},
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "sizes",
+				Usage: "Show the size of each image on the nodes and list the largest images first",
+			},
+			&cli.IntFlag{
+				Name:  "top",
+				Usage: "Number of images to show, 0 for all of them",
+				Value: 20,
+			},
+		}, kube.Flags()...),
+	}
+}
+
+// Image is a container image used by pods in the cluster
+type Image struct {
+	// Name is the image as pods refer to it, e.g. nginx:1.25
+	Name string
+
+	// SizeBytes is the size of the image as reported by the nodes, 0 when
+	// no node reported it
+	SizeBytes int64
+
+	// Nodes is the number of nodes the image is on
+	Nodes int
+
+	// Pods is the number of pods using the image
+	Pods int
+
+	// Consumers are the workloads using the image, e.g.
+	// default/Deployment/api, sorted
+	Consumers []string
+}
+
+// normalize returns the fully qualified form of an image reference that
+// container runtimes report, e.g. nginx becomes
+// docker.io/library/nginx:latest
+func normalize(image string) string {
+	image = strings.TrimPrefix(image, "docker-pullable://")
+	image = strings.TrimPrefix(image, "docker://")
+
+	name := image
+	if i := strings.Index(name, "/"); i < 0 {
+		name = "docker.io/library/" + name
+	} else if host := name[:i]; !strings.ContainsAny(host, ".:") && host != "localhost" {
+		name = "docker.io/" + name
+	}
+
+	// Images without a tag or digest are pulled as latest
+	last := name[strings.LastIndex(name, "/")+1:]
+	if !strings.ContainsAny(last, ":@") {
+		name += ":latest"
+	}
+	return name
+}
+
+// nodeImage is an image reported in a node's status
+type nodeImage struct {
+	// sizeBytes is the size of the image
+	sizeBytes int64
+
+	// nodes are the nodes the image is on
+	nodes map[string]bool
+}
+
+// nodeImages returns the images the nodes reported by each of their
+// normalized names
+func nodeImages(nodes []corev1.Node) map[string]*nodeImage {
+	images := make(map[string]*nodeImage)
+	for i := range nodes {
+		n := &nodes[i]
+		for j := range n.Status.Images {
+			ci := &n.Status.Images[j]
+
+			// Every name of the image refers to the same image
+			var img *nodeImage
+			for _, name := range ci.Names {
+				if existing, ok := images[normalize(name)]; ok {
+					img = existing
+					break
+				}
+			}
+			if img == nil {
+				img = &nodeImage{sizeBytes: ci.SizeBytes, nodes: make(map[string]bool)}
+			}
+			img.nodes[n.Name] = true
+			for _, name := range ci.Names {
+				images[normalize(name)] = img
+			}
+		}
+	}
+	return images
+}
+
+// consumer returns the workload that owns a pod, pods owned by a
+// ReplicaSet are attributed to its Deployment
+func consumer(pod *corev1.Pod) string {
+	for i := range pod.OwnerReferences {
+		ref := &pod.OwnerReferences[i]
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ref.Kind == "ReplicaSet" &&
+			hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return fmt.Sprintf("%s/Deployment/%s", pod.Namespace, strings.TrimSuffix(ref.Name, "-"+hash))
+		}
+		return fmt.Sprintf("%s/%s/%s", pod.Namespace, ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("%s/Pod/%s", pod.Namespace, pod.Name)
+}
+
+// imageID returns the ID of the image a container runs, if it has started
+func imageID(pod *corev1.Pod, container string) string {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for i := range statuses {
+			if statuses[i].Name == container {
+				return statuses[i].ImageID
+			}
+		}
+	}
+	return ""
+}
+
+// Images returns the images used by the pods with the sizes and nodes the
+// nodes reported for them, sorted by the number of pods using them
+func Images(pods []corev1.Pod, nodes []corev1.Node) []Image {
+	onNodes := nodeImages(nodes)
+
+	byName := make(map[string]*Image)
+	consumers := make(map[string]map[string]bool)
+	for i := range pods {
+		p := &pods[i]
+		containers := append(append([]corev1.Container{}, p.Spec.InitContainers...), p.Spec.Containers...)
+		seen := make(map[string]bool)
+		for j := range containers {
+			c := &containers[j]
+			if seen[c.Image] {
+				continue
+			}
+			seen[c.Image] = true
+
+			img, ok := byName[c.Image]
+			if !ok {
+				img = &Image{Name: c.Image}
+				byName[c.Image] = img
+				consumers[c.Image] = make(map[string]bool)
+			}
+			img.Pods++
+			consumers[c.Image][consumer(p)] = true
+
+			// The image ID is the most precise match, the tag may have
+			// been moved to another image since the pod started
+			if img.SizeBytes != 0 {
+				continue
+			}
+			for _, ref := range []string{imageID(p, c.Name), c.Image} {
+				if ref == "" {
+					continue
+				}
+				if n, ok := onNodes[normalize(ref)]; ok {
+					img.SizeBytes = n.sizeBytes
+					img.Nodes = len(n.nodes)
+					break
+				}
+			}
+		}
+	}
+
+	images := make([]Image, 0, len(byName))
+	for name, img := range byName {
+		for c := range consumers[name] {
+			img.Consumers = append(img.Consumers, c)
+		}
+		sort.Strings(img.Consumers)
+		images = append(images, *img)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Pods != images[j].Pods {
+			return images[i].Pods > images[j].Pods
+		}
+		return images[i].Name < images[j].Name
+	})
+	return images
+}
+
+// SortBySize sorts images largest first
+func SortBySize(images []Image) {
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].SizeBytes > images[j].SizeBytes
+	})
+}
+
+// FormatBytes formats a size in bytes, e.g. 1.3GiB
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGT"[exp])
+}
+
+// consumerList formats the consumers of an image, only the first few are
+// named
+func consumerList(consumers []string) string {
+	if len(consumers) <= maxConsumers {
+		return strings.Join(consumers, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(consumers[:maxConsumers], ", "), len(consumers)-maxConsumers)
+}
+
+// list lists the pods and, for sizes, the nodes of the cluster
+func (o *Options) list(ctx context.Context, k kubernetes.Interface) ([]corev1.Pod, []corev1.Node, error) {
+	pods, err := k.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list pods")
+	}
+	if !o.Sizes {
+		return pods.Items, nil, nil
+	}
+
+	nodes, err := k.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list nodes")
+	}
+	return pods.Items, nodes.Items, nil
+}
+
+// Run runs the images command
+func (o *Options) Run(ctx context.Context) error {
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	pods, nodes, err := o.list(ctx, k)
+	if err != nil {
+		return kube.ExplainError(err)
+	}
+
+	images := Images(pods, nodes)
+	if o.Sizes {
+		SortBySize(images)
+	}
+	total := len(images)
+	if o.Top > 0 && len(images) > o.Top {
+		images = images[:o.Top]
+	}
+
+	if o.Sizes {
+		bold.Println("📦  Largest images in use:")
+	} else {
+		bold.Println("📦  Images in use:")
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	if o.Sizes {
+		fmt.Fprintln(tw, "    SIZE\tIMAGE\tNODES\tPODS\tUSED BY")
+	} else {
+		fmt.Fprintln(tw, "    IMAGE\tPODS\tUSED BY")
+	}
+	unknown := 0
+	for i := range images {
+		img := &images[i]
+		if !o.Sizes {
+			fmt.Fprintf(tw, "    %s\t%d\t%s\n", img.Name, img.Pods, consumerList(img.Consumers))
+			continue
+		}
+
+		size := "?"
+		if img.SizeBytes != 0 {
+			size = FormatBytes(img.SizeBytes)
+		} else {
+			unknown++
+		}
+		fmt.Fprintf(tw, "    %s\t%s\t%d\t%d\t%s\n", size, img.Name, img.Nodes, img.Pods, consumerList(img.Consumers))
+	}
+	tw.Flush()
+
+	if len(images) < total {
+		fmt.Printf("\n%d of %d images shown, use --top 0 to see all of them\n", len(images), total)
+	}
+	if unknown != 0 {
+		// Nodes only report their 50 largest images by default
+		fmt.Printf("\nNo node reported the size of %d images, nodes only report their largest images\n", unknown)
+	}
+	return nil
+}
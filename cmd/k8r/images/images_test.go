@@ -0,0 +1,73 @@
+package images_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func pod(name, image, imageID string, owner *metav1.OwnerReference, labels map[string]string) corev1.Pod {
+	p := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+		Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "app", ImageID: imageID}}},
+	}
+	if owner != nil {
+		p.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return p
+}
+
+func controller(kind, name string) *metav1.OwnerReference {
+	yes := true
+	return &metav1.OwnerReference{Kind: kind, Name: name, Controller: &yes}
+}
+
+func node(name string, images ...corev1.ContainerImage) corev1.Node {
+	return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: corev1.NodeStatus{Images: images}}
+}
+
+func TestImages(t *testing.T) {
+	ml := corev1.ContainerImage{
+		Names:     []string{"example.com/ml@sha256:abc", "example.com/ml:2.0"},
+		SizeBytes: 6 << 30,
+	}
+	nginx := corev1.ContainerImage{
+		Names:     []string{"docker.io/library/nginx@sha256:def", "docker.io/library/nginx:1.25"},
+		SizeBytes: 70 << 20,
+	}
+
+	pods := []corev1.Pod{
+		pod("web-7d9f-a", "nginx:1.25", "", controller("ReplicaSet", "web-7d9f"), map[string]string{"pod-template-hash": "7d9f"}),
+		pod("web-7d9f-b", "nginx:1.25", "", controller("ReplicaSet", "web-7d9f"), map[string]string{"pod-template-hash": "7d9f"}),
+		pod("trainer-0", "example.com/ml:latest", "example.com/ml@sha256:abc", controller("StatefulSet", "trainer"), nil),
+		pod("debug", "busybox", "", nil, nil),
+	}
+	nodes := []corev1.Node{node("node-1", ml, nginx), node("node-2", ml)}
+
+	got := images.Images(pods, nodes)
+	images.SortBySize(got)
+	want := []images.Image{
+		{Name: "example.com/ml:latest", SizeBytes: 6 << 30, Nodes: 2, Pods: 1, Consumers: []string{"default/StatefulSet/trainer"}},
+		{Name: "nginx:1.25", SizeBytes: 70 << 20, Nodes: 1, Pods: 2, Consumers: []string{"default/Deployment/web"}},
+		{Name: "busybox", Pods: 1, Consumers: []string{"default/Pod/debug"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Images() = %+v, expected %+v", got, want)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for b, want := range map[int64]string{
+		512:             "512B",
+		70 << 20:        "70.0MiB",
+		6<<30 + 300<<20: "6.3GiB",
+	} {
+		if got := images.FormatBytes(b); got != want {
+			t.Errorf("FormatBytes(%d) = %q, expected %q", b, got, want)
+		}
+	}
+}
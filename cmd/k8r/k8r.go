@@ -16,7 +16,17 @@ import (
 
 	// Place any extra imports for your startup code here
 	// <<Stencil::Block(imports)>>
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/auth"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/bench"
 	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/drift"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/fleet"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/images"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/quota"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/rbac"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/scorecard"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/startup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/top"
 	// <</Stencil::Block>>
 )
 
@@ -64,6 +74,17 @@ func main() {
 	app.Commands = []*cli.Command{
 		// <<Stencil::Block(commands)>>
 		checkup.NewCommand(log),
+		checkup.NewLintCommand(log),
+		bench.NewCommand(log),
+		drift.NewCommand(log),
+		images.NewCommand(log),
+		auth.NewCommand(log),
+		rbac.NewCommand(log),
+		startup.NewCommand(log),
+		top.NewCommand(log),
+		scorecard.NewCommand(log),
+		quota.NewCommand(log),
+		fleet.NewCommand(log),
 		// <</Stencil::Block>>
 	}
 
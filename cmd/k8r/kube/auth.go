@@ -0,0 +1,134 @@
+// Description: This file contains code for authenticating with clusters
+// and explaining authentication failures
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	// Registers the oidc auth provider, exec credential plugins, e.g.
+	// aws eks get-token, are supported by client-go without registering
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+)
+
+// removedAuthProviders are auth providers that were removed from client-go
+// in favour of exec credential plugins, mapped to the plugin to use instead
+var removedAuthProviders = map[string]string{
+	"gcp":   "gke-gcloud-auth-plugin (https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-access-for-kubectl)",
+	"azure": "kubelogin (https://azure.github.io/kubelogin/)",
+}
+
+// checkAuthProvider returns an error explaining how to migrate when the
+// kubeconfig uses an auth provider that is no longer supported
+func checkAuthProvider(config *rest.Config) error {
+	if config.AuthProvider == nil {
+		return nil
+	}
+
+	if plugin, ok := removedAuthProviders[config.AuthProvider.Name]; ok {
+		return fmt.Errorf("the %s auth provider in your kubeconfig is no longer supported, use %s instead",
+			config.AuthProvider.Name, plugin)
+	}
+	return nil
+}
+
+// AuthMethod returns a short description of how the client config
+// authenticates, e.g. exec plugin (aws)
+func AuthMethod(config *rest.Config) string {
+	switch {
+	case config.ExecProvider != nil:
+		return fmt.Sprintf("exec plugin (%s)", config.ExecProvider.Command)
+	case config.AuthProvider != nil:
+		return fmt.Sprintf("%s auth provider", config.AuthProvider.Name)
+	case len(config.CertData) != 0 || config.CertFile != "":
+		return "client certificate"
+	case config.BearerToken != "" || config.BearerTokenFile != "":
+		return "bearer token"
+	case config.Username != "":
+		return "basic auth"
+	}
+	return "none"
+}
+
+// ExplainError adds a hint on how to fix authentication failures to errors
+// returned by the API server or the credential plugin, other errors are
+// returned as is
+func ExplainError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsUnauthorized(err):
+		return errors.Wrap(err, "the cluster rejected the credentials in your kubeconfig, they may have expired, log in again and retry")
+	case strings.Contains(err.Error(), "getting credentials: exec:"):
+		return errors.Wrap(err, "the credential plugin in your kubeconfig failed, check that it is installed and works on its own")
+	case strings.Contains(err.Error(), "no Auth Provider found"):
+		return errors.Wrap(err, "the auth provider in your kubeconfig isn't supported, use an exec credential plugin instead")
+	}
+	return err
+}
+
+// selfSubjectReviewVersions are the authentication.k8s.io versions that
+// serve SelfSubjectReviews, newest first
+var selfSubjectReviewVersions = []string{"v1", "v1beta1", "v1alpha1"}
+
+// Identity is who the API server authenticated the client as
+type Identity struct {
+	// Username is the name of the user
+	Username string
+
+	// Groups are the groups the user is in
+	Groups []string
+}
+
+// WhoAmI returns who the API server authenticates the client as. This
+// requires SelfSubjectReviews, which were added in Kubernetes 1.26, ok is
+// false when they aren't served.
+func WhoAmI(ctx context.Context, k kubernetes.Interface) (identity *Identity, ok bool, err error) {
+	rc := k.Discovery().RESTClient()
+	if c, isRest := rc.(*rest.RESTClient); rc == nil || (isRest && c == nil) {
+		return nil, false, nil
+	}
+
+	for _, version := range selfSubjectReviewVersions {
+		body := fmt.Sprintf(`{"apiVersion":"authentication.k8s.io/%s","kind":"SelfSubjectReview"}`, version)
+		raw, err := rc.Post().
+			AbsPath("/apis/authentication.k8s.io", version, "selfsubjectreviews").
+			SetHeader("Content-Type", "application/json").
+			Body([]byte(body)).
+			Do(ctx).
+			Raw()
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(ExplainError(err), "failed to get identity")
+		}
+
+		var review struct {
+			Status struct {
+				UserInfo struct {
+					Username string   `json:"username"`
+					Groups   []string `json:"groups"`
+				} `json:"userInfo"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(raw, &review); err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode SelfSubjectReview")
+		}
+
+		return &Identity{
+			Username: review.Status.UserInfo.Username,
+			Groups:   review.Status.UserInfo.Groups,
+		}, true, nil
+	}
+
+	return nil, false, nil
+}
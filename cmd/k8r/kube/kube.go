@@ -0,0 +1,100 @@
+// Description: This file contains code for creating Kubernetes clients
+// from a kubeconfig
+
+// Package kube contains code for creating Kubernetes clients shared by
+// the k8r commands.
+package kube
+
+import (
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Options are the options used to load the kubeconfig a client is
+// created from
+type Options struct {
+	// Kubeconfig is the path to the kubeconfig, when empty the default
+	// loading rules are used, i.e. $KUBECONFIG or ~/.kube/config
+	Kubeconfig string
+
+	// Context is the kubeconfig context to use, when empty the current
+	// context is used
+	Context string
+}
+
+// Flags returns the flags used to choose which cluster commands talk to
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "kubeconfig",
+			Usage: "Path to the kubeconfig to use, defaults to $KUBECONFIG or ~/.kube/config",
+		},
+		&cli.StringFlag{
+			Name:  "context",
+			Usage: "The kubeconfig context to use, defaults to the current context",
+		},
+	}
+}
+
+// OptionsFromFlags returns the Options set by the flags returned by Flags
+func OptionsFromFlags(c *cli.Context) Options {
+	return Options{
+		Kubeconfig: c.String("kubeconfig"),
+		Context:    c.String("context"),
+	}
+}
+
+// clientConfig returns the kubeconfig loader for the kubeconfig and context
+func (o *Options) clientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: o.Context}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// RESTConfig loads the client config for the kubeconfig and context, when
+// no kubeconfig is found the in-cluster config is used
+func (o *Options) RESTConfig() (*rest.Config, error) {
+	config, err := o.clientConfig().ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kubeconfig")
+	}
+
+	if err := checkAuthProvider(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ContextName returns the name of the kubeconfig context that is used, or
+// an empty string if there isn't one, e.g. when running in a pod
+func (o *Options) ContextName() string {
+	if o.Context != "" {
+		return o.Context
+	}
+
+	raw, err := o.clientConfig().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return raw.CurrentContext
+}
+
+// NewClient creates a Kubernetes client for the kubeconfig and context
+func (o *Options) NewClient() (kubernetes.Interface, error) {
+	config, err := o.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+	return k, nil
+}
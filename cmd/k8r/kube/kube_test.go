@@ -0,0 +1,105 @@
+package kube_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// kubeconfig has a context for each way of authenticating that is tested
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: token
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: token
+  context: {cluster: dev, user: token}
+- name: exec
+  context: {cluster: dev, user: exec}
+- name: gcp
+  context: {cluster: dev, user: gcp}
+users:
+- name: token
+  user:
+    token: abc
+- name: exec
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: [eks, get-token, --cluster-name, dev]
+- name: gcp
+  user:
+    auth-provider:
+      name: gcp
+`
+
+func writeKubeconfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRESTConfig(t *testing.T) {
+	path := writeKubeconfig(t)
+
+	tests := []struct {
+		context     string
+		wantContext string
+		wantMethod  string
+		wantErr     string
+	}{
+		{context: "", wantContext: "token", wantMethod: "bearer token"},
+		{context: "exec", wantContext: "exec", wantMethod: "exec plugin (aws)"},
+		{context: "gcp", wantErr: "use gke-gcloud-auth-plugin"},
+		{context: "missing", wantErr: "failed to load kubeconfig"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.context, func(t *testing.T) {
+			o := kube.Options{Kubeconfig: path, Context: tt.context}
+			config, err := o.RESTConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RESTConfig() error = %v, expected it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RESTConfig() error = %v", err)
+			}
+
+			if got := o.ContextName(); got != tt.wantContext {
+				t.Errorf("ContextName() = %q, expected %q", got, tt.wantContext)
+			}
+			if got := kube.AuthMethod(config); got != tt.wantMethod {
+				t.Errorf("AuthMethod() = %q, expected %q", got, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestExplainError(t *testing.T) {
+	err := kube.ExplainError(apierrors.NewUnauthorized("Unauthorized"))
+	if !strings.Contains(err.Error(), "log in again") {
+		t.Errorf("ExplainError() = %v, expected a hint to log in again", err)
+	}
+	if !apierrors.IsUnauthorized(err) {
+		t.Errorf("ExplainError() = %v, expected the original error to be kept", err)
+	}
+
+	if got := kube.ExplainError(nil); got != nil {
+		t.Errorf("ExplainError(nil) = %v, expected nil", got)
+	}
+}
@@ -0,0 +1,226 @@
+// Description: This file contains the code for the 'k8r quota' command.
+
+// Package quota implements a 'k8r quota' command that shows how much of
+// their ResourceQuotas namespaces use and what their LimitRanges default
+// containers to, as an at-a-glance capacity view for namespace admins.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// usage thresholds the percentage is highlighted at
+const (
+	// warnPercent is the usage shown in yellow
+	warnPercent = 75
+
+	// fullPercent is the usage shown in red, new pods are close to being
+	// rejected
+	fullPercent = 90
+)
+
+// Options contains options for the quota command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Namespace is the namespace quotas are listed in, empty for all
+	// namespaces
+	Namespace string
+
+	// HistoryFile is where usage is recorded between runs, empty to not
+	// show trends
+	HistoryFile string
+
+	// TrendWindow is how far back usage is compared to for the trend
+	TrendWindow time.Duration
+
+	// Kube is the kubeconfig and context of the cluster
+	Kube kube.Options
+}
+
+// NewOptions contains options for the quota command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new quota command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "quota",
+		Usage: "Show how much of their ResourceQuotas namespaces use and the defaults their LimitRanges set",
+		Action: func(c *cli.Context) error {
+			o.Namespace = c.String("namespace")
+			o.HistoryFile = c.String("history-file")
+			o.TrendWindow = c.Duration("trend-window")
+			o.Kube = kube.OptionsFromFlags(c)
+			return o.Run(c.Context)
+		},
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:    "namespace",
+				Aliases: []string{"n"},
+				Usage:   "Namespace to show, defaults to all namespaces",
+			},
+			&cli.StringFlag{
+				Name:  "history-file",
+				Usage: "File usage is recorded in between runs to show trends, set to an empty string to disable",
+				Value: DefaultHistoryFile(),
+			},
+			&cli.DurationFlag{
+				Name:  "trend-window",
+				Usage: "How far back usage is compared to for the trend, the oldest run within it is used",
+				Value: 7 * 24 * time.Hour,
+			},
+		}, kube.Flags()...),
+	}
+}
+
+// formatPercent formats usage in percent, colored when it is high
+func formatPercent(p float64) string {
+	s := fmt.Sprintf("%.0f%%", p)
+	switch {
+	case p >= fullPercent:
+		return color.RedString(s)
+	case p >= warnPercent:
+		return color.YellowString(s)
+	}
+	return s
+}
+
+// formatQuantity formats a quantity of a LimitRange, - when it isn't set
+func formatQuantity(q *resource.Quantity) string {
+	if q == nil {
+		return "-"
+	}
+	return q.String()
+}
+
+// Run runs the quota command
+func (o *Options) Run(ctx context.Context) error {
+	config, err := o.Kube.RESTConfig()
+	if err != nil {
+		return err
+	}
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	quotas, err := k.CoreV1().ResourceQuotas(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return kube.ExplainError(errors.Wrap(err, "failed to list resourcequotas"))
+	}
+	usage := QuotaUsage(quotas.Items)
+
+	// LimitRanges only add defaults to the view, so it works without them
+	var defaults []LimitDefault
+	if list, err := k.CoreV1().LimitRanges(o.Namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		o.log.WithError(err).Debug("Failed to list limitranges, their defaults aren't shown")
+	} else {
+		defaults = LimitDefaults(list.Items)
+	}
+
+	if len(usage) == 0 && len(defaults) == 0 {
+		fmt.Println("No ResourceQuotas or LimitRanges found")
+		return nil
+	}
+
+	// History is best effort, failing to read or write it only hides the
+	// trend
+	now := time.Now().UTC()
+	var h History
+	if o.HistoryFile != "" {
+		if h, err = LoadHistory(o.HistoryFile); err != nil {
+			o.log.WithError(err).Warn("failed to load quota history")
+		}
+	}
+
+	namespaces := make([]string, 0)
+	seen := make(map[string]bool)
+	for i := range usage {
+		if !seen[usage[i].Namespace] {
+			seen[usage[i].Namespace] = true
+			namespaces = append(namespaces, usage[i].Namespace)
+		}
+	}
+	for i := range defaults {
+		if !seen[defaults[i].Namespace] {
+			seen[defaults[i].Namespace] = true
+			namespaces = append(namespaces, defaults[i].Namespace)
+		}
+	}
+
+	for n, ns := range namespaces {
+		if n != 0 {
+			fmt.Println()
+		}
+		bold.Printf("📦 %s\n", ns)
+
+		tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		header := false
+		for i := range usage {
+			u := &usage[i]
+			if u.Namespace != ns {
+				continue
+			}
+			if !header {
+				fmt.Fprintln(tw, "    QUOTA\tRESOURCE\tUSED\tHARD\tUSAGE\tTREND")
+				header = true
+			}
+			trend := "-"
+			if change, ago, ok := h.Trend(config.Host, u, now, o.TrendWindow); ok {
+				trend = fmt.Sprintf("%+.0f%% in %s", change, duration.HumanDuration(ago))
+			}
+			fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\t%s\t%s\n", u.Quota, u.Resource, u.Used.String(), u.Hard.String(),
+				formatPercent(u.Percent), trend)
+		}
+		tw.Flush()
+
+		tw = tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		header = false
+		for i := range defaults {
+			d := &defaults[i]
+			if d.Namespace != ns {
+				continue
+			}
+			if !header {
+				fmt.Fprintln(tw, "    LIMITRANGE\tTYPE\tRESOURCE\tDEFAULT REQUEST\tDEFAULT LIMIT\tMIN\tMAX")
+				header = true
+			}
+			fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.LimitRange, d.Type, d.Resource,
+				formatQuantity(d.DefaultRequest), formatQuantity(d.Default), formatQuantity(d.Min), formatQuantity(d.Max))
+		}
+		tw.Flush()
+	}
+
+	if o.HistoryFile != "" && h != nil {
+		h.Add(config.Host, usage, now)
+		if err := h.Save(o.HistoryFile); err != nil {
+			o.log.WithError(err).Warn("failed to save quota history")
+		}
+	}
+	return nil
+}
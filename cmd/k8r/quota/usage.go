@@ -0,0 +1,263 @@
+// Description: This file contains code for working out how much of their
+// ResourceQuotas namespaces use, how that changed since earlier runs and
+// the defaults their LimitRanges set
+
+package quota
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// history constants
+const (
+	// historyMaxAge is how long usage samples are kept for
+	historyMaxAge = 31 * 24 * time.Hour
+
+	// historyMaxSamples is how many samples are kept per quota resource
+	historyMaxSamples = 500
+)
+
+// Usage is how much of one resource of a ResourceQuota is used
+type Usage struct {
+	// Namespace is the namespace of the quota
+	Namespace string
+
+	// Quota is the name of the ResourceQuota
+	Quota string
+
+	// Resource is the resource the quota limits, e.g. requests.cpu
+	Resource corev1.ResourceName
+
+	// Used is how much of the resource is in use
+	Used resource.Quantity
+
+	// Hard is the limit the quota sets
+	Hard resource.Quantity
+
+	// Percent is how much of the limit is used, in percent
+	Percent float64
+}
+
+// Key identifies a quota resource across runs
+func (u *Usage) Key() string {
+	return u.Namespace + "/" + u.Quota + "/" + string(u.Resource)
+}
+
+// percent returns how much of hard used is, in percent. A limit of zero
+// is fully used as nothing more can be created.
+func percent(used, hard resource.Quantity) float64 {
+	if hard.IsZero() {
+		if used.IsZero() {
+			return 0
+		}
+		return 100
+	}
+	return float64(used.MilliValue()) / float64(hard.MilliValue()) * 100
+}
+
+// QuotaUsage returns the usage of every resource of the quotas, by
+// namespace, quota and resource
+func QuotaUsage(quotas []corev1.ResourceQuota) []Usage {
+	usage := make([]Usage, 0)
+	for i := range quotas {
+		q := &quotas[i]
+		for name, hard := range q.Status.Hard {
+			used := q.Status.Used[name]
+			usage = append(usage, Usage{
+				Namespace: q.Namespace,
+				Quota:     q.Name,
+				Resource:  name,
+				Used:      used,
+				Hard:      hard,
+				Percent:   percent(used, hard),
+			})
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Key() < usage[j].Key()
+	})
+	return usage
+}
+
+// LimitDefault is what a LimitRange sets for one resource of one type of
+// object
+type LimitDefault struct {
+	// Namespace is the namespace of the LimitRange
+	Namespace string
+
+	// LimitRange is the name of the LimitRange
+	LimitRange string
+
+	// Type is what the limits apply to, e.g. Container
+	Type corev1.LimitType
+
+	// Resource is the resource, e.g. memory
+	Resource corev1.ResourceName
+
+	// DefaultRequest is the request set on containers without one
+	DefaultRequest *resource.Quantity
+
+	// Default is the limit set on containers without one
+	Default *resource.Quantity
+
+	// Min is the smallest request allowed
+	Min *resource.Quantity
+
+	// Max is the largest limit allowed
+	Max *resource.Quantity
+}
+
+// LimitDefaults returns what the LimitRanges set, by namespace,
+// LimitRange, type and resource
+func LimitDefaults(limitRanges []corev1.LimitRange) []LimitDefault {
+	defaults := make([]LimitDefault, 0)
+	for i := range limitRanges {
+		lr := &limitRanges[i]
+		for j := range lr.Spec.Limits {
+			item := &lr.Spec.Limits[j]
+
+			resources := make(map[corev1.ResourceName]bool)
+			for _, list := range []corev1.ResourceList{item.DefaultRequest, item.Default, item.Min, item.Max} {
+				for name := range list {
+					resources[name] = true
+				}
+			}
+
+			// lookup returns the quantity for the resource, if it is set
+			lookup := func(list corev1.ResourceList, name corev1.ResourceName) *resource.Quantity {
+				if q, ok := list[name]; ok {
+					return &q
+				}
+				return nil
+			}
+			for name := range resources {
+				defaults = append(defaults, LimitDefault{
+					Namespace:      lr.Namespace,
+					LimitRange:     lr.Name,
+					Type:           item.Type,
+					Resource:       name,
+					DefaultRequest: lookup(item.DefaultRequest, name),
+					Default:        lookup(item.Default, name),
+					Min:            lookup(item.Min, name),
+					Max:            lookup(item.Max, name),
+				})
+			}
+		}
+	}
+	sort.Slice(defaults, func(i, j int) bool {
+		a, b := &defaults[i], &defaults[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.LimitRange != b.LimitRange {
+			return a.LimitRange < b.LimitRange
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Resource < b.Resource
+	})
+	return defaults
+}
+
+// Sample is how much of a quota resource was used at one point in time
+type Sample struct {
+	// Time is when the usage was recorded
+	Time time.Time `json:"time"`
+
+	// Percent is how much of the limit was used, in percent
+	Percent float64 `json:"percent"`
+}
+
+// History is the usage recorded by earlier runs, keyed by the cluster
+// and then by Usage.Key
+type History map[string]map[string][]Sample
+
+// DefaultHistoryFile returns where history is stored by default, or an
+// empty string if there is no cache directory
+func DefaultHistoryFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "k8r", "quota.json")
+}
+
+// LoadHistory reads the history from a file, a file that doesn't exist
+// yet is an empty history
+func LoadHistory(path string) (History, error) {
+	h := make(History)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read quota history")
+	}
+
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, errors.Wrap(err, "failed to parse quota history")
+	}
+	return h, nil
+}
+
+// Save writes the history to a file
+func (h History) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create quota history directory")
+	}
+
+	b, err := json.Marshal(h)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal quota history")
+	}
+
+	return errors.Wrap(os.WriteFile(path, b, 0o600), "failed to write quota history")
+}
+
+// Trend returns how many percentage points the usage of a quota resource
+// changed since the oldest sample within the window, and how long ago
+// that sample was recorded. It returns false without such a sample.
+func (h History) Trend(cluster string, u *Usage, now time.Time, window time.Duration) (float64, time.Duration, bool) {
+	for _, s := range h[cluster][u.Key()] {
+		if now.Sub(s.Time) <= window && s.Time.Before(now) {
+			return u.Percent - s.Percent, now.Sub(s.Time), true
+		}
+	}
+	return 0, 0, false
+}
+
+// Add records the usage of a run and drops samples that are too old
+func (h History) Add(cluster string, usage []Usage, now time.Time) {
+	samples := h[cluster]
+	if samples == nil {
+		samples = make(map[string][]Sample)
+		h[cluster] = samples
+	}
+	for i := range usage {
+		key := usage[i].Key()
+		samples[key] = append(samples[key], Sample{Time: now, Percent: usage[i].Percent})
+	}
+
+	for key, kept := range samples {
+		for len(kept) > 0 && now.Sub(kept[0].Time) > historyMaxAge {
+			kept = kept[1:]
+		}
+		if len(kept) > historyMaxSamples {
+			kept = kept[len(kept)-historyMaxSamples:]
+		}
+		if len(kept) == 0 {
+			delete(samples, key)
+			continue
+		}
+		samples[key] = kept
+	}
+}
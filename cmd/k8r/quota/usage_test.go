@@ -0,0 +1,99 @@
+package quota_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/quota"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestQuotaUsage(t *testing.T) {
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "web"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("4"),
+				corev1.ResourceRequestsMemory: resource.MustParse("8Gi"),
+				corev1.ResourcePods:           resource.MustParse("0"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("3500m"),
+				corev1.ResourceRequestsMemory: resource.MustParse("2Gi"),
+			},
+		},
+	}}
+
+	usage := quota.QuotaUsage(quotas)
+	want := map[string]float64{"web/compute/pods": 0, "web/compute/requests.cpu": 87.5, "web/compute/requests.memory": 25}
+	if len(usage) != len(want) {
+		t.Fatalf("QuotaUsage() returned %d rows, expected %d", len(usage), len(want))
+	}
+	for i := range usage {
+		if got := usage[i].Percent; math.Abs(got-want[usage[i].Key()]) > 0.001 {
+			t.Errorf("QuotaUsage() %s = %.1f%%, expected %.1f%%", usage[i].Key(), got, want[usage[i].Key()])
+		}
+	}
+	if usage[0].Resource != corev1.ResourcePods {
+		t.Errorf("QuotaUsage() isn't sorted by resource, first row is %s", usage[0].Resource)
+	}
+}
+
+func TestLimitDefaults(t *testing.T) {
+	limitRanges := []corev1.LimitRange{{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "web"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			Default:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			DefaultRequest: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi"), corev1.ResourceCPU: resource.MustParse("100m")},
+		}}},
+	}}
+
+	defaults := quota.LimitDefaults(limitRanges)
+	if len(defaults) != 2 {
+		t.Fatalf("LimitDefaults() returned %d rows, expected 2", len(defaults))
+	}
+	cpu, memory := defaults[0], defaults[1]
+	if cpu.Resource != corev1.ResourceCPU || cpu.DefaultRequest.String() != "100m" || cpu.Default != nil {
+		t.Errorf("LimitDefaults() cpu = %+v, expected a default request of 100m and no default limit", cpu)
+	}
+	if memory.Resource != corev1.ResourceMemory || memory.DefaultRequest.String() != "256Mi" || memory.Default.String() != "512Mi" {
+		t.Errorf("LimitDefaults() memory = %+v, expected a default request of 256Mi and a default limit of 512Mi", memory)
+	}
+}
+
+func TestHistoryTrend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	h, err := quota.LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage := func(p float64) []quota.Usage {
+		return []quota.Usage{{Namespace: "web", Quota: "compute", Resource: corev1.ResourceRequestsCPU, Percent: p}}
+	}
+	h.Add("cluster", usage(40), now.Add(-10*24*time.Hour))
+	h.Add("cluster", usage(50), now.Add(-3*24*time.Hour))
+	h.Add("cluster", usage(60), now.Add(-24*time.Hour))
+	if err := h.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = quota.LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+
+	current := usage(80)[0]
+	change, ago, ok := h.Trend("cluster", &current, now, 7*24*time.Hour)
+	if !ok || change != 30 || ago != 3*24*time.Hour {
+		t.Errorf("Trend() = %.0f, %s, %t, expected +30 over 72h0m0s", change, ago, ok)
+	}
+	if _, _, ok := h.Trend("other", &current, now, 7*24*time.Hour); ok {
+		t.Errorf("Trend() found a trend for a cluster without history")
+	}
+}
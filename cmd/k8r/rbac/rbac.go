@@ -0,0 +1,370 @@
+// Description: This file contains the code for the 'k8r rbac' command.
+
+// Package rbac implements a 'k8r rbac' command for answering questions
+// about who has access to what by resolving RBAC bindings.
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// Options contains options for the rbac who-can command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Namespace is the namespace access is checked in, empty for
+	// any namespace
+	Namespace string
+
+	// Kube is the kubeconfig and context of the cluster to check
+	Kube kube.Options
+}
+
+// NewOptions contains options for the rbac who-can command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new rbac command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "rbac",
+		Usage: "Answer questions about RBAC access",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "who-can",
+				Usage:     "List the users, groups and service accounts that can perform an action",
+				ArgsUsage: "<verb> <resource>[.group][/subresource] | <verb> <non-resource URL>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return errors.New("expected a verb and a resource, e.g. k8r rbac who-can delete pods -n prod")
+					}
+					o.Namespace = c.String("namespace")
+					o.Kube = kube.OptionsFromFlags(c)
+					return o.WhoCan(c.Context, ParseAction(c.Args().Get(0), c.Args().Get(1)))
+				},
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "namespace",
+						Aliases: []string{"n"},
+						Usage:   "Namespace to check access in, defaults to any namespace",
+					},
+				}, kube.Flags()...),
+			},
+		},
+	}
+}
+
+// Action is an action that access is checked for
+type Action struct {
+	// Verb is the verb, e.g. delete
+	Verb string
+
+	// Group is the API group of the resource, empty for the core group
+	Group string
+
+	// Resource is the resource, e.g. pods
+	Resource string
+
+	// Subresource is the subresource, e.g. exec
+	Subresource string
+
+	// NonResourceURL is the path of a request that isn't for a resource,
+	// e.g. /metrics
+	NonResourceURL string
+}
+
+// String returns the action in the format it was parsed from
+func (a *Action) String() string {
+	if a.NonResourceURL != "" {
+		return fmt.Sprintf("%s %s", a.Verb, a.NonResourceURL)
+	}
+
+	resource := a.Resource
+	if a.Group != "" {
+		resource += "." + a.Group
+	}
+	if a.Subresource != "" {
+		resource += "/" + a.Subresource
+	}
+	return fmt.Sprintf("%s %s", a.Verb, resource)
+}
+
+// ParseAction parses an action in the format kubectl auth can-i uses,
+// e.g. delete pods, create deployments.apps or get pods/log
+func ParseAction(verb, resource string) Action {
+	a := Action{Verb: strings.ToLower(verb)}
+	if strings.HasPrefix(resource, "/") {
+		a.NonResourceURL = resource
+		return a
+	}
+
+	parts := strings.SplitN(resource, "/", 2)
+	if len(parts) == 2 {
+		a.Subresource = parts[1]
+	}
+	parts = strings.SplitN(parts[0], ".", 2)
+	a.Resource = strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		a.Group = parts[1]
+	}
+	return a
+}
+
+// matches returns true if the values contain the value or a wildcard
+func matches(values []string, value string) bool {
+	for _, v := range values {
+		if v == rbacv1.ResourceAll || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleAllows returns true if the rule allows the action, rules limited to
+// specific resource names are considered to allow it
+func RuleAllows(rule *rbacv1.PolicyRule, a *Action) bool {
+	if !matches(rule.Verbs, a.Verb) {
+		return false
+	}
+
+	if a.NonResourceURL != "" {
+		for _, u := range rule.NonResourceURLs {
+			if u == rbacv1.NonResourceAll || u == a.NonResourceURL ||
+				(strings.HasSuffix(u, "*") && strings.HasPrefix(a.NonResourceURL, strings.TrimSuffix(u, "*"))) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !matches(rule.APIGroups, a.Group) {
+		return false
+	}
+
+	if a.Subresource == "" {
+		return matches(rule.Resources, a.Resource)
+	}
+	for _, r := range rule.Resources {
+		if r == rbacv1.ResourceAll || r == a.Resource+"/"+a.Subresource || r == "*/"+a.Subresource {
+			return true
+		}
+	}
+	return false
+}
+
+// Grant is a subject that is granted an action through a binding
+type Grant struct {
+	// Subject is who is granted the action
+	Subject rbacv1.Subject
+
+	// Namespace is the namespace the action is granted in, empty when it
+	// is granted in every namespace
+	Namespace string
+
+	// Binding is the binding that grants the action, in the format
+	// kind/[namespace/]name
+	Binding string
+
+	// Role is the role the binding refers to, in the format kind/name
+	Role string
+
+	// ResourceNames are the only resources the action is granted on, if
+	// the role limits it
+	ResourceNames []string
+}
+
+// rulesAllow returns true if any of the rules allow the action, along with
+// the resource names the rules limit it to, if all of the matching rules
+// limit it
+func rulesAllow(rules []rbacv1.PolicyRule, a *Action) (resourceNames []string, allowed bool) {
+	for i := range rules {
+		if !RuleAllows(&rules[i], a) {
+			continue
+		}
+		if len(rules[i].ResourceNames) == 0 {
+			return nil, true
+		}
+		allowed = true
+		resourceNames = append(resourceNames, rules[i].ResourceNames...)
+	}
+	return resourceNames, allowed
+}
+
+// WhoCan returns the subjects that are granted the action in the namespace,
+// or in any namespace when it is empty, by the bindings in the cluster
+func WhoCan(ctx context.Context, k kubernetes.Interface, namespace string, a *Action) ([]Grant, error) {
+	clusterRoles, err := k.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list clusterroles")
+	}
+	clusterRoleRules := make(map[string][]rbacv1.PolicyRule)
+	for i := range clusterRoles.Items {
+		clusterRoleRules[clusterRoles.Items[i].Name] = clusterRoles.Items[i].Rules
+	}
+
+	grants := make([]Grant, 0)
+	add := func(subjects []rbacv1.Subject, namespace, binding, role string, rules []rbacv1.PolicyRule) {
+		names, ok := rulesAllow(rules, a)
+		if !ok {
+			return
+		}
+		for _, s := range subjects {
+			grants = append(grants, Grant{Subject: s, Namespace: namespace, Binding: binding, Role: role, ResourceNames: names})
+		}
+	}
+
+	clusterRoleBindings, err := k.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list clusterrolebindings")
+	}
+	for i := range clusterRoleBindings.Items {
+		b := &clusterRoleBindings.Items[i]
+		add(b.Subjects, "", "ClusterRoleBinding/"+b.Name, "ClusterRole/"+b.RoleRef.Name, clusterRoleRules[b.RoleRef.Name])
+	}
+
+	// Non-resource URLs can only be granted cluster wide
+	if a.NonResourceURL != "" {
+		return grants, nil
+	}
+
+	roles, err := k.RbacV1().Roles(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list roles")
+	}
+	roleRules := make(map[string][]rbacv1.PolicyRule)
+	for i := range roles.Items {
+		roleRules[roles.Items[i].Namespace+"/"+roles.Items[i].Name] = roles.Items[i].Rules
+	}
+
+	roleBindings, err := k.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list rolebindings")
+	}
+	for i := range roleBindings.Items {
+		b := &roleBindings.Items[i]
+		binding := fmt.Sprintf("RoleBinding/%s/%s", b.Namespace, b.Name)
+		if b.RoleRef.Kind == "ClusterRole" {
+			add(b.Subjects, b.Namespace, binding, "ClusterRole/"+b.RoleRef.Name, clusterRoleRules[b.RoleRef.Name])
+		} else {
+			add(b.Subjects, b.Namespace, binding, "Role/"+b.RoleRef.Name, roleRules[b.Namespace+"/"+b.RoleRef.Name])
+		}
+	}
+
+	sort.SliceStable(grants, func(i, j int) bool {
+		if grants[i].Subject.Kind != grants[j].Subject.Kind {
+			return grants[i].Subject.Kind < grants[j].Subject.Kind
+		}
+		return subjectName(&grants[i].Subject) < subjectName(&grants[j].Subject)
+	})
+
+	return grants, nil
+}
+
+// subjectName returns the name of a subject, service accounts are
+// returned in the format namespace/name
+func subjectName(s *rbacv1.Subject) string {
+	if s.Kind == rbacv1.ServiceAccountKind {
+		return s.Namespace + "/" + s.Name
+	}
+	return s.Name
+}
+
+// resolveResource fills in the API group of the action's resource and
+// expands short names, e.g. deploy becomes deployments.apps, using the
+// resources the API server serves. The action is left as is when the
+// resource can't be found.
+func resolveResource(k kubernetes.Interface, a *Action) {
+	if a.NonResourceURL != "" || a.Group != "" || a.Resource == rbacv1.ResourceAll {
+		return
+	}
+
+	// Discovery returns the resources it could find alongside an error
+	// when some API groups are unavailable
+	//nolint:errcheck // Why: Best effort
+	lists, _ := k.Discovery().ServerPreferredResources()
+	for _, l := range lists {
+		gv, err := schema.ParseGroupVersion(l.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for i := range l.APIResources {
+			r := &l.APIResources[i]
+			if r.Name == a.Resource || r.SingularName == a.Resource || matches(r.ShortNames, a.Resource) {
+				a.Resource = r.Name
+				a.Group = gv.Group
+				return
+			}
+		}
+	}
+}
+
+// WhoCan prints the subjects that can perform the action
+func (o *Options) WhoCan(ctx context.Context, a Action) error {
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+	resolveResource(k, &a)
+
+	grants, err := WhoCan(ctx, k, o.Namespace, &a)
+	if err != nil {
+		return kube.ExplainError(err)
+	}
+
+	where := "any namespace"
+	if o.Namespace != "" {
+		where = "namespace " + o.Namespace
+	}
+	bold.Printf("🔐  Who can %s in %s:\n", a.String(), where)
+
+	if len(grants) == 0 {
+		fmt.Println("    No bindings grant this")
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "    KIND\tSUBJECT\tNAMESPACE\tBINDING\tROLE")
+		for i := range grants {
+			g := &grants[i]
+			scope := g.Namespace
+			if scope == "" {
+				scope = "*"
+			}
+			role := g.Role
+			if len(g.ResourceNames) != 0 {
+				role += fmt.Sprintf(" (only %s)", strings.Join(g.ResourceNames, ", "))
+			}
+			fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\t%s\n", g.Subject.Kind, subjectName(&g.Subject), scope, g.Binding, role)
+		}
+		tw.Flush()
+	}
+
+	fmt.Println()
+	fmt.Println("Members of the system:masters group can do anything without a binding")
+
+	return nil
+}
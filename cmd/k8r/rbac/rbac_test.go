@@ -0,0 +1,118 @@
+package rbac_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/rbac"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   rbacv1.PolicyRule
+		action string
+		want   bool
+	}{
+		{
+			name:   "exact",
+			rule:   rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"delete"}},
+			action: "pods",
+			want:   true,
+		},
+		{
+			name:   "other verb",
+			rule:   rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			action: "pods",
+		},
+		{
+			name:   "other group",
+			rule:   rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"pods"}, Verbs: []string{"delete"}},
+			action: "pods",
+		},
+		{
+			name:   "wildcards",
+			rule:   rbacv1.PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			action: "deployments.apps",
+			want:   true,
+		},
+		{
+			name:   "subresource",
+			rule:   rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"delete"}},
+			action: "pods/exec",
+			want:   true,
+		},
+		{
+			name:   "subresource not granted by resource",
+			rule:   rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"delete"}},
+			action: "pods/exec",
+		},
+		{
+			name:   "non-resource URL prefix",
+			rule:   rbacv1.PolicyRule{NonResourceURLs: []string{"/metrics/*"}, Verbs: []string{"delete"}},
+			action: "/metrics/cadvisor",
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := rbac.ParseAction("delete", tt.action)
+			if got := rbac.RuleAllows(&tt.rule, &a); got != tt.want {
+				t.Errorf("RuleAllows(%s) = %v, expected %v", a.String(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhoCan(t *testing.T) {
+	deletePods := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"delete"}}}
+	k := fake.NewSimpleClientset(
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "admin"}, Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+		}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "admins"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "sre"}},
+		},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "pod-deleter"}, Rules: deletePods},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "deployer"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "pod-deleter"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ci", Name: "deployer"}},
+		},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "staging", Name: "pod-deleter"}, Rules: deletePods},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "staging", Name: "dev"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "pod-deleter"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+		},
+	)
+
+	a := rbac.ParseAction("delete", "pods")
+	grants, err := rbac.WhoCan(context.Background(), k, "prod", &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(grants))
+	for _, g := range grants {
+		got = append(got, g.Subject.Kind+" "+g.Subject.Name+" "+g.Binding)
+	}
+	want := []string{
+		"Group sre ClusterRoleBinding/admins",
+		"ServiceAccount deployer RoleBinding/prod/deployer",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("WhoCan() = %v, expected %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("WhoCan()[%d] = %q, expected %q", i, got[i], want[i])
+		}
+	}
+}
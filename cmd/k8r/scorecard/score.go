@@ -0,0 +1,190 @@
+// Description: This file contains the code for scoring the findings of a
+// report per team.
+
+package scorecard
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+	"github.com/pkg/errors"
+)
+
+// scoring constants
+const (
+	// unowned is the team findings without an owner are scored under
+	unowned = "unowned"
+
+	// ageDoublingPeriod is how long a finding has to stay open for its
+	// weight to grow by its severity weight again
+	ageDoublingPeriod = 7 * 24 * time.Hour
+
+	// maxAgeFactor is the most a finding's weight grows with age
+	maxAgeFactor = 5.0
+
+	// scoreScale is the penalty at which a team's score halves
+	scoreScale = 100.0
+)
+
+// severityWeights are how much a finding of each severity counts against
+// its team
+var severityWeights = map[string]float64{
+	report.SeverityCritical: 10,
+	report.SeverityError:    5,
+	report.SeverityWarning:  2,
+	report.SeverityInfo:     1,
+}
+
+// TeamScore is the score of a team's findings
+type TeamScore struct {
+	// Team is the owner of the findings, unowned for findings without one
+	Team string `json:"team"`
+
+	// Score is from 0 to 100, 100 meaning no findings
+	Score float64 `json:"score"`
+
+	// Penalty is the sum of the weights of the team's findings, which
+	// grow with severity and how long they have been open
+	Penalty float64 `json:"penalty"`
+
+	// Findings is the number of findings the team owns
+	Findings int `json:"findings"`
+
+	// BySeverity is the number of findings by severity
+	BySeverity map[string]int `json:"bySeverity"`
+
+	// OldestOpenDays is how many days the oldest finding has been open
+	OldestOpenDays float64 `json:"oldestOpenDays"`
+}
+
+// Scorecard are the scores of every team with findings
+type Scorecard struct {
+	// GeneratedAt is when the scorecard was generated
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// Teams are the scores of the teams, lowest score first
+	Teams []TeamScore `json:"teams"`
+}
+
+// State is what is kept between runs to know how long findings have been
+// open
+type State struct {
+	// FirstSeen is when each open finding was first seen, keyed by
+	// problem, type and resource
+	FirstSeen map[string]time.Time `json:"firstSeen"`
+}
+
+// DefaultStateFile returns where the state is stored by default, or an
+// empty string if there is no cache directory
+func DefaultStateFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "k8r", "scorecard.json")
+}
+
+// LoadState reads the state from a file, a file that doesn't exist yet is
+// an empty state
+func LoadState(path string) (*State, error) {
+	s := &State{FirstSeen: make(map[string]time.Time)}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read scorecard state")
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, errors.Wrap(err, "failed to parse scorecard state")
+	}
+	if s.FirstSeen == nil {
+		s.FirstSeen = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+// Save writes the state to a file
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create scorecard state directory")
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal scorecard state")
+	}
+
+	return errors.Wrap(os.WriteFile(path, b, 0o600), "failed to write scorecard state")
+}
+
+// findingKey identifies a finding across runs
+func findingKey(f *report.Finding) string {
+	return f.ProblemID + "|" + f.Type + "|" + f.Resource
+}
+
+// weight returns how much a finding that has been open for the given time
+// counts against its team
+func weight(severity string, open time.Duration) float64 {
+	age := 1 + float64(open)/float64(ageDoublingPeriod)
+	return severityWeights[severity] * math.Min(age, maxAgeFactor)
+}
+
+// Score scores the findings of a report by team. The state is updated
+// with the findings that are new and forgets the ones that were resolved.
+// Findings that are symptoms of other findings aren't scored.
+func Score(r *report.Report, state *State, now time.Time) *Scorecard {
+	open := make(map[string]time.Time)
+	byTeam := make(map[string]*TeamScore)
+	for i := range r.Findings {
+		f := &r.Findings[i]
+		if f.SuppressedBy != "" {
+			continue
+		}
+
+		key := findingKey(f)
+		since, ok := state.FirstSeen[key]
+		if !ok {
+			since = now
+		}
+		open[key] = since
+
+		team := f.Owner
+		if team == "" {
+			team = unowned
+		}
+		t, ok := byTeam[team]
+		if !ok {
+			t = &TeamScore{Team: team, BySeverity: make(map[string]int)}
+			byTeam[team] = t
+		}
+		t.Findings++
+		t.BySeverity[f.Severity]++
+		t.Penalty += weight(f.Severity, now.Sub(since))
+		if days := now.Sub(since).Hours() / 24; days > t.OldestOpenDays {
+			t.OldestOpenDays = days
+		}
+	}
+	state.FirstSeen = open
+
+	card := &Scorecard{GeneratedAt: now, Teams: make([]TeamScore, 0, len(byTeam))}
+	for _, t := range byTeam {
+		t.Score = math.Round(1000*scoreScale/(scoreScale+t.Penalty)) / 10
+		t.Penalty = math.Round(t.Penalty*10) / 10
+		t.OldestOpenDays = math.Round(t.OldestOpenDays*10) / 10
+		card.Teams = append(card.Teams, *t)
+	}
+	sort.Slice(card.Teams, func(i, j int) bool {
+		if card.Teams[i].Score != card.Teams[j].Score {
+			return card.Teams[i].Score < card.Teams[j].Score
+		}
+		return card.Teams[i].Team < card.Teams[j].Team
+	})
+	return card
+}
@@ -0,0 +1,75 @@
+package scorecard_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/scorecard"
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+)
+
+var now = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestScore(t *testing.T) {
+	r := &report.Report{Findings: []report.Finding{
+		{ProblemID: "PodCrashLoopBackOff", Type: "pod", Resource: "web/api", Severity: report.SeverityError, Owner: "payments"},
+		{ProblemID: "NoLimits", Type: "pod", Resource: "web/api", Severity: report.SeverityWarning, Owner: "payments"},
+		{ProblemID: "PodPending", Type: "pod", Resource: "web/api", Severity: report.SeverityError, Owner: "payments",
+			SuppressedBy: "PodCrashLoopBackOff"},
+		{ProblemID: "NodeNotReady", Type: "node", Resource: "node-1", Severity: report.SeverityCritical},
+	}}
+	state := &scorecard.State{FirstSeen: map[string]time.Time{
+		"PodCrashLoopBackOff|pod|web/api": now.Add(-14 * 24 * time.Hour),
+		"ImagePullBackOff|pod|web/old":    now.Add(-30 * 24 * time.Hour),
+	}}
+
+	card := scorecard.Score(r, state, now)
+	if len(card.Teams) != 2 {
+		t.Fatalf("Score() returned %d teams, expected 2: %+v", len(card.Teams), card.Teams)
+	}
+
+	// The crash loop has been open two weeks so it counts 3 times its
+	// weight, 5*3 + 2 = 17
+	payments := card.Teams[0]
+	if payments.Team != "payments" || payments.Penalty != 17 || payments.Findings != 2 ||
+		payments.BySeverity[report.SeverityError] != 1 || payments.OldestOpenDays != 14 {
+		t.Errorf("Score() payments = %+v, expected a penalty of 17 for 2 findings open up to 14 days", payments)
+	}
+	if payments.Score != 85.5 {
+		t.Errorf("Score() payments score = %v, expected 85.5", payments.Score)
+	}
+
+	unowned := card.Teams[1]
+	if unowned.Team != "unowned" || unowned.Penalty != 10 || unowned.Score != 90.9 {
+		t.Errorf("Score() unowned = %+v, expected a penalty of 10 and a score of 90.9", unowned)
+	}
+
+	if _, ok := state.FirstSeen["ImagePullBackOff|pod|web/old"]; ok {
+		t.Error("Score() kept a resolved finding in the state")
+	}
+	if got := state.FirstSeen["NodeNotReady|node|node-1"]; !got.Equal(now) {
+		t.Errorf("Score() recorded a new finding as first seen at %v, expected %v", got, now)
+	}
+
+	var b bytes.Buffer
+	if err := card.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "team,score,penalty,findings,critical,error,warning,info,oldest_open_days\n" +
+		"payments,85.5,17,2,0,1,1,0,14\n" +
+		"unowned,90.9,10,1,1,0,0,0,0\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, expected %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := card.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `k8r_team_score{team="payments"} 85.5`) ||
+		!strings.Contains(b.String(), `k8r_team_findings{team="unowned",severity="critical"} 1`) {
+		t.Errorf("WritePrometheus() = %s, expected team scores and findings", b.String())
+	}
+}
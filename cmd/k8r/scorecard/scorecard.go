@@ -0,0 +1,282 @@
+// Description: This file contains the code for the 'k8r scorecard' command.
+
+// Package scorecard implements a 'k8r scorecard' command that turns the
+// findings of a 'k8r checkup' report into a score per team, weighted by
+// severity, how long findings have been open and how many there are, for
+// cluster hygiene dashboards.
+package scorecard
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Formats the scorecard can be written in
+const (
+	// FormatJSON is the Scorecard as JSON
+	FormatJSON = "json"
+
+	// FormatCSV is a row per team
+	FormatCSV = "csv"
+
+	// FormatPrometheus is the Prometheus text exposition format
+	FormatPrometheus = "prometheus"
+)
+
+// severities are the severities in the order they are written as columns
+var severities = []string{report.SeverityCritical, report.SeverityError, report.SeverityWarning, report.SeverityInfo}
+
+// Options contains options for the scorecard command
+type Options struct {
+	log logrus.FieldLogger
+
+	// ReportFile is the report written by 'k8r checkup --output json'
+	ReportFile string
+
+	// Format is one of the Format constants
+	Format string
+
+	// OutputFile is where the scorecard is written, stdout when empty
+	OutputFile string
+
+	// StateFile is where when findings were first seen is kept between
+	// runs, empty to score every finding as new
+	StateFile string
+
+	// Pushgateway is the URL of a Prometheus Pushgateway the scores are
+	// pushed to, if set
+	Pushgateway string
+
+	// PushgatewayJob is the job the scores are pushed as
+	PushgatewayJob string
+}
+
+// NewOptions contains options for the scorecard command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new scorecard command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "scorecard",
+		Usage: "Score the findings of a 'k8r checkup' report per team, run it periodically to track how long findings stay open",
+		Action: func(c *cli.Context) error {
+			o.ReportFile = c.String("report")
+			o.Format = c.String("format")
+			o.OutputFile = c.String("output-file")
+			o.StateFile = c.String("state-file")
+			o.Pushgateway = c.String("pushgateway")
+			o.PushgatewayJob = c.String("pushgateway-job")
+			return o.Run(c.Context)
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "report",
+				Usage:    "Report written by k8r checkup --output json or yaml",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Format to write the scorecard in, one of json, csv or prometheus",
+				Value: FormatJSON,
+			},
+			&cli.StringFlag{
+				Name:  "output-file",
+				Usage: "File to write the scorecard to, defaults to stdout",
+			},
+			&cli.StringFlag{
+				Name:  "state-file",
+				Usage: "File that records when findings were first seen, so that findings count more the longer they are open, set to an empty string to disable",
+				Value: DefaultStateFile(),
+			},
+			&cli.StringFlag{
+				Name:  "pushgateway",
+				Usage: "URL of a Prometheus Pushgateway to push the scores to, e.g. http://pushgateway:9091",
+			},
+			&cli.StringFlag{
+				Name:  "pushgateway-job",
+				Usage: "Job label the scores are pushed to the Pushgateway with",
+				Value: "k8r_scorecard",
+			},
+		},
+	}
+}
+
+// WriteJSON writes the scorecard as indented JSON
+func (s *Scorecard) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode scorecard")
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteCSV writes the scorecard as CSV with a header and a row per team
+func (s *Scorecard) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"team", "score", "penalty", "findings"}, severities...)
+	if err := cw.Write(append(header, "oldest_open_days")); err != nil {
+		return errors.Wrap(err, "failed to write scorecard")
+	}
+	for i := range s.Teams {
+		t := &s.Teams[i]
+		row := []string{
+			t.Team,
+			strconv.FormatFloat(t.Score, 'f', -1, 64),
+			strconv.FormatFloat(t.Penalty, 'f', -1, 64),
+			strconv.Itoa(t.Findings),
+		}
+		for _, sev := range severities {
+			row = append(row, strconv.Itoa(t.BySeverity[sev]))
+		}
+		if err := cw.Write(append(row, strconv.FormatFloat(t.OldestOpenDays, 'f', -1, 64))); err != nil {
+			return errors.Wrap(err, "failed to write scorecard")
+		}
+	}
+	cw.Flush()
+	return errors.Wrap(cw.Error(), "failed to write scorecard")
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// WritePrometheus writes the scorecard in the Prometheus text exposition
+// format
+func (s *Scorecard) WritePrometheus(w io.Writer) error {
+	var b bytes.Buffer
+	metric := func(name, help string, value func(t *TeamScore) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for i := range s.Teams {
+			fmt.Fprintf(&b, "%s{team=\"%s\"} %g\n", name, escapeLabel(s.Teams[i].Team), value(&s.Teams[i]))
+		}
+	}
+	metric("k8r_team_score", "Cluster hygiene score of the team from 0 to 100, 100 meaning no findings",
+		func(t *TeamScore) float64 { return t.Score })
+	metric("k8r_team_penalty", "Sum of the weights of the team's findings",
+		func(t *TeamScore) float64 { return t.Penalty })
+	metric("k8r_team_oldest_finding_days", "Days the team's oldest finding has been open",
+		func(t *TeamScore) float64 { return t.OldestOpenDays })
+
+	fmt.Fprintf(&b, "# HELP k8r_team_findings Findings the team owns by severity\n# TYPE k8r_team_findings gauge\n")
+	for i := range s.Teams {
+		t := &s.Teams[i]
+		for _, sev := range severities {
+			fmt.Fprintf(&b, "k8r_team_findings{team=\"%s\",severity=\"%s\"} %d\n", escapeLabel(t.Team), sev, t.BySeverity[sev])
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// push replaces the scores of the job in the Pushgateway
+func (o *Options) push(ctx context.Context, card *Scorecard) error {
+	var b bytes.Buffer
+	if err := card.WritePrometheus(&b); err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(o.Pushgateway, "/") + "/metrics/job/" + o.PushgatewayJob
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, &b)
+	if err != nil {
+		return errors.Wrap(err, "failed to create pushgateway request")
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to push to pushgateway")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to push to pushgateway: got status %s", resp.Status)
+	}
+	return nil
+}
+
+// Run runs the scorecard command
+func (o *Options) Run(ctx context.Context) error {
+	b, err := os.ReadFile(o.ReportFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read report")
+	}
+	r, err := report.Read(b)
+	if err != nil {
+		return err
+	}
+
+	state := &State{FirstSeen: make(map[string]time.Time)}
+	if o.StateFile != "" {
+		if state, err = LoadState(o.StateFile); err != nil {
+			return err
+		}
+	}
+
+	// Findings are aged by when the report was written, so scoring an old
+	// report doesn't make its findings look older than they were
+	now := r.GeneratedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	card := Score(r, state, now)
+
+	out := io.Writer(os.Stdout)
+	if o.OutputFile != "" {
+		f, err := os.Create(o.OutputFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to create scorecard file")
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch o.Format {
+	case FormatJSON:
+		err = card.WriteJSON(out)
+	case FormatCSV:
+		err = card.WriteCSV(out)
+	case FormatPrometheus:
+		err = card.WritePrometheus(out)
+	default:
+		return fmt.Errorf("unknown format %q, expected json, csv or prometheus", o.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.Pushgateway != "" {
+		if err := o.push(ctx, card); err != nil {
+			return err
+		}
+	}
+
+	// The state is only saved once the scorecard was exported, so that a
+	// failed run doesn't lose track of when findings were first seen
+	if o.StateFile != "" {
+		return state.Save(o.StateFile)
+	}
+	return nil
+}
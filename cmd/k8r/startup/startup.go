@@ -0,0 +1,266 @@
+// Description: This file contains the code for the 'k8r startup' command.
+
+// Package startup implements a 'k8r startup' command that reports how
+// long the pods of each workload take from being created to being ready,
+// which bounds how fast HPAs can scale up and spot nodes can be replaced.
+package startup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// Options contains options for the startup command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Namespace is the namespace pods are listed in, empty for all
+	// namespaces
+	Namespace string
+
+	// Threshold is the p90 start time above which a workload is flagged
+	Threshold time.Duration
+
+	// Kube is the kubeconfig and context of the cluster to analyze
+	Kube kube.Options
+}
+
+// NewOptions contains options for the startup command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new startup command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "startup",
+		Usage: "Report how long the pods of each workload take from being created to being ready",
+		Action: func(c *cli.Context) error {
+			o.Namespace = c.String("namespace")
+			o.Threshold = c.Duration("threshold")
+			o.Kube = kube.OptionsFromFlags(c)
+			return o.Run(c.Context)
+		},
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:    "namespace",
+				Aliases: []string{"n"},
+				Usage:   "Namespace to analyze, defaults to all namespaces",
+			},
+			&cli.DurationFlag{
+				Name:  "threshold",
+				Usage: "Flags workloads whose pods take longer than this to become ready at the 90th percentile",
+				Value: 2 * time.Minute,
+			},
+		}, kube.Flags()...),
+	}
+}
+
+// Sample is how long a single pod took to start
+type Sample struct {
+	// Scheduling is the time from the pod being created to being
+	// scheduled
+	Scheduling time.Duration
+
+	// Ready is the time from the pod being created to being ready
+	Ready time.Duration
+}
+
+// Latency is the distribution of start times of a workload's pods
+type Latency struct {
+	// Workload is the workload, e.g. default/Deployment/api
+	Workload string
+
+	// Pods is the number of pods the distribution is from
+	Pods int
+
+	// P50 is the median time to ready
+	P50 time.Duration
+
+	// P90 is the 90th percentile time to ready
+	P90 time.Duration
+
+	// Max is the longest time to ready
+	Max time.Duration
+
+	// SchedulingP50 is the median time to being scheduled, which is
+	// included in the time to ready
+	SchedulingP50 time.Duration
+}
+
+// workload returns the workload that owns a pod, pods owned by a
+// ReplicaSet are attributed to its Deployment
+func workload(pod *corev1.Pod) string {
+	for i := range pod.OwnerReferences {
+		ref := &pod.OwnerReferences[i]
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ref.Kind == "ReplicaSet" &&
+			hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return fmt.Sprintf("%s/Deployment/%s", pod.Namespace, strings.TrimSuffix(ref.Name, "-"+hash))
+		}
+		return fmt.Sprintf("%s/%s/%s", pod.Namespace, ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("%s/Pod/%s", pod.Namespace, pod.Name)
+}
+
+// condition returns when the condition last became true
+func condition(pod *corev1.Pod, t corev1.PodConditionType) (time.Time, bool) {
+	for i := range pod.Status.Conditions {
+		c := &pod.Status.Conditions[i]
+		if c.Type == t && c.Status == corev1.ConditionTrue && !c.LastTransitionTime.IsZero() {
+			return c.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// restarted returns true if any container of the pod restarted, its Ready
+// condition then changed after it first started
+func restarted(pod *corev1.Pod) bool {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].RestartCount != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sample returns how long a pod took to start, pods that aren't ready or
+// restarted since they were ready first are left out
+func sample(pod *corev1.Pod) (Sample, bool) {
+	ready, ok := condition(pod, corev1.PodReady)
+	if !ok || restarted(pod) {
+		return Sample{}, false
+	}
+
+	created := pod.CreationTimestamp.Time
+	s := Sample{Ready: ready.Sub(created)}
+	if scheduled, ok := condition(pod, corev1.PodScheduled); ok {
+		s.Scheduling = scheduled.Sub(created)
+	}
+	return s, s.Ready >= 0
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Latencies returns the distribution of start times of each workload's
+// pods, slowest first by p90
+func Latencies(pods []corev1.Pod) []Latency {
+	samples := make(map[string][]Sample)
+	for i := range pods {
+		if s, ok := sample(&pods[i]); ok {
+			w := workload(&pods[i])
+			samples[w] = append(samples[w], s)
+		}
+	}
+
+	latencies := make([]Latency, 0, len(samples))
+	for w, s := range samples {
+		ready := make([]time.Duration, 0, len(s))
+		scheduling := make([]time.Duration, 0, len(s))
+		for i := range s {
+			ready = append(ready, s[i].Ready)
+			scheduling = append(scheduling, s[i].Scheduling)
+		}
+		sort.Slice(ready, func(i, j int) bool { return ready[i] < ready[j] })
+		sort.Slice(scheduling, func(i, j int) bool { return scheduling[i] < scheduling[j] })
+
+		latencies = append(latencies, Latency{
+			Workload:      w,
+			Pods:          len(s),
+			P50:           percentile(ready, 50),
+			P90:           percentile(ready, 90),
+			Max:           ready[len(ready)-1],
+			SchedulingP50: percentile(scheduling, 50),
+		})
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		if latencies[i].P90 != latencies[j].P90 {
+			return latencies[i].P90 > latencies[j].P90
+		}
+		return latencies[i].Workload < latencies[j].Workload
+	})
+	return latencies
+}
+
+// Run runs the startup command
+func (o *Options) Run(ctx context.Context) error {
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	pods, err := k.CoreV1().Pods(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return kube.ExplainError(errors.Wrap(err, "failed to list pods"))
+	}
+
+	latencies := Latencies(pods.Items)
+	if len(latencies) == 0 {
+		fmt.Println("No ready pods to measure")
+		return nil
+	}
+
+	bold.Println("⏱️  Time from pod creation to ready, slowest first:")
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "    WORKLOAD\tPODS\tP50\tP90\tMAX\tSCHEDULING P50")
+	slow := 0
+	for i := range latencies {
+		l := &latencies[i]
+		row := fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s", l.Workload, l.Pods,
+			l.P50.Round(time.Second), l.P90.Round(time.Second), l.Max.Round(time.Second), l.SchedulingP50.Round(time.Second))
+		if o.Threshold > 0 && l.P90 > o.Threshold {
+			slow++
+			fmt.Fprintf(tw, "  ⚠️ %s\n", color.HiYellowString(row))
+			continue
+		}
+		fmt.Fprintf(tw, "    %s\n", row)
+	}
+	tw.Flush()
+
+	fmt.Println()
+	if slow == 0 {
+		fmt.Printf("Every workload starts within %s at p90 🎉\n", o.Threshold)
+		return nil
+	}
+	bold.Printf("%d of %d workloads take longer than %s to start at p90, which delays HPA scale-ups and spot recovery\n",
+		slow, len(latencies), o.Threshold)
+	return nil
+}
@@ -0,0 +1,63 @@
+package startup_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/startup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var created = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func pod(name, owner string, scheduled, ready time.Duration, restarts int32) corev1.Pod {
+	yes := true
+	p := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "StatefulSet", Name: owner, Controller: &yes}},
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(created.Add(scheduled))},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: restarts}},
+		},
+	}
+	if ready != 0 {
+		p.Status.Conditions = append(p.Status.Conditions, corev1.PodCondition{
+			Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(created.Add(ready)),
+		})
+	}
+	return p
+}
+
+func TestLatencies(t *testing.T) {
+	pods := []corev1.Pod{
+		pod("api-0", "api", time.Second, 10*time.Second, 0),
+		pod("api-1", "api", 2*time.Second, 20*time.Second, 0),
+		pod("api-2", "api", 3*time.Second, 30*time.Second, 0),
+		pod("db-0", "db", 5*time.Second, 4*time.Minute, 0),
+		pod("db-1", "db", 5*time.Second, 0, 0),
+		pod("db-2", "db", 5*time.Second, time.Hour, 3),
+	}
+
+	got := startup.Latencies(pods)
+	want := []startup.Latency{
+		{
+			Workload: "default/StatefulSet/db", Pods: 1,
+			P50: 4 * time.Minute, P90: 4 * time.Minute, Max: 4 * time.Minute, SchedulingP50: 5 * time.Second,
+		},
+		{
+			Workload: "default/StatefulSet/api", Pods: 3,
+			P50: 20 * time.Second, P90: 30 * time.Second, Max: 30 * time.Second, SchedulingP50: 2 * time.Second,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Latencies() = %+v, expected %+v", got, want)
+	}
+}
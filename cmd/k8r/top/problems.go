@@ -0,0 +1,144 @@
+// Description: This file contains the code for the 'k8r top problems'
+// command.
+
+package top
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// chronicRatio is the share of runs a problem has to be found in to be
+// called chronic
+const chronicRatio = 0.5
+
+// Frequency is how often a problem, or the problems of an owner, were
+// found across checkup runs
+type Frequency struct {
+	// Name is the problem ID or owner
+	Name string
+
+	// Runs is the number of runs it was found in
+	Runs int
+
+	// Occurrences is how many times it was found across all runs
+	Occurrences int
+}
+
+// Pattern returns whether something found in the given number of runs is
+// chronic, recurring or a one-off
+func (f *Frequency) Pattern(runs int) string {
+	switch {
+	case runs > 1 && float64(f.Runs) >= float64(runs)*chronicRatio:
+		return "chronic"
+	case f.Runs > 1:
+		return "recurring"
+	}
+	return "one-off"
+}
+
+// rankFrequencies sorts counts by the number of runs they were found in,
+// then by occurrences
+func rankFrequencies(byName map[string]*Frequency) []Frequency {
+	ranked := make([]Frequency, 0, len(byName))
+	for _, f := range byName {
+		ranked = append(ranked, *f)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Runs != ranked[j].Runs {
+			return ranked[i].Runs > ranked[j].Runs
+		}
+		if ranked[i].Occurrences != ranked[j].Occurrences {
+			return ranked[i].Occurrences > ranked[j].Occurrences
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked
+}
+
+// RankProblems returns how often each problem ID and each owner's problems
+// were found in the given runs, found in the most runs first
+func RankProblems(entries []checkup.HistoryEntry) (problems, owners []Frequency) {
+	byProblem := make(map[string]*Frequency)
+	byOwner := make(map[string]*Frequency)
+	count := func(into map[string]*Frequency, counts map[string]int) {
+		for name, n := range counts {
+			if n == 0 {
+				continue
+			}
+			f, ok := into[name]
+			if !ok {
+				f = &Frequency{Name: name}
+				into[name] = f
+			}
+			f.Runs++
+			f.Occurrences += n
+		}
+	}
+	for i := range entries {
+		count(byProblem, entries[i].ProblemIDs)
+		count(byOwner, entries[i].Owners)
+	}
+	return rankFrequencies(byProblem), rankFrequencies(byOwner)
+}
+
+// printFrequencies prints up to top frequencies in a table
+func printFrequencies(header string, ranked []Frequency, runs, top int) {
+	shown := ranked
+	if top > 0 && len(shown) > top {
+		shown = shown[:top]
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "    %s\tRUNS\tOCCURRENCES\tPER RUN\tPATTERN\n", header)
+	for i := range shown {
+		f := &shown[i]
+		fmt.Fprintf(tw, "    %s\t%d/%d\t%d\t%.1f\t%s\n", f.Name, f.Runs, runs, f.Occurrences,
+			float64(f.Occurrences)/float64(f.Runs), f.Pattern(runs))
+	}
+	tw.Flush()
+	if len(shown) < len(ranked) {
+		fmt.Printf("    ... and %d more, pass --top 0 to show all of them\n", len(ranked)-len(shown))
+	}
+}
+
+// Problems runs the top problems command
+func (o *Options) Problems(ctx context.Context) error {
+	if o.HistoryFile == "" {
+		return errors.New("no history file, pass --history-file")
+	}
+	h, err := checkup.LoadHistory(o.HistoryFile)
+	if err != nil {
+		return err
+	}
+
+	entries := h.Since(o.Cluster, time.Now().Add(-o.Since))
+	problems, owners := RankProblems(entries)
+	if len(problems) == 0 {
+		fmt.Printf("No problems were recorded by 'k8r checkup' runs in the last %s\n", duration.HumanDuration(o.Since))
+		return nil
+	}
+
+	bold.Printf("📈 Problems found by %d checkup run(s) in the last %s, most persistent first:\n",
+		len(entries), duration.HumanDuration(o.Since))
+	printFrequencies("PROBLEM", problems, len(entries), o.Top)
+
+	if len(owners) != 0 {
+		fmt.Println()
+		bold.Println("👥 Owners of those problems:")
+		printFrequencies("OWNER", owners, len(entries), o.Top)
+	}
+
+	fmt.Println()
+	fmt.Printf("Chronic problems were found in at least %.0f%% of runs and are worth engineering time, "+
+		"one-offs were found once\n", chronicRatio*100)
+	return nil
+}
@@ -0,0 +1,292 @@
+// Description: This file contains the code for the 'k8r top' command.
+
+// Package top implements a 'k8r top' command that ranks what is noisiest
+// in a cluster, e.g. the workloads whose containers restart the most, as a
+// quick pulse check at the start of an on-call shift, or the problems that
+// keep coming back.
+package top
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/kube"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// contains string helpers
+var (
+	// bold returns a string in bold
+	bold = color.New(color.Bold)
+)
+
+// ownerLabel is the label that the owning team of a workload is read
+// from, the same one 'k8r checkup' uses
+const ownerLabel = "reporting_team"
+
+// Options contains options for the top command
+type Options struct {
+	log logrus.FieldLogger
+
+	// Namespace is the namespace pods are listed in, empty for all
+	// namespaces
+	Namespace string
+
+	// Since is how far back restarts or checkup runs are counted
+	Since time.Duration
+
+	// Top is the number of rows shown, 0 for all of them
+	Top int
+
+	// HistoryFile is the history 'k8r checkup' records problems in
+	HistoryFile string
+
+	// Cluster selects the clusters in the history whose name contains
+	// it, empty for all of them
+	Cluster string
+
+	// Kube is the kubeconfig and context of the cluster to rank
+	Kube kube.Options
+}
+
+// NewOptions contains options for the top command
+func NewOptions(log logrus.FieldLogger) *Options {
+	return &Options{
+		log: log,
+	}
+}
+
+// NewCommand creates a new top command
+func NewCommand(log logrus.FieldLogger) *cli.Command {
+	o := NewOptions(log)
+
+	return &cli.Command{
+		Name:  "top",
+		Usage: "Rank what is noisiest in the cluster",
+		Subcommands: []*cli.Command{
+			{
+				Name: "restarts",
+				Usage: "Rank workloads by container restarts. Kubernetes only keeps a running count per container, " +
+					"so containers that restarted within --since count all of their restarts",
+				Action: func(c *cli.Context) error {
+					o.Namespace = c.String("namespace")
+					o.Since = c.Duration("since")
+					o.Top = c.Int("top")
+					o.Kube = kube.OptionsFromFlags(c)
+					return o.Restarts(c.Context)
+				},
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "namespace",
+						Aliases: []string{"n"},
+						Usage:   "Namespace to rank, defaults to all namespaces",
+					},
+					&cli.DurationFlag{
+						Name:  "since",
+						Usage: "Only counts containers that restarted within this long",
+						Value: 24 * time.Hour,
+					},
+					&cli.IntFlag{
+						Name:  "top",
+						Usage: "Number of workloads to show, 0 for all of them",
+						Value: 20,
+					},
+				}, kube.Flags()...),
+			},
+			{
+				Name:  "problems",
+				Usage: "Rank problems and owners by how often 'k8r checkup' found them, from its history",
+				Action: func(c *cli.Context) error {
+					o.Since = c.Duration("since")
+					o.Top = c.Int("top")
+					o.HistoryFile = c.String("history-file")
+					o.Cluster = c.String("cluster")
+					return o.Problems(c.Context)
+				},
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "since",
+						Usage: "Only counts runs within this long, e.g. 720h for the past month",
+						Value: 7 * 24 * time.Hour,
+					},
+					&cli.IntFlag{
+						Name:  "top",
+						Usage: "Number of problems and owners to show, 0 for all of them",
+						Value: 20,
+					},
+					&cli.StringFlag{
+						Name:  "history-file",
+						Usage: "File 'k8r checkup' records its runs in",
+						Value: checkup.DefaultHistoryFile(),
+					},
+					&cli.StringFlag{
+						Name:  "cluster",
+						Usage: "Only counts runs against clusters whose API server address contains this, defaults to all clusters",
+					},
+				},
+			},
+		},
+	}
+}
+
+// WorkloadRestarts are the container restarts of a workload's pods
+type WorkloadRestarts struct {
+	// Workload is the workload, e.g. default/Deployment/api
+	Workload string
+
+	// Owner is the team that owns the workload, from the reporting_team
+	// label of its pods or their namespace
+	Owner string
+
+	// Restarts is the total restart count of the containers that
+	// restarted within the window
+	Restarts int32
+
+	// Pods is the number of pods with containers that restarted within
+	// the window
+	Pods int
+
+	// LastRestart is when a container of the workload last restarted
+	LastRestart time.Time
+
+	// Reason is why the container that restarted last exited, e.g.
+	// OOMKilled
+	Reason string
+}
+
+// workload returns the workload that owns a pod, pods owned by a
+// ReplicaSet are attributed to its Deployment
+func workload(pod *corev1.Pod) string {
+	for i := range pod.OwnerReferences {
+		ref := &pod.OwnerReferences[i]
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ref.Kind == "ReplicaSet" &&
+			hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return fmt.Sprintf("%s/Deployment/%s", pod.Namespace, strings.TrimSuffix(ref.Name, "-"+hash))
+		}
+		return fmt.Sprintf("%s/%s/%s", pod.Namespace, ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("%s/Pod/%s", pod.Namespace, pod.Name)
+}
+
+// RankRestarts returns the workloads whose containers restarted after the
+// given time, most restarts first. Namespaces are used to attribute pods
+// without a reporting_team label to a team.
+func RankRestarts(pods []corev1.Pod, namespaces []corev1.Namespace, after time.Time) []WorkloadRestarts {
+	owners := make(map[string]string, len(namespaces))
+	for i := range namespaces {
+		owners[namespaces[i].Name] = namespaces[i].Labels[ownerLabel]
+	}
+
+	byWorkload := make(map[string]*WorkloadRestarts)
+	for i := range pods {
+		p := &pods[i]
+		statuses := append(append([]corev1.ContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...)
+
+		restarted := false
+		for j := range statuses {
+			cs := &statuses[j]
+			last := cs.LastTerminationState.Terminated
+			if cs.RestartCount == 0 || last == nil || !last.FinishedAt.After(after) {
+				continue
+			}
+
+			name := workload(p)
+			w, ok := byWorkload[name]
+			if !ok {
+				w = &WorkloadRestarts{Workload: name, Owner: p.Labels[ownerLabel]}
+				if w.Owner == "" {
+					w.Owner = owners[p.Namespace]
+				}
+				byWorkload[name] = w
+			}
+			w.Restarts += cs.RestartCount
+			if last.FinishedAt.After(w.LastRestart) {
+				w.LastRestart, w.Reason = last.FinishedAt.Time, last.Reason
+			}
+			if !restarted {
+				restarted = true
+				w.Pods++
+			}
+		}
+	}
+
+	ranked := make([]WorkloadRestarts, 0, len(byWorkload))
+	for _, w := range byWorkload {
+		ranked = append(ranked, *w)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Restarts != ranked[j].Restarts {
+			return ranked[i].Restarts > ranked[j].Restarts
+		}
+		return ranked[i].Workload < ranked[j].Workload
+	})
+	return ranked
+}
+
+// Restarts runs the top restarts command
+func (o *Options) Restarts(ctx context.Context) error {
+	k, err := o.Kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	pods, err := k.CoreV1().Pods(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return kube.ExplainError(errors.Wrap(err, "failed to list pods"))
+	}
+
+	// Namespaces are only used for owners, so ranking works without them
+	var namespaces []corev1.Namespace
+	if list, err := k.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err != nil {
+		o.log.WithError(err).Debug("Failed to list namespaces, owners are only read from pods")
+	} else {
+		namespaces = list.Items
+	}
+
+	now := time.Now()
+	ranked := RankRestarts(pods.Items, namespaces, now.Add(-o.Since))
+	if len(ranked) == 0 {
+		fmt.Printf("No containers restarted in the last %s 🎉\n", duration.HumanDuration(o.Since))
+		return nil
+	}
+
+	shown := ranked
+	if o.Top > 0 && len(shown) > o.Top {
+		shown = shown[:o.Top]
+	}
+
+	bold.Printf("🔁 Workloads with containers that restarted in the last %s:\n", duration.HumanDuration(o.Since))
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "    WORKLOAD\tOWNER\tRESTARTS\tPODS\tLAST RESTART\tREASON")
+	for i := range shown {
+		w := &shown[i]
+		owner := w.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		fmt.Fprintf(tw, "    %s\t%s\t%d\t%d\t%s ago\t%s\n", w.Workload, owner, w.Restarts, w.Pods,
+			duration.HumanDuration(now.Sub(w.LastRestart)), w.Reason)
+	}
+	tw.Flush()
+
+	if len(shown) < len(ranked) {
+		fmt.Printf("\n%d more workload(s) restarted, pass --top 0 to show all of them\n", len(ranked)-len(shown))
+	}
+	return nil
+}
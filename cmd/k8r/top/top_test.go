@@ -0,0 +1,98 @@
+package top_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/checkup"
+	"github.com/Ashvin-Ranjan/k8r/cmd/k8r/top"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func pod(name, namespace, owner, team string, restarts int32, lastRestart time.Duration) corev1.Pod {
+	yes := true
+	p := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: owner, Controller: &yes}},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: restarts}},
+		},
+	}
+	if team != "" {
+		p.Labels = map[string]string{"reporting_team": team}
+	}
+	if restarts != 0 {
+		p.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+			Reason: "OOMKilled", FinishedAt: metav1.NewTime(now.Add(-lastRestart)),
+		}
+	}
+	return p
+}
+
+func TestRankRestarts(t *testing.T) {
+	pods := []corev1.Pod{
+		pod("db-0", "data", "db", "", 4, time.Hour),
+		pod("db-1", "data", "db", "", 3, 2*time.Hour),
+		pod("api-0", "web", "api", "payments", 12, 10*time.Minute),
+		pod("cache-0", "web", "cache", "payments", 50, 48*time.Hour),
+		pod("queue-0", "web", "queue", "payments", 0, 0),
+	}
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "data", Labels: map[string]string{"reporting_team": "storage"}}},
+	}
+
+	got := top.RankRestarts(pods, namespaces, now.Add(-24*time.Hour))
+	want := []top.WorkloadRestarts{
+		{Workload: "web/StatefulSet/api", Owner: "payments", Restarts: 12, Pods: 1, LastRestart: now.Add(-10 * time.Minute), Reason: "OOMKilled"},
+		{Workload: "data/StatefulSet/db", Owner: "storage", Restarts: 7, Pods: 2, LastRestart: now.Add(-time.Hour), Reason: "OOMKilled"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RankRestarts() = %+v, expected %+v", got, want)
+	}
+}
+
+func TestRankProblems(t *testing.T) {
+	entries := []checkup.HistoryEntry{
+		{ProblemIDs: map[string]int{"PodCrashLoopBackOff": 3, "HighRestarts": 1}, Owners: map[string]int{"payments": 4}},
+		{ProblemIDs: map[string]int{"PodCrashLoopBackOff": 2}, Owners: map[string]int{"payments": 2}},
+		{ProblemIDs: map[string]int{"PodCrashLoopBackOff": 1, "NodeNotReady": 1}},
+		{},
+	}
+
+	problems, owners := top.RankProblems(entries)
+	wantProblems := []top.Frequency{
+		{Name: "PodCrashLoopBackOff", Runs: 3, Occurrences: 6},
+		{Name: "HighRestarts", Runs: 1, Occurrences: 1},
+		{Name: "NodeNotReady", Runs: 1, Occurrences: 1},
+	}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("RankProblems() problems = %+v, expected %+v", problems, wantProblems)
+	}
+	wantOwners := []top.Frequency{{Name: "payments", Runs: 2, Occurrences: 6}}
+	if !reflect.DeepEqual(owners, wantOwners) {
+		t.Errorf("RankProblems() owners = %+v, expected %+v", owners, wantOwners)
+	}
+
+	for _, tt := range []struct {
+		f    top.Frequency
+		want string
+	}{
+		{top.Frequency{Runs: 3}, "chronic"},
+		{top.Frequency{Runs: 2}, "chronic"},
+		{top.Frequency{Runs: 1}, "one-off"},
+	} {
+		if got := tt.f.Pattern(len(entries)); got != tt.want {
+			t.Errorf("Pattern(%d) for %d run(s) = %q, expected %q", len(entries), tt.f.Runs, got, tt.want)
+		}
+	}
+	if got := (&top.Frequency{Runs: 2}).Pattern(10); got != "recurring" {
+		t.Errorf("Pattern(10) for 2 runs = %q, expected recurring", got)
+	}
+}
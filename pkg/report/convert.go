@@ -0,0 +1,78 @@
+// Description: This file contains code for converting reports written
+// with older schema versions to the current one
+
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// resourceV0 is a resource with a problem in an unversioned report, the
+// fields of checkup.Resource at the time
+type resourceV0 struct {
+	Name           string
+	Owner          string
+	Type           string
+	ProblemID      string
+	ProblemDetails string
+	Warning        bool
+	Source         string
+	Labels         map[string]string
+	SuppressedBy   string
+	Related        []resourceV0
+	CreatedAt      *time.Time
+	LastRestartAt  *time.Time
+	Node           string
+	Images         []string
+}
+
+// readV0 converts a list of resources to a report, problems only have
+// their ID as the descriptions weren't included
+func readV0(b []byte) (*Report, error) {
+	var resources []resourceV0
+	if err := json.Unmarshal(b, &resources); err != nil {
+		return nil, errors.Wrap(err, "failed to parse unversioned report")
+	}
+
+	r := &Report{SchemaVersion: SchemaVersion, Problems: make([]Problem, 0), Findings: make([]Finding, 0, len(resources))}
+	seen := make(map[string]bool)
+	for i := range resources {
+		r.Findings = append(r.Findings, resources[i].finding())
+		if id := resources[i].ProblemID; !seen[id] {
+			seen[id] = true
+			r.Problems = append(r.Problems, Problem{ID: id})
+		}
+	}
+	return r, nil
+}
+
+// finding converts the resource to a finding
+func (res *resourceV0) finding() Finding {
+	severity := SeverityError
+	if res.Warning {
+		severity = SeverityWarning
+	}
+
+	f := Finding{
+		ProblemID:     res.ProblemID,
+		Resource:      res.Name,
+		Type:          res.Type,
+		Severity:      severity,
+		Details:       res.ProblemDetails,
+		Owner:         res.Owner,
+		Source:        res.Source,
+		Labels:        res.Labels,
+		SuppressedBy:  res.SuppressedBy,
+		CreatedAt:     res.CreatedAt,
+		LastRestartAt: res.LastRestartAt,
+		Node:          res.Node,
+		Images:        res.Images,
+	}
+	for i := range res.Related {
+		f.Related = append(f.Related, res.Related[i].finding())
+	}
+	return f
+}
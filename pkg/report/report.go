@@ -0,0 +1,216 @@
+// Description: This file contains the machine-readable report k8r writes
+// with --output json or yaml, and reading reports written by older versions
+
+// Package report defines the schema of the machine-readable reports k8r
+// writes. Fields are only ever added within a schema version, renaming or
+// removing one bumps SchemaVersion and adds a conversion to Read, so that
+// files written by older versions of k8r can still be read.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// SchemaVersion is the version of the report schema written by this
+// version of k8r
+//
+// Versions:
+//   - 0: the list of checkup.Resource values marshaled as is, e.g. by
+//     scripts using the checkup package before reports were versioned
+//   - 1: the Report type
+const SchemaVersion = 1
+
+// Severities problems are reported with
+const (
+	// SeverityCritical needs attention right away
+	SeverityCritical = "critical"
+
+	// SeverityError is causing a problem now
+	SeverityError = "error"
+
+	// SeverityWarning isn't causing a problem now
+	SeverityWarning = "warning"
+
+	// SeverityInfo is worth knowing about but not acting on
+	SeverityInfo = "info"
+)
+
+// Report is a report of the problems found in a cluster or in manifests
+type Report struct {
+	// SchemaVersion is the version of the schema the report was written
+	// with
+	SchemaVersion int `json:"schemaVersion"`
+
+	// GeneratedAt is when the report was written
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// Cluster is the API server host of the cluster the report is about,
+	// empty for reports about manifests
+	Cluster string `json:"cluster,omitempty"`
+
+	// Interrupted is true if the scan was interrupted, so the report only
+	// covers part of the cluster
+	Interrupted bool `json:"interrupted,omitempty"`
+
+	// Suppressed is the number of findings that were left out as symptoms
+	// of other findings
+	Suppressed int `json:"suppressed"`
+
+	// Incomplete are the resources that couldn't be listed, problems with
+	// them aren't in the report
+	Incomplete []Incomplete `json:"incomplete,omitempty"`
+
+	// Problems are the problems that were found
+	Problems []Problem `json:"problems"`
+
+	// Findings are the problems found with each resource
+	Findings []Finding `json:"findings"`
+}
+
+// Problem is a kind of problem k8r checks for
+type Problem struct {
+	// ID is the ID of the problem, e.g. PodNotReady
+	ID string `json:"id"`
+
+	// Description is a short description of the problem
+	Description string `json:"description"`
+
+	// HelpURL is where to read about fixing the problem
+	HelpURL string `json:"helpURL,omitempty"`
+}
+
+// Incomplete is a kind of resource that couldn't be listed
+type Incomplete struct {
+	// Resource is the resource with its group, e.g. statefulsets.apps
+	Resource string `json:"resource"`
+
+	// Namespace is the namespace it couldn't be listed in, empty when it
+	// couldn't be listed in any namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// Reason is why it couldn't be listed
+	Reason string `json:"reason"`
+}
+
+// Alert is an Alertmanager alert about the resource of a finding
+type Alert struct {
+	// Name is the alertname of the alert
+	Name string `json:"name"`
+
+	// State is firing, silenced or inhibited
+	State string `json:"state"`
+}
+
+// Finding is a problem with a resource
+type Finding struct {
+	// ProblemID is the ID of the problem
+	ProblemID string `json:"problemID"`
+
+	// Resource is the name of the resource, namespace/name for
+	// namespaced resources
+	Resource string `json:"resource"`
+
+	// Type is the type of the resource, e.g. pod
+	Type string `json:"type"`
+
+	// Severity is one of the Severity constants
+	Severity string `json:"severity"`
+
+	// Details are details about the problem specific to the resource
+	Details string `json:"details,omitempty"`
+
+	// Owner is the team that owns the resource, if known
+	Owner string `json:"owner,omitempty"`
+
+	// Source is where the resource was read from when it didn't come
+	// from a cluster, e.g. a manifest file and line
+	Source string `json:"source,omitempty"`
+
+	// Labels are the labels of the resource
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// SuppressedBy is the finding that explains this one, if it is a
+	// symptom of another
+	SuppressedBy string `json:"suppressedBy,omitempty"`
+
+	// Related are other findings with the same resource that were
+	// consolidated into this one
+	Related []Finding `json:"related,omitempty"`
+
+	// CreatedAt is when the resource was created, if known
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+
+	// LastRestartAt is when a container of the pod last restarted
+	LastRestartAt *time.Time `json:"lastRestartAt,omitempty"`
+
+	// Node is the node the pod is scheduled on
+	Node string `json:"node,omitempty"`
+
+	// Images are the images of the pod's containers
+	Images []string `json:"images,omitempty"`
+
+	// Alerts are the Alertmanager alerts about the resource, if an
+	// Alertmanager was configured
+	Alerts []Alert `json:"alerts,omitempty"`
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode report")
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteYAML writes the report as YAML
+func (r *Report) WriteYAML(w io.Writer) error {
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode report")
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Read reads a JSON or YAML report written by any version of k8r,
+// converting it to the current schema
+func Read(b []byte) (*Report, error) {
+	b, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse report")
+	}
+
+	// Unversioned reports are lists of resources
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		return readV0(b)
+	}
+
+	var version struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(b, &version); err != nil {
+		return nil, errors.Wrap(err, "failed to parse report")
+	}
+	switch {
+	case version.SchemaVersion == 0:
+		return nil, fmt.Errorf("report has no schemaVersion")
+	case version.SchemaVersion > SchemaVersion:
+		return nil, fmt.Errorf("report schema version %d is newer than %d, upgrade k8r to read it",
+			version.SchemaVersion, SchemaVersion)
+	}
+
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, errors.Wrap(err, "failed to parse report")
+	}
+	return &r, nil
+}
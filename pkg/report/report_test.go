@@ -0,0 +1,78 @@
+package report_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ashvin-Ranjan/k8r/pkg/report"
+)
+
+func TestRoundTrip(t *testing.T) {
+	want := &report.Report{
+		SchemaVersion: report.SchemaVersion,
+		GeneratedAt:   time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		Suppressed:    1,
+		Problems:      []report.Problem{{ID: "PodNotReady", Description: "A pod is not ready"}},
+		Findings: []report.Finding{{
+			ProblemID: "PodNotReady",
+			Resource:  "default/api",
+			Type:      "pod",
+			Severity:  report.SeverityError,
+			Details:   "Container app is not ready",
+		}},
+	}
+
+	for name, write := range map[string]func(*report.Report, *bytes.Buffer) error{
+		"json": func(r *report.Report, b *bytes.Buffer) error { return r.WriteJSON(b) },
+		"yaml": func(r *report.Report, b *bytes.Buffer) error { return r.WriteYAML(b) },
+	} {
+		var b bytes.Buffer
+		if err := write(want, &b); err != nil {
+			t.Fatalf("%s: write error = %v", name, err)
+		}
+		got, err := report.Read(b.Bytes())
+		if err != nil {
+			t.Fatalf("%s: Read() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got.Findings, want.Findings) || got.Suppressed != want.Suppressed || !got.GeneratedAt.Equal(want.GeneratedAt) {
+			t.Errorf("%s: Read() = %+v, expected %+v", name, got, want)
+		}
+	}
+}
+
+func TestReadUnversioned(t *testing.T) {
+	got, err := report.Read([]byte(`[
+  {"Name": "default/api", "Type": "pod", "ProblemID": "HighRestarts", "ProblemDetails": "restarted 5 times", "Warning": true,
+   "Related": [{"Name": "default/api", "Type": "pod", "ProblemID": "PodOOMKilled"}]}
+]`))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if got.SchemaVersion != report.SchemaVersion || len(got.Findings) != 1 || len(got.Problems) != 1 {
+		t.Fatalf("Read() = %+v, expected one finding converted to version %d", got, report.SchemaVersion)
+	}
+	f := got.Findings[0]
+	if f.Resource != "default/api" || f.Severity != report.SeverityWarning || f.Details != "restarted 5 times" {
+		t.Errorf("finding = %+v, expected the resource's fields", f)
+	}
+	if len(f.Related) != 1 || f.Related[0].ProblemID != "PodOOMKilled" || f.Related[0].Severity != report.SeverityError {
+		t.Errorf("related = %+v, expected the related resource as an error", f.Related)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for contents, want := range map[string]string{
+		`{"findings": []}`:       "no schemaVersion",
+		`{"schemaVersion": 99}`:  "newer than",
+		"schemaVersion: [broken": "failed to parse",
+	} {
+		_, err := report.Read([]byte(contents))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Read(%q) error = %v, expected it to contain %q", contents, err, want)
+		}
+	}
+}